@@ -47,6 +47,8 @@ func TestIntegration(t *testing.T) {
 			"UserInfo",
 			"Disconnect",
 			"ListP",
+			"DeleteObjects",
+			"HardLink",
 		},
 	}
 	if *fstest.RemoteName == "" {
@@ -202,6 +202,7 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 		UserDirMetadata:          true,
 		DirModTimeUpdatesOnWrite: true,
 		PartialUploads:           true,
+		TransformsData:           true,
 	}).Fill(ctx, f).Mask(ctx, wrappedFs).WrapsFs(f, wrappedFs)
 	// We support reading MIME types no matter the wrapped fs
 	f.features.ReadMimeType = true
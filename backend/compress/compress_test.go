@@ -26,6 +26,8 @@ var defaultOpt = fstests.Opt{
 		"PutStream",
 		"UserInfo",
 		"Disconnect",
+		"DeleteObjects",
+		"HardLink",
 	},
 	TiersToTest:                  []string{"STANDARD", "STANDARD_IA"},
 	UnimplementableObjectMethods: []string{},
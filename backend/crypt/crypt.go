@@ -292,6 +292,7 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 		UserDirMetadata:          true,
 		DirModTimeUpdatesOnWrite: true,
 		PartialUploads:           true,
+		TransformsData:           true,
 	}).Fill(ctx, f).Mask(ctx, wrappedFs).WrapsFs(f, wrappedFs)
 
 	// Enable ListP always
@@ -24,6 +24,8 @@ func TestIntegration(t *testing.T) {
 		UnimplementableFsMethods: []string{
 			"OpenWriterAt",
 			"OpenChunkWriter",
+			"DeleteObjects",
+			"HardLink",
 		},
 		UnimplementableObjectMethods: []string{},
 	}
@@ -0,0 +1,45 @@
+// Hard link functions
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/file"
+	"github.com/rclone/rclone/lib/random"
+)
+
+// HardLink makes remote another name for the file src, replacing
+// anything at remote, and returns the new object.
+//
+// src must be a file on the local disk, on the same filesystem as
+// remote.
+func (f *Fs) HardLink(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := fs.UnWrapObject(src).(*Object)
+	if !ok {
+		return nil, fmt.Errorf("can't hard link to %v: not a local file", src)
+	}
+	dstPath := f.localPath(remote)
+	err := file.MkdirAll(filepath.Dir(dstPath), 0777)
+	if err != nil {
+		return nil, err
+	}
+
+	// Link to a temporary name then rename it over the destination
+	// so there is always a file at remote
+	tmpPath := dstPath + ".rclone-link-" + random.String(8)
+	err = os.Link(srcObj.path, tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hard link: %w", err)
+	}
+	err = os.Rename(tmpPath, dstPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to rename hard link: %w", err)
+	}
+	return f.NewObject(ctx, remote)
+}
@@ -0,0 +1,14 @@
+// Hard link reading functions
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package local
+
+// HardLinkID returns an ID which is the same for all the names of the
+// file on the disk and the number of names it has.
+//
+// Hard links aren't detected on this OS so it always returns an empty
+// ID.
+func (o *Object) HardLinkID() (id string, links uint64) {
+	return "", 0
+}
@@ -0,0 +1,31 @@
+// Hard link reading functions
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package local
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// HardLinkID returns an ID which is the same for all the names of the
+// file on the disk and the number of names it has.
+//
+// It returns an empty ID if it can't be read.
+func (o *Object) HardLinkID() (id string, links uint64) {
+	fi, err := os.Lstat(o.path)
+	if err != nil {
+		fs.Debugf(o, "Failed to read hard link ID: %v", err)
+		return "", 0
+	}
+	statT, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		fs.Debugf(o, "Type assertion fi.Sys().(*syscall.Stat_t) failed from: %#v", fi.Sys())
+		return "", 0
+	}
+	return fmt.Sprintf("%d:%d", statT.Dev, statT.Ino), uint64(statT.Nlink) // nolint: unconvert
+}
@@ -195,6 +195,40 @@ func TestSymlink(t *testing.T) {
 	require.NoError(t, in.Close())
 }
 
+func TestHardLink(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" || runtime.GOOS == "js" {
+		t.Skip("hard links not detected on " + runtime.GOOS)
+	}
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	f := r.Flocal.(*Fs)
+	modTime := fstest.Time("2001-02-03T04:05:10.123123123Z")
+	r.WriteFile("file.txt", "hello", modTime)
+	r.WriteFile("sub/other.txt", "other", modTime)
+
+	o, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	id, links := o.(*Object).HardLinkID()
+	assert.NotEqual(t, "", id)
+	assert.Equal(t, uint64(1), links)
+
+	// Link to a new name and over an existing file
+	for _, remote := range []string{"dir/link.txt", "sub/other.txt"} {
+		link, err := f.HardLink(ctx, o, remote)
+		require.NoError(t, err)
+		assert.Equal(t, remote, link.Remote())
+		assert.Equal(t, int64(5), link.Size())
+		linkID, _ := link.(*Object).HardLinkID()
+		assert.Equal(t, id, linkID)
+	}
+	_, links = o.(*Object).HardLinkID()
+	assert.Equal(t, uint64(3), links)
+
+	// Not a local file
+	_, err = f.HardLink(ctx, object.NewMemoryObject("x", modTime, []byte("x")), "x")
+	assert.Error(t, err)
+}
+
 func TestSymlinkError(t *testing.T) {
 	m := configmap.Simple{
 		"links":      "true",
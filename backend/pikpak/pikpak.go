@@ -1043,6 +1043,22 @@ func (f *Fs) Purge(ctx context.Context, dir string) error {
 	return f.purgeCheck(ctx, dir, false)
 }
 
+// DeleteObjects deletes the objects passed in with one request
+//
+// Optional interface: Only implement this if you have a way of
+// deleting lots of files quicker than calling Remove() on each
+func (f *Fs) DeleteObjects(ctx context.Context, objs []fs.Object) error {
+	IDs := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		o, ok := obj.(*Object)
+		if !ok {
+			return fmt.Errorf("can't delete %v as it isn't a pikpak object", obj)
+		}
+		IDs = append(IDs, o.id)
+	}
+	return f.deleteObjects(ctx, IDs, f.opt.UseTrash)
+}
+
 // CleanUp empties the trash
 func (f *Fs) CleanUp(ctx context.Context) (err error) {
 	opts := rest.Opts{
@@ -1933,6 +1949,7 @@ var (
 	_ fs.Copier          = (*Fs)(nil)
 	_ fs.Mover           = (*Fs)(nil)
 	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.ObjectsDeleter  = (*Fs)(nil)
 	_ fs.Commander       = (*Fs)(nil)
 	_ fs.DirCacheFlusher = (*Fs)(nil)
 	_ fs.PublicLinker    = (*Fs)(nil)
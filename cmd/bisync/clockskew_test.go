@@ -0,0 +1,66 @@
+package bisync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisyncClockSkewTolerance(t *testing.T) {
+	ctx := context.Background()
+	dir1, dir2, workdir := t.TempDir(), t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir1, "file.txt"), []byte("file"), 0666))
+	fs1, err := cache.Get(ctx, dir1)
+	require.NoError(t, err)
+	fs2, err := cache.Get(ctx, dir2)
+	require.NoError(t, err)
+	require.NoError(t, Bisync(ctx, fs1, fs2, &Options{Workdir: workdir, Resync: true}))
+
+	// Skew the modtime on Path2 by a few seconds
+	path2 := filepath.Join(dir2, "file.txt")
+	info, err := os.Stat(path2)
+	require.NoError(t, err)
+	skewed := info.ModTime().Add(3 * time.Second)
+	require.NoError(t, os.Chtimes(path2, skewed, skewed))
+
+	call := rc.Calls.Get("sync/bisync-explain")
+	require.NotNil(t, call)
+	explain := func(tolerance string) rc.Params {
+		in := rc.Params{"path1": dir1, "path2": dir2, "workdir": workdir, "file": "file.txt"}
+		if tolerance != "" {
+			in["clockSkewTolerance"] = tolerance
+		}
+		out, err := call.Fn(ctx, in)
+		require.NoError(t, err)
+		return out
+	}
+
+	out := explain("")
+	assert.Equal(t, "modified: time (newer)", out["change2"])
+	out = explain("1s")
+	assert.Equal(t, "modified: time (newer)", out["change2"])
+	out = explain("5s")
+	assert.Equal(t, "unchanged", out["change2"])
+	assert.Equal(t, "none - unchanged", out["action"])
+
+	// A run with the tolerance doesn't change anything
+	ctx, ci := fs.AddConfig(ctx)
+	require.NoError(t, Bisync(ctx, fs1, fs2, &Options{Workdir: workdir, ClockSkewTolerance: fs.Duration(5 * time.Second)}))
+	assert.Equal(t, fs.Duration(5*time.Second), ci.ModifyWindow)
+	info, err = os.Stat(filepath.Join(dir1, "file.txt"))
+	require.NoError(t, err)
+	assert.NotEqual(t, skewed.Unix(), info.ModTime().Unix())
+
+	// Negative tolerances are rejected
+	in := rc.Params{"path1": dir1, "path2": dir2, "workdir": workdir, "file": "file.txt", "clockSkewTolerance": "-1s"}
+	_, err = call.Fn(ctx, in)
+	assert.ErrorContains(t, err, "can't be negative")
+}
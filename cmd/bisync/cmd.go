@@ -61,6 +61,12 @@ type Options struct {
 	ConflictSuffixFlag    string
 	ConflictSuffix1       string
 	ConflictSuffix2       string
+	ConflictRetention     fs.Duration
+	TypeConflict          TypeConflict
+	RollbackScript        string
+	VFSCacheDir           string
+	ClockSkewTolerance    fs.Duration
+	HardLinks             bool
 }
 
 // Default values
@@ -150,6 +156,12 @@ func init() {
 	flags.FVarP(cmdFlags, &Opt.ConflictResolve, "conflict-resolve", "", "Automatically resolve conflicts by preferring the version that is: "+ConflictResolveList+" (default: none)", "")
 	flags.FVarP(cmdFlags, &Opt.ConflictLoser, "conflict-loser", "", "Action to take on the loser of a sync conflict (when there is a winner) or on both files (when there is no winner): "+ConflictLoserList+" (default: num)", "")
 	flags.StringVarP(cmdFlags, &Opt.ConflictSuffixFlag, "conflict-suffix", "", Opt.ConflictSuffixFlag, "Suffix to use when renaming a --conflict-loser. Can be either one string or two comma-separated strings to assign different suffixes to Path1/Path2. (default: 'conflict')", "")
+	flags.FVarP(cmdFlags, &Opt.ConflictRetention, "conflict-retention", "", "Delete conflict losers once bisync renamed them longer ago than this (default: 0 (keep forever))", "")
+	flags.FVarP(cmdFlags, &Opt.TypeConflict, "type-conflict", "", "How to resolve a path being a file on one side and a directory on the other: "+TypeConflictList+" (default: rename)", "")
+	flags.StringVarP(cmdFlags, &Opt.RollbackScript, "rollback-script", "", Opt.RollbackScript, "Write a shell script to this file which undoes the changes made by the run", "")
+	flags.StringVarP(cmdFlags, &Opt.VFSCacheDir, "vfs-cache-dir", "", Opt.VFSCacheDir, "Read files from the VFS cache in this --cache-dir where they are up to date", "")
+	flags.FVarP(cmdFlags, &Opt.ClockSkewTolerance, "clock-skew-tolerance", "", "Treat modtimes within this of each other as equal, to allow for clocks out of step (default: 0)", "")
+	flags.BoolVarP(cmdFlags, &Opt.HardLinks, "hard-links", "", Opt.HardLinks, "Preserve hard links between the files copied where both paths support it, such as local disks", "")
 	_ = cmdFlags.MarkHidden("debugname")
 	_ = cmdFlags.MarkHidden("localtime")
 }
@@ -50,6 +50,15 @@ func (b *bisyncRun) setCompareDefaults(ctx context.Context) error {
 		b.opt.Compare.Size = false
 	}
 
+	// Widen the modify window so the deltas and the sync agree on
+	// which modtimes are the same
+	if b.opt.ClockSkewTolerance < 0 {
+		return errors.New("--clock-skew-tolerance can't be negative")
+	}
+	if b.opt.ClockSkewTolerance > ci.ModifyWindow {
+		ci.ModifyWindow = b.opt.ClockSkewTolerance
+	}
+
 	err = b.setFromCompareFlag(ctx)
 	if err != nil {
 		return err
@@ -120,7 +129,10 @@ func sizeDiffers(a, b int64) bool {
 
 // returns true if the hashes are definitely different.
 // returns false if equal, or if either is unknown.
-func hashDiffers(a, b string, ht1, ht2 hash.Type, size1, size2 int64) bool {
+//
+// If canDownload is set an MD5 can be compared with a hash which will
+// be downloaded.
+func hashDiffers(a, b string, ht1, ht2 hash.Type, size1, size2 int64, canDownload bool) bool {
 	if a == "" || b == "" {
 		if ht1 != hash.None && ht2 != hash.None && !(size1 <= 0 || size2 <= 0) {
 			fs.Logf(nil, Color(terminal.YellowFg, "WARNING: hash unexpectedly blank despite Fs support (%s, %s) (you may need to --resync!)"), a, b)
@@ -128,7 +140,7 @@ func hashDiffers(a, b string, ht1, ht2 hash.Type, size1, size2 int64) bool {
 		return false
 	}
 	if ht1 != ht2 {
-		if !(downloadHash && ((ht1 == hash.MD5 && ht2 == hash.None) || (ht1 == hash.None && ht2 == hash.MD5))) {
+		if !(canDownload && ((ht1 == hash.MD5 && ht2 == hash.None) || (ht1 == hash.None && ht2 == hash.MD5))) {
 			fs.Infof(nil, Color(terminal.YellowFg, "WARNING: Can't compare hashes of different types (%s, %s)"), ht1.String(), ht2.String())
 			return false
 		}
@@ -138,7 +150,7 @@ func hashDiffers(a, b string, ht1, ht2 hash.Type, size1, size2 int64) bool {
 
 // chooses hash type, giving priority to types both sides have in common
 func (b *bisyncRun) setHashType(ci *fs.ConfigInfo) {
-	downloadHash = b.opt.Compare.DownloadHash
+	b.downloadHash = b.opt.Compare.DownloadHash
 	if b.opt.Compare.NoSlowHash && b.opt.Compare.SlowHashDetected {
 		fs.Infof(nil, "Not checking for common hash as at least one slow hash detected.")
 	} else {
@@ -273,10 +285,15 @@ var downloadHash bool
 var downloadHashWarn mutex.Once
 var firstDownloadHash mutex.Once
 
-func tryDownloadHash(ctx context.Context, o fs.DirEntry, hashVal string) (string, error) {
+func (b *bisyncRun) tryDownloadHash(ctx context.Context, o fs.DirEntry, hashVal string) (string, error) {
 	if hashVal != "" || !downloadHash {
 		return hashVal, nil
 	}
+	return b.downloadHashSum(ctx, o, hashVal)
+}
+
+// downloadHashSum computes the MD5 of o by downloading it
+func (b *bisyncRun) downloadHashSum(ctx context.Context, o fs.DirEntry, hashVal string) (string, error) {
 	obj, ok := o.(fs.Object)
 	if !ok {
 		fs.Infof(o, "failed to download hash -- not an fs.Object")
@@ -298,7 +315,7 @@ func tryDownloadHash(ctx context.Context, o fs.DirEntry, hashVal string) (string
 		tr.Done(ctx, nil)
 	}()
 
-	sum, err := operations.HashSum(ctx, hash.MD5, false, true, obj)
+	sum, err := operations.HashSum(ctx, hash.MD5, false, true, readThroughVFSCacheObject(obj, b.opt.VFSCacheDir))
 	if err != nil {
 		fs.Infof(o, "DownloadHash -- hash: %v, err: %v", sum, err)
 	} else {
@@ -0,0 +1,166 @@
+package bisync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rclone/rclone/cmd/bisync/bilib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// conflictTimes records when bisync renamed each conflict loser, so
+// --conflict-retention only deletes the files bisync renamed itself
+// and counts their age from when they were renamed rather than from
+// their modification time.
+//
+// It is kept in the workdir next to the listings. The methods may be
+// called on a nil *conflictTimes which does nothing.
+type conflictTimes struct {
+	path    string               // file the times are kept in
+	renamed map[string]time.Time // when each conflict loser was renamed
+}
+
+// loadConflictTimes reads the times kept in path
+func loadConflictTimes(path string) (*conflictTimes, error) {
+	c := &conflictTimes{
+		path:    path,
+		renamed: map[string]time.Time{},
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &c.renamed); err != nil {
+		return nil, fmt.Errorf("failed to read conflict rename times from %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// save writes the times back to the workdir
+func (c *conflictTimes) save() error {
+	if c == nil {
+		return nil
+	}
+	data, err := json.Marshal(c.renamed)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, bilib.PermSecure)
+}
+
+// add records that the conflict loser remote was renamed just now
+func (c *conflictTimes) add(remote string) error {
+	if c == nil {
+		return nil
+	}
+	c.renamed[remote] = time.Now()
+	return c.save()
+}
+
+// cleanConflicts deletes the conflict losers renamed by earlier runs
+// more than --conflict-retention ago
+//
+// The files are found in the prior listings, which are updated so the
+// deletions aren't seen as changes. It also loads the times the
+// renames made by this run are recorded in.
+func (b *bisyncRun) cleanConflicts(ctx context.Context) (err error) {
+	if b.opt.ConflictRetention <= 0 {
+		return nil
+	}
+	b.conflictTimes, err = loadConflictTimes(b.basePath + ".conflicts")
+	if err != nil {
+		return err
+	}
+	if len(b.conflictTimes.renamed) == 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-time.Duration(b.opt.ConflictRetention))
+	present := map[string]int{}
+	for pathNum, f := range []fs.Fs{b.fs1, b.fs2} {
+		pathNum++
+		listing := b.listing1
+		if pathNum == 2 {
+			listing = b.listing2
+		}
+		if err = b.cleanConflictsIn(ctx, f, pathNum, listing, cutoff, present); err != nil {
+			return err
+		}
+	}
+	if b.opt.DryRun {
+		return nil
+	}
+	// Forget the files which have gone from both paths
+	for remote := range b.conflictTimes.renamed {
+		if present[remote] == 0 {
+			delete(b.conflictTimes.renamed, remote)
+		}
+	}
+	return b.conflictTimes.save()
+}
+
+// cleanConflictsIn deletes the expired conflict losers in listing from
+// f, which is Path1 if pathNum is 1, otherwise Path2
+//
+// The recorded conflict losers left in the listing are counted in
+// present.
+func (b *bisyncRun) cleanConflictsIn(ctx context.Context, f fs.Fs, pathNum int, listing string, cutoff time.Time, present map[string]int) (err error) {
+	ls, err := b.loadListing(listing)
+	if err != nil {
+		return err
+	}
+	ctx, ci := fs.AddConfig(ctx)
+	ctx = b.setBackupDir(ctx, pathNum)
+	var backupDir fs.Fs
+	changed := false
+	defer func() {
+		if changed {
+			if saveErr := ls.save(ctx, listing); saveErr != nil && err == nil {
+				err = saveErr
+			}
+		}
+	}()
+	for remote, renamed := range b.conflictTimes.renamed {
+		if !ls.has(remote) || ls.isDir(remote) {
+			continue
+		}
+		present[remote]++
+		if !renamed.Before(cutoff) {
+			continue
+		}
+		if operations.SkipDestructive(ctx, remote, "delete expired conflict loser") {
+			continue
+		}
+		obj, err := f.NewObject(ctx, remote)
+		if errors.Is(err, fs.ErrorObjectNotFound) {
+			// already gone so let the deltas find it
+			continue
+		} else if err != nil {
+			return err
+		}
+		if obj.ModTime(ctx).After(renamed) {
+			// changed since it was renamed
+			continue
+		}
+		b.indent(fmt.Sprintf("!Path%d", pathNum), bilib.FsPath(f)+remote, "Deleting conflict loser renamed more than --conflict-retention ago")
+		if ci.BackupDir != "" && backupDir == nil {
+			backupDir, err = operations.BackupDir(ctx, f, f, remote)
+			if err != nil {
+				return err
+			}
+		}
+		if err = operations.DeleteFileWithBackupDir(ctx, obj, backupDir); err != nil {
+			return fmt.Errorf("failed to delete expired conflict loser %s: %w", bilib.FsPath(f)+remote, err)
+		}
+		ls.remove(remote)
+		changed = true
+		present[remote]--
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package bisync
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisyncConflictRetention(t *testing.T) {
+	ctx := context.Background()
+	dir1, dir2, workdir := t.TempDir(), t.TempDir(), t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	write := func(dir, name, contents string, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0666))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	for _, dir := range []string{dir1, dir2} {
+		write(dir, "file.txt", "original", old)
+		write(dir, "user.txt.conflict1", "named by the user", old)
+	}
+	fs1, err := cache.Get(ctx, dir1)
+	require.NoError(t, err)
+	fs2, err := cache.Get(ctx, dir2)
+	require.NoError(t, err)
+	opt := &Options{Workdir: workdir, Resync: true, ConflictRetention: fs.Duration(24 * time.Hour)}
+	require.NoError(t, Bisync(ctx, fs1, fs2, opt))
+	opt.Resync = false
+
+	// Change both sides so the next run renames the conflict losers,
+	// keeping the old modification times
+	write(dir1, "file.txt", "changed on path1", old.Add(time.Hour))
+	write(dir2, "file.txt", "changed on path two", old.Add(2*time.Hour))
+	require.NoError(t, Bisync(ctx, fs1, fs2, opt))
+	for _, dir := range []string{dir1, dir2} {
+		assert.FileExists(t, filepath.Join(dir, "file.txt.conflict1"))
+		assert.FileExists(t, filepath.Join(dir, "file.txt.conflict2"))
+	}
+
+	// Just renamed conflict losers are kept even though their
+	// modification times are old, and files bisync didn't rename
+	// are never deleted
+	require.NoError(t, Bisync(ctx, fs1, fs2, opt))
+	for _, dir := range []string{dir1, dir2} {
+		assert.FileExists(t, filepath.Join(dir, "file.txt.conflict1"))
+		assert.FileExists(t, filepath.Join(dir, "file.txt.conflict2"))
+		assert.FileExists(t, filepath.Join(dir, "user.txt.conflict1"))
+	}
+
+	// Conflict losers renamed longer ago than --conflict-retention
+	// are deleted
+	matches, err := filepath.Glob(filepath.Join(workdir, "*.conflicts"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	times, err := loadConflictTimes(matches[0])
+	require.NoError(t, err)
+	assert.Len(t, times.renamed, 2)
+	times.renamed["file.txt.conflict1"] = time.Now().Add(-25 * time.Hour)
+	require.NoError(t, times.save())
+	require.NoError(t, Bisync(ctx, fs1, fs2, opt))
+	for _, dir := range []string{dir1, dir2} {
+		assert.NoFileExists(t, filepath.Join(dir, "file.txt.conflict1"))
+		assert.FileExists(t, filepath.Join(dir, "file.txt.conflict2"))
+		assert.FileExists(t, filepath.Join(dir, "user.txt.conflict1"))
+	}
+
+	// The next run sees no changes and forgets the deleted file
+	require.NoError(t, Bisync(ctx, fs1, fs2, opt))
+	for _, dir := range []string{dir1, dir2} {
+		assert.NoFileExists(t, filepath.Join(dir, "file.txt.conflict1"))
+	}
+	times, err = loadConflictTimes(matches[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file.txt.conflict2"}, slices.Collect(maps.Keys(times.renamed)))
+}
@@ -190,41 +190,16 @@ func (b *bisyncRun) findDeltas(fctx context.Context, f fs.Fs, oldListing string,
 			d |= deltaDeleted
 		} else if !now.isDir(file) {
 			// skip dirs here, as we only care if they are new/deleted, not newer/older
-			whatchanged := []string{}
-			if b.opt.Compare.Size {
-				if sizeDiffers(old.getSize(file), now.getSize(file)) {
-					fs.Debugf(file, "(old: %v current: %v)", old.getSize(file), now.getSize(file))
-					if now.getSize(file) > old.getSize(file) {
-						whatchanged = append(whatchanged, Color(terminal.MagentaFg, "size (larger)"))
-						d |= deltaLarger
-					} else {
-						whatchanged = append(whatchanged, Color(terminal.MagentaFg, "size (smaller)"))
-						d |= deltaSmaller
-					}
-					s = now.getSize(file)
-				}
+			var whatchanged []string
+			d, whatchanged = b.fileDelta(fctx, f, old, now, file)
+			if d.is(deltaSize) {
+				s = now.getSize(file)
 			}
-			if b.opt.Compare.Modtime {
-				if timeDiffers(fctx, old.getTime(file), now.getTime(file), f, f) {
-					if old.beforeOther(now, file) {
-						fs.Debugf(file, "(old: %v current: %v)", old.getTime(file), now.getTime(file))
-						whatchanged = append(whatchanged, Color(terminal.MagentaFg, "time (newer)"))
-						d |= deltaNewer
-					} else { // Current version is older than prior sync.
-						fs.Debugf(file, "(old: %v current: %v)", old.getTime(file), now.getTime(file))
-						whatchanged = append(whatchanged, Color(terminal.MagentaFg, "time (older)"))
-						d |= deltaOlder
-					}
-					t = now.getTime(file)
-				}
+			if d.is(deltaTime) {
+				t = now.getTime(file)
 			}
-			if b.opt.Compare.Checksum {
-				if hashDiffers(old.getHash(file), now.getHash(file), old.hash, now.hash, old.getSize(file), now.getSize(file)) {
-					fs.Debugf(file, "(old: %v current: %v)", old.getHash(file), now.getHash(file))
-					whatchanged = append(whatchanged, Color(terminal.MagentaFg, "hash"))
-					d |= deltaHash
-					h = now.getHash(file)
-				}
+			if d.is(deltaHash) {
+				h = now.getHash(file)
 			}
 			// concat changes and print log
 			if d.is(deltaModified) {
@@ -283,6 +258,45 @@ func (b *bisyncRun) findDeltas(fctx context.Context, f fs.Fs, oldListing string,
 	return
 }
 
+// fileDelta compares a file which is in both the old and the current
+// listing of the same side, returning what changed and a description of
+// each change for the log
+func (b *bisyncRun) fileDelta(fctx context.Context, f fs.Fs, old, now *fileList, file string) (d delta, whatchanged []string) {
+	if b.opt.Compare.Size {
+		if sizeDiffers(old.getSize(file), now.getSize(file)) {
+			fs.Debugf(file, "(old: %v current: %v)", old.getSize(file), now.getSize(file))
+			if now.getSize(file) > old.getSize(file) {
+				whatchanged = append(whatchanged, Color(terminal.MagentaFg, "size (larger)"))
+				d |= deltaLarger
+			} else {
+				whatchanged = append(whatchanged, Color(terminal.MagentaFg, "size (smaller)"))
+				d |= deltaSmaller
+			}
+		}
+	}
+	if b.opt.Compare.Modtime {
+		if timeDiffers(fctx, old.getTime(file), now.getTime(file), f, f) {
+			if old.beforeOther(now, file) {
+				fs.Debugf(file, "(old: %v current: %v)", old.getTime(file), now.getTime(file))
+				whatchanged = append(whatchanged, Color(terminal.MagentaFg, "time (newer)"))
+				d |= deltaNewer
+			} else { // Current version is older than prior sync.
+				fs.Debugf(file, "(old: %v current: %v)", old.getTime(file), now.getTime(file))
+				whatchanged = append(whatchanged, Color(terminal.MagentaFg, "time (older)"))
+				d |= deltaOlder
+			}
+		}
+	}
+	if b.opt.Compare.Checksum {
+		if hashDiffers(old.getHash(file), now.getHash(file), old.hash, now.hash, old.getSize(file), now.getSize(file), b.downloadHash) {
+			fs.Debugf(file, "(old: %v current: %v)", old.getHash(file), now.getHash(file))
+			whatchanged = append(whatchanged, Color(terminal.MagentaFg, "hash"))
+			d |= deltaHash
+		}
+	}
+	return d, whatchanged
+}
+
 // applyDeltas
 func (b *bisyncRun) applyDeltas(ctx context.Context, ds1, ds2 *deltaSet) (results2to1, results1to2 []Results, queues queues, err error) {
 	path1 := bilib.FsPath(b.fs1)
@@ -346,7 +360,7 @@ func (b *bisyncRun) applyDeltas(ctx context.Context, ds1, ds2 *deltaSet) (result
 			if d2.is(deltaOther) {
 				// if size or hash differ, skip this, as we already know they're not equal
 				if (b.opt.Compare.Size && sizeDiffers(ds1.size[file], ds2.size[file2])) ||
-					(b.opt.Compare.Checksum && hashDiffers(ds1.hash[file], ds2.hash[file2], b.opt.Compare.HashType1, b.opt.Compare.HashType2, ds1.size[file], ds2.size[file2])) {
+					(b.opt.Compare.Checksum && hashDiffers(ds1.hash[file], ds2.hash[file2], b.opt.Compare.HashType1, b.opt.Compare.HashType2, ds1.size[file], ds2.size[file2], b.downloadHash)) {
 					fs.Debugf(file, "skipping equality check as size/hash definitely differ")
 				} else {
 					checkit := func(filename string) {
@@ -368,7 +382,18 @@ func (b *bisyncRun) applyDeltas(ctx context.Context, ds1, ds2 *deltaSet) (result
 	// if there are potential conflicts to check, check them all here (outside the loop) in one fell swoop
 	matches, err := b.checkconflicts(ctxCheck, filterCheck, b.fs1, b.fs2)
 
+	// deal with paths which are a file on one side and a directory on the other
+	if conflicts := findTypeConflicts(ds1, ds2, ls1, ls2); len(conflicts) > 0 {
+		err = b.resolveTypeConflicts(ctxMove, conflicts, ds1, ds2, &copy1to2, &copy2to1, &handled, &renameSkipped)
+		if err != nil {
+			return
+		}
+	}
+
 	for _, file := range ds1.sort() {
+		if handled.Has(file) {
+			continue // already dealt with as a type conflict
+		}
 		alias := b.aliases.Alias(file)
 		p1 := path1 + file
 		p2 := path2 + alias
@@ -427,6 +452,7 @@ func (b *bisyncRun) applyDeltas(ctx context.Context, ds1, ds2 *deltaSet) (result
 						}
 					} else {
 						fs.Debugf(nil, "Files are NOT equal: %s", file)
+						b.conflicts++
 						err = b.resolve(ctxMove, path1, path2, file, alias, &renameSkipped, &copy1to2, &copy2to1, ds1, ds2)
 						if err != nil {
 							return
@@ -0,0 +1,274 @@
+package bisync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/cmd/bisync/bilib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/rc"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "sync/bisync-explain",
+		AuthRequired: true,
+		Fn:           rcBisyncExplain,
+		Title:        "Explain what bisync would do with a single file.",
+		Help:         explainHelp,
+	})
+}
+
+func rcBisyncExplain(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	opt := &Options{}
+	octx, _ := fs.AddConfig(ctx)
+
+	file, err := in.GetString("file")
+	if err != nil {
+		return nil, err
+	}
+	file = strings.Trim(file, "/")
+	if opt.Workdir, err = in.GetString("workdir"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	if opt.CompareFlag, err = in.GetString("compare"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	if opt.IgnoreListingChecksum, err = in.GetBool("ignoreListingChecksum"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	if opt.ClockSkewTolerance, err = in.GetFsDuration("clockSkewTolerance"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	for name, value := range map[string]interface{ Set(string) error }{
+		"conflictResolve": &opt.ConflictResolve,
+		"conflictLoser":   &opt.ConflictLoser,
+	} {
+		s, err := in.GetString(name)
+		if rc.NotErrParamNotFound(err) {
+			return nil, err
+		}
+		if s != "" {
+			if err := value.Set(s); err != nil {
+				return nil, rc.NewErrParamInvalid(err)
+			}
+		}
+	}
+
+	fs1, err := rc.GetFsNamed(octx, in, "path1")
+	if err != nil {
+		return nil, err
+	}
+	fs2, err := rc.GetFsNamed(octx, in, "path2")
+	if err != nil {
+		return nil, err
+	}
+
+	return explain(octx, fs1, fs2, opt, file)
+}
+
+// explain works out what bisync would do with file on its next run
+// without changing anything
+func explain(ctx context.Context, fs1, fs2 fs.Fs, opt *Options, file string) (out rc.Params, err error) {
+	b := &bisyncRun{
+		fs1:  fs1,
+		fs2:  fs2,
+		opt:  opt,
+		octx: ctx,
+		fctx: ctx,
+	}
+	if opt.Workdir == "" {
+		opt.Workdir = DefaultWorkdir
+	}
+	if err = b.setCompareDefaults(ctx); err != nil {
+		return nil, err
+	}
+	if err = b.setResolveDefaults(ctx); err != nil {
+		return nil, err
+	}
+	if b.workDir, err = filepath.Abs(opt.Workdir); err != nil {
+		return nil, fmt.Errorf("failed to make workdir absolute: %w", err)
+	}
+	b.basePath = bilib.BasePath(ctx, b.workDir, fs1, fs2)
+	b.listing1 = b.basePath + ".path1.lst"
+	b.listing2 = b.basePath + ".path2.lst"
+
+	old1, now1, d1, err := b.explainSide(ctx, fs1, b.listing1, opt.Compare.HashType1, file)
+	if err != nil {
+		return nil, err
+	}
+	old2, now2, d2, err := b.explainSide(ctx, fs2, b.listing2, opt.Compare.HashType2, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return rc.Params{
+		"file":    file,
+		"path1":   explainFingerprint(now1, old1, file),
+		"path2":   explainFingerprint(now2, old2, file),
+		"change1": explainDelta(d1),
+		"change2": explainDelta(d2),
+		"action":  b.explainAction(file, now1, now2, d1, d2),
+	}, nil
+}
+
+// explainSide reads the prior listing and the current state of file on
+// one side and works out how it has changed in the same way as
+// findDeltas does
+func (b *bisyncRun) explainSide(ctx context.Context, f fs.Fs, listing string, hashType hash.Type, file string) (old, now *fileList, d delta, err error) {
+	old, err = b.loadListing(listing)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, d, fmt.Errorf("no prior listing for %s - must run --resync first: %w", fs.ConfigString(f), err)
+	} else if err != nil {
+		return nil, nil, d, err
+	}
+	if old.isDir(file) {
+		return nil, nil, d, rc.NewErrParamInvalid(fmt.Errorf("%q is a directory", file))
+	}
+
+	now = newFileList()
+	now.hash = hashType
+	o, err := f.NewObject(ctx, file)
+	if err != nil && err != fs.ErrorObjectNotFound {
+		return nil, nil, d, err
+	}
+	if err == nil {
+		var hashVal string
+		if hashType != hash.None {
+			if hashVal, err = o.Hash(ctx, hashType); err != nil {
+				return nil, nil, d, err
+			}
+		}
+		if hashVal == "" && b.downloadHash {
+			if hashVal, err = b.downloadHashSum(ctx, o, hashVal); err != nil {
+				return nil, nil, d, err
+			}
+		}
+		var modtime time.Time
+		if b.opt.Compare.Modtime {
+			modtime = o.ModTime(ctx).In(TZ)
+		}
+		now.put(o.Remote(), o.Size(), modtime, hashVal, "", "-")
+	}
+
+	switch {
+	case old.has(file) && !now.has(file):
+		d = deltaDeleted
+	case !old.has(file) && now.has(file):
+		d = deltaNew
+	case old.has(file):
+		d, _ = b.fileDelta(ctx, f, old, now, file)
+	}
+	return old, now, d, nil
+}
+
+// explainAction describes what applyDeltas would do with file given
+// the changes on each side
+func (b *bisyncRun) explainAction(file string, now1, now2 *fileList, d1, d2 delta) string {
+	switch {
+	case d1.is(deltaOther) && d2.is(deltaOther):
+		if !b.explainDiffers(file, now1, now2) {
+			return "none - changed identically on both paths"
+		}
+		return b.explainConflict(file, now1, now2)
+	case d1.is(deltaOther):
+		return "copy Path1 to Path2"
+	case d2.is(deltaOther):
+		return "copy Path2 to Path1"
+	case d1.is(deltaDeleted) && d2.is(deltaDeleted):
+		return "none - deleted on both paths"
+	case d1.is(deltaDeleted):
+		return "delete from Path2"
+	case d2.is(deltaDeleted):
+		return "delete from Path1"
+	}
+	return "none - unchanged"
+}
+
+// explainDiffers returns true if file is definitely different on the
+// two sides - this is like fileInfoEqual but doesn't log
+func (b *bisyncRun) explainDiffers(file string, now1, now2 *fileList) bool {
+	return (b.opt.Compare.Size && sizeDiffers(now1.getSize(file), now2.getSize(file))) ||
+		(b.opt.Compare.Modtime && timeDiffers(b.fctx, now1.getTime(file), now2.getTime(file), b.fs1, b.fs2)) ||
+		(b.opt.Compare.Checksum && !b.opt.IgnoreListingChecksum && hashDiffers(now1.getHash(file), now2.getHash(file), b.opt.Compare.HashType1, b.opt.Compare.HashType2, now1.getSize(file), now2.getSize(file), b.downloadHash))
+}
+
+// explainConflict describes how a file changed differently on both
+// sides would be resolved
+func (b *bisyncRun) explainConflict(file string, now1, now2 *fileList) string {
+	winner := 0
+	if b.opt.ConflictResolve != PreferNone {
+		ds := func(ls *fileList) *deltaSet {
+			return &deltaSet{
+				size: map[string]int64{file: ls.getSize(file)},
+				time: map[string]time.Time{file: ls.getTime(file)},
+			}
+		}
+		winner = b.conflictWinner(ds(now1), ds(now2), file, file)
+	}
+	if winner == 0 {
+		return "conflict - no winner so rename both versions and copy each to the other path"
+	}
+	loser := 3 - winner
+	if b.opt.ConflictLoser == ConflictLoserDelete {
+		return fmt.Sprintf("conflict - Path%d wins so copy Path%d to Path%d replacing the loser", winner, winner, loser)
+	}
+	return fmt.Sprintf("conflict - Path%d wins so rename the Path%d version and copy each to the other path", winner, loser)
+}
+
+// explainFingerprint returns what bisync sees of file now and in the
+// prior listing
+func explainFingerprint(now, old *fileList, file string) rc.Params {
+	fingerprint := func(ls *fileList) rc.Params {
+		if !ls.has(file) {
+			return rc.Params{"exists": false}
+		}
+		modtime := ""
+		if t := ls.getTime(file); !t.IsZero() {
+			modtime = t.UTC().Format(time.RFC3339Nano)
+		}
+		return rc.Params{
+			"exists":  true,
+			"size":    ls.getSize(file),
+			"modtime": modtime,
+			"hash":    ls.getHash(file),
+		}
+	}
+	out := fingerprint(now)
+	out["prior"] = fingerprint(old)
+	return out
+}
+
+// explainDelta describes the change on one side
+func explainDelta(d delta) string {
+	switch {
+	case d.is(deltaNew):
+		return "new"
+	case d.is(deltaDeleted):
+		return "deleted"
+	case !d.is(deltaModified):
+		return "unchanged"
+	}
+	var changes []string
+	for _, change := range []struct {
+		d    delta
+		what string
+	}{
+		{deltaLarger, "size (larger)"},
+		{deltaSmaller, "size (smaller)"},
+		{deltaNewer, "time (newer)"},
+		{deltaOlder, "time (older)"},
+		{deltaHash, "hash"},
+	} {
+		if d.is(change.d) {
+			changes = append(changes, change.what)
+		}
+	}
+	return "modified: " + strings.Join(changes, ", ")
+}
@@ -0,0 +1,109 @@
+package bisync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisyncExplain(t *testing.T) {
+	ctx := context.Background()
+	dir1, dir2, workdir := t.TempDir(), t.TempDir(), t.TempDir()
+	for _, name := range []string{"same.txt", "changed.txt", "deleted.txt", "conflict.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir1, name), []byte(name), 0666))
+	}
+	fs1, err := cache.Get(ctx, dir1)
+	require.NoError(t, err)
+	fs2, err := cache.Get(ctx, dir2)
+	require.NoError(t, err)
+	require.NoError(t, Bisync(ctx, fs1, fs2, &Options{Workdir: workdir, Resync: true}))
+
+	// Make some changes
+	later := time.Now().Add(time.Hour)
+	write := func(dir, name, contents string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0666))
+		require.NoError(t, os.Chtimes(path, later, later))
+	}
+	write(dir1, "changed.txt", "changed on path1")
+	require.NoError(t, os.Remove(filepath.Join(dir2, "deleted.txt")))
+	write(dir1, "conflict.txt", "conflict on path1")
+	write(dir2, "conflict.txt", "conflict on path2!")
+	write(dir2, "new.txt", "new")
+
+	call := rc.Calls.Get("sync/bisync-explain")
+	require.NotNil(t, call)
+	explain := func(file string, extra rc.Params) rc.Params {
+		in := rc.Params{"path1": dir1, "path2": dir2, "workdir": workdir, "file": file}
+		for k, v := range extra {
+			in[k] = v
+		}
+		out, err := call.Fn(ctx, in)
+		require.NoError(t, err)
+		return out
+	}
+
+	out := explain("same.txt", nil)
+	assert.Equal(t, "unchanged", out["change1"])
+	assert.Equal(t, "unchanged", out["change2"])
+	assert.Equal(t, "none - unchanged", out["action"])
+
+	out = explain("changed.txt", nil)
+	assert.Equal(t, "modified: size (larger), time (newer)", out["change1"])
+	assert.Equal(t, "unchanged", out["change2"])
+	assert.Equal(t, "copy Path1 to Path2", out["action"])
+	path1 := out["path1"].(rc.Params)
+	assert.Equal(t, true, path1["exists"])
+	assert.Equal(t, int64(len("changed on path1")), path1["size"])
+	assert.Equal(t, int64(len("changed.txt")), path1["prior"].(rc.Params)["size"])
+
+	out = explain("deleted.txt", nil)
+	assert.Equal(t, "unchanged", out["change1"])
+	assert.Equal(t, "deleted", out["change2"])
+	assert.Equal(t, "delete from Path1", out["action"])
+	assert.Equal(t, false, out["path2"].(rc.Params)["exists"])
+
+	out = explain("new.txt", nil)
+	assert.Equal(t, "new", out["change2"])
+	assert.Equal(t, "copy Path2 to Path1", out["action"])
+
+	out = explain("conflict.txt", nil)
+	assert.Equal(t, "conflict - no winner so rename both versions and copy each to the other path", out["action"])
+	out = explain("conflict.txt", rc.Params{"conflictResolve": "larger"})
+	assert.Equal(t, "conflict - Path2 wins so rename the Path1 version and copy each to the other path", out["action"])
+
+	// Nothing was changed
+	_, err = os.Stat(filepath.Join(dir1, "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// A running bisync's globals are left alone
+	downloadHash = true
+	_ = explain("same.txt", nil)
+	assert.True(t, downloadHash)
+	downloadHash = false
+
+	// Must have a prior listing
+	_, err = call.Fn(ctx, rc.Params{"path1": dir1, "path2": dir2, "workdir": t.TempDir(), "file": "same.txt"})
+	assert.ErrorContains(t, err, "must run --resync first")
+}
+
+func TestBisyncExplainIgnoreListingChecksum(t *testing.T) {
+	ls1, ls2 := newFileList(), newFileList()
+	ls1.put("file.txt", 1, time.Time{}, "aaaa", "", "-")
+	ls2.put("file.txt", 1, time.Time{}, "bbbb", "", "-")
+	b := &bisyncRun{opt: &Options{}}
+	b.opt.Compare.Checksum = true
+	b.opt.Compare.HashType1 = hash.MD5
+	b.opt.Compare.HashType2 = hash.MD5
+	assert.True(t, b.explainDiffers("file.txt", ls1, ls2))
+	b.opt.IgnoreListingChecksum = true
+	assert.False(t, b.explainDiffers("file.txt", ls1, ls2))
+}
@@ -0,0 +1,178 @@
+package bisync
+
+import (
+	"context"
+	"sort"
+
+	"github.com/rclone/rclone/cmd/bisync/bilib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// hardLinkIDer is implemented by objects which can say which other
+// names are hard links to the same file, such as local files
+type hardLinkIDer interface {
+	HardLinkID() (id string, links uint64)
+}
+
+// hardLinker makes remote on the destination another name for src
+type hardLinker func(ctx context.Context, src fs.Object, remote string) (fs.Object, error)
+
+// hardLinks maps each name which is a hard link to a file copied
+// under another name onto that name
+type hardLinks map[string]string
+
+// hardLinkerFor returns the hardLinker to use to copy the hard links
+// in fsrc to fdst, or nil if --hard-links isn't set or fdst can't make
+// hard links
+func (b *bisyncRun) hardLinkerFor(fsrc, fdst fs.Fs) hardLinker {
+	if !b.opt.HardLinks || b.opt.DryRun {
+		return nil
+	}
+	linker := fdst.Features().HardLink
+	if linker == nil {
+		fs.Debugf(fdst, "Not preserving hard links from %v as the destination can't make them", fsrc)
+		return nil
+	}
+	return linker
+}
+
+// findHardLinks finds the objects passed in which are hard links to
+// each other. The first name of each file in sorted order is copied as
+// normal and the others are returned so they can be made into hard
+// links to it.
+func findHardLinks(objects []fs.Object) hardLinks {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Remote() < objects[j].Remote()
+	})
+	links := hardLinks{}
+	firstByID := map[string]string{}
+	for _, o := range objects {
+		ider, ok := fs.UnWrapObject(o).(hardLinkIDer)
+		if !ok {
+			continue
+		}
+		id, n := ider.HardLinkID()
+		if id == "" || n < 2 {
+			continue
+		}
+		if first, found := firstByID[id]; found {
+			links[o.Remote()] = first
+		} else {
+			firstByID[id] = o.Remote()
+		}
+	}
+	return links
+}
+
+// queuedHardLinks finds the files queued to be copied from fsrc which
+// are hard links to other files.
+//
+// All the files in fsrc are looked at, so files which are hard links
+// to files which haven't changed are linked to them.
+func queuedHardLinks(ctx context.Context, fsrc fs.Fs, files bilib.Names) (hardLinks, error) {
+	all, err := allHardLinks(ctx, fsrc)
+	if err != nil {
+		return nil, err
+	}
+	links := hardLinks{}
+	for name, first := range all {
+		if files.Has(name) {
+			links[name] = first
+		}
+	}
+	return links, nil
+}
+
+// allHardLinks finds the hard links among all the files in fsrc
+func allHardLinks(ctx context.Context, fsrc fs.Fs) (hardLinks, error) {
+	var objects []fs.Object
+	err := walk.ListR(ctx, fsrc, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		entries.ForObject(func(o fs.Object) {
+			objects = append(objects, o)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return findHardLinks(objects), nil
+}
+
+// sameContents returns true if dst has the same size and hash as src
+func sameContents(ctx context.Context, src, dst fs.Object) bool {
+	if src.Size() != dst.Size() {
+		return false
+	}
+	equal, _, err := operations.CheckHashes(ctx, src, dst)
+	return err == nil && equal
+}
+
+// makeHardLinks makes the names in links on fdst into hard links to
+// the names they link to, which should be on fdst already.
+//
+// If writeResults is set then the names weren't copied by the sync, so
+// the ones which can't be linked as the name they link to is missing or
+// different on fdst are copied instead, and the results are written
+// for the sync logger.
+func (b *bisyncRun) makeHardLinks(ctx context.Context, linker hardLinker, fsrc, fdst fs.Fs, links hardLinks, writeResults bool) (err error) {
+	names := make([]string, 0, len(links))
+	for name := range links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	dstFirst := map[string]fs.Object{}
+	for _, name := range names {
+		first := links[name]
+		src, srcErr := fsrc.NewObject(ctx, name)
+		if srcErr != nil {
+			// deleted since it was queued so leave for the next run
+			fs.Debugf(name, "Not hard linking as it is no longer on %v: %v", fsrc, srcErr)
+			continue
+		}
+		dst, dstErr := fdst.NewObject(ctx, name)
+		if dstErr != nil {
+			dst = nil
+		}
+		sigil := operations.MissingOnDst
+		if dst != nil {
+			sigil = operations.Differ
+		}
+		target, ok := dstFirst[first]
+		if !ok {
+			target, _ = fdst.NewObject(ctx, first)
+			if target != nil && !sameContents(ctx, src, target) {
+				target = nil
+			}
+			dstFirst[first] = target
+		}
+		if target == nil {
+			if !writeResults {
+				fs.Debugf(name, "Not hard linking as %q is missing or different on %v", first, fdst)
+				continue
+			}
+			fs.Infof(name, "Copying instead of hard linking as %q is missing or different on %v", first, fdst)
+			copied, copyErr := operations.Copy(ctx, fdst, dst, name, src)
+			if copyErr != nil {
+				err = copyErr
+			}
+			b.WriteResults(ctx, sigil, src, copied, copyErr)
+			continue
+		}
+		linked, linkErr := linker(ctx, target, name)
+		if linkErr != nil {
+			fs.Errorf(name, "Failed to hard link to %q: %v", first, linkErr)
+			err = linkErr
+			if writeResults {
+				b.WriteResults(ctx, sigil, src, dst, linkErr)
+			}
+			continue
+		}
+		fs.Infof(name, "Hard linked to %q on %v", first, fdst)
+		if writeResults {
+			b.WriteResults(ctx, sigil, src, linked, nil)
+		}
+	}
+	return err
+}
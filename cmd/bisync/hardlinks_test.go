@@ -0,0 +1,67 @@
+package bisync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisyncHardLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test as hard links aren't detected on Windows")
+	}
+	ctx := context.Background()
+	dir1, dir2, workdir := t.TempDir(), t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir1, "a.txt"), []byte("hello"), 0666))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir1, "sub"), 0777))
+	require.NoError(t, os.Link(filepath.Join(dir1, "a.txt"), filepath.Join(dir1, "sub", "b.txt")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir1, "other.txt"), []byte("other"), 0666))
+
+	call := rc.Calls.Get("sync/bisync")
+	require.NotNil(t, call)
+	bisync := func(resync bool) {
+		_, err := call.Fn(ctx, rc.Params{
+			"path1":     dir1,
+			"path2":     dir2,
+			"resync":    resync,
+			"hardLinks": true,
+			"workdir":   workdir,
+		})
+		require.NoError(t, err)
+	}
+	sameFile := func(name1, name2 string) bool {
+		fi1, err := os.Stat(filepath.Join(dir2, name1))
+		require.NoError(t, err)
+		fi2, err := os.Stat(filepath.Join(dir2, name2))
+		require.NoError(t, err)
+		return os.SameFile(fi1, fi2)
+	}
+
+	// The resync links the names on Path2
+	bisync(true)
+	assert.True(t, sameFile("a.txt", "sub/b.txt"))
+	assert.False(t, sameFile("a.txt", "other.txt"))
+
+	// New links found by a normal run are linked too
+	require.NoError(t, os.Link(filepath.Join(dir1, "other.txt"), filepath.Join(dir1, "sub", "c.txt")))
+	require.NoError(t, os.Link(filepath.Join(dir1, "other.txt"), filepath.Join(dir1, "sub", "d.txt")))
+	require.NoError(t, os.Link(filepath.Join(dir1, "a.txt"), filepath.Join(dir1, "sub", "e.txt")))
+	bisync(false)
+	assert.True(t, sameFile("sub/c.txt", "sub/d.txt"))
+
+	// Including to the names which haven't changed
+	assert.True(t, sameFile("other.txt", "sub/c.txt"))
+	assert.True(t, sameFile("a.txt", "sub/e.txt"))
+	data, err := os.ReadFile(filepath.Join(dir2, "sub", "d.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "other", string(data))
+
+	// And the listings agree with the files so the next run is clean
+	bisync(false)
+}
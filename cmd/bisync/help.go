@@ -41,11 +41,67 @@ var rcHelp = makeHelp(`This takes the following parameters
 - backupdir1 - --backup-dir for Path1. Must be a non-overlapping path on the same remote.
 - backupdir2 - --backup-dir for Path2. Must be a non-overlapping path on the same remote.
 - noCleanup - retain working files
+- rollbackScript - write a shell script to this server file which undoes the changes made by the run
+- vfsCacheDir - read files from the VFS cache in this server directory where they are up to date
+- clockSkewTolerance - treat modtimes within this duration of each other as equal
+- conflictRetention - delete conflict losers once bisync renamed them longer ago than this duration
+- hardLinks - preserve hard links between the files copied where both paths support it
+- typeConflict - how to resolve a path being a file on one side and a directory on the other:
+                 |rename| (default), |preferPath1|, |preferPath2|, |skip| or |error|
+- links - translate symlinks to and from |.rclonelink| files as |--links| and
+          |--vfs-links| do, so symlinks round-trip the same as through a mount
+- profile - name of a stored set of these parameters to use as the defaults
+            for any which aren't passed
+- profilesFile - server file to read the profiles from
+                 (default: |profiles.json| in the workdir)
+
+The profiles file is a JSON object with a set of parameters for each
+profile, for example
+
+    {
+        "photos": {
+            "path1": "drive:photos",
+            "path2": "/home/user/photos",
+            "maxDelete": 10,
+            "filtersFile": "/home/user/photos-filters.txt"
+        }
+    }
+
+Parameters passed in the call override the ones in the profile, so
+|{"profile": "photos", "dryRun": true}| does a dry run of the photos pair.
 
 See [bisync command help](https://rclone.org/commands/rclone_bisync/)
 and [full bisync description](https://rclone.org/bisync/)
 for more information.`)
 
+var metricsHelp = makeHelp(`This shows the lifetime counters for each pair of paths which
+has been bisynced by this rclone process, so you can monitor the health
+of the syncs without parsing the output of every run.
+
+The counters are kept in memory so they start again from zero when
+rclone is restarted. Dry runs are not counted.
+
+This takes the following optional parameters
+
+- path1 - only show pairs with this Path1 e.g. |drive:path1|
+- path2 - only show pairs with this Path2 e.g. |drive:path2|
+
+It returns a list under the key |pairs| with entries like this
+
+    {
+        "path1": "drive:path1",             // Path1 of the pair
+        "path2": "/home/user/path2",        // Path2 of the pair
+        "runs": 12,                         // number of bisync runs
+        "transfers": 34,                    // number of files copied by all the runs
+        "deletes": 5,                       // number of files deleted by all the runs
+        "renames": 2,                       // number of files moved, e.g. renamed conflicts, by all the runs
+        "conflicts": 1,                     // number of conflicts found by all the runs
+        "errors": 2,                        // number of runs which finished with an error
+        "lastRun": "2024-01-02T15:04:05Z",  // time the last run started
+        "lastDuration": 3.25,               // duration of the last run in seconds
+        "lastError": ""                     // error from the last run or "" if it succeeded
+    }`)
+
 var longHelp = shortHelp + makeHelp(`
 
 [Bisync](https://rclone.org/bisync/) provides a
@@ -63,3 +119,48 @@ or data loss can result. Questions can be asked in the [Rclone Forum](https://fo
 
 See [full bisync description](https://rclone.org/bisync/) for details.
 `)
+
+var explainHelp = makeHelp(`This explains what bisync would do with a single file on its next
+run, which is useful for finding out why a file was or wasn't synced.
+Nothing is changed on either path.
+
+It compares the file on each path with the prior listings in the same
+way as a real run does, so the pair must have been bisynced before.
+
+This takes the following parameters
+
+- path1 - a remote directory string e.g. |drive:path1|
+- path2 - a remote directory string e.g. |drive:path2|
+- file - the path of the file relative to path1 and path2
+- workdir - server directory for history files (default: |~/.cache/rclone/bisync|)
+- compare - comma-separated list of |size|, |modtime| and |checksum| as for |--compare|
+- ignoreListingChecksum - Do not use checksums for listings
+- clockSkewTolerance - as for |--clock-skew-tolerance|
+- conflictResolve - as for |--conflict-resolve|
+- conflictLoser - as for |--conflict-loser|
+
+It returns something like this
+
+    {
+        "file": "dir/file.txt",
+        "path1": {
+            "exists": true,
+            "size": 1234,
+            "modtime": "2024-01-02T15:04:05Z",
+            "hash": "",
+            "prior": {
+                "exists": true,
+                "size": 1000,
+                "modtime": "2024-01-01T15:04:05Z",
+                "hash": ""
+            }
+        },
+        "path2": { ... },
+        "change1": "modified: size (larger), time (newer)",
+        "change2": "unchanged",
+        "action": "copy Path1 to Path2"
+    }
+
+|change1| and |change2| are one of |new|, |deleted|, |unchanged| or
+|modified:| followed by what changed. |action| describes what bisync
+would do, including how a conflict would be resolved.`)
@@ -0,0 +1,62 @@
+package bisync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisyncLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test as symlinks need privileges on Windows")
+	}
+	ctx := context.Background()
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir1, "target.txt"), []byte("target"), 0666))
+	require.NoError(t, os.Symlink("target.txt", filepath.Join(dir1, "link")))
+
+	call := rc.Calls.Get("sync/bisync")
+	require.NotNil(t, call)
+	_, err := call.Fn(ctx, rc.Params{
+		"path1":   dir1,
+		"path2":   dir2,
+		"resync":  true,
+		"links":   true,
+		"workdir": t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	// The symlink is recreated on Path2
+	target, err := os.Readlink(filepath.Join(dir2, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "target.txt", target)
+	_, err = os.Stat(filepath.Join(dir2, "link.rclonelink"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBisyncLinksFs(t *testing.T) {
+	ctx := context.Background()
+
+	// Local backends translate links
+	f, err := cache.Get(ctx, t.TempDir())
+	require.NoError(t, err)
+	linked, err := linksFs(ctx, f)
+	require.NoError(t, err)
+	assert.NotEqual(t, fs.ConfigString(f), fs.ConfigString(linked))
+	assert.Equal(t, f.Root(), linked.Root())
+
+	// Other backends are left alone
+	f, err = cache.Get(ctx, ":memory:bucket")
+	require.NoError(t, err)
+	linked, err = linksFs(ctx, f)
+	require.NoError(t, err)
+	assert.Equal(t, f, linked)
+}
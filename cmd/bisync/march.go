@@ -147,7 +147,7 @@ func (b *bisyncRun) ForObject(o fs.Object, isPath1 bool) {
 		}
 		marchErrLock.Unlock()
 	}
-	hashVal, hashErr = tryDownloadHash(marchCtx, o, hashVal)
+	hashVal, hashErr = b.tryDownloadHash(marchCtx, o, hashVal)
 	marchErrLock.Lock()
 	if firstErr == nil {
 		firstErr = hashErr
@@ -0,0 +1,148 @@
+package bisync
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/rc"
+)
+
+// pairKey identifies a Path1, Path2 pair
+type pairKey struct {
+	path1 string
+	path2 string
+}
+
+// pairMetrics are the lifetime counters for a Path1, Path2 pair
+type pairMetrics struct {
+	runs         int64
+	transfers    int64
+	deletes      int64
+	renames      int64
+	conflicts    int64
+	errors       int64
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastError    string
+}
+
+// Lifetime counters for each path pair bisynced by this process
+var (
+	metricsMu sync.Mutex
+	metrics   = map[pairKey]*pairMetrics{}
+)
+
+// runMetrics counts what a single bisync run does
+//
+// The counts are read from the stats at the start and end of the run.
+// A move, such as renaming a conflict loser or moving a file into
+// --backup-dir, is counted by the stats as a transfer as well as a
+// rename so it is only counted as a rename here.
+type runMetrics struct {
+	start     time.Time
+	stats     *accounting.StatsInfo
+	transfers int64
+	deletes   int64
+	renames   int64
+}
+
+// startMetrics starts counting a bisync run
+func startMetrics(ctx context.Context) *runMetrics {
+	stats := accounting.Stats(ctx)
+	return &runMetrics{
+		start:     time.Now(),
+		stats:     stats,
+		transfers: stats.GetTransfers(),
+		deletes:   stats.GetDeletes(),
+		renames:   stats.Renames(0),
+	}
+}
+
+// recordMetrics adds the run to the lifetime counters for its path pair
+func (b *bisyncRun) recordMetrics(rm *runMetrics, err error) {
+	if b.opt.DryRun {
+		return
+	}
+	key := pairKey{path1: fs.ConfigString(b.fs1), path2: fs.ConfigString(b.fs2)}
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	m := metrics[key]
+	if m == nil {
+		m = &pairMetrics{}
+		metrics[key] = m
+	}
+	m.runs++
+	renames := rm.stats.Renames(0) - rm.renames
+	m.transfers += rm.stats.GetTransfers() - rm.transfers - renames
+	m.deletes += rm.stats.GetDeletes() - rm.deletes
+	m.renames += renames
+	m.conflicts += int64(b.conflicts)
+	m.lastRun = rm.start
+	m.lastDuration = time.Since(rm.start)
+	m.lastError = ""
+	if err != nil {
+		m.errors++
+		m.lastError = err.Error()
+	}
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "sync/bisync-metrics",
+		AuthRequired: true,
+		Fn:           rcBisyncMetrics,
+		Title:        "Show the lifetime counters for each pair of paths bisynced.",
+		Help:         metricsHelp,
+	})
+}
+
+func rcBisyncMetrics(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	var filter pairKey
+	for _, name := range []string{"path1", "path2"} {
+		if _, err := in.GetString(name); rc.IsErrParamNotFound(err) {
+			continue
+		}
+		f, err := rc.GetFsNamed(ctx, in, name)
+		if err != nil {
+			return nil, err
+		}
+		if name == "path1" {
+			filter.path1 = fs.ConfigString(f)
+		} else {
+			filter.path2 = fs.ConfigString(f)
+		}
+	}
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	pairs := []rc.Params{}
+	for key, m := range metrics {
+		if (filter.path1 != "" && filter.path1 != key.path1) || (filter.path2 != "" && filter.path2 != key.path2) {
+			continue
+		}
+		pairs = append(pairs, rc.Params{
+			"path1":        key.path1,
+			"path2":        key.path2,
+			"runs":         m.runs,
+			"transfers":    m.transfers,
+			"deletes":      m.deletes,
+			"renames":      m.renames,
+			"conflicts":    m.conflicts,
+			"errors":       m.errors,
+			"lastRun":      m.lastRun.UTC().Format(time.RFC3339Nano),
+			"lastDuration": m.lastDuration.Seconds(),
+			"lastError":    m.lastError,
+		})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i]["path1"] != pairs[j]["path1"] {
+			return pairs[i]["path1"].(string) < pairs[j]["path1"].(string)
+		}
+		return pairs[i]["path2"].(string) < pairs[j]["path2"].(string)
+	})
+	return rc.Params{"pairs": pairs}, nil
+}
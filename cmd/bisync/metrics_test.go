@@ -0,0 +1,77 @@
+package bisync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisyncMetrics(t *testing.T) {
+	ctx := context.Background()
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir1, "file0.txt"), []byte("file0"), 0666))
+	require.NoError(t, os.WriteFile(filepath.Join(dir1, "file1.txt"), []byte("file1"), 0666))
+	fs1, err := cache.Get(ctx, dir1)
+	require.NoError(t, err)
+	fs2, err := cache.Get(ctx, dir2)
+	require.NoError(t, err)
+
+	opt := &Options{
+		Workdir: t.TempDir(),
+		Resync:  true,
+	}
+	require.NoError(t, Bisync(ctx, fs1, fs2, opt))
+
+	// Make a change on each side and a conflict
+	require.NoError(t, os.WriteFile(filepath.Join(dir1, "file2.txt"), []byte("file2"), 0666))
+	require.NoError(t, os.Remove(filepath.Join(dir2, "file1.txt")))
+	later := time.Now().Add(time.Minute)
+	for i, dir := range []string{dir1, dir2} {
+		filePath := filepath.Join(dir, "file0.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte(fmt.Sprintf("changed on path%d", i+1)), 0666))
+		require.NoError(t, os.Chtimes(filePath, later, later.Add(time.Duration(i)*time.Second)))
+	}
+	opt.Resync = false
+
+	// The first attempt fails the --max-delete check
+	assert.Error(t, Bisync(ctx, fs1, fs2, opt))
+	opt.Force = true
+	require.NoError(t, Bisync(ctx, fs1, fs2, opt))
+
+	// Dry runs aren't counted
+	opt.DryRun = true
+	require.NoError(t, Bisync(ctx, fs1, fs2, opt))
+
+	call := rc.Calls.Get("sync/bisync-metrics")
+	require.NotNil(t, call)
+	out, err := call.Fn(ctx, rc.Params{"path1": dir1})
+	require.NoError(t, err)
+	pairs := out["pairs"].([]rc.Params)
+	require.Len(t, pairs, 1)
+	pair := pairs[0]
+	assert.Equal(t, fs.ConfigString(fs1), pair["path1"])
+	assert.Equal(t, fs.ConfigString(fs2), pair["path2"])
+	assert.Equal(t, int64(3), pair["runs"])
+	// file0 and file1 by the resync, then file2 and the renamed
+	// copies of file0 from each side
+	assert.Equal(t, int64(5), pair["transfers"])
+	assert.Equal(t, int64(1), pair["deletes"])
+	assert.Equal(t, int64(2), pair["renames"])
+	assert.Equal(t, int64(1), pair["conflicts"])
+	assert.Equal(t, int64(1), pair["errors"])
+	assert.Equal(t, "", pair["lastError"])
+
+	// Filtering on another path finds nothing
+	out, err = call.Fn(ctx, rc.Params{"path2": dir1})
+	require.NoError(t, err)
+	assert.Len(t, out["pairs"], 0)
+}
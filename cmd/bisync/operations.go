@@ -51,6 +51,9 @@ type bisyncRun struct {
 	lockFile           string
 	renames            renames
 	resyncIs1to2       bool
+	conflicts          int            // number of conflicts found
+	conflictTimes      *conflictTimes // when the conflict losers were renamed
+	downloadHash       bool           // compute missing hashes by downloading
 }
 
 type queues struct {
@@ -72,6 +75,8 @@ func Bisync(ctx context.Context, fs1, fs2 fs.Fs, optArg *Options) (err error) {
 		opt:       &opt,
 		DebugName: opt.DebugName,
 	}
+	rm := startMetrics(ctx)
+	defer func() { b.recordMetrics(rm, err) }()
 
 	if opt.CheckFilename == "" {
 		opt.CheckFilename = DefaultCheckFilename
@@ -90,6 +95,7 @@ func Bisync(ctx context.Context, fs1, fs2 fs.Fs, optArg *Options) (err error) {
 	if err != nil {
 		return err
 	}
+	downloadHash = b.downloadHash
 
 	b.setResyncDefaults()
 
@@ -296,6 +302,11 @@ func (b *bisyncRun) runLocked(octx context.Context) (err error) {
 		}
 	}
 
+	// Delete the expired conflict losers from earlier runs
+	if err = b.cleanConflicts(fctx); err != nil {
+		return err
+	}
+
 	fs.Infof(nil, "Building Path1 and Path2 listings")
 	ls1, ls2, err = b.makeMarchListing(fctx)
 	if err != nil || accounting.Stats(fctx).Errored() {
@@ -369,7 +380,15 @@ func (b *bisyncRun) runLocked(octx context.Context) (err error) {
 	} else {
 		fs.Infof(nil, "Applying changes")
 		results2to1, results1to2, queues, err = b.applyDeltas(octx, ds1, ds2)
+		if scriptErr := b.writeRollbackScript(octx, results2to1, results1to2); scriptErr != nil {
+			fs.Errorf(nil, "%v", scriptErr)
+		}
 		if err != nil {
+			if errors.Is(err, errTypeConflict) {
+				// nothing has been changed so no need to --resync
+				b.abort = true
+				return err
+			}
 			if b.InGracefulShutdown && (err == context.Canceled || err == accounting.ErrorMaxTransferLimitReachedGraceful || strings.Contains(err.Error(), "context canceled")) {
 				fs.Infof(nil, "Ignoring sync error due to Graceful Shutdown: %v", err)
 			} else {
@@ -542,13 +561,7 @@ func (b *bisyncRun) handleErr(o any, msg string, err error, critical, retryable
 // setBackupDir overrides --backup-dir with path-specific version, if set, in each direction
 func (b *bisyncRun) setBackupDir(ctx context.Context, destPath int) context.Context {
 	ci := fs.GetConfig(ctx)
-	ci.BackupDir = b.opt.OrigBackupDir
-	if destPath == 1 && b.opt.BackupDir1 != "" {
-		ci.BackupDir = b.opt.BackupDir1
-	}
-	if destPath == 2 && b.opt.BackupDir2 != "" {
-		ci.BackupDir = b.opt.BackupDir2
-	}
+	ci.BackupDir = b.backupDir(destPath)
 	fs.Debugf(ci.BackupDir, "updated backup-dir for Path%d", destPath)
 	return ctx
 }
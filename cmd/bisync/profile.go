@@ -0,0 +1,66 @@
+package bisync
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rclone/rclone/fs/rc"
+)
+
+// profilesFileName is the name of the file in the default working
+// directory holding the profiles for sync/bisync
+const profilesFileName = "profiles.json"
+
+// loadProfile returns the parameters for sync/bisync from in merged
+// with the profile named by the "profile" parameter if it is set.
+//
+// The profiles are read from the "profilesFile" parameter or
+// profiles.json in the working directory. Each profile is a set of
+// sync/bisync parameters and parameters passed in explicitly override
+// the ones in the profile.
+func loadProfile(in rc.Params) (rc.Params, error) {
+	name, err := in.GetString("profile")
+	if rc.IsErrParamNotFound(err) {
+		return in, nil
+	} else if err != nil {
+		return nil, err
+	}
+	profilesFile, err := in.GetString("profilesFile")
+	if rc.IsErrParamNotFound(err) {
+		workdir, err := in.GetString("workdir")
+		if rc.NotErrParamNotFound(err) {
+			return nil, err
+		}
+		if workdir == "" {
+			workdir = DefaultWorkdir
+		}
+		profilesFile = filepath.Join(workdir, profilesFileName)
+	} else if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(profilesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bisync profiles: %w", err)
+	}
+	var profiles map[string]rc.Params
+	err = json.Unmarshal(data, &profiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bisync profiles %q: %w", profilesFile, err)
+	}
+	profile, found := profiles[name]
+	if !found {
+		return nil, rc.NewErrParamInvalid(fmt.Errorf("bisync profile %q not found in %q", name, profilesFile))
+	}
+	out := rc.Params{}
+	for key, value := range profile {
+		out[key] = value
+	}
+	for key, value := range in {
+		out[key] = value
+	}
+	delete(out, "profile")
+	delete(out, "profilesFile")
+	return out, nil
+}
@@ -0,0 +1,57 @@
+package bisync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisyncLoadProfile(t *testing.T) {
+	workdir := t.TempDir()
+	profilesFile := filepath.Join(workdir, profilesFileName)
+	require.NoError(t, os.WriteFile(profilesFile, []byte(`{
+	"photos": {
+		"path1": "drive:photos",
+		"path2": "/home/user/photos",
+		"maxDelete": 10,
+		"dryRun": false
+	}
+}`), 0666))
+
+	// No profile leaves the parameters alone
+	in := rc.Params{"path1": "a", "path2": "b"}
+	out, err := loadProfile(in)
+	require.NoError(t, err)
+	assert.Equal(t, in, out)
+
+	// Explicit parameters override the profile
+	out, err = loadProfile(rc.Params{"profile": "photos", "workdir": workdir, "dryRun": true})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"path1":     "drive:photos",
+		"path2":     "/home/user/photos",
+		"maxDelete": float64(10),
+		"dryRun":    true,
+		"workdir":   workdir,
+	}, out)
+	maxDelete, err := out.GetInt64("maxDelete")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), maxDelete)
+
+	// The profiles can be read from another file
+	otherFile := filepath.Join(t.TempDir(), "other.json")
+	require.NoError(t, os.Rename(profilesFile, otherFile))
+	out, err = loadProfile(rc.Params{"profile": "photos", "profilesFile": otherFile})
+	require.NoError(t, err)
+	assert.Equal(t, "drive:photos", out["path1"])
+
+	// Missing profiles are an error
+	_, err = loadProfile(rc.Params{"profile": "music", "profilesFile": otherFile})
+	assert.True(t, rc.IsErrParamInvalid(err))
+	_, err = loadProfile(rc.Params{"profile": "photos", "workdir": workdir})
+	assert.Error(t, err)
+}
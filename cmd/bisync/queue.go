@@ -106,7 +106,7 @@ func altName(name string, src, dst fs.DirEntry) string {
 }
 
 // WriteResults is Bisync's LoggerFn
-func WriteResults(ctx context.Context, sigil operations.Sigil, src, dst fs.DirEntry, err error) {
+func (b *bisyncRun) WriteResults(ctx context.Context, sigil operations.Sigil, src, dst fs.DirEntry, err error) {
 	lock.Lock()
 	defer lock.Unlock()
 
@@ -138,7 +138,7 @@ func WriteResults(ctx context.Context, sigil operations.Sigil, src, dst fs.DirEn
 				sideObj, ok := side.(fs.ObjectInfo)
 				if ok {
 					result.Hash, _ = sideObj.Hash(ctx, getHashType(sideObj.Fs().Name()))
-					result.Hash, _ = tryDownloadHash(ctx, sideObj, result.Hash)
+					result.Hash, _ = b.tryDownloadHash(ctx, sideObj, result.Hash)
 				}
 
 			}
@@ -196,7 +196,7 @@ func (b *bisyncRun) preCopy(ctx context.Context) context.Context {
 		b.fs1.Name(): b.opt.Compare.HashType1,
 		b.fs2.Name(): b.opt.Compare.HashType2,
 	}
-	logger.LoggerFn = WriteResults
+	logger.LoggerFn = b.WriteResults
 	overridingEqual := false
 	if (b.opt.Compare.Modtime && b.opt.Compare.Checksum) || b.opt.Compare.DownloadHash {
 		overridingEqual = true
@@ -232,8 +232,23 @@ func (b *bisyncRun) fastCopy(ctx context.Context, fsrc, fdst fs.Fs, files bilib.
 		return nil, err
 	}
 
+	// Copy only the first name of files with --hard-links and link
+	// the others to it afterwards
+	var links hardLinks
+	linker := b.hardLinkerFor(fsrc, fdst)
+	if linker != nil {
+		var err error
+		links, err = queuedHardLinks(ctx, fsrc, files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find hard links: %w", err)
+		}
+	}
+
 	ctxCopy, filterCopy := filter.AddConfig(b.opt.setDryRun(ctx))
 	for _, file := range files.ToList() {
+		if _, found := links[file]; found {
+			continue
+		}
 		if err := filterCopy.AddFile(file); err != nil {
 			return nil, err
 		}
@@ -249,7 +264,13 @@ func (b *bisyncRun) fastCopy(ctx context.Context, fsrc, fdst fs.Fs, files bilib.
 	accounting.MaxCompletedTransfers = -1 // we need a complete list in the event of graceful shutdown
 	ctxCopy, b.CancelSync = context.WithCancel(ctxCopy)
 	b.testFn()
-	err := sync.Sync(ctxCopy, fdst, fsrc, b.opt.CreateEmptySrcDirs)
+	err := sync.Sync(ctxCopy, fdst, b.readThroughVFSCache(ctxCopy, fsrc), b.opt.CreateEmptySrcDirs)
+	if len(links) > 0 && !b.InGracefulShutdown {
+		linkErr := b.makeHardLinks(ctxCopy, linker, fsrc, fdst, links, true)
+		if err == nil {
+			err = linkErr
+		}
+	}
 	prettyprint(logger, "logger", fs.LogLevelDebug)
 
 	getResults := ReadResults(logger.JSON)
@@ -291,7 +312,18 @@ func (b *bisyncRun) retryFastCopy(ctx context.Context, fsrc, fdst fs.Fs, files b
 func (b *bisyncRun) resyncDir(ctx context.Context, fsrc, fdst fs.Fs) ([]Results, error) {
 	ctx = b.preCopy(ctx)
 
-	err := sync.CopyDir(ctx, fdst, fsrc, b.opt.CreateEmptySrcDirs)
+	err := sync.CopyDir(ctx, fdst, b.readThroughVFSCache(ctx, fsrc), b.opt.CreateEmptySrcDirs)
+
+	// Link the files copied separately which are hard links on fsrc
+	// with --hard-links. They were copied with the same contents so
+	// the results don't need changing.
+	if linker := b.hardLinkerFor(fsrc, fdst); linker != nil && err == nil {
+		var links hardLinks
+		links, err = allHardLinks(ctx, fsrc)
+		if err == nil {
+			err = b.makeHardLinks(ctx, linker, fsrc, fdst, links, false)
+		}
+	}
 	prettyprint(logger, "logger", fs.LogLevelDebug)
 
 	getResults := ReadResults(logger.JSON)
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"log"
+	"strings"
 
 	"github.com/rclone/rclone/cmd/bisync/bilib"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/rc"
 )
 
@@ -21,6 +24,11 @@ func init() {
 }
 
 func rcBisync(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	in, err = loadProfile(in)
+	if err != nil {
+		return nil, err
+	}
+
 	opt := &Options{}
 	octx, ci := fs.AddConfig(ctx)
 
@@ -80,6 +88,31 @@ func rcBisync(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	if opt.BackupDir2, err = in.GetString("backupdir2"); rc.NotErrParamNotFound(err) {
 		return
 	}
+	if opt.RollbackScript, err = in.GetString("rollbackScript"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	if opt.VFSCacheDir, err = in.GetString("vfsCacheDir"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	if opt.ClockSkewTolerance, err = in.GetFsDuration("clockSkewTolerance"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	if opt.ConflictRetention, err = in.GetFsDuration("conflictRetention"); rc.NotErrParamNotFound(err) {
+		return
+	}
+	if opt.HardLinks, err = in.GetBool("hardLinks"); rc.NotErrParamNotFound(err) {
+		return
+	}
+
+	typeConflict, err := in.GetString("typeConflict")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	if typeConflict != "" {
+		if err := opt.TypeConflict.Set(typeConflict); err != nil {
+			return nil, rc.NewErrParamInvalid(err)
+		}
+	}
 
 	checkSync, err := in.GetString("checkSync")
 	if rc.NotErrParamNotFound(err) {
@@ -102,9 +135,50 @@ func rcBisync(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 		return nil, err
 	}
 
+	links, err := in.GetBool("links")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	if links {
+		ci.Links = true
+		if fs1, err = linksFs(octx, fs1); err != nil {
+			return nil, err
+		}
+		if fs2, err = linksFs(octx, fs2); err != nil {
+			return nil, err
+		}
+	}
+
 	output := bilib.CaptureOutput(func() {
 		err = Bisync(octx, fs1, fs2, opt)
 	})
 	_, _ = log.Writer().Write(output)
 	return rc.Params{"output": string(output)}, err
 }
+
+// linksFs returns f opened so that symlinks are translated to and
+// from files with the .rclonelink suffix, as the VFS does with
+// --vfs-links, so symlinks synced by bisync look the same as ones
+// made through a mount.
+//
+// Backends which don't have a links option, which is all but local,
+// store .rclonelink files as normal files so f is returned unchanged.
+func linksFs(ctx context.Context, f fs.Fs) (fs.Fs, error) {
+	remote := fs.ConfigStringFull(f)
+	fsInfo, _, _, _, err := fs.ParseRemote(remote)
+	if err != nil {
+		return nil, err
+	}
+	if fsInfo.Options.Get("links") == nil {
+		return f, nil
+	}
+	parsed, err := fspath.Parse(remote)
+	if err != nil {
+		return nil, err
+	}
+	configString := strings.TrimSuffix(parsed.ConfigString, ":")
+	if configString == "" {
+		configString = ":" + fsInfo.Name
+	}
+	return cache.Get(ctx, configString+",links=true:"+parsed.Path)
+}
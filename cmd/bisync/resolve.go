@@ -312,6 +312,9 @@ func (b *bisyncRun) rename(ctx context.Context, thisNamePair namePair, thisPath,
 				b.critical = true
 				return err
 			}
+			if err = b.conflictTimes.add(thisNamePair.newName); err != nil {
+				fs.Errorf(thisNamePair.newName, "Failed to record when the conflict loser was renamed for --conflict-retention: %v", err)
+			}
 		} else {
 			renameSkipped.Add(thisNamePair.oldName) // (due to dry-run, not equality)
 		}
@@ -0,0 +1,116 @@
+package bisync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/cmd/bisync/bilib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// shellQuote quotes s for use as a single argument in a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// backupDir returns the --backup-dir in use for destPath, or "" if none
+func (b *bisyncRun) backupDir(destPath int) string {
+	if destPath == 1 && b.opt.BackupDir1 != "" {
+		return b.opt.BackupDir1
+	}
+	if destPath == 2 && b.opt.BackupDir2 != "" {
+		return b.opt.BackupDir2
+	}
+	return b.opt.OrigBackupDir
+}
+
+// rollbackCommands returns the commands needed to undo the changes
+// recorded in results, which were made to dst (Path1 if destPath is 1,
+// otherwise Path2).
+//
+// The commands are returned in the reverse order to that the changes
+// were made. Changes which can't be undone are returned as comments.
+func (b *bisyncRun) rollbackCommands(ctx context.Context, results []Results, dst fs.Fs, destPath int) (cmds []string) {
+	dstPath := bilib.FsPath(dst)
+	backupDir := b.backupDir(destPath)
+	backupPath := func(name string) string {
+		return strings.TrimRight(backupDir, "/") + "/" + operations.SuffixName(ctx, name)
+	}
+	for _, result := range slices.Backward(results) {
+		if !result.IsDst || result.Err != nil || result.Name == "" {
+			continue
+		}
+		target := shellQuote(dstPath + result.Name)
+		isDir := result.Flags == "d"
+		var cmd string
+		switch result.Sigil {
+		case operations.MissingOnDst:
+			// created on dst
+			if isDir {
+				cmd = "rclone rmdir " + target
+			} else {
+				cmd = "rclone deletefile " + target
+			}
+		case operations.Differ:
+			// overwritten on dst
+			if backupDir == "" {
+				cmd = fmt.Sprintf("# CANNOT ROLLBACK: %q on Path%d was overwritten without a backup dir", result.Name, destPath)
+			} else {
+				cmd = "rclone moveto " + shellQuote(backupPath(result.Name)) + " " + target
+			}
+		case operations.MissingOnSrc:
+			// deleted from dst
+			switch {
+			case isDir:
+				cmd = "rclone mkdir " + target
+			case backupDir == "":
+				cmd = fmt.Sprintf("# CANNOT ROLLBACK: %q on Path%d was deleted without a backup dir", result.Name, destPath)
+			default:
+				cmd = "rclone moveto " + shellQuote(backupPath(result.Name)) + " " + target
+			}
+		default:
+			continue
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// writeRollbackScript writes a shell script to --rollback-script which
+// undoes the transfers and deletes made by this run.
+func (b *bisyncRun) writeRollbackScript(ctx context.Context, results2to1, results1to2 []Results) error {
+	if b.opt.RollbackScript == "" {
+		return nil
+	}
+	if b.opt.DryRun {
+		fs.Infof(nil, "Not writing rollback script as --dry-run is set")
+		return nil
+	}
+	var out strings.Builder
+	out.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&out, "# Rollback script for bisync of %s and %s\n", quotePath(bilib.FsPath(b.fs1)), quotePath(bilib.FsPath(b.fs2)))
+	fmt.Fprintf(&out, "# made at %s\n", time.Now().Format(time.RFC3339))
+	out.WriteString("#\n")
+	out.WriteString("# Files which were overwritten or deleted can only be restored if a\n")
+	out.WriteString("# backup dir was in use. Conflict renames are not undone. Run a\n")
+	out.WriteString("# --resync after running this script. Review it before running!\n")
+	out.WriteString("set -e\n")
+	// results1to2 were applied after results2to1 so undo them first
+	for _, cmd := range b.rollbackCommands(ctx, results1to2, b.fs2, 2) {
+		out.WriteString(cmd + "\n")
+	}
+	for _, cmd := range b.rollbackCommands(ctx, results2to1, b.fs1, 1) {
+		out.WriteString(cmd + "\n")
+	}
+	err := os.WriteFile(b.opt.RollbackScript, []byte(out.String()), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to write rollback script: %w", err)
+	}
+	fs.Infof(nil, "Wrote rollback script to %s", quotePath(b.opt.RollbackScript))
+	return nil
+}
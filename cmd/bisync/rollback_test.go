@@ -0,0 +1,45 @@
+package bisync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackCommands(t *testing.T) {
+	ctx := context.Background()
+	f, err := mockfs.NewFs(ctx, "path1", "dir", nil)
+	require.NoError(t, err)
+
+	results := []Results{
+		{Name: "new.txt", Sigil: operations.MissingOnDst, IsSrc: true},
+		{Name: "new.txt", Sigil: operations.MissingOnDst, IsDst: true},
+		{Name: "changed.txt", Sigil: operations.Differ, IsDst: true},
+		{Name: "deleted.txt", Sigil: operations.MissingOnSrc, IsDst: true},
+		{Name: "same.txt", Sigil: operations.Match, IsDst: true},
+		{Name: "newdir", Sigil: operations.MissingOnDst, IsDst: true, Flags: "d"},
+		{Name: "it's.txt", Sigil: operations.MissingOnDst, IsDst: true},
+	}
+
+	b := &bisyncRun{opt: &Options{}}
+	assert.Equal(t, []string{
+		`rclone deletefile 'path1:dir/it'\''s.txt'`,
+		`rclone rmdir 'path1:dir/newdir'`,
+		`# CANNOT ROLLBACK: "deleted.txt" on Path1 was deleted without a backup dir`,
+		`# CANNOT ROLLBACK: "changed.txt" on Path1 was overwritten without a backup dir`,
+		`rclone deletefile 'path1:dir/new.txt'`,
+	}, b.rollbackCommands(ctx, results, f, 1))
+
+	b = &bisyncRun{opt: &Options{BackupDir1: "path1:backup/"}}
+	assert.Equal(t, []string{
+		`rclone deletefile 'path1:dir/it'\''s.txt'`,
+		`rclone rmdir 'path1:dir/newdir'`,
+		`rclone moveto 'path1:backup/deleted.txt' 'path1:dir/deleted.txt'`,
+		`rclone moveto 'path1:backup/changed.txt' 'path1:dir/changed.txt'`,
+		`rclone deletefile 'path1:dir/new.txt'`,
+	}, b.rollbackCommands(ctx, results, f, 1))
+}
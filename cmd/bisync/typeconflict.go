@@ -0,0 +1,210 @@
+package bisync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/rclone/rclone/cmd/bisync/bilib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/terminal"
+)
+
+// TypeConflict describes strategies for resolving conflicts where a
+// path is a file on one side and a directory on the other
+type TypeConflict = fs.Enum[typeConflictChoices]
+
+// Supported --type-conflict strategies
+const (
+	TypeConflictRename      TypeConflict = iota // rename the file with a conflict suffix
+	TypeConflictPreferPath1                     // keep whatever is on Path1
+	TypeConflictPreferPath2                     // keep whatever is on Path2
+	TypeConflictSkip                            // leave the path alone on both sides
+	TypeConflictError                           // abort the run with an error
+)
+
+type typeConflictChoices struct{}
+
+func (typeConflictChoices) Choices() []string {
+	return []string{
+		TypeConflictRename:      "rename",
+		TypeConflictPreferPath1: "preferPath1",
+		TypeConflictPreferPath2: "preferPath2",
+		TypeConflictSkip:        "skip",
+		TypeConflictError:       "error",
+	}
+}
+
+func (typeConflictChoices) Type() string {
+	return "TypeConflict"
+}
+
+// errTypeConflict is returned if --type-conflict is error and a type conflict is found
+var errTypeConflict = errors.New("type conflict")
+
+// TypeConflictList is a list of --type-conflict flag choices used in the help
+var TypeConflictList = Opt.TypeConflict.Help()
+
+// dirSet returns the directories in ls, including the parents of
+// every entry in it
+func dirSet(ls *fileList) map[string]struct{} {
+	dirs := map[string]struct{}{}
+	for _, name := range ls.list {
+		if ls.get(name).flags == "d" {
+			dirs[name] = struct{}{}
+		}
+		for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			dirs[dir] = struct{}{}
+		}
+	}
+	return dirs
+}
+
+// changedSet returns the entries changed in ds along with all their
+// parent directories
+func changedSet(ds *deltaSet) map[string]struct{} {
+	changed := map[string]struct{}{}
+	for name := range ds.deltas {
+		changed[name] = struct{}{}
+		for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			changed[dir] = struct{}{}
+		}
+	}
+	return changed
+}
+
+// findTypeConflicts returns the paths which are a file on one side
+// and a directory on the other where the path or something inside it
+// has changed on both sides. The value is the number of the path
+// which has the file.
+func findTypeConflicts(ds1, ds2 *deltaSet, ls1, ls2 *fileList) map[string]int {
+	conflicts := map[string]int{}
+	dirs1, dirs2 := dirSet(ls1), dirSet(ls2)
+	changed2 := changedSet(ds2)
+	isFile := func(ls *fileList, name string) bool {
+		return ls.has(name) && ls.get(name).flags != "d"
+	}
+	for name := range changedSet(ds1) {
+		if _, ok := changed2[name]; !ok {
+			continue
+		}
+		_, isDir1 := dirs1[name]
+		_, isDir2 := dirs2[name]
+		if isFile(ls1, name) && isDir2 {
+			conflicts[name] = 1
+		} else if isFile(ls2, name) && isDir1 {
+			conflicts[name] = 2
+		}
+	}
+	return conflicts
+}
+
+// inside returns the names in list which are inside the directory dir
+func inside(list []string, dir string) (names []string) {
+	prefix := dir + "/"
+	for _, name := range list {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// resolveTypeConflicts resolves the conflicts found by
+// findTypeConflicts according to --type-conflict.
+//
+// The names it deals with completely are added to handled so they
+// aren't processed any further.
+func (b *bisyncRun) resolveTypeConflicts(ctxMove context.Context, conflicts map[string]int, ds1, ds2 *deltaSet, copy1to2, copy2to1, handled, renameSkipped *bilib.Names) (err error) {
+	names := make([]string, 0, len(conflicts))
+	for name := range conflicts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	paths := [3]string{"", bilib.FsPath(b.fs1), bilib.FsPath(b.fs2)}
+	fses := [3]fs.Fs{nil, b.fs1, b.fs2}
+	lists := [3]*fileList{nil, ls1, ls2}
+	deltas := [3]*deltaSet{nil, ds1, ds2}
+	queues := [3]*bilib.Names{nil, copy2to1, copy1to2} // queues[n] copies to Path n
+	handleAll := func(name string) {
+		handled.Add(name)
+		for _, ds := range deltas[1:] {
+			for _, inner := range inside(ds.sort(), name) {
+				handled.Add(inner)
+			}
+		}
+	}
+
+	for _, name := range names {
+		filePath := conflicts[name]
+		dirPath := 3 - filePath
+		b.conflicts++
+		b.indent("!WARNING", name, fmt.Sprintf("File on Path%d but directory on Path%d", filePath, dirPath))
+		switch b.opt.TypeConflict {
+		case TypeConflictError:
+			return fmt.Errorf("%w: %q is a file on Path%d but a directory on Path%d (see --type-conflict)", errTypeConflict, name, filePath, dirPath)
+		case TypeConflictSkip:
+			fs.Logf(name, Color(terminal.YellowFg, "Skipping as it is a file on Path%d but a directory on Path%d"), filePath, dirPath)
+			handleAll(name)
+		case TypeConflictRename:
+			// Move the file out of the way and let the directory sync as normal
+			suffix := b.opt.ConflictSuffix1
+			if filePath == 2 {
+				suffix = b.opt.ConflictSuffix2
+			}
+			num := b.numerate(ctxMove, 1, name, name)
+			r := namePair{oldName: name, newName: SuffixName(ctxMove, name, suffix+fmt.Sprint(num))}
+			err = b.rename(ctxMove, r, paths[filePath], paths[dirPath], fses[filePath], filePath, dirPath, 0, queues[dirPath], renameSkipped)
+			if err != nil {
+				return err
+			}
+			handled.Add(name)
+		case TypeConflictPreferPath1, TypeConflictPreferPath2:
+			winner := 1
+			if b.opt.TypeConflict == TypeConflictPreferPath2 {
+				winner = 2
+			}
+			loser := 3 - winner
+			fs.Infof(name, Color(terminal.GreenFg, "The winner is: Path%d"), winner)
+			if winner == filePath {
+				// Remove the directory from the loser then copy the file over
+				for _, inner := range inside(lists[loser].list, name) {
+					if lists[loser].get(inner).flags == "d" {
+						continue
+					}
+					err = b.delete(ctxMove, namePair{oldName: inner}, paths[loser], paths[winner], fses[loser], loser, winner, renameSkipped)
+					if err != nil {
+						return err
+					}
+					queues[loser].Add(inner)
+				}
+				if !operations.SkipDestructive(ctxMove, name, "remove directory") {
+					err = operations.Rmdirs(ctxMove, fses[loser], name, false)
+					if err != nil {
+						b.critical = true
+						return fmt.Errorf("%s failed to remove directory %s: %w", paths[loser], paths[loser]+name, err)
+					}
+				}
+			} else {
+				// Remove the file from the loser then copy the directory over
+				err = b.delete(ctxMove, namePair{oldName: name}, paths[loser], paths[winner], fses[loser], loser, winner, renameSkipped)
+				if err != nil {
+					return err
+				}
+				for _, inner := range inside(lists[winner].list, name) {
+					b.indent(fmt.Sprintf("Path%d", winner), paths[loser]+inner, fmt.Sprintf("Queue copy to Path%d", loser))
+					queues[loser].Add(inner)
+				}
+			}
+			b.indent(fmt.Sprintf("Path%d", winner), paths[loser]+name, fmt.Sprintf("Queue copy to Path%d", loser))
+			queues[loser].Add(name)
+			handleAll(name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package bisync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindTypeConflicts(t *testing.T) {
+	now := time.Now()
+	ls1 := newFileList()
+	ls1.put("a", 1, now, "", "", "-")
+	ls1.put("b", 0, now, "", "", "d")
+	ls1.put("b/file", 1, now, "", "", "-")
+	ls1.put("same", 1, now, "", "", "-")
+	ls1.put("quiet", 1, now, "", "", "-")
+	ls2 := newFileList()
+	ls2.put("a", 0, now, "", "", "d")
+	ls2.put("a/file", 1, now, "", "", "-")
+	ls2.put("b", 1, now, "", "", "-")
+	ls2.put("same", 1, now, "", "", "-")
+	ls2.put("quiet/file", 1, now, "", "", "-")
+
+	ds1 := &deltaSet{deltas: map[string]delta{
+		"a":      deltaNew,
+		"b/file": deltaNew,
+		"same":   deltaModified,
+	}}
+	ds2 := &deltaSet{deltas: map[string]delta{
+		"a/file":     deltaNew,
+		"b":          deltaNew,
+		"same":       deltaModified,
+		"quiet/file": deltaNew,
+	}}
+
+	assert.Equal(t, map[string]int{
+		"a": 1,
+		"b": 2,
+	}, findTypeConflicts(ds1, ds2, ls1, ls2))
+}
+
+func TestBisyncTypeConflict(t *testing.T) {
+	ctx := context.Background()
+	call := rc.Calls.Get("sync/bisync")
+	require.NotNil(t, call)
+
+	// files returns the names of the files under dir
+	files := func(dir string) (names []string) {
+		err := filepath.Walk(dir, func(osPath string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				name, _ := filepath.Rel(dir, osPath)
+				names = append(names, filepath.ToSlash(name))
+			}
+			return err
+		})
+		require.NoError(t, err)
+		sort.Strings(names)
+		return names
+	}
+
+	for _, test := range []struct {
+		typeConflict string
+		wantErr      bool
+		want         []string
+	}{
+		{"", false, []string{"other.txt", "x.conflict1", "x/file.txt"}},
+		{"preferPath1", false, []string{"other.txt", "x"}},
+		{"preferPath2", false, []string{"other.txt", "x/file.txt"}},
+		{"error", true, nil},
+	} {
+		t.Run(test.typeConflict, func(t *testing.T) {
+			dir1, dir2, workdir := t.TempDir(), t.TempDir(), t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir1, "other.txt"), []byte("other"), 0666))
+			bisync := func(resync bool) error {
+				params := rc.Params{
+					"path1":   dir1,
+					"path2":   dir2,
+					"resync":  resync,
+					"workdir": workdir,
+				}
+				if test.typeConflict != "" {
+					params["typeConflict"] = test.typeConflict
+				}
+				_, err := call.Fn(ctx, params)
+				return err
+			}
+			require.NoError(t, bisync(true))
+
+			// x is made a file on Path1 and a directory on Path2
+			require.NoError(t, os.WriteFile(filepath.Join(dir1, "x"), []byte("file"), 0666))
+			require.NoError(t, os.Mkdir(filepath.Join(dir2, "x"), 0777))
+			require.NoError(t, os.WriteFile(filepath.Join(dir2, "x", "file.txt"), []byte("dir"), 0666))
+			err := bisync(false)
+			if test.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, []string{"other.txt", "x"}, files(dir1))
+				assert.Equal(t, []string{"other.txt", "x/file.txt"}, files(dir2))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, files(dir1))
+			assert.Equal(t, test.want, files(dir2))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package bisync
+
+import (
+	"context"
+	"io"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscache"
+)
+
+// vfsCacheFs wraps an fs.Fs so files read from it are read from the
+// VFS cache if there is an up to date copy there
+type vfsCacheFs struct {
+	fs.Fs
+	features *fs.Features
+	cacheDir string // the --cache-dir of the VFS
+}
+
+// readThroughVFSCache returns f wrapped so that files read from it are
+// read from the VFS cache where possible, or f if --vfs-cache-dir
+// isn't set
+func (b *bisyncRun) readThroughVFSCache(ctx context.Context, f fs.Fs) fs.Fs {
+	if b.opt.VFSCacheDir == "" {
+		return f
+	}
+	cf := &vfsCacheFs{Fs: f, cacheDir: b.opt.VFSCacheDir}
+	cf.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          true,
+		ReadMimeType:            true,
+		ReadMetadata:            true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		BucketBasedRootOK:       true,
+		SlowModTime:             true,
+		SlowHash:                true,
+	}).Fill(ctx, cf).Mask(ctx, f).WrapsFs(cf, f)
+	return cf
+}
+
+// Features returns the optional features of this Fs
+func (f *vfsCacheFs) Features() *fs.Features {
+	return f.features
+}
+
+// List the objects and directories in dir into entries
+func (f *vfsCacheFs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			entries[i] = readThroughVFSCacheObject(o, f.cacheDir)
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote
+func (f *vfsCacheFs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return readThroughVFSCacheObject(o, f.cacheDir), nil
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *vfsCacheFs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// vfsCacheObject is an object read from the VFS cache where possible
+type vfsCacheObject struct {
+	fs.Object
+	cacheDir string // the --cache-dir of the VFS
+}
+
+// readThroughVFSCacheObject returns o wrapped so that it is read from
+// the VFS cache in cacheDir where possible, or o if cacheDir is empty
+func readThroughVFSCacheObject(o fs.Object, cacheDir string) fs.Object {
+	if cacheDir == "" {
+		return o
+	}
+	if _, ok := o.(*vfsCacheObject); ok {
+		return o
+	}
+	return &vfsCacheObject{Object: o, cacheDir: cacheDir}
+}
+
+// Open the cached copy of the object if it is up to date, otherwise
+// open the object on the remote
+func (o *vfsCacheObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	in, err := vfscache.OpenCached(ctx, o.cacheDir, o.Object, options...)
+	if err != nil {
+		fs.Debugf(o, "Failed to read from the VFS cache: %v", err)
+	}
+	if in != nil && err == nil {
+		return in, nil
+	}
+	return o.Object.Open(ctx, options...)
+}
+
+// MimeType returns the content type of the Object if known
+func (o *vfsCacheObject) MimeType(ctx context.Context) string {
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// Metadata returns metadata for an object
+func (o *vfsCacheObject) Metadata(ctx context.Context) (fs.Metadata, error) {
+	return fs.GetMetadata(ctx, o.Object)
+}
+
+// UnWrap returns the wrapped Object
+func (o *vfsCacheObject) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*vfsCacheFs)(nil)
+	_ fs.UnWrapper       = (*vfsCacheFs)(nil)
+	_ fs.Object          = (*vfsCacheObject)(nil)
+	_ fs.MimeTyper       = (*vfsCacheObject)(nil)
+	_ fs.Metadataer      = (*vfsCacheObject)(nil)
+	_ fs.ObjectUnWrapper = (*vfsCacheObject)(nil)
+)
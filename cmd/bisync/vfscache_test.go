@@ -0,0 +1,69 @@
+package bisync
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadThroughVFSCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("original"), 0666))
+	f, err := cache.Get(ctx, dir)
+	require.NoError(t, err)
+
+	// Read the file into the VFS cache
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.FastFingerprint = true
+	opt.WriteBack = 0
+	v := vfs.New(f, &opt)
+	defer func() {
+		require.NoError(t, v.CleanUp())
+		v.Shutdown()
+	}()
+	data, err := v.ReadFile("file.txt")
+	require.NoError(t, err)
+	require.Equal(t, "original", string(data))
+
+	// Change the file without changing its fingerprint so we can
+	// tell whether it was read from the cache
+	fi, err := os.Stat(filePath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filePath, []byte("modified"), 0666))
+	require.NoError(t, os.Chtimes(filePath, time.Now(), fi.ModTime()))
+
+	read := func(f fs.Fs) string {
+		entries, err := f.List(ctx, "")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		in, err := entries[0].(fs.Object).Open(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return string(data)
+	}
+
+	// Each run reads from its own VFS cache directory
+	noCache := &bisyncRun{opt: &Options{}}
+	withCache := &bisyncRun{opt: &Options{VFSCacheDir: config.GetCacheDir()}}
+	fNoCache := noCache.readThroughVFSCache(ctx, f)
+	fWithCache := withCache.readThroughVFSCache(ctx, f)
+	assert.Equal(t, "modified", read(fNoCache))
+	assert.Equal(t, "original", read(fWithCache))
+	assert.Equal(t, "modified", read(fNoCache))
+}
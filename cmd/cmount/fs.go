@@ -4,8 +4,10 @@ package cmount
 
 import (
 	"io"
+	"maps"
 	"os"
 	"path"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -139,12 +141,20 @@ func (fsys *FS) getNode(path string, fh uint64) (node vfs.Node, handle vfs.Handl
 // stat fills up the stat block for Node
 func (fsys *FS) stat(node vfs.Node, stat *fuse.Stat_t) (errc int) {
 	Size := uint64(node.Size())
-	Blocks := (Size + 511) / 512
+	DiskSize := Size
+	Nlink := uint32(1)
 	modTime := node.ModTime()
+	birthTime := modTime
+	if f, ok := node.(*vfs.File); ok {
+		DiskSize = uint64(f.DiskSize())
+		Nlink = f.LinkCount()
+		birthTime = f.BirthTime()
+	}
+	Blocks := (DiskSize + 511) / 512
 	//stat.Dev = 1
 	stat.Ino = node.Inode() // FIXME do we need to set the inode number?
 	stat.Mode = getMode(node)
-	stat.Nlink = 1
+	stat.Nlink = Nlink
 	stat.Uid = fsys.VFS.Opt.UID
 	stat.Gid = fsys.VFS.Opt.GID
 	//stat.Rdev
@@ -155,7 +165,7 @@ func (fsys *FS) stat(node vfs.Node, stat *fuse.Stat_t) (errc int) {
 	stat.Ctim = t
 	stat.Blksize = 512
 	stat.Blocks = int64(Blocks)
-	stat.Birthtim = t
+	stat.Birthtim = fuse.NewTimespec(birthTime)
 	// fs.Debugf(nil, "stat = %+v", *stat)
 	return 0
 }
@@ -538,7 +548,15 @@ func (fsys *FS) Setxattr(path string, name string, value []byte, flags int) (err
 // Getxattr gets extended attributes.
 func (fsys *FS) Getxattr(path string, name string) (errc int, value []byte) {
 	defer log.Trace(path, "name=%q", name)("errc=%d, value=%q", &errc, &value)
-	return -fuse.ENOSYS, nil
+	xattrs, errc := fsys.xattrs(path)
+	if errc != 0 {
+		return errc, nil
+	}
+	s, found := xattrs[name]
+	if !found {
+		return -fuse.ENOATTR, nil
+	}
+	return 0, []byte(s)
 }
 
 // Removexattr removes extended attributes.
@@ -550,7 +568,33 @@ func (fsys *FS) Removexattr(path string, name string) (errc int) {
 // Listxattr lists extended attributes.
 func (fsys *FS) Listxattr(path string, fill func(name string) bool) (errc int) {
 	defer log.Trace(path, "fill=%p", fill)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	xattrs, errc := fsys.xattrs(path)
+	if errc != 0 {
+		return errc
+	}
+	for _, name := range slices.Sorted(maps.Keys(xattrs)) {
+		if !fill(name) {
+			return -fuse.ERANGE
+		}
+	}
+	return 0
+}
+
+// xattrs returns the extended attributes of the file at path
+//
+// It returns ENOSYS if the node doesn't have any.
+func (fsys *FS) xattrs(path string) (xattrs map[string]string, errc int) {
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return nil, errc
+	}
+	if file, ok := node.(*vfs.File); ok {
+		xattrs = file.Xattrs()
+	}
+	if xattrs == nil {
+		return nil, -fuse.ENOSYS // only implemented for --vfs-cache-xattrs
+	}
+	return xattrs, 0
 }
 
 // Getpath allows a case-insensitive file system to report the correct case of
@@ -599,6 +643,18 @@ func translateError(err error) (errc int) {
 		return -fuse.EINVAL
 	case vfs.ELOOP:
 		return -fuse.ELOOP
+	case vfs.ENOSPC:
+		return -fuse.ENOSPC
+	case vfs.EBUSY:
+		return -fuse.EBUSY
+	case vfs.EAGAIN:
+		return -fuse.EAGAIN
+	case vfs.ETIMEDOUT:
+		return -fuse.ETIMEDOUT
+	case vfs.EACCES:
+		return -fuse.EACCES
+	case vfs.EIO:
+		return -fuse.EIO
 	}
 	fs.Errorf(nil, "IO error: %v", err)
 	return -fuse.EIO
@@ -4,7 +4,9 @@ package mount
 
 import (
 	"context"
+	"maps"
 	"os"
+	"slices"
 	"syscall"
 	"time"
 
@@ -29,11 +31,12 @@ func (f *File) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 	a.Valid = time.Duration(f.fsys.opt.AttrTimeout)
 	modTime := f.File.ModTime()
 	Size := uint64(f.File.Size())
-	Blocks := (Size + 511) / 512
+	Blocks := (uint64(f.File.DiskSize()) + 511) / 512
 	a.Gid = f.VFS().Opt.GID
 	a.Uid = f.VFS().Opt.UID
 	a.Mode = f.File.Mode() &^ os.ModeAppend
 	a.Size = Size
+	a.Nlink = f.File.LinkCount()
 	a.Atime = modTime
 	a.Mtime = modTime
 	a.Ctime = modTime
@@ -101,14 +104,28 @@ func (f *File) Fsync(ctx context.Context, req *fuse.FsyncRequest) (err error) {
 //
 // If there is no xattr by that name, returns fuse.ErrNoXattr.
 func (f *File) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
-	return syscall.ENOSYS // we never implement this
+	xattrs := f.Xattrs()
+	if xattrs == nil {
+		return syscall.ENOSYS // only implemented for --vfs-cache-xattrs
+	}
+	value, found := xattrs[req.Name]
+	if !found {
+		return fuse.ErrNoXattr
+	}
+	resp.Xattr = []byte(value)
+	return nil
 }
 
 var _ fusefs.NodeGetxattrer = (*File)(nil)
 
 // Listxattr lists the extended attributes recorded for the node.
 func (f *File) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
-	return syscall.ENOSYS // we never implement this
+	xattrs := f.Xattrs()
+	if xattrs == nil {
+		return syscall.ENOSYS // only implemented for --vfs-cache-xattrs
+	}
+	resp.Append(slices.Sorted(maps.Keys(xattrs))...)
+	return nil
 }
 
 var _ fusefs.NodeListxattrer = (*File)(nil)
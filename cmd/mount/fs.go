@@ -102,6 +102,18 @@ func translateError(err error) error {
 		return fuse.Errno(syscall.EINVAL)
 	case vfs.ELOOP:
 		return fuse.Errno(syscall.ELOOP)
+	case vfs.ENOSPC:
+		return fuse.Errno(syscall.ENOSPC)
+	case vfs.EBUSY:
+		return fuse.Errno(syscall.EBUSY)
+	case vfs.EAGAIN:
+		return fuse.Errno(syscall.EAGAIN)
+	case vfs.ETIMEDOUT:
+		return fuse.Errno(syscall.ETIMEDOUT)
+	case vfs.EACCES:
+		return fuse.Errno(syscall.EACCES)
+	case vfs.EIO:
+		return fuse.Errno(syscall.EIO)
 	}
 	fs.Errorf(nil, "IO error: %v", err)
 	return err
@@ -69,7 +69,13 @@ func getMode(node os.FileInfo) uint32 {
 func setAttr(node vfs.Node, attr *fuse.Attr) {
 	Size := uint64(node.Size())
 	const BlockSize = 512
-	Blocks := (Size + BlockSize - 1) / BlockSize
+	DiskSize := Size
+	Nlink := uint32(1)
+	if f, ok := node.(*vfs.File); ok {
+		DiskSize = uint64(f.DiskSize())
+		Nlink = f.LinkCount()
+	}
+	Blocks := (DiskSize + BlockSize - 1) / BlockSize
 	modTime := node.ModTime()
 	// set attributes
 	vfs := node.VFS()
@@ -77,7 +83,7 @@ func setAttr(node vfs.Node, attr *fuse.Attr) {
 	attr.Owner.Uid = vfs.Opt.UID
 	attr.Mode = getMode(node)
 	attr.Size = Size
-	attr.Nlink = 1
+	attr.Nlink = Nlink
 	attr.Blocks = Blocks
 	// attr.Blksize = BlockSize // not supported in freebsd/darwin, defaults to 4k if not set
 	s := uint64(modTime.Unix())
@@ -135,6 +141,18 @@ func translateError(err error) syscall.Errno {
 		return syscall.EINVAL
 	case vfs.ELOOP:
 		return syscall.ELOOP
+	case vfs.ENOSPC:
+		return syscall.ENOSPC
+	case vfs.EBUSY:
+		return syscall.EBUSY
+	case vfs.EAGAIN:
+		return syscall.EAGAIN
+	case vfs.ETIMEDOUT:
+		return syscall.ETIMEDOUT
+	case vfs.EACCES:
+		return syscall.EACCES
+	case vfs.EIO:
+		return syscall.EIO
 	}
 	fs.Errorf(nil, "IO error: %v", err)
 	return syscall.EIO
@@ -4,8 +4,10 @@ package mount2
 
 import (
 	"context"
+	"maps"
 	"os"
 	"path"
+	"slices"
 	"syscall"
 
 	fusefs "github.com/hanwen/go-fuse/v2/fs"
@@ -427,7 +429,18 @@ var _ = (fusefs.NodeRenamer)((*Node)(nil))
 // small, it should return ERANGE and the size of the attribute.
 // If not defined, Getxattr will return ENOATTR.
 func (n *Node) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
-	return 0, syscall.ENOSYS // we never implement this
+	xattrs := n.xattrs()
+	if xattrs == nil {
+		return 0, syscall.ENOSYS // only implemented for --vfs-cache-xattrs
+	}
+	value, found := xattrs[attr]
+	if !found {
+		return 0, fusefs.ENOATTR
+	}
+	if len(dest) < len(value) {
+		return uint32(len(value)), syscall.ERANGE
+	}
+	return uint32(copy(dest, value)), 0
 }
 
 var _ fusefs.NodeGetxattrer = (*Node)(nil)
@@ -454,7 +467,28 @@ var _ fusefs.NodeRemovexattrer = (*Node)(nil)
 // and the correct size.  If not defined, return an empty list and
 // success.
 func (n *Node) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
-	return 0, syscall.ENOSYS // we never implement this
+	xattrs := n.xattrs()
+	if xattrs == nil {
+		return 0, syscall.ENOSYS // only implemented for --vfs-cache-xattrs
+	}
+	var names []byte
+	for _, name := range slices.Sorted(maps.Keys(xattrs)) {
+		names = append(names, name...)
+		names = append(names, 0)
+	}
+	if len(dest) < len(names) {
+		return uint32(len(names)), syscall.ERANGE
+	}
+	return uint32(copy(dest, names)), 0
+}
+
+// xattrs returns the extended attributes of the node or nil if it
+// doesn't have any
+func (n *Node) xattrs() map[string]string {
+	if file, ok := n.node.(*vfs.File); ok {
+		return file.Xattrs()
+	}
+	return nil
 }
 
 var _ fusefs.NodeListxattrer = (*Node)(nil)
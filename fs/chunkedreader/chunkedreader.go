@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/rclone/rclone/fs"
 )
@@ -22,6 +23,7 @@ type ChunkedReader interface {
 	io.Closer
 	fs.RangeSeeker
 	Open() (ChunkedReader, error)
+	Stats() Stats
 }
 
 // New returns a ChunkedReader for the Object.
@@ -31,6 +33,31 @@ type ChunkedReader interface {
 // doubled after each chunk read with a maximum of maxChunkSize.
 // A Seek or RangeSeek will reset the chunk size to it's initial value
 func New(ctx context.Context, o fs.Object, initialChunkSize int64, maxChunkSize int64, streams int) ChunkedReader {
+	return NewWithLimit(ctx, o, initialChunkSize, maxChunkSize, streams, LimitCap, 0, 0)
+}
+
+// NewWithLimit returns a ChunkedReader for the Object like New, with
+// limit controlling what happens once the chunk size has doubled up to
+// maxChunkSize.
+//
+// With LimitCap chunks of maxChunkSize carry on being read. With
+// LimitStay the rest of the object is read with a single request. With
+// LimitMoreStreams the rest of the object is read with streams
+// parallel streams (DefaultMoreStreams if streams <= 1) of
+// maxChunkSize, and the chunk size doubles up to maxChunkSize before
+// that even if streams > 1.
+//
+// limit has no effect if chunked reading is disabled or maxChunkSize
+// is -1.
+//
+// If stall is set then a parallel stream which receives no data for
+// that long is abandoned and the rest of its range is read with a new
+// stream.
+//
+// If minStreamSize is set then the parallel streams use fewer, larger
+// requests if the chunks would otherwise be smaller than it, keeping
+// the amount of data read ahead the same.
+func NewWithLimit(ctx context.Context, o fs.Object, initialChunkSize int64, maxChunkSize int64, streams int, limit Limit, stall time.Duration, minStreamSize int64) ChunkedReader {
 	if initialChunkSize <= 0 {
 		initialChunkSize = -1
 	}
@@ -40,8 +67,26 @@ func New(ctx context.Context, o fs.Object, initialChunkSize int64, maxChunkSize
 	if streams < 0 {
 		streams = 0
 	}
+	if initialChunkSize <= 0 || maxChunkSize == -1 {
+		limit = LimitCap
+	}
+	if limit == LimitMoreStreams && o.Size() >= 0 {
+		if streams <= 1 {
+			streams = DefaultMoreStreams
+		}
+		cr := newSequential(ctx, o, initialChunkSize, maxChunkSize)
+		cr.limit = limit
+		cr.streams = streams
+		cr.stall = stall
+		cr.minStreamSize = minStreamSize
+		return cr
+	}
 	if streams <= 1 || o.Size() < 0 {
-		return newSequential(ctx, o, initialChunkSize, maxChunkSize)
+		cr := newSequential(ctx, o, initialChunkSize, maxChunkSize)
+		if limit == LimitStay {
+			cr.limit = limit
+		}
+		return cr
 	}
-	return newParallel(ctx, o, initialChunkSize, streams)
+	return newParallel(ctx, o, initialChunkSize, streams, stall, minStreamSize)
 }
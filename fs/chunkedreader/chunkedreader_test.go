@@ -45,6 +45,58 @@ func TestChunkedReader(t *testing.T) {
 	}
 }
 
+func TestChunkedReaderLimit(t *testing.T) {
+	ctx := context.Background()
+	content := makeContent(t, 1024)
+
+	for _, limit := range []Limit{LimitCap, LimitMoreStreams, LimitStay} {
+		for _, streams := range []int{0, 2} {
+			for _, maxChunkSize := range []int64{-1, 16, 100} {
+				what := fmt.Sprintf("limit %v streams %d maxChunkSize %d", limit, streams, maxChunkSize)
+				for _, mode := range mockobject.SeekModes {
+					o := mockobject.New("test.bin").WithContent(content, mode)
+
+					// Read the whole object
+					cr := NewWithLimit(ctx, o, 1, maxChunkSize, streams, limit, 0, 0)
+					got, err := io.ReadAll(cr)
+					require.NoError(t, err, what)
+					require.Equal(t, content, got, what)
+					if limit == LimitMoreStreams && maxChunkSize > 0 {
+						assert.NotNil(t, cr.(*sequential).parallel, what)
+					}
+
+					// Seek back after the limit has been reached
+					_, err = cr.RangeSeek(ctx, 500, io.SeekStart, -1)
+					require.NoError(t, err, what)
+					buf := make([]byte, 32)
+					_, err = io.ReadFull(cr, buf)
+					require.NoError(t, err, what)
+					require.Equal(t, content[500:532], buf, what)
+					require.NoError(t, cr.Close(), what)
+				}
+			}
+		}
+	}
+}
+
+func TestChunkedReaderLimitType(t *testing.T) {
+	ctx := context.Background()
+	o := mockobject.New("test.bin").WithContent([]byte("hello"), mockobject.SeekModeRegular)
+	cr := NewWithLimit(ctx, o, 1, 4, 0, LimitMoreStreams, 0, 0)
+	assert.IsType(t, new(sequential), cr)
+	assert.Equal(t, DefaultMoreStreams, cr.(*sequential).streams)
+	require.NoError(t, cr.Close())
+
+	cr = NewWithLimit(ctx, o, 1, 4, 2, LimitStay, 0, 0)
+	assert.IsType(t, new(parallel), cr)
+	require.NoError(t, cr.Close())
+
+	var limit Limit
+	require.NoError(t, limit.Set("more-streams"))
+	assert.Equal(t, LimitMoreStreams, limit)
+	assert.Equal(t, "ChunkLimit", limit.Type())
+}
+
 func testRead(content []byte, mode mockobject.SeekMode, streams int) func(*testing.T) {
 	return func(t *testing.T) {
 		ctx := context.Background()
@@ -134,3 +186,28 @@ func makeContent(t *testing.T, size int) []byte {
 	assert.NoError(t, err)
 	return content
 }
+
+func TestChunkedReaderStats(t *testing.T) {
+	ctx := context.Background()
+	content := makeContent(t, 1024)
+	o := mockobject.New("test.bin").WithContent(content, mockobject.SeekModeRegular)
+
+	cr := New(ctx, o, 16, 64, 0)
+	assert.Equal(t, Stats{ChunkSize: 16, Streams: 1, Offset: 0}, cr.Stats())
+	buf := make([]byte, 40)
+	_, err := io.ReadFull(cr, buf)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{ChunkSize: 32, Streams: 1, Offset: 40}, cr.Stats())
+	_, err = cr.RangeSeek(ctx, 500, io.SeekStart, -1)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{ChunkSize: 16, Streams: 1, Offset: 500}, cr.Stats())
+	require.NoError(t, cr.Close())
+
+	cr = New(ctx, o, 16, 64, 2)
+	_, err = io.ReadFull(cr, buf)
+	require.NoError(t, err)
+	stats := cr.Stats()
+	assert.Equal(t, 2, stats.Streams)
+	assert.Equal(t, int64(40), stats.Offset)
+	require.NoError(t, cr.Close())
+}
@@ -0,0 +1,35 @@
+package chunkedreader
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type limitChoices struct{}
+
+func (limitChoices) Choices() []string {
+	return []string{
+		LimitCap:         "cap",
+		LimitMoreStreams: "more-streams",
+		LimitStay:        "stay",
+	}
+}
+
+// Limit controls what the chunked reader does once the chunk size has
+// doubled up to the maximum chunk size
+type Limit = fs.Enum[limitChoices]
+
+// Limit options
+const (
+	LimitCap         Limit = iota // carry on reading chunks of the maximum size
+	LimitMoreStreams              // read the rest of the object with parallel streams
+	LimitStay                     // read the rest of the object with a single request
+)
+
+// Type of the value
+func (limitChoices) Type() string {
+	return "ChunkLimit"
+}
+
+// DefaultMoreStreams is the number of streams used by LimitMoreStreams
+// if no number of streams is given
+const DefaultMoreStreams = 4
@@ -2,9 +2,12 @@ package chunkedreader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/hash"
@@ -17,14 +20,16 @@ import (
 // parallel reads Object in chunks of a given size in parallel.
 type parallel struct {
 	ctx       context.Context
-	o         fs.Object  // source to read from
-	mu        sync.Mutex // protects following fields
-	endStream int64      // offset we have started streams for
-	offset    int64      // offset the read file pointer is at
-	chunkSize int64      // length of the chunks to read
-	nstreams  int        // number of streams to use
-	streams   []*stream  // the opened streams in offset order - the current one is first
-	closed    bool       // has Close been called?
+	o         fs.Object     // source to read from
+	stall     time.Duration // abandon a stream which makes no progress for this long if set
+	mu        sync.Mutex    // protects following fields
+	endStream int64         // offset we have started streams for
+	offset    int64         // offset the read file pointer is at
+	chunkSize int64         // length of the chunks to read
+	nstreams  int           // number of streams to use
+	streams   []*stream     // the opened streams in offset order - the current one is first
+	closed    bool          // has Close been called?
+	readerStats
 }
 
 // stream holds the info about a single download
@@ -39,6 +44,51 @@ type stream struct {
 	rw        *pool.RW        // buffer for read
 	err       chan error      // error returned from the read
 	name      string          // name of this stream for debugging
+	progress  atomic.Int64    // time in UnixNano the stream last received data
+}
+
+// errStreamStalled is returned by stream.read when the stream has
+// made no progress for the stall timeout
+var errStreamStalled = errors.New("stream stalled")
+
+// progressObject opens the Object with a reader which records in s
+// each time data is received.
+//
+// This is below the buffering done by operations.Open so a stream
+// which is slow but still receiving data isn't thought to be stalled.
+type progressObject struct {
+	fs.Object
+	s *stream
+}
+
+// Open the object recording the progress of the stream
+func (o progressObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	in, err := o.Object.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	return progressReader{ReadCloser: in, s: o.s}, nil
+}
+
+// progressReader records in s each time data is read from the ReadCloser
+type progressReader struct {
+	io.ReadCloser
+	s *stream
+}
+
+// Read records the progress of the stream
+func (pr progressReader) Read(p []byte) (n int, err error) {
+	n, err = pr.ReadCloser.Read(p)
+	if n > 0 {
+		pr.s.progress.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+// stalled returns true if the stream has received no data for the
+// stall timeout
+func (s *stream) stalled() bool {
+	return s.cr.stall > 0 && time.Since(time.Unix(0, s.progress.Load())) >= s.cr.stall
 }
 
 // Start a stream reading (offset, offset+size)
@@ -57,6 +107,7 @@ func (cr *parallel) newStream(ctx context.Context, offset, size int64) (s *strea
 		err:    make(chan error, 1),
 	}
 	s.name = fmt.Sprintf("stream(%d,%d,%p)", s.offset, s.size, s)
+	s.progress.Store(time.Now().UnixNano())
 
 	// Start the background read into the buffer
 	go s.readFrom(ctx)
@@ -69,7 +120,11 @@ func (cr *parallel) newStream(ctx context.Context, offset, size int64) (s *strea
 func (s *stream) readFrom(ctx context.Context) {
 	// Open the object at the correct range
 	fs.Debugf(s.cr.o, "%s: open", s.name)
-	rc, err := operations.Open(ctx, s.cr.o,
+	var src fs.Object = s.cr.o
+	if s.cr.stall > 0 {
+		src = progressObject{Object: src, s: s}
+	}
+	rc, err := operations.Open(ctx, src,
 		&fs.HashesOption{Hashes: hash.Set(hash.None)},
 		&fs.RangeOption{Start: s.offset, End: s.offset + s.size - 1})
 	if err != nil {
@@ -114,6 +169,10 @@ func (s *stream) read(p []byte) (n int, err error) {
 		if n >= len(p) {
 			break
 		}
+		// Give up on the stream if it has stopped sending data
+		if nn == 0 && s.stalled() {
+			return n, errStreamStalled
+		}
 		// Wait for a write to happen to read more
 		s.rw.WaitWrite(s.ctx)
 	}
@@ -144,12 +203,19 @@ func (s *stream) close() (err error) {
 
 // Make a new parallel chunked reader
 //
+// If stall is set then a stream which makes no progress for that long
+// is abandoned and the rest of its range read with a new stream.
+//
 // Mustn't be called for an unknown size object
-func newParallel(ctx context.Context, o fs.Object, chunkSize int64, streams int) ChunkedReader {
+func newParallel(ctx context.Context, o fs.Object, chunkSize int64, streams int, stall time.Duration, minStreamSize int64) ChunkedReader {
 	// Make sure chunkSize is a multiple of multipart.BufferSize
 	if chunkSize < 0 {
 		chunkSize = multipart.BufferSize
 	}
+	if newChunkSize, newStreams := streamsForMinSize(chunkSize, streams, minStreamSize); newStreams != streams || newChunkSize != chunkSize {
+		fs.Debugf(o, "newParallel using %d streams of %d instead of %d streams of %d to read at least %d per request", newStreams, newChunkSize, streams, chunkSize, minStreamSize)
+		chunkSize, streams = newChunkSize, newStreams
+	}
 	newChunkSize := multipart.BufferSize * (chunkSize / multipart.BufferSize)
 	if newChunkSize < chunkSize {
 		newChunkSize += multipart.BufferSize
@@ -157,13 +223,43 @@ func newParallel(ctx context.Context, o fs.Object, chunkSize int64, streams int)
 
 	fs.Debugf(o, "newParallel chunkSize=%d, streams=%d", chunkSize, streams)
 
-	return &parallel{
+	cr := &parallel{
 		ctx:       ctx,
 		o:         o,
 		offset:    0,
 		chunkSize: newChunkSize,
 		nstreams:  streams,
+		stall:     stall,
+	}
+	cr._updateStats()
+	return cr
+}
+
+// streamsForMinSize returns the chunkSize and number of streams to
+// use so that each request reads at least minStreamSize.
+//
+// The number of streams is reduced and the chunk size increased to
+// keep the amount of data being read ahead, streams * chunkSize, the
+// same, so fewer larger requests are made. If minStreamSize <= 0 then
+// chunkSize and streams are returned unchanged.
+func streamsForMinSize(chunkSize int64, streams int, minStreamSize int64) (int64, int) {
+	if minStreamSize <= 0 || chunkSize >= minStreamSize {
+		return chunkSize, streams
 	}
+	window := chunkSize * int64(max(streams, 1))
+	newStreams := max(int(window/minStreamSize), 1)
+	return max(window/int64(newStreams), minStreamSize), newStreams
+}
+
+// _updateStats updates the stats returned by Stats
+//
+// Call with the lock held
+func (cr *parallel) _updateStats() {
+	cr.set(Stats{
+		ChunkSize: cr.chunkSize,
+		Streams:   cr.nstreams,
+		Offset:    cr.offset,
+	})
 }
 
 // _open starts the file transferring at offset
@@ -219,6 +315,27 @@ func (cr *parallel) _popStream() (err error) {
 	return err
 }
 
+// The current stream has stalled so abandon it and read the rest of
+// its range with a new stream
+//
+// Call with lock held
+func (cr *parallel) _replaceStream() (err error) {
+	old := cr.streams[0]
+	offset := old.offset + old.readBytes
+	size := old.size - old.readBytes
+	fs.Logf(cr.o, "parallel chunked reader: stream at %d size %d made no progress for %v - reading %d-%d with a new stream", old.offset, old.size, cr.stall, offset, offset+size-1)
+	s, err := cr.newStream(cr.ctx, offset, size)
+	if err != nil {
+		return err
+	}
+	cr.streams[0] = s
+	// Closing waits for the stalled read to return so don't wait for it
+	go func() {
+		_ = old.close()
+	}()
+	return nil
+}
+
 // Get rid of all the streams
 //
 // Call with lock held
@@ -236,6 +353,7 @@ func (cr *parallel) Read(p []byte) (n int, err error) {
 	defer log.Trace(cr.o, "Read len(p)=%d", len(p))("n=%d, err=%v", &n, &err)
 	cr.mu.Lock()
 	defer cr.mu.Unlock()
+	defer cr._updateStats()
 
 	if cr.closed {
 		return 0, ErrorFileClosed
@@ -258,11 +376,17 @@ func (cr *parallel) Read(p []byte) (n int, err error) {
 		nn, err := stream.read(p[n:])
 		n += nn
 		cr.offset += int64(nn)
+		cr._updateStats()
 		if err == io.EOF {
 			err = cr._popStream()
 			if err != nil {
 				break
 			}
+		} else if err == errStreamStalled {
+			err = cr._replaceStream()
+			if err != nil {
+				break
+			}
 		} else if err != nil {
 			break
 		}
@@ -290,6 +414,7 @@ func (cr *parallel) Close() error {
 func (cr *parallel) Seek(offset int64, whence int) (int64, error) {
 	cr.mu.Lock()
 	defer cr.mu.Unlock()
+	defer cr._updateStats()
 
 	fs.Debugf(cr.o, "parallel chunked reader: seek from %d to %d whence %d", cr.offset, offset, whence)
 
@@ -374,6 +499,7 @@ func (cr *parallel) RangeSeek(ctx context.Context, offset int64, whence int, len
 func (cr *parallel) Open() (ChunkedReader, error) {
 	cr.mu.Lock()
 	defer cr.mu.Unlock()
+	defer cr._updateStats()
 
 	return cr, cr._open()
 }
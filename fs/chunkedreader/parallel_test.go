@@ -4,8 +4,11 @@ import (
 	"context"
 	"io"
 	"math/rand"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fstest/mockobject"
 	"github.com/rclone/rclone/lib/multipart"
 	"github.com/stretchr/testify/assert"
@@ -98,5 +101,90 @@ func TestParallelLarge(t *testing.T) {
 
 		require.NoError(t, cr.Close())
 	})
+}
+
+// stallObject is an object whose first Open returns a reader which
+// stalls after some data until its context is cancelled
+type stallObject struct {
+	fs.Object
+	opens atomic.Int32
+}
+
+func (o *stallObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	in, err := o.Object.Open(ctx, options...)
+	if err != nil || o.opens.Add(1) > 1 {
+		return in, err
+	}
+	return &stallReader{ReadCloser: in, ctx: ctx, remaining: 100}, nil
+}
+
+type stallReader struct {
+	io.ReadCloser
+	ctx       context.Context
+	remaining int
+}
+
+func (r *stallReader) Read(p []byte) (n int, err error) {
+	if r.remaining <= 0 {
+		<-r.ctx.Done()
+		return 0, r.ctx.Err()
+	}
+	if len(p) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err = io.ReadFull(r.ReadCloser, p)
+	r.remaining -= n
+	return n, err
+}
+
+func TestParallelStall(t *testing.T) {
+	ctx := context.Background()
+	const streams = 2
+	const chunkSize = multipart.BufferSize
+	const size = 3 * chunkSize
+	content := makeContent(t, size)
+	o := &stallObject{Object: mockobject.New("test.bin").WithContent(content, mockobject.SeekModeNone)}
 
+	cr := NewWithLimit(ctx, o, chunkSize, 0, streams, LimitCap, 100*time.Millisecond, 0)
+	got, err := io.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	require.NoError(t, cr.Close())
+	assert.GreaterOrEqual(t, o.opens.Load(), int32(4))
+}
+
+func TestStreamsForMinSize(t *testing.T) {
+	for _, test := range []struct {
+		chunkSize     int64
+		streams       int
+		minStreamSize int64
+		wantChunkSize int64
+		wantStreams   int
+	}{
+		{chunkSize: 1 << 20, streams: 16, minStreamSize: 0, wantChunkSize: 1 << 20, wantStreams: 16},
+		{chunkSize: 8 << 20, streams: 16, minStreamSize: 4 << 20, wantChunkSize: 8 << 20, wantStreams: 16},
+		{chunkSize: 1 << 20, streams: 16, minStreamSize: 4 << 20, wantChunkSize: 4 << 20, wantStreams: 4},
+		{chunkSize: 1 << 20, streams: 16, minStreamSize: 5 << 20, wantChunkSize: (16 << 20) / 3, wantStreams: 3},
+		{chunkSize: 1 << 20, streams: 4, minStreamSize: 16 << 20, wantChunkSize: 16 << 20, wantStreams: 1},
+	} {
+		gotChunkSize, gotStreams := streamsForMinSize(test.chunkSize, test.streams, test.minStreamSize)
+		assert.Equal(t, test.wantChunkSize, gotChunkSize, "%+v", test)
+		assert.Equal(t, test.wantStreams, gotStreams, "%+v", test)
+	}
+}
+
+func TestParallelMinStreamSize(t *testing.T) {
+	ctx := context.Background()
+	const chunkSize = multipart.BufferSize
+	const size = 8 * chunkSize
+	content := makeContent(t, size)
+	o := mockobject.New("test.bin").WithContent(content, mockobject.SeekModeNone)
+
+	cr := NewWithLimit(ctx, o, chunkSize, 0, 4, LimitCap, 0, 2*chunkSize)
+	assert.Equal(t, Stats{ChunkSize: 2 * chunkSize, Streams: 2}, cr.Stats())
+
+	got, err := io.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	require.NoError(t, cr.Close())
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/hash"
@@ -25,11 +26,17 @@ type sequential struct {
 	maxChunkSize     int64         // consecutive read chunks will double in size until reached. -1 means no limit
 	customChunkSize  bool          // is the current chunkSize set by RangeSeek?
 	closed           bool          // has Close been called?
+	limit            Limit         // what to do when the chunk size reaches maxChunkSize
+	streams          int           // number of streams to use for LimitMoreStreams
+	stall            time.Duration // stall timeout for the streams used for LimitMoreStreams
+	minStreamSize    int64         // smallest request for the streams used for LimitMoreStreams
+	parallel         *parallel     // if set the reader the rest of the object is being read with
+	readerStats
 }
 
 // Make a new sequential chunked reader
-func newSequential(ctx context.Context, o fs.Object, initialChunkSize int64, maxChunkSize int64) ChunkedReader {
-	return &sequential{
+func newSequential(ctx context.Context, o fs.Object, initialChunkSize int64, maxChunkSize int64) *sequential {
+	cr := &sequential{
 		ctx:              ctx,
 		o:                o,
 		offset:           -1,
@@ -37,18 +44,49 @@ func newSequential(ctx context.Context, o fs.Object, initialChunkSize int64, max
 		initialChunkSize: initialChunkSize,
 		maxChunkSize:     maxChunkSize,
 	}
+	cr._updateStats()
+	return cr
+}
+
+// _updateStats updates the stats returned by Stats
+//
+// Call with the lock held
+func (cr *sequential) _updateStats() {
+	if cr.parallel != nil {
+		cr.set(cr.parallel.Stats())
+		return
+	}
+	offset := cr.offset
+	if offset == -1 {
+		offset = cr.chunkOffset
+	}
+	cr.set(Stats{
+		ChunkSize: cr.chunkSize,
+		Streams:   1,
+		Offset:    offset,
+	})
 }
 
 // Read from the file - for details see io.Reader
 func (cr *sequential) Read(p []byte) (n int, err error) {
 	cr.mu.Lock()
 	defer cr.mu.Unlock()
+	defer cr._updateStats()
 
 	if cr.closed {
 		return 0, ErrorFileClosed
 	}
 
 	for reqSize := int64(len(p)); reqSize > 0; reqSize = int64(len(p)) {
+		// the rest of the object is being read in parallel
+		if cr.parallel != nil {
+			var pn int
+			pn, err = cr.parallel.Read(p)
+			n += pn
+			cr.offset += int64(pn)
+			return n, err
+		}
+
 		// the current chunk boundary. valid only when chunkSize > 0
 		chunkEnd := cr.chunkOffset + cr.chunkSize
 
@@ -62,8 +100,14 @@ func (cr *sequential) Read(p []byte) (n int, err error) {
 				cr.chunkSize = cr.initialChunkSize
 			} else {
 				cr.chunkSize *= 2
-				if cr.chunkSize > cr.maxChunkSize && cr.maxChunkSize != -1 {
+				if cr.chunkSize >= cr.maxChunkSize && cr.maxChunkSize != -1 {
 					cr.chunkSize = cr.maxChunkSize
+					if err = cr.reachedLimit(); err != nil {
+						return n, err
+					}
+					if cr.parallel != nil {
+						continue
+					}
 				}
 			}
 			// recalculate the chunk boundary. valid only when chunkSize > 0
@@ -110,7 +154,50 @@ func (cr *sequential) Close() error {
 	}
 	cr.closed = true
 
-	return cr.resetReader(nil, 0)
+	err := cr.closeParallel()
+	orErr(&err, cr.resetReader(nil, 0))
+	return err
+}
+
+// reachedLimit is called when the chunk size has doubled up to
+// maxChunkSize and switches to reading the rest of the object in the
+// way set by limit
+//
+// Call with the lock held
+func (cr *sequential) reachedLimit() error {
+	switch cr.limit {
+	case LimitStay:
+		fs.Debugf(cr.o, "ChunkedReader reached chunk size limit at %d - reading the rest with one request", cr.offset)
+		cr.chunkSize = -1
+	case LimitMoreStreams:
+		size := cr.o.Size()
+		if size < 0 || cr.offset >= size {
+			return nil
+		}
+		fs.Debugf(cr.o, "ChunkedReader reached chunk size limit at %d - reading the rest with %d streams", cr.offset, cr.streams)
+		if err := cr.resetReader(nil, cr.offset); err != nil {
+			return err
+		}
+		p := newParallel(cr.ctx, cr.o, cr.maxChunkSize, cr.streams, cr.stall, cr.minStreamSize).(*parallel)
+		if _, err := p.Seek(cr.offset, io.SeekStart); err != nil {
+			_ = p.Close()
+			return err
+		}
+		cr.parallel = p
+	}
+	return nil
+}
+
+// closeParallel stops reading the rest of the object in parallel
+//
+// Call with the lock held
+func (cr *sequential) closeParallel() error {
+	if cr.parallel == nil {
+		return nil
+	}
+	err := cr.parallel.Close()
+	cr.parallel = nil
+	return err
 }
 
 // Seek the file - for details see io.Seeker
@@ -125,12 +212,16 @@ func (cr *sequential) Seek(offset int64, whence int) (int64, error) {
 func (cr *sequential) RangeSeek(ctx context.Context, offset int64, whence int, length int64) (int64, error) {
 	cr.mu.Lock()
 	defer cr.mu.Unlock()
+	defer cr._updateStats()
 
 	fs.Debugf(cr.o, "ChunkedReader.RangeSeek from %d to %d length %d", cr.offset, offset, length)
 
 	if cr.closed {
 		return 0, ErrorFileClosed
 	}
+	if err := cr.closeParallel(); err != nil {
+		fs.Debugf(cr.o, "ChunkedReader.RangeSeek failed to close parallel reader: %v", err)
+	}
 
 	size := cr.o.Size()
 	switch whence {
@@ -163,8 +254,9 @@ func (cr *sequential) RangeSeek(ctx context.Context, offset int64, whence int, l
 func (cr *sequential) Open() (ChunkedReader, error) {
 	cr.mu.Lock()
 	defer cr.mu.Unlock()
+	defer cr._updateStats()
 
-	if cr.rc != nil && cr.offset != -1 {
+	if cr.parallel != nil || (cr.rc != nil && cr.offset != -1) {
 		return cr, nil
 	}
 	return cr, cr.openRange()
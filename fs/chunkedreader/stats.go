@@ -0,0 +1,31 @@
+package chunkedreader
+
+import "sync"
+
+// Stats describes what a ChunkedReader is doing
+type Stats struct {
+	ChunkSize int64 // size of the chunks being read, -1 if reading the rest of the object in one request
+	Streams   int   // number of streams reading the object
+	Offset    int64 // offset in the object the next byte will be read from
+}
+
+// readerStats holds the Stats of a reader so they can be read without
+// waiting for a Read in progress to finish
+type readerStats struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+// set the stats
+func (s *readerStats) set(stats Stats) {
+	s.mu.Lock()
+	s.stats = stats
+	s.mu.Unlock()
+}
+
+// Stats returns what the reader is doing
+func (s *readerStats) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
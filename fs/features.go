@@ -40,6 +40,7 @@ type Features struct {
 	Overlay                  bool // this wraps one or more backends to add functionality
 	ChunkWriterDoesntSeek    bool // set if the chunk writer doesn't need to read the data more than once
 	DoubleSlash              bool // set if backend supports double slashes in paths
+	TransformsData           bool // set if the data is transformed, e.g. encrypted or compressed, on its way to the wrapped backend
 
 	// Purge all files in the directory specified
 	//
@@ -81,6 +82,18 @@ type Features struct {
 	// If destination exists then return fs.ErrorDirExists
 	DirMove func(ctx context.Context, src Fs, srcRemote, dstRemote string) error
 
+	// DeleteObjects deletes the objects passed in, which must all be
+	// from this Fs, using as few requests as possible.
+	//
+	// If it returns an error some of the objects may not have been
+	// deleted.
+	DeleteObjects func(ctx context.Context, objs []Object) error
+
+	// HardLink makes remote another name for the file src, which
+	// must be from this Fs, replacing anything at remote, and
+	// returns the new object.
+	HardLink func(ctx context.Context, src Object, remote string) (Object, error)
+
 	// MkdirMetadata makes the directory passed in as dir.
 	//
 	// It shouldn't return an error if it already exists.
@@ -304,6 +317,12 @@ func (ft *Features) Fill(ctx context.Context, f Fs) *Features {
 	if do, ok := f.(DirMover); ok {
 		ft.DirMove = do.DirMove
 	}
+	if do, ok := f.(ObjectsDeleter); ok {
+		ft.DeleteObjects = do.DeleteObjects
+	}
+	if do, ok := f.(HardLinker); ok {
+		ft.HardLink = do.HardLink
+	}
 	if do, ok := f.(MkdirMetadataer); ok {
 		ft.MkdirMetadata = do.MkdirMetadata
 	}
@@ -404,6 +423,7 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	// ft.Overlay = ft.Overlay && mask.Overlay don't propagate Overlay
 	ft.ChunkWriterDoesntSeek = ft.ChunkWriterDoesntSeek && mask.ChunkWriterDoesntSeek
 	ft.DoubleSlash = ft.DoubleSlash && mask.DoubleSlash
+	// ft.TransformsData = ft.TransformsData && mask.TransformsData don't propagate TransformsData
 
 	if mask.Purge == nil {
 		ft.Purge = nil
@@ -417,6 +437,12 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	if mask.DirMove == nil {
 		ft.DirMove = nil
 	}
+	if mask.DeleteObjects == nil {
+		ft.DeleteObjects = nil
+	}
+	if mask.HardLink == nil {
+		ft.HardLink = nil
+	}
 	if mask.MkdirMetadata == nil {
 		ft.MkdirMetadata = nil
 	}
@@ -554,6 +580,24 @@ type DirMover interface {
 	DirMove(ctx context.Context, src Fs, srcRemote, dstRemote string) error
 }
 
+// ObjectsDeleter is an optional interface for Fs
+type ObjectsDeleter interface {
+	// DeleteObjects deletes the objects passed in, which must all be
+	// from this Fs, using as few requests as possible.
+	//
+	// If it returns an error some of the objects may not have been
+	// deleted.
+	DeleteObjects(ctx context.Context, objs []Object) error
+}
+
+// HardLinker is an optional interface for Fs
+type HardLinker interface {
+	// HardLink makes remote another name for the file src, which
+	// must be from this Fs, replacing anything at remote, and
+	// returns the new object.
+	HardLink(ctx context.Context, src Object, remote string) (Object, error)
+}
+
 // MkdirMetadataer is an optional interface for Fs
 type MkdirMetadataer interface {
 	// MkdirMetadata makes the directory passed in as dir.
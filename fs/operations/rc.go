@@ -452,6 +452,8 @@ This returns info about the remote passed in;
                 "CleanUp": false,
                 "Command": true,
                 "Copy": false,
+                "DeleteObjects": false,
+                "HardLink": false,
                 "DirCacheFlush": false,
                 "DirMove": true,
                 "Disconnect": false,
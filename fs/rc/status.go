@@ -0,0 +1,88 @@
+// Define the admin/status rc function which combines the status of
+// the other subsystems
+
+package rc
+
+import (
+	"context"
+	"strings"
+)
+
+func init() {
+	Add(Call{
+		Path:         "admin/status",
+		AuthRequired: true,
+		Fn:           rcAdminStatus,
+		Title:        "Show the status of the VFSes and bisync in one call.",
+		Help: strings.ReplaceAll(`
+This returns the status of all the active VFSes and the results of
+the bisync runs done by this rclone in one call, for use by
+dashboards. It saves calling |vfs/list|, |vfs/stats|, |vfs/poll-info|
+and |sync/bisync-metrics| separately.
+
+It returns
+
+    {
+        // Status of each active VFS keyed on the VFS name from vfs/list
+        "vfses": {
+            "/mnt/a": {
+                "stats": {},    // as returned by vfs/stats including the cache stats and dirty files
+                "pollInfo": {}  // as returned by vfs/poll-info
+            }
+        },
+        // Results of bisync runs for each path pair as returned by sync/bisync-metrics
+        "bisync": []
+    }
+
+If part of the status can't be read then that part is replaced with
+an object with the error under the key "error", so the rest of the
+status is still returned. If rclone was built without the VFS or
+bisync then that part is left out.
+`, "|", "`"),
+	})
+}
+
+// callStatus calls the rc function at path returning its output or
+// the error as an "error" key so it can be shown in the status
+func callStatus(ctx context.Context, path string, in Params) (out Params, found bool) {
+	call := Calls.Get(path)
+	if call == nil {
+		return nil, false
+	}
+	out, err := call.Fn(ctx, in)
+	if err != nil {
+		return Params{"error": err.Error()}, true
+	}
+	return out, true
+}
+
+// Show the status of the VFSes and bisync
+func rcAdminStatus(ctx context.Context, in Params) (out Params, err error) {
+	out = Params{}
+	if list, found := callStatus(ctx, "vfs/list", Params{}); found {
+		pollInfos, _ := callStatus(ctx, "vfs/poll-info", Params{})
+		pollInfoByName, _ := pollInfos["vfses"].(Params)
+		vfses := Params{}
+		names, _ := list["vfses"].([]string)
+		for _, name := range names {
+			stats, _ := callStatus(ctx, "vfs/stats", Params{"fs": name})
+			pollInfo, ok := pollInfoByName[name]
+			if !ok && pollInfos["error"] != nil {
+				pollInfo = pollInfos
+			}
+			vfses[name] = Params{
+				"stats":    stats,
+				"pollInfo": pollInfo,
+			}
+		}
+		out["vfses"] = vfses
+	}
+	if metrics, found := callStatus(ctx, "sync/bisync-metrics", Params{}); found {
+		if pairs, ok := metrics["pairs"]; ok {
+			out["bisync"] = pairs
+		} else {
+			out["bisync"] = metrics
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,92 @@
+package vfs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs/rc"
+)
+
+// Operations recorded in the access history
+const (
+	accessOpen  = "open"
+	accessRead  = "read"
+	accessWrite = "write"
+)
+
+// access is a record of a file being accessed
+type access struct {
+	path  string
+	when  time.Time
+	op    string
+	bytes int64
+}
+
+// accessHistory keeps the most recent --vfs-access-history file
+// accesses in a ring buffer for vfs/access-history.
+//
+// The methods may be called on a nil *accessHistory which does nothing.
+type accessHistory struct {
+	mu      sync.Mutex
+	records []access // ring buffer of records
+	next    int      // index of the next record to write
+	full    bool     // set if the ring buffer has wrapped
+}
+
+// newAccessHistory makes an accessHistory holding up to size records
+func newAccessHistory(size int) *accessHistory {
+	return &accessHistory{
+		records: make([]access, size),
+	}
+}
+
+// add records an access of op to path transferring bytes
+func (h *accessHistory) add(path string, op string, bytes int64) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records[h.next] = access{
+		path:  path,
+		when:  time.Now(),
+		op:    op,
+		bytes: bytes,
+	}
+	h.next++
+	if h.next >= len(h.records) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// list returns the records held, oldest first
+func (h *accessHistory) list() []access {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.full {
+		return append([]access(nil), h.records[:h.next]...)
+	}
+	out := make([]access, 0, len(h.records))
+	out = append(out, h.records[h.next:]...)
+	return append(out, h.records[:h.next]...)
+}
+
+// recordAccess records an access of op to f transferring bytes if
+// --vfs-access-history is set
+func (f *File) recordAccess(op string, bytes int) {
+	f.VFS().accessHistory.add(f.Path(), op, int64(bytes))
+}
+
+// rcParams returns the access as rc.Params
+func (a *access) rcParams() rc.Params {
+	return rc.Params{
+		"path":  a.path,
+		"time":  a.when,
+		"op":    a.op,
+		"bytes": a.bytes,
+	}
+}
@@ -0,0 +1,57 @@
+package vfs
+
+import (
+	"context"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// BirthTime returns the creation time of the file
+//
+// If --vfs-birth-time is set this is read from the "btime" metadata
+// of the object on the remote. If that isn't set, or the remote
+// doesn't support it, or the file hasn't been uploaded yet then the
+// modification time is returned instead.
+func (f *File) BirthTime() time.Time {
+	if !f.VFS().Opt.BirthTime {
+		return f.ModTime()
+	}
+	f.mu.RLock()
+	o, birthTime, birthTimeObj := f.o, f.birthTime, f.birthTimeObj
+	f.mu.RUnlock()
+	if o == nil {
+		return f.ModTime()
+	}
+	// Read the metadata once for each object as it may be expensive
+	if birthTimeObj != o {
+		birthTime = readBirthTime(o)
+		f.mu.Lock()
+		f.birthTime, f.birthTimeObj = birthTime, o
+		f.mu.Unlock()
+	}
+	if birthTime.IsZero() {
+		return f.ModTime()
+	}
+	return birthTime
+}
+
+// readBirthTime reads the "btime" metadata from o returning a zero
+// time if it isn't available
+func readBirthTime(o fs.Object) time.Time {
+	metadata, err := fs.GetMetadata(context.TODO(), o)
+	if err != nil {
+		fs.Debugf(o, "vfs: failed to read metadata for birth time: %v", err)
+		return time.Time{}
+	}
+	btime, ok := metadata["btime"]
+	if !ok {
+		return time.Time{}
+	}
+	birthTime, err := time.Parse(time.RFC3339Nano, btime)
+	if err != nil {
+		fs.Debugf(o, "vfs: failed to parse birth time %q: %v", btime, err)
+		return time.Time{}
+	}
+	return birthTime
+}
@@ -0,0 +1,40 @@
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBirthTime(t *testing.T) {
+	for _, birthTime := range []bool{false, true} {
+		t.Run(fmt.Sprintf("birthTime=%v", birthTime), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.BirthTime = birthTime
+			r, vfs := newTestVFSOpt(t, &opt)
+			ctx := context.Background()
+
+			before := time.Now()
+			file1 := r.WriteObject(ctx, "file1", "file1 contents", t1)
+			node, err := vfs.Stat("file1")
+			require.NoError(t, err)
+			file := node.(*File)
+
+			metadata, err := fs.GetMetadata(ctx, file.DirEntry().(fs.Object))
+			require.NoError(t, err)
+			if !birthTime || metadata["btime"] == "" {
+				fstest.AssertTimeEqualWithPrecision(t, "file1", t1, file.BirthTime(), vfs.f.Precision())
+				return
+			}
+			assert.False(t, file.BirthTime().Before(before.Add(-time.Minute)), "birth time %v before creation at %v", file.BirthTime(), before)
+			fstest.AssertTimeEqualWithPrecision(t, "file1", file1.ModTime, file.ModTime(), vfs.f.Precision())
+		})
+	}
+}
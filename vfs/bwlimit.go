@@ -0,0 +1,166 @@
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+	"golang.org/x/time/rate"
+)
+
+// readBwRule is a single entry from --vfs-per-handle-read-bwlimit-rules
+type readBwRule struct {
+	glob  string
+	re    *regexp.Regexp
+	limit fs.SizeSuffix
+}
+
+// splitReadBwRules splits rules on commas which aren't inside {}
+func splitReadBwRules(rules string) (out []string) {
+	depth, start := 0, 0
+	for i, c := range rules {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				out = append(out, rules[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, rules[start:])
+}
+
+// parseReadBwRules parses rules in the form "glob=limit,glob=limit"
+func parseReadBwRules(rules string, ignoreCase bool) (out []readBwRule, err error) {
+	if strings.TrimSpace(rules) == "" {
+		return nil, nil
+	}
+	for _, rule := range splitReadBwRules(rules) {
+		rule = strings.TrimSpace(rule)
+		i := strings.LastIndex(rule, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("bad per handle read bwlimit rule %q: expecting glob=limit", rule)
+		}
+		glob, limitString := strings.TrimSpace(rule[:i]), strings.TrimSpace(rule[i+1:])
+		var limit fs.SizeSuffix
+		err = limit.Set(limitString)
+		if err != nil {
+			return nil, fmt.Errorf("bad limit in per handle read bwlimit rule %q: %w", rule, err)
+		}
+		re, err := filter.GlobPathToRegexp(glob, ignoreCase)
+		if err != nil {
+			return nil, fmt.Errorf("bad glob in per handle read bwlimit rule %q: %w", rule, err)
+		}
+		out = append(out, readBwRule{glob: glob, re: re, limit: limit})
+	}
+	return out, nil
+}
+
+// readBwLimits holds the per handle read bandwidth limits for a VFS
+type readBwLimits struct {
+	mu    sync.Mutex
+	limit fs.SizeSuffix // default limit for each handle
+	rules []readBwRule  // per path overrides - first match wins
+	raw   string        // rules as supplied
+	gen   atomic.Int64  // incremented whenever the limits change
+}
+
+// set the limit and the rules
+func (l *readBwLimits) set(limit fs.SizeSuffix, rules string, ignoreCase bool) error {
+	parsed, err := parseReadBwRules(rules, ignoreCase)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.limit = limit
+	l.rules = parsed
+	l.raw = rules
+	l.mu.Unlock()
+	l.gen.Add(1)
+	return nil
+}
+
+// get the limit and the rules as supplied
+func (l *readBwLimits) get() (limit fs.SizeSuffix, rules string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit, l.raw
+}
+
+// forPath returns the limit which applies to handles open on name
+//
+// A limit <= 0 means unlimited
+func (l *readBwLimits) forPath(name string) fs.SizeSuffix {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, rule := range l.rules {
+		if rule.re.MatchString(name) {
+			return rule.limit
+		}
+	}
+	return l.limit
+}
+
+// readLimiter limits the read bandwidth of a single open file handle
+// independently of the global --bwlimit
+type readLimiter struct {
+	limits *readBwLimits
+	name   string // path of the file the handle is open on
+	mu     sync.Mutex
+	gen    int64         // generation of limits tb was made from
+	tb     *rate.Limiter // nil if unlimited
+}
+
+// newReadLimiter makes a limiter for a handle open on name
+func (vfs *VFS) newReadLimiter(name string) *readLimiter {
+	return &readLimiter{
+		limits: &vfs.readBw,
+		name:   name,
+		gen:    -1,
+	}
+}
+
+// wait until n bytes are allowed to have been read
+//
+// The limits are looked up again if they have been changed since the
+// last call.
+func (rl *readLimiter) wait(n int) {
+	rl.mu.Lock()
+	if gen := rl.limits.gen.Load(); gen != rl.gen {
+		rl.gen = gen
+		limit := rl.limits.forPath(rl.name)
+		switch {
+		case limit <= 0:
+			rl.tb = nil
+		case rl.tb == nil:
+			rl.tb = rate.NewLimiter(rate.Limit(limit), int(limit))
+		default:
+			rl.tb.SetLimit(rate.Limit(limit))
+			rl.tb.SetBurst(int(limit))
+		}
+	}
+	tb := rl.tb
+	rl.mu.Unlock()
+	if tb == nil {
+		return
+	}
+	// WaitN can't wait for more than the burst at once
+	for burst := tb.Burst(); n > 0; n -= burst {
+		err := tb.WaitN(context.Background(), min(n, burst))
+		if err != nil {
+			fs.Errorf(rl.name, "Per handle read bwlimit failed: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReadBwRules(t *testing.T) {
+	rules, err := parseReadBwRules("", false)
+	require.NoError(t, err)
+	assert.Nil(t, rules)
+
+	rules, err = parseReadBwRules("*.{mkv,mp4}=4M, backup/**=off", false)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "*.{mkv,mp4}", rules[0].glob)
+	assert.Equal(t, 4*fs.Mebi, rules[0].limit)
+	assert.Equal(t, "backup/**", rules[1].glob)
+	assert.Equal(t, fs.SizeSuffix(-1), rules[1].limit)
+
+	for _, bad := range []string{"*.mkv", "=4M", "*.mkv=potato", "[=4M"} {
+		_, err = parseReadBwRules(bad, false)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestReadBwLimitsForPath(t *testing.T) {
+	var l readBwLimits
+	require.NoError(t, l.set(fs.Mebi, "backup/**=off,*.mkv=4M", false))
+
+	assert.Equal(t, fs.Mebi, l.forPath("file.txt"))
+	assert.Equal(t, 4*fs.Mebi, l.forPath("films/film.mkv"))
+	assert.Equal(t, fs.SizeSuffix(-1), l.forPath("backup/film.mkv"))
+	assert.Equal(t, fs.Mebi, l.forPath("films/film.MKV"))
+
+	require.NoError(t, l.set(fs.Mebi, "*.mkv=4M", true))
+	assert.Equal(t, 4*fs.Mebi, l.forPath("films/film.MKV"))
+
+	assert.Error(t, l.set(0, "potato", false))
+	limit, rules := l.get()
+	assert.Equal(t, fs.Mebi, limit)
+	assert.Equal(t, "*.mkv=4M", rules)
+}
+
+func TestReadLimiter(t *testing.T) {
+	var l readBwLimits
+	rl := &readLimiter{limits: &l, name: "file.txt", gen: -1}
+
+	// Unlimited
+	rl.wait(1 << 30)
+	assert.Nil(t, rl.tb)
+
+	// Limited - the first second's worth is free then
+	// it should take half a second to read another 500 bytes
+	require.NoError(t, l.set(1000, "", false))
+	rl.wait(1000)
+	require.NotNil(t, rl.tb)
+	start := time.Now()
+	rl.wait(500)
+	assert.InDelta(t, 500*time.Millisecond, time.Since(start), float64(250*time.Millisecond))
+
+	// Changing the limits is picked up by the next read
+	require.NoError(t, l.set(1000, "*.txt=off", false))
+	start = time.Now()
+	rl.wait(1 << 30)
+	assert.Nil(t, rl.tb)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestReadLimiterUnlocked(t *testing.T) {
+	for _, cacheMode := range []vfscommon.CacheMode{vfscommon.CacheModeOff, vfscommon.CacheModeFull} {
+		t.Run(cacheMode.String(), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.CacheMode = cacheMode
+			opt.PerHandleReadBwLimit = 1000
+			r, vfs := newTestVFSOpt(t, &opt)
+			r.WriteObject(context.Background(), "file", strings.Repeat("x", 3000), t1)
+
+			fh, err := vfs.OpenFile("file", os.O_RDONLY, 0)
+			require.NoError(t, err)
+			buf := make([]byte, 1000)
+			_, err = fh.Read(buf)
+			require.NoError(t, err)
+
+			// The next read waits for the limit, but the handle
+			// can be used meanwhile
+			done := make(chan struct{})
+			go func() {
+				_, _ = fh.ReadAt(make([]byte, 1000), 1000)
+				close(done)
+			}()
+			time.Sleep(100 * time.Millisecond)
+			start := time.Now()
+			_, err = fh.Stat()
+			require.NoError(t, err)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+			<-done
+			require.NoError(t, fh.Close())
+		})
+	}
+}
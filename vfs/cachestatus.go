@@ -0,0 +1,60 @@
+package vfs
+
+import (
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/rclone/rclone/fs/object"
+)
+
+// isCacheStatusName returns true if leaf in d is the name of the file
+// set by --vfs-cache-status-file
+func (d *Dir) isCacheStatusName(leaf string) bool {
+	name := d.vfs.Opt.CacheStatusFile
+	return name != "" && leaf == name && d.path == "" && d.versions == versionsNone
+}
+
+// cacheStatusFile returns a read only file whose contents are the
+// current stats of the VFS as JSON, as returned by vfs/stats
+//
+// The file isn't added to the directory cache so it is made afresh
+// with the latest stats each time it is looked up.
+func (d *Dir) cacheStatusFile() *File {
+	status, err := json.MarshalIndent(d.vfs.Stats(), "", "\t")
+	if err != nil {
+		status = jsonErrorf("failed to read cache status: %v", err)
+	}
+	status = append(status, '\n')
+	leaf := d.vfs.Opt.CacheStatusFile
+	o := object.NewMemoryObject(path.Join(d.path, leaf), time.Now(), status)
+	f := newFile(d, d.path, o, leaf)
+	// Base the inode number off the root inode number to keep it
+	// constant
+	f.inode = d.Inode() ^ (1 << 61)
+	return f
+}
+
+// _isCacheStatusFile returns true if f is the file made by
+// cacheStatusFile rather than a real file with the same name
+//
+// call with the lock held
+func (f *File) _isCacheStatusFile() bool {
+	_, isMemory := f.o.(*object.MemoryObject)
+	return isMemory && f.d.isCacheStatusName(f.leaf)
+}
+
+// isCacheStatusFile returns true if f is the file made by
+// cacheStatusFile rather than a real file with the same name
+func (f *File) isCacheStatusFile() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f._isCacheStatusFile()
+}
+
+// readOnly returns whether the file can't be changed
+func (f *File) readOnly() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.d.readOnly() || f._isCacheStatusFile() || f._isMetadataFile()
+}
@@ -0,0 +1,77 @@
+package vfs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheStatusFile(t *testing.T) {
+	const name = ".rclone-cache-status"
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.CacheStatusFile = name
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	file1 := r.WriteObject(context.Background(), "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+
+	// readStatus reads and decodes the status file
+	readStatus := func() (status map[string]any) {
+		fd, err := vfs.OpenFile(name, os.O_RDONLY, 0)
+		require.NoError(t, err)
+		buf, err := io.ReadAll(fd)
+		require.NoError(t, err)
+		require.NoError(t, fd.Close())
+		require.NoError(t, json.Unmarshal(buf, &status))
+		return status
+	}
+
+	// Shown in the root listing only
+	root, err := vfs.Root()
+	require.NoError(t, err)
+	nodes, err := root.ReadDirAll()
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, name, nodes[0].Name())
+	assert.True(t, nodes[0].IsFile())
+	assert.Equal(t, "dir", nodes[1].Name())
+	_, err = vfs.Stat("dir/" + name)
+	assert.Equal(t, ENOENT, err)
+
+	status := readStatus()
+	assert.Equal(t, r.Fremote.Root(), status["fs"])
+	assert.Contains(t, status, "diskCache")
+	assert.Equal(t, float64(0), status["diskCache"].(map[string]any)["files"])
+
+	// Read afresh each time
+	_, err = vfs.ReadFile("dir/file1")
+	require.NoError(t, err)
+	status = readStatus()
+	assert.Equal(t, float64(1), status["diskCache"].(map[string]any)["files"])
+
+	// Can't be changed
+	_, err = vfs.OpenFile(name, os.O_WRONLY|os.O_TRUNC, 0)
+	assert.Equal(t, EROFS, err)
+	assert.Equal(t, EROFS, vfs.Remove(name))
+	assert.Equal(t, EROFS, vfs.Rename(name, "dir/status"))
+	assert.Equal(t, EROFS, vfs.Rename("dir/file1", name))
+	node, err := vfs.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, EROFS, node.SetModTime(t2))
+
+	// Nothing is written to the remote or the cache
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{"dir"}, r.Fremote.Precision())
+	assert.False(t, vfs.cache.Exists(name))
+
+	// Doesn't stop everything being removed
+	require.NoError(t, root.RemoveAll())
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{}, []string{}, r.Fremote.Precision())
+}
@@ -0,0 +1,229 @@
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// collapsedDir is a chain of directories on the remote shown as a
+// single directory by --vfs-collapse-dirs
+type collapsedDir struct {
+	remote string   // the deepest directory in the chain
+	chain  []string // the directories in the chain above remote, top first
+}
+
+// collapseMapper shows chains of directories which each have a single
+// child directory and nothing else as one directory, so a/b/c/file is
+// shown as a-b-c/file.
+//
+// The chains are found when the parent directory is listed and are
+// remembered so the VFS paths can be mapped back to the remote. Names
+// are never parsed, so directories with the separator in their names
+// are created and renamed with the name as given.
+type collapseMapper struct {
+	sep       string
+	mu        sync.Mutex
+	collapsed map[string]collapsedDir // VFS path of each collapsed directory
+	remotes   map[string]string       // remote path of each directory in a chain to its VFS path
+}
+
+// newCollapseMapper makes a collapseMapper joining the names with sep
+func newCollapseMapper(sep string) *collapseMapper {
+	return &collapseMapper{
+		sep:       sep,
+		collapsed: make(map[string]collapsedDir),
+		remotes:   make(map[string]string),
+	}
+}
+
+// String returns a description of the mapping
+//
+// This includes the separator as it is used to tell remotes collapsed
+// with different separators apart.
+func (m *collapseMapper) String() string {
+	return fmt.Sprintf("directories collapsed with %q", m.sep)
+}
+
+// toRemote converts a VFS path into a path on the remote using the
+// deepest collapsed directory it is in
+func (m *collapseMapper) toRemote(name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dir := name; dir != ""; dir = parentDir(dir) {
+		if c, ok := m.collapsed[dir]; ok {
+			return c.remote + name[len(dir):]
+		}
+	}
+	return name
+}
+
+// toVFS converts a path on the remote into a VFS path using the
+// deepest chain it is in.
+//
+// Paths inside the upper directories of a chain can only come from
+// changes made outside the VFS. They are mapped to the collapsed
+// directory so it is read again.
+func (m *collapseMapper) toVFS(remote string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dir := remote; dir != ""; dir = parentDir(dir) {
+		if name, ok := m.remotes[dir]; ok {
+			if m.collapsed[name].remote != dir {
+				return name
+			}
+			return name + remote[len(dir):]
+		}
+	}
+	return remote
+}
+
+// parentDir returns the parent of the non empty path p or "" for the root
+func parentDir(p string) string {
+	p = path.Dir(p)
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+// listDir collapses the chains below each directory in entries, the
+// listing of the VFS directory dir
+func (m *collapseMapper) listDir(ctx context.Context, f fs.Fs, dir string, entries fs.DirEntries) (fs.DirEntries, error) {
+	m.mu.Lock()
+	for name := range m.collapsed {
+		if parentDir(name) == dir {
+			m._forget(name)
+		}
+	}
+	m.mu.Unlock()
+
+	seen := make(map[string]struct{}, len(entries))
+	remoteDir, haveDirs := "", false
+	for _, entry := range entries {
+		seen[path.Base(entry.Remote())] = struct{}{}
+		if _, ok := entry.(fs.Directory); ok {
+			remoteDir, haveDirs = parentDir(entry.Remote()), true
+		}
+	}
+	if !haveDirs {
+		return entries, nil
+	}
+	children, err := listChildren(ctx, f, remoteDir)
+	if err != nil {
+		fs.Debugf(remoteDir, "Not collapsing directories: %v", err)
+		return entries, nil
+	}
+	for i, entry := range entries {
+		d, ok := entry.(fs.Directory)
+		if !ok {
+			continue
+		}
+		var chain []string
+		leaves := []string{path.Base(d.Remote())}
+		for {
+			c := children[d.Remote()]
+			if c.n != 1 || c.dir == nil {
+				break
+			}
+			child := c.dir
+			chain = append(chain, d.Remote())
+			leaves = append(leaves, path.Base(child.Remote()))
+			d = child
+		}
+		if len(chain) == 0 {
+			continue
+		}
+		leaf := strings.Join(leaves, m.sep)
+		if _, found := seen[leaf]; found {
+			fs.Logf(d, "Not collapsing directory as %q exists already", leaf)
+			continue
+		}
+		seen[leaf] = struct{}{}
+		name := path.Join(dir, leaf)
+		m.mu.Lock()
+		m.collapsed[name] = collapsedDir{remote: d.Remote(), chain: chain}
+		m.remotes[d.Remote()] = name
+		for _, remote := range chain {
+			m.remotes[remote] = name
+		}
+		m.mu.Unlock()
+		entries[i] = d
+	}
+	return entries, nil
+}
+
+// dirChildren describes the contents of a directory on the remote
+type dirChildren struct {
+	n   int          // number of entries in the directory
+	dir fs.Directory // a directory in the directory - the only entry if n is 1
+}
+
+// listChildren lists everything below the remote directory dir with
+// a single recursive listing and returns the contents of each
+// directory in it.
+func listChildren(ctx context.Context, f fs.Fs, dir string) (map[string]dirChildren, error) {
+	children := make(map[string]dirChildren)
+	err := walk.ListR(ctx, f, dir, true, -1, walk.ListAll, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			parent := parentDir(entry.Remote())
+			c := children[parent]
+			c.n++
+			if d, ok := entry.(fs.Directory); ok {
+				c.dir = d
+			}
+			children[parent] = c
+		}
+		return nil
+	})
+	return children, err
+}
+
+// removedDir removes the now empty directories above a collapsed
+// directory which has been removed or moved away
+func (m *collapseMapper) removedDir(ctx context.Context, f fs.Fs, dir string) {
+	m.mu.Lock()
+	c, ok := m.collapsed[dir]
+	if ok {
+		m._forget(dir)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	for i := len(c.chain) - 1; i >= 0; i-- {
+		err := f.Rmdir(ctx, c.chain[i])
+		if err != nil {
+			fs.Debugf(c.chain[i], "Not removing directory above collapsed directory %q: %v", dir, err)
+			return
+		}
+	}
+}
+
+// _forget the collapsed directory name and those inside it
+//
+// call with the lock held
+func (m *collapseMapper) _forget(name string) {
+	for other, c := range m.collapsed {
+		if other != name && !strings.HasPrefix(other, name+"/") {
+			continue
+		}
+		delete(m.remotes, c.remote)
+		for _, remote := range c.chain {
+			delete(m.remotes, remote)
+		}
+		delete(m.collapsed, other)
+	}
+}
+
+// Check the interfaces are satisfied
+var (
+	_ pathMapper = (*collapseMapper)(nil)
+	_ dirLister  = (*collapseMapper)(nil)
+	_ dirRemover = (*collapseMapper)(nil)
+)
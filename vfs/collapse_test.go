@@ -0,0 +1,110 @@
+package vfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVFSCollapseDirs(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CollapseDirs = true
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+
+	file1 := r.WriteObject(ctx, "a/b/c/file1", "file1 contents", t1)
+	file2 := r.WriteObject(ctx, "a/b/c/d/e/file2", "file2 contents", t1)
+	file3 := r.WriteObject(ctx, "x/file3", "file3 contents", t1)
+	file4 := r.WriteObject(ctx, "x/y/file4", "file4 contents", t1)
+	r.CheckRemoteItems(t, file1, file2, file3, file4)
+
+	// Listed with the chains collapsed
+	nodes, err := vfs.ReadDir("")
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "a-b-c", nodes[0].Name())
+	assert.Equal(t, "x", nodes[1].Name(), "directories with files in aren't collapsed")
+	nodes, err = vfs.ReadDir("a-b-c")
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "d-e", nodes[0].Name())
+	assert.Equal(t, "file1", nodes[1].Name())
+	_, err = vfs.Stat("a")
+	assert.Equal(t, ENOENT, err)
+
+	// Read through the collapsed names
+	contents, err := vfs.ReadFile("a-b-c/d-e/file2")
+	require.NoError(t, err)
+	assert.Equal(t, "file2 contents", string(contents))
+
+	// Created and renamed inside the chain on the remote
+	require.NoError(t, vfs.WriteFile("a-b-c/d-e/file5", []byte("file5 contents"), 0600))
+	require.NoError(t, vfs.Rename("a-b-c/file1", "a-b-c/d-e/file1"))
+	file1 = fstest.NewItem("a/b/c/d/e/file1", "file1 contents", t1)
+	file5 := fstest.NewItem("a/b/c/d/e/file5", "file5 contents", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2, file3, file4, file5}, []string{"a", "a/b", "a/b/c", "a/b/c/d", "a/b/c/d/e", "x", "x/y"}, fs.ModTimeNotSupported)
+
+	// Renaming a collapsed directory moves the deepest directory
+	// and removes the empty ones above it
+	require.NoError(t, vfs.Rename("a-b-c/d-e", "moved"))
+	file1 = fstest.NewItem("moved/file1", "file1 contents", t1)
+	file2 = fstest.NewItem("moved/file2", "file2 contents", t1)
+	file5 = fstest.NewItem("moved/file5", "file5 contents", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2, file3, file4, file5}, []string{"a", "a/b", "a/b/c", "moved", "x", "x/y"}, fs.ModTimeNotSupported)
+
+	// Removing a collapsed directory removes the whole chain
+	require.NoError(t, vfs.Remove("a-b-c"))
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2, file3, file4, file5}, []string{"moved", "x", "x/y"}, fs.ModTimeNotSupported)
+}
+
+func TestVFSCollapseDirsIdentity(t *testing.T) {
+	r := fstest.NewRun(t)
+	newVFS := func(collapse bool, sep string) *VFS {
+		opt := vfscommon.Opt
+		opt.CollapseDirs = collapse
+		opt.CollapseDirsSeparator = sep
+		vfs := New(r.Fremote, &opt)
+		t.Cleanup(func() { cleanupVFS(t, vfs) })
+		return vfs
+	}
+	plain := newVFS(false, "-")
+	vfs1 := newVFS(true, "-")
+	vfs2 := newVFS(true, "_")
+
+	// Each separator has its own identity so the caches keyed on
+	// it aren't shared with the uncollapsed remote or each other
+	assert.NotEqual(t, fs.ConfigString(plain.Fs()), fs.ConfigString(vfs1.Fs()))
+	assert.NotEqual(t, fs.ConfigString(vfs1.Fs()), fs.ConfigString(vfs2.Fs()))
+	assert.Equal(t, fs.ConfigString(vfs1.Fs()), fs.ConfigString(newVFS(true, "-").Fs()))
+}
+
+func TestCollapseListChildren(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx := context.Background()
+
+	file1 := r.WriteObject(ctx, "top/a/b/file1", "file1 contents", t1)
+	file2 := r.WriteObject(ctx, "top/x/file2", "file2 contents", t1)
+	file3 := r.WriteObject(ctx, "top/x/y/file3", "file3 contents", t1)
+	r.CheckRemoteItems(t, file1, file2, file3)
+
+	children, err := listChildren(ctx, r.Fremote, "top")
+	require.NoError(t, err)
+	counts := map[string]int{}
+	for dir, c := range children {
+		counts[dir] = c.n
+	}
+	assert.Equal(t, map[string]int{
+		"top":     2,
+		"top/a":   1,
+		"top/a/b": 1,
+		"top/x":   2,
+		"top/x/y": 1,
+	}, counts)
+	assert.Equal(t, "top/a/b", children["top/a"].dir.Remote())
+	assert.Nil(t, children["top/a/b"].dir)
+}
@@ -0,0 +1,65 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// revalidate reads the object for the file from the remote as it is
+// opened if --vfs-consistency needs it, so changes made elsewhere are
+// seen.
+//
+// Files with local changes aren't checked as they are newer than the
+// remote. With close-to-open files which are open already aren't
+// checked either so all the handles see the same data.
+//
+// It returns ENOENT if the file is being opened for read only and it
+// has been removed from the remote.
+func (f *File) revalidate(write bool) error {
+	vfs := f.VFS()
+	consistency := vfs.Opt.Consistency
+	if consistency == vfscommon.ConsistencyEventual {
+		return nil
+	}
+	f.mu.RLock()
+	o, d, writing := f.o, f.d, f._writingInProgress()
+	cachePath := f._cachePath()
+	f.mu.RUnlock()
+	if writing || d.versions != versionsNone || o.Fs() == object.MemoryFs {
+		// being written, an old version or a virtual file
+		return nil
+	}
+	if vfs.cache != nil {
+		if vfs.cache.DirtyItem(cachePath) != nil {
+			return nil
+		}
+		if consistency == vfscommon.ConsistencyCloseToOpen && vfs.cache.InUse(cachePath) {
+			return nil
+		}
+	}
+
+	ctx := context.TODO()
+	newO, err := d.Fs().NewObject(ctx, o.Remote())
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		if write {
+			return nil
+		}
+		fs.Debugf(o, "File has been removed from the remote")
+		return ENOENT
+	} else if err != nil {
+		fs.Debugf(o, "Failed to check file against the remote: %v", err)
+		return nil
+	}
+	if vfscommon.FingerprintsEqual(fs.Fingerprint(ctx, newO, true), fs.Fingerprint(ctx, o, true), time.Duration(vfs.Opt.ClockSkewTolerance)) {
+		return nil
+	}
+	fs.Debugf(o, "File has changed on the remote")
+	f.setObjectNoUpdate(newO)
+	f.setSize(newO.Size())
+	return nil
+}
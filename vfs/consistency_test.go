@@ -0,0 +1,78 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileOpenConsistency(t *testing.T) {
+	const (
+		data1 = "data1"
+		data2 = "data1 changed"
+		data3 = "data1 changed again"
+	)
+	for _, test := range []struct {
+		consistency vfscommon.Consistency
+		wantOpen    string // data seen when opened after a change
+		wantInUse   string // data seen when opened after a change while open, if set
+		wantClosed  string // data seen when opened again after closing, if set
+	}{
+		{consistency: vfscommon.ConsistencyEventual, wantOpen: data1},
+		{consistency: vfscommon.ConsistencyCloseToOpen, wantOpen: data2, wantInUse: data2, wantClosed: data3},
+		{consistency: vfscommon.ConsistencyStrong, wantOpen: data2, wantInUse: data3, wantClosed: data3},
+	} {
+		t.Run(test.consistency.String(), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.CacheMode = vfscommon.CacheModeFull
+			opt.Consistency = test.consistency
+			r, vfs := newTestVFSOpt(t, &opt)
+			ctx := context.Background()
+
+			file1 := r.WriteObject(ctx, "file1", data1, t1)
+			r.CheckRemoteItems(t, file1)
+			data, err := vfs.ReadFile("file1")
+			require.NoError(t, err)
+			assert.Equal(t, data1, string(data))
+
+			size := func() int64 {
+				node, err := vfs.Stat("file1")
+				require.NoError(t, err)
+				return node.Size()
+			}
+
+			r.WriteObject(ctx, "file1", data2, t2)
+			fd, err := vfs.OpenFile("file1", os.O_RDONLY, 0)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(test.wantOpen)), size())
+			_, err = fd.Read(make([]byte, 1))
+			require.NoError(t, err)
+
+			r.WriteObject(ctx, "file1", data3, t3)
+			fd2, err := vfs.OpenFile("file1", os.O_RDONLY, 0)
+			require.NoError(t, err)
+			if test.wantInUse != "" {
+				assert.Equal(t, int64(len(test.wantInUse)), size())
+			}
+			require.NoError(t, fd2.Close())
+			require.NoError(t, fd.Close())
+
+			if test.wantClosed != "" {
+				data, err = vfs.ReadFile("file1")
+				require.NoError(t, err)
+				assert.Equal(t, test.wantClosed, string(data))
+
+				// Files removed from the remote can't be opened
+				o, err := r.Fremote.NewObject(ctx, "file1")
+				require.NoError(t, err)
+				require.NoError(t, o.Remove(ctx))
+				_, err = vfs.OpenFile("file1", os.O_RDONLY, 0)
+				assert.Equal(t, ENOENT, err)
+			}
+		})
+	}
+}
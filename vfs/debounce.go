@@ -0,0 +1,73 @@
+package vfs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// notifyKey identifies the changes to one path which are coalesced
+type notifyKey struct {
+	path      string
+	entryType fs.EntryType
+}
+
+// notifyDebouncer coalesces change notifications for the same path
+// which arrive within --vfs-notify-debounce of each other into one
+// invalidation which is applied once they have stopped.
+type notifyDebouncer struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	notify  func(relativePath string, entryType fs.EntryType)
+	pending map[notifyKey]*time.Timer
+}
+
+// newNotifyDebouncer makes a notifyDebouncer which calls notify once
+// there have been no changes to a path for delay
+func newNotifyDebouncer(delay time.Duration, notify func(relativePath string, entryType fs.EntryType)) *notifyDebouncer {
+	return &notifyDebouncer{
+		delay:   delay,
+		notify:  notify,
+		pending: make(map[notifyKey]*time.Timer),
+	}
+}
+
+// add a change notification, restarting the quiet period for the path
+// if it has changed already
+func (nd *notifyDebouncer) add(relativePath string, entryType fs.EntryType) {
+	key := notifyKey{path: relativePath, entryType: entryType}
+	nd.mu.Lock()
+	defer nd.mu.Unlock()
+	if nd.pending == nil {
+		return // stopped
+	}
+	if timer, found := nd.pending[key]; found && timer.Stop() {
+		timer.Reset(nd.delay)
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(nd.delay, func() {
+		nd.mu.Lock()
+		current := nd.pending[key] == timer
+		if current {
+			delete(nd.pending, key)
+		}
+		nd.mu.Unlock()
+		// if the timer was replaced the replacement will notify
+		if current {
+			nd.notify(relativePath, entryType)
+		}
+	})
+	nd.pending[key] = timer
+}
+
+// stop discards the pending changes
+func (nd *notifyDebouncer) stop() {
+	nd.mu.Lock()
+	defer nd.mu.Unlock()
+	for _, timer := range nd.pending {
+		timer.Stop()
+	}
+	nd.pending = nil
+}
@@ -0,0 +1,48 @@
+package vfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVFSNotifyDebounce(t *testing.T) {
+	const debounce = 200 * time.Millisecond
+	opt := vfscommon.Opt
+	opt.NotifyDebounce = fs.Duration(debounce)
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+
+	r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	node, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	dir := node.(*Dir)
+	_, err = dir.ReadDirAll()
+	require.NoError(t, err)
+
+	read := func() bool {
+		dir.mu.RLock()
+		defer dir.mu.RUnlock()
+		return !dir.read.IsZero()
+	}
+	require.True(t, read())
+
+	// Repeated changes keep putting the invalidation off
+	start := time.Now()
+	for time.Since(start) < 2*debounce {
+		vfs.changeNotify("dir/file1", fs.EntryObject)
+		assert.True(t, read(), "invalidated before the changes stopped")
+		time.Sleep(debounce / 4)
+	}
+
+	// Then it is invalidated once they stop
+	assert.Eventually(t, func() bool { return !read() }, 5*time.Second, 10*time.Millisecond)
+	vfs.debouncer.mu.Lock()
+	assert.Empty(t, vfs.debouncer.pending)
+	vfs.debouncer.mu.Unlock()
+}
@@ -0,0 +1,168 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/sync/errgroup"
+)
+
+// deleteBatcher holds back the objects deleted through the VFS for
+// --vfs-delete-batch-window and then deletes them together, so that
+// removing lots of files quickly, for example clearing a directory,
+// is done in one go. Backends which can delete lots of objects in one
+// request do so, otherwise --checkers deletes are run at once.
+//
+// The methods apart from add may be called on a nil *deleteBatcher
+// which does nothing.
+type deleteBatcher struct {
+	f       fs.Fs               // Fs the objects are deleted from
+	window  time.Duration       // how long to wait before deleting
+	failed  func(remote string) // called for each object which couldn't be deleted
+	flushMu sync.Mutex          // held while deleting so flushes wait for deletes in progress
+	mu      sync.Mutex
+	pending map[string]fs.Object // objects waiting to be deleted by path
+	timer   *time.Timer          // deletes the pending objects when the window is over
+}
+
+// newDeleteBatcher makes a deleteBatcher which waits for window
+// before deleting the objects from f, calling failed with the path of
+// each object it couldn't delete
+func newDeleteBatcher(f fs.Fs, window time.Duration, failed func(remote string)) *deleteBatcher {
+	return &deleteBatcher{
+		f:       f,
+		window:  window,
+		failed:  failed,
+		pending: make(map[string]fs.Object),
+	}
+}
+
+// add o to the objects waiting to be deleted
+func (b *deleteBatcher) add(o fs.Object) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[o.Remote()] = o
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+}
+
+// isPending returns true if the object at remote is waiting to be
+// deleted so shouldn't be shown in directory listings
+func (b *deleteBatcher) isPending(remote string) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, found := b.pending[remote]
+	return found
+}
+
+// flushMatching deletes the pending objects whose paths match and
+// waits for them to be deleted
+func (b *deleteBatcher) flushMatching(match func(remote string) bool) {
+	if b == nil {
+		return
+	}
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	var objs []fs.Object
+	b.mu.Lock()
+	for remote, o := range b.pending {
+		if match(remote) {
+			objs = append(objs, o)
+			delete(b.pending, remote)
+		}
+	}
+	if len(b.pending) == 0 && b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mu.Unlock()
+	if len(objs) == 0 {
+		return
+	}
+	fs.Debugf(nil, "vfs: deleting batch of %d files", len(objs))
+	for _, o := range b.remove(context.TODO(), objs) {
+		b.failed(o.Remote())
+	}
+}
+
+// remove deletes objs from the remote, returning the ones which
+// couldn't be deleted
+func (b *deleteBatcher) remove(ctx context.Context, objs []fs.Object) (failed []fs.Object) {
+	if deleteObjects := b.f.Features().DeleteObjects; deleteObjects != nil {
+		// Find the objects on the remote for the ones read
+		// from directory listings kept on disk
+		for i, o := range objs {
+			if indexed, ok := o.(*indexedObject); ok {
+				if obj, err := indexed.object(ctx); err == nil {
+					objs[i] = obj
+				}
+			}
+		}
+		err := deleteObjects(ctx, objs)
+		if err == nil {
+			return nil
+		}
+		fs.Errorf(b.f, "vfs: failed to delete batch of %d files: %v", len(objs), err)
+		// Find the ones which are still there
+		for _, o := range objs {
+			_, err := b.f.NewObject(ctx, o.Remote())
+			if !errors.Is(err, fs.ErrorObjectNotFound) {
+				failed = append(failed, o)
+			}
+		}
+		return failed
+	}
+	var (
+		mu sync.Mutex
+		g  errgroup.Group
+	)
+	g.SetLimit(fs.GetConfig(ctx).Checkers)
+	for _, o := range objs {
+		g.Go(func() error {
+			if err := o.Remove(ctx); err != nil {
+				fs.Errorf(o, "vfs: failed to delete: %v", err)
+				mu.Lock()
+				failed = append(failed, o)
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return failed
+}
+
+// flush deletes all the pending objects
+func (b *deleteBatcher) flush() {
+	b.flushMatching(func(remote string) bool {
+		return true
+	})
+}
+
+// flushFile deletes the pending object at remote if any, so a new
+// file or directory can be made there
+func (b *deleteBatcher) flushFile(remote string) {
+	b.flushMatching(func(pendingRemote string) bool {
+		return pendingRemote == remote || pendingRemote == remote+fs.LinkSuffix
+	})
+}
+
+// flushDir deletes the pending objects in dir and its subdirectories
+// so it can be removed or renamed
+func (b *deleteBatcher) flushDir(dir string) {
+	if dir == "" {
+		b.flush()
+		return
+	}
+	b.flushMatching(func(remote string) bool {
+		return strings.HasPrefix(remote, dir+"/")
+	})
+}
@@ -0,0 +1,169 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVFSDeleteBatch(t *testing.T) {
+	ctx := context.Background()
+	opt := vfscommon.Opt
+	opt.DeleteBatchWindow = fs.Duration(time.Hour)
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1", t1)
+	file2 := r.WriteObject(ctx, "dir/file2", "file2", t1)
+	file3 := r.WriteObject(ctx, "file3", "file3", t1)
+
+	require.NoError(t, vfs.Remove("dir/file1"))
+	require.NoError(t, vfs.Remove("dir/file2"))
+	require.NoError(t, vfs.Remove("file3"))
+
+	// The files are gone from the VFS but not yet from the remote
+	_, err := vfs.Stat("file3")
+	assert.Equal(t, ENOENT, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	// Even if the directory is read again
+	vfs.FlushDirCache()
+	_, err = vfs.Stat("dir/file1")
+	assert.Equal(t, ENOENT, err)
+
+	// Creating a file at the path deletes the old one first
+	require.NoError(t, vfs.WriteFile("file3", []byte("new3"), 0666))
+	vfs.WaitForWriters(waitForWritersDelay)
+	file3 = fstest.NewItem("file3", "new3", time.Now())
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2, file3}, []string{"dir"}, fs.ModTimeNotSupported)
+
+	// Removing the directory deletes the files in it
+	require.NoError(t, vfs.Remove("dir"))
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file3}, []string{}, fs.ModTimeNotSupported)
+}
+
+func TestVFSDeleteBatchWindow(t *testing.T) {
+	ctx := context.Background()
+	opt := vfscommon.Opt
+	opt.DeleteBatchWindow = fs.Duration(10 * time.Millisecond)
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	r.WriteObject(ctx, "file1", "file1", t1)
+	r.WriteObject(ctx, "file2", "file2", t1)
+
+	require.NoError(t, vfs.Remove("file1"))
+	require.NoError(t, vfs.Remove("file2"))
+
+	// The files are deleted once the window is over
+	assert.Eventually(t, func() bool {
+		_, err1 := r.Fremote.NewObject(ctx, "file1")
+		_, err2 := r.Fremote.NewObject(ctx, "file2")
+		return err1 == fs.ErrorObjectNotFound && err2 == fs.ErrorObjectNotFound
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.False(t, vfs.deletes.isPending("file1"))
+}
+
+// failRemoveObject is an object which can't be removed
+type failRemoveObject struct {
+	fs.Object
+}
+
+// Remove fails
+func (o failRemoveObject) Remove(ctx context.Context) error {
+	return errors.New("failed to remove")
+}
+
+// batchDeleteFs is an Fs which deletes objects in batches but only
+// manages to delete the first of each batch
+type batchDeleteFs struct {
+	fs.Fs
+	batches int
+}
+
+// Features returns the optional features with DeleteObjects set
+func (f *batchDeleteFs) Features() *fs.Features {
+	features := *f.Fs.Features()
+	features.DeleteObjects = func(ctx context.Context, objs []fs.Object) error {
+		f.batches++
+		if err := objs[0].Remove(ctx); err != nil {
+			return err
+		}
+		return errors.New("failed to delete the rest")
+	}
+	return &features
+}
+
+func TestDeleteBatcherFailed(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	r.WriteObject(ctx, "file1", "file1", t1)
+	file2 := r.WriteObject(ctx, "file2", "file2", t1)
+	file3 := r.WriteObject(ctx, "file3", "file3", t1)
+	object := func(remote string) fs.Object {
+		o, err := r.Fremote.NewObject(ctx, remote)
+		require.NoError(t, err)
+		return o
+	}
+
+	// Objects are removed one by one if the backend can't
+	// delete them together
+	var failed []string
+	b := newDeleteBatcher(r.Fremote, time.Hour, func(remote string) {
+		failed = append(failed, remote)
+	})
+	b.add(object("file1"))
+	b.add(failRemoveObject{object("file2")})
+	b.flush()
+	assert.Equal(t, []string{"file2"}, failed)
+	r.CheckRemoteItems(t, file2, file3)
+
+	// Otherwise they are deleted together and the ones left are
+	// found
+	failed = nil
+	f := &batchDeleteFs{Fs: r.Fremote}
+	b = newDeleteBatcher(f, time.Hour, func(remote string) {
+		failed = append(failed, remote)
+	})
+	b.add(object("file2"))
+	b.add(object("file3"))
+	b.flush()
+	assert.Equal(t, 1, f.batches)
+	require.Len(t, failed, 1)
+	left := file2
+	if failed[0] == "file3" {
+		left = file3
+	}
+	r.CheckRemoteItems(t, left)
+}
+
+func TestVFSDeleteBatchFailed(t *testing.T) {
+	ctx := context.Background()
+	opt := vfscommon.Opt
+	opt.DeleteBatchWindow = fs.Duration(time.Hour)
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1", t1)
+	_, err := vfs.ReadDir("dir")
+	require.NoError(t, err)
+	require.NoError(t, vfs.Remove("dir/file1"))
+	_, err = vfs.Stat("dir/file1")
+	assert.Equal(t, ENOENT, err)
+
+	// Make the delete fail
+	vfs.deletes.mu.Lock()
+	vfs.deletes.pending["dir/file1"] = failRemoveObject{vfs.deletes.pending["dir/file1"]}
+	vfs.deletes.mu.Unlock()
+	vfs.deletes.flush()
+
+	// The file is shown again
+	r.CheckRemoteItems(t, file1)
+	node, err := vfs.Stat("dir/file1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), node.Size())
+}
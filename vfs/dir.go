@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"path"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -14,35 +16,47 @@ import (
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/dirtree"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/list"
 	"github.com/rclone/rclone/fs/log"
 	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/walk"
+	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"golang.org/x/text/unicode/norm"
 )
 
 // Dir represents a directory entry
 type Dir struct {
-	vfs          *VFS        // read only
-	inode        uint64      // read only: inode number
-	f            fs.Fs       // read only
-	cleanupTimer *time.Timer // read only: timer to call cacheCleanup
-
-	mu      sync.RWMutex // protects the following
-	parent  *Dir         // parent, nil for root
-	path    string
-	entry   fs.Directory
-	read    time.Time         // time directory entry last read
-	items   map[string]Node   // directory entries - can be empty but not nil
-	virtual map[string]vState // virtual directory entries - may be nil
-	sys     atomic.Value      // user defined info to be attached here
+	vfs          *VFS         // read only
+	inode        uint64       // read only: inode number
+	f            fs.Fs        // read only
+	cleanupTimer *time.Timer  // read only: timer to call cacheCleanup
+	versions     versionsKind // read only: set if this is a .versions pseudo directory
+	recent       bool         // read only: set if this is the .recent pseudo directory
+
+	mu           sync.RWMutex // protects the following
+	parent       *Dir         // parent, nil for root
+	path         string
+	entry        fs.Directory
+	read         time.Time         // time directory entry last read
+	jitter       time.Duration     // random time added to DirCacheTime for this read
+	items        map[string]Node   // directory entries - can be empty but not nil
+	virtual      map[string]vState // virtual directory entries - may be nil
+	listed       bool              // set once the directory has been listed
+	listing      chan struct{}     // closed when the listing in progress finishes - nil if none
+	indexTried   bool              // set once the listing kept by --vfs-persist-dir-cache has been tried
+	unstable     map[string]int    // number of listings in a row a name has been added or removed in - may be nil
+	versionsNode *Dir              // the .versions pseudo directory - may be nil
+	recentNode   *Dir              // the .recent pseudo directory - may be nil
+	sys          atomic.Value      // user defined info to be attached here
 
 	modTimeMu sync.Mutex // protects the following
 	modTime   time.Time
 
 	_virtuals atomic.Int32 // number of virtual directory entries in this directory and children
+	pending   atomic.Bool  // set if creating the directory on the remote has been deferred by --vfs-lazy-mkdir
 }
 
 //go:generate stringer -type=vState
@@ -65,9 +79,12 @@ func newDir(vfs *VFS, f fs.Fs, parent *Dir, fsDir fs.Directory) *Dir {
 		entry:   fsDir,
 		path:    fsDir.Remote(),
 		modTime: fsDir.ModTime(context.TODO()),
-		inode:   newInode(),
+		inode:   vfs.inodes.get(fsDir.Remote()),
 		items:   make(map[string]Node),
 	}
+	if parent != nil && parent.versions == versionsRoot {
+		d.versions = versionsFile
+	}
 	// Set timer up like this to avoid race of d.cacheCleanup being called
 	// before d.cleanupTimer is assigned to
 	d.cleanupTimer = time.AfterFunc(time.Hour, d.cacheCleanup)
@@ -248,9 +265,16 @@ func (d *Dir) ForgetAll() (hasVirtual bool) {
 	// directory or any children
 	hasVirtual = d.hasVirtual()
 	if !hasVirtual {
+		for leaf, node := range d.items {
+			d.vfs.inodes.forget(node.Inode(), path.Join(d.path, leaf))
+		}
 		d.read = time.Time{}
 		d.items = make(map[string]Node)
+		d.listed = false
+		d.indexTried = false
+		d.unstable = nil
 		d.cleanupTimer.Stop()
+		d.vfs.dirLRU.remove(d)
 	} else {
 		d.cleanupTimer.Reset(time.Duration(d.vfs.Opt.DirCacheTime * 2))
 	}
@@ -268,11 +292,24 @@ func (d *Dir) forgetDirPath(relativePath string) {
 	if dir == nil {
 		return
 	}
+	dir.forgetIndex()
 	dir.ForgetAll()
 }
 
+// forgetIndex removes the listings kept by --vfs-persist-dir-cache
+// for the directory and its cached subdirectories
+func (d *Dir) forgetIndex() {
+	if d.vfs.dirIndex == nil {
+		return
+	}
+	d.walk(func(dir *Dir) {
+		d.vfs.dirIndex.remove(dir.path)
+	})
+}
+
 // invalidateDir invalidates the directory cache for absPath relative to the root
 func (d *Dir) invalidateDir(absPath string) {
+	d.vfs.dirIndex.remove(absPath)
 	node := d.vfs.root.cachedNode(absPath)
 	if dir, ok := node.(*Dir); ok {
 		dir.mu.Lock()
@@ -293,6 +330,10 @@ func (d *Dir) changeNotify(relativePath string, entryType fs.EntryType) {
 	d.mu.RLock()
 	absPath := path.Join(d.path, relativePath)
 	d.mu.RUnlock()
+	if entryType == fs.EntryObject {
+		d.vfs.resolveDirtyConflict(absPath)
+		d.vfs.recent.touch(absPath)
+	}
 	d.invalidateDir(vfscommon.FindParent(absPath))
 	if entryType == fs.EntryDirectory {
 		d.invalidateDir(absPath)
@@ -363,10 +404,25 @@ func (d *Dir) _age(when time.Time) (age time.Duration, stale bool) {
 		return age, true
 	}
 	age = when.Sub(d.read)
-	stale = age > time.Duration(d.vfs.Opt.DirCacheTime)
+	stale = age > time.Duration(d.vfs.Opt.DirCacheTime)+d.jitter
 	return
 }
 
+// _setRead records that the directory contents were read at when.
+//
+// If --vfs-dir-cache-jitter is set a random time up to it is added to
+// the time the contents are cached for, so directories read together
+// don't all expire together.
+//
+// call with d.mu held
+func (d *Dir) _setRead(when time.Time) {
+	d.read = when
+	d.jitter = 0
+	if jitter := time.Duration(d.vfs.Opt.DirCacheJitter); jitter > 0 {
+		d.jitter = rand.N(jitter)
+	}
+}
+
 // renameTree renames the directories under this directory
 //
 // path should be the desired path
@@ -380,6 +436,7 @@ func (d *Dir) renameTree(dirPath string) {
 		delete(d.parent.items, name(d.path))
 		d.path = dirPath
 		d.parent.items[name(d.path)] = d
+		d.vfs.inodes.rename(d.inode, dirPath)
 		d.entry = fs.NewDirCopy(context.TODO(), d.entry).SetRemote(dirPath)
 	}
 
@@ -401,6 +458,7 @@ func (d *Dir) renameTree(dirPath string) {
 // Reset the directory to new state, discarding all the objects and
 // reading everything again
 func (d *Dir) rename(newParent *Dir, fsDir fs.Directory) {
+	d.forgetIndex()
 	d.ForgetAll()
 
 	d.modTimeMu.Lock()
@@ -412,9 +470,11 @@ func (d *Dir) rename(newParent *Dir, fsDir fs.Directory) {
 	d.entry = fsDir
 	d.path = fsDir.Remote()
 	newPath := d.path
+	d.vfs.inodes.rename(d.inode, newPath)
 	delete(d.parent.items, name(oldPath))
 	d.parent.items[name(d.path)] = d
 	d.read = time.Time{}
+	d.versionsNode = nil
 	d.mu.Unlock()
 
 	// Rename any remaining items in the tree that we couldn't forget
@@ -459,6 +519,10 @@ func (d *Dir) addObject(node Node) {
 	}
 	d.virtual[leaf] = vAdd
 	fs.Debugf(d.path, "Added virtual directory entry %v: %q", vAdd, leaf)
+	d.vfs.dirIndex.remove(d.path)
+	if node.IsDir() {
+		d.vfs.dirIndex.remove(path.Join(d.path, leaf))
+	}
 	d.mu.Unlock()
 }
 
@@ -506,6 +570,9 @@ func (d *Dir) AddVirtual(leaf string, size int64, isDir bool) {
 // from a remote directory listing.
 func (d *Dir) delObject(leaf string) {
 	d.mu.Lock()
+	if node, found := d.items[leaf]; found {
+		d.vfs.inodes.forget(node.Inode(), path.Join(d.path, leaf))
+	}
 	delete(d.items, leaf)
 	if d.virtual == nil {
 		d.virtual = make(map[string]vState)
@@ -515,6 +582,8 @@ func (d *Dir) delObject(leaf string) {
 	}
 	d.virtual[leaf] = vDel
 	fs.Debugf(d.path, "Added virtual directory entry %v: %q", vDel, leaf)
+	d.vfs.dirIndex.remove(d.path)
+	d.vfs.dirIndex.remove(path.Join(d.path, leaf))
 	d.mu.Unlock()
 }
 
@@ -531,20 +600,37 @@ func (d *Dir) DelVirtual(leaf string) {
 
 // read the directory and sets d.items - must be called with the lock held
 func (d *Dir) _readDir() error {
+	// Wait for any listing which is waiting to retry to finish
+	// rather than starting another one alongside it
+	for d.listing != nil {
+		listing := d.listing
+		d.mu.Unlock()
+		<-listing
+		d.mu.Lock()
+	}
 	when := time.Now()
 	if age, stale := d._age(when); stale {
 		if age != 0 {
 			fs.Debugf(d.path, "Re-reading directory (%v old)", age)
 		}
 	} else {
+		d.vfs.dirLRU.touch(d)
 		return nil
 	}
-	entries, err := list.DirSorted(context.TODO(), d.f, false, d.path)
+	if d._readDirFromIndex(when) {
+		return nil
+	}
+	entries, err := d._list(context.TODO())
 	if err == fs.ErrorDirNotFound {
 		// We treat directory not found as empty because we
 		// create directories on the fly
 	} else if err != nil {
-		return err
+		if d.vfs.Opt.ListRetries > 0 && d.listed && fserrors.ShouldRetry(err) {
+			// Carry on using the old listing and try again next time
+			fs.Errorf(d.path, "Failed to re-read directory - using cached listing: %v", err)
+			return nil
+		}
+		return d.vfs.mapError(err)
 	}
 
 	if d.vfs.Opt.BlockNormDupes { // do this only if requested, as it will have a performance hit
@@ -580,13 +666,67 @@ func (d *Dir) _readDir() error {
 	if err != nil {
 		return err
 	}
+	read := time.Now()
+	if d.versions == versionsNone && !d.recent {
+		d.vfs.recent.syncDir(d.path, entries)
+		d.vfs.dirIndex.save(d.path, entries, read)
+	}
 
-	d.read = time.Now()
+	d._setRead(read)
 	d.cleanupTimer.Reset(time.Duration(d.vfs.Opt.DirCacheTime * 2))
+	d.vfs.dirLRU.touch(d)
+	d._prefetch(entries)
 
 	return nil
 }
 
+// _list reads the entries of the directory from the remote, retrying
+// transient errors up to --vfs-list-retries times
+//
+// call with the lock held - it is released while waiting to retry
+// with d.listing set so no other listing is started meanwhile
+func (d *Dir) _list(ctx context.Context) (entries fs.DirEntries, err error) {
+	if d.vfs.snapshotErr != nil {
+		return nil, d.vfs.snapshotErr
+	}
+	retries, delay := d.vfs.Opt.ListRetries, time.Duration(d.vfs.Opt.ListRetryDelay)
+	for try := 1; ; try++ {
+		if d.versions != versionsNone {
+			entries, err = d._listVersions(ctx)
+		} else if d.recent {
+			entries, err = d._listRecent(ctx)
+		} else {
+			entries, err = list.DirSorted(ctx, d.f, false, d.path)
+		}
+		if err == nil || try > retries || !fserrors.ShouldRetry(err) {
+			return entries, err
+		}
+		fs.Debugf(d.path, "Directory listing failed - retry %d/%d in %v: %v", try, retries, delay, err)
+		if d.listing == nil {
+			d.listing = make(chan struct{})
+			defer func() {
+				close(d.listing)
+				d.listing = nil
+			}()
+		}
+		// Wait with the lock released so the directory can be used meanwhile
+		d.mu.Unlock()
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-d.vfs.ctx.Done():
+			err = d.vfs.ctx.Err()
+		}
+		timer.Stop()
+		d.mu.Lock()
+		if ctx.Err() != nil || d.vfs.ctx.Err() != nil {
+			return nil, err
+		}
+	}
+}
+
 // update d.items for each dir in the DirTree below this one and
 // set the last read time - must be called with the lock held
 func (d *Dir) _readDirFromDirTree(dirTree dirtree.DirTree, when time.Time) error {
@@ -623,6 +763,10 @@ func (d *Dir) _purgeVirtual() {
 	for name, virtualState := range d.virtual {
 		switch virtualState {
 		case vAddDir:
+			if dir, ok := d.items[name].(*Dir); ok && dir.pending.Load() {
+				// leave directories which haven't been created yet
+				continue
+			}
 			if canHaveEmptyDirectories {
 				// if remote can have empty directories then a
 				// new dir will be read in the listing
@@ -699,8 +843,11 @@ func (mv manageVirtuals) add(d *Dir, name string) bool {
 // This should be called after the directory entry is read to update d.items
 // with virtual entries
 //
+// changes should be the names which were added or removed in this
+// listing as recorded by d._stable.
+//
 // must be called with the Dir lock held
-func (mv manageVirtuals) end(d *Dir) {
+func (mv manageVirtuals) end(d *Dir, changes map[string]int) {
 	// delete unused d.items
 	for name := range d.items {
 		if _, ok := mv[name]; !ok {
@@ -710,8 +857,11 @@ func (mv manageVirtuals) end(d *Dir) {
 			case vAddFile, vAddDir:
 				// virtually added so leave virtual item
 			default:
-				// otherwise delete it
-				delete(d.items, name)
+				// otherwise delete it if it has been gone long enough
+				if d._stable(name, changes) {
+					d.vfs.inodes.forget(d.items[name].Inode(), path.Join(d.path, name))
+					delete(d.items, name)
+				}
 			}
 		}
 	}
@@ -726,6 +876,65 @@ func (mv manageVirtuals) end(d *Dir) {
 			}
 		}
 	}
+	// names which didn't change in this listing start counting again
+	if len(changes) == 0 {
+		changes = nil
+	}
+	d.unstable = changes
+	d.listed = true
+}
+
+// _stable returns true if a name being added to or removed from the
+// directory in this listing should be applied to d.items.
+//
+// If --vfs-listing-stability-window is set then the change must be
+// seen in that many listings in a row before it is applied. This
+// smooths over backends which return inconsistent listings. The
+// first listing of the directory is always applied.
+//
+// Changes which aren't applied yet are recorded in changes.
+//
+// must be called with the Dir lock held
+func (d *Dir) _stable(name string, changes map[string]int) bool {
+	window := d.vfs.Opt.ListingStabilityWindow
+	if window <= 1 || !d.listed {
+		return true
+	}
+	n := d.unstable[name] + 1
+	if n >= window {
+		return true
+	}
+	fs.Debugf(path.Join(d.path, name), "Ignoring change in directory listing until seen %d more times", window-n)
+	changes[name] = n
+	return false
+}
+
+// reservedNameEncoder replaces the names and characters which can't
+// be used in a file system for --vfs-reserved-names encode
+const reservedNameEncoder = encoder.EncodeDot | encoder.EncodeZero
+
+// checkListedName checks the name of the entry at remote from a
+// directory listing, returning the name to show it with or false if
+// it shouldn't be shown.
+//
+// Names which can't be used in a file system, such as "." and "..",
+// are left out or encoded as set by --vfs-reserved-names.
+//
+// call with the lock held
+func (d *Dir) _checkListedName(remote, name string) (string, bool) {
+	if remote == "" {
+		return "", false // the root itself
+	}
+	if name != "" && name != "." && name != ".." && !strings.ContainsRune(name, 0) {
+		return name, true
+	}
+	if d.vfs.Opt.ReservedNames == vfscommon.ReservedNamesEncode && name != "" {
+		encoded := reservedNameEncoder.Encode(name)
+		fs.Logf(d.path, "vfs: showing entry %q with invalid name as %q", remote, encoded)
+		return encoded, true
+	}
+	fs.Logf(d.path, "vfs: hiding entry %q with invalid name %q", remote, name)
+	return "", false
 }
 
 // update d.items and if dirTree is not nil update each dir in the DirTree below this one and
@@ -733,21 +942,30 @@ func (mv manageVirtuals) end(d *Dir) {
 func (d *Dir) _readDirFromEntries(entries fs.DirEntries, dirTree dirtree.DirTree, when time.Time) error {
 	var err error
 	mv := d._newManageVirtuals()
+	changes := make(map[string]int)
 	for _, entry := range entries {
 		name := path.Base(entry.Remote())
-		if name == "." || name == ".." {
-			continue
-		}
 		if d.vfs.Opt.Links {
 			name, _ = strings.CutSuffix(name, fs.LinkSuffix)
 		}
+		name, ok := d._checkListedName(entry.Remote(), name)
+		if !ok {
+			continue
+		}
 		node := d.items[name]
 		if mv.add(d, name) {
 			continue
 		}
+		if node == nil && !d._stable(name, changes) {
+			continue
+		}
 		switch item := entry.(type) {
 		case fs.Object:
 			obj := item
+			// Don't show files which are waiting to be deleted
+			if d.vfs.deletes.isPending(obj.Remote()) {
+				continue
+			}
 			// Reuse old file value if it exists
 			if file, ok := node.(*File); node != nil && ok {
 				file.setObjectNoUpdate(obj)
@@ -768,8 +986,9 @@ func (d *Dir) _readDirFromEntries(entries fs.DirEntries, dirTree dirtree.DirTree
 				if err != nil {
 					dir.read = time.Time{}
 				} else {
-					dir.read = when
+					dir._setRead(when)
 					dir.cleanupTimer.Reset(time.Duration(d.vfs.Opt.DirCacheTime * 2))
+					d.vfs.dirLRU.touch(dir)
 				}
 			}
 			dir.mu.Unlock()
@@ -783,7 +1002,7 @@ func (d *Dir) _readDirFromEntries(entries fs.DirEntries, dirTree dirtree.DirTree
 		}
 		d.items[name] = node
 	}
-	mv.end(d)
+	mv.end(d, changes)
 	return nil
 }
 
@@ -793,6 +1012,9 @@ func (d *Dir) readDirTree() error {
 	f, path := d.f, d.path
 	d.mu.RUnlock()
 	when := time.Now()
+	if d.vfs.snapshotErr != nil {
+		return d.vfs.snapshotErr
+	}
 	fs.Debugf(path, "Reading directory tree")
 	dt, err := walk.NewDirTree(context.TODO(), f, path, false, -1)
 	if err != nil {
@@ -806,8 +1028,9 @@ func (d *Dir) readDirTree() error {
 		return err
 	}
 	fs.Debugf(d.path, "Reading directory tree done in %s", time.Since(when))
-	d.read = when
+	d._setRead(when)
 	d.cleanupTimer.Reset(time.Duration(d.vfs.Opt.DirCacheTime * 2))
+	d.vfs.dirLRU.touch(d)
 	return nil
 }
 
@@ -816,9 +1039,36 @@ func (d *Dir) readDir() error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.read = time.Time{}
+	d.indexTried = true
 	return d._readDir()
 }
 
+// refreshDir re-reads the directory from the remote now and returns
+// its entries.
+//
+// Unlike readDir every change in the listing is applied straight away
+// even if --vfs-listing-stability-window is set, and if the listing
+// fails the error is returned rather than carrying on with the cached
+// listing as --vfs-list-retries does.
+func (d *Dir) refreshDir() (items Nodes, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	listed, unstable := d.listed, d.unstable
+	d.read = time.Time{}
+	d.listed = false
+	d.indexTried = true
+	err = d._readDir()
+	if err != nil {
+		d.listed, d.unstable = listed, unstable
+		return nil, err
+	}
+	for _, item := range d.items {
+		items = append(items, item)
+	}
+	sort.Sort(items)
+	return items, nil
+}
+
 // jsonErrorf formats the string according to a format specifier and
 // returns the resulting string as a JSON blob with key "error"
 func jsonErrorf(format string, a ...any) []byte {
@@ -836,6 +1086,12 @@ func (d *Dir) statMetadata(leaf, baseLeaf string) (metaNode Node, err error) {
 	if err != nil {
 		return node, err
 	}
+	return d.metadataFile(leaf, node), nil
+}
+
+// metadataFile returns a read only file named leaf in d whose contents
+// are the metadata of node as JSON
+func (d *Dir) metadataFile(leaf string, node Node) *File {
 	// Read the metadata from the original entry into a JSON dump
 	entry := node.DirEntry()
 	var metadataDump []byte
@@ -856,12 +1112,32 @@ func (d *Dir) statMetadata(leaf, baseLeaf string) (metaNode Node, err error) {
 	}
 	// Make a memory based file with metadataDump in
 	remote := path.Join(d.path, leaf)
-	o := object.NewMemoryObject(remote, entry.ModTime(context.TODO()), metadataDump)
+	o := object.NewMemoryObject(remote, node.ModTime(), metadataDump)
 	f := newFile(d, d.path, o, leaf)
 	// Base the metadata inode number off the real file inode number
 	// to keep it constant
 	f.inode = node.Inode() ^ (1 << 63)
-	return f, nil
+	return f
+}
+
+// downloadingMarker returns a marker file for file if it is being
+// downloaded into the cache and --vfs-cache-downloading-suffix is set
+// or nil otherwise.
+//
+// The marker is an empty file named after file with the suffix added.
+func (d *Dir) downloadingMarker(file *File) Node {
+	suffix := d.vfs.Opt.CacheDownloadingSuffix
+	if suffix == "" || d.vfs.cache == nil || !d.vfs.cache.Downloading(file.Path()) {
+		return nil
+	}
+	leaf := file.Name() + suffix
+	dirPath := d.Path()
+	o := object.NewMemoryObject(path.Join(dirPath, leaf), file.ModTime(), nil)
+	f := newFile(d, dirPath, o, leaf)
+	// Base the marker inode number off the real file inode number
+	// to keep it constant
+	f.inode = file.Inode() ^ (1 << 62)
+	return f
 }
 
 // stat a single item in the directory
@@ -887,17 +1163,62 @@ func (d *Dir) stat(leaf string) (Node, error) {
 				return nil, err
 			}
 			// Add metadata file to directory as virtual object
-			d.addObject(node)
+			// unless the listings make them already, as they
+			// would stop the directory being removed
+			if !d.vfs.Opt.ExposeMetadata {
+				d.addObject(node)
+			}
 			return node, nil
 		}
 	}
 
+	// Look for the file showing the cache status
+	if !ok && d.isCacheStatusName(leaf) {
+		return d.cacheStatusFile(), nil
+	}
+
+	// Look for the pseudo directory showing old versions
+	if !ok && leaf == versionsDirName {
+		if versionsDir := d.versionsDir(); versionsDir != nil {
+			return versionsDir, nil
+		}
+	}
+
+	// Look for the pseudo directory showing recent files
+	if !ok && leaf == recentDirName {
+		if recentDir := d.recentDir(); recentDir != nil {
+			return recentDir, nil
+		}
+	}
+
+	// Look for a downloading marker
+	if !ok {
+		if baseLeaf, found := d.vfs.isDownloadingMarker(leaf); found {
+			d.mu.RLock()
+			file, isFile := d.items[baseLeaf].(*File)
+			d.mu.RUnlock()
+			if isFile {
+				if node := d.downloadingMarker(file); node != nil {
+					return node, nil
+				}
+			}
+		}
+	}
+
 	ci := fs.GetConfig(context.TODO())
 	normUnicode := !ci.NoUnicodeNormalization
 	normCase := ci.IgnoreCaseSync || d.vfs.Opt.CaseInsensitive
 	if !ok && (normUnicode || normCase) {
 		leafNormalized := operations.ToNormal(leaf, normUnicode, normCase) // this handles both case and unicode normalization
 		d.mu.Lock()
+		// Give up rather than scan a huge directory. The whole
+		// directory is skipped so the result doesn't depend on the
+		// order the entries are scanned in.
+		if maxScan := d.vfs.Opt.CaseInsensitiveMaxScan; maxScan > 0 && len(d.items) > maxScan {
+			fs.Debugf(d, "Not looking for a normalized match for %q in %d entries as --vfs-case-insensitive-max-scan is %d", leaf, len(d.items), maxScan)
+			d.mu.Unlock()
+			return nil, ENOENT
+		}
 		for name, node := range d.items {
 			if operations.ToNormal(name, normUnicode, normCase) == leafNormalized {
 				if ok {
@@ -939,13 +1260,16 @@ func (d *Dir) ModTime() time.Time {
 }
 
 // Size of the directory
+//
+// This is 0 unless --vfs-dir-sizes is set when it is the total size
+// of the objects in the directory and its subdirectories.
 func (d *Dir) Size() int64 {
-	return 0
+	return d.vfs.dirSize(d.Path())
 }
 
 // SetModTime sets the modTime for this dir
 func (d *Dir) SetModTime(modTime time.Time) error {
-	if d.vfs.Opt.ReadOnly {
+	if d.readOnly() {
 		return EROFS
 	}
 	d.modTimeMu.Lock()
@@ -1001,7 +1325,28 @@ func (d *Dir) Stat(name string) (node Node, err error) {
 }
 
 // ReadDirAll reads the contents of the directory sorted
+//
+// This is the listing shown to clients so at most
+// --vfs-max-dir-entries entries are returned.
 func (d *Dir) ReadDirAll() (items Nodes, err error) {
+	items, err = d.readDirAll(true)
+	if err != nil {
+		return nil, err
+	}
+	// Hide the entries over the limit
+	if limit := d.vfs.Opt.MaxDirEntries; limit > 0 && len(items) > limit {
+		fs.Logf(d, "Showing only the first %d of %d entries in the directory as --vfs-max-dir-entries is set", limit, len(items))
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// readDirAll reads all the contents of the directory sorted
+//
+// If client is set the pseudo entries shown to clients are added.
+// These can't be changed so are left out when the directory is being
+// changed, for example by RemoveAll.
+func (d *Dir) readDirAll(client bool) (items Nodes, err error) {
 	// fs.Debugf(d.path, "Dir.ReadDirAll")
 	d.mu.Lock()
 	err = d._readDir()
@@ -1014,6 +1359,46 @@ func (d *Dir) ReadDirAll() (items Nodes, err error) {
 		items = append(items, item)
 	}
 	d.mu.Unlock()
+	// Add markers for files being downloaded into the cache
+	if d.vfs.Opt.CacheDownloadingSuffix != "" {
+		names := make(map[string]struct{}, len(items))
+		for _, item := range items {
+			names[item.Name()] = struct{}{}
+		}
+		for _, item := range items {
+			if file, ok := item.(*File); ok {
+				marker := d.downloadingMarker(file)
+				if marker == nil {
+					continue
+				}
+				if _, found := names[marker.Name()]; !found {
+					items = append(items, marker)
+				}
+			}
+		}
+	}
+	// Add the metadata files
+	if client && d.vfs.Opt.ExposeMetadata {
+		items = d.addMetadataFiles(items)
+	}
+	// Add the file showing the cache status
+	if name := d.vfs.Opt.CacheStatusFile; client && d.isCacheStatusName(name) && !slices.ContainsFunc(items, func(item Node) bool {
+		return item.Name() == name
+	}) {
+		items = append(items, d.cacheStatusFile())
+	}
+	// Add the pseudo directory showing old versions
+	if versionsDir := d.versionsDir(); client && versionsDir != nil && !slices.ContainsFunc(items, func(item Node) bool {
+		return item.Name() == versionsDirName
+	}) {
+		items = append(items, versionsDir)
+	}
+	// Add the pseudo directory showing recent files
+	if recentDir := d.recentDir(); client && recentDir != nil && !slices.ContainsFunc(items, func(item Node) bool {
+		return item.Name() == recentDirName
+	}) {
+		items = append(items, recentDir)
+	}
 	sort.Sort(items)
 	// fs.Debugf(d.path, "Dir.ReadDirAll OK with %d entries", len(items))
 	return items, nil
@@ -1037,6 +1422,17 @@ func (d *Dir) Create(name string, flags int) (*File, error) {
 	// fs.Debugf(path, "Dir.Create")
 	// Return existing node if one exists
 	node, err := d.stat(name)
+	if err == nil && node.IsFile() && node.Name() != name {
+		// only found with a different case
+		switch d.vfs.Opt.CaseInsensitiveCreate {
+		case vfscommon.CaseCreateNew:
+			fs.Debugf(d, "Dir.Create: creating %q alongside %q as --vfs-case-insensitive-create is new", name, node.Name())
+			err = ENOENT
+		case vfscommon.CaseCreateError:
+			fs.Errorf(d, "Dir.Create: can't create %q as %q exists and --vfs-case-insensitive-create is error", name, node.Name())
+			return nil, EEXIST
+		}
+	}
 	switch err {
 	case ENOENT:
 		// not found, carry on
@@ -1052,9 +1448,13 @@ func (d *Dir) Create(name string, flags int) (*File, error) {
 		return nil, err
 	}
 	// node doesn't exist so create it
-	if d.vfs.Opt.ReadOnly {
+	if d.readOnly() {
 		return nil, EROFS
 	}
+	if err = d.checkName(name); err != nil {
+		return nil, err
+	}
+	d.vfs.deletes.flushFile(path.Join(d.Path(), name))
 	if err = d.SetModTime(time.Now()); err != nil {
 		fs.Errorf(d, "Dir.Create failed to set modtime on parent dir: %v", err)
 		return nil, err
@@ -1065,7 +1465,7 @@ func (d *Dir) Create(name string, flags int) (*File, error) {
 
 // Mkdir creates a new directory
 func (d *Dir) Mkdir(name string) (*Dir, error) {
-	if d.vfs.Opt.ReadOnly {
+	if d.readOnly() {
 		return nil, EROFS
 	}
 	path := path.Join(d.path, name)
@@ -1084,14 +1484,26 @@ func (d *Dir) Mkdir(name string) (*Dir, error) {
 		fs.Errorf(d, "Dir.Mkdir failed to read directory: %v", err)
 		return nil, err
 	}
-	// fs.Debugf(path, "Dir.Mkdir")
-	err = d.f.Mkdir(context.TODO(), path)
-	if err != nil {
-		fs.Errorf(d, "Dir.Mkdir failed to create directory: %v", err)
+	if err = d.checkName(name); err != nil {
 		return nil, err
 	}
+	// fs.Debugf(path, "Dir.Mkdir")
+	d.vfs.deletes.flushFile(path)
+	// Directories are only made in the VFS with --vfs-no-write-back
+	lazy := d.vfs.Opt.LazyMkdir || d.vfs.Opt.NoWriteBack
+	if !lazy {
+		err = d.f.Mkdir(context.TODO(), path)
+		if err != nil {
+			fs.Errorf(d, "Dir.Mkdir failed to create directory: %v", err)
+			return nil, err
+		}
+	}
 	fsDir := fs.NewDir(path, time.Now())
 	dir := newDir(d.vfs, d.f, d, fsDir)
+	if lazy {
+		fs.Debugf(path, "Dir.Mkdir deferring creating directory until a file is written in it")
+		dir.pending.Store(true)
+	}
 	d.addObject(dir)
 	if err = d.SetModTime(time.Now()); err != nil {
 		fs.Errorf(d, "Dir.Mkdir failed to set modtime on parent dir: %v", err)
@@ -1101,9 +1513,34 @@ func (d *Dir) Mkdir(name string) (*Dir, error) {
 	return dir, nil
 }
 
+// materialize creates the directory and any of its parents on the
+// remote if creating them was deferred by --vfs-lazy-mkdir
+func (d *Dir) materialize() error {
+	// Nothing is written to the remote with --vfs-no-write-back so
+	// the directory stays in the VFS only
+	if !d.pending.Load() || d.vfs.Opt.NoWriteBack {
+		return nil
+	}
+	if d.parent != nil {
+		if err := d.parent.materialize(); err != nil {
+			return err
+		}
+	}
+	d.mu.RLock()
+	dirPath := d.path
+	d.mu.RUnlock()
+	err := d.f.Mkdir(context.TODO(), dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to create deferred directory %q: %w", dirPath, err)
+	}
+	d.pending.Store(false)
+	fs.Debugf(dirPath, "Created deferred directory")
+	return nil
+}
+
 // Remove the directory
 func (d *Dir) Remove() error {
-	if d.vfs.Opt.ReadOnly {
+	if d.readOnly() {
 		return EROFS
 	}
 	// Check directory is empty first
@@ -1116,11 +1553,21 @@ func (d *Dir) Remove() error {
 		fs.Errorf(d, "Dir.Remove not empty")
 		return ENOTEMPTY
 	}
-	// remove directory
-	err = d.f.Rmdir(context.TODO(), d.path)
-	if err != nil {
-		fs.Errorf(d, "Dir.Remove failed to remove directory: %v", err)
-		return err
+	// directories on the remote can't be removed with --vfs-no-write-back
+	if !d.pending.Load() && d.vfs.Opt.NoWriteBack {
+		return EROFS
+	}
+	// delete any files in it which are waiting to be deleted
+	d.vfs.deletes.flushDir(d.Path())
+	// remove directory unless it was never created
+	if d.pending.Load() {
+		fs.Debugf(d, "Dir.Remove not removing directory from remote as it was never created")
+	} else {
+		err = d.f.Rmdir(context.TODO(), d.path)
+		if err != nil {
+			fs.Errorf(d, "Dir.Remove failed to remove directory: %v", err)
+			return err
+		}
 	}
 	// Remove the item from the parent directory listing
 	if d.parent != nil {
@@ -1131,11 +1578,11 @@ func (d *Dir) Remove() error {
 
 // RemoveAll removes the directory and any contents recursively
 func (d *Dir) RemoveAll() error {
-	if d.vfs.Opt.ReadOnly {
+	if d.readOnly() {
 		return EROFS
 	}
 	// Remove contents of the directory
-	nodes, err := d.ReadDirAll()
+	nodes, err := d.readDirAll(false)
 	if err != nil {
 		fs.Errorf(d, "Dir.RemoveAll failed to read directory: %v", err)
 		return err
@@ -1159,7 +1606,7 @@ func (d *Dir) DirEntry() (entry fs.DirEntry) {
 // which must be a directory.  The entry to be removed may correspond
 // to a file (unlink) or to a directory (rmdir).
 func (d *Dir) RemoveName(name string) error {
-	if d.vfs.Opt.ReadOnly {
+	if d.readOnly() {
 		return EROFS
 	}
 	// fs.Debugf(path, "Dir.Remove")
@@ -1178,9 +1625,12 @@ func (d *Dir) RemoveName(name string) error {
 // Rename the file
 func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 	// fs.Debugf(d, "BEFORE\n%s", d.dump())
-	if d.vfs.Opt.ReadOnly {
+	if d.readOnly() || destDir.readOnly() || d.isCacheStatusName(oldName) || destDir.isCacheStatusName(newName) {
 		return EROFS
 	}
+	if err := destDir.checkName(newName); err != nil {
+		return err
+	}
 	oldPath := path.Join(d.path, oldName)
 	newPath := path.Join(destDir.path, newName)
 	// fs.Debugf(oldPath, "Dir.Rename to %q", newPath)
@@ -1189,6 +1639,32 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 		fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 		return err
 	}
+	oldDir, _ := oldNode.(*Dir)
+	if oldDir != nil && oldDir.readOnly() {
+		return EROFS
+	}
+	if oldFile, ok := oldNode.(*File); ok && oldFile.readOnly() {
+		return EROFS
+	}
+	pending := oldDir != nil && oldDir.pending.Load()
+	// Only things which aren't on the remote can be renamed with
+	// --vfs-no-write-back
+	if d.vfs.Opt.NoWriteBack && ((oldDir != nil && !pending) || (oldDir == nil && oldNode.DirEntry() != nil)) {
+		return EROFS
+	}
+	// delete anything waiting to be deleted at the destination or
+	// in the directory being renamed
+	d.vfs.deletes.flushFile(newPath)
+	if oldDir != nil {
+		d.vfs.deletes.flushDir(oldPath)
+	}
+	if !pending {
+		// make sure the destination exists on the remote
+		if err = destDir.materialize(); err != nil {
+			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
+			return err
+		}
+	}
 	switch x := oldNode.DirEntry().(type) {
 	case nil:
 		if oldFile, ok := oldNode.(*File); ok {
@@ -1220,10 +1696,15 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 		}
 		srcRemote := x.Remote()
 		dstRemote := newPath
-		err = operations.DirMove(context.TODO(), d.f, srcRemote, dstRemote)
-		if err != nil {
-			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
-			return err
+		if pending {
+			// nothing to move on the remote
+			fs.Debugf(oldPath, "Dir.Rename renaming directory which hasn't been created yet")
+		} else {
+			err = operations.DirMove(context.TODO(), d.f, srcRemote, dstRemote)
+			if err != nil {
+				fs.Errorf(oldPath, "Dir.Rename error: %v", err)
+				return err
+			}
 		}
 		newDir := fs.NewDirCopy(context.TODO(), x).SetRemote(newPath)
 		// Update the node with the new details
@@ -3,7 +3,9 @@ package vfs
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"slices"
@@ -15,6 +17,9 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -83,6 +88,35 @@ func TestDirMethods(t *testing.T) {
 	assert.Equal(t, vfs, dir.VFS())
 }
 
+func TestDirCacheJitter(t *testing.T) {
+	_, vfs, dir, _ := dirCreate(t)
+	cacheTime := time.Duration(vfs.Opt.DirCacheTime)
+	jitter := time.Minute
+
+	now := time.Now()
+	dir.mu.Lock()
+	defer dir.mu.Unlock()
+
+	// No jitter by default
+	dir._setRead(now)
+	assert.Equal(t, time.Duration(0), dir.jitter)
+	_, stale := dir._age(now.Add(cacheTime + time.Second))
+	assert.True(t, stale)
+
+	vfs.Opt.DirCacheJitter = fs.Duration(jitter)
+	jitters := map[time.Duration]struct{}{}
+	for range 20 {
+		dir._setRead(now)
+		assert.True(t, dir.jitter >= 0 && dir.jitter < jitter, "jitter %v", dir.jitter)
+		jitters[dir.jitter] = struct{}{}
+		_, stale = dir._age(now.Add(cacheTime + dir.jitter))
+		assert.False(t, stale)
+		_, stale = dir._age(now.Add(cacheTime + jitter))
+		assert.True(t, stale)
+	}
+	assert.Greater(t, len(jitters), 1, "jitter should be random")
+}
+
 func TestDirForgetAll(t *testing.T) {
 	_, vfs, dir, file1 := dirCreate(t)
 
@@ -330,6 +364,34 @@ func TestDirReadDirAll(t *testing.T) {
 	})
 }
 
+func TestDirReadDirAllMaxEntries(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.MaxDirEntries = 2
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	file1 := r.WriteObject(context.Background(), "dir/file1", "file1 contents", t1)
+	file2 := r.WriteObject(context.Background(), "dir/file2", "file2- contents", t2)
+	file3 := r.WriteObject(context.Background(), "dir/subdir/file3", "file3-- contents", t3)
+	r.CheckRemoteItems(t, file1, file2, file3)
+
+	node, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	dir := node.(*Dir)
+
+	// Only the first entries are shown
+	checkListing(t, dir, []string{"file1,14,false", "file2,15,false"})
+
+	// The hidden entries can still be found by name
+	node, err = vfs.Stat("dir/subdir")
+	require.NoError(t, err)
+	assert.True(t, node.IsDir())
+	checkListing(t, node.(*Dir), []string{"file3,16,false"})
+
+	// RemoveAll removes the hidden entries too
+	require.NoError(t, dir.RemoveAll())
+	r.CheckRemoteItems(t)
+}
+
 func TestDirOpen(t *testing.T) {
 	_, _, dir, _ := dirCreate(t)
 
@@ -409,6 +471,47 @@ func TestDirMkdir(t *testing.T) {
 	assert.Equal(t, EROFS, err)
 }
 
+func TestDirMkdirLazy(t *testing.T) {
+	r, vfs, dir, file1 := dirCreate(t)
+	vfs.Opt.LazyMkdir = true
+
+	sub, err := dir.Mkdir("sub")
+	require.NoError(t, err)
+	subsub, err := sub.Mkdir("subsub")
+	require.NoError(t, err)
+	gone, err := dir.Mkdir("gone")
+	require.NoError(t, err)
+
+	// check the vfs shows the directories but they aren't on the remote
+	checkListing(t, dir, []string{"file1,14,false", "gone,0,true", "sub,0,true"})
+	checkListing(t, sub, []string{"subsub,0,true"})
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{"dir"}, r.Fremote.Precision())
+
+	// check they are still shown after the directory cache is flushed
+	dir.ForgetAll()
+	checkListing(t, dir, []string{"file1,14,false", "gone,0,true", "sub,0,true"})
+
+	// removing and renaming pending directories works
+	require.NoError(t, gone.Remove())
+	require.NoError(t, sub.Rename("subsub", "subsub2", sub))
+	checkListing(t, dir, []string{"file1,14,false", "sub,0,true"})
+	checkListing(t, sub, []string{"subsub2,0,true"})
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{"dir"}, r.Fremote.Precision())
+	assert.True(t, subsub.pending.Load())
+
+	// writing a file creates the directories
+	fd, err := vfs.OpenFile("dir/sub/subsub2/file2", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	_, err = fd.Write([]byte("file2 contents"))
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+
+	file2 := fstest.NewItem("dir/sub/subsub2/file2", "file2 contents", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2}, []string{"dir", "dir/sub", "dir/sub/subsub2"}, fs.ModTimeNotSupported)
+	assert.False(t, sub.pending.Load())
+	assert.False(t, subsub.pending.Load())
+}
+
 func TestDirMkdirSub(t *testing.T) {
 	r, vfs, dir, file1 := dirCreate(t)
 
@@ -763,3 +866,295 @@ func TestDirMetadataExtension(t *testing.T) {
 		assert.Equal(t, modTime.Format(time.RFC3339Nano), metadata["mtime"])
 	}
 }
+
+func TestDirExposeMetadata(t *testing.T) {
+	r, vfs, dir, _ := dirCreate(t)
+	root, err := vfs.Root()
+	require.NoError(t, err)
+	vfs.Opt.MetadataExtension = ".metadata"
+	vfs.Opt.ExposeMetadata = true
+
+	// The metadata files are listed without being looked up first
+	node, err := vfs.Stat("dir/file1.metadata")
+	require.NoError(t, err)
+	size := node.Size()
+	checkListing(t, dir, []string{"file1,14,false", fmt.Sprintf("file1.metadata,%d,false", size)})
+	node, err = vfs.Stat("dir.metadata")
+	require.NoError(t, err)
+	checkListing(t, root, []string{"dir,0,true", fmt.Sprintf("dir.metadata,%d,false", node.Size())})
+
+	// The metadata files can't be changed
+	_, err = vfs.OpenFile("dir/file1.metadata", os.O_WRONLY|os.O_TRUNC, 0777)
+	assert.Equal(t, EROFS, err)
+	_, err = vfs.OpenFile("dir/file1.metadata", os.O_RDWR, 0777)
+	assert.Equal(t, EROFS, err)
+	assert.Equal(t, EROFS, vfs.Remove("dir/file1.metadata"))
+	assert.Equal(t, EROFS, vfs.Rename("dir/file1.metadata", "dir/file2"))
+	assert.Equal(t, EROFS, vfs.Chtimes("dir/file1.metadata", t1, t1))
+	blob, err := vfs.ReadFile("dir/file1.metadata")
+	require.NoError(t, err)
+	assert.True(t, json.Valid(blob))
+
+	// The real file is unchanged
+	r.CheckRemoteItems(t, fstest.NewItem("dir/file1", "file1 contents", t1))
+
+	// The metadata files don't stop the directory being removed
+	require.NoError(t, dir.RemoveAll())
+	r.CheckRemoteItems(t)
+}
+
+func TestDirListingStabilityWindow(t *testing.T) {
+	r, vfs, dir, _ := dirCreate(t)
+	vfs.Opt.ListingStabilityWindow = 2
+	ctx := context.Background()
+
+	list := func() (names []string) {
+		require.NoError(t, dir.readDir())
+		dir.mu.RLock()
+		for name := range dir.items {
+			names = append(names, name)
+		}
+		dir.mu.RUnlock()
+		sort.Strings(names)
+		return names
+	}
+	remove := func(remote string) {
+		o, err := r.Fremote.NewObject(ctx, remote)
+		require.NoError(t, err)
+		require.NoError(t, o.Remove(ctx))
+	}
+
+	// First listing is always applied
+	assert.Equal(t, []string{"file1"}, list())
+
+	// New entries must be seen twice
+	r.WriteObject(ctx, "dir/file2", "file2", t2)
+	assert.Equal(t, []string{"file1"}, list())
+	assert.Equal(t, []string{"file1", "file2"}, list())
+
+	// Entries which flap are left alone
+	remove("dir/file2")
+	assert.Equal(t, []string{"file1", "file2"}, list())
+	r.WriteObject(ctx, "dir/file2", "file2", t2)
+	assert.Equal(t, []string{"file1", "file2"}, list())
+
+	// Removed entries must be missing twice
+	remove("dir/file2")
+	assert.Equal(t, []string{"file1", "file2"}, list())
+	assert.Equal(t, []string{"file1"}, list())
+}
+
+func TestDirDownloadingMarker(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.CacheDownloadingSuffix = ".downloading"
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	file1 := r.WriteObject(context.Background(), "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+
+	node, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	dir := node.(*Dir)
+
+	names := func() (out []string) {
+		nodes, err := dir.ReadDirAll()
+		require.NoError(t, err)
+		for _, node := range nodes {
+			out = append(out, node.Name())
+		}
+		return out
+	}
+	assert.Equal(t, []string{"file1"}, names())
+	_, err = vfs.Stat("dir/file1.downloading")
+	assert.Equal(t, ENOENT, err)
+
+	// Opening the file starts the download
+	fd, err := vfs.OpenFile("dir/file1", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	fh, ok := fd.(*RWFileHandle)
+	require.True(t, ok)
+	require.NoError(t, fh.openPending())
+	assert.Equal(t, []string{"file1", "file1.downloading"}, names())
+	marker, err := vfs.Stat("dir/file1.downloading")
+	require.NoError(t, err)
+	assert.True(t, marker.IsFile())
+	assert.Equal(t, int64(0), marker.Size())
+
+	// Once it is fully cached the marker goes
+	data, err := io.ReadAll(fd)
+	require.NoError(t, err)
+	assert.Equal(t, "file1 contents", string(data))
+	assert.Equal(t, []string{"file1"}, names())
+	_, err = vfs.Stat("dir/file1.downloading")
+	assert.Equal(t, ENOENT, err)
+
+	require.NoError(t, fd.Close())
+}
+
+// flakyListFs is a mock Fs whose listings fail while fails > 0
+type flakyListFs struct {
+	*mockfs.Fs
+	err   error
+	fails int
+	calls int
+}
+
+func (f *flakyListFs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	f.calls++
+	if f.fails > 0 {
+		f.fails--
+		return nil, f.err
+	}
+	return f.Fs.List(ctx, dir)
+}
+
+func TestDirListRetries(t *testing.T) {
+	ctx := context.Background()
+	fMock, err := mockfs.NewFs(ctx, "test", "root", nil)
+	require.NoError(t, err)
+	fMock.(*mockfs.Fs).AddObject(mockobject.New("file1").WithContent([]byte("file1"), mockobject.SeekModeNone))
+	f := &flakyListFs{Fs: fMock.(*mockfs.Fs), err: io.ErrUnexpectedEOF}
+
+	opt := vfscommon.Opt
+	opt.ListRetries = 2
+	opt.ListRetryDelay = 0
+	vfs := New(f, &opt)
+	defer cleanupVFS(t, vfs)
+	dir := vfs.root
+
+	names := func() (names []string) {
+		dir.mu.RLock()
+		for name := range dir.items {
+			names = append(names, name)
+		}
+		dir.mu.RUnlock()
+		sort.Strings(names)
+		return names
+	}
+
+	// Transient errors are retried
+	f.fails, f.calls = 2, 0
+	require.NoError(t, dir.readDir())
+	assert.Equal(t, 3, f.calls)
+	assert.Equal(t, []string{"file1"}, names())
+
+	// Once the retries run out the old listing is used
+	f.fails, f.calls = 5, 0
+	require.NoError(t, dir.readDir())
+	assert.Equal(t, 3, f.calls)
+	assert.Equal(t, []string{"file1"}, names())
+
+	// Permanent errors aren't retried and are returned
+	f.fails, f.calls, f.err = 5, 0, fs.ErrorPermissionDenied
+	assert.Error(t, dir.readDir())
+	assert.Equal(t, 1, f.calls)
+
+	// Errors which aren't known to be transient aren't retried
+	f.fails, f.calls, f.err = 5, 0, errors.New("unknown")
+	assert.Error(t, dir.readDir())
+	assert.Equal(t, 1, f.calls)
+
+	// Without retries errors are returned straight away
+	vfs.Opt.ListRetries = 0
+	f.fails, f.calls, f.err = 5, 0, io.ErrUnexpectedEOF
+	assert.Error(t, dir.readDir())
+	assert.Equal(t, 1, f.calls)
+}
+
+func TestDirListRetryUnlocked(t *testing.T) {
+	ctx := context.Background()
+	fMock, err := mockfs.NewFs(ctx, "test", "root", nil)
+	require.NoError(t, err)
+	f := &flakyListFs{Fs: fMock.(*mockfs.Fs), err: io.ErrUnexpectedEOF, fails: 1}
+
+	opt := vfscommon.Opt
+	opt.ListRetries = 1
+	opt.ListRetryDelay = fs.Duration(500 * time.Millisecond)
+	vfs := New(f, &opt)
+	defer cleanupVFS(t, vfs)
+	dir := vfs.root
+
+	// The directory can be used while waiting to retry
+	done := make(chan error)
+	go func() {
+		done <- dir.readDir()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	dir.mu.Lock()
+	assert.Empty(t, dir.items)
+	dir.mu.Unlock()
+	assert.Less(t, time.Since(start), 250*time.Millisecond)
+
+	// Another read waits for the listing rather than starting its own
+	done2 := make(chan error)
+	go func() {
+		done2 <- dir.readDir()
+	}()
+	require.NoError(t, <-done)
+	require.NoError(t, <-done2)
+	assert.Equal(t, 2, f.calls)
+}
+
+func TestDirListRetryShutdown(t *testing.T) {
+	ctx := context.Background()
+	fMock, err := mockfs.NewFs(ctx, "test", "root", nil)
+	require.NoError(t, err)
+	f := &flakyListFs{Fs: fMock.(*mockfs.Fs), err: io.ErrUnexpectedEOF, fails: 1}
+
+	opt := vfscommon.Opt
+	opt.ListRetries = 1
+	opt.ListRetryDelay = fs.Duration(time.Hour)
+	vfs := New(f, &opt)
+	defer cleanupVFS(t, vfs)
+	dir := vfs.root
+
+	// Shutting down the VFS stops the wait to retry
+	done := make(chan error)
+	go func() {
+		done <- dir.readDir()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	vfs.cancel()
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the listing to stop")
+	}
+	assert.Equal(t, 1, f.calls)
+}
+
+func TestDirReservedNames(t *testing.T) {
+	ctx := context.Background()
+	fMock, err := mockfs.NewFs(ctx, "test", "root", nil)
+	require.NoError(t, err)
+	for _, remote := range []string{"..", "bad\x00name", "ok"} {
+		fMock.(*mockfs.Fs).AddObject(mockobject.New(remote).WithContent([]byte(remote), mockobject.SeekModeNone))
+	}
+
+	for _, test := range []struct {
+		mode vfscommon.ReservedNames
+		want []string
+	}{
+		{mode: vfscommon.ReservedNamesHide, want: []string{"ok"}},
+		{mode: vfscommon.ReservedNamesEncode, want: []string{"bad␀name", "ok", "．．"}},
+	} {
+		t.Run(test.mode.String(), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.ReservedNames = test.mode
+			vfs := New(fMock, &opt)
+			defer cleanupVFS(t, vfs)
+
+			nodes, err := vfs.root.ReadDirAll()
+			require.NoError(t, err)
+			var names []string
+			for _, node := range nodes {
+				names = append(names, node.Name())
+			}
+			sort.Strings(names)
+			assert.Equal(t, test.want, names)
+		})
+	}
+}
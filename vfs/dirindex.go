@@ -0,0 +1,325 @@
+package vfs
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/vfs/vfscache"
+)
+
+// indexedEntry is a directory entry kept on disk
+type indexedEntry struct {
+	Remote  string    `json:"remote"`
+	Dir     bool      `json:"dir,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// indexedListing is a directory listing kept on disk
+type indexedListing struct {
+	Path    string         `json:"path"`    // path of the directory
+	Read    time.Time      `json:"read"`    // when the directory was listed
+	Entries []indexedEntry `json:"entries"` // the directory entries
+}
+
+// dirIndex keeps directory listings on disk in the cache directory if
+// --vfs-persist-dir-cache is set, so directories listed less than
+// --dir-cache-time ago can be read from disk after a restart instead
+// of being listed again.
+//
+// Listings are removed when a change to the directory is noticed, or
+// made through the VFS, so they are only used while they are still
+// what the remote would return.
+//
+// The methods may be called on a nil *dirIndex which does nothing.
+type dirIndex struct {
+	dir string // directory the listings are kept in
+}
+
+// newDirIndex makes a dirIndex for f
+func newDirIndex(f fs.Fs) (*dirIndex, error) {
+	dir, err := vfscache.RootDir(f, "vfsDirs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory for listings: %w", err)
+	}
+	return &dirIndex{dir: dir}, nil
+}
+
+// listingPath returns the path the listing of dirPath is kept at
+func (x *dirIndex) listingPath(dirPath string) string {
+	sum := md5.Sum([]byte(dirPath))
+	return filepath.Join(x.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load returns the listing kept for dirPath or nil if there isn't one
+func (x *dirIndex) load(dirPath string) *indexedListing {
+	if x == nil {
+		return nil
+	}
+	data, err := os.ReadFile(x.listingPath(dirPath))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fs.Debugf(dirPath, "vfs: failed to read directory listing kept on disk: %v", err)
+		}
+		return nil
+	}
+	var listing indexedListing
+	if err = json.Unmarshal(data, &listing); err != nil {
+		fs.Debugf(dirPath, "vfs: ignoring unreadable directory listing kept on disk: %v", err)
+		return nil
+	}
+	if listing.Path != dirPath {
+		return nil
+	}
+	return &listing
+}
+
+// save keeps entries as the listing of dirPath made at read
+func (x *dirIndex) save(dirPath string, entries fs.DirEntries, read time.Time) {
+	if x == nil {
+		return
+	}
+	ctx := context.TODO()
+	listing := indexedListing{
+		Path:    dirPath,
+		Read:    read,
+		Entries: make([]indexedEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		_, isDir := entry.(fs.Directory)
+		listing.Entries = append(listing.Entries, indexedEntry{
+			Remote:  entry.Remote(),
+			Dir:     isDir,
+			Size:    entry.Size(),
+			ModTime: entry.ModTime(ctx),
+		})
+	}
+	err := x.write(x.listingPath(dirPath), &listing)
+	if err != nil {
+		fs.Errorf(dirPath, "vfs: failed to keep directory listing on disk: %v", err)
+	}
+}
+
+// write listing to path by writing a temporary file and renaming it
+// so readers never see a partial listing
+func (x *dirIndex) write(path string, listing *indexedListing) (err error) {
+	out, err := os.CreateTemp(x.dir, "listing-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := out.Name()
+	err = json.NewEncoder(out).Encode(listing)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+	}
+	return err
+}
+
+// remove forgets the listing kept for dirPath
+func (x *dirIndex) remove(dirPath string) {
+	if x == nil {
+		return
+	}
+	err := os.Remove(x.listingPath(dirPath))
+	if err != nil && !os.IsNotExist(err) {
+		fs.Errorf(dirPath, "vfs: failed to remove directory listing kept on disk: %v", err)
+	}
+}
+
+// clear forgets all the listings
+func (x *dirIndex) clear() {
+	if x == nil {
+		return
+	}
+	entries, err := os.ReadDir(x.dir)
+	if err != nil {
+		fs.Errorf(nil, "vfs: failed to clear directory listings kept on disk: %v", err)
+		return
+	}
+	for _, entry := range entries {
+		if err := os.Remove(filepath.Join(x.dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			fs.Errorf(nil, "vfs: failed to clear directory listings kept on disk: %v", err)
+		}
+	}
+}
+
+// entries makes the directory entries of the listing for f
+func (listing *indexedListing) entries(f fs.Fs) fs.DirEntries {
+	entries := make(fs.DirEntries, 0, len(listing.Entries))
+	for _, entry := range listing.Entries {
+		if entry.Dir {
+			entries = append(entries, fs.NewDir(entry.Remote, entry.ModTime).SetSize(entry.Size))
+		} else {
+			entries = append(entries, &indexedObject{
+				f:       f,
+				remote:  entry.Remote,
+				size:    entry.Size,
+				modTime: entry.ModTime,
+			})
+		}
+	}
+	return entries
+}
+
+// _readDirFromIndex sets d.items from the listing kept on disk if
+// there is one which was made less than --dir-cache-time ago and it
+// hasn't been tried already, returning true if it was used.
+//
+// call with d.mu held
+func (d *Dir) _readDirFromIndex(when time.Time) bool {
+	if d.vfs.dirIndex == nil || d.indexTried || d.versions != versionsNone || d.recent {
+		return false
+	}
+	d.indexTried = true
+	listing := d.vfs.dirIndex.load(d.path)
+	if listing == nil {
+		return false
+	}
+	age := when.Sub(listing.Read)
+	if age < 0 || age > time.Duration(d.vfs.Opt.DirCacheTime) {
+		fs.Debugf(d.path, "Not using directory listing kept on disk as it is %v old", age)
+		return false
+	}
+	if err := d._readDirFromEntries(listing.entries(d.f), nil, time.Time{}); err != nil {
+		return false
+	}
+	fs.Debugf(d.path, "Read directory listing kept on disk (%v old)", age)
+	d._setRead(listing.Read)
+	d.cleanupTimer.Reset(time.Duration(d.vfs.Opt.DirCacheTime * 2))
+	d.vfs.dirLRU.touch(d)
+	return true
+}
+
+// indexedObject is an object read from a directory listing kept on
+// disk. It finds the object on the remote the first time it is needed
+// for anything other than its size and modification time.
+type indexedObject struct {
+	f       fs.Fs
+	remote  string
+	size    int64
+	modTime time.Time
+
+	mu sync.Mutex
+	o  fs.Object // object on the remote once found
+}
+
+// object finds the object on the remote
+func (o *indexedObject) object(ctx context.Context) (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.o == nil {
+		obj, err := o.f.NewObject(ctx, o.remote)
+		if err != nil {
+			return nil, err
+		}
+		o.o = obj
+	}
+	return o.o, nil
+}
+
+// found returns the object on the remote if it has been found already
+func (o *indexedObject) found() fs.Object {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.o
+}
+
+// String returns a description of the Object
+func (o *indexedObject) String() string {
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *indexedObject) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification date of the file
+func (o *indexedObject) ModTime(ctx context.Context) time.Time {
+	if obj := o.found(); obj != nil {
+		return obj.ModTime(ctx)
+	}
+	return o.modTime
+}
+
+// Size returns the size of the file
+func (o *indexedObject) Size() int64 {
+	if obj := o.found(); obj != nil {
+		return obj.Size()
+	}
+	return o.size
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *indexedObject) Fs() fs.Info {
+	return o.f
+}
+
+// Hash returns the requested hash of the object
+func (o *indexedObject) Hash(ctx context.Context, ty hash.Type) (string, error) {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ctx, ty)
+}
+
+// Storable says whether this object can be stored
+func (o *indexedObject) Storable() bool {
+	return true
+}
+
+// SetModTime sets the metadata on the object to set the modification date
+func (o *indexedObject) SetModTime(ctx context.Context, t time.Time) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.SetModTime(ctx, t)
+}
+
+// Open opens the file for read
+func (o *indexedObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(ctx, options...)
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *indexedObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.Update(ctx, in, src, options...)
+}
+
+// Remove this object
+func (o *indexedObject) Remove(ctx context.Context) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.Remove(ctx)
+}
+
+// Check the interfaces are satisfied
+var _ fs.Object = (*indexedObject)(nil)
@@ -0,0 +1,93 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVFSPersistDirCache(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	r.WriteObject(ctx, "dir/file.txt", "hello", t1)
+
+	var vfs *VFS
+	// reopen starts a new VFS keeping listings for dirCacheTime
+	reopen := func(dirCacheTime time.Duration) {
+		if vfs != nil {
+			cleanupVFS(t, vfs)
+		}
+		opt := vfscommon.Opt
+		opt.PersistDirCache = true
+		opt.DirCacheTime = fs.Duration(dirCacheTime)
+		vfs = New(r.Fremote, &opt)
+	}
+	defer func() {
+		cleanupVFS(t, vfs)
+	}()
+	names := func() (names []string) {
+		entries, err := vfs.ReadDir("dir")
+		require.NoError(t, err)
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	reopen(time.Hour)
+	assert.Equal(t, []string{"file.txt"}, names())
+
+	// After a restart the listing comes from disk so doesn't show
+	// files added to the remote behind the VFS's back
+	r.WriteObject(ctx, "dir/new.txt", "new", t1)
+	reopen(time.Hour)
+	assert.Equal(t, []string{"file.txt"}, names())
+
+	// Files from the kept listing can be read
+	data, err := vfs.ReadFile("dir/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	// vfs/refresh straight after a restart reads the directory from
+	// the remote rather than the kept listing
+	reopen(time.Hour)
+	_, err = rc.Calls.Get("vfs/refresh").Fn(ctx, rc.Params{"fs": fs.ConfigString(r.Fremote), "dir": "dir"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"file.txt", "new.txt"}, names())
+	reopen(time.Hour)
+	assert.Equal(t, []string{"file.txt", "new.txt"}, names())
+	require.NoError(t, vfs.Remove("dir/new.txt"))
+	r.WriteObject(ctx, "dir/new.txt", "new", t1)
+
+	// A change notification removes the kept listing
+	vfs.root.changeNotify("dir/new.txt", fs.EntryObject)
+	reopen(time.Hour)
+	assert.Equal(t, []string{"file.txt", "new.txt"}, names())
+
+	// Changes made through the VFS remove the kept listing
+	require.NoError(t, vfs.Remove("dir/new.txt"))
+	reopen(time.Hour)
+	assert.Equal(t, []string{"file.txt"}, names())
+
+	// Listings older than --dir-cache-time aren't used
+	r.WriteObject(ctx, "dir/new.txt", "new", t1)
+	reopen(time.Nanosecond)
+	assert.Equal(t, []string{"file.txt", "new.txt"}, names())
+
+	// Flushing the directory cache removes the kept listings
+	reopen(time.Hour)
+	assert.Equal(t, []string{"file.txt", "new.txt"}, names())
+	require.NoError(t, r.Fremote.Mkdir(ctx, "dir/sub"))
+	vfs.FlushDirCache()
+	_, err = os.Stat(vfs.dirIndex.listingPath("dir"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, []string{"file.txt", "new.txt", "sub"}, names())
+}
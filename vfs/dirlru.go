@@ -0,0 +1,97 @@
+package vfs
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// dirLRU keeps track of the directories with cached listings so the
+// least recently used can be forgotten when there are more than
+// --vfs-dir-cache-max-entries of them.
+//
+// The methods may be called on a nil *dirLRU which does nothing.
+type dirLRU struct {
+	limit    int
+	mu       sync.Mutex
+	order    *list.List             // of *Dir - front is the most recently used
+	elems    map[*Dir]*list.Element // where each Dir is in order
+	evicting bool                   // set while evict is running
+}
+
+// newDirLRU makes a dirLRU holding up to limit directories
+func newDirLRU(limit int) *dirLRU {
+	return &dirLRU{
+		limit: limit,
+		order: list.New(),
+		elems: make(map[*Dir]*list.Element),
+	}
+}
+
+// touch marks d as the most recently used directory
+//
+// If there are too many directories then the least recently used are
+// forgotten in the background. This may be called with d.mu held.
+func (l *dirLRU) touch(d *Dir) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, found := l.elems[d]; found {
+		l.order.MoveToFront(elem)
+	} else {
+		l.elems[d] = l.order.PushFront(d)
+	}
+	if l.order.Len() > l.limit && !l.evicting {
+		l.evicting = true
+		go l.evict()
+	}
+}
+
+// remove d as its listing has been forgotten
+func (l *dirLRU) remove(d *Dir) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, found := l.elems[d]; found {
+		l.order.Remove(elem)
+		delete(l.elems, d)
+	}
+}
+
+// len returns the number of directories being tracked
+func (l *dirLRU) len() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// evict forgets the least recently used directories until there are
+// no more than limit.
+//
+// This must be called without any Dir locks held.
+func (l *dirLRU) evict() {
+	for {
+		l.mu.Lock()
+		if l.order.Len() <= l.limit {
+			l.evicting = false
+			l.mu.Unlock()
+			return
+		}
+		elem := l.order.Back()
+		d := elem.Value.(*Dir)
+		l.order.Remove(elem)
+		delete(l.elems, d)
+		l.mu.Unlock()
+
+		fs.Debugf(d.Path(), "forgetting directory listing as over --vfs-dir-cache-max-entries")
+		d.ForgetAll()
+	}
+}
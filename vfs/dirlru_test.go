@@ -0,0 +1,57 @@
+package vfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirCacheMaxEntries(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.DirCacheMaxEntries = 3
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+	for _, name := range []string{"a/file", "b/file", "c/file"} {
+		r.WriteObject(ctx, name, "contents", t1)
+	}
+
+	dir := func(name string) *Dir {
+		node, err := vfs.Stat(name)
+		require.NoError(t, err)
+		return node.(*Dir)
+	}
+	listed := func(d *Dir) bool {
+		d.mu.RLock()
+		defer d.mu.RUnlock()
+		return !d.read.IsZero()
+	}
+	read := func(d *Dir) {
+		_, err := d.ReadDirAll()
+		require.NoError(t, err)
+	}
+
+	// The root, a and b fit
+	a, b := dir("a"), dir("b")
+	read(a)
+	read(b)
+	assert.Equal(t, 3, vfs.dirLRU.len())
+	assert.True(t, listed(a))
+
+	// Use a so b is the least recently used, then c pushes b out
+	read(a)
+	c := dir("c")
+	read(c)
+	assert.Eventually(t, func() bool { return !listed(b) }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 3, vfs.dirLRU.len())
+	assert.True(t, listed(a))
+	assert.True(t, listed(c))
+
+	// b is listed again when it is next used
+	read(b)
+	assert.True(t, listed(b))
+	assert.Eventually(t, func() bool { return vfs.dirLRU.len() <= 3 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,106 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// resolveDirtyConflict is called when the remote reports a change to
+// the file at absPath. If the file has local changes waiting to be
+// uploaded and the remote object is different to the one they were
+// made to then the conflict is resolved according to
+// --vfs-dirty-conflict.
+func (vfs *VFS) resolveDirtyConflict(absPath string) {
+	if vfs.cache == nil {
+		return
+	}
+	item := vfs.cache.DirtyItem(absPath)
+	if item == nil {
+		return
+	}
+	o, err := vfs.f.NewObject(context.TODO(), absPath)
+	if err != nil {
+		// Deleting the remote file doesn't conflict with the
+		// upload which will create it again
+		fs.Debugf(absPath, "vfs: not checking for conflict with local changes: %v", err)
+		return
+	}
+	if !item.RemoteChanged(o) {
+		return
+	}
+	switch vfs.Opt.DirtyConflict {
+	case vfscommon.DirtyConflictRemoteWins:
+		if !vfs.cache.DiscardChanges(absPath) {
+			fs.Logf(absPath, "vfs: remote file changed while local changes are waiting to upload - keeping local changes as the file is open")
+			return
+		}
+		fs.Logf(absPath, "vfs: remote file changed while local changes are waiting to upload - discarding local changes")
+	case vfscommon.DirtyConflictRenameLocal:
+		newPath, err := vfs.renameConflict(absPath)
+		if err != nil {
+			fs.Errorf(absPath, "vfs: remote file changed while local changes are waiting to upload - failed to rename local changes, keeping them: %v", err)
+			return
+		}
+		fs.Logf(absPath, "vfs: remote file changed while local changes are waiting to upload - uploading local changes as %q", newPath)
+	case vfscommon.DirtyConflictError:
+		item.HoldUpload()
+		fs.Errorf(absPath, "vfs: remote file changed while local changes are waiting to upload - not uploading local changes until the file is written again")
+	default:
+		fs.Logf(absPath, "vfs: remote file changed while local changes are waiting to upload - keeping local changes")
+	}
+}
+
+// renameConflict renames the file at absPath which has local changes
+// to a new name so that the local changes are uploaded there leaving
+// the remote file in place.
+//
+// It returns the new path.
+func (vfs *VFS) renameConflict(absPath string) (newPath string, err error) {
+	node, err := vfs.Stat(absPath)
+	if err != nil {
+		return "", err
+	}
+	f, ok := node.(*File)
+	if !ok {
+		return "", errors.New("not a file")
+	}
+	d := f.Dir()
+	oldName := f.Name()
+	newName, err := d.conflictName(oldName)
+	if err != nil {
+		return "", err
+	}
+
+	// Forget the remote object so the rename only changes the
+	// cache and the local changes are uploaded as a new file
+	f.mu.Lock()
+	f.o = nil
+	f.mu.Unlock()
+	if err = f.rename(context.TODO(), d, newName); err != nil {
+		return "", err
+	}
+	d.delObject(oldName)
+	d.addObject(f)
+	return path.Join(d.Path(), newName), nil
+}
+
+// conflictName returns an unused name in d for the local changes of
+// the file called leaf
+func (d *Dir) conflictName(leaf string) (string, error) {
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		name := vfscommon.ConflictName(leaf, now, i)
+		_, err := d.stat(name)
+		if err == ENOENT {
+			return name, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("no free conflict name")
+}
@@ -0,0 +1,75 @@
+package vfs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscache/writeback"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVFSDirtyConflict(t *testing.T) {
+	for _, conflict := range []vfscommon.DirtyConflict{
+		vfscommon.DirtyConflictLocalWins,
+		vfscommon.DirtyConflictRemoteWins,
+		vfscommon.DirtyConflictRenameLocal,
+		vfscommon.DirtyConflictError,
+	} {
+		t.Run(conflict.String(), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.CacheMode = vfscommon.CacheModeFull
+			opt.WriteBack = fs.Duration(5 * time.Second)
+			opt.DirtyConflict = conflict
+			r, vfs := newTestVFSOpt(t, &opt)
+			ctx := context.Background()
+
+			r.WriteObject(ctx, "file.txt", "original", t1)
+			require.NoError(t, vfs.WriteFile("file.txt", []byte("local changes"), 0600))
+			require.NotNil(t, vfs.cache.DirtyItem("file.txt"))
+
+			// A change notification for an unchanged remote isn't a conflict
+			vfs.changeNotify("file.txt", fs.EntryObject)
+			require.NotNil(t, vfs.cache.DirtyItem("file.txt"))
+
+			r.WriteObject(ctx, "file.txt", "remote changes", t2)
+			vfs.changeNotify("file.txt", fs.EntryObject)
+
+			queued := len(vfs.cache.Queue()["queue"].([]writeback.QueueInfo))
+			contents, err := vfs.ReadFile("file.txt")
+			require.NoError(t, err)
+			switch conflict {
+			case vfscommon.DirtyConflictLocalWins:
+				assert.Equal(t, "local changes", string(contents))
+				assert.Equal(t, 1, queued)
+			case vfscommon.DirtyConflictRemoteWins:
+				assert.Equal(t, "remote changes", string(contents))
+				assert.Nil(t, vfs.cache.DirtyItem("file.txt"))
+				assert.Equal(t, 0, queued)
+			case vfscommon.DirtyConflictRenameLocal:
+				assert.Equal(t, "remote changes", string(contents))
+				assert.Equal(t, 1, queued)
+				entries, err := vfs.ReadDir("")
+				require.NoError(t, err)
+				var conflictName string
+				for _, entry := range entries {
+					if strings.HasPrefix(entry.Name(), "file.conflict-") && strings.HasSuffix(entry.Name(), ".txt") {
+						conflictName = entry.Name()
+					}
+				}
+				require.NotEmpty(t, conflictName)
+				contents, err = vfs.ReadFile(conflictName)
+				require.NoError(t, err)
+				assert.Equal(t, "local changes", string(contents))
+			case vfscommon.DirtyConflictError:
+				assert.Equal(t, "local changes", string(contents))
+				assert.NotNil(t, vfs.cache.DirtyItem("file.txt"))
+				assert.Equal(t, 0, queued)
+			}
+		})
+	}
+}
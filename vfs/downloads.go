@@ -0,0 +1,160 @@
+package vfs
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/chunkedreader"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/vfs/vfscache"
+)
+
+// errDownloadCancelled is returned by reads from a handle whose
+// download was cancelled with vfs/downloads-cancel
+var errDownloadCancelled = errors.New("vfs: download cancelled")
+
+// download is a file being read straight from the remote by a
+// ReadFileHandle
+type download struct {
+	id int64
+	fh *ReadFileHandle
+	tr *accounting.Transfer
+}
+
+// addDownload records fh as downloading with the transfer tr
+func (vfs *VFS) addDownload(fh *ReadFileHandle, tr *accounting.Transfer) int64 {
+	vfs.downloadsMu.Lock()
+	defer vfs.downloadsMu.Unlock()
+	if vfs.downloads == nil {
+		vfs.downloads = make(map[int64]*download)
+	}
+	vfs.downloadID++
+	id := vfs.downloadID
+	vfs.downloads[id] = &download{id: id, fh: fh, tr: tr}
+	return id
+}
+
+// removeDownload stops recording the download with id
+func (vfs *VFS) removeDownload(id int64) {
+	vfs.downloadsMu.Lock()
+	defer vfs.downloadsMu.Unlock()
+	delete(vfs.downloads, id)
+}
+
+// listDownloads returns the downloads in progress sorted by id
+func (vfs *VFS) listDownloads() []*download {
+	vfs.downloadsMu.Lock()
+	defer vfs.downloadsMu.Unlock()
+	downloads := make([]*download, 0, len(vfs.downloads))
+	for _, d := range vfs.downloads {
+		downloads = append(downloads, d)
+	}
+	sort.Slice(downloads, func(i, j int) bool {
+		return downloads[i].id < downloads[j].id
+	})
+	return downloads
+}
+
+// cancelDownloads cancels the downloads matching id if it is non zero
+// and matching path if it is non empty, returning the number
+// cancelled.
+func (vfs *VFS) cancelDownloads(id int64, path string) (n int) {
+	for _, d := range vfs.listDownloads() {
+		if (id != 0 && d.id != id) || (path != "" && d.fh.file.Path() != path) {
+			continue
+		}
+		d.fh.abortDownload()
+		vfs.removeDownload(d.id)
+		n++
+	}
+	return n
+}
+
+// getDownload returns the download with id or nil if not found
+func (vfs *VFS) getDownload(id int64) *download {
+	vfs.downloadsMu.Lock()
+	defer vfs.downloadsMu.Unlock()
+	return vfs.downloads[id]
+}
+
+// speed returns the average speed of the download in bytes per second
+func (d *download) speed(snapshot accounting.TransferSnapshot) float64 {
+	if elapsed := time.Since(snapshot.StartedAt).Seconds(); elapsed > 0 {
+		return float64(snapshot.Bytes) / elapsed
+	}
+	return 0
+}
+
+// rcStats returns the stats for the download
+func (d *download) rcStats() rc.Params {
+	snapshot := d.tr.Snapshot()
+	return rc.Params{
+		"id":        d.id,
+		"path":      d.fh.file.Path(),
+		"bytes":     snapshot.Bytes,
+		"size":      snapshot.Size,
+		"streams":   d.fh.streams.Load(),
+		"speed":     d.speed(snapshot),
+		"startedAt": snapshot.StartedAt,
+	}
+}
+
+// rcChunkReaderInfo returns the state of the chunked reader of the
+// download
+//
+// This doesn't wait for reads in progress so can be used on stuck
+// downloads.
+func (d *download) rcChunkReaderInfo() rc.Params {
+	fh := d.fh
+	position := fh.position.Load()
+	var stats chunkedreader.Stats
+	if r := fh.chunked.Load(); r != nil {
+		stats = (*r).Stats()
+	}
+	return rc.Params{
+		"id":             d.id,
+		"path":           fh.file.Path(),
+		"chunkSize":      stats.ChunkSize,
+		"streams":        stats.Streams,
+		"buffered":       max(stats.Offset-position, 0),
+		"position":       position,
+		"speed":          d.speed(d.tr.Snapshot()),
+		"tunedChunkSize": fh.chunkSize.Load(),
+		"tunedStreams":   fh.streams.Load(),
+		"tunePending":    fh.retune.Load(),
+	}
+}
+
+// tuneChunkReader sets the chunk size and streams used for new chunked
+// readers of the download. The reader is re-opened with them at the
+// current position at the next read.
+func (d *download) tuneChunkReader(chunkSize int64, streams int64) {
+	fs.Infof(d.fh.remote, "ReadFileHandle: tuning chunked reader to chunk size %v with %d streams", fs.SizeSuffix(chunkSize), streams)
+	d.fh.chunkSize.Store(chunkSize)
+	d.fh.streams.Store(streams)
+	d.fh.retune.Store(true)
+}
+
+// openProgressParams returns the progress of a file being downloaded
+// into the cache as rc.Params
+func openProgressParams(p vfscache.DownloadProgress) rc.Params {
+	out := rc.Params{
+		"path":    p.Name,
+		"bytes":   p.Bytes,
+		"size":    p.Size,
+		"percent": 0,
+		"speed":   p.Speed,
+		"eta":     nil,
+		"waiting": p.Waiters > 0,
+	}
+	if p.Size > 0 {
+		out["percent"] = int(100 * p.Bytes / p.Size)
+	}
+	if p.Speed > 0 && p.Size >= p.Bytes {
+		out["eta"] = int64(float64(p.Size-p.Bytes) / p.Speed)
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package vfs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// encodingMapper shows the names on a remote with the extra encodings
+// from --vfs-encoding applied.
+//
+// The encoder quotes any characters which would be decoded so this is
+// lossless.
+type encodingMapper struct {
+	enc encoder.MultiEncoder
+}
+
+// String returns a description of the mapping
+func (m encodingMapper) String() string {
+	return fmt.Sprintf("encoding %v", m.enc)
+}
+
+// toVFS encodes a path on the remote
+func (m encodingMapper) toVFS(remote string) string {
+	return m.enc.FromStandardPath(remote)
+}
+
+// toRemote decodes a VFS path
+func (m encodingMapper) toRemote(name string) string {
+	return m.enc.ToStandardPath(name)
+}
+
+// invalidNameMapper stores the names the backend can't store with the
+// encodings from --vfs-invalid-names-encoding applied, as set by
+// --vfs-invalid-names encode.
+//
+// This is the opposite way round to encodingMapper - the names are
+// encoded on the remote and shown decoded by the VFS.
+type invalidNameMapper struct {
+	enc encoder.MultiEncoder
+}
+
+// String returns a description of the mapping
+func (m invalidNameMapper) String() string {
+	return fmt.Sprintf("invalid names %v", m.enc)
+}
+
+// toVFS decodes a path on the remote
+func (m invalidNameMapper) toVFS(remote string) string {
+	return m.enc.ToStandardPath(remote)
+}
+
+// toRemote encodes a VFS path
+func (m invalidNameMapper) toRemote(name string) string {
+	return m.enc.FromStandardPath(name)
+}
+
+// validName returns false if name contains characters which enc
+// encodes, so the backend can't store it.
+//
+// The encoder quotes characters which look encoded already, but these
+// can be stored so are ignored.
+func validName(enc encoder.MultiEncoder, name string) bool {
+	unquote := func(s string) string {
+		return strings.ReplaceAll(s, string(encoder.QuoteRune), "")
+	}
+	return unquote(enc.Encode(name)) == unquote(name)
+}
+
+// backendRestrictions returns the characters the backend f can't
+// store.
+//
+// These are the encodings in the default for the backend's encoding
+// option which its configured encoding leaves out, so it returns
+// EncodeZero for backends using their default encoding or without an
+// encoding option.
+func backendRestrictions(f fs.Fs) encoder.MultiEncoder {
+	ri, _, _, m, err := fs.ConfigFs(fs.ConfigStringFull(f))
+	if err != nil {
+		fs.Debugf(f, "Can't read the backend encoding for --vfs-invalid-names: %v", err)
+		return encoder.EncodeZero
+	}
+	opt := ri.Options.Get(config.ConfigEncoding)
+	if opt == nil {
+		return encoder.EncodeZero
+	}
+	def, ok := opt.Default.(encoder.MultiEncoder)
+	if !ok {
+		return encoder.EncodeZero
+	}
+	enc := def
+	if value, ok := m.Get(config.ConfigEncoding); ok {
+		if err := enc.Set(value); err != nil {
+			fs.Debugf(f, "Can't parse the backend encoding %q for --vfs-invalid-names: %v", value, err)
+			return encoder.EncodeZero
+		}
+	}
+	return def &^ enc
+}
+
+// invalidNamesEncoding returns the characters the backend f can't
+// store for --vfs-invalid-names, which are the ones from
+// --vfs-invalid-names-encoding if set, or the ones the backend's
+// encoding leaves out if not.
+func invalidNamesEncoding(f fs.Fs, opt *vfscommon.Options) encoder.MultiEncoder {
+	if opt.InvalidNames == vfscommon.InvalidNamesAllow {
+		return encoder.EncodeZero
+	}
+	if opt.InvalidNamesEncoding != encoder.EncodeZero {
+		return opt.InvalidNamesEncoding
+	}
+	enc := backendRestrictions(f)
+	if enc != encoder.EncodeZero {
+		fs.Infof(f, "Names containing %v can't be stored as the backend encoding leaves them out - see --vfs-invalid-names", enc)
+	}
+	return enc
+}
+
+// checkName returns EINVAL if name can't be stored on the backend and
+// --vfs-invalid-names is reject
+func (d *Dir) checkName(name string) error {
+	opt := &d.vfs.Opt
+	if opt.InvalidNames != vfscommon.InvalidNamesReject || validName(d.vfs.invalidNames, name) {
+		return nil
+	}
+	fs.Errorf(d, "Can't create %q as the backend can't store it - see --vfs-invalid-names", name)
+	return EINVAL
+}
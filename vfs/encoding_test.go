@@ -0,0 +1,156 @@
+package vfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVFSEncoding(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.Encoding = encoder.EncodeRightSpace | encoder.EncodeRightPeriod
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+
+	file1 := r.WriteObject(ctx, "dir /file1.", "file1 contents", t1)
+	file4 := r.WriteObject(ctx, "dir /file4␠", "file4 contents", t1)
+	r.CheckRemoteItems(t, file1, file4)
+
+	// Listed with the names encoded
+	node, err := vfs.Stat("dir␠")
+	require.NoError(t, err)
+	assert.True(t, node.IsDir())
+	nodes, err := node.(*Dir).ReadDirAll()
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "file1．", nodes[0].Name())
+	assert.Equal(t, "file4‛␠", nodes[1].Name(), "names which look encoded already should be quoted")
+	_, err = vfs.Stat("dir /file1.")
+	assert.Equal(t, ENOENT, err)
+
+	// Read with the encoded name
+	contents, err := vfs.ReadFile("dir␠/file1．")
+	require.NoError(t, err)
+	assert.Equal(t, "file1 contents", string(contents))
+
+	// Created with the names decoded on the remote
+	require.NoError(t, vfs.WriteFile("dir␠/file2␠", []byte("file2 contents"), 0600))
+	require.NoError(t, vfs.Rename("dir␠/file1．", "dir␠/file3．"))
+	file2 := fstest.NewItem("dir /file2 ", "file2 contents", t1)
+	file3 := fstest.NewItem("dir /file3.", "file1 contents", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file2, file3, file4}, []string{"dir "}, fs.ModTimeNotSupported)
+}
+
+func TestVFSEncodingIdentity(t *testing.T) {
+	r := fstest.NewRun(t)
+	newVFS := func(enc encoder.MultiEncoder) *VFS {
+		opt := vfscommon.Opt
+		opt.Encoding = enc
+		vfs := New(r.Fremote, &opt)
+		t.Cleanup(func() { cleanupVFS(t, vfs) })
+		return vfs
+	}
+	plain := newVFS(encoder.EncodeZero)
+	vfs1 := newVFS(encoder.EncodeRightSpace)
+	vfs2 := newVFS(encoder.EncodeRightPeriod)
+
+	// Each mapping has its own identity so the caches keyed on it
+	// aren't shared with the unmapped remote
+	assert.Equal(t, fs.ConfigString(r.Fremote), fs.ConfigString(plain.Fs()))
+	assert.NotEqual(t, fs.ConfigString(plain.Fs()), fs.ConfigString(vfs1.Fs()))
+	assert.NotEqual(t, fs.ConfigString(vfs1.Fs()), fs.ConfigString(vfs2.Fs()))
+	assert.Equal(t, fs.ConfigString(vfs1.Fs()), fs.ConfigString(newVFS(encoder.EncodeRightSpace).Fs()))
+	assert.Equal(t, r.Fremote.Root(), vfs1.Fs().Root())
+}
+
+func TestValidName(t *testing.T) {
+	enc := encoder.EncodeCtl | encoder.EncodeInvalidUtf8
+	for _, test := range []struct {
+		name string
+		want bool
+	}{
+		{"file.txt", true},
+		{"file\x01.txt", false},
+		{"file\xff.txt", false},
+		{"file␁.txt", true},
+		{"file‛.txt", true},
+	} {
+		assert.Equal(t, test.want, validName(enc, test.name), test.name)
+	}
+}
+
+func TestVFSInvalidNames(t *testing.T) {
+	t.Run("reject", func(t *testing.T) {
+		opt := vfscommon.Opt
+		opt.InvalidNames = vfscommon.InvalidNamesReject
+		opt.InvalidNamesEncoding = encoder.EncodeCtl | encoder.EncodeInvalidUtf8
+		r, vfs := newTestVFSOpt(t, &opt)
+
+		assert.ErrorIs(t, vfs.WriteFile("bad\x01file", []byte("contents"), 0600), EINVAL)
+		assert.ErrorIs(t, vfs.Mkdir("bad\x01dir", 0777), EINVAL)
+		require.NoError(t, vfs.WriteFile("file", []byte("contents"), 0600))
+		assert.ErrorIs(t, vfs.Rename("file", "bad\x01file"), EINVAL)
+		fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{
+			fstest.NewItem("file", "contents", t1),
+		}, nil, fs.ModTimeNotSupported)
+	})
+	t.Run("encode", func(t *testing.T) {
+		opt := vfscommon.Opt
+		opt.InvalidNames = vfscommon.InvalidNamesEncode
+		opt.InvalidNamesEncoding = encoder.EncodeCtl | encoder.EncodeInvalidUtf8
+		r, vfs := newTestVFSOpt(t, &opt)
+
+		require.NoError(t, vfs.WriteFile("bad\x01file", []byte("contents"), 0600))
+		contents, err := vfs.ReadFile("bad\x01file")
+		require.NoError(t, err)
+		assert.Equal(t, "contents", string(contents))
+		fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{
+			fstest.NewItem("bad␁file", "contents", t1),
+		}, nil, fs.ModTimeNotSupported)
+	})
+}
+
+func TestBackendRestrictions(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	f, err := fs.NewFs(ctx, dir)
+	require.NoError(t, err)
+	assert.Equal(t, encoder.EncodeZero, backendRestrictions(f))
+
+	f, err = fs.NewFs(ctx, ":local,encoding=None:"+dir)
+	require.NoError(t, err)
+	assert.Equal(t, encoder.OS, backendRestrictions(f))
+
+	f, err = fs.NewFs(ctx, ":local,encoding='Dot':"+dir)
+	require.NoError(t, err)
+	assert.Equal(t, encoder.OS&^encoder.EncodeDot, backendRestrictions(f))
+}
+
+func TestVFSInvalidNamesBackend(t *testing.T) {
+	ctx := context.Background()
+	f, err := fs.NewFs(ctx, ":local,encoding=None:"+t.TempDir())
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		invalidNames vfscommon.InvalidNames
+		encoding     encoder.MultiEncoder
+		want         encoder.MultiEncoder
+	}{
+		{vfscommon.InvalidNamesAllow, encoder.EncodeZero, encoder.EncodeZero},
+		{vfscommon.InvalidNamesReject, encoder.EncodeZero, encoder.OS},
+		{vfscommon.InvalidNamesEncode, encoder.EncodeZero, encoder.OS},
+		{vfscommon.InvalidNamesReject, encoder.EncodeCtl, encoder.EncodeCtl},
+	} {
+		opt := vfscommon.Opt
+		opt.InvalidNames = test.invalidNames
+		opt.InvalidNamesEncoding = test.encoding
+		assert.Equal(t, test.want, invalidNamesEncoding(f, &opt), test.invalidNames.String())
+	}
+}
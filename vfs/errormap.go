@@ -0,0 +1,156 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+)
+
+// errorClass is a class of backend error which --vfs-error-map can
+// return as a specific error number
+type errorClass struct {
+	name     string
+	match    func(err error) bool
+	statuses []int // HTTP status codes in the class
+}
+
+// errorClasses are the classes of backend error in the order they
+// are checked
+var errorClasses = []errorClass{{
+	name:     "ratelimit",
+	match:    fserrors.IsRetryAfterError,
+	statuses: []int{http.StatusTooManyRequests},
+}, {
+	name: "timeout",
+	match: func(err error) bool {
+		var netErr net.Error
+		return errors.Is(err, errTimeout) || errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+	},
+	statuses: []int{http.StatusRequestTimeout, http.StatusGatewayTimeout},
+}, {
+	name: "permission",
+	match: func(err error) bool {
+		return errors.Is(err, os.ErrPermission)
+	},
+	statuses: []int{http.StatusUnauthorized, http.StatusForbidden},
+}, {
+	name: "quota",
+	match: func(err error) bool {
+		_, cause := fserrors.Cause(err)
+		return fserrors.IsErrNoSpace(cause)
+	},
+	statuses: []int{http.StatusInsufficientStorage},
+}}
+
+// httpStatus returns the HTTP status code of err if the backend
+// reports it or 0 if not
+func httpStatus(err error) int {
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		return statusErr.HTTPStatusCode()
+	}
+	return 0
+}
+
+// matches returns true if err is in the class
+func (class errorClass) matches(err error) bool {
+	return class.match(err) || slices.Contains(class.statuses, httpStatus(err))
+}
+
+// errnos are the error numbers which backend errors can be returned as
+var errnos = map[string]error{
+	"EIO":       EIO,
+	"EAGAIN":    EAGAIN,
+	"ETIMEDOUT": ETIMEDOUT,
+	"EACCES":    EACCES,
+	"EPERM":     EPERM,
+	"ENOSPC":    ENOSPC,
+	"EBUSY":     EBUSY,
+	"EROFS":     EROFS,
+	"ENOSYS":    ENOSYS,
+	"EINVAL":    EINVAL,
+	"ENOENT":    ENOENT,
+}
+
+// errorMapping returns backend errors of class as errno
+type errorMapping struct {
+	class errorClass
+	errno error
+}
+
+// parseErrorMap parses the --vfs-error-map list of class=errno pairs
+// returning the mappings in the order the classes are checked
+func parseErrorMap(s string) (mappings []errorMapping, err error) {
+	errnoForClass := map[string]error{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		class, errnoName, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("expecting class=errno but got %q", part)
+		}
+		class = strings.ToLower(strings.TrimSpace(class))
+		errnoName = strings.ToUpper(strings.TrimSpace(errnoName))
+		errno, ok := errnos[errnoName]
+		if !ok {
+			return nil, fmt.Errorf("unknown errno %q in %q", errnoName, part)
+		}
+		errnoForClass[class] = errno
+	}
+	for _, class := range errorClasses {
+		if errno, ok := errnoForClass[class.name]; ok {
+			mappings = append(mappings, errorMapping{class: class, errno: errno})
+			delete(errnoForClass, class.name)
+		}
+	}
+	for class := range errnoForClass {
+		return nil, fmt.Errorf("unknown error class %q", class)
+	}
+	return mappings, nil
+}
+
+// knownError returns true if err is already returned to clients as a
+// specific error number rather than as an I/O error
+func knownError(err error) bool {
+	_, cause := fserrors.Cause(err)
+	if _, ok := cause.(Error); ok {
+		return true
+	}
+	switch cause {
+	case io.EOF, ENOENT, EEXIST, EPERM, EINVAL, ECLOSED,
+		fs.ErrorDirNotFound, fs.ErrorObjectNotFound, fs.ErrorDirExists,
+		fs.ErrorPermissionDenied, fs.ErrorNotImplemented:
+		return true
+	}
+	return false
+}
+
+// mapError returns err as the error number set for its class with
+// --vfs-error-map so clients can tell why the backend failed.
+//
+// Errors which don't match a class, or which are already returned as
+// a specific error number, are returned unchanged.
+func (vfs *VFS) mapError(err error) error {
+	if err == nil || len(vfs.errorMap) == 0 || knownError(err) {
+		return err
+	}
+	for _, mapping := range vfs.errorMap {
+		if mapping.class.matches(err) {
+			fs.Debugf(nil, "vfs: returning %s error as %q: %v", mapping.class.name, mapping.errno, err)
+			// The error number goes last so it is the cause
+			return fmt.Errorf("%w: %w", err, mapping.errno)
+		}
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testErrorMap = "ratelimit=EAGAIN,timeout=ETIMEDOUT,permission=EACCES,quota=ENOSPC"
+
+func TestParseErrorMap(t *testing.T) {
+	assert.Equal(t, "", vfscommon.Opt.ErrorMap)
+
+	mappings, err := parseErrorMap(testErrorMap)
+	require.NoError(t, err)
+	require.Len(t, mappings, 4)
+	assert.Equal(t, "ratelimit", mappings[0].class.name)
+	assert.Equal(t, EAGAIN, mappings[0].errno)
+	assert.Equal(t, "quota", mappings[3].class.name)
+	assert.Equal(t, ENOSPC, mappings[3].errno)
+
+	mappings, err = parseErrorMap(" Quota = eio ,")
+	require.NoError(t, err)
+	require.Len(t, mappings, 1)
+	assert.Equal(t, EIO, mappings[0].errno)
+
+	mappings, err = parseErrorMap("")
+	require.NoError(t, err)
+	assert.Empty(t, mappings)
+
+	for _, bad := range []string{"ratelimit", "ratelimit=EPOTATO", "potato=EIO"} {
+		_, err = parseErrorMap(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+// statusError is a backend error with an HTTP status code
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("HTTP error %d", int(e))
+}
+
+func (e statusError) HTTPStatusCode() int {
+	return int(e)
+}
+
+func TestMapError(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.ErrorMap = testErrorMap
+	_, vfs := newTestVFSOpt(t, &opt)
+
+	assert.NoError(t, vfs.mapError(nil))
+	for _, test := range []struct {
+		err  error
+		want error
+	}{
+		{fserrors.NewErrorRetryAfter(time.Second), EAGAIN},
+		{fmt.Errorf("upload failed: %w", statusError(http.StatusTooManyRequests)), EAGAIN},
+		{fserrors.RetryError(errTimeout), ETIMEDOUT},
+		{statusError(http.StatusGatewayTimeout), ETIMEDOUT},
+		{statusError(http.StatusForbidden), EACCES},
+		{&os.PathError{Op: "open", Path: "file", Err: syscall.EACCES}, EACCES},
+		{&os.PathError{Op: "write", Path: "file", Err: syscall.ENOSPC}, ENOSPC},
+		{statusError(http.StatusInsufficientStorage), ENOSPC},
+	} {
+		err := vfs.mapError(test.err)
+		_, cause := fserrors.Cause(err)
+		assert.Equal(t, test.want, cause, test.err.Error())
+		// The backend error is still wrapped
+		assert.ErrorIs(t, err, test.err)
+	}
+
+	// Errors which are already known or don't match are unchanged
+	for _, err := range []error{ENOENT, io.EOF, errors.New("potato"), errors.New("403 Forbidden"), statusError(http.StatusNotFound)} {
+		assert.Equal(t, err, vfs.mapError(err))
+	}
+
+	vfs.errorMap = nil
+	err := statusError(http.StatusForbidden)
+	assert.Equal(t, err, vfs.mapError(err))
+}
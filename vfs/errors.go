@@ -3,6 +3,7 @@
 package vfs
 
 import (
+	"errors"
 	"fmt"
 	"os"
 )
@@ -21,6 +22,12 @@ const (
 	EROFS
 	ENOSYS
 	ELOOP
+	ENOSPC
+	EBUSY
+	EAGAIN
+	ETIMEDOUT
+	EACCES
+	EIO
 )
 
 // Errors which have exact counterparts in os
@@ -32,6 +39,10 @@ var (
 	ECLOSED = os.ErrClosed
 )
 
+// errTimeout is wrapped in the retryable errors returned when a read or
+// write exceeds --vfs-read-timeout or --vfs-write-timeout
+var errTimeout = errors.New("vfs: operation timed out")
+
 var errorNames = []string{
 	OK:        "Success",
 	ENOTEMPTY: "Directory not empty",
@@ -40,6 +51,12 @@ var errorNames = []string{
 	EROFS:     "Read only file system",
 	ENOSYS:    "Function not implemented",
 	ELOOP:     "Too many symbolic links",
+	ENOSPC:    "No space left on device",
+	EBUSY:     "Device or resource busy",
+	EAGAIN:    "Resource temporarily unavailable",
+	ETIMEDOUT: "Connection timed out",
+	EACCES:    "Permission denied",
+	EIO:       "Input/output error",
 }
 
 // Error renders the error as a string
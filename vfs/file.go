@@ -18,6 +18,7 @@ import (
 	"github.com/rclone/rclone/fs/log"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/vfs/vfscommon"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // The File object is tightly coupled to the Dir object. Since they
@@ -53,11 +54,15 @@ type File struct {
 	writers          []Handle                        // writers for this file
 	virtualModTime   *time.Time                      // modtime for backends with Precision == fs.ModTimeNotSupported
 	pendingModTime   time.Time                       // will be applied once o becomes available, i.e. after file was written
+	keptModTime      time.Time                       // modtime kept with --vfs-modtime-store for keptModTimeObj - zero if none
+	keptModTimeObj   fs.Object                       // object keptModTime was read or set for
 	pendingRenameFun func(ctx context.Context) error // will be run/renamed after all writers close
 	sys              atomic.Value                    // user defined info to be attached here
 	nwriters         atomic.Int32                    // len(writers)
 	appendMode       bool                            // file was opened with O_APPEND
 	isLink           bool                            // file represents a symlink
+	birthTime        time.Time                       // creation time read from birthTimeObj for --vfs-birth-time
+	birthTimeObj     fs.Object                       // object birthTime was read from
 }
 
 // newFile creates a new File
@@ -69,7 +74,7 @@ func newFile(d *Dir, dPath string, o fs.Object, leaf string) *File {
 		dPath: dPath,
 		o:     o,
 		leaf:  leaf,
-		inode: newInode(),
+		inode: d.vfs.inodes.get(path.Join(dPath, leaf)),
 	}
 	if o != nil {
 		f.size.Store(o.Size())
@@ -206,6 +211,7 @@ func (f *File) Node() Node {
 func (f *File) renameDir(dPath string) {
 	f.mu.RLock()
 	f.dPath = dPath
+	f.d.vfs.inodes.rename(f.inode, path.Join(dPath, f.leaf))
 	f.mu.RUnlock()
 }
 
@@ -286,6 +292,11 @@ func (f *File) rename(ctx context.Context, destDir *Dir, newName string) error {
 				fs.Infof(f.Path(), "File.Rename failed in Cache: %v", err)
 			}
 		}
+		if newObject != nil {
+			d.vfs.recent.remove(o.Remote())
+			d.vfs.recent.add(newObject)
+			d.vfs.modTimes.rename(ctx, o.Remote(), newObject)
+		}
 		// Update the node with the new details
 		fs.Debugf(f.Path(), "Updating file with %v %p", newObject, f)
 		// f.rename(destDir, newObject)
@@ -305,6 +316,7 @@ func (f *File) rename(ctx context.Context, destDir *Dir, newName string) error {
 	f.d = destDir
 	f.dPath = dPath
 	f.leaf = newName
+	destDir.vfs.inodes.rename(f.inode, path.Join(dPath, newName))
 	writing := f._writingInProgress()
 	f.mu.Unlock()
 
@@ -397,6 +409,11 @@ func (f *File) ModTime() (modTime time.Time) {
 	// Read the modtime from a dirty item if it exists
 	if f.d.vfs.Opt.CacheMode >= vfscommon.CacheModeMinimal {
 		if item := f.d.vfs.cache.DirtyItem(f._cachePath()); item != nil {
+			if d.vfs.Opt.DirtyWriteTime {
+				if writeTime := item.GetWriteTime(); !writeTime.IsZero() {
+					return f._roundModTime(writeTime)
+				}
+			}
 			modTime, err := item.GetModTime()
 			if err != nil {
 				fs.Errorf(f._path(), "ModTime: Item GetModTime failed: %v", err)
@@ -408,6 +425,11 @@ func (f *File) ModTime() (modTime time.Time) {
 	if !pendingModTime.IsZero() {
 		return f._roundModTime(pendingModTime)
 	}
+	if o != nil && d.vfs.modTimes != nil {
+		if keptModTime, ok := f.getKeptModTime(o); ok {
+			return keptModTime
+		}
+	}
 	if virtualModTime != nil && !virtualModTime.IsZero() {
 		fs.Debugf(f._path(), "Returning virtual modtime %v", f.virtualModTime)
 		return f._roundModTime(*virtualModTime)
@@ -450,6 +472,35 @@ func (f *File) Size() int64 {
 	return nonNegative(f.o.Size())
 }
 
+// DiskSize returns the number of bytes to report the file as using on
+// disk.
+//
+// This is the size of the file unless --vfs-report-size is allocated
+// in which case it is the size of the data stored in the cache.
+func (f *File) DiskSize() int64 {
+	vfs := f.VFS()
+	if vfs.Opt.ReportSize != vfscommon.ReportSizeAllocated {
+		return f.Size()
+	}
+	if vfs.cache == nil {
+		return 0
+	}
+	return min(vfs.cache.DiskSize(f.CachePath()), f.Size())
+}
+
+// LinkCount returns the number of hard links to report for the file.
+//
+// This is 1 unless --vfs-link-count dedup is set, in which case files
+// whose cache files are shared with --vfs-cache-dedup report the
+// number of files sharing it.
+func (f *File) LinkCount() uint32 {
+	vfs := f.VFS()
+	if vfs.Opt.LinkCount != vfscommon.LinkCountDedup || vfs.cache == nil {
+		return 1
+	}
+	return uint32(vfs.cache.LinkCount(f.CachePath()))
+}
+
 // SetModTime sets the modtime for the file
 //
 // if NoModTime is set then it does nothing
@@ -459,14 +510,20 @@ func (f *File) SetModTime(modTime time.Time) error {
 	if f.d.vfs.Opt.NoModTime {
 		return nil
 	}
-	if f.d.vfs.Opt.ReadOnly {
+	if f.d.readOnly() || f._isCacheStatusFile() || f._isMetadataFile() {
+		return EROFS
+	}
+
+	// With --vfs-no-write-back the time can only be kept in the VFS
+	inCache := f.d.vfs.cache != nil && f.d.vfs.cache.Exists(f._cachePath())
+	if f.d.vfs.Opt.NoWriteBack && !inCache && f.o != nil {
 		return EROFS
 	}
 
 	f.pendingModTime = modTime
 
 	// set the time of the file in the cache
-	if f.d.vfs.cache != nil && f.d.vfs.cache.Exists(f._cachePath()) {
+	if inCache {
 		f.d.vfs.cache.SetModTime(f._cachePath(), f.pendingModTime)
 	}
 
@@ -485,12 +542,21 @@ func (f *File) _applyPendingModTime() error {
 	if f.pendingModTime.IsZero() {
 		return nil
 	}
+	// keep the time in the VFS only with --vfs-no-write-back
+	if f.d.vfs.Opt.NoWriteBack {
+		return nil
+	}
 	defer func() { f.pendingModTime = time.Time{} }()
 
 	if f.o == nil {
 		return errors.New("cannot apply ModTime, file object is not available")
 	}
 
+	// keep the time if the backend can't set it
+	if f.d.vfs.modTimes != nil && f.d.f.Precision() == fs.ModTimeNotSupported {
+		return f._keepModTime(f.pendingModTime)
+	}
+
 	dt := f.pendingModTime.Sub(f.o.ModTime(context.Background()))
 	modifyWindow := f.o.Fs().Precision()
 	if dt < modifyWindow && dt > -modifyWindow {
@@ -503,7 +569,11 @@ func (f *File) _applyPendingModTime() error {
 	switch err {
 	case nil:
 		fs.Debugf(f.o, "Applied pending mod time %v OK", f.pendingModTime)
+		f.d.vfs.dirIndex.remove(f.d.path)
 	case fs.ErrorCantSetModTime, fs.ErrorCantSetModTimeWithoutDelete:
+		if f.d.vfs.modTimes != nil {
+			return f._keepModTime(f.pendingModTime)
+		}
 		// do nothing, in order to not break "touch somefile" if it exists already
 	default:
 		fs.Errorf(f.o, "Failed to apply pending mod time %v: %v", f.pendingModTime, err)
@@ -549,6 +619,8 @@ func (f *File) setObject(o fs.Object) {
 
 	// Release File.mu before calling Dir method
 	d.addObject(f)
+	d.vfs.recent.add(o)
+	f.keepUploadedModTime(o)
 }
 
 // Update the object but don't update the directory cache - for use by
@@ -614,13 +686,25 @@ func (f *File) openRead() (fh *ReadFileHandle, err error) {
 	return fh, nil
 }
 
+// openReadRemote opens the version of the file last written to the
+// remote for read, ignoring any data being written to it
+//
+// It returns ENOENT if the file hasn't been written to the remote yet.
+func (f *File) openReadRemote() (fh *ReadFileHandle, err error) {
+	if f.getObject() == nil {
+		fs.Debugf(f.Path(), "Can't open for read while being written as not on the remote yet")
+		return nil, ENOENT
+	}
+	return f.openRead()
+}
+
 // openWrite open the file for write
 func (f *File) openWrite(flags int) (fh *WriteFileHandle, err error) {
 	f.mu.RLock()
 	d := f.d
 	f.mu.RUnlock()
 
-	if d.vfs.Opt.ReadOnly {
+	if d.readOnly() {
 		return nil, EROFS
 	}
 	// fs.Debugf(f.Path(), "File.openWrite")
@@ -633,6 +717,55 @@ func (f *File) openWrite(flags int) (fh *WriteFileHandle, err error) {
 	return fh, nil
 }
 
+// skipCache returns true if a read only open of this file should
+// bypass the cache.
+//
+// This is the case for zero-byte files when --vfs-cache-skip-empty is
+// set. Files which are being written or are in the cache already are
+// never skipped so that a file which is created then written to will
+// be cached as normal.
+//
+// Old versions shown by --vfs-expose-versions and the files in the
+// .recent directory are never cached.
+//
+// Call without the mutex held
+func (f *File) skipCache() bool {
+	f.mu.RLock()
+	d := f.d
+	f.mu.RUnlock()
+	if d.versions != versionsNone || d.recent {
+		// old versions and recent files are always read directly
+		return true
+	}
+	if !d.vfs.Opt.CacheSkipEmpty || f.writingInProgress() {
+		return false
+	}
+	return f.Size() == 0
+}
+
+// cacheHasSpace checks there is room in the cache to read the file
+// according to --vfs-cache-space-check.
+//
+// It returns false if the file should be read without the cache.
+func (f *File) cacheHasSpace() (ok bool, err error) {
+	f.mu.RLock()
+	d := f.d
+	f.mu.RUnlock()
+	if d.vfs.Opt.CacheSpaceCheck == vfscommon.CacheSpaceCheckOff {
+		return true, nil
+	}
+	err = d.vfs.cache.CheckSpace(f.CachePath(), f.Size())
+	if err == nil {
+		return true, nil
+	}
+	if d.vfs.Opt.CacheSpaceCheck == vfscommon.CacheSpaceCheckPassthrough {
+		fs.Logf(f.Path(), "Reading without the VFS cache: %v", err)
+		return false, nil
+	}
+	fs.Errorf(f.Path(), "Can't open for reading: %v", err)
+	return false, err
+}
+
 // openRW open the file for read and write using a temporary file
 //
 // It uses the open flags passed in.
@@ -642,7 +775,7 @@ func (f *File) openRW(flags int) (fh *RWFileHandle, err error) {
 	f.mu.RUnlock()
 
 	// FIXME chunked
-	if flags&accessModeMask != os.O_RDONLY && d.vfs.Opt.ReadOnly {
+	if flags&accessModeMask != os.O_RDONLY && d.readOnly() {
 		return nil, EROFS
 	}
 	// fs.Debugf(f.Path(), "File.openRW")
@@ -669,7 +802,16 @@ func (f *File) Remove() (err error) {
 	d := f.d
 	f.mu.RUnlock()
 
-	if d.vfs.Opt.ReadOnly {
+	if f.readOnly() {
+		return EROFS
+	}
+
+	// Only files which aren't on the remote can be removed with
+	// --vfs-no-write-back
+	f.mu.RLock()
+	onRemote := f.o != nil
+	f.mu.RUnlock()
+	if onRemote && d.vfs.Opt.NoWriteBack {
 		return EROFS
 	}
 
@@ -682,7 +824,11 @@ func (f *File) Remove() (err error) {
 	f.muRW.Lock() // muRW must be locked before mu to avoid
 	f.mu.Lock()   // deadlock in RWFileHandle.openPending and .close
 	if f.o != nil {
-		err = f.o.Remove(context.TODO())
+		if d.vfs.deletes != nil {
+			d.vfs.deletes.add(f.o)
+		} else {
+			err = f.o.Remove(context.TODO())
+		}
 	}
 	f.mu.Unlock()
 	f.muRW.Unlock()
@@ -700,6 +846,8 @@ func (f *File) Remove() (err error) {
 	// called with File.mu released when there is no error removing the underlying file
 	if err == nil {
 		d.delObject(f.Name())
+		d.vfs.recent.remove(f.Path())
+		d.vfs.modTimes.remove(f.Path())
 	}
 	return err
 }
@@ -737,7 +885,9 @@ func (f *File) Fs() fs.Fs {
 	return f.d.Fs()
 }
 
-// MaxSymlinkIterations is the largest number of symlink evaluations EvalSymlinks will do.
+// MaxSymlinkIterations is the default for the largest number of
+// symlink evaluations resolveNode will do, as set by
+// --vfs-links-max-depth.
 const MaxSymlinkIterations = 32
 
 // If f is a symlink then it resolves it to a new Node.
@@ -749,16 +899,26 @@ const MaxSymlinkIterations = 32
 // It returns the target node after the evaluation of all symbolic
 // links.
 //
-// It returns an error if too many symlinks need to be resolved
-// (ELOOP) or there is a loop.
+// It returns an error if more than --vfs-links-max-depth symlinks
+// need to be resolved or there is a loop (ELOOP). The symlink is not
+// followed so is presented to the caller as a broken link.
 func (f *File) resolveNode() (target Node, err error) {
 	defer log.Trace(f.Path(), "")("target=%v, err=%v", &target, &err)
-	seen := make(map[string]struct{})
-	for range MaxSymlinkIterations {
+	maxDepth := f.d.vfs.Opt.LinksMaxDepth
+	if maxDepth <= 0 {
+		maxDepth = MaxSymlinkIterations
+	}
+	chain := []string{f.Path()}
+	seen := map[string]struct{}{f.Path(): {}}
+	for depth := 0; ; depth++ {
 		// If f isn't a symlink, we've arrived at the target
 		if !f.IsSymlink() {
 			return f, nil
 		}
+		if depth >= maxDepth {
+			fs.Errorf(chain[0], "vfs: not following more than %d symlinks (--vfs-links-max-depth): %s", maxDepth, strings.Join(chain, " -> "))
+			return nil, ELOOP
+		}
 
 		// Read the symlink
 		fd, err := f.Open(os.O_RDONLY | o_SYMLINK)
@@ -783,13 +943,15 @@ func (f *File) resolveNode() (target Node, err error) {
 		}
 
 		// Clean the path, rclone style
-		targetPath = path.Clean(targetPath)
+		targetPath = strings.TrimPrefix(path.Clean(targetPath), "/")
 		if targetPath == "." {
 			targetPath = ""
 		}
+		chain = append(chain, targetPath)
 
 		// Check if we've already seen this path
 		if _, ok := seen[targetPath]; ok {
+			fs.Errorf(chain[0], "vfs: symlink loop detected: %s", strings.Join(chain, " -> "))
 			return nil, ELOOP
 		}
 		seen[targetPath] = struct{}{}
@@ -807,7 +969,6 @@ func (f *File) resolveNode() (target Node, err error) {
 			return target, nil
 		}
 	}
-	return nil, ELOOP
 }
 
 // Open also also implements the internal flag o_SYMLINK which instead
@@ -831,6 +992,16 @@ const o_SYMLINK = 0x4000_0000 //nolint:revive
 // We ignore O_SYNC and O_EXCL
 func (f *File) Open(flags int) (fd Handle, err error) {
 	defer log.Trace(f.Path(), "flags=%s", decodeOpenFlags(flags))("fd=%v, err=%v", &fd, &err)
+	span := f.startSpan("vfs.Open")
+	defer func() {
+		if span.IsRecording() {
+			span.SetAttributes(attribute.String("vfs.flags", decodeOpenFlags(flags)))
+		}
+		vfscommon.EndSpan(span, err)
+		if err == nil {
+			f.recordAccess(accessOpen, 0)
+		}
+	}()
 	var (
 		write    bool // if set need write support
 		read     bool // if set need read support
@@ -868,6 +1039,15 @@ func (f *File) Open(flags int) (fd Handle, err error) {
 		return nil, EPERM
 	}
 
+	// The cache status and metadata files can only be read and are
+	// never cached
+	if f.isCacheStatusFile() || f.isMetadataFile() {
+		if write || flags&(os.O_APPEND|os.O_TRUNC) != 0 {
+			return nil, EROFS
+		}
+		return f.openRead()
+	}
+
 	// If append is set then set read to force openRW
 	if flags&os.O_APPEND != 0 {
 		read = true
@@ -886,11 +1066,29 @@ func (f *File) Open(flags int) (fd Handle, err error) {
 		write = true
 	}
 
+	// Check the file against the remote if required
+	if err = f.revalidate(write); err != nil {
+		return nil, err
+	}
+
 	// Open the correct sort of handle
 	f.mu.RLock()
 	d := f.d
 	f.mu.RUnlock()
-	CacheMode := d.vfs.Opt.CacheMode
+	CacheMode := d.vfs.cacheMode()
+	if write && d.vfs.Opt.NoWriteBack && CacheMode < vfscommon.CacheModeWrites {
+		fs.Errorf(f.Path(), "Can't open for write as the cache isn't usable and --vfs-no-write-back is set")
+		return nil, EROFS
+	}
+	if read && !write && f.activeWriters() > 0 {
+		switch d.vfs.Opt.OpenWhileWriting {
+		case vfscommon.OpenWhileWritingBusy:
+			fs.Debugf(f.Path(), "Can't open for read while being written: %v", EBUSY)
+			return nil, EBUSY
+		case vfscommon.OpenWhileWritingRemote:
+			return f.openReadRemote()
+		}
+	}
 	if CacheMode >= vfscommon.CacheModeMinimal && (d.vfs.cache.InUse(f.CachePath()) || d.vfs.cache.Exists(f.CachePath())) {
 		fd, err = f.openRW(flags)
 	} else if read && write {
@@ -903,13 +1101,21 @@ func (f *File) Open(flags int) (fd Handle, err error) {
 			fd, err = f.openWrite(flags)
 		}
 	} else if write {
-		if CacheMode >= vfscommon.CacheModeWrites {
+		if CacheMode >= vfscommon.CacheModeWrites && !f.writeThrough(flags) {
 			fd, err = f.openRW(flags)
 		} else {
 			fd, err = f.openWrite(flags)
 		}
 	} else if read {
-		if CacheMode >= vfscommon.CacheModeFull {
+		useCache := CacheMode >= vfscommon.CacheModeFull && !f.skipCache()
+		if useCache {
+			d.vfs.cache.WarnLargeFile(f.CachePath(), f.Size())
+			useCache, err = f.cacheHasSpace()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if useCache {
 			fd, err = f.openRW(flags)
 		} else {
 			fd, err = f.openRead()
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/rclone/rclone/fs"
@@ -13,6 +14,7 @@ import (
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/mockfs"
 	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/vfs/vfscache"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -163,6 +165,38 @@ func TestFileSetModTime(t *testing.T) {
 	}
 }
 
+func TestFileDirtyWriteTime(t *testing.T) {
+	for _, dirtyWriteTime := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dirtyWriteTime=%v", dirtyWriteTime), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.CacheMode = vfscommon.CacheModeWrites
+			opt.WriteBack = fs.Duration(2 * time.Second)
+			opt.DirtyWriteTime = dirtyWriteTime
+			_, vfs := newTestVFSOpt(t, &opt)
+
+			// Write the file then set its modtime to the past
+			// as "cp -p" would
+			before := time.Now()
+			require.NoError(t, vfs.WriteFile("file1", []byte("file1 contents"), 0600))
+			node, err := vfs.Stat("file1")
+			require.NoError(t, err)
+			file := node.(*File)
+			require.NoError(t, file.SetModTime(t1))
+
+			modTime := file.ModTime()
+			if dirtyWriteTime {
+				assert.False(t, modTime.Before(before.Truncate(time.Second)), "modTime %v before write at %v", modTime, before)
+			} else {
+				fstest.AssertTimeEqualWithPrecision(t, "file1", t1, modTime, vfs.f.Precision())
+			}
+
+			// Once uploaded the modtime comes from the remote
+			vfs.WaitForWriters(waitForWritersDelay)
+			fstest.AssertTimeEqualWithPrecision(t, "file1", t1, file.ModTime(), vfs.f.Precision())
+		})
+	}
+}
+
 func fileCheckContents(t *testing.T, file *File) {
 	fd, err := file.Open(os.O_RDONLY)
 	require.NoError(t, err)
@@ -273,6 +307,253 @@ func TestFileRemoveAll(t *testing.T) {
 	assert.Equal(t, EROFS, err)
 }
 
+func TestFileOpenCacheSpaceCheck(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.CacheMaxSize = 10
+	opt.CacheSpaceCheck = vfscommon.CacheSpaceCheckError
+	opt.WriteBack = writeBackDelay
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	file1 := r.WriteObject(context.Background(), "file1", "file1 contents", t1)
+	small := r.WriteObject(context.Background(), "small", "small", t1)
+	r.CheckRemoteItems(t, file1, small)
+
+	open := func(name string) (Handle, error) {
+		node, err := vfs.Stat(name)
+		require.NoError(t, err)
+		return node.(*File).Open(os.O_RDONLY)
+	}
+
+	// A file which fits is read through the cache
+	fd, err := open("small")
+	require.NoError(t, err)
+	_, ok := fd.(*RWFileHandle)
+	assert.True(t, ok)
+	require.NoError(t, fd.Close())
+
+	// A file which doesn't fit fails to open
+	_, err = open("file1")
+	assert.ErrorIs(t, err, vfscache.ErrNoSpace)
+	assert.False(t, vfs.cache.Exists("file1"))
+
+	// Or is read without the cache
+	vfs.Opt.CacheSpaceCheck = vfscommon.CacheSpaceCheckPassthrough
+	fd, err = open("file1")
+	require.NoError(t, err)
+	_, ok = fd.(*ReadFileHandle)
+	assert.True(t, ok)
+	buf := make([]byte, 32)
+	n, err := fd.Read(buf)
+	assert.True(t, err == nil || err == io.EOF)
+	assert.Equal(t, "file1 contents", string(buf[:n]))
+	require.NoError(t, fd.Close())
+	assert.False(t, vfs.cache.Exists("file1"))
+}
+
+func TestFileOpenCacheSkipEmpty(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.CacheSkipEmpty = true
+	opt.WriteBack = writeBackDelay
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	empty := r.WriteObject(context.Background(), "empty", "", t1)
+	r.CheckRemoteItems(t, empty)
+
+	node, err := vfs.Stat("empty")
+	require.NoError(t, err)
+	file := node.(*File)
+
+	// Reading a zero-byte file should bypass the cache
+	fd, err := file.Open(os.O_RDONLY)
+	require.NoError(t, err)
+	_, ok := fd.(*ReadFileHandle)
+	assert.True(t, ok)
+	require.NoError(t, fd.Close())
+	assert.False(t, vfs.cache.Exists("empty"))
+
+	// Writing to it should go through the cache
+	fd, err = file.Open(os.O_WRONLY | os.O_TRUNC)
+	require.NoError(t, err)
+	_, ok = fd.(*RWFileHandle)
+	assert.True(t, ok)
+	_, err = fd.WriteString("hello")
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+
+	// Now it has contents it should be read from the cache
+	fd, err = file.Open(os.O_RDONLY)
+	require.NoError(t, err)
+	_, ok = fd.(*RWFileHandle)
+	assert.True(t, ok)
+	require.NoError(t, fd.Close())
+}
+
+func TestFileDiskSize(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	file1 := r.WriteObject(context.Background(), "file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+	node, err := vfs.Stat("file1")
+	require.NoError(t, err)
+	file := node.(*File)
+
+	// The apparent size is reported by default
+	assert.Equal(t, int64(14), file.DiskSize())
+
+	// Nothing is stored in the cache yet
+	vfs.Opt.ReportSize = vfscommon.ReportSizeAllocated
+	assert.Equal(t, int64(0), file.DiskSize())
+
+	// Read the file into the cache
+	fd, err := file.Open(os.O_RDONLY)
+	require.NoError(t, err)
+	_, err = io.ReadAll(fd)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	assert.Equal(t, int64(14), file.DiskSize())
+	assert.Equal(t, int64(14), file.Size())
+}
+
+func TestFileXattrs(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	file1 := r.WriteObject(context.Background(), "file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+	node, err := vfs.Stat("file1")
+	require.NoError(t, err)
+	file := node.(*File)
+
+	// No extended attributes unless asked for
+	assert.Nil(t, file.Xattrs())
+
+	vfs.Opt.CacheXattrs = true
+	assert.Equal(t, map[string]string{
+		XattrCacheMode:   "full",
+		XattrCacheStatus: "uncached",
+		XattrCacheBytes:  "0",
+	}, file.Xattrs())
+
+	// Read the file into the cache
+	fd, err := file.Open(os.O_RDONLY)
+	require.NoError(t, err)
+	_, err = io.ReadAll(fd)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	xattrs := file.Xattrs()
+	assert.Equal(t, "cached", xattrs[XattrCacheStatus])
+	assert.Equal(t, "14", xattrs[XattrCacheBytes])
+
+	// Change the file so it is dirty
+	fd, err = file.Open(os.O_WRONLY)
+	require.NoError(t, err)
+	_, err = fd.WriteAt([]byte("FILE1"), 0)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	assert.Equal(t, "dirty", file.Xattrs()[XattrCacheStatus])
+}
+
+func TestFileOpenWriteThrough(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	opt.WriteThrough = "big/**"
+	opt.WriteBack = writeBackDelay
+	r, vfs := newTestVFSOpt(t, &opt)
+	require.NoError(t, vfs.Mkdir("big", 0777))
+
+	write := func(name string) Handle {
+		fd, err := vfs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0777)
+		require.NoError(t, err)
+		_, err = fd.WriteString("contents")
+		require.NoError(t, err)
+		require.NoError(t, fd.Close())
+		return fd
+	}
+
+	// Matching files are written straight to the remote
+	fd := write("big/file1")
+	_, ok := fd.(*WriteFileHandle)
+	assert.True(t, ok)
+	assert.False(t, vfs.cache.Exists("big/file1"))
+	file1 := fstest.NewItem("big/file1", "contents", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{"big"}, fs.ModTimeNotSupported)
+
+	// Other files use the cache
+	fd = write("file2")
+	_, ok = fd.(*RWFileHandle)
+	assert.True(t, ok)
+	assert.True(t, vfs.cache.Exists("file2"))
+
+	// Matching files which aren't being truncated use the cache
+	fd, err := vfs.OpenFile("big/file1", os.O_WRONLY, 0777)
+	require.NoError(t, err)
+	_, ok = fd.(*RWFileHandle)
+	assert.True(t, ok)
+	require.NoError(t, fd.Close())
+}
+
+func TestFileOpenWhileWriting(t *testing.T) {
+	for _, test := range []struct {
+		mode vfscommon.OpenWhileWriting
+		want string
+		err  error
+	}{
+		{mode: vfscommon.OpenWhileWritingCache, want: "new contents"},
+		{mode: vfscommon.OpenWhileWritingRemote, want: "file1 contents"},
+		{mode: vfscommon.OpenWhileWritingBusy, err: EBUSY},
+	} {
+		t.Run(test.mode.String(), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.CacheMode = vfscommon.CacheModeWrites
+			opt.WriteBack = writeBackDelay
+			opt.OpenWhileWriting = test.mode
+			r, vfs := newTestVFSOpt(t, &opt)
+			r.WriteObject(context.Background(), "file1", "file1 contents", t1)
+
+			w, err := vfs.OpenFile("file1", os.O_WRONLY|os.O_TRUNC, 0777)
+			require.NoError(t, err)
+			_, err = w.WriteString("new contents")
+			require.NoError(t, err)
+
+			fd, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+			if test.err != nil {
+				assert.Equal(t, test.err, err)
+			} else {
+				require.NoError(t, err)
+				buf := make([]byte, 64)
+				n, _ := fd.Read(buf)
+				assert.Equal(t, test.want, string(buf[:n]))
+				require.NoError(t, fd.Close())
+			}
+			require.NoError(t, w.Close())
+
+			// Once closed the file opens as usual
+			fd, err = vfs.OpenFile("file1", os.O_RDONLY, 0777)
+			require.NoError(t, err)
+			require.NoError(t, fd.Close())
+		})
+	}
+
+	// A file which isn't on the remote yet can't be read from it
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	opt.WriteBack = writeBackDelay
+	opt.OpenWhileWriting = vfscommon.OpenWhileWritingRemote
+	_, vfs := newTestVFSOpt(t, &opt)
+	w, err := vfs.OpenFile("new", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	_, err = vfs.OpenFile("new", os.O_RDONLY, 0777)
+	assert.Equal(t, ENOENT, err)
+	require.NoError(t, w.Close())
+}
+
 func TestFileOpen(t *testing.T) {
 	_, _, file, _ := fileCreate(t, vfscommon.CacheModeOff)
 
@@ -415,6 +696,55 @@ func TestFileRename(t *testing.T) {
 	}
 }
 
+func TestFileResolveNodeLoop(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.Links = true
+	opt.LinksMaxDepth = 2
+	r, vfs := newTestVFSOpt(t, &opt)
+	r.WriteObject(context.Background(), "file", "hello", t1)
+
+	for _, link := range []struct{ oldname, newname string }{
+		{"file", "link1"},
+		{"link1", "link2"},
+		{"link2", "link3"},
+		{"loop2", "loop1"},
+		{"loop1", "loop2"},
+	} {
+		_, err := vfs.CreateSymlink(link.oldname, link.newname)
+		require.NoError(t, err, link.newname)
+	}
+
+	readLink := func(name string) (string, error) {
+		fd, err := vfs.OpenFile(name, os.O_RDONLY, 0)
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			require.NoError(t, fd.Close())
+		}()
+		b, err := io.ReadAll(fd)
+		return string(b), err
+	}
+
+	// Within the max depth links are followed
+	got, err := readLink("link2")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", got)
+
+	// Beyond it they are treated as broken
+	_, err = readLink("link3")
+	assert.Equal(t, ELOOP, err)
+
+	// Loops are detected
+	_, err = readLink("loop1")
+	assert.Equal(t, ELOOP, err)
+
+	// The links can still be read
+	target, err := vfs.Readlink("loop1")
+	require.NoError(t, err)
+	assert.Equal(t, "loop2", target)
+}
+
 func TestFileStructSize(t *testing.T) {
 	t.Logf("File struct has size %d bytes", unsafe.Sizeof(File{}))
 }
@@ -0,0 +1,280 @@
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// pathMapper maps the paths on a remote to the paths shown by the VFS
+// and back again
+type pathMapper interface {
+	fmt.Stringer
+
+	// toVFS converts a path on the remote into a VFS path
+	toVFS(remote string) string
+
+	// toRemote converts a VFS path into a path on the remote
+	toRemote(name string) string
+}
+
+// dirLister is an optional interface for a pathMapper which needs to
+// change the entries listed in a directory
+type dirLister interface {
+	// listDir is called with the entries listed from the remote
+	// for the VFS directory dir before they are mapped
+	listDir(ctx context.Context, f fs.Fs, dir string, entries fs.DirEntries) (fs.DirEntries, error)
+}
+
+// dirRemover is an optional interface for a pathMapper which needs to
+// know when a directory is removed from the remote
+type dirRemover interface {
+	// removedDir is called after the VFS directory dir has been
+	// removed or moved away
+	removedDir(ctx context.Context, f fs.Fs, dir string)
+}
+
+// mappedFs wraps an fs.Fs so the paths on it are shown as mapped by a
+// pathMapper.
+//
+// Paths are mapped on the way out of the backend and mapped back on
+// the way in so the paths on the remote are unchanged.
+type mappedFs struct {
+	fs.Fs
+	m        pathMapper
+	name     string
+	features *fs.Features
+}
+
+// newMappedFs returns f with its paths mapped with m
+func newMappedFs(ctx context.Context, f fs.Fs, m pathMapper) *mappedFs {
+	mf := &mappedFs{
+		Fs: f,
+		m:  m,
+		// Add the mapping to the name so the VFS cache directory,
+		// the fs cache and the active VFS cache don't mistake
+		// this Fs for f.
+		name: fmt.Sprintf("%s~%08x", f.Name(), crc32.ChecksumIEEE([]byte(m.String()))),
+	}
+	mf.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		BucketBasedRootOK:       true,
+		SlowModTime:             true,
+		SlowHash:                true,
+		PartialUploads:          true,
+		NoMultiThreading:        true,
+	}).Fill(ctx, mf).Mask(ctx, f).WrapsFs(mf, f)
+	return mf
+}
+
+// unwrap returns the underlying object of o if it came from f
+func (f *mappedFs) unwrap(o fs.Object) fs.Object {
+	if mo, ok := o.(*mappedObject); ok && mo.f == f {
+		return mo.Object
+	}
+	return o
+}
+
+// newObject wraps o so it shows the mapped path
+func (f *mappedFs) newObject(o fs.Object) fs.Object {
+	if o == nil {
+		return nil
+	}
+	return &mappedObject{Object: o, f: f}
+}
+
+// removedDir tells the mapper that the VFS directory dir has gone
+func (f *mappedFs) removedDir(ctx context.Context, dir string) {
+	if do, ok := f.m.(dirRemover); ok {
+		do.removedDir(ctx, f.Fs, dir)
+	}
+}
+
+// Name of the remote with the mapping added
+func (f *mappedFs) Name() string {
+	return f.name
+}
+
+// String returns a description of the Fs
+func (f *mappedFs) String() string {
+	return fmt.Sprintf("%v with %v", f.Fs, f.m)
+}
+
+// Features returns the optional features of this Fs
+func (f *mappedFs) Features() *fs.Features {
+	return f.features
+}
+
+// List the objects and directories in dir into entries
+func (f *mappedFs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, f.m.toRemote(dir))
+	if err != nil {
+		return nil, err
+	}
+	if do, ok := f.m.(dirLister); ok {
+		entries, err = do.listDir(ctx, f.Fs, dir, entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			entries[i] = f.newObject(x)
+		case fs.Directory:
+			entries[i] = fs.NewDirWrapper(f.m.toVFS(x.Remote()), x)
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote
+func (f *mappedFs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(ctx, f.m.toRemote(remote))
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+func (f *mappedFs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := f.Fs.Put(ctx, in, fs.NewOverrideRemote(src, f.m.toRemote(src.Remote())), options...)
+	return f.newObject(o), err
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *mappedFs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := f.Fs.Features().PutStream(ctx, in, fs.NewOverrideRemote(src, f.m.toRemote(src.Remote())), options...)
+	return f.newObject(o), err
+}
+
+// Mkdir makes the directory
+func (f *mappedFs) Mkdir(ctx context.Context, dir string) error {
+	return f.Fs.Mkdir(ctx, f.m.toRemote(dir))
+}
+
+// Rmdir removes the directory if empty
+func (f *mappedFs) Rmdir(ctx context.Context, dir string) error {
+	err := f.Fs.Rmdir(ctx, f.m.toRemote(dir))
+	if err == nil {
+		f.removedDir(ctx, dir)
+	}
+	return err
+}
+
+// Purge deletes all the files in the directory
+func (f *mappedFs) Purge(ctx context.Context, dir string) error {
+	err := f.Fs.Features().Purge(ctx, f.m.toRemote(dir))
+	if err == nil {
+		f.removedDir(ctx, dir)
+	}
+	return err
+}
+
+// Copy src to this remote using server-side copy operations
+func (f *mappedFs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	o, err := f.Fs.Features().Copy(ctx, f.unwrap(src), f.m.toRemote(remote))
+	return f.newObject(o), err
+}
+
+// Move src to this remote using server-side move operations
+func (f *mappedFs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	o, err := f.Fs.Features().Move(ctx, f.unwrap(src), f.m.toRemote(remote))
+	return f.newObject(o), err
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote using
+// server-side move operations
+func (f *mappedFs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*mappedFs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	err := f.Fs.Features().DirMove(ctx, srcFs.Fs, srcFs.m.toRemote(srcRemote), f.m.toRemote(dstRemote))
+	if err == nil {
+		srcFs.removedDir(ctx, srcRemote)
+	}
+	return err
+}
+
+// ChangeNotify calls notifyFunc with the mapped path of each change
+func (f *mappedFs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	f.Fs.Features().ChangeNotify(ctx, func(path string, entryType fs.EntryType) {
+		notifyFunc(f.m.toVFS(path), entryType)
+	}, pollIntervalChan)
+}
+
+// About gets quota information from the Fs
+func (f *mappedFs) About(ctx context.Context) (*fs.Usage, error) {
+	return f.Fs.Features().About(ctx)
+}
+
+// DirCacheFlush resets the directory cache
+func (f *mappedFs) DirCacheFlush() {
+	f.Fs.Features().DirCacheFlush()
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *mappedFs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// mappedObject is an object on a mappedFs
+type mappedObject struct {
+	fs.Object
+	f *mappedFs
+}
+
+// Fs returns the Fs the object is on
+func (o *mappedObject) Fs() fs.Info {
+	return o.f
+}
+
+// Remote returns the mapped remote path
+func (o *mappedObject) Remote() string {
+	return o.f.m.toVFS(o.Object.Remote())
+}
+
+// String returns a description of the Object
+func (o *mappedObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Remote()
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *mappedObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return o.Object.Update(ctx, in, fs.NewOverrideRemote(src, o.Object.Remote()), options...)
+}
+
+// UnWrap returns the wrapped Object
+func (o *mappedObject) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*mappedFs)(nil)
+	_ fs.PutStreamer     = (*mappedFs)(nil)
+	_ fs.Purger          = (*mappedFs)(nil)
+	_ fs.Copier          = (*mappedFs)(nil)
+	_ fs.Mover           = (*mappedFs)(nil)
+	_ fs.DirMover        = (*mappedFs)(nil)
+	_ fs.ChangeNotifier  = (*mappedFs)(nil)
+	_ fs.Abouter         = (*mappedFs)(nil)
+	_ fs.DirCacheFlusher = (*mappedFs)(nil)
+	_ fs.UnWrapper       = (*mappedFs)(nil)
+	_ fs.Object          = (*mappedObject)(nil)
+	_ fs.ObjectUnWrapper = (*mappedObject)(nil)
+)
@@ -0,0 +1,58 @@
+package vfs
+
+import (
+	"github.com/rclone/rclone/fs/object"
+)
+
+// defaultMetadataExtension is the extension used for the metadata
+// files if --vfs-expose-metadata is set without --vfs-metadata-extension
+const defaultMetadataExtension = ".metadata"
+
+// _isMetadataFile returns true if f is a file made by metadataFile
+// rather than a real file with the same name
+//
+// call with the lock held
+func (f *File) _isMetadataFile() bool {
+	if _, isMemory := f.o.(*object.MemoryObject); !isMemory {
+		return false
+	}
+	_, found := f.d.vfs.isMetadataFile(f.leaf)
+	return found
+}
+
+// isMetadataFile returns true if f is a file made by metadataFile
+// rather than a real file with the same name
+func (f *File) isMetadataFile() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f._isMetadataFile()
+}
+
+// addMetadataFiles adds a metadata file for each real file and
+// directory in items for --vfs-expose-metadata, unless there is an
+// entry with that name already, and returns the new items.
+//
+// The metadata is read for each file, which may need an extra
+// transaction per file on some backends.
+func (d *Dir) addMetadataFiles(items Nodes) Nodes {
+	ext := d.vfs.Opt.MetadataExtension
+	names := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		names[item.Name()] = struct{}{}
+	}
+	for _, item := range items {
+		// Don't make metadata for markers or metadata files
+		if file, ok := item.(*File); ok {
+			if _, isMemory := file.getObject().(*object.MemoryObject); isMemory {
+				continue
+			}
+		}
+		leaf := item.Name() + ext
+		if _, found := names[leaf]; found {
+			continue
+		}
+		names[leaf] = struct{}{}
+		items = append(items, d.metadataFile(leaf, item))
+	}
+	return items
+}
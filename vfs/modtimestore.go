@@ -0,0 +1,302 @@
+package vfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscache"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// modTimeMetadataKey is the metadata key the modification time is
+// kept in with --vfs-modtime-store metadata
+const modTimeMetadataKey = "rclone-vfs-mtime"
+
+// modTimeSaveDelay is how long changes to the index are collected
+// before it is written to disk
+const modTimeSaveDelay = 5 * time.Second
+
+// keptModTime is a modification time kept in the index along with
+// the details of the object it was set on
+type keptModTime struct {
+	ModTime       time.Time // modification time set on the file - zero if none
+	RemoteModTime time.Time // modification time of the object on the remote
+	Size          int64     // size of the object on the remote
+}
+
+// modTimeStore keeps the modification times set on files which the
+// backend can't set, either in the metadata of the objects or in an
+// index in the cache directory, as set by --vfs-modtime-store.
+//
+// With metadata the index is kept in memory only, as a cache of the
+// times read from the metadata so it is only read once per object.
+//
+// The methods may be called on a nil *modTimeStore which does nothing.
+type modTimeStore struct {
+	mode      vfscommon.ModTimeStore
+	indexPath string // path of the index file or "" if not saved
+	mu        sync.Mutex
+	index     map[string]keptModTime // modification times by path
+	saveTimer *time.Timer            // saves the index when it has changed
+}
+
+// newModTimeStore makes a modTimeStore for f keeping the
+// modification times as set by mode
+func newModTimeStore(f fs.Fs, mode vfscommon.ModTimeStore) (s *modTimeStore, err error) {
+	s = &modTimeStore{
+		mode:  mode,
+		index: make(map[string]keptModTime),
+	}
+	if mode == vfscommon.ModTimeStoreMetadata {
+		if !f.Features().UserMetadata {
+			return nil, errors.New("backend can't write metadata")
+		}
+		return s, nil
+	}
+	dir, err := vfscache.RootDir(f, "vfsModTime")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory for index: %w", err)
+	}
+	s.indexPath = filepath.Join(dir, "modtimes.json")
+	in, err := os.Open(s.indexPath)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	if err = json.NewDecoder(in).Decode(&s.index); err != nil {
+		return nil, fmt.Errorf("failed to read index %q: %w", s.indexPath, err)
+	}
+	return s, nil
+}
+
+// _changed is called when the index has changed and saves it to disk
+// after modTimeSaveDelay, so lots of changes are saved together
+//
+// call with the lock held
+func (s *modTimeStore) _changed() {
+	if s.indexPath == "" || s.saveTimer != nil {
+		return
+	}
+	s.saveTimer = time.AfterFunc(modTimeSaveDelay, s.flush)
+}
+
+// flush saves the index to disk now if it has changed
+func (s *modTimeStore) flush() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saveTimer == nil {
+		return
+	}
+	s.saveTimer.Stop()
+	s.saveTimer = nil
+	if err := s._save(); err != nil {
+		fs.Errorf(nil, "vfs: %v", err)
+	}
+}
+
+// _save writes the index to disk
+//
+// call with the lock held
+func (s *modTimeStore) _save() (err error) {
+	tmpPath := s.indexPath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to save modification times: %w", err)
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "\t")
+	err = encoder.Encode(s.index)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save modification times: %w", err)
+	}
+	return os.Rename(tmpPath, s.indexPath)
+}
+
+// set keeps modTime as the modification time of o
+func (s *modTimeStore) set(ctx context.Context, o fs.Object, modTime time.Time) error {
+	if s == nil {
+		return nil
+	}
+	if s.mode == vfscommon.ModTimeStoreMetadata {
+		do, ok := o.(fs.SetMetadataer)
+		if !ok {
+			return fs.ErrorNotImplemented
+		}
+		err := do.SetMetadata(ctx, fs.Metadata{modTimeMetadataKey: modTime.Format(time.RFC3339Nano)})
+		if err != nil {
+			return err
+		}
+	}
+	s.put(ctx, o, modTime)
+	return nil
+}
+
+// put puts modTime in the index as the modification time of o
+func (s *modTimeStore) put(ctx context.Context, o fs.Object, modTime time.Time) {
+	kept := keptModTime{
+		ModTime:       modTime,
+		RemoteModTime: o.ModTime(ctx),
+		Size:          o.Size(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index[o.Remote()] = kept
+	s._changed()
+}
+
+// get returns the modification time kept for o, or false if there
+// isn't one.
+//
+// Modification times in the index are ignored if o has been changed
+// since they were set.
+func (s *modTimeStore) get(ctx context.Context, o fs.Object) (modTime time.Time, ok bool) {
+	if s == nil {
+		return modTime, false
+	}
+	s.mu.Lock()
+	kept, found := s.index[o.Remote()]
+	s.mu.Unlock()
+	if found && kept.Size == o.Size() && kept.RemoteModTime.Equal(o.ModTime(ctx)) {
+		return kept.ModTime, !kept.ModTime.IsZero()
+	}
+	if s.mode != vfscommon.ModTimeStoreMetadata {
+		return modTime, false
+	}
+	modTime, ok = readModTimeMetadata(ctx, o)
+	s.put(ctx, o, modTime)
+	return modTime, ok
+}
+
+// readModTimeMetadata reads the modification time kept in the
+// metadata of o, or false if there isn't one.
+func readModTimeMetadata(ctx context.Context, o fs.Object) (modTime time.Time, ok bool) {
+	metadata, err := fs.GetMetadata(ctx, o)
+	if err != nil {
+		fs.Debugf(o, "vfs: failed to read modification time from metadata: %v", err)
+		return modTime, false
+	}
+	value, found := metadata[modTimeMetadataKey]
+	if !found {
+		return modTime, false
+	}
+	modTime, err = time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		fs.Debugf(o, "vfs: failed to parse modification time from metadata: %v", err)
+		return time.Time{}, false
+	}
+	return modTime, true
+}
+
+// rename moves the modification time kept for the object at remote
+// to o which it has been renamed to
+func (s *modTimeStore) rename(ctx context.Context, remote string, o fs.Object) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept, found := s.index[remote]
+	if !found {
+		return
+	}
+	delete(s.index, remote)
+	kept.RemoteModTime, kept.Size = o.ModTime(ctx), o.Size()
+	s.index[o.Remote()] = kept
+	s._changed()
+}
+
+// remove forgets the modification time kept for the object at remote
+func (s *modTimeStore) remove(remote string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.index[remote]; !found {
+		return
+	}
+	delete(s.index, remote)
+	s._changed()
+}
+
+// _keepModTime keeps modTime as the modification time of the file
+// with --vfs-modtime-store as the backend can't set it
+//
+// call with the lock held
+func (f *File) _keepModTime(modTime time.Time) error {
+	if f.o == nil {
+		return errors.New("cannot keep ModTime, file object is not available")
+	}
+	err := f.d.vfs.modTimes.set(context.TODO(), f.o, modTime)
+	if err != nil {
+		fs.Errorf(f.o, "Failed to keep mod time %v with --vfs-modtime-store %v: %v", modTime, f.d.vfs.Opt.ModTimeStore, err)
+		return err
+	}
+	fs.Debugf(f.o, "Kept mod time %v with --vfs-modtime-store %v", modTime, f.d.vfs.Opt.ModTimeStore)
+	f.keptModTime, f.keptModTimeObj = modTime, f.o
+	return nil
+}
+
+// getKeptModTime returns the modification time kept for o with
+// --vfs-modtime-store, or false if there isn't one
+//
+// Call without the mutex held
+func (f *File) getKeptModTime(o fs.Object) (modTime time.Time, ok bool) {
+	f.mu.RLock()
+	if f.keptModTimeObj == o {
+		modTime = f.keptModTime
+		f.mu.RUnlock()
+		return modTime, !modTime.IsZero()
+	}
+	f.mu.RUnlock()
+	modTime, _ = f.d.vfs.modTimes.get(context.TODO(), o)
+	f.mu.Lock()
+	f.keptModTime, f.keptModTimeObj = modTime, o
+	f.mu.Unlock()
+	return modTime, !modTime.IsZero()
+}
+
+// keepUploadedModTime is called when o has been uploaded from the
+// cache. If the backend didn't set the modification time of the
+// cache file on o then it is kept with --vfs-modtime-store.
+//
+// Call without the mutex held
+func (f *File) keepUploadedModTime(o fs.Object) {
+	vfs := f.VFS()
+	if vfs.modTimes == nil || vfs.cache == nil {
+		return
+	}
+	item := vfs.cache.DirtyItem(f.CachePath())
+	if item == nil {
+		return
+	}
+	modTime, err := item.GetModTime()
+	if err != nil || modTime.IsZero() {
+		return
+	}
+	if precision := vfs.f.Precision(); precision != fs.ModTimeNotSupported {
+		if dt := o.ModTime(context.TODO()).Sub(modTime); dt < precision && dt > -precision {
+			return
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.o == o {
+		_ = f._keepModTime(modTime)
+	}
+}
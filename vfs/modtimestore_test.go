@@ -0,0 +1,197 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noModTimeFs is an Fs which says it can't set modification times
+type noModTimeFs struct {
+	fs.Fs
+}
+
+func (f noModTimeFs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+func TestVFSModTimeStoreIndex(t *testing.T) {
+	ctx := context.Background()
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() { _ = config.SetCacheDir(oldCacheDir) }()
+
+	r := fstest.NewRun(t)
+	r.WriteObject(ctx, "file.txt", "hello", t1)
+	var vfs *VFS
+	// reopen starts a new VFS with the modification times stored by mode
+	reopen := func(mode vfscommon.ModTimeStore) {
+		if vfs != nil {
+			cleanupVFS(t, vfs)
+		}
+		opt := vfscommon.Opt
+		opt.ModTimeStore = mode
+		vfs = New(noModTimeFs{Fs: r.Fremote}, &opt)
+	}
+	defer func() {
+		cleanupVFS(t, vfs)
+	}()
+	modTime := func() time.Time {
+		node, err := vfs.Stat("file.txt")
+		require.NoError(t, err)
+		return node.ModTime()
+	}
+
+	reopen(vfscommon.ModTimeStoreIndex)
+	require.NotNil(t, vfs.modTimes)
+	node, err := vfs.Stat("file.txt")
+	require.NoError(t, err)
+	require.NoError(t, node.SetModTime(t2))
+	assert.True(t, t2.Equal(node.ModTime()))
+
+	// The time is kept in the index not on the remote
+	o, err := r.Fremote.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	fstest.AssertTimeEqualWithPrecision(t, "file.txt", t1, o.ModTime(ctx), time.Second)
+
+	// It is read from the index by a new VFS
+	reopen(vfscommon.ModTimeStoreIndex)
+	assert.True(t, t2.Equal(modTime()))
+	reopen(vfscommon.ModTimeStoreOff)
+	assert.False(t, t2.Equal(modTime()))
+
+	// Renaming the file keeps the time
+	reopen(vfscommon.ModTimeStoreIndex)
+	require.NoError(t, vfs.Rename("file.txt", "renamed.txt"))
+	reopen(vfscommon.ModTimeStoreIndex)
+	_, err = vfs.Stat("renamed.txt")
+	require.NoError(t, err)
+	require.NoError(t, vfs.Rename("renamed.txt", "file.txt"))
+	assert.True(t, t2.Equal(modTime()))
+
+	// It is ignored once the file has changed on the remote
+	r.WriteObject(ctx, "file.txt", "hello world", t3)
+	reopen(vfscommon.ModTimeStoreIndex)
+	assert.False(t, t2.Equal(modTime()))
+
+	// Removing the file forgets it
+	require.NoError(t, vfs.Remove("file.txt"))
+	assert.Empty(t, vfs.modTimes.index)
+}
+
+func TestVFSModTimeStoreSaveDelay(t *testing.T) {
+	ctx := context.Background()
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() { _ = config.SetCacheDir(oldCacheDir) }()
+
+	r := fstest.NewRun(t)
+	r.WriteObject(ctx, "file1.txt", "hello", t1)
+	r.WriteObject(ctx, "file2.txt", "hello", t1)
+	s, err := newModTimeStore(r.Fremote, vfscommon.ModTimeStoreIndex)
+	require.NoError(t, err)
+
+	// Changes are saved together after the delay
+	for _, name := range []string{"file1.txt", "file2.txt"} {
+		o, err := r.Fremote.NewObject(ctx, name)
+		require.NoError(t, err)
+		require.NoError(t, s.set(ctx, o, t2))
+	}
+	_, err = os.Stat(s.indexPath)
+	assert.True(t, os.IsNotExist(err))
+	s.flush()
+	s2, err := newModTimeStore(r.Fremote, vfscommon.ModTimeStoreIndex)
+	require.NoError(t, err)
+	assert.Len(t, s2.index, 2)
+}
+
+// metadataObject is an object which counts the reads of its metadata
+type metadataObject struct {
+	fs.Object
+	metadata fs.Metadata
+	reads    int
+}
+
+func (o *metadataObject) Metadata(ctx context.Context) (fs.Metadata, error) {
+	o.reads++
+	return o.metadata, nil
+}
+
+func (o *metadataObject) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	o.metadata.Merge(metadata)
+	return nil
+}
+
+func TestVFSModTimeStoreMetadataCache(t *testing.T) {
+	ctx := context.Background()
+	s := &modTimeStore{
+		mode:  vfscommon.ModTimeStoreMetadata,
+		index: make(map[string]keptModTime),
+	}
+	o := &metadataObject{Object: mockobject.New("file.txt"), metadata: fs.Metadata{}}
+
+	// Files without a time are only read once
+	for range 2 {
+		_, ok := s.get(ctx, o)
+		assert.False(t, ok)
+	}
+	assert.Equal(t, 1, o.reads)
+
+	// Nor are files with one
+	require.NoError(t, s.set(ctx, o, t2))
+	for range 2 {
+		modTime, ok := s.get(ctx, o)
+		assert.True(t, ok)
+		assert.True(t, t2.Equal(modTime))
+	}
+	assert.Equal(t, 1, o.reads)
+
+	// Until they are read from the remote again
+	s.remove("file.txt")
+	modTime, ok := s.get(ctx, o)
+	assert.True(t, ok)
+	assert.True(t, t2.Equal(modTime))
+	assert.Equal(t, 2, o.reads)
+}
+
+func TestVFSModTimeStoreUploaded(t *testing.T) {
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() { _ = config.SetCacheDir(oldCacheDir) }()
+
+	r := fstest.NewRun(t)
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	opt.WriteBack = 0
+	opt.ModTimeStore = vfscommon.ModTimeStoreIndex
+	vfs := New(noModTimeFs{Fs: r.Fremote}, &opt)
+	defer cleanupVFS(t, vfs)
+
+	// Write a file through the cache with the time set on the
+	// cache file only
+	fh, err := vfs.OpenFile("file.txt", os.O_CREATE|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = fh.Write([]byte("hello"))
+	require.NoError(t, err)
+	vfs.cache.SetModTime("file.txt", t2)
+	require.NoError(t, fh.Close())
+	vfs.WaitForWriters(waitForWritersDelay)
+
+	// The time is kept for the uploaded object
+	node, err := vfs.Stat("file.txt")
+	require.NoError(t, err)
+	o := node.(*File).getObject()
+	require.NotNil(t, o)
+	modTime, ok := vfs.modTimes.get(context.Background(), o)
+	require.True(t, ok)
+	assert.True(t, t2.Equal(modTime))
+}
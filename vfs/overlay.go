@@ -0,0 +1,617 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// whiteoutPrefix is put before the leaf of a marker file kept in the
+// writable layer to hide the entry with the rest of the name in the
+// base layer, which can't be removed itself
+const whiteoutPrefix = ".wh."
+
+// overlayFs shows a writable Fs on top of a read only base Fs as set
+// by --vfs-overlay-base, or by --vfs-write-back-remote with the remote
+// as the base.
+//
+// Listings are merged and objects are found in the layer which takes
+// precedence first. All writes go to the writable Fs and objects only
+// in the base are copied up to the writable Fs when they are updated.
+// Removing or renaming files and directories in the base hides them
+// with whiteout markers in the writable Fs.
+type overlayFs struct {
+	fs.Fs            // the writable layer
+	base       fs.Fs // the read only base layer
+	precedence vfscommon.OverlayPrecedence
+	name       string
+	root       string
+	features   *fs.Features
+}
+
+// newOverlayFs returns f shown on top of base
+func newOverlayFs(ctx context.Context, f, base fs.Fs, precedence vfscommon.OverlayPrecedence) *overlayFs {
+	of := &overlayFs{
+		Fs:         f,
+		base:       base,
+		precedence: precedence,
+		// Add the base to the name and root so the VFS cache
+		// directory, the fs cache and the active VFS cache don't
+		// mistake this Fs for f or f over a different base.
+		name: f.Name() + "~overlay",
+		root: fmt.Sprintf("%s~%08x", f.Root(), crc32.ChecksumIEEE([]byte(fs.ConfigString(base)+" "+precedence.String()))),
+	}
+	of.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		BucketBasedRootOK:       true,
+		SlowModTime:             true,
+		SlowHash:                true,
+		PartialUploads:          true,
+		NoMultiThreading:        true,
+	}).Fill(ctx, of).Mask(ctx, f).WrapsFs(of, f)
+	return of
+}
+
+// layers returns the Fs to look in, the one taking precedence first
+func (f *overlayFs) layers() (first, second fs.Fs) {
+	if f.precedence == vfscommon.OverlayPrecedenceBase {
+		return f.base, f.Fs
+	}
+	return f.Fs, f.base
+}
+
+// unwrap returns the underlying object of o if it came from the
+// writable layer of f
+//
+// It returns nil if o is only in the base layer.
+func (f *overlayFs) unwrap(o fs.Object) fs.Object {
+	if oo, ok := o.(*overlayObject); ok && oo.f == f {
+		obj, base := oo.layer()
+		if base {
+			return nil
+		}
+		return obj
+	}
+	return o
+}
+
+// newObject wraps o which came from the layer lf
+func (f *overlayFs) newObject(o fs.Object, lf fs.Fs) fs.Object {
+	if o == nil {
+		return nil
+	}
+	return &overlayObject{Object: o, f: f, base: lf == f.base}
+}
+
+// whiteoutName returns the name of the marker hiding remote in the
+// base layer
+func whiteoutName(remote string) string {
+	dir, leaf := path.Split(remote)
+	return dir + whiteoutPrefix + leaf
+}
+
+// isWhiteout returns true if remote is a whiteout marker
+func isWhiteout(remote string) bool {
+	return strings.HasPrefix(path.Base(remote), whiteoutPrefix)
+}
+
+// whiteoutTarget returns the remote hidden by the whiteout marker
+func whiteoutTarget(marker string) string {
+	dir, leaf := path.Split(marker)
+	return dir + strings.TrimPrefix(leaf, whiteoutPrefix)
+}
+
+// whitedOut returns true if remote or one of the directories it is in
+// has been hidden in the base layer
+func (f *overlayFs) whitedOut(ctx context.Context, remote string) (bool, error) {
+	for ; remote != "" && remote != "."; remote = path.Dir(remote) {
+		_, err := f.Fs.NewObject(ctx, whiteoutName(remote))
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, fs.ErrorObjectNotFound) && !errors.Is(err, fs.ErrorDirNotFound) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// whiteout hides remote in the base layer
+func (f *overlayFs) whiteout(ctx context.Context, remote string) error {
+	src := object.NewStaticObjectInfo(whiteoutName(remote), time.Now(), 0, true, nil, f.Fs)
+	_, err := f.Fs.Put(ctx, bytes.NewReader(nil), src)
+	if err != nil {
+		return fmt.Errorf("failed to hide %q in %v: %w", remote, f.base, err)
+	}
+	fs.Debugf(remote, "hidden in %v", f.base)
+	return nil
+}
+
+// inBase returns true if remote is shown from the base layer as an
+// object, or a directory if isDir is set
+func (f *overlayFs) inBase(ctx context.Context, remote string, isDir bool) (bool, error) {
+	hidden, err := f.whitedOut(ctx, remote)
+	if err != nil || hidden {
+		return false, err
+	}
+	if isDir {
+		_, err = f.base.List(ctx, remote)
+		if errors.Is(err, fs.ErrorDirNotFound) {
+			return false, nil
+		}
+	} else {
+		_, err = f.base.NewObject(ctx, remote)
+		if errors.Is(err, fs.ErrorObjectNotFound) {
+			return false, nil
+		}
+	}
+	return err == nil, err
+}
+
+// hideInBase hides remote in the base layer if it is shown from there
+func (f *overlayFs) hideInBase(ctx context.Context, remote string, isDir bool) error {
+	inBase, err := f.inBase(ctx, remote, isDir)
+	if err != nil || !inBase {
+		return err
+	}
+	return f.whiteout(ctx, remote)
+}
+
+// Name of the remote with the overlay added
+func (f *overlayFs) Name() string {
+	return f.name
+}
+
+// Root of the remote with the base added
+func (f *overlayFs) Root() string {
+	return f.root
+}
+
+// String returns a description of the Fs
+func (f *overlayFs) String() string {
+	return fmt.Sprintf("%v over %v", f.Fs, f.base)
+}
+
+// Features returns the optional features of this Fs
+func (f *overlayFs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *overlayFs) Precision() time.Duration {
+	return max(f.Fs.Precision(), f.base.Precision())
+}
+
+// Hashes returns the hash types supported by both layers
+func (f *overlayFs) Hashes() hash.Set {
+	return f.Fs.Hashes().Overlap(f.base.Hashes())
+}
+
+// List the objects and directories in dir into entries
+//
+// The entries of both layers are merged with the entries of the layer
+// taking precedence shown if the names clash. Entries of the base
+// layer hidden by whiteout markers are left out, as are the markers.
+// The directory is only not found if it isn't in either layer.
+func (f *overlayFs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	hideBase, err := f.whitedOut(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	layerEntries := map[fs.Fs]fs.DirEntries{}
+	found := false
+	for _, lf := range []fs.Fs{f.Fs, f.base} {
+		if lf == f.base && hideBase {
+			continue
+		}
+		entries, err := lf.List(ctx, dir)
+		if errors.Is(err, fs.ErrorDirNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		layerEntries[lf] = entries
+	}
+	if !found {
+		return nil, fs.ErrorDirNotFound
+	}
+	// The whiteout markers hide the base entries
+	seen := map[string]struct{}{}
+	hidden := map[string]struct{}{}
+	for _, entry := range layerEntries[f.Fs] {
+		if _, ok := entry.(fs.Object); ok && isWhiteout(entry.Remote()) {
+			hidden[whiteoutTarget(entry.Remote())] = struct{}{}
+		}
+	}
+	first, second := f.layers()
+	for _, lf := range []fs.Fs{first, second} {
+		for _, entry := range layerEntries[lf] {
+			if _, clash := seen[entry.Remote()]; clash {
+				continue
+			}
+			if lf == f.Fs && isWhiteout(entry.Remote()) {
+				continue
+			}
+			if _, ok := hidden[entry.Remote()]; ok && lf == f.base {
+				continue
+			}
+			seen[entry.Remote()] = struct{}{}
+			if o, ok := entry.(fs.Object); ok {
+				entry = f.newObject(o, lf)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote in the layer taking precedence
+// first, leaving out objects hidden in the base layer
+func (f *overlayFs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if isWhiteout(remote) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	first, second := f.layers()
+	for _, lf := range []fs.Fs{first, second} {
+		if lf == f.base {
+			hidden, err := f.whitedOut(ctx, remote)
+			if err != nil {
+				return nil, err
+			}
+			if hidden {
+				continue
+			}
+		}
+		o, err := lf.NewObject(ctx, remote)
+		if err == nil {
+			return f.newObject(o, lf), nil
+		}
+		if !errors.Is(err, fs.ErrorObjectNotFound) {
+			return nil, err
+		}
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// written wraps o which has just been written to the writable layer
+//
+// If the base layer takes precedence the object with the same name in
+// it is hidden so the new contents are shown.
+func (f *overlayFs) written(ctx context.Context, o fs.Object, err error) (fs.Object, error) {
+	if err != nil {
+		return nil, err
+	}
+	if f.precedence == vfscommon.OverlayPrecedenceBase {
+		if err = f.hideInBase(ctx, o.Remote(), false); err != nil {
+			return nil, err
+		}
+	}
+	return f.newObject(o, f.Fs), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+func (f *overlayFs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := f.Fs.Put(ctx, in, src, options...)
+	return f.written(ctx, o, err)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *overlayFs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := f.Fs.Features().PutStream(ctx, in, src, options...)
+	return f.written(ctx, o, err)
+}
+
+// Rmdir removes the directory dir
+//
+// If the directory is in the base layer it is hidden there.
+func (f *overlayFs) Rmdir(ctx context.Context, dir string) error {
+	// Remove the whiteout markers in the directory as the directory
+	// is going to be hidden or wasn't in the base layer
+	entries, err := f.Fs.List(ctx, dir)
+	inWritable := err == nil
+	if err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+		return err
+	}
+	for _, entry := range entries {
+		if o, ok := entry.(fs.Object); ok && isWhiteout(o.Remote()) {
+			if err = o.Remove(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if inWritable {
+		if err = f.Fs.Rmdir(ctx, dir); err != nil {
+			return err
+		}
+	}
+	inBase := false
+	if dir != "" {
+		inBase, err = f.inBase(ctx, dir, true)
+		if err != nil {
+			return err
+		}
+	}
+	if !inBase {
+		if !inWritable {
+			return fs.ErrorDirNotFound
+		}
+		return nil
+	}
+	return f.whiteout(ctx, dir)
+}
+
+// srcObject returns the underlying object of src and whether it is in
+// the base layer or nil if it isn't on f
+func (f *overlayFs) srcObject(src fs.Object) (obj fs.Object, base bool) {
+	oo, ok := src.(*overlayObject)
+	if !ok || oo.f != f {
+		return nil, false
+	}
+	return oo.layer()
+}
+
+// Copy src to this remote using server-side copy operations
+//
+// Objects in the base layer are copied up to the writable layer.
+func (f *overlayFs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, base := f.srcObject(src)
+	if srcObj == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	var o fs.Object
+	var err error
+	if base {
+		o, err = operations.Copy(ctx, f.Fs, nil, remote, srcObj)
+	} else {
+		o, err = f.Fs.Features().Copy(ctx, srcObj, remote)
+	}
+	return f.written(ctx, o, err)
+}
+
+// Move src to this remote using server-side move operations
+//
+// Objects in the base layer are copied up to the writable layer and
+// hidden in the base layer.
+func (f *overlayFs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, base := f.srcObject(src)
+	if srcObj == nil {
+		return nil, fs.ErrorCantMove
+	}
+	var o fs.Object
+	var err error
+	if base {
+		o, err = operations.Copy(ctx, f.Fs, nil, remote, srcObj)
+	} else {
+		o, err = f.Fs.Features().Move(ctx, srcObj, remote)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = f.hideInBase(ctx, srcObj.Remote(), false); err != nil {
+		return nil, err
+	}
+	return f.written(ctx, o, nil)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote using
+// server-side move operations
+//
+// Directories which are in the base layer are moved an entry at a
+// time, copying up the objects in the base layer, and hidden there.
+func (f *overlayFs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*overlayFs)
+	if !ok || srcFs != f {
+		return fs.ErrorCantDirMove
+	}
+	inBase, err := f.inBase(ctx, srcRemote, true)
+	if err != nil {
+		return err
+	}
+	if !inBase {
+		return f.Fs.Features().DirMove(ctx, f.Fs, srcRemote, dstRemote)
+	}
+	_, err = f.List(ctx, dstRemote)
+	if err == nil {
+		return fs.ErrorDirExists
+	} else if !errors.Is(err, fs.ErrorDirNotFound) {
+		return err
+	}
+	return f.moveDir(ctx, srcRemote, dstRemote)
+}
+
+// moveDir moves the entries of srcRemote to dstRemote one at a time
+// then removes srcRemote
+func (f *overlayFs) moveDir(ctx context.Context, srcRemote, dstRemote string) error {
+	entries, err := f.List(ctx, srcRemote)
+	if err != nil {
+		return err
+	}
+	if err = f.Fs.Mkdir(ctx, dstRemote); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		dst := path.Join(dstRemote, path.Base(entry.Remote()))
+		switch x := entry.(type) {
+		case fs.Object:
+			_, err = f.Move(ctx, x, dst)
+		case fs.Directory:
+			err = f.moveDir(ctx, x.Remote(), dst)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return f.Rmdir(ctx, srcRemote)
+}
+
+// ChangeNotify calls notifyFunc with the changes to the writable layer
+func (f *overlayFs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	f.Fs.Features().ChangeNotify(ctx, notifyFunc, pollIntervalChan)
+}
+
+// About gets quota information from the writable layer
+func (f *overlayFs) About(ctx context.Context) (*fs.Usage, error) {
+	return f.Fs.Features().About(ctx)
+}
+
+// DirCacheFlush resets the directory cache of both layers
+func (f *overlayFs) DirCacheFlush() {
+	f.Fs.Features().DirCacheFlush()
+	if do := f.base.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *overlayFs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// overlayObject is an object on an overlayFs
+type overlayObject struct {
+	fs.Object
+	f    *overlayFs
+	mu   sync.Mutex
+	base bool // set if Object is in the base layer
+}
+
+// layer returns the underlying object, which changes when it is
+// copied up, and whether it is in the base layer
+func (o *overlayObject) layer() (obj fs.Object, base bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.Object, o.base
+}
+
+// obj returns the underlying object
+func (o *overlayObject) obj() fs.Object {
+	obj, _ := o.layer()
+	return obj
+}
+
+// Fs returns the Fs the object is on
+func (o *overlayObject) Fs() fs.Info {
+	return o.f
+}
+
+// String returns a description of the Object
+func (o *overlayObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Remote()
+}
+
+// Remote returns the remote path
+func (o *overlayObject) Remote() string {
+	return o.obj().Remote()
+}
+
+// ModTime returns the modification time of the object
+func (o *overlayObject) ModTime(ctx context.Context) time.Time {
+	return o.obj().ModTime(ctx)
+}
+
+// Size returns the size of the object in bytes
+func (o *overlayObject) Size() int64 {
+	return o.obj().Size()
+}
+
+// Hash returns the selected checksum of the object
+func (o *overlayObject) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	return o.obj().Hash(ctx, ht)
+}
+
+// Open the object for read
+func (o *overlayObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	return o.obj().Open(ctx, options...)
+}
+
+// Update in to the object with the modTime given of the given size
+//
+// If the object is in the base layer then it is copied up to the
+// writable layer and hidden in the base layer, so the copy is shown
+// whichever layer takes precedence.
+func (o *overlayObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, base := o.layer()
+	if !base {
+		return obj.Update(ctx, in, src, options...)
+	}
+	newObj, err := o.f.Fs.Put(ctx, in, fs.NewOverrideRemote(src, obj.Remote()), options...)
+	if err != nil {
+		return err
+	}
+	if err = o.f.whiteout(ctx, obj.Remote()); err != nil {
+		return err
+	}
+	fs.Debugf(o, "copied up from %v", o.f.base)
+	o.mu.Lock()
+	o.Object = newObj
+	o.base = false
+	o.mu.Unlock()
+	return nil
+}
+
+// SetModTime sets the modification time of the object
+//
+// Objects only in the base layer can't be changed.
+func (o *overlayObject) SetModTime(ctx context.Context, modTime time.Time) error {
+	obj, base := o.layer()
+	if base {
+		return fs.ErrorCantSetModTime
+	}
+	return obj.SetModTime(ctx, modTime)
+}
+
+// Remove the object
+//
+// Objects in the base layer are hidden there.
+func (o *overlayObject) Remove(ctx context.Context) error {
+	obj, base := o.layer()
+	if !base {
+		if err := obj.Remove(ctx); err != nil {
+			return err
+		}
+	}
+	return o.f.hideInBase(ctx, obj.Remote(), false)
+}
+
+// UnWrap returns the wrapped Object
+func (o *overlayObject) UnWrap() fs.Object {
+	return o.obj()
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*overlayFs)(nil)
+	_ fs.PutStreamer     = (*overlayFs)(nil)
+	_ fs.Copier          = (*overlayFs)(nil)
+	_ fs.Mover           = (*overlayFs)(nil)
+	_ fs.DirMover        = (*overlayFs)(nil)
+	_ fs.ChangeNotifier  = (*overlayFs)(nil)
+	_ fs.Abouter         = (*overlayFs)(nil)
+	_ fs.DirCacheFlusher = (*overlayFs)(nil)
+	_ fs.UnWrapper       = (*overlayFs)(nil)
+	_ fs.Object          = (*overlayObject)(nil)
+	_ fs.ObjectUnWrapper = (*overlayObject)(nil)
+)
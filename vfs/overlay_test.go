@@ -0,0 +1,253 @@
+package vfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOverlayVFS makes a VFS of r.Fremote on top of r.Flocal
+func newOverlayVFS(t *testing.T, precedence vfscommon.OverlayPrecedence) (*fstest.Run, *VFS) {
+	r := fstest.NewRun(t)
+	ctx := context.Background()
+	r.WriteFile("both", "base both", t1)
+	r.WriteFile("dir/base", "base contents", t1)
+	r.WriteObject(ctx, "both", "writable both", t2)
+	r.WriteObject(ctx, "upper", "upper contents", t1)
+
+	opt := vfscommon.Opt
+	opt.OverlayBase = r.LocalName
+	opt.OverlayPrecedence = precedence
+	vfs := New(r.Fremote, &opt)
+	t.Cleanup(func() {
+		cleanupVFS(t, vfs)
+	})
+	_, ok := vfs.f.(*overlayFs)
+	require.True(t, ok)
+	return r, vfs
+}
+
+func TestVFSOverlay(t *testing.T) {
+	r, vfs := newOverlayVFS(t, vfscommon.OverlayPrecedenceWritable)
+
+	// Listings are merged
+	nodes, err := vfs.ReadDir("")
+	require.NoError(t, err)
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.Name())
+	}
+	assert.Equal(t, []string{"both", "dir", "upper"}, names)
+
+	// Files in both are read from the writable remote
+	contents, err := vfs.ReadFile("both")
+	require.NoError(t, err)
+	assert.Equal(t, "writable both", string(contents))
+	contents, err = vfs.ReadFile("dir/base")
+	require.NoError(t, err)
+	assert.Equal(t, "base contents", string(contents))
+
+	// Writing to a file only in the base copies it up
+	require.NoError(t, vfs.WriteFile("dir/base", []byte("new contents"), 0600))
+	contents, err = vfs.ReadFile("dir/base")
+	require.NoError(t, err)
+	assert.Equal(t, "new contents", string(contents))
+
+	// New files go to the writable remote
+	require.NoError(t, vfs.WriteFile("dir/new", []byte("new file"), 0600))
+
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{
+		fstest.NewItem("both", "writable both", t2),
+		fstest.NewItem("upper", "upper contents", t1),
+		fstest.NewItem("dir/base", "new contents", t1),
+		fstest.NewItem("dir/new", "new file", t1),
+	}, []string{"dir"}, fs.ModTimeNotSupported)
+	fstest.CheckListingWithPrecision(t, r.Flocal, []fstest.Item{
+		fstest.NewItem("both", "base both", t1),
+		fstest.NewItem("dir/base", "base contents", t1),
+	}, []string{"dir"}, fs.ModTimeNotSupported)
+}
+
+func TestVFSOverlayIdentity(t *testing.T) {
+	r, vfs := newOverlayVFS(t, vfscommon.OverlayPrecedenceWritable)
+	newVFS := func(base string, precedence vfscommon.OverlayPrecedence) *VFS {
+		opt := vfscommon.Opt
+		opt.OverlayBase = base
+		opt.OverlayPrecedence = precedence
+		vfs := New(r.Fremote, &opt)
+		t.Cleanup(func() { cleanupVFS(t, vfs) })
+		return vfs
+	}
+
+	// The overlay has its own identity so the caches keyed on it
+	// aren't shared with the writable remote
+	assert.NotEqual(t, r.Fremote.Name(), vfs.Fs().Name())
+	assert.NotEqual(t, r.Fremote.Root(), vfs.Fs().Root())
+	plain := newVFS("", vfscommon.OverlayPrecedenceWritable)
+	assert.NotEqual(t, fs.ConfigString(plain.Fs()), fs.ConfigString(vfs.Fs()))
+
+	// Different precedences aren't shared and the same ones are reused
+	other := newVFS(r.LocalName, vfscommon.OverlayPrecedenceBase)
+	assert.NotEqual(t, fs.ConfigString(other.Fs()), fs.ConfigString(vfs.Fs()))
+	assert.True(t, vfs == newVFS(r.LocalName, vfscommon.OverlayPrecedenceWritable))
+}
+
+func TestVFSOverlayPrecedenceBase(t *testing.T) {
+	r, vfs := newOverlayVFS(t, vfscommon.OverlayPrecedenceBase)
+
+	contents, err := vfs.ReadFile("both")
+	require.NoError(t, err)
+	assert.Equal(t, "base both", string(contents))
+	contents, err = vfs.ReadFile("upper")
+	require.NoError(t, err)
+	assert.Equal(t, "upper contents", string(contents))
+
+	// Once written the copy in the writable remote is shown
+	require.NoError(t, vfs.WriteFile("both", []byte("new both"), 0600))
+	require.NoError(t, vfs.WriteFile("dir/base", []byte("new base"), 0600))
+	vfs.FlushDirCache()
+	contents, err = vfs.ReadFile("both")
+	require.NoError(t, err)
+	assert.Equal(t, "new both", string(contents))
+	contents, err = vfs.ReadFile("dir/base")
+	require.NoError(t, err)
+	assert.Equal(t, "new base", string(contents))
+
+	// Removing a file in the base hides it rather than showing the
+	// file underneath in the writable remote
+	require.NoError(t, vfs.Remove("both"))
+	vfs.FlushDirCache()
+	_, err = vfs.Stat("both")
+	assert.Equal(t, ENOENT, err)
+
+	fstest.CheckListingWithPrecision(t, r.Flocal, []fstest.Item{
+		fstest.NewItem("both", "base both", t1),
+		fstest.NewItem("dir/base", "base contents", t1),
+	}, []string{"dir"}, fs.ModTimeNotSupported)
+}
+
+func TestVFSWriteBackRemote(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx := context.Background()
+	r.WriteFile("dir/old", "old contents", t1)
+	r.WriteFile("both", "source both", t1)
+	r.WriteObject(ctx, "both", "migrated both", t2)
+
+	// Serve the local remote writing back to the remote
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	opt.WriteBackRemote = fs.ConfigString(r.Fremote)
+	vfs := New(r.Flocal, &opt)
+	t.Cleanup(func() {
+		cleanupVFS(t, vfs)
+	})
+	_, ok := vfs.f.(*overlayFs)
+	require.True(t, ok)
+
+	// Files not migrated yet are read from the source
+	contents, err := vfs.ReadFile("dir/old")
+	require.NoError(t, err)
+	assert.Equal(t, "old contents", string(contents))
+	contents, err = vfs.ReadFile("both")
+	require.NoError(t, err)
+	assert.Equal(t, "migrated both", string(contents))
+
+	// Changes are written back to the destination only
+	require.NoError(t, vfs.WriteFile("dir/old", []byte("changed contents"), 0600))
+	require.NoError(t, vfs.WriteFile("new", []byte("new file"), 0600))
+	vfs.WaitForWriters(waitForWritersDelay)
+	contents, err = vfs.ReadFile("dir/old")
+	require.NoError(t, err)
+	assert.Equal(t, "changed contents", string(contents))
+
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{
+		fstest.NewItem("both", "migrated both", t2),
+		fstest.NewItem("dir/old", "changed contents", t1),
+		fstest.NewItem("new", "new file", t1),
+	}, []string{"dir"}, fs.ModTimeNotSupported)
+	fstest.CheckListingWithPrecision(t, r.Flocal, []fstest.Item{
+		fstest.NewItem("both", "source both", t1),
+		fstest.NewItem("dir/old", "old contents", t1),
+	}, []string{"dir"}, fs.ModTimeNotSupported)
+}
+
+func TestVFSWriteBackRemoteWhiteout(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx := context.Background()
+	r.WriteFile("remove", "remove", t1)
+	r.WriteFile("rename", "rename", t1)
+	r.WriteFile("shadowed", "source shadowed", t1)
+	r.WriteFile("dir/file", "dir file", t1)
+	r.WriteFile("dir/sub/file", "dir sub file", t1)
+	r.WriteFile("empty/file", "empty file", t1)
+	r.WriteFile("gone/file", "gone file", t1)
+	r.WriteObject(ctx, "shadowed", "migrated shadowed", t2)
+
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	opt.WriteBackRemote = fs.ConfigString(r.Fremote)
+	vfs := New(r.Flocal, &opt)
+	t.Cleanup(func() {
+		cleanupVFS(t, vfs)
+	})
+
+	names := func(dir string) (names []string) {
+		nodes, err := vfs.ReadDir(dir)
+		require.NoError(t, err)
+		for _, node := range nodes {
+			names = append(names, node.Name())
+		}
+		return names
+	}
+
+	// Files, directories and files in both can be removed and renamed
+	require.NoError(t, vfs.Remove("remove"))
+	require.NoError(t, vfs.Rename("rename", "renamed"))
+	require.NoError(t, vfs.Remove("shadowed"))
+	require.NoError(t, vfs.Rename("dir", "newdir"))
+	require.NoError(t, vfs.Remove("empty/file"))
+	require.NoError(t, vfs.Remove("empty"))
+	vfs.WaitForWriters(waitForWritersDelay)
+
+	assert.Equal(t, []string{"gone", "newdir", "renamed"}, names(""))
+	assert.Equal(t, []string{"file", "sub"}, names("newdir"))
+	contents, err := vfs.ReadFile("newdir/sub/file")
+	require.NoError(t, err)
+	assert.Equal(t, "dir sub file", string(contents))
+
+	// A new VFS sees the same as the changes are kept in the
+	// destination
+	vfs.FlushDirCache()
+	vfs2 := New(r.Flocal, &opt)
+	defer cleanupVFS(t, vfs2)
+	nodes, err := vfs2.ReadDir("")
+	require.NoError(t, err)
+	require.Len(t, nodes, 3)
+	assert.Equal(t, "newdir", nodes[1].Name())
+	assert.Equal(t, "renamed", nodes[2].Name())
+	_, err = vfs2.Stat("shadowed")
+	assert.Equal(t, ENOENT, err)
+
+	// Removing a directory and making it again doesn't show the
+	// contents from the source
+	require.NoError(t, vfs.Remove("gone/file"))
+	require.NoError(t, vfs.Remove("gone"))
+	require.NoError(t, vfs.Mkdir("gone", 0777))
+	assert.Empty(t, names("gone"))
+
+	// The source is never changed
+	fstest.CheckListingWithPrecision(t, r.Flocal, []fstest.Item{
+		fstest.NewItem("remove", "remove", t1),
+		fstest.NewItem("rename", "rename", t1),
+		fstest.NewItem("shadowed", "source shadowed", t1),
+		fstest.NewItem("dir/file", "dir file", t1),
+		fstest.NewItem("dir/sub/file", "dir sub file", t1),
+		fstest.NewItem("empty/file", "empty file", t1),
+		fstest.NewItem("gone/file", "gone file", t1),
+	}, []string{"dir", "dir/sub", "empty", "gone"}, fs.ModTimeNotSupported)
+}
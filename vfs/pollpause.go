@@ -0,0 +1,85 @@
+package vfs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// errPollNotSupported is returned when changing the polling of a
+// remote which doesn't support change notifications
+var errPollNotSupported = errors.New("poll-interval is not supported by this remote")
+
+// _sendPollInterval tells the polling function to poll every interval,
+// or to stop polling if it is 0. If timeout > 0 it gives up waiting
+// for the polling function after timeout and returns false.
+//
+// Call with pollMu held
+func (vfs *VFS) _sendPollInterval(interval time.Duration, timeout time.Duration) (ok bool) {
+	var timeoutChan <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+	select {
+	case vfs.pollChan <- interval:
+		return true
+	case <-timeoutChan:
+		return false
+	}
+}
+
+// pausePolling stops polling the remote for changes until
+// resumePolling is called. The directory cache expires after
+// --dir-cache-time as normal while polling is paused.
+//
+// It returns false if the polling function didn't accept the change
+// within timeout.
+func (vfs *VFS) pausePolling(timeout time.Duration) (ok bool, err error) {
+	vfs.pollMu.Lock()
+	defer vfs.pollMu.Unlock()
+	if vfs.pollChan == nil {
+		return false, errPollNotSupported
+	}
+	if vfs.pollPaused {
+		return true, nil
+	}
+	if !vfs._sendPollInterval(0, timeout) {
+		return false, nil
+	}
+	vfs.pollPaused = true
+	fs.Infof(vfs.f, "vfs: paused polling for changes")
+	return true, nil
+}
+
+// resumePolling starts polling the remote for changes again every
+// --poll-interval after pausePolling.
+//
+// It returns false if the polling function didn't accept the change
+// within timeout.
+func (vfs *VFS) resumePolling(timeout time.Duration) (ok bool, err error) {
+	vfs.pollMu.Lock()
+	defer vfs.pollMu.Unlock()
+	if vfs.pollChan == nil {
+		return false, errPollNotSupported
+	}
+	if !vfs.pollPaused {
+		return true, nil
+	}
+	if !vfs._sendPollInterval(time.Duration(vfs.Opt.PollInterval), timeout) {
+		return false, nil
+	}
+	vfs.pollPaused = false
+	fs.Infof(vfs.f, "vfs: resumed polling for changes every %v", vfs.Opt.PollInterval)
+	return true, nil
+}
+
+// isPollPaused returns true if polling has been paused with
+// pausePolling
+func (vfs *VFS) isPollPaused() bool {
+	vfs.pollMu.Lock()
+	defer vfs.pollMu.Unlock()
+	return vfs.pollPaused
+}
@@ -0,0 +1,109 @@
+package vfs
+
+import (
+	"context"
+	"path"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// prefetchQueueSize is the number of files which can be waiting to be
+// prefetched - any more are dropped until the queue has room.
+const prefetchQueueSize = 256
+
+// _prefetch starts downloading the first files in entries into the
+// cache in the background if --vfs-prefetch-on-list is set.
+//
+// call with the lock held
+func (d *Dir) _prefetch(entries fs.DirEntries) {
+	limit := d.vfs.Opt.PrefetchOnList
+	if limit <= 0 || d.vfs.cache == nil || d.vfs.Opt.CacheMode < vfscommon.CacheModeFull || d.versions != versionsNone {
+		return
+	}
+	pattern := d.vfs.Opt.PrefetchOnListPattern
+	n := 0
+	for _, entry := range entries {
+		o, ok := entry.(fs.Object)
+		if !ok {
+			continue
+		}
+		if pattern != "" {
+			if match, _ := path.Match(pattern, path.Base(o.Remote())); !match {
+				continue
+			}
+		}
+		if !d.vfs.prefetcher.add(o) {
+			fs.Debugf(o, "vfs cache: not prefetching as the prefetch queue is full")
+			return
+		}
+		n++
+		if n >= limit {
+			break
+		}
+	}
+}
+
+// prefetcher downloads files into the cache with a fixed number of
+// workers so listing lots of directories doesn't start lots of
+// downloads.
+type prefetcher struct {
+	ctx     context.Context
+	workers int
+	fn      func(o fs.Object) error
+	queue   chan fs.Object
+	start   sync.Once
+}
+
+// newPrefetcher makes a prefetcher calling fn for each object with up
+// to workers at once until ctx is cancelled.
+//
+// The workers aren't started until the first object is added.
+func newPrefetcher(ctx context.Context, workers int, queueSize int, fn func(o fs.Object) error) *prefetcher {
+	return &prefetcher{
+		ctx:     ctx,
+		workers: max(workers, 1),
+		fn:      fn,
+		queue:   make(chan fs.Object, queueSize),
+	}
+}
+
+// add queues o to be prefetched returning false if the queue is full
+func (p *prefetcher) add(o fs.Object) bool {
+	p.start.Do(func() {
+		for range p.workers {
+			go p.run()
+		}
+	})
+	select {
+	case p.queue <- o:
+		return true
+	default:
+		return false
+	}
+}
+
+// run prefetches objects from the queue until the context is cancelled
+func (p *prefetcher) run() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case o := <-p.queue:
+			err := p.fn(o)
+			if err != nil && p.ctx.Err() == nil {
+				fs.Errorf(o, "vfs cache: failed to prefetch: %v", err)
+			}
+		}
+	}
+}
+
+// prefetch downloads o into the cache if it is still running
+func (vfs *VFS) prefetch(o fs.Object) error {
+	cache := vfs.cache
+	if cache == nil {
+		return nil
+	}
+	return cache.Prefetch(vfs.ctx, o)
+}
@@ -0,0 +1,90 @@
+package vfs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/ranges"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirPrefetch(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.PrefetchOnList = 1
+	opt.PrefetchOnListPattern = "*.mkv"
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+
+	file1 := r.WriteObject(ctx, "dir/a.txt", "file1 contents", t1)
+	file2 := r.WriteObject(ctx, "dir/b.mkv", "file2 contents", t1)
+	file3 := r.WriteObject(ctx, "dir/c.mkv", "file3 contents", t1)
+	r.CheckRemoteItems(t, file1, file2, file3)
+
+	node, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	_, err = node.(*Dir).ReadDirAll()
+	require.NoError(t, err)
+
+	// Only the first file matching the pattern is downloaded
+	files := func() int {
+		return vfs.cache.Stats()["files"].(int)
+	}
+	assert.Eventually(t, func() bool {
+		return files() == 1 && vfs.cache.Item("dir/b.mkv").HasRange(ranges.Range{Pos: 0, Size: file2.Size})
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestPrefetcherBounded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu        sync.Mutex
+		active    int
+		maxActive int
+		done      int
+	)
+	release := make(chan struct{})
+	p := newPrefetcher(ctx, 2, 3, func(o fs.Object) error {
+		mu.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		active--
+		done++
+		mu.Unlock()
+		return nil
+	})
+
+	// Wait for both workers to be busy
+	assert.True(t, p.add(mockobject.Object("a")))
+	assert.True(t, p.add(mockobject.Object("b")))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return active == 2
+	}, 10*time.Second, time.Millisecond)
+
+	// The queue fills up then objects are dropped
+	assert.True(t, p.add(mockobject.Object("c")))
+	assert.True(t, p.add(mockobject.Object("d")))
+	assert.True(t, p.add(mockobject.Object("e")))
+	assert.False(t, p.add(mockobject.Object("f")))
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return done == 5
+	}, 10*time.Second, time.Millisecond)
+	assert.Equal(t, 2, maxActive)
+}
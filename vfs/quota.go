@@ -0,0 +1,63 @@
+package vfs
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+// quotaEnforced returns true if writes need to be checked with
+// checkQuota
+func (vfs *VFS) quotaEnforced() bool {
+	return (vfs.Opt.DiskSpaceTotalSizeEnforce && vfs.Opt.DiskSpaceTotalSize >= 0) || vfs.Opt.BackendMinFreeSpace >= 0
+}
+
+// checkQuota should be called before files on the VFS grow by grow
+// bytes, or shrink if it is negative.
+//
+// If --vfs-disk-space-total-size-enforce is set it returns ENOSPC if
+// the growth would take the used space reported by Statfs over
+// --vfs-disk-space-total-size. If --vfs-backend-min-free-space is set
+// it returns ENOSPC if the growth would take the free space reported
+// by the remote under it. Otherwise it counts the growth in the used
+// space until the usage is read from the remote again.
+func (vfs *VFS) checkQuota(grow int64) error {
+	if !vfs.quotaEnforced() || grow == 0 {
+		return nil
+	}
+	vfs.usageMu.Lock()
+	defer vfs.usageMu.Unlock()
+	total, used, _ := vfs._statfs()
+	if grow > 0 && vfs.Opt.DiskSpaceTotalSizeEnforce && vfs.Opt.DiskSpaceTotalSize >= 0 && used+grow > total {
+		fs.Errorf(nil, "Can't write %d bytes as only %d bytes of --vfs-disk-space-total-size %v are free", grow, max(total-used, 0), vfs.Opt.DiskSpaceTotalSize)
+		return ENOSPC
+	}
+	if grow > 0 && vfs.Opt.BackendMinFreeSpace >= 0 {
+		if free := vfs._backendFree(); free >= 0 && free-grow < int64(vfs.Opt.BackendMinFreeSpace) {
+			fs.Errorf(nil, "Can't write %d bytes as only %d bytes are free on the remote which must keep --vfs-backend-min-free-space %v free", grow, free, vfs.Opt.BackendMinFreeSpace)
+			return ENOSPC
+		}
+	}
+	vfs.quotaGrown += grow
+	return nil
+}
+
+// _backendFree returns the free space reported by the remote the last
+// time the usage was read, less the growth since then, or -1 if the
+// remote doesn't report it.
+//
+// Call with usageMu held after _statfs.
+func (vfs *VFS) _backendFree() int64 {
+	u := vfs.usage
+	if u == nil {
+		return -1
+	}
+	var free int64
+	switch {
+	case u.Free != nil:
+		free = *u.Free
+	case u.Total != nil && u.Used != nil:
+		free = *u.Total - *u.Used
+	default:
+		return -1
+	}
+	return max(free-vfs.quotaGrown, 0)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/vfs/vfscache"
 	"github.com/rclone/rclone/vfs/vfscache/writeback"
 )
 
@@ -204,6 +205,7 @@ func rcForget(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 
 	forgotten := []string{}
 	if len(in) == 0 {
+		vfs.dirIndex.clear()
 		root.ForgetAll()
 	} else {
 		for k, v := range in {
@@ -278,6 +280,7 @@ func getStatus(vfs *VFS, in rc.Params) (out rc.Params, err error) {
 	return rc.Params{
 		"enabled":   vfs.Opt.PollInterval != 0,
 		"supported": vfs.pollChan != nil,
+		"paused":    vfs.isPollPaused(),
 		"interval": map[string]any{
 			"raw":     vfs.Opt.PollInterval,
 			"seconds": time.Duration(vfs.Opt.PollInterval) / time.Second,
@@ -333,25 +336,23 @@ func rcPollInterval(ctx context.Context, in rc.Params) (out rc.Params, err error
 		return nil, fmt.Errorf("invalid parameter: %s=%s", k, v)
 	}
 	if vfs.pollChan == nil {
-		return nil, errors.New("poll-interval is not supported by this remote")
+		return nil, errPollNotSupported
 	}
 
 	if !intervalPresent {
 		return getStatus(vfs, in)
 	}
 	var timeoutHit bool
-	var timeoutChan <-chan time.Time
-	if timeout > 0 {
-		timer := time.NewTimer(timeout)
-		defer timer.Stop()
-		timeoutChan = timer.C
-	}
-	select {
-	case vfs.pollChan <- interval:
+	vfs.pollMu.Lock()
+	if vfs.pollPaused {
+		// Use the new interval when polling is resumed
 		vfs.Opt.PollInterval = fs.Duration(interval)
-	case <-timeoutChan:
+	} else if vfs._sendPollInterval(interval, timeout) {
+		vfs.Opt.PollInterval = fs.Duration(interval)
+	} else {
 		timeoutHit = true
 	}
+	vfs.pollMu.Unlock()
 	out, err = getStatus(vfs, in)
 	if out != nil {
 		out["timeout"] = timeoutHit
@@ -359,6 +360,111 @@ func rcPollInterval(ctx context.Context, in rc.Params) (out rc.Params, err error
 	return
 }
 
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/poll-pause",
+		Fn:    rcPollPause,
+		Title: "Pause polling the remote for changes.",
+		Help: strings.ReplaceAll(`
+This stops the VFS polling the remote for changes every
+|--poll-interval| until vfs/poll-resume is called. Use it before a
+bulk operation on the remote which would otherwise cause lots of
+change notifications and directory cache invalidations.
+
+While polling is paused the directory cache expires after
+|--dir-cache-time| as it does for remotes which don't support
+polling. Changes made to the remote while polling is paused may not
+be picked up when it is resumed, depending on the remote, so use the
+|refresh| parameter of vfs/poll-resume or vfs/refresh afterwards.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |timeout| - time to wait for the polling function to stop as a duration (optional, default 10s)
+
+It returns the state of polling as returned by vfs/poll-info with an
+extra |timeout| key which is true if the polling function didn't stop
+within the timeout, in which case polling isn't paused.
+
+    rclone rc vfs/poll-pause
+`, "|", "`") + getVFSHelp,
+	})
+}
+
+func rcPollPause(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := getTimeout(in)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := vfs.pausePolling(timeout)
+	if err != nil {
+		return nil, err
+	}
+	out = pollInfo(vfs)
+	out["timeout"] = !ok
+	return out, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/poll-resume",
+		Fn:    rcPollResume,
+		Title: "Resume polling the remote for changes.",
+		Help: strings.ReplaceAll(`
+This starts polling the remote for changes every |--poll-interval|
+again after vfs/poll-pause. If the interval was changed with
+vfs/poll-interval while polling was paused then the new interval is
+used.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |timeout| - time to wait for the polling function to start as a duration (optional, default 10s)
+- |refresh| - set to true to forget the whole directory cache so changes made while polling was paused are picked up (optional)
+
+It returns the state of polling as returned by vfs/poll-info with an
+extra |timeout| key which is true if the polling function didn't start
+within the timeout, in which case polling is still paused.
+
+    rclone rc vfs/poll-resume refresh=true
+`, "|", "`") + getVFSHelp,
+	})
+}
+
+func rcPollResume(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := getTimeout(in)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := in.GetBool("refresh")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	ok, err := vfs.resumePolling(timeout)
+	if err != nil {
+		return nil, err
+	}
+	if ok && refresh {
+		root, err := vfs.Root()
+		if err != nil {
+			return nil, err
+		}
+		vfs.dirIndex.clear()
+		root.ForgetAll()
+	}
+	out = pollInfo(vfs)
+	out["timeout"] = !ok
+	return out, nil
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:  "vfs/list",
@@ -402,9 +508,14 @@ This returns stats for the selected VFS.
         // Status of the disk cache - only present if --vfs-cache-mode > off
         "diskCache": {
             "bytesUsed": 0,
+            "degraded": false, // true if fallen back to --vfs-cache-mode minimal after cache write errors
+            "degradedError": "",
+            "degradedSince": "",
+            "dirtyFiles": 0, // files with changes not yet uploaded
             "erroredFiles": 0,
             "files": 0,
             "hashType": 1,
+            "largeFiles": 0, // files read which were bigger than --vfs-cache-large-file-warn-threshold
             "outOfSpace": false,
             "path": "/home/user/.cache/rclone/vfs/local/mnt/a",
             "pathMeta": "/home/user/.cache/rclone/vfsMeta/local/mnt/a",
@@ -554,3 +665,1312 @@ func rcQueueSetExpiry(ctx context.Context, in rc.Params) (out rc.Params, err err
 	err = vfs.cache.QueueSetExpiry(writeback.Handle(id), refTime, time.Duration(float64(time.Second)*expiry))
 	return nil, err
 }
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/flush-file",
+		Title: "Upload a single file in the upload queue now.",
+		Help: strings.ReplaceAll(`
+
+Use this to upload a file which has been changed straight away rather
+than waiting for its |--vfs-write-back| time to expire. The other items
+in the upload queue are left alone.
+
+The call returns when the upload has finished. If the file is being
+uploaded already it waits for that upload to finish.
+
+This will return an error if called with |--vfs-cache-mode| off, if the
+|file| doesn't exist, if it has no changes to upload or if the upload
+fails. If the upload fails the file stays in the queue and will be
+retried as normal.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |file| - the path of the file to upload relative to the root of the VFS
+
+This returns an empty result on success, or an error.
+
+`, "|", "`") + getVFSHelp,
+		Fn: rcFlushFile,
+	})
+}
+
+func rcFlushFile(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.cache == nil {
+		return nil, rc.NewErrParamInvalid(errors.New("can't call this unless using the VFS cache"))
+	}
+	name, err := in.GetString("file")
+	if err != nil {
+		return nil, err
+	}
+	node, err := vfs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if node.IsDir() {
+		return nil, rc.NewErrParamInvalid(fmt.Errorf("%q is a directory", name))
+	}
+	return nil, vfs.cache.FlushFile(ctx, node.Path())
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/downloads",
+		Title: "List the files being downloaded by open file handles.",
+		Help: strings.ReplaceAll(`
+This lists the files which open file handles are reading straight
+from the remote using chunked reading, as controlled by
+|--vfs-read-chunk-size| and |--vfs-read-chunk-streams|. Reads through
+the VFS cache aren't listed.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+
+It returns
+
+    {
+        "downloads": // an array of files being downloaded
+        [
+            {
+                "id":        1,                     // integer: id of the download to pass to vfs/downloads-cancel
+                "path":      "films/film.mkv",      // string: path of the file relative to the root of the VFS
+                "bytes":     16777216,              // integer: number of bytes read so far
+                "size":      2147483648,            // integer: size of the file in bytes
+                "streams":   0,                     // integer: number of streams used to read the file
+                "speed":     8388608.0,             // float: average speed of the download in bytes per second
+                "startedAt": "2024-01-02T15:04:05Z" // string: time the download started
+            },
+        ],
+    }
+`, "|", "`") + getVFSHelp,
+		Fn: rcDownloads,
+	})
+}
+
+func rcDownloads(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	downloads := []rc.Params{}
+	for _, d := range vfs.listDownloads() {
+		downloads = append(downloads, d.rcStats())
+	}
+	return rc.Params{"downloads": downloads}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/downloads-cancel",
+		Title: "Cancel downloads being made by open file handles.",
+		Help: strings.ReplaceAll(`
+This cancels downloads listed by vfs/downloads so they stop using
+bandwidth. Reads from the file handles doing the download will fail
+with an input/output error from then on. The file can be opened
+again to read it.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |id| - id of the download to cancel
+- |path| - cancel all the downloads of the file with this path
+
+One of |id| or |path| must be given.
+
+This returns the number of downloads cancelled, eg
+
+    {
+        "cancelled": 2
+    }
+
+It returns an error if there were no downloads to cancel.
+`, "|", "`") + getVFSHelp,
+		Fn: rcDownloadsCancel,
+	})
+}
+
+func rcDownloadsCancel(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	id, err := in.GetInt64("id")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	path, err := in.GetString("path")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	if id == 0 && path == "" {
+		return nil, rc.NewErrParamInvalid(errors.New("need id or path parameter"))
+	}
+	n := vfs.cancelDownloads(id, path)
+	if n == 0 {
+		return nil, errors.New("no matching downloads found")
+	}
+	return rc.Params{"cancelled": n}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/chunk-reader-info",
+		Title: "Show the state of the chunked readers of open file handles.",
+		Help: strings.ReplaceAll(`
+This shows what the chunked readers of the downloads listed by
+vfs/downloads are doing, to help diagnose slow or stuck streams. It
+doesn't wait for reads in progress.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |id| - id of the download to show (optional, all are shown if not set)
+
+It returns
+
+    {
+        "readers": // an array of chunked readers
+        [
+            {
+                "id":             1,                // integer: id of the download
+                "path":           "films/film.mkv", // string: path of the file relative to the root of the VFS
+                "chunkSize":      67108864,         // integer: size of the chunks being read, -1 for the rest of the file
+                "streams":        1,                // integer: number of streams reading the file
+                "buffered":       1048576,          // integer: bytes read from the remote but not by the handle yet
+                "position":       16777216,         // integer: offset the handle will read from next
+                "speed":          8388608.0,        // float: average speed of the download in bytes per second
+                "tunedChunkSize": 134217728,        // integer: initial chunk size used when the reader is re-opened
+                "tunedStreams":   0,                // integer: streams used when the reader is re-opened
+                "tunePending":    false             // bool: set if the reader will be re-opened at the next read
+            },
+        ],
+    }
+
+It returns an error if |id| is given but not found.
+`, "|", "`") + getVFSHelp,
+		Fn: rcChunkReaderInfo,
+	})
+}
+
+func rcChunkReaderInfo(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	id, err := in.GetInt64("id")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	readers := []rc.Params{}
+	for _, d := range vfs.listDownloads() {
+		if id != 0 && d.id != id {
+			continue
+		}
+		readers = append(readers, d.rcChunkReaderInfo())
+	}
+	if id != 0 && len(readers) == 0 {
+		return nil, rc.NewErrParamInvalid(fmt.Errorf("download %d not found", id))
+	}
+	return rc.Params{"readers": readers}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/chunk-reader-tune",
+		Title: "Change the chunk size and streams of an open file handle.",
+		Help: strings.ReplaceAll(`
+This changes the chunk size and number of streams the chunked reader
+of a download listed by vfs/downloads uses, overriding
+|--vfs-read-chunk-size| and |--vfs-read-chunk-streams| for that file
+handle only. The reader is re-opened with the new settings at the
+current position at the next read from the handle. Handles opened with
+|--no-seek| can't be re-opened so can't be changed.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |id| - id of the download to change
+- |chunkSize| - initial chunk size, eg "32M" or "off" to read without chunks (optional)
+- |chunkStreams| - number of streams to read with (optional)
+
+At least one of |chunkSize| or |chunkStreams| must be given. The
+settings last until the handle is closed.
+
+    rclone rc vfs/chunk-reader-tune id=1 chunkSize=64M chunkStreams=4
+
+This returns the state of the reader as in vfs/chunk-reader-info.
+`, "|", "`") + getVFSHelp,
+		Fn: rcChunkReaderTune,
+	})
+}
+
+func rcChunkReaderTune(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	id, err := in.GetInt64("id")
+	if err != nil {
+		return nil, err
+	}
+	d := vfs.getDownload(id)
+	if d == nil {
+		return nil, rc.NewErrParamInvalid(fmt.Errorf("download %d not found", id))
+	}
+	if d.fh.noSeek {
+		return nil, rc.NewErrParamInvalid(fmt.Errorf("download %d can't be re-opened as --no-seek is set", id))
+	}
+	_, chunkSizeErr := in.Get("chunkSize")
+	_, streamsErr := in.Get("chunkStreams")
+	if rc.IsErrParamNotFound(chunkSizeErr) && rc.IsErrParamNotFound(streamsErr) {
+		return nil, rc.NewErrParamInvalid(errors.New("need chunkSize or chunkStreams parameter"))
+	}
+	chunkSize, err := getSizeSuffix(in, "chunkSize", fs.SizeSuffix(d.fh.chunkSize.Load()))
+	if err != nil {
+		return nil, err
+	}
+	streams, err := in.GetInt64("chunkStreams")
+	if rc.IsErrParamNotFound(err) {
+		streams = d.fh.streams.Load()
+	} else if err != nil {
+		return nil, err
+	} else if streams < 0 {
+		return nil, rc.NewErrParamInvalid(errors.New("chunkStreams must not be negative"))
+	}
+	d.tuneChunkReader(int64(chunkSize), streams)
+	return d.rcChunkReaderInfo(), nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/open-progress",
+		Title: "Show the progress of files being downloaded into the VFS cache.",
+		Help: strings.ReplaceAll(`
+This shows how far the files being downloaded into the VFS cache have
+got, so a user interface can show progress while a client waits for
+the first read of a large file with |--vfs-cache-mode full|, rather
+than an unexplained hang.
+
+Files are listed while they are open and not yet completely in the
+cache. It needs |--vfs-cache-mode| |minimal| or above.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |path| - only show the file with this path (optional)
+
+It returns
+
+    {
+        "files": // an array of files being downloaded
+        [
+            {
+                "path":    "films/film.mkv", // string: path of the file relative to the root of the VFS
+                "bytes":   16777216,         // integer: number of bytes of the file in the cache
+                "size":    2147483648,       // integer: size of the file in bytes
+                "percent": 0,                // integer: percentage of the file in the cache
+                "speed":   8388608.0,        // float: combined speed of the downloads in bytes per second
+                "eta":     254,              // integer: seconds until the file is in the cache or null if unknown
+                "waiting": true              // boolean: set if reads are waiting for data to be downloaded
+            },
+        ],
+    }
+`, "|", "`") + getVFSHelp,
+		Fn: rcOpenProgress,
+	})
+}
+
+func rcOpenProgress(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.cache == nil {
+		return nil, errors.New("need --vfs-cache-mode minimal or above")
+	}
+	path, err := in.GetString("path")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	files := []rc.Params{}
+	for _, p := range vfs.cache.DownloadProgress() {
+		if path != "" && p.Name != path {
+			continue
+		}
+		files = append(files, openProgressParams(p))
+	}
+	return rc.Params{"files": files}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/cache-gc",
+		Title: "Garbage collect the VFS cache directory.",
+		Help: strings.ReplaceAll(`
+This scans the cache directories of the selected VFS and removes any
+files which are not tracked by the cache. These can be left behind by
+crashed sessions.
+
+Temporary files (those ending in |--partial-suffix|) are only removed
+if they are older than |--vfs-temp-file-timeout|.
+
+Files which are tracked by the cache, whether clean or dirty, are
+never touched, so this is safe to call on a live mount.
+
+This will return an error if called with |--vfs-cache-mode| off.
+
+It returns a summary of what was removed
+
+    {
+        "orphans": 2,       // integer: number of untracked data files removed
+        "metaOrphans": 1,   // integer: number of untracked metadata files removed
+        "tempFiles": 0,     // integer: number of old temporary files removed
+        "bytes": 123456,    // integer: number of bytes reclaimed
+        "errors": 0         // integer: number of files which couldn't be removed
+    }
+
+`, "|", "`") + getVFSHelp,
+		Fn: rcCacheGC,
+	})
+}
+
+func rcCacheGC(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.cache == nil {
+		return nil, rc.NewErrParamInvalid(errors.New("can't call this unless using the VFS cache"))
+	}
+	res, err := vfs.cache.GC()
+	if err != nil {
+		return nil, err
+	}
+	out = rc.Params{}
+	err = rc.Reshape(&out, res)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/cache-eviction-preview",
+		Title: "Preview which files would be evicted from the VFS cache.",
+		Help: strings.ReplaceAll(`
+This shows which files would be evicted from the VFS cache if the
+cache cleaner ran with the limits passed in, without evicting
+anything. This can be used to try out values for the cache limits
+before setting them.
+
+It takes these optional parameters, each of which defaults to the
+value currently in use.
+
+- maxSize - maximum size of the cache, eg "10G" - see |--vfs-cache-max-size|
+- maxAge - maximum age of files in the cache, eg "1h" - see |--vfs-cache-max-age|
+- minFreeSpace - minimum free space on the cache disk, eg "1G" - see |--vfs-cache-min-free-space|
+
+Use "off" or "0" for maxSize or minFreeSpace to remove that limit.
+
+Files which are open or haven't been uploaded yet are never evicted so
+won't appear in the list.
+
+This will return an error if called with |--vfs-cache-mode| off.
+
+It returns the files in the order they would be evicted
+
+    {
+        "evicted": [
+            {
+                "name": "dir/file.txt",                  // string: name of the file
+                "size": 123456,                          // integer: bytes used in the cache
+                "atime": "2024-01-02T15:04:05.000Z",     // string: time last accessed
+                "reason": "quota"                        // string: "age" or "quota"
+            }
+        ],
+        "count": 1,        // integer: number of files which would be evicted
+        "bytes": 123456    // integer: number of bytes which would be freed
+    }
+
+`, "|", "`") + getVFSHelp,
+		Fn: rcCacheEvictionPreview,
+	})
+}
+
+// getSizeSuffix reads the SizeSuffix key from in, or returns def if not found
+func getSizeSuffix(in rc.Params, key string, def fs.SizeSuffix) (fs.SizeSuffix, error) {
+	s, err := in.GetString(key)
+	if rc.IsErrParamNotFound(err) {
+		return def, nil
+	} else if err != nil {
+		return 0, err
+	}
+	var size fs.SizeSuffix
+	err = size.Set(s)
+	if err != nil {
+		return 0, rc.NewErrParamInvalid(fmt.Errorf("parse %s: %w", key, err))
+	}
+	return size, nil
+}
+
+// getCacheLimits reads the maxAge, maxSize and minFreeSpace keys from
+// in, using the values in def for any not found
+func getCacheLimits(in rc.Params, def vfscache.Limits) (limits vfscache.Limits, err error) {
+	limits.MaxSize, err = getSizeSuffix(in, "maxSize", def.MaxSize)
+	if err != nil {
+		return limits, err
+	}
+	limits.MinFreeSpace, err = getSizeSuffix(in, "minFreeSpace", def.MinFreeSpace)
+	if err != nil {
+		return limits, err
+	}
+	limits.MaxAge, err = in.GetDuration("maxAge")
+	if rc.IsErrParamNotFound(err) {
+		limits.MaxAge = def.MaxAge
+	} else if err != nil {
+		return limits, err
+	}
+	return limits, nil
+}
+
+func rcCacheEvictionPreview(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.cache == nil {
+		return nil, rc.NewErrParamInvalid(errors.New("can't call this unless using the VFS cache"))
+	}
+	limits, err := getCacheLimits(in, vfs.cache.Limits())
+	if err != nil {
+		return nil, err
+	}
+	candidates := vfs.cache.EvictionPreview(limits)
+	evicted := []rc.Params{}
+	bytes := int64(0)
+	for _, candidate := range candidates {
+		var item rc.Params
+		err = rc.Reshape(&item, candidate)
+		if err != nil {
+			return nil, err
+		}
+		evicted = append(evicted, item)
+		bytes += candidate.Size
+	}
+	return rc.Params{
+		"evicted": evicted,
+		"count":   len(candidates),
+		"bytes":   bytes,
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/cache-lru",
+		Title: "List the files in the VFS cache in the order they would be evicted.",
+		Help: strings.ReplaceAll(`
+This lists all the files in the VFS cache in the order the cache
+cleaner would evict them with the current limits and
+|--vfs-cache-evict-policy|, so the first files are the next to go.
+
+Files older than |--vfs-cache-max-age| (or the max age from
+|--vfs-cache-max-age-rules| for their directory) come first as they
+are evicted the next time the cache cleaner runs. Then come the files
+which are evicted in turn when the cache is over
+|--vfs-cache-max-size| or |--vfs-cache-min-free-space|, then the files
+which are open or haven't been uploaded yet, which are never evicted.
+
+Use |vfs/cache-eviction-preview| to see which of these files would be
+evicted with different limits.
+
+This will return an error if called with |--vfs-cache-mode| off.
+
+It returns
+
+    {
+        "files": [
+            {
+                "name": "dir/file.txt",                  // string: name of the file
+                "size": 123456,                          // integer: bytes used in the cache
+                "atime": "2024-01-02T15:04:05.000Z",     // string: time last accessed
+                "expires": "2024-01-02T16:04:05.000Z",   // string: time it becomes older than the max age
+                "score": 0,                              // float: policy score - lower is evicted first, 0 for lru
+                "status": "evictable"                    // string: "expired", "evictable", "open" or "dirty"
+            }
+        ],
+        "policy": "lru",   // string: the --vfs-cache-evict-policy in use
+        "count": 1,        // integer: number of files in the cache
+        "bytes": 123456    // integer: number of bytes used by them
+    }
+
+`, "|", "`") + getVFSHelp,
+		Fn: rcCacheLRU,
+	})
+}
+
+func rcCacheLRU(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.cache == nil {
+		return nil, rc.NewErrParamInvalid(errors.New("can't call this unless using the VFS cache"))
+	}
+	entries := vfs.cache.EvictionOrder()
+	files := []rc.Params{}
+	bytes := int64(0)
+	for _, entry := range entries {
+		var file rc.Params
+		err = rc.Reshape(&file, entry)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+		bytes += entry.Size
+	}
+	return rc.Params{
+		"files":  files,
+		"policy": vfs.Opt.CacheEvictPolicy.String(),
+		"count":  len(entries),
+		"bytes":  bytes,
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/cache-manifest",
+		Title: "Export a manifest of the files in the VFS cache.",
+		Help: strings.ReplaceAll(`
+This lists the files in the VFS cache along with what is cached of
+each. It can be used with |vfs/cache-manifest-check| to check the cache
+after copying the cache directory to another machine, or after a copy
+or restore of it.
+
+The cache files of completely cached files are hashed if they haven't
+been already so this may take some time with a large cache.
+
+This will return an error if called with |--vfs-cache-mode| off.
+
+It returns the files sorted by name
+
+    {
+        "items": [
+            {
+                "name": "dir/file.txt",                 // string: name of the file
+                "size": 123456,                         // integer: size of the file
+                "hash": "e3b0c44298fc1c149afb...",      // string: SHA-256 of the cached data if completely cached
+                "ranges": [                             // array: which parts of the file are cached
+                    {"Pos": 0, "Size": 123456}
+                ],
+                "dirty": false,                         // boolean: set if changes are waiting to be uploaded
+                "modTime": "2024-01-02T15:04:05.000Z",  // string: modification time of the file
+                "fingerprint": "123456,2024-01-02..."   // string: fingerprint of the remote object
+            }
+        ],
+        "count": 1    // integer: number of files
+    }
+
+`, "|", "`") + getVFSHelp,
+		Fn: rcCacheManifest,
+	})
+}
+
+func rcCacheManifest(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.cache == nil {
+		return nil, rc.NewErrParamInvalid(errors.New("can't call this unless using the VFS cache"))
+	}
+	entries := vfs.cache.Manifest()
+	items := []rc.Params{}
+	for _, entry := range entries {
+		var item rc.Params
+		err = rc.Reshape(&item, entry)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return rc.Params{
+		"items": items,
+		"count": len(entries),
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/cache-manifest-check",
+		Title: "Check the VFS cache against a manifest.",
+		Help: strings.ReplaceAll(`
+This checks the files in the VFS cache against a manifest made with
+|vfs/cache-manifest|, for example after copying the cache directory to
+another machine and starting rclone there with it.
+
+It takes this parameter
+
+- manifest - the output of |vfs/cache-manifest|
+
+For each file in the manifest it checks the file is in the cache with
+the same size and fingerprint, that the parts which were cached still
+are, that changes which were waiting to be uploaded still are and, if
+the manifest has a hash, that the cached data has the same hash.
+
+This doesn't change the cache.
+
+This will return an error if called with |--vfs-cache-mode| off.
+
+It returns the files which don't match
+
+    {
+        "problems": [
+            {
+                "name": "dir/file.txt",       // string: name of the file
+                "problem": "hash differs"     // string: what doesn't match
+            }
+        ],
+        "checked": 10,    // integer: number of files in the manifest
+        "ok": 9           // integer: number of files which match
+    }
+
+`, "|", "`") + getVFSHelp,
+		Fn: rcCacheManifestCheck,
+	})
+}
+
+func rcCacheManifestCheck(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.cache == nil {
+		return nil, rc.NewErrParamInvalid(errors.New("can't call this unless using the VFS cache"))
+	}
+	var manifest struct {
+		Items []vfscache.ManifestEntry `json:"items"`
+	}
+	err = in.GetStruct("manifest", &manifest)
+	if err != nil {
+		return nil, err
+	}
+	problems := []rc.Params{}
+	for _, problem := range vfs.cache.CheckManifest(manifest.Items) {
+		var item rc.Params
+		err = rc.Reshape(&item, problem)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, item)
+	}
+	return rc.Params{
+		"problems": problems,
+		"checked":  len(manifest.Items),
+		"ok":       len(manifest.Items) - len(problems),
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/poll-info",
+		Fn:    rcPollInfo,
+		Title: "Show how changes on the remote are being picked up.",
+		Help: strings.ReplaceAll(`
+This shows how each VFS finds out about changes made directly on the
+remote, which is useful for working out why changes aren't being
+picked up promptly.
+
+If the remote supports change notifications then rclone polls it for
+changes every |--poll-interval|. Otherwise, or if |--poll-interval| is
+0, changes are only picked up when the directory cache expires after
+|--dir-cache-time|.
+
+If the "fs" parameter is supplied then only that VFS is shown,
+otherwise all the active VFSes are.
+
+It returns an object under the key "vfses" keyed on the VFS name, as
+returned by vfs/list, with values like this
+
+    {
+        "mode": "changenotify",           // string: "changenotify", "paused", "disabled" or "dircache"
+        "changeNotify": true,             // bool: true if change notifications are in use
+        "supported": true,                // bool: true if the remote supports change notifications
+        "paused": false,                  // bool: true if polling has been paused with vfs/poll-pause
+        "interval": {                     // effective interval changes are picked up within
+            "raw": 60000000000,
+            "seconds": 60,
+            "string": "1m0s"
+        },
+        "lastNotification": "2024-01-02T15:04:05.000000000Z" // string: time of the last change notification or "" if none
+    }
+
+The modes are
+
+- changenotify - the remote is polled every |--poll-interval|
+- paused - polling has been paused with vfs/poll-pause
+- disabled - the remote supports change notifications but |--poll-interval| is 0
+- dircache - the remote doesn't support change notifications
+`, "|", "`"),
+	})
+}
+
+// pollInfo returns info about how vfs picks up changes on the remote
+func pollInfo(vfs *VFS) rc.Params {
+	supported := vfs.pollChan != nil
+	paused := vfs.isPollPaused()
+	interval := vfs.Opt.PollInterval
+	var mode string
+	switch {
+	case supported && paused:
+		mode = "paused"
+		interval = vfs.Opt.DirCacheTime
+	case supported && interval > 0:
+		mode = "changenotify"
+	case supported:
+		mode = "disabled"
+		interval = vfs.Opt.DirCacheTime
+	default:
+		mode = "dircache"
+		interval = vfs.Opt.DirCacheTime
+	}
+	lastNotification := ""
+	if t := vfs.lastNotify.Load(); t != 0 {
+		lastNotification = time.Unix(0, t).UTC().Format(time.RFC3339Nano)
+	}
+	return rc.Params{
+		"mode":         mode,
+		"changeNotify": mode == "changenotify",
+		"supported":    supported,
+		"paused":       paused,
+		"interval": map[string]any{
+			"raw":     interval,
+			"seconds": time.Duration(interval) / time.Second,
+			"string":  interval.String(),
+		},
+		"lastNotification": lastNotification,
+	}
+}
+
+func rcPollInfo(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfses := rc.Params{}
+	if _, err := in.GetString("fs"); rc.IsErrParamNotFound(err) {
+		activeMu.Lock()
+		for name, activeVFS := range active {
+			for i, vfs := range activeVFS {
+				if len(activeVFS) > 1 {
+					vfses[fmt.Sprintf("%s[%d]", name, i)] = pollInfo(vfs)
+				} else {
+					vfses[name] = pollInfo(vfs)
+				}
+			}
+		}
+		activeMu.Unlock()
+	} else {
+		vfs, err := getVFS(in)
+		if err != nil {
+			return nil, err
+		}
+		vfses[fs.ConfigString(vfs.f)] = pollInfo(vfs)
+	}
+	return rc.Params{"vfses": vfses}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/per-handle-read-bwlimit",
+		Fn:    rcPerHandleReadBwLimit,
+		Title: "Get or set the per handle read bandwidth limits.",
+		Help: strings.ReplaceAll(`
+This shows or changes the limits set by |--vfs-per-handle-read-bwlimit|
+and |--vfs-per-handle-read-bwlimit-rules| which cap the rate each open
+file handle can read at, independently of the global |--bwlimit|.
+
+Without any parameters this returns the current limits. The following
+parameters may be supplied to change them
+
+- |rate| - the default limit for each handle, eg "10M" or "off"
+- |rules| - per path limits in the form "glob=limit,glob=limit"
+
+Any parameter not supplied is left unchanged. Pass |rules=""| to
+remove all the rules. The new limits apply to handles which are
+already open from their next read.
+
+    rclone rc vfs/per-handle-read-bwlimit rate=10M rules="*.mkv=4M,backup/**=off"
+
+This returns the limits in use like this
+
+    {
+        "rate": "10Mi",             // string: default limit for each handle
+        "bytesPerSecond": 10485760, // int: default limit in bytes per second or -1 if off
+        "rules": "*.mkv=4M,backup/**=off"
+    }
+`, "|", "`") + getVFSHelp,
+	})
+}
+
+func rcPerHandleReadBwLimit(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	limit, rules := vfs.readBw.get()
+	limit, err = getSizeSuffix(in, "rate", limit)
+	if err != nil {
+		return nil, err
+	}
+	newRules, err := in.GetString("rules")
+	if err == nil {
+		rules = newRules
+	} else if !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	err = vfs.readBw.set(limit, rules, vfs.Opt.CaseInsensitive)
+	if err != nil {
+		return nil, rc.NewErrParamInvalid(err)
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	return rc.Params{
+		"rate":           limit.String(),
+		"bytesPerSecond": int64(limit),
+		"rules":          rules,
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/write-back-bwlimit",
+		Fn:    rcWriteBackBwLimit,
+		Title: "Get or set the bandwidth limit for uploads from the VFS cache.",
+		Help: strings.ReplaceAll(`
+This shows or changes the limit set by |--vfs-write-back-bwlimit| which
+caps the rate files are uploaded from the VFS cache at, independently
+of the global |--bwlimit| and of reads.
+
+Without any parameters this returns the current limit. Pass |rate| to
+change it. This takes a single limit, eg "1M" or "off", or a timetable
+in the same format as |--bwlimit|, eg "08:00,512k 19:00,off". For
+upload:download pairs only the upload limit is used.
+
+    rclone rc vfs/write-back-bwlimit rate="08:00,512k 19:00,off"
+
+This will return an error if called with |--vfs-cache-mode| off.
+
+This returns the limit in use like this
+
+    {
+        "rate": "08:00,512k 19:00,off", // string: limit or timetable as supplied
+        "bytesPerSecond": 524288        // int: limit in use now in bytes per second or -1 if off
+    }
+`, "|", "`") + getVFSHelp,
+	})
+}
+
+func rcWriteBackBwLimit(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.cache == nil {
+		return nil, rc.NewErrParamInvalid(errors.New("can't call this unless using the VFS cache"))
+	}
+	rate, err := in.GetString("rate")
+	if err == nil {
+		err = vfs.cache.SetWriteBackBwLimit(rate)
+		if err != nil {
+			return nil, rc.NewErrParamInvalid(err)
+		}
+	} else if !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	timetable, limit := vfs.cache.WriteBackBwLimit()
+	if timetable == "" {
+		timetable = "off"
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	return rc.Params{
+		"rate":           timetable,
+		"bytesPerSecond": int64(limit),
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/set-cache-limits",
+		Fn:    rcSetCacheLimits,
+		Title: "Change the limits of the VFS cache while running.",
+		Help: strings.ReplaceAll(`
+This changes the limits set by |--vfs-cache-max-age|,
+|--vfs-cache-max-size| and |--vfs-cache-min-free-space| without
+remounting, for example to shrink the cache when the disk is short of
+space.
+
+The following parameters may be supplied, any not supplied are left
+as they are
+
+- maxAge - max time since last access of objects in the cache, eg "1h"
+- maxSize - max total size of objects in the cache, eg "10G" or "off"
+- minFreeSpace - target minimum free space on the cache disk, eg "1G" or "off"
+
+    rclone rc vfs/set-cache-limits maxSize=10G maxAge=30m
+
+If the new limits are tighter than the old ones the cache cleaner runs
+straight away to evict files, otherwise they are used from its next
+run. Files which are open or haven't been uploaded yet are never
+evicted. The limits go back to the ones set on the command line when
+rclone is restarted.
+
+Without any parameters this returns the limits in use.
+
+This will return an error if called with |--vfs-cache-mode| off.
+
+This returns the limits in use like this
+
+    {
+        "maxAge": "30m0s",            // string: max age
+        "maxAgeSeconds": 1800,        // float: max age in seconds
+        "maxSize": "10Gi",            // string: max size or "off"
+        "maxSizeBytes": 10737418240,  // int: max size in bytes or -1 if off
+        "minFreeSpace": "off",        // string: min free space or "off"
+        "minFreeSpaceBytes": -1       // int: min free space in bytes or -1 if off
+    }
+`, "|", "`") + getVFSHelp,
+	})
+}
+
+func rcSetCacheLimits(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.cache == nil {
+		return nil, rc.NewErrParamInvalid(errors.New("can't call this unless using the VFS cache"))
+	}
+	old := vfs.cache.Limits()
+	limits, err := getCacheLimits(in, old)
+	if err != nil {
+		return nil, err
+	}
+	if limits != old {
+		err = vfs.cache.SetLimits(limits)
+		if err != nil {
+			return nil, rc.NewErrParamInvalid(err)
+		}
+	}
+	limits = vfs.cache.Limits()
+	bytes := func(size fs.SizeSuffix) int64 {
+		if size <= 0 {
+			return -1
+		}
+		return int64(size)
+	}
+	return rc.Params{
+		"maxAge":            limits.MaxAge.String(),
+		"maxAgeSeconds":     limits.MaxAge.Seconds(),
+		"maxSize":           limits.MaxSize.String(),
+		"maxSizeBytes":      bytes(limits.MaxSize),
+		"minFreeSpace":      limits.MinFreeSpace.String(),
+		"minFreeSpaceBytes": bytes(limits.MinFreeSpace),
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/set-read-only",
+		Fn:    rcSetReadOnly,
+		Title: "Make a running VFS read only or read write.",
+		Help: strings.ReplaceAll(`
+This makes the VFS read only or read write without remounting, for
+example to protect a mount during a risky operation.
+
+The following parameters may be supplied
+
+- |readOnly| - true to make the VFS read only, false to make it read write
+- |rejectInFlight| - if true, writes to files which are already open
+  for writing fail while the VFS is read only, otherwise they are
+  allowed to complete (default false)
+
+While the VFS is read only, creating, changing, renaming and removing
+files and directories fails as it does with |--read-only|. A VFS
+started with |--read-only| can't be made read write.
+
+Without the |readOnly| parameter this returns the current state.
+
+    rclone rc vfs/set-read-only readOnly=true
+
+This returns the state in use like this
+
+    {
+        "readOnly": true,
+        "rejectInFlight": false
+    }
+
+The effective state is also shown as |ReadOnly| in the options
+returned by |vfs/stats|.
+`, "|", "`") + getVFSHelp,
+	})
+}
+
+func rcSetReadOnly(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	readOnly, err := in.GetBool("readOnly")
+	if err == nil {
+		rejectInFlight, err := in.GetBool("rejectInFlight")
+		if err != nil && !rc.IsErrParamNotFound(err) {
+			return nil, err
+		}
+		err = vfs.setReadOnly(readOnly, rejectInFlight)
+		if err != nil {
+			return nil, rc.NewErrParamInvalid(err)
+		}
+	} else if !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	return rc.Params{
+		"readOnly":       vfs.isReadOnly(),
+		"rejectInFlight": vfs.writesRejected(),
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/access-history",
+		Fn:    rcAccessHistory,
+		Title: "List the recent file accesses.",
+		Help: strings.ReplaceAll(`
+This lists the most recent file accesses through the VFS, oldest
+first, to help find which paths are used most when tuning the VFS
+cache.
+
+Accesses are only recorded if |--vfs-access-history| is set to the
+number of them to keep. Each open of a file, and each read or write
+which transfers data, is recorded.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+
+It returns
+
+    {
+        "size": 1000, // integer: the number of accesses kept
+        "accesses":   // an array of the accesses, oldest first
+        [
+            {
+                "path":  "films/film.mkv",      // string: path of the file relative to the root of the VFS
+                "time":  "2024-01-02T15:04:05Z", // string: time of the access
+                "op":    "read",                 // string: open, read or write
+                "bytes": 131072                  // integer: number of bytes read or written
+            },
+        ],
+    }
+
+It returns an error if |--vfs-access-history| isn't set.
+`, "|", "`") + getVFSHelp,
+	})
+}
+
+func rcAccessHistory(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	if vfs.accessHistory == nil {
+		return nil, errors.New("access history not enabled - set --vfs-access-history")
+	}
+	accesses := []rc.Params{}
+	for _, a := range vfs.accessHistory.list() {
+		accesses = append(accesses, a.rcParams())
+	}
+	return rc.Params{
+		"size":     vfs.Opt.AccessHistory,
+		"accesses": accesses,
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/refresh-dir",
+		Fn:    rcRefreshDir,
+		Title: "Re-read a directory from the remote now.",
+		Help: strings.ReplaceAll(`
+This reads one directory from the remote straight away and returns
+its entries, to check that changes made outside the VFS have arrived.
+
+Unlike |vfs/refresh| this ignores every setting which smooths over
+changes in the listings - the listing is read even if it is newer than
+|--dir-cache-time|, all the changes in it are applied even if
+|--vfs-listing-stability-window| is set, and if it can't be read the
+error is returned rather than the cached listing being used as with
+|--vfs-list-retries|. If the directory isn't in the cached listing of
+a directory above it then that is read from the remote too.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |dir| - the path of the directory to read (optional, default root)
+
+It returns
+
+    {
+        "dir": "home/junk", // string: the directory read
+        "entries":          // an array of the entries in it
+        [
+            {
+                "name":    "file.txt",             // string: leaf name of the entry
+                "isDir":   false,                  // boolean: true if it is a directory
+                "size":    1234,                   // integer: size in bytes
+                "modTime": "2024-01-02T15:04:05Z" // string: modification time
+            },
+        ],
+    }
+`, "|", "`") + getVFSHelp,
+	})
+}
+
+// refreshDirPath returns the directory at dirPath, re-reading each
+// directory on the way from the remote if the next one isn't in its
+// cached listing
+func (vfs *VFS) refreshDirPath(dirPath string) (*Dir, error) {
+	dir, err := vfs.Root()
+	if err != nil {
+		return nil, err
+	}
+	for _, leaf := range strings.Split(strings.Trim(dirPath, "/"), "/") {
+		if leaf == "" {
+			continue
+		}
+		node, err := dir.stat(leaf)
+		if err == ENOENT {
+			if _, err = dir.refreshDir(); err != nil {
+				return nil, err
+			}
+			node, err = dir.stat(leaf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		next, ok := node.(*Dir)
+		if !ok {
+			return nil, EINVAL
+		}
+		dir = next
+	}
+	return dir, nil
+}
+
+func rcRefreshDir(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	dirPath, err := in.GetString("dir")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	dir, err := vfs.refreshDirPath(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	items, err := dir.refreshDir()
+	if err != nil {
+		return nil, err
+	}
+	entries := []rc.Params{}
+	for _, item := range items {
+		entries = append(entries, rc.Params{
+			"name":    item.Name(),
+			"isDir":   item.IsDir(),
+			"size":    item.Size(),
+			"modTime": item.ModTime(),
+		})
+	}
+	return rc.Params{
+		"dir":     dir.Path(),
+		"entries": entries,
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/write-state",
+		Title: "Show the reads and writes of open file handles waiting to be in sequence.",
+		Help: strings.ReplaceAll(`
+Without |--vfs-cache-mode writes| or above files can only be written
+sequentially, and are read sequentially where possible. A write at an
+offset after the end of the data written so far waits up to
+|--vfs-write-wait| for the writes before it to arrive, and a read just
+after the current position waits up to |--vfs-read-wait| in the same
+way. This shows the file handles doing this, which helps diagnose
+writes stuck because a client never sends the data they are waiting
+for.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |path| - only show the handles open on the file with this path (optional)
+
+It returns
+
+    {
+        "handles": // an array of open file handles
+        [
+            {
+                "id":       1,                // integer: id of the handle
+                "path":     "dir/file.bin",   // string: path of the file relative to the root of the VFS
+                "type":     "write",          // string: "read" or "write"
+                "offset":   1048576,          // integer: offset the next in sequence read or write is expected at
+                "waiting":  // an array of the reads or writes waiting, in offset order
+                [
+                    {
+                        "offset": 2097152,    // integer: offset of the read or write
+                        "size":   131072,     // integer: size of the read or write in bytes
+                        "waited": 0.5         // float: seconds it has been waiting
+                    },
+                ],
+                "waits":    3,                // integer: number of reads or writes which have waited
+                "timeouts": 1,                // integer: number of those which gave up waiting
+                "waited":   1.25              // float: total seconds spent waiting, including the waits in progress
+            },
+        ],
+    }
+
+Handles opened with |--vfs-cache-mode writes| or above read and write
+the cache so are not shown.
+`, "|", "`") + getVFSHelp,
+		Fn: rcWriteState,
+	})
+}
+
+func rcWriteState(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	path, err := in.GetString("path")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	path = strings.Trim(path, "/")
+	handles := []rc.Params{}
+	for _, s := range vfs.listSeqStates() {
+		if path != "" && s.file.Path() != path {
+			continue
+		}
+		handles = append(handles, s.rcParams())
+	}
+	return rc.Params{"handles": handles}, nil
+}
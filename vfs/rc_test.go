@@ -2,11 +2,16 @@ package vfs
 
 import (
 	"context"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/vfs/vfscache"
+	"github.com/rclone/rclone/vfs/vfscache/writeback"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -101,6 +106,82 @@ func TestRcPollInterval(t *testing.T) {
 	// FIXME needs more tests
 }
 
+func TestRcPollInfo(t *testing.T) {
+	r, vfs, call := rcNewRun(t, "vfs/poll-info")
+	name := fs.ConfigString(r.Fremote)
+
+	info := func(in rc.Params) rc.Params {
+		out, err := call.Fn(context.Background(), in)
+		require.NoError(t, err)
+		vfses, ok := out["vfses"].(rc.Params)
+		require.True(t, ok)
+		require.Contains(t, vfses, name)
+		return vfses[name].(rc.Params)
+	}
+
+	out := info(nil)
+	if r.Fremote.Features().ChangeNotify == nil {
+		assert.Equal(t, "dircache", out["mode"])
+		assert.Equal(t, false, out["changeNotify"])
+		assert.Equal(t, vfs.Opt.DirCacheTime.String(), out["interval"].(map[string]any)["string"])
+	} else {
+		assert.Equal(t, "changenotify", out["mode"])
+		assert.Equal(t, true, out["changeNotify"])
+	}
+	assert.Equal(t, "", out["lastNotification"])
+
+	// Check notifications are recorded
+	vfs.changeNotify("file", fs.EntryObject)
+	out = info(rc.Params{"fs": name})
+	assert.NotEqual(t, "", out["lastNotification"])
+}
+
+func TestRcPollPause(t *testing.T) {
+	r, vfs, pause := rcNewRun(t, "vfs/poll-pause")
+	resume := rc.Calls.Get("vfs/poll-resume")
+	require.NotNil(t, resume)
+	ctx := context.Background()
+
+	if r.Fremote.Features().ChangeNotify == nil {
+		_, err := pause.Fn(ctx, rc.Params{})
+		assert.Equal(t, errPollNotSupported, err)
+
+		// Pretend to be a polling function for the rest of the test
+		vfs.pollChan = make(chan time.Duration)
+	}
+	intervals := make(chan time.Duration, 10)
+	go func() {
+		for interval := range vfs.pollChan {
+			intervals <- interval
+		}
+	}()
+	vfs.Opt.PollInterval = fs.Duration(time.Minute)
+
+	out, err := pause.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, "paused", out["mode"])
+	assert.Equal(t, true, out["paused"])
+	assert.Equal(t, false, out["timeout"])
+	assert.Equal(t, time.Duration(0), <-intervals)
+
+	// Pausing again does nothing
+	_, err = pause.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+
+	// Changing the interval while paused is used on resume
+	_, err = rc.Calls.Get("vfs/poll-interval").Fn(ctx, rc.Params{"interval": "2m"})
+	require.NoError(t, err)
+	assert.True(t, vfs.isPollPaused())
+
+	out, err = resume.Fn(ctx, rc.Params{"refresh": true})
+	require.NoError(t, err)
+	assert.Equal(t, "changenotify", out["mode"])
+	assert.Equal(t, false, out["paused"])
+	assert.Equal(t, false, out["timeout"])
+	assert.Equal(t, 2*time.Minute, <-intervals)
+	assert.Len(t, intervals, 0)
+}
+
 func TestRcList(t *testing.T) {
 	r, vfs, call := rcNewRun(t, "vfs/list")
 	_ = vfs
@@ -125,3 +206,669 @@ func TestRcStats(t *testing.T) {
 	assert.Equal(t, 1, out["metadataCache"].(rc.Params)["dirs"])
 	assert.Equal(t, vfs.Opt, out["opt"].(vfscommon.Options))
 }
+
+func TestRcCacheGC(t *testing.T) {
+	_, _, call := rcNewRun(t, "vfs/cache-gc")
+
+	// The test VFS has no cache so this should error
+	_, err := call.Fn(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "VFS cache")
+}
+
+func TestRcCacheEvictionPreview(t *testing.T) {
+	_, _, call := rcNewRun(t, "vfs/cache-eviction-preview")
+
+	// The test VFS has no cache so this should error
+	_, err := call.Fn(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "VFS cache")
+}
+
+func TestRcCacheLRU(t *testing.T) {
+	t.Run("NoCache", func(t *testing.T) {
+		_, _, call := rcNewRun(t, "vfs/cache-lru")
+		_, err := call.Fn(context.Background(), nil)
+		assert.ErrorContains(t, err, "VFS cache")
+	})
+
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+	call := rc.Calls.Get("vfs/cache-lru")
+	require.NotNil(t, call)
+
+	r.WriteObject(ctx, "file.txt", "hello", t1)
+	_, err := vfs.ReadFile("file.txt")
+	require.NoError(t, err)
+
+	out, err := call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, out["count"])
+	assert.Equal(t, int64(5), out["bytes"])
+	assert.Equal(t, "lru", out["policy"])
+	files := out["files"].([]rc.Params)
+	require.Len(t, files, 1)
+	assert.Equal(t, "file.txt", files[0]["name"])
+	assert.Equal(t, "evictable", files[0]["status"])
+}
+
+func TestRcPerHandleReadBwLimit(t *testing.T) {
+	_, vfs, call := rcNewRun(t, "vfs/per-handle-read-bwlimit")
+
+	out, err := call.Fn(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"rate":           "off",
+		"bytesPerSecond": int64(-1),
+		"rules":          "",
+	}, out)
+
+	out, err = call.Fn(context.Background(), rc.Params{"rate": "10M", "rules": "*.mkv=4M"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"rate":           "10Mi",
+		"bytesPerSecond": int64(10 * fs.Mebi),
+		"rules":          "*.mkv=4M",
+	}, out)
+	assert.Equal(t, 4*fs.Mebi, vfs.readBw.forPath("dir/film.mkv"))
+
+	// Leaves the rate alone if not supplied
+	out, err = call.Fn(context.Background(), rc.Params{"rules": ""})
+	require.NoError(t, err)
+	assert.Equal(t, "10Mi", out["rate"])
+	assert.Equal(t, 10*fs.Mebi, vfs.readBw.forPath("dir/film.mkv"))
+
+	_, err = call.Fn(context.Background(), rc.Params{"rules": "potato"})
+	assert.Error(t, err)
+	_, err = call.Fn(context.Background(), rc.Params{"rate": "potato"})
+	assert.Error(t, err)
+}
+
+func TestRcSetReadOnly(t *testing.T) {
+	_, vfs, call := rcNewRun(t, "vfs/set-read-only")
+	ctx := context.Background()
+
+	out, err := call.Fn(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"readOnly": false, "rejectInFlight": false}, out)
+
+	fh, err := vfs.OpenFile("file1", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	_, err = fh.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	// Handles already open can carry on writing
+	out, err = call.Fn(ctx, rc.Params{"readOnly": true})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"readOnly": true, "rejectInFlight": false}, out)
+	assert.Equal(t, true, vfs.Stats()["opt"].(vfscommon.Options).ReadOnly)
+	_, err = fh.Write([]byte(" world"))
+	require.NoError(t, err)
+	_, err = vfs.OpenFile("file2", os.O_WRONLY|os.O_CREATE, 0777)
+	assert.Equal(t, EROFS, err)
+	assert.Equal(t, EROFS, vfs.Mkdir("dir", 0777))
+
+	// Unless rejectInFlight is set
+	out, err = call.Fn(ctx, rc.Params{"readOnly": true, "rejectInFlight": true})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"readOnly": true, "rejectInFlight": true}, out)
+	_, err = fh.Write([]byte("!"))
+	assert.Equal(t, EROFS, err)
+	require.NoError(t, fh.Close())
+
+	out, err = call.Fn(ctx, rc.Params{"readOnly": false})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"readOnly": false, "rejectInFlight": false}, out)
+	assert.Equal(t, false, vfs.Stats()["opt"].(vfscommon.Options).ReadOnly)
+	require.NoError(t, vfs.Mkdir("dir", 0777))
+
+	// Can't make a --read-only VFS read write
+	vfs.Opt.ReadOnly = true
+	_, err = call.Fn(ctx, rc.Params{"readOnly": false})
+	assert.Error(t, err)
+	vfs.Opt.ReadOnly = false
+}
+
+func TestRcFlushFile(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	opt.WriteBack = fs.Duration(time.Hour)
+	r, vfs := newTestVFSOpt(t, &opt)
+	call := rc.Calls.Get("vfs/flush-file")
+	require.NotNil(t, call)
+	ctx := context.Background()
+
+	require.NoError(t, vfs.WriteFile("file1", []byte("file1 contents"), 0600))
+	require.NoError(t, vfs.WriteFile("file2", []byte("file2 contents"), 0600))
+	r.CheckRemoteItems(t)
+
+	// Only the file asked for is uploaded
+	_, err := call.Fn(ctx, rc.Params{"file": "file1"})
+	require.NoError(t, err)
+	file1 := fstest.NewItem("file1", "file1 contents", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, nil, fs.ModTimeNotSupported)
+	queue := vfs.cache.Queue()["queue"].([]writeback.QueueInfo)
+	require.Len(t, queue, 1)
+	assert.Equal(t, "file2", queue[0].Name)
+
+	// Errors if there is nothing to upload
+	_, err = call.Fn(ctx, rc.Params{"file": "file1"})
+	assert.ErrorContains(t, err, "no changes to upload")
+	_, err = call.Fn(ctx, rc.Params{"file": "notfound"})
+	assert.Equal(t, ENOENT, err)
+	_, err = call.Fn(ctx, rc.Params{})
+	assert.True(t, rc.IsErrParamNotFound(err))
+
+	_, err = call.Fn(ctx, rc.Params{"file": "file2"})
+	require.NoError(t, err)
+	file2 := fstest.NewItem("file2", "file2 contents", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2}, nil, fs.ModTimeNotSupported)
+}
+
+func TestRcWriteBackBwLimit(t *testing.T) {
+	_, _, call := rcNewRun(t, "vfs/write-back-bwlimit")
+
+	// The test VFS has no cache so this should error
+	_, err := call.Fn(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "VFS cache")
+}
+
+func TestRcWriteBackBwLimitCache(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	_, vfs := newTestVFSOpt(t, &opt)
+	call := rc.Calls.Get("vfs/write-back-bwlimit")
+	require.NotNil(t, call)
+	ctx := context.Background()
+
+	out, err := call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"rate":           "off",
+		"bytesPerSecond": int64(-1),
+	}, out)
+
+	out, err = call.Fn(ctx, rc.Params{"rate": "1M:10M"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"rate":           "1M:10M",
+		"bytesPerSecond": int64(fs.Mebi),
+	}, out)
+	timetable, limit := vfs.cache.WriteBackBwLimit()
+	assert.Equal(t, "1M:10M", timetable)
+	assert.Equal(t, fs.Mebi, limit)
+
+	_, err = call.Fn(ctx, rc.Params{"rate": "potato"})
+	assert.Error(t, err)
+	timetable, _ = vfs.cache.WriteBackBwLimit()
+	assert.Equal(t, "1M:10M", timetable)
+}
+
+func TestRcSetCacheLimits(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	opt.CacheMaxAge = fs.Duration(time.Hour)
+	opt.CacheMaxSize = -1
+	opt.CacheMinFreeSpace = -1
+	_, vfs := newTestVFSOpt(t, &opt)
+	call := rc.Calls.Get("vfs/set-cache-limits")
+	require.NotNil(t, call)
+	ctx := context.Background()
+
+	out, err := call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"maxAge":            "1h0m0s",
+		"maxAgeSeconds":     3600.0,
+		"maxSize":           "off",
+		"maxSizeBytes":      int64(-1),
+		"minFreeSpace":      "off",
+		"minFreeSpaceBytes": int64(-1),
+	}, out)
+
+	out, err = call.Fn(ctx, rc.Params{"maxSize": "10M", "maxAge": "30m"})
+	require.NoError(t, err)
+	assert.Equal(t, "30m0s", out["maxAge"])
+	assert.Equal(t, "10Mi", out["maxSize"])
+	assert.Equal(t, int64(10*fs.Mebi), out["maxSizeBytes"])
+	assert.Equal(t, "off", out["minFreeSpace"])
+	assert.Equal(t, 10*fs.Mebi, vfs.cache.Limits().MaxSize)
+	assert.Equal(t, 30*time.Minute, vfs.cache.Limits().MaxAge)
+
+	_, err = call.Fn(ctx, rc.Params{"maxSize": "potato"})
+	assert.Error(t, err)
+	_, err = call.Fn(ctx, rc.Params{"maxAge": "-1h"})
+	assert.Error(t, err)
+	assert.Equal(t, 10*fs.Mebi, vfs.cache.Limits().MaxSize)
+	assert.Equal(t, 30*time.Minute, vfs.cache.Limits().MaxAge)
+}
+
+func TestRcDownloads(t *testing.T) {
+	r, vfs, call := rcNewRun(t, "vfs/downloads")
+	cancelCall := rc.Calls.Get("vfs/downloads-cancel")
+	require.NotNil(t, cancelCall)
+	ctx := context.Background()
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+
+	list := func() []rc.Params {
+		out, err := call.Fn(ctx, rc.Params{})
+		require.NoError(t, err)
+		return out["downloads"].([]rc.Params)
+	}
+	assert.Len(t, list(), 0)
+
+	// Reading the file starts a download
+	fd, err := vfs.OpenFile("dir/file1", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	assert.Len(t, list(), 0)
+	buf := make([]byte, 5)
+	_, err = fd.ReadAt(buf, 0)
+	require.NoError(t, err)
+	downloads := list()
+	require.Len(t, downloads, 1)
+	assert.Equal(t, "dir/file1", downloads[0]["path"])
+	assert.Equal(t, int64(14), downloads[0]["size"])
+	id := downloads[0]["id"].(int64)
+
+	// Check the parameters
+	_, err = cancelCall.Fn(ctx, rc.Params{})
+	assert.ErrorContains(t, err, "need id or path")
+	_, err = cancelCall.Fn(ctx, rc.Params{"path": "potato"})
+	assert.ErrorContains(t, err, "no matching downloads")
+
+	// Cancelling it stops the reads
+	out, err := cancelCall.Fn(ctx, rc.Params{"id": id})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"cancelled": 1}, out)
+	assert.Len(t, list(), 0)
+	_, err = fd.ReadAt(buf, 0)
+	assert.ErrorIs(t, err, errDownloadCancelled)
+	require.NoError(t, fd.Close())
+
+	// The file can be read again
+	data, err := vfs.ReadFile("dir/file1")
+	require.NoError(t, err)
+	assert.Equal(t, "file1 contents", string(data))
+	assert.Len(t, list(), 0)
+}
+
+func TestRcChunkReader(t *testing.T) {
+	r, vfs, call := rcNewRun(t, "vfs/chunk-reader-info")
+	tuneCall := rc.Calls.Get("vfs/chunk-reader-tune")
+	require.NotNil(t, tuneCall)
+	ctx := context.Background()
+	vfs.Opt.ChunkSize = 4
+	vfs.Opt.ChunkSizeLimit = 4
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+
+	out, err := call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Len(t, out["readers"], 0)
+	_, err = call.Fn(ctx, rc.Params{"id": 1})
+	assert.ErrorContains(t, err, "not found")
+
+	fd, err := vfs.OpenFile("dir/file1", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = fd.ReadAt(buf, 0)
+	require.NoError(t, err)
+
+	out, err = call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	readers := out["readers"].([]rc.Params)
+	require.Len(t, readers, 1)
+	info := readers[0]
+	id := info["id"].(int64)
+	assert.Equal(t, "dir/file1", info["path"])
+	assert.Equal(t, int64(4), info["chunkSize"])
+	assert.Equal(t, 1, info["streams"])
+	assert.Equal(t, int64(5), info["position"])
+	assert.Equal(t, int64(4), info["tunedChunkSize"])
+	assert.Equal(t, false, info["tunePending"])
+
+	// Check the parameters
+	_, err = tuneCall.Fn(ctx, rc.Params{"id": id + 1, "chunkSize": "8B"})
+	assert.ErrorContains(t, err, "not found")
+	_, err = tuneCall.Fn(ctx, rc.Params{"id": id})
+	assert.ErrorContains(t, err, "need chunkSize or chunkStreams")
+	_, err = tuneCall.Fn(ctx, rc.Params{"id": id, "chunkStreams": -1})
+	assert.ErrorContains(t, err, "must not be negative")
+
+	// Tuning re-opens the reader at the next read
+	info, err = tuneCall.Fn(ctx, rc.Params{"id": id, "chunkSize": "8B", "chunkStreams": 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info["tunedChunkSize"])
+	assert.Equal(t, int64(2), info["tunedStreams"])
+	assert.Equal(t, true, info["tunePending"])
+	_, err = fd.ReadAt(buf, 5)
+	require.NoError(t, err)
+	assert.Equal(t, " cont", string(buf))
+	info, err = call.Fn(ctx, rc.Params{"id": id})
+	require.NoError(t, err)
+	info = info["readers"].([]rc.Params)[0]
+	assert.Equal(t, 2, info["streams"])
+	assert.Equal(t, int64(10), info["position"])
+	assert.Equal(t, false, info["tunePending"])
+	require.NoError(t, fd.Close())
+}
+
+func TestRcAccessHistory(t *testing.T) {
+	r, vfs, call := rcNewRun(t, "vfs/access-history")
+	ctx := context.Background()
+
+	_, err := call.Fn(ctx, rc.Params{})
+	assert.ErrorContains(t, err, "not enabled")
+
+	vfs.Opt.AccessHistory = 3
+	vfs.accessHistory = newAccessHistory(vfs.Opt.AccessHistory)
+	list := func() []rc.Params {
+		out, err := call.Fn(ctx, rc.Params{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, out["size"])
+		return out["accesses"].([]rc.Params)
+	}
+	assert.Len(t, list(), 0)
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+	fd, err := vfs.OpenFile("dir/file1", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = fd.ReadAt(buf, 0)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+
+	accesses := list()
+	require.Len(t, accesses, 2)
+	assert.Equal(t, "dir/file1", accesses[0]["path"])
+	assert.Equal(t, "open", accesses[0]["op"])
+	assert.Equal(t, int64(0), accesses[0]["bytes"])
+	assert.Equal(t, "read", accesses[1]["op"])
+	assert.Equal(t, int64(5), accesses[1]["bytes"])
+
+	// The oldest accesses are dropped when the history is full
+	_, err = vfs.ReadFile("dir/file1")
+	require.NoError(t, err)
+	accesses = list()
+	require.Len(t, accesses, 3)
+	assert.Equal(t, "read", accesses[0]["op"])
+	assert.Equal(t, int64(5), accesses[0]["bytes"])
+	assert.Equal(t, "open", accesses[1]["op"])
+	assert.Equal(t, "read", accesses[2]["op"])
+	assert.Equal(t, int64(14), accesses[2]["bytes"])
+}
+
+func TestRcRefreshDir(t *testing.T) {
+	r, vfs, call := rcNewRun(t, "vfs/refresh-dir")
+	ctx := context.Background()
+	vfs.Opt.DirCacheTime = fs.Duration(time.Hour)
+	vfs.Opt.ListingStabilityWindow = 3
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+	names := func(out rc.Params) (names []string) {
+		for _, entry := range out["entries"].([]rc.Params) {
+			names = append(names, entry["name"].(string))
+		}
+		return names
+	}
+
+	out, err := call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, "", out["dir"])
+	assert.Equal(t, []string{"dir"}, names(out))
+
+	// Changes made outside the VFS are seen straight away even
+	// though the listings are cached and must be stable
+	file2 := r.WriteObject(ctx, "dir/file2", "file2 contents", t1)
+	file3 := r.WriteObject(ctx, "dir2/sub/file3", "file3 contents", t1)
+	r.CheckRemoteItems(t, file1, file2, file3)
+	out, err = call.Fn(ctx, rc.Params{"dir": "dir"})
+	require.NoError(t, err)
+	assert.Equal(t, "dir", out["dir"])
+	assert.Equal(t, []string{"file1", "file2"}, names(out))
+	entry := out["entries"].([]rc.Params)[1]
+	assert.Equal(t, false, entry["isDir"])
+	assert.Equal(t, int64(14), entry["size"])
+
+	// Directories above which aren't in the cached listings are
+	// read too
+	out, err = call.Fn(ctx, rc.Params{"dir": "dir2/sub"})
+	require.NoError(t, err)
+	assert.Equal(t, "dir2/sub", out["dir"])
+	assert.Equal(t, []string{"file3"}, names(out))
+	node, err := vfs.Stat("dir2")
+	require.NoError(t, err)
+	assert.True(t, node.IsDir())
+
+	_, err = call.Fn(ctx, rc.Params{"dir": "dir/file1"})
+	assert.Equal(t, EINVAL, err)
+	_, err = call.Fn(ctx, rc.Params{"dir": "potato"})
+	assert.Equal(t, ENOENT, err)
+}
+
+func TestRcOpenProgress(t *testing.T) {
+	t.Run("NoCache", func(t *testing.T) {
+		_, _, call := rcNewRun(t, "vfs/open-progress")
+		_, err := call.Fn(context.Background(), nil)
+		assert.ErrorContains(t, err, "need --vfs-cache-mode")
+	})
+
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	r, vfs := newTestVFSOpt(t, &opt)
+	call := rc.Calls.Get("vfs/open-progress")
+	require.NotNil(t, call)
+	ctx := context.Background()
+	const size = 16 * 1024 * 1024
+	r.WriteObject(ctx, "big", random.String(size), t1)
+
+	out, err := call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Len(t, out["files"], 0)
+
+	// Reading the start of the file only downloads part of it
+	fd, err := vfs.OpenFile("big", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, fd.Close())
+	}()
+	buf := make([]byte, 5)
+	_, err = fd.ReadAt(buf, 0)
+	require.NoError(t, err)
+
+	out, err = call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	files := out["files"].([]rc.Params)
+	require.Len(t, files, 1)
+	assert.Equal(t, "big", files[0]["path"])
+	assert.Equal(t, int64(size), files[0]["size"])
+	bytes := files[0]["bytes"].(int64)
+	assert.True(t, bytes >= 5 && bytes < size, bytes)
+	assert.Equal(t, false, files[0]["waiting"])
+
+	out, err = call.Fn(ctx, rc.Params{"path": "other"})
+	require.NoError(t, err)
+	assert.Len(t, out["files"], 0)
+}
+
+func TestOpenProgressParams(t *testing.T) {
+	out := openProgressParams(vfscache.DownloadProgress{
+		Name:    "file",
+		Bytes:   25,
+		Size:    100,
+		Speed:   15,
+		Waiters: 1,
+	})
+	assert.Equal(t, rc.Params{
+		"path":    "file",
+		"bytes":   int64(25),
+		"size":    int64(100),
+		"percent": 25,
+		"speed":   15.0,
+		"eta":     int64(5),
+		"waiting": true,
+	}, out)
+
+	// Unknown speed gives no eta
+	out = openProgressParams(vfscache.DownloadProgress{Name: "file", Size: 100})
+	assert.Equal(t, 0, out["percent"])
+	assert.Nil(t, out["eta"])
+}
+
+func TestRcCacheManifest(t *testing.T) {
+	t.Run("NoCache", func(t *testing.T) {
+		_, _, call := rcNewRun(t, "vfs/cache-manifest")
+		_, err := call.Fn(context.Background(), nil)
+		assert.ErrorContains(t, err, "VFS cache")
+		call = rc.Calls.Get("vfs/cache-manifest-check")
+		require.NotNil(t, call)
+		_, err = call.Fn(context.Background(), rc.Params{"manifest": rc.Params{}})
+		assert.ErrorContains(t, err, "VFS cache")
+	})
+
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+	manifestCall := rc.Calls.Get("vfs/cache-manifest")
+	require.NotNil(t, manifestCall)
+	checkCall := rc.Calls.Get("vfs/cache-manifest-check")
+	require.NotNil(t, checkCall)
+
+	r.WriteObject(ctx, "file.txt", "hello", t1)
+	_, err := vfs.ReadFile("file.txt")
+	require.NoError(t, err)
+
+	manifest, err := manifestCall.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, manifest["count"])
+	items := manifest["items"].([]rc.Params)
+	require.Len(t, items, 1)
+	assert.Equal(t, "file.txt", items[0]["name"])
+	assert.Equal(t, float64(5), items[0]["size"])
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", items[0]["hash"])
+	assert.Equal(t, false, items[0]["dirty"])
+
+	out, err := checkCall.Fn(ctx, rc.Params{"manifest": manifest})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"problems": []rc.Params{}, "checked": 1, "ok": 1}, out)
+
+	// Files which don't match are reported
+	items[0]["hash"] = "potato"
+	items = append(items, rc.Params{"name": "missing.txt", "size": 1})
+	out, err = checkCall.Fn(ctx, rc.Params{"manifest": rc.Params{"items": items}})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"problems": []rc.Params{
+			{"name": "file.txt", "problem": "hash differs"},
+			{"name": "missing.txt", "problem": "missing from cache"},
+		},
+		"checked": 2,
+		"ok":      0,
+	}, out)
+
+	_, err = checkCall.Fn(ctx, rc.Params{})
+	assert.Error(t, err)
+}
+
+func TestRcAdminStatus(t *testing.T) {
+	r, _, call := rcNewRun(t, "admin/status")
+	name := fs.ConfigString(r.Fremote)
+
+	out, err := call.Fn(context.Background(), nil)
+	require.NoError(t, err)
+	vfses, ok := out["vfses"].(rc.Params)
+	require.True(t, ok)
+	require.Contains(t, vfses, name)
+	status := vfses[name].(rc.Params)
+
+	stats, ok := status["stats"].(rc.Params)
+	require.True(t, ok)
+	assert.Equal(t, name, stats["fs"])
+	assert.NotNil(t, stats["metadataCache"])
+
+	pollInfo, ok := status["pollInfo"].(rc.Params)
+	require.True(t, ok)
+	assert.Contains(t, pollInfo, "mode")
+
+	// bisync isn't linked into this test
+	assert.NotContains(t, out, "bisync")
+}
+
+func TestRcWriteState(t *testing.T) {
+	_, vfs, call := rcNewRun(t, "vfs/write-state")
+	ctx := context.Background()
+	vfs.Opt.WriteWait = fs.Duration(10 * time.Second)
+
+	out, err := call.Fn(ctx, rc.Params{})
+	require.NoError(t, err)
+	assert.Len(t, out["handles"], 0)
+
+	fd, err := vfs.OpenFile("file1", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+
+	// handles returns the handles open on path
+	handles := func(path string) []rc.Params {
+		out, err := call.Fn(ctx, rc.Params{"path": path})
+		require.NoError(t, err)
+		return out["handles"].([]rc.Params)
+	}
+
+	// A write after the end waits for the data before it
+	done := make(chan error)
+	go func() {
+		_, err := fd.WriteAt([]byte("world"), 6)
+		done <- err
+	}()
+	require.Eventually(t, func() bool {
+		h := handles("file1")
+		return len(h) == 1 && len(h[0]["waiting"].([]rc.Params)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	h := handles("file1")[0]
+	assert.Equal(t, "write", h["type"])
+	assert.Equal(t, int64(0), h["offset"])
+	waiting := h["waiting"].([]rc.Params)[0]
+	assert.Equal(t, int64(6), waiting["offset"])
+	assert.Equal(t, 5, waiting["size"])
+	assert.Len(t, handles("other"), 0)
+
+	_, err = fd.WriteAt([]byte("hello "), 0)
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+	h = handles("file1")[0]
+	assert.Equal(t, int64(11), h["offset"])
+	assert.Len(t, h["waiting"], 0)
+	assert.Equal(t, 1, h["waits"])
+	assert.Equal(t, 0, h["timeouts"])
+	assert.Greater(t, h["waited"].(float64), 0.0)
+
+	// Closed handles aren't shown
+	require.NoError(t, fd.Close())
+	assert.Len(t, handles(""), 0)
+}
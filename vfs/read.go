@@ -13,6 +13,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/chunkedreader"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
 )
 
@@ -23,10 +24,24 @@ type ReadFileHandle struct {
 	mu          sync.Mutex
 	cond        sync.Cond // cond lock for out of sequence reads
 	r           *accounting.Account
-	size        int64 // size of the object (0 for unknown length)
-	offset      int64 // offset of read of o
-	roffset     int64 // offset of Read() calls
+	cancel      context.CancelFunc                          // cancels the context of the current reader
+	ctx         context.Context                             // parent context of the readers
+	abort       context.CancelFunc                          // cancels ctx
+	aborted     atomic.Bool                                 // set if the download was cancelled with vfs/downloads-cancel
+	downloadID  int64                                       // id of the download while opened
+	seq         *seqState                                   // reads waiting to be in sequence for vfs/write-state
+	chunked     atomic.Pointer[chunkedreader.ChunkedReader] // chunked reader in use for vfs/chunk-reader-info, may be nil
+	position    atomic.Int64                                // copy of offset for vfs/chunk-reader-info
+	chunkSize   atomic.Int64                                // chunk size for new chunked readers, changed by vfs/chunk-reader-tune
+	streams     atomic.Int64                                // streams for new chunked readers, changed by vfs/chunk-reader-tune
+	retune      atomic.Bool                                 // set to re-open the chunked reader at the next read after vfs/chunk-reader-tune
+	size        int64                                       // size of the object (0 for unknown length)
+	offset      int64                                       // offset of read of o
+	roffset     int64                                       // offset of Read() calls
 	file        *File
+	fallback    fs.Object // object on --vfs-fallback-remote being read, or nil
+	placeholder []byte    // --vfs-read-error-file contents read instead after reading failed, or nil
+	limiter     *readLimiter
 	hash        *hash.MultiHasher
 	remote      string
 	closed      bool // set if handle has been closed
@@ -60,34 +75,146 @@ func newReadFileHandle(f *File) (*ReadFileHandle, error) {
 		remote:      o.Remote(),
 		noSeek:      f.VFS().Opt.NoSeek,
 		file:        f,
+		limiter:     f.VFS().newReadLimiter(f.Path()),
 		hash:        mhash,
 		size:        nonNegative(o.Size()),
 		sizeUnknown: o.Size() < 0,
 	}
+	fh.ctx, fh.abort = context.WithCancel(context.Background())
 	fh.cond = sync.Cond{L: &fh.mu}
+	fh.chunkSize.Store(int64(f.VFS().Opt.ChunkSize))
+	fh.streams.Store(int64(f.VFS().Opt.ChunkStreams))
+	fh.seq = f.VFS().addSeqState("read", f, &fh.position)
 	return fh, nil
 }
 
+// newChunkedReader makes a chunked reader for o with the chunk size
+// and streams of the handle
+//
+// Must be called with fh.mu held
+func (fh *ReadFileHandle) newChunkedReader(o fs.Object) chunkedreader.ChunkedReader {
+	opt := &fh.file.VFS().Opt
+	return chunkedreader.NewWithLimit(fh.newReaderContext(), o, fh.chunkSize.Load(), int64(opt.ChunkSizeLimit), int(fh.streams.Load()), opt.ChunkLimitStrategy, time.Duration(opt.ChunkStallTimeout), int64(opt.MinChunkStreamSize))
+}
+
+// setChunkedReader records r as the chunked reader in use
+func (fh *ReadFileHandle) setChunkedReader(r chunkedreader.ChunkedReader) {
+	if r == nil {
+		fh.chunked.Store(nil)
+		return
+	}
+	fh.chunked.Store(&r)
+}
+
+// setOffset sets the offset of the reads of o
+//
+// Must be called with fh.mu held
+func (fh *ReadFileHandle) setOffset(offset int64) {
+	fh.offset = offset
+	fh.position.Store(offset)
+}
+
 // openPending opens the file if there is a pending open
 // call with the lock held
 func (fh *ReadFileHandle) openPending() (err error) {
 	if fh.opened {
 		return nil
 	}
-	o := fh.file.getObject()
-	opt := &fh.file.VFS().Opt
-	r, err := chunkedreader.New(context.TODO(), o, int64(opt.ChunkSize), int64(opt.ChunkSizeLimit), opt.ChunkStreams).Open()
+	o := fh.getObject()
+	r, err := fh.newChunkedReader(o).Open()
+	if err != nil && fh.openFallback(err) {
+		o = fh.fallback
+		r, err = fh.newChunkedReader(o).Open()
+	}
 	if err != nil {
 		return err
 	}
+	fh.setChunkedReader(r)
 	tr := accounting.GlobalStats().NewTransfer(o, nil)
 	fh.done = tr.Done
 	fh.r = tr.Account(context.TODO(), r).WithBuffer() // account the transfer
 	fh.opened = true
+	fh.downloadID = fh.file.VFS().addDownload(fh, tr)
 
 	return nil
 }
 
+// getObject returns the object being read from
+//
+// Must be called with fh.mu held
+func (fh *ReadFileHandle) getObject() fs.Object {
+	if fh.fallback != nil {
+		return fh.fallback
+	}
+	return fh.file.getObject()
+}
+
+// openFallback switches to reading from the object on
+// --vfs-fallback-remote after reading from the remote failed with
+// err. The reader must be re-opened after this.
+//
+// It returns false if there is nothing to fall back to.
+//
+// Must be called with fh.mu held
+func (fh *ReadFileHandle) openFallback(err error) bool {
+	f := fh.file.VFS().fallback
+	if f == nil || fh.fallback != nil {
+		return false
+	}
+	o, oErr := f.NewObject(context.TODO(), fh.remote)
+	if oErr != nil {
+		fs.Errorf(fh.remote, "ReadFileHandle: failed to find file on fallback remote %v: %v", f, oErr)
+		return false
+	}
+	if !fh.sizeUnknown && o.Size() != fh.size {
+		fs.Errorf(fh.remote, "ReadFileHandle: not using fallback remote %v as size differs: %d vs %d", f, o.Size(), fh.size)
+		return false
+	}
+	fs.Logf(fh.remote, "ReadFileHandle: reading from fallback remote %v as read failed: %v", f, err)
+	fh.fallback = o
+	fh.hash = nil // the hashes may not be comparable
+	return true
+}
+
+// usePlaceholder switches to reading the --vfs-read-error-file
+// contents after reading failed with err.
+//
+// It returns false if err should be returned instead.
+//
+// Must be called with fh.mu held
+func (fh *ReadFileHandle) usePlaceholder(err error) bool {
+	content := fh.file.readErrorContent(err)
+	if content == nil {
+		return false
+	}
+	fh.placeholder = content
+	fh.hash = nil // the placeholder won't match the hash
+	return true
+}
+
+// newReaderContext cancels the context of any existing reader and
+// returns a new one for a new reader.
+//
+// Must be called with fh.mu held
+func (fh *ReadFileHandle) newReaderContext() context.Context {
+	if fh.cancel != nil {
+		fh.cancel()
+	}
+	ctx, cancel := context.WithCancel(fh.ctx)
+	fh.cancel = cancel
+	return ctx
+}
+
+// abortDownload cancels the download so the current and future reads
+// from the handle fail.
+//
+// This can be called without fh.mu held.
+func (fh *ReadFileHandle) abortDownload() {
+	fs.Infof(fh.remote, "ReadFileHandle: cancelling download")
+	fh.aborted.Store(true)
+	fh.abort()
+}
+
 // String converts it to printable
 func (fh *ReadFileHandle) String() string {
 	if fh == nil {
@@ -122,7 +249,7 @@ func (fh *ReadFileHandle) seek(offset int64, reopen bool) (err error) {
 		ar := fh.r.GetAsyncReader()
 		// try to fulfill the seek with buffer discard
 		if ar != nil && ar.SkipBytes(int(offset-fh.offset)) {
-			fh.offset = offset
+			fh.setOffset(offset)
 			return nil
 		}
 	}
@@ -148,9 +275,8 @@ func (fh *ReadFileHandle) seek(offset int64, reopen bool) (err error) {
 			fs.Debugf(fh.remote, "ReadFileHandle.Read seek close old failed: %v", err)
 		}
 		// re-open with a seek
-		o := fh.file.getObject()
-		opt := &fh.file.VFS().Opt
-		r = chunkedreader.New(context.TODO(), o, int64(opt.ChunkSize), int64(opt.ChunkSizeLimit), opt.ChunkStreams)
+		o := fh.getObject()
+		r = fh.newChunkedReader(o)
 		_, err := r.Seek(offset, 0)
 		if err != nil {
 			fs.Debugf(fh.remote, "ReadFileHandle.Read seek failed: %v", err)
@@ -163,7 +289,8 @@ func (fh *ReadFileHandle) seek(offset int64, reopen bool) (err error) {
 		}
 	}
 	fh.r.UpdateReader(context.TODO(), r)
-	fh.offset = offset
+	fh.setChunkedReader(r)
+	fh.setOffset(offset)
 	return nil
 }
 
@@ -212,16 +339,23 @@ func (fh *ReadFileHandle) Seek(offset int64, whence int) (n int64, err error) {
 // Implementations must not retain p.
 func (fh *ReadFileHandle) ReadAt(p []byte, off int64) (n int, err error) {
 	fh.mu.Lock()
-	defer fh.mu.Unlock()
-	return fh.readAt(p, off)
+	n, err = fh.readAt(p, off)
+	fh.mu.Unlock()
+	// Wait for the bandwidth limit with the mutex unlocked
+	fh.limiter.wait(n)
+	return n, err
 }
 
 // This waits for *poff to equal off or aborts after the timeout.
 //
 // Waits here potentially affect all seeks so need to keep them short.
 //
+// The wait for the read or write of size is recorded in seq for
+// vfs/write-state.
+//
 // Call with fh.mu Locked
-func waitSequential(what string, remote string, cond *sync.Cond, maxWait time.Duration, poff *int64, off int64) {
+func waitSequential(what string, remote string, cond *sync.Cond, maxWait time.Duration, poff *int64, off int64, seq *seqState, size int) {
+	w := seq.startWait(off, size)
 	var (
 		timeout = time.NewTimer(maxWait)
 		done    = make(chan struct{})
@@ -251,13 +385,65 @@ func waitSequential(what string, remote string, cond *sync.Cond, maxWait time.Du
 	if *poff != off {
 		fs.Debugf(remote, "failed to wait for in-sequence %s to %d", what, off)
 	}
+	seq.endWait(w, *poff != off)
+}
+
+// readFull reads len(p) bytes from the underlying reader.
+//
+// If --vfs-read-timeout is set and the read takes longer than that
+// then the context of the reader is cancelled and a retryable error
+// is returned. The reader must be re-opened after this.
+//
+// Call with fh.mu Locked
+func (fh *ReadFileHandle) readFull(p []byte) (n int, err error) {
+	timeout := time.Duration(fh.file.VFS().Opt.ReadTimeout)
+	if timeout <= 0 {
+		return io.ReadFull(fh.r, p)
+	}
+	var timedOut atomic.Bool
+	cancel := fh.cancel
+	timer := time.AfterFunc(timeout, func() {
+		timedOut.Store(true)
+		cancel()
+	})
+	n, err = io.ReadFull(fh.r, p)
+	timer.Stop()
+	if timedOut.Load() {
+		err = fserrors.RetryError(fmt.Errorf("read timed out after %v: %w", timeout, errTimeout))
+	}
+	return n, err
 }
 
 // Implementation of ReadAt - call with lock held
 func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 	// defer log.Trace(fh.remote, "p[%d], off=%d", len(p), off)("n=%d, err=%v", &n, &err)
+	span := fh.file.startSpan("vfs.Read")
+	defer func() {
+		endIOSpan(span, off, len(p), n, err)
+		if n > 0 {
+			fh.file.recordAccess(accessRead, n)
+		}
+		err = fh.file.VFS().mapError(err)
+	}()
+	if fh.placeholder != nil && !fh.closed {
+		return readPlaceholder(fh.placeholder, p, off)
+	}
+	// Don't open the remote for a zero length read or a read past
+	// the end, as clients probing whether a file exists do these.
+	if !fh.opened && !fh.sizeUnknown && (len(p) == 0 || off >= fh.size) {
+		if fh.closed {
+			return 0, ECLOSED
+		}
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
 	err = fh.openPending() // FIXME pending open could be more efficient in the presence of seek (and retries)
 	if err != nil {
+		if fh.usePlaceholder(err) {
+			return readPlaceholder(fh.placeholder, p, off)
+		}
 		return 0, err
 	}
 	// fs.Debugf(fh.remote, "ReadFileHandle.Read size %d offset %d", reqSize, off)
@@ -265,9 +451,12 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 		fs.Errorf(fh.remote, "ReadFileHandle.Read error: %v", EBADF)
 		return 0, ECLOSED
 	}
+	if fh.aborted.Load() {
+		return 0, errDownloadCancelled
+	}
 	maxBuf := min(len(p), 1024*1024)
 	if gap := off - fh.offset; gap > 0 && gap < int64(8*maxBuf) {
-		waitSequential("read", fh.remote, &fh.cond, time.Duration(fh.file.VFS().Opt.ReadWait), &fh.offset, off)
+		waitSequential("read", fh.remote, &fh.cond, time.Duration(fh.file.VFS().Opt.ReadWait), &fh.offset, off, fh.seq, len(p))
 	}
 	doSeek := off != fh.offset
 	if doSeek && fh.noSeek {
@@ -277,6 +466,10 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 	retries := 0
 	reqSize := len(p)
 	doReopen := false
+	// Re-open the reader with the settings from vfs/chunk-reader-tune
+	if fh.retune.Swap(false) && !fh.noSeek {
+		doSeek, doReopen = true, true
+	}
 	lowLevelRetries := fs.GetConfig(context.TODO()).LowLevelRetries
 	for {
 		if doSeek {
@@ -296,7 +489,7 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 			if reqSize > 0 {
 				fh.readCalled = true
 			}
-			n, err = io.ReadFull(fh.r, p)
+			n, err = fh.readFull(p)
 			newOffset = fh.offset + int64(n)
 			// if err == nil && rand.Intn(10) == 0 {
 			// 	err = errors.New("random error")
@@ -314,9 +507,19 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 				break
 			}
 		}
-		if retries >= lowLevelRetries {
+		if fh.aborted.Load() {
+			err = errDownloadCancelled
 			break
 		}
+		if retries >= lowLevelRetries {
+			if !fh.openFallback(err) {
+				break
+			}
+			retries = 0
+			doSeek = true
+			doReopen = true
+			continue
+		}
 		retries++
 		fs.Errorf(fh.remote, "ReadFileHandle.Read error: low level retry %d/%d: %v", retries, lowLevelRetries, err)
 		doSeek = true
@@ -324,8 +527,12 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 	}
 	if err != nil {
 		fs.Errorf(fh.remote, "ReadFileHandle.Read error: %v", err)
+		if fh.usePlaceholder(err) {
+			fh.cond.Broadcast()
+			return readPlaceholder(fh.placeholder, p, off)
+		}
 	} else {
-		fh.offset = newOffset
+		fh.setOffset(newOffset)
 		// fs.Debugf(fh.remote, "ReadFileHandle.Read OK")
 
 		if fh.hash != nil {
@@ -394,12 +601,15 @@ func (fh *ReadFileHandle) checkHash() error {
 // Implementations must not retain p.
 func (fh *ReadFileHandle) Read(p []byte) (n int, err error) {
 	fh.mu.Lock()
-	defer fh.mu.Unlock()
 	if fh.roffset >= fh.size && !fh.sizeUnknown {
+		fh.mu.Unlock()
 		return 0, io.EOF
 	}
 	n, err = fh.readAt(p, fh.roffset)
 	fh.roffset += int64(n)
+	fh.mu.Unlock()
+	// Wait for the bandwidth limit with the mutex unlocked
+	fh.limiter.wait(n)
 	return n, err
 }
 
@@ -412,12 +622,16 @@ func (fh *ReadFileHandle) close() error {
 		return ECLOSED
 	}
 	fh.closed = true
+	defer fh.abort()
+	fh.file.VFS().removeSeqState(fh.seq)
 
 	if fh.opened {
+		fh.file.VFS().removeDownload(fh.downloadID)
 		var err error
 		defer func() {
 			fh.done(context.TODO(), err)
 		}()
+		defer fh.cancel()
 		// Close first so that we have hashes
 		err = fh.r.Close()
 		if err != nil {
@@ -2,11 +2,18 @@ package vfs
 
 import (
 	"context"
+	"errors"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -178,6 +185,30 @@ func TestReadFileHandleReadAt(t *testing.T) {
 	assert.Equal(t, ECLOSED, err)
 }
 
+func TestReadFileHandleZeroLength(t *testing.T) {
+	_, _, fh := readHandleCreate(t)
+
+	// Zero length reads and reads past the end don't open the remote
+	n, err := fh.ReadAt(nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	buf := make([]byte, 1)
+	n, err = fh.ReadAt(buf, 16)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 0, n)
+	assert.False(t, fh.opened)
+
+	// A real read does
+	n, err = fh.ReadAt(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "0", string(buf[:n]))
+	assert.True(t, fh.opened)
+
+	require.NoError(t, fh.Close())
+	_, err = fh.ReadAt(nil, 0)
+	assert.Equal(t, ECLOSED, err)
+}
+
 func TestReadFileHandleFlush(t *testing.T) {
 	_, _, fh := readHandleCreate(t)
 
@@ -230,3 +261,123 @@ func TestReadFileHandleRelease(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, fh.closed)
 }
+
+func TestReadFileHandleFallback(t *testing.T) {
+	r := fstest.NewRun(t)
+	opt := vfscommon.Opt
+	opt.FallbackRemote = r.LocalName
+	vfs := New(r.Fremote, &opt)
+	t.Cleanup(func() {
+		cleanupVFS(t, vfs)
+	})
+	require.NotNil(t, vfs.fallback)
+
+	ctx := context.Background()
+	file1 := r.WriteObject(ctx, "dir/file1", "0123456789abcdef", t1)
+	r.CheckRemoteItems(t, file1)
+	r.WriteFile("dir/file1", "0123456789abcdef", t1)
+
+	h, err := vfs.OpenFile("dir/file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*ReadFileHandle)
+	require.True(t, ok)
+
+	// Remove the file from the remote so reading it fails
+	o, err := r.Fremote.NewObject(ctx, "dir/file1")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+
+	// Check the read falls back
+	assert.Equal(t, "0123456789abcdef", readString(t, fh, 16))
+	assert.NotNil(t, fh.fallback)
+	require.NoError(t, fh.Close())
+}
+
+func TestReadFileHandleReadError(t *testing.T) {
+	r := fstest.NewRun(t)
+	placeholder := filepath.Join(t.TempDir(), "unavailable.txt")
+	require.NoError(t, os.WriteFile(placeholder, []byte("unavailable"), 0666))
+	opt := vfscommon.Opt
+	opt.ReadErrorFile = placeholder
+	opt.ReadErrorInclude = "*.mkv"
+	vfs := New(r.Fremote, &opt)
+	t.Cleanup(func() {
+		cleanupVFS(t, vfs)
+	})
+	require.NotNil(t, vfs.readError)
+
+	ctx := context.Background()
+	file1 := r.WriteObject(ctx, "dir/file1.mkv", "0123456789abcdef", t1)
+	file2 := r.WriteObject(ctx, "dir/file2.txt", "0123456789abcdef", t1)
+	r.CheckRemoteItems(t, file1, file2)
+
+	open := func(name string) *ReadFileHandle {
+		h, err := vfs.OpenFile(name, os.O_RDONLY, 0777)
+		require.NoError(t, err)
+		fh, ok := h.(*ReadFileHandle)
+		require.True(t, ok)
+		return fh
+	}
+	fh1, fh2 := open("dir/file1.mkv"), open("dir/file2.txt")
+
+	// Remove the files from the remote so reading them fails
+	for _, remote := range []string{"dir/file1.mkv", "dir/file2.txt"} {
+		o, err := r.Fremote.NewObject(ctx, remote)
+		require.NoError(t, err)
+		require.NoError(t, o.Remove(ctx))
+	}
+
+	// Check matching files read the placeholder
+	buf := make([]byte, 16)
+	n, err := fh1.Read(buf)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "unavailable", string(buf[:n]))
+	n, err = fh1.ReadAt(buf, 2)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "available", string(buf[:n]))
+	require.NoError(t, fh1.Close())
+
+	// Check other files return the error
+	_, err = fh2.Read(buf)
+	assert.Error(t, err)
+	assert.NotEqual(t, io.EOF, err)
+	assert.Nil(t, fh2.placeholder)
+	_ = fh2.Close()
+}
+
+// ctxReader is a reader which blocks until its context is cancelled
+type ctxReader struct {
+	ctx context.Context
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func TestReadFileHandleReadTimeout(t *testing.T) {
+	_, vfs, fh := readHandleCreate(t)
+	defer func() {
+		_ = fh.Close()
+	}()
+	vfs.Opt.ReadTimeout = fs.Duration(50 * time.Millisecond)
+
+	// Replace the reader with one which never returns any data
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tr := accounting.Stats(ctx).NewTransferRemoteSize("dir/file1", 16, nil, nil)
+	defer tr.Done(ctx, nil)
+	fh.mu.Lock()
+	oldR, oldCancel := fh.r, fh.cancel
+	fh.r = tr.Account(ctx, io.NopCloser(ctxReader{ctx: ctx}))
+	fh.cancel = cancel
+	n, err := fh.readFull(make([]byte, 5))
+	fh.r, fh.cancel = oldR, oldCancel
+	fh.mu.Unlock()
+
+	assert.Equal(t, 0, n)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errTimeout))
+	assert.True(t, fserrors.IsRetryError(err))
+	assert.Error(t, ctx.Err(), "reader not cancelled")
+}
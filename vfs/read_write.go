@@ -17,15 +17,17 @@ import (
 // transferred to the remote.
 type RWFileHandle struct {
 	// read only variables
-	file  *File
-	d     *Dir
-	flags int            // open flags
-	item  *vfscache.Item // cached file item
+	file    *File
+	d       *Dir
+	flags   int            // open flags
+	item    *vfscache.Item // cached file item
+	limiter *readLimiter   // per handle read bandwidth limiter
 
 	// read write variables protected by mutex
 	mu          sync.Mutex
-	offset      int64 // file pointer offset
-	closed      bool  // set if handle has been closed
+	offset      int64  // file pointer offset
+	placeholder []byte // --vfs-read-error-file contents read instead after reading failed, or nil
+	closed      bool   // set if handle has been closed
 	opened      bool
 	writeCalled bool // if any Write() methods have been called
 }
@@ -53,10 +55,11 @@ func newRWFileHandle(d *Dir, f *File, flags int) (fh *RWFileHandle, err error) {
 	}
 
 	fh = &RWFileHandle{
-		file:  f,
-		d:     d,
-		flags: flags,
-		item:  item,
+		file:    f,
+		d:       d,
+		flags:   flags,
+		item:    item,
+		limiter: d.vfs.newReadLimiter(f.Path()),
 	}
 
 	// truncate immediately if O_TRUNC is set or O_CREATE is set and file doesn't exist
@@ -95,11 +98,17 @@ func (fh *RWFileHandle) openPending() (err error) {
 	}
 	defer log.Trace(fh.logPrefix(), "")("err=%v", &err)
 
+	if !fh.readOnly() {
+		if err = fh.d.materialize(); err != nil {
+			return fmt.Errorf("open RW handle failed: %w", err)
+		}
+	}
+
 	fh.file.muRW.Lock()
 	defer fh.file.muRW.Unlock()
 
 	o := fh.file.getObject()
-	err = fh.item.Open(o)
+	err = fh.item.Open(fh.d.vfs.ctx, o)
 	if err != nil {
 		return fmt.Errorf("open RW handle failed to open cache file: %w", err)
 	}
@@ -256,16 +265,30 @@ func (fh *RWFileHandle) Stat() (os.FileInfo, error) {
 // call with lock held
 func (fh *RWFileHandle) _readAt(b []byte, off int64, release bool) (n int, err error) {
 	defer log.Trace(fh.logPrefix(), "size=%d, off=%d", len(b), off)("n=%d, err=%v", &n, &err)
+	span := fh.file.startSpan("vfs.Read")
+	defer func() {
+		endIOSpan(span, off, len(b), n, err)
+		if n > 0 {
+			fh.file.recordAccess(accessRead, n)
+		}
+		err = fh.d.vfs.mapError(err)
+	}()
 	if fh.closed {
 		return n, ECLOSED
 	}
 	if fh.writeOnly() {
 		return n, EBADF
 	}
+	if fh.placeholder != nil {
+		return readPlaceholder(fh.placeholder, b, off)
+	}
 	if off >= fh._size() {
 		return n, io.EOF
 	}
 	if err = fh.openPending(); err != nil {
+		if fh._usePlaceholder(err) {
+			return readPlaceholder(fh.placeholder, b, off)
+		}
 		return n, err
 	}
 	if release {
@@ -278,22 +301,49 @@ func (fh *RWFileHandle) _readAt(b []byte, off int64, release bool) (n int, err e
 	if release {
 		fh.mu.Lock()
 	}
+	if err != nil && err != io.EOF && fh._usePlaceholder(err) {
+		return readPlaceholder(fh.placeholder, b, off)
+	}
 	return n, err
 }
 
+// _usePlaceholder switches to reading the --vfs-read-error-file
+// contents after reading failed with err. This is only done for read
+// only handles so the placeholder can't be written back.
+//
+// It returns false if err should be returned instead.
+//
+// call with lock held
+func (fh *RWFileHandle) _usePlaceholder(err error) bool {
+	if fh.flags&accessModeMask != os.O_RDONLY || fh.writeCalled {
+		return false
+	}
+	content := fh.file.readErrorContent(err)
+	if content == nil {
+		return false
+	}
+	fh.placeholder = content
+	return true
+}
+
 // ReadAt bytes from the file at off
 func (fh *RWFileHandle) ReadAt(b []byte, off int64) (n int, err error) {
 	fh.mu.Lock()
-	defer fh.mu.Unlock()
-	return fh._readAt(b, off, true)
+	n, err = fh._readAt(b, off, true)
+	fh.mu.Unlock()
+	// Wait for the bandwidth limit with the mutex unlocked
+	fh.limiter.wait(n)
+	return n, err
 }
 
 // Read bytes from the file
 func (fh *RWFileHandle) Read(b []byte) (n int, err error) {
 	fh.mu.Lock()
-	defer fh.mu.Unlock()
 	n, err = fh._readAt(b, fh.offset, false)
 	fh.offset += int64(n)
+	fh.mu.Unlock()
+	// Wait for the bandwidth limit with the mutex unlocked
+	fh.limiter.wait(n)
 	return n, err
 }
 
@@ -328,12 +378,22 @@ func (fh *RWFileHandle) Seek(offset int64, whence int) (ret int64, err error) {
 // call with lock held
 func (fh *RWFileHandle) _writeAt(b []byte, off int64, release bool) (n int, err error) {
 	defer log.Trace(fh.logPrefix(), "size=%d, off=%d", len(b), off)("n=%d, err=%v", &n, &err)
+	span := fh.file.startSpan("vfs.Write")
+	defer func() {
+		endIOSpan(span, off, len(b), n, err)
+		if n > 0 {
+			fh.file.recordAccess(accessWrite, n)
+		}
+	}()
 	if fh.closed {
 		return n, ECLOSED
 	}
 	if fh.readOnly() {
 		return n, EBADF
 	}
+	if fh.d.vfs.writesRejected() {
+		return n, EROFS
+	}
 	if err = fh.openPending(); err != nil {
 		return n, err
 	}
@@ -344,6 +404,14 @@ func (fh *RWFileHandle) _writeAt(b []byte, off int64, release bool) (n int, err
 		fh.offset = size
 		off = fh.offset
 	}
+	if err = fh.d.vfs.checkSequentialWrite(fh.logPrefix(), off, fh._size()); err != nil {
+		return n, err
+	}
+	if fh.d.vfs.quotaEnforced() {
+		if err = fh.d.vfs.checkQuota(max(off+int64(len(b))-fh._size(), 0)); err != nil {
+			return n, err
+		}
+	}
 	fh.writeCalled = true
 	if release {
 		// Do the writing with fh.mu unlocked
@@ -390,9 +458,13 @@ func (fh *RWFileHandle) WriteString(s string) (n int, err error) {
 //
 // Call with mutex held
 func (fh *RWFileHandle) _truncate(size int64) (err error) {
-	if size == fh._size() {
+	oldSize := fh._size()
+	if size == oldSize {
 		return nil
 	}
+	if err = fh.d.vfs.checkQuota(size - oldSize); err != nil {
+		return err
+	}
 	fh.file.setSize(size)
 	return fh.item.Truncate(size)
 }
@@ -404,6 +476,9 @@ func (fh *RWFileHandle) Truncate(size int64) (err error) {
 	if fh.closed {
 		return ECLOSED
 	}
+	if fh.d.vfs.writesRejected() {
+		return EROFS
+	}
 	if err = fh.openPending(); err != nil {
 		return err
 	}
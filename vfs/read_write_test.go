@@ -758,3 +758,24 @@ func TestRWCacheUpdate(t *testing.T) {
 		fstest.AssertTimeEqualWithPrecision(t, filename, modTime, fi.ModTime(), r.Fremote.Precision())
 	}
 }
+
+func TestRWFileHandleSequentialWriteOnly(t *testing.T) {
+	_, vfs, fh := rwHandleCreateFlags(t, true, "file1", os.O_RDWR)
+	vfs.Opt.SequentialWriteOnly = true
+
+	// Writes must carry on from the end of the file
+	_, err := fh.WriteAt([]byte("xx"), 0)
+	assert.Equal(t, ESPIPE, err)
+	_, err = fh.WriteAt([]byte("xx"), 17)
+	assert.Equal(t, ESPIPE, err)
+	n, err := fh.WriteAt([]byte("gh"), 16)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// Truncating the file moves the end
+	require.NoError(t, fh.Truncate(0))
+	n, err = fh.Write([]byte("new"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	require.NoError(t, fh.Close())
+}
@@ -0,0 +1,67 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+)
+
+// readError is the placeholder read from files when reading them
+// fails if --vfs-read-error-file is set
+type readError struct {
+	content []byte
+	include *regexp.Regexp // files the placeholder is used for, or nil for all of them
+}
+
+// newReadError reads the --vfs-read-error-file and parses the
+// --vfs-read-error-include glob returning nil if it isn't set
+func newReadError(file, include string, ignoreCase bool) (*readError, error) {
+	if file == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --vfs-read-error-file: %w", err)
+	}
+	re := &readError{content: content}
+	if include != "" {
+		re.include, err = filter.GlobPathToRegexp(include, ignoreCase)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --vfs-read-error-include %q: %w", include, err)
+		}
+	}
+	return re, nil
+}
+
+// readErrorContent returns the placeholder to read instead of the file
+// after reading it failed with err, or nil if reads should return err.
+//
+// Reads which are cancelled on purpose always return the error.
+func (f *File) readErrorContent(err error) []byte {
+	re := f.VFS().readError
+	if re == nil || errors.Is(err, errDownloadCancelled) || errors.Is(err, ECLOSED) {
+		return nil
+	}
+	if re.include != nil && !re.include.MatchString(f.Path()) {
+		return nil
+	}
+	fs.Errorf(f.Path(), "Reading --vfs-read-error-file instead as read failed: %v", err)
+	return re.content
+}
+
+// readPlaceholder reads the placeholder content at off into p
+func readPlaceholder(content []byte, p []byte, off int64) (n int, err error) {
+	if off >= int64(len(content)) {
+		return 0, io.EOF
+	}
+	n = copy(p, content[off:])
+	if n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,34 @@
+package vfs
+
+import "errors"
+
+// isReadOnly returns whether the VFS is read only, either because
+// --read-only was set or because of vfs/set-read-only
+func (vfs *VFS) isReadOnly() bool {
+	return vfs.Opt.ReadOnly || vfs.readOnly.Load()
+}
+
+// writesRejected returns whether writes to file handles which are
+// already open should fail because the VFS was made read only
+func (vfs *VFS) writesRejected() bool {
+	return vfs.readOnly.Load() && vfs.rejectOpen.Load()
+}
+
+// setReadOnly makes the VFS read only or read write
+//
+// If rejectOpen is set then writes to file handles which are already
+// open fail while the VFS is read only, otherwise they are allowed to
+// complete.
+func (vfs *VFS) setReadOnly(readOnly, rejectOpen bool) error {
+	if !readOnly && vfs.Opt.ReadOnly {
+		return errors.New("can't make the VFS read write as it was started with --read-only")
+	}
+	vfs.rejectOpen.Store(rejectOpen)
+	vfs.readOnly.Store(readOnly)
+	return nil
+}
+
+// readOnly returns whether the directory can't be changed
+func (d *Dir) readOnly() bool {
+	return d.vfs.isReadOnly() || d.versions != versionsNone || d.recent
+}
@@ -0,0 +1,225 @@
+package vfs
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// recentDirName is the name of the pseudo directory in the root
+// showing the most recently modified files if --vfs-recent-files is
+// set
+const recentDirName = ".recent"
+
+// recentSeparator replaces "/" in the paths of the files to make the
+// names of the entries in the .recent directory
+const recentSeparator = "／" // FULLWIDTH SOLIDUS as used by the encoder
+
+// recentEntry is a file in the recent files index
+type recentEntry struct {
+	modTime time.Time
+	o       fs.Object // nil if only known from a change notification
+}
+
+// recentIndex keeps the most recently modified files the VFS has seen
+// in directory listings, change notifications and writes.
+//
+// The methods may be called on a nil *recentIndex which does nothing.
+type recentIndex struct {
+	mu      sync.Mutex
+	size    int                    // number of files to show
+	files   map[string]recentEntry // files by path
+	changed bool                   // set if files has changed since the .recent directory was listed
+}
+
+// newRecentIndex makes a recentIndex showing size files
+func newRecentIndex(size int) *recentIndex {
+	return &recentIndex{
+		size:  size,
+		files: make(map[string]recentEntry),
+	}
+}
+
+// _set records the file at remote
+//
+// call with the lock held
+func (r *recentIndex) _set(remote string, entry recentEntry) {
+	r.files[remote] = entry
+	r.changed = true
+	// Trim back to size entries once it has grown to double that
+	if len(r.files) >= 2*r.size {
+		for _, remote := range r._sorted()[r.size:] {
+			delete(r.files, remote)
+		}
+	}
+}
+
+// add records the object o
+func (r *recentIndex) add(o fs.Object) {
+	if r == nil || o == nil {
+		return
+	}
+	modTime := o.ModTime(context.TODO())
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, found := r.files[o.Remote()]; found && old.o == o {
+		return
+	}
+	r._set(o.Remote(), recentEntry{modTime: modTime, o: o})
+}
+
+// touch records that the file at remote has just been changed
+func (r *recentIndex) touch(remote string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r._set(remote, recentEntry{modTime: time.Now()})
+}
+
+// remove forgets the file at remote
+func (r *recentIndex) remove(remote string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, found := r.files[remote]; found {
+		delete(r.files, remote)
+		r.changed = true
+	}
+}
+
+// syncDir updates the files in dir from a fresh listing of it
+func (r *recentIndex) syncDir(dir string, entries fs.DirEntries) {
+	if r == nil {
+		return
+	}
+	listed := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			listed[o.Remote()] = struct{}{}
+			r.add(o)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for remote, entry := range r.files {
+		if _, found := listed[remote]; !found && entry.o != nil && path.Dir("/"+remote) == path.Clean("/"+dir) {
+			delete(r.files, remote)
+			r.changed = true
+		}
+	}
+}
+
+// _sorted returns the paths of the files newest first
+//
+// call with the lock held
+func (r *recentIndex) _sorted() []string {
+	remotes := make([]string, 0, len(r.files))
+	for remote := range r.files {
+		remotes = append(remotes, remote)
+	}
+	slices.SortFunc(remotes, func(a, b string) int {
+		if c := r.files[b].modTime.Compare(r.files[a].modTime); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	return remotes
+}
+
+// list returns the newest files, newest first
+func (r *recentIndex) list() (remotes []string, entries []recentEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remotes = r._sorted()
+	if len(remotes) > r.size {
+		remotes = remotes[:r.size]
+	}
+	for _, remote := range remotes {
+		entries = append(entries, r.files[remote])
+	}
+	return remotes, entries
+}
+
+// takeChanged returns whether the files have changed since it was
+// last called
+func (r *recentIndex) takeChanged() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := r.changed
+	r.changed = false
+	return changed
+}
+
+// recentName returns the name of the entry in the .recent directory
+// for the file at remote
+func recentName(remote string) string {
+	return strings.ReplaceAll(remote, "/", recentSeparator)
+}
+
+// recentObject is a file shown in the .recent directory which reads
+// the file at its real path
+type recentObject struct {
+	fs.Object
+}
+
+// Remote returns the path of the entry in the .recent directory
+func (o recentObject) Remote() string {
+	return path.Join(recentDirName, recentName(o.Object.Remote()))
+}
+
+// recentDir returns the .recent pseudo directory for d or nil if it
+// doesn't have one.
+func (d *Dir) recentDir() *Dir {
+	if d.vfs.recent == nil || d.parent != nil {
+		return nil
+	}
+	modTime := d.ModTime()
+	d.mu.Lock()
+	if d.recentNode == nil {
+		fsDir := fs.NewDir(recentDirName, modTime)
+		d.recentNode = newDir(d.vfs, d.f, d, fsDir)
+		d.recentNode.recent = true
+	}
+	recentDir := d.recentNode
+	d.mu.Unlock()
+	// Re-read the directory if the recent files have changed
+	if d.vfs.recent.takeChanged() {
+		recentDir.mu.Lock()
+		recentDir.read = time.Time{}
+		recentDir.mu.Unlock()
+	}
+	return recentDir
+}
+
+// list the entries for the .recent pseudo directory
+//
+// These are the most recently modified files, named by their path
+// with "/" replaced by recentSeparator.
+//
+// call with the lock held
+func (d *Dir) _listRecent(ctx context.Context) (entries fs.DirEntries, err error) {
+	remotes, recentEntries := d.vfs.recent.list()
+	for i, remote := range remotes {
+		o := recentEntries[i].o
+		if o == nil {
+			o, err = d.f.NewObject(ctx, remote)
+			if err != nil {
+				fs.Debugf(remote, "vfs: not showing in %s: %v", recentDirName, err)
+				d.vfs.recent.remove(remote)
+				continue
+			}
+			d.vfs.recent.add(o)
+		}
+		entries = append(entries, recentObject{Object: o})
+	}
+	return entries, nil
+}
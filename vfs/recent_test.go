@@ -0,0 +1,76 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVFSRecentFiles(t *testing.T) {
+	ctx := context.Background()
+	opt := vfscommon.Opt
+	opt.RecentFiles = 2
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	r.WriteObject(ctx, "old.txt", "old", t1)
+	r.WriteObject(ctx, "dir/new.txt", "new", t3)
+	r.WriteObject(ctx, "dir/middle.txt", "middle", t2)
+
+	recentNames := func() (names []string) {
+		entries, err := vfs.ReadDir(recentDirName)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	// Only files in directories which have been listed are shown
+	assert.Equal(t, []string{"old.txt"}, recentNames())
+	_, err := vfs.ReadDir("dir")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"dir／new.txt", "dir／middle.txt"}, recentNames())
+
+	// The entries read the real files and can't be written
+	contents, err := vfs.ReadFile(recentDirName + "/dir／new.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(contents))
+	err = vfs.WriteFile(recentDirName+"/dir／new.txt", []byte("potato"), 0666)
+	assert.ErrorIs(t, err, EROFS)
+	err = vfs.WriteFile(recentDirName+"/potato.txt", []byte("potato"), 0666)
+	assert.ErrorIs(t, err, EROFS)
+
+	// Files written through the VFS are added
+	require.NoError(t, vfs.WriteFile("written.txt", []byte("written"), 0666))
+	assert.Contains(t, recentNames(), "written.txt")
+
+	// Files changed on the remote are added when notified
+	r.WriteObject(ctx, "other/notified.txt", "notified", t1)
+	vfs.changeNotify("other/notified.txt", fs.EntryObject)
+	assert.Contains(t, recentNames(), "other／notified.txt")
+
+	// Removed files are taken out
+	require.NoError(t, vfs.Remove("written.txt"))
+	assert.NotContains(t, recentNames(), "written.txt")
+
+	// Notifications of files which don't exist are ignored
+	vfs.changeNotify("missing.txt", fs.EntryObject)
+	assert.NotContains(t, recentNames(), "missing.txt")
+
+	// Doesn't stop everything being removed
+	root, err := vfs.Root()
+	require.NoError(t, err)
+	require.NoError(t, root.RemoveAll())
+	r.CheckRemoteItems(t)
+}
+
+func TestVFSRecentFilesOff(t *testing.T) {
+	_, vfs := newTestVFS(t)
+	_, err := vfs.Stat(recentDirName)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,144 @@
+package vfs
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/fs/rc"
+)
+
+// seqWait is a read or write waiting for the reads or writes before
+// it with --vfs-read-wait or --vfs-write-wait
+type seqWait struct {
+	off   int64     // offset of the read or write
+	size  int       // size of the read or write
+	start time.Time // when it started waiting
+}
+
+// seqState records the reads or writes of an open file handle which
+// are waiting to be in sequence, for vfs/write-state. It has its own
+// lock so it can be read while the handle is busy.
+//
+// The methods may be called on a nil *seqState which does nothing.
+type seqState struct {
+	id       int64         // read only: id of the handle
+	kind     string        // read only: "read" or "write"
+	file     *File         // read only: file the handle is open on
+	position *atomic.Int64 // read only: offset the handle expects the next read or write at
+
+	mu       sync.Mutex    // protects the following
+	waiting  []*seqWait    // reads or writes waiting
+	waits    int           // number of reads or writes which have waited
+	timeouts int           // number of waits which timed out
+	waited   time.Duration // total time the finished waits took
+}
+
+// addSeqState records a handle of kind open on file expecting the next
+// read or write at position until removeSeqState is called
+func (vfs *VFS) addSeqState(kind string, file *File, position *atomic.Int64) *seqState {
+	vfs.seqMu.Lock()
+	defer vfs.seqMu.Unlock()
+	vfs.seqID++
+	s := &seqState{
+		id:       vfs.seqID,
+		kind:     kind,
+		file:     file,
+		position: position,
+	}
+	if vfs.seqStates == nil {
+		vfs.seqStates = make(map[int64]*seqState)
+	}
+	vfs.seqStates[s.id] = s
+	return s
+}
+
+// removeSeqState forgets the handle recorded with addSeqState
+func (vfs *VFS) removeSeqState(s *seqState) {
+	if s == nil {
+		return
+	}
+	vfs.seqMu.Lock()
+	defer vfs.seqMu.Unlock()
+	delete(vfs.seqStates, s.id)
+}
+
+// listSeqStates returns the handles recorded in id order
+func (vfs *VFS) listSeqStates() []*seqState {
+	vfs.seqMu.Lock()
+	states := make([]*seqState, 0, len(vfs.seqStates))
+	for _, s := range vfs.seqStates {
+		states = append(states, s)
+	}
+	vfs.seqMu.Unlock()
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].id < states[j].id
+	})
+	return states
+}
+
+// startWait records that the read or write of size at off has started
+// waiting
+func (s *seqState) startWait(off int64, size int) *seqWait {
+	if s == nil {
+		return nil
+	}
+	w := &seqWait{off: off, size: size, start: time.Now()}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waiting = append(s.waiting, w)
+	return w
+}
+
+// endWait records that w has finished waiting, timing out if
+// timedOut is set
+func (s *seqState) endWait(w *seqWait, timedOut bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, waiting := range s.waiting {
+		if waiting == w {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			break
+		}
+	}
+	s.waits++
+	if timedOut {
+		s.timeouts++
+	}
+	s.waited += time.Since(w.start)
+}
+
+// rcParams returns the state of the handle for vfs/write-state
+func (s *seqState) rcParams() rc.Params {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	waited := s.waited
+	waiting := make([]rc.Params, 0, len(s.waiting))
+	for _, w := range s.waiting {
+		age := now.Sub(w.start)
+		waited += age
+		waiting = append(waiting, rc.Params{
+			"offset": w.off,
+			"size":   w.size,
+			"waited": age.Seconds(),
+		})
+	}
+	sort.Slice(waiting, func(i, j int) bool {
+		return waiting[i]["offset"].(int64) < waiting[j]["offset"].(int64)
+	})
+	return rc.Params{
+		"id":       s.id,
+		"path":     s.file.Path(),
+		"type":     s.kind,
+		"offset":   s.position.Load(),
+		"waiting":  waiting,
+		"waits":    s.waits,
+		"timeouts": s.timeouts,
+		"waited":   waited.Seconds(),
+	}
+}
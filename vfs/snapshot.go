@@ -0,0 +1,16 @@
+package vfs
+
+import (
+	"context"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// openSnapshotFs opens a copy of f which shows each object as it was
+// at time t.
+//
+// This only works for backends with a "version_at" option.
+func openSnapshotFs(ctx context.Context, f fs.Fs, t fs.Time) (fs.Fs, error) {
+	return reopenWithOption(ctx, f, "version_at", time.Time(t).UTC().Format(time.RFC3339Nano), "snapshots")
+}
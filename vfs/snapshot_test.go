@@ -0,0 +1,32 @@
+package vfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVFSSnapshotTime(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.SnapshotTime = fs.Time(time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC))
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	file1 := r.WriteObject(context.Background(), "file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+
+	_, err := openSnapshotFs(context.Background(), r.Fremote, opt.SnapshotTime)
+	assert.ErrorContains(t, err, "doesn't support snapshots")
+
+	// The VFS is read only and as the local backend doesn't support
+	// snapshots it doesn't show any files
+	assert.True(t, vfs.Opt.ReadOnly)
+	assert.ErrorContains(t, vfs.snapshotErr, "can't use --vfs-snapshot-time")
+	_, err = vfs.ReadDir("")
+	assert.ErrorIs(t, err, vfs.snapshotErr)
+	_, err = vfs.Stat("file1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package vfs
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// stableInodeMask keeps the stable inode numbers below the top three
+// bits which are used to make the inode numbers of the metadata,
+// marker and cache status files from the inode numbers of real nodes
+const stableInodeMask = 1<<61 - 1
+
+// stableInodes gives out inode numbers made from the paths of the
+// nodes if --vfs-stable-inodes is set, so the same path gets the same
+// inode number each time the VFS is started.
+//
+// Inode numbers which collide are given a different number by hashing
+// the path again, so each path given out keeps its number until its
+// node is forgotten by the directory cache.
+//
+// The methods may be called on a nil *stableInodes which gives out
+// inode numbers from a counter instead.
+type stableInodes struct {
+	mu    sync.Mutex
+	paths map[uint64]string // path each inode number was given out for
+}
+
+// newStableInodes makes a new stableInodes
+func newStableInodes() *stableInodes {
+	return &stableInodes{
+		paths: make(map[uint64]string),
+	}
+}
+
+// hashInode returns the inode number for remote for the try'th time
+func hashInode(remote string, try int) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(remote))
+	if try > 0 {
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(strconv.Itoa(try)))
+	}
+	inode := h.Sum64() & stableInodeMask
+	if inode == 0 {
+		inode = 1
+	}
+	return inode
+}
+
+// get returns the inode number for the node at remote
+func (s *stableInodes) get(remote string) uint64 {
+	if s == nil {
+		return newInode()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for try := 0; ; try++ {
+		inode := hashInode(remote, try)
+		existing, found := s.paths[inode]
+		if !found {
+			s.paths[inode] = remote
+			return inode
+		}
+		if existing == remote {
+			return inode
+		}
+		fs.Debugf(remote, "vfs: inode number %d already used for %q - trying again", inode, existing)
+	}
+}
+
+// rename records that the node with inode is now at remote so a new
+// node at its old path gets a different inode number
+func (s *stableInodes) rename(inode uint64, remote string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.paths[inode]; found {
+		s.paths[inode] = remote
+	}
+}
+
+// forget records that the node with inode at remote has been dropped
+// from the directory cache so its inode number is no longer in use.
+//
+// Nothing is forgotten if the node has been renamed from remote as it
+// is still in use at its new path.
+func (s *stableInodes) forget(inode uint64, remote string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paths[inode] == remote {
+		delete(s.paths, inode)
+	}
+}
@@ -0,0 +1,115 @@
+package vfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStableInodesCollision(t *testing.T) {
+	s := newStableInodes()
+
+	// Pretend "b" was given the number "a" hashes to
+	s.paths[hashInode("a", 0)] = "b"
+
+	inode := s.get("a")
+	assert.Equal(t, hashInode("a", 1), inode)
+	assert.Equal(t, inode, s.get("a"))
+
+	// Renamed nodes keep their number and free up the old path
+	s.rename(inode, "c")
+	assert.NotEqual(t, inode, s.get("a"))
+	assert.Less(t, inode, uint64(stableInodeMask+1))
+
+	// Forgetting a renamed node's old path keeps its number
+	s.forget(inode, "a")
+	assert.Equal(t, "c", s.paths[inode])
+	s.forget(inode, "c")
+	_, found := s.paths[inode]
+	assert.False(t, found)
+
+	// nil gives out numbers from the counter
+	var nilInodes *stableInodes
+	assert.NotEqual(t, nilInodes.get("a"), nilInodes.get("a"))
+}
+
+func TestVFSStableInodes(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	r.WriteObject(ctx, "dir/file.txt", "hello", t1)
+	r.WriteObject(ctx, "other.txt", "other", t1)
+
+	var vfs *VFS
+	// reopen starts a new VFS with stable inodes set to stable
+	reopen := func(stable bool) {
+		if vfs != nil {
+			cleanupVFS(t, vfs)
+		}
+		opt := vfscommon.Opt
+		opt.StableInodes = stable
+		vfs = New(r.Fremote, &opt)
+	}
+	defer func() {
+		cleanupVFS(t, vfs)
+	}()
+	inodes := func() (inodes []uint64) {
+		for _, name := range []string{"", "dir", "dir/file.txt", "other.txt"} {
+			node, err := vfs.Stat(name)
+			require.NoError(t, err)
+			inodes = append(inodes, node.Inode())
+		}
+		return inodes
+	}
+
+	reopen(true)
+	first := inodes()
+	reopen(true)
+	assert.Equal(t, first, inodes())
+	reopen(false)
+	assert.NotEqual(t, first, inodes())
+
+	// A new file at the path of a renamed one gets a different number
+	reopen(true)
+	require.NoError(t, vfs.Rename("other.txt", "renamed.txt"))
+	require.NoError(t, vfs.WriteFile("other.txt", []byte("new"), 0666))
+	renamed, err := vfs.Stat("renamed.txt")
+	require.NoError(t, err)
+	created, err := vfs.Stat("other.txt")
+	require.NoError(t, err)
+	assert.Equal(t, first[3], renamed.Inode())
+	assert.NotEqual(t, renamed.Inode(), created.Inode())
+}
+
+func TestVFSStableInodesForget(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	r.WriteObject(ctx, "dir/file.txt", "hello", t1)
+	r.WriteObject(ctx, "other.txt", "other", t1)
+	opt := vfscommon.Opt
+	opt.StableInodes = true
+	vfs := New(r.Fremote, &opt)
+	defer cleanupVFS(t, vfs)
+
+	count := func() int {
+		vfs.inodes.mu.Lock()
+		defer vfs.inodes.mu.Unlock()
+		return len(vfs.inodes.paths)
+	}
+	_, err := vfs.Stat("dir/file.txt")
+	require.NoError(t, err)
+	_, err = vfs.Stat("other.txt")
+	require.NoError(t, err)
+	assert.Equal(t, 4, count())
+
+	// Removed files are forgotten
+	require.NoError(t, vfs.Remove("other.txt"))
+	assert.Equal(t, 3, count())
+
+	// So are the nodes dropped from the directory cache
+	vfs.FlushDirCache()
+	assert.Equal(t, 1, count())
+}
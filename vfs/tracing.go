@@ -0,0 +1,42 @@
+package vfs
+
+import (
+	"context"
+	"io"
+
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a span called name for an operation on f if
+// --vfs-tracing is set
+//
+// This does nothing and returns a span which does nothing otherwise.
+func (f *File) startSpan(name string) trace.Span {
+	vfs := f.VFS()
+	if !vfs.Opt.Tracing {
+		_, span := vfscommon.StartSpan(context.TODO(), nil, name, "")
+		return span
+	}
+	_, span := vfscommon.StartSpan(context.TODO(), &vfs.Opt, name, f.Path())
+	return span
+}
+
+// endIOSpan ends span for a read or write of size bytes at off which
+// transferred n bytes and returned err
+//
+// Reaching the end of the file isn't recorded as an error.
+func endIOSpan(span trace.Span, off int64, size int, n int, err error) {
+	if err == io.EOF {
+		err = nil
+	}
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.Int64("vfs.offset", off),
+			attribute.Int("vfs.size", size),
+			attribute.Int("vfs.bytes", n),
+		)
+	}
+	vfscommon.EndSpan(span, err)
+}
@@ -0,0 +1,75 @@
+package vfs
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// spanRecorder is a tracer provider which records the names of the
+// spans started
+type spanRecorder struct {
+	noop.TracerProvider
+	mu    sync.Mutex
+	names []string
+}
+
+func (p *spanRecorder) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return spanRecorderTracer{p: p}
+}
+
+func (p *spanRecorder) spans() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.names)
+}
+
+type spanRecorderTracer struct {
+	noop.Tracer
+	p *spanRecorder
+}
+
+func (t spanRecorderTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.p.mu.Lock()
+	t.p.names = append(t.p.names, name)
+	t.p.mu.Unlock()
+	return t.Tracer.Start(ctx, name, opts...)
+}
+
+func TestVFSTracing(t *testing.T) {
+	recorder := &spanRecorder{}
+	otel.SetTracerProvider(recorder)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	for _, tracing := range []bool{false, true} {
+		opt := vfscommon.Opt
+		opt.CacheMode = vfscommon.CacheModeFull
+		opt.Tracing = tracing
+		opt.WriteBack = fs.Duration(10 * time.Millisecond)
+		_, vfs := newTestVFSOpt(t, &opt)
+
+		require.NoError(t, vfs.WriteFile("file.txt", []byte("hello"), 0666))
+		vfs.WaitForWriters(waitForWritersDelay)
+		_, err := vfs.ReadFile("file.txt")
+		require.NoError(t, err)
+
+		spans := recorder.spans()
+		if !tracing {
+			assert.Empty(t, spans)
+			continue
+		}
+		for _, name := range []string{"vfs.Open", "vfs.Write", "vfs.Read", "vfs.cache.Read", "vfs.cache.Upload"} {
+			assert.Contains(t, spans, name)
+		}
+	}
+}
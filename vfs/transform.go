@@ -0,0 +1,38 @@
+package vfs
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+// transformingFs returns the first of f and the backends it wraps
+// which transforms the data it reads, such as crypt or compress, or
+// nil if there isn't one. Data read ahead through these costs CPU as
+// well as bandwidth even if it is never used.
+func transformingFs(f fs.Fs) fs.Fs {
+	for f != nil {
+		if f.Features().TransformsData {
+			return f
+		}
+		unWrap := f.Features().UnWrap
+		if unWrap == nil {
+			break
+		}
+		f = unWrap()
+	}
+	return nil
+}
+
+// limitTransformReadAhead reduces --vfs-read-ahead to
+// --vfs-read-ahead-transform if f transforms the data it reads
+func (vfs *VFS) limitTransformReadAhead(f fs.Fs) {
+	limit := vfs.Opt.ReadAheadTransform
+	if limit < 0 || vfs.Opt.ReadAhead <= limit {
+		return
+	}
+	transformer := transformingFs(f)
+	if transformer == nil {
+		return
+	}
+	fs.Logf(f, "Reducing --vfs-read-ahead from %v to %v as %v transforms the data - set --vfs-read-ahead-transform off to stop this", vfs.Opt.ReadAhead, limit, transformer)
+	vfs.Opt.ReadAhead = limit
+}
@@ -0,0 +1,41 @@
+package vfs
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/crypt" // import the crypt backend
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVFSLimitTransformReadAhead(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	fcrypt, err := fs.NewFs(ctx, ":crypt,remote='"+fs.ConfigStringFull(r.Fremote)+"',password='"+obscure.MustObscure("potato")+"':")
+	require.NoError(t, err)
+
+	assert.Nil(t, transformingFs(r.Fremote))
+	assert.Equal(t, fcrypt, transformingFs(fcrypt))
+	assert.Equal(t, fs.SizeSuffix(-1), vfscommon.Opt.ReadAheadTransform)
+
+	// readAhead returns the read ahead a VFS on f uses
+	readAhead := func(f fs.Fs, transform fs.SizeSuffix) fs.SizeSuffix {
+		opt := vfscommon.Opt
+		opt.ReadAhead = 64 * fs.Mebi
+		opt.ReadAheadTransform = transform
+		vfs := New(f, &opt)
+		defer cleanupVFS(t, vfs)
+		return vfs.Opt.ReadAhead
+	}
+
+	assert.Equal(t, fs.SizeSuffix(0), readAhead(fcrypt, 0))
+	assert.Equal(t, fs.Mebi, readAhead(fcrypt, fs.Mebi))
+	assert.Equal(t, 64*fs.Mebi, readAhead(fcrypt, 128*fs.Mebi))
+	assert.Equal(t, 64*fs.Mebi, readAhead(fcrypt, -1))
+	assert.Equal(t, 64*fs.Mebi, readAhead(r.Fremote, 0))
+}
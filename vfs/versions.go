@@ -0,0 +1,129 @@
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/lib/version"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// versionsDirName is the name of the pseudo directory showing the old
+// versions of the files in each directory if --vfs-expose-versions is set
+const versionsDirName = ".versions"
+
+// versionsKind describes whether a Dir is one of the pseudo
+// directories used to show the old versions of files
+type versionsKind byte
+
+const (
+	versionsNone versionsKind = iota // a normal directory
+	versionsRoot                     // .versions - has a directory for each file with old versions
+	versionsFile                     // .versions/<name> - has the old versions of <name>
+)
+
+// openVersionsFs opens a copy of f which lists the old versions of
+// each object as well as the current one.
+//
+// This only works for backends with a "versions" option.
+func openVersionsFs(ctx context.Context, f fs.Fs) (fs.Fs, error) {
+	return reopenWithOption(ctx, f, "versions", "true", "versions")
+}
+
+// reopenWithOption opens a copy of f with the backend option name set
+// to value.
+//
+// It returns an error mentioning feature if the backend doesn't have
+// the option.
+func reopenWithOption(ctx context.Context, f fs.Fs, name, value, feature string) (fs.Fs, error) {
+	remote := fs.ConfigStringFull(f)
+	fsInfo, _, _, _, err := fs.ParseRemote(remote)
+	if err != nil {
+		return nil, err
+	}
+	supported := false
+	for _, opt := range fsInfo.Options {
+		if opt.Name == name {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, fmt.Errorf("%q backend doesn't support %s", fsInfo.Name, feature)
+	}
+	parsed, err := fspath.Parse(remote)
+	if err != nil {
+		return nil, err
+	}
+	value = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	remote = strings.TrimSuffix(parsed.ConfigString, ":") + "," + name + "=" + value + ":" + parsed.Path
+	return cache.Get(ctx, remote)
+}
+
+// versionsDir returns the .versions pseudo directory for d or nil if
+// it doesn't have one.
+func (d *Dir) versionsDir() *Dir {
+	if d.vfs.versions == nil || d.versions != versionsNone || d.recent {
+		return nil
+	}
+	modTime := d.ModTime()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.versionsNode == nil {
+		fsDir := fs.NewDir(path.Join(d.path, versionsDirName), modTime)
+		d.versionsNode = newDir(d.vfs, d.vfs.versions, d, fsDir)
+		d.versionsNode.versions = versionsRoot
+	}
+	return d.versionsNode
+}
+
+// list the entries for a .versions pseudo directory
+//
+// For .versions this is a directory for each file with old versions
+// and for .versions/<name> it is the old versions of <name>. These
+// are named as the backend names them, with the version time added
+// before the extension.
+//
+// call with the lock held
+func (d *Dir) _listVersions(ctx context.Context) (entries fs.DirEntries, err error) {
+	dirPath, leaf := vfscommon.FindParent(d.path), ""
+	if d.versions == versionsFile {
+		dirPath, leaf = vfscommon.FindParent(dirPath), path.Base(d.path)
+	}
+	all, err := list.DirSorted(ctx, d.f, false, dirPath)
+	if err == fs.ErrorDirNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	for _, entry := range all {
+		o, ok := entry.(fs.Object)
+		if !ok {
+			continue
+		}
+		versionLeaf := path.Base(o.Remote())
+		if !version.Match(versionLeaf) {
+			continue
+		}
+		_, base := version.Remove(versionLeaf)
+		switch d.versions {
+		case versionsRoot:
+			if _, found := seen[base]; !found {
+				seen[base] = struct{}{}
+				entries = append(entries, fs.NewDir(path.Join(d.path, base), o.ModTime(ctx)))
+			}
+		case versionsFile:
+			if base == leaf {
+				entries = append(entries, o)
+			}
+		}
+	}
+	return entries, nil
+}
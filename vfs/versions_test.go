@@ -0,0 +1,77 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenVersionsFs(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.ExposeVersions = true
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	_, err := openVersionsFs(context.Background(), r.Flocal)
+	assert.ErrorContains(t, err, "doesn't support versions")
+
+	// The local backend doesn't support versions so there is no
+	// .versions directory
+	assert.Nil(t, vfs.versions)
+	_, err = vfs.Stat(versionsDirName)
+	assert.Equal(t, ENOENT, err)
+}
+
+func TestDirVersions(t *testing.T) {
+	r, vfs := newTestVFS(t)
+
+	file := r.WriteObject(context.Background(), "dir/file.txt", "current", t3)
+	other := r.WriteObject(context.Background(), "dir/other.txt", "other", t3)
+	r.CheckRemoteItems(t, file, other)
+
+	// Use r.Flocal to hold the old versions
+	r.WriteFile("dir/file.txt", "current", t3)
+	r.WriteFile("dir/file-v2001-02-03-040506-123.txt", "old", t1)
+	r.WriteFile("dir/file-v2002-02-03-040506-123.txt", "older!", t2)
+	r.WriteFile("dir/other.txt", "other", t3)
+	vfs.versions = r.Flocal
+
+	node, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	dir := node.(*Dir)
+	checkListing(t, dir, []string{".versions,0,true", "file.txt,7,false", "other.txt,5,false"})
+
+	node, err = vfs.Stat("dir/.versions")
+	require.NoError(t, err)
+	versions := node.(*Dir)
+	checkListing(t, versions, []string{"file.txt,0,true"})
+
+	node, err = vfs.Stat("dir/.versions/file.txt")
+	require.NoError(t, err)
+	fileVersions := node.(*Dir)
+	checkListing(t, fileVersions, []string{"file-v2001-02-03-040506-123.txt,3,false", "file-v2002-02-03-040506-123.txt,6,false"})
+
+	// Old versions can be read
+	contents, err := vfs.ReadFile("dir/.versions/file.txt/file-v2001-02-03-040506-123.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(contents))
+
+	// But nothing can be changed
+	_, err = vfs.OpenFile("dir/.versions/file.txt/file-v2001-02-03-040506-123.txt", os.O_WRONLY, 0777)
+	assert.Equal(t, EROFS, err)
+	_, err = vfs.OpenFile("dir/.versions/file.txt/new.txt", os.O_WRONLY|os.O_CREATE, 0777)
+	assert.Equal(t, EROFS, err)
+	assert.Equal(t, EROFS, vfs.Remove("dir/.versions/file.txt/file-v2001-02-03-040506-123.txt"))
+	assert.Equal(t, EROFS, vfs.Mkdir("dir/.versions/potato", 0777))
+	assert.Equal(t, EROFS, vfs.Rename("dir/other.txt", "dir/.versions/other.txt"))
+	assert.Equal(t, EROFS, vfs.Rename("dir/.versions", "dir/versions"))
+	assert.Equal(t, EROFS, versions.RemoveAll())
+	checkListing(t, fileVersions, []string{"file-v2001-02-03-040506-123.txt,3,false", "file-v2002-02-03-040506-123.txt,6,false"})
+
+	// The pseudo directory doesn't stop the directory being removed
+	require.NoError(t, dir.RemoveAll())
+	r.CheckRemoteItems(t)
+}
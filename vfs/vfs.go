@@ -27,6 +27,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -41,8 +42,10 @@ import (
 	"github.com/rclone/rclone/fs/log"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fs/walk"
+	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/vfs/vfscache"
 	"github.com/rclone/rclone/vfs/vfscommon"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 //go:embed vfs.md
@@ -175,16 +178,51 @@ var (
 
 // VFS represents the top level filing system
 type VFS struct {
-	f           fs.Fs
-	root        *Dir
-	Opt         vfscommon.Options
-	cache       *vfscache.Cache
-	cancelCache context.CancelFunc
-	usageMu     sync.Mutex
-	usageTime   time.Time
-	usage       *fs.Usage
-	pollChan    chan time.Duration
-	inUse       atomic.Int32 // count of number of opens
+	f             fs.Fs
+	root          *Dir
+	Opt           vfscommon.Options
+	ctx           context.Context // cancelled when the VFS is shut down
+	cancel        context.CancelFunc
+	cache         *vfscache.Cache
+	cancelCache   context.CancelFunc
+	fallback      fs.Fs          // fs to read from if reading from f fails, or nil
+	readError     *readError     // placeholder to read if reading fails, or nil
+	errorMap      []errorMapping // error numbers to return for backend errors from --vfs-error-map
+	readBw        readBwLimits   // per handle read bandwidth limits
+	versions      fs.Fs          // fs listing old versions if --vfs-expose-versions is set, or nil
+	snapshotErr   error          // set if --vfs-snapshot-time is set but can't be used
+	writeThrough  *regexp.Regexp // files matching this are written straight to the remote, or nil
+	readOnly      atomic.Bool    // set if made read only with vfs/set-read-only
+	rejectOpen    atomic.Bool    // set if writes to handles already open should fail while read only
+	usageMu       sync.Mutex
+	usageTime     time.Time
+	usage         *fs.Usage
+	quotaGrown    int64 // bytes written since usage was read if enforcing --vfs-disk-space-total-size or --vfs-backend-min-free-space
+	dirSizesMu    sync.Mutex
+	dirSizes      map[string]int64 // total size of the objects under each directory
+	dirSizesAt    time.Time        // when dirSizes was last updated
+	dirSizesRun   bool             // set if dirSizes is being updated
+	prefetcher    *prefetcher      // downloads files into the cache for --vfs-prefetch-on-list
+	downloadsMu   sync.Mutex
+	downloads     map[int64]*download // files being read straight from the remote
+	downloadID    int64               // id of the last download added
+	seqMu         sync.Mutex
+	seqStates     map[int64]*seqState  // open file handles for vfs/write-state by id
+	seqID         int64                // id of the last handle added to seqStates
+	accessHistory *accessHistory       // recent file accesses if --vfs-access-history is set, or nil
+	recent        *recentIndex         // most recently modified files if --vfs-recent-files is set, or nil
+	modTimes      *modTimeStore        // modification times the backend can't set if --vfs-modtime-store is set, or nil
+	deletes       *deleteBatcher       // deletes waiting to be done together if --vfs-delete-batch-window is set, or nil
+	inodes        *stableInodes        // inode numbers made from the paths if --vfs-stable-inodes is set, or nil
+	dirIndex      *dirIndex            // directory listings kept on disk if --vfs-persist-dir-cache is set, or nil
+	invalidNames  encoder.MultiEncoder // characters the backend can't store for --vfs-invalid-names
+	pollChan      chan time.Duration
+	pollMu        sync.Mutex       // held while changing the poll interval
+	pollPaused    bool             // set if polling has been paused with vfs/poll-pause
+	debouncer     *notifyDebouncer // coalesces change notifications if --vfs-notify-debounce is set, or nil
+	dirLRU        *dirLRU          // forgets the least recently used listings if --vfs-dir-cache-max-entries is set, or nil
+	lastNotify    atomic.Int64     // time of last change notification in unix nanoseconds or 0
+	inUse         atomic.Int32     // count of number of opens
 }
 
 // Keep track of active VFS keyed on fs.ConfigString(f)
@@ -212,6 +250,91 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 	// Fill out anything else
 	vfs.Opt.Init()
 
+	// Keep all changes in the cache if required
+	if vfs.Opt.NoWriteBack && !vfs.Opt.ReadOnly {
+		if vfs.Opt.CacheMode < vfscommon.CacheModeWrites {
+			fs.Logf(f, "--vfs-no-write-back needs --vfs-cache-mode writes or full so using writes")
+			vfs.Opt.CacheMode = vfscommon.CacheModeWrites
+		}
+		if vfs.Opt.WriteThrough != "" {
+			fs.Logf(f, "Ignoring --vfs-write-through as --vfs-no-write-back is set")
+			vfs.Opt.WriteThrough = ""
+		}
+		fs.Logf(f, "--vfs-no-write-back is set so changes to files will be lost when rclone exits")
+	}
+
+	// Find the characters the backend can't store if required
+	vfs.invalidNames = invalidNamesEncoding(f, &vfs.Opt)
+
+	// Read less ahead from remotes which transform the data if required
+	vfs.limitTransformReadAhead(f)
+
+	// Show the remote as it was at the snapshot time if required
+	if vfs.Opt.SnapshotTime.IsSet() {
+		vfs.Opt.ReadOnly = true
+		snapshot, err := openSnapshotFs(context.TODO(), f, vfs.Opt.SnapshotTime)
+		if err != nil {
+			vfs.snapshotErr = fmt.Errorf("can't use --vfs-snapshot-time: %w", err)
+			fs.Errorf(f, "Not showing any files: %v", vfs.snapshotErr)
+		} else {
+			f = snapshot
+			vfs.f = f
+			fs.Infof(f, "Showing files as they were at %v", vfs.Opt.SnapshotTime)
+		}
+	}
+
+	// Show the remote on top of a read only base remote if required
+	if vfs.Opt.OverlayBase != "" {
+		base, err := cache.Get(context.TODO(), vfs.Opt.OverlayBase)
+		if err != nil {
+			fs.Errorf(f, "Failed to open --vfs-overlay-base %q - not using it: %v", vfs.Opt.OverlayBase, err)
+		} else if fs.ConfigString(base) == fs.ConfigString(f) {
+			fs.Errorf(f, "Not using --vfs-overlay-base %q as it is the same as the remote", vfs.Opt.OverlayBase)
+		} else {
+			f = newOverlayFs(context.TODO(), f, base, vfs.Opt.OverlayPrecedence)
+			vfs.f = f
+			fs.Infof(f, "Showing files from %v underneath", base)
+		}
+	}
+
+	// Write to a different remote, reading files not yet on it from
+	// the remote, if required
+	if vfs.Opt.WriteBackRemote != "" {
+		dst, err := cache.Get(context.TODO(), vfs.Opt.WriteBackRemote)
+		if err != nil {
+			fs.Errorf(f, "Failed to open --vfs-write-back-remote %q - not using it: %v", vfs.Opt.WriteBackRemote, err)
+		} else if fs.ConfigString(dst) == fs.ConfigString(f) {
+			fs.Errorf(f, "Not using --vfs-write-back-remote %q as it is the same as the remote", vfs.Opt.WriteBackRemote)
+		} else {
+			fs.Infof(f, "Writing changes to %v and reading files not there from the remote", dst)
+			f = newOverlayFs(context.TODO(), dst, f, vfscommon.OverlayPrecedenceWritable)
+			vfs.f = f
+		}
+	}
+
+	// Encode the names the backend can't store if required
+	if vfs.Opt.InvalidNames == vfscommon.InvalidNamesEncode && vfs.invalidNames != encoder.EncodeZero {
+		f = newMappedFs(context.TODO(), f, invalidNameMapper{enc: vfs.invalidNames})
+		vfs.f = f
+	}
+
+	// Show the names with the extra encodings if required
+	if vfs.Opt.Encoding != encoder.EncodeZero {
+		f = newMappedFs(context.TODO(), f, encodingMapper{enc: vfs.Opt.Encoding})
+		vfs.f = f
+	}
+
+	// Show single child directory chains as one directory if required
+	if vfs.Opt.CollapseDirs {
+		sep := vfs.Opt.CollapseDirsSeparator
+		if sep == "" || strings.Contains(sep, "/") {
+			fs.Errorf(f, "Ignoring invalid --vfs-collapse-dirs-separator %q", sep)
+			sep = "-"
+		}
+		f = newMappedFs(context.TODO(), f, newCollapseMapper(sep))
+		vfs.f = f
+	}
+
 	// Find a VFS with the same name and options and return it if possible
 	activeMu.Lock()
 	defer activeMu.Unlock()
@@ -226,19 +349,82 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 	// Put the VFS into the active cache
 	active[configName] = append(active[configName], vfs)
 
+	// Make the context and the workers now the VFS won't be thrown
+	// away in favour of an active one
+	vfs.ctx, vfs.cancel = context.WithCancel(context.Background())
+	vfs.prefetcher = newPrefetcher(vfs.ctx, fs.GetConfig(vfs.ctx).Transfers, prefetchQueueSize, vfs.prefetch)
+
+	// Limit the number of directory listings cached if required
+	if vfs.Opt.DirCacheMaxEntries > 0 {
+		vfs.dirLRU = newDirLRU(vfs.Opt.DirCacheMaxEntries)
+	}
+
+	// Keep the recent file accesses if required
+	if vfs.Opt.AccessHistory > 0 {
+		vfs.accessHistory = newAccessHistory(vfs.Opt.AccessHistory)
+	}
+
+	// Keep the most recently modified files if required
+	if vfs.Opt.RecentFiles > 0 {
+		vfs.recent = newRecentIndex(vfs.Opt.RecentFiles)
+	}
+
+	// Keep the modification times the backend can't set if required
+	if vfs.Opt.ModTimeStore != vfscommon.ModTimeStoreOff {
+		if vfs.Opt.NoModTime {
+			fs.Logf(f, "Ignoring --vfs-modtime-store as --no-modtime is set")
+		} else if modTimes, err := newModTimeStore(f, vfs.Opt.ModTimeStore); err != nil {
+			fs.Errorf(f, "Can't use --vfs-modtime-store %v: %v", vfs.Opt.ModTimeStore, err)
+		} else {
+			vfs.modTimes = modTimes
+		}
+	}
+
+	// Keep the directory listings on disk if required
+	if vfs.Opt.PersistDirCache {
+		if dirIndex, err := newDirIndex(f); err != nil {
+			fs.Errorf(f, "Can't use --vfs-persist-dir-cache: %v", err)
+		} else {
+			vfs.dirIndex = dirIndex
+		}
+	}
+
+	// Batch up the deletes if required
+	if vfs.Opt.DeleteBatchWindow > 0 {
+		vfs.deletes = newDeleteBatcher(f, time.Duration(vfs.Opt.DeleteBatchWindow), func(remote string) {
+			// Show the file again as it is still there
+			vfs.root.ForgetPath(remote, fs.EntryObject)
+		})
+	}
+
+	// Make the inode numbers from the paths if required
+	if vfs.Opt.StableInodes {
+		vfs.inodes = newStableInodes()
+	}
+
 	// Create root directory
 	vfs.root = newDir(vfs, f, nil, fsDir)
 
+	// Coalesce change notifications for the same path if required
+	if vfs.Opt.NotifyDebounce > 0 {
+		vfs.debouncer = newNotifyDebouncer(time.Duration(vfs.Opt.NotifyDebounce), vfs.root.changeNotify)
+	}
+
 	// Start polling function
 	features := vfs.f.Features()
 	if do := features.ChangeNotify; do != nil {
 		vfs.pollChan = make(chan time.Duration)
-		do(context.TODO(), vfs.root.changeNotify, vfs.pollChan)
+		do(context.TODO(), vfs.changeNotify, vfs.pollChan)
 		vfs.pollChan <- time.Duration(vfs.Opt.PollInterval)
 	} else if vfs.Opt.PollInterval > 0 {
 		fs.Infof(f, "poll-interval is not supported by this remote")
 	}
 
+	// Warn if the link counts can't be found
+	if vfs.Opt.LinkCount == vfscommon.LinkCountDedup && (!vfs.Opt.CacheDedup || vfs.Opt.CacheMode < vfscommon.CacheModeMinimal) {
+		fs.Logf(f, "--vfs-link-count dedup needs --vfs-cache-dedup and a --vfs-cache-mode to report link counts")
+	}
+
 	// Warn if can't stream
 	if !vfs.Opt.ReadOnly && vfs.Opt.CacheMode < vfscommon.CacheModeWrites && features.PutStream == nil {
 		fs.Logf(f, "--vfs-cache-mode writes or full is recommended for this remote as it can't stream")
@@ -249,6 +435,61 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 		fs.Logf(f, "Symlinks support enabled")
 	}
 
+	// Set the per handle read bandwidth limits
+	err := vfs.readBw.set(vfs.Opt.PerHandleReadBwLimit, vfs.Opt.PerHandleReadBwLimitRules, vfs.Opt.CaseInsensitive)
+	if err != nil {
+		fs.Errorf(f, "Ignoring --vfs-per-handle-read-bwlimit-rules: %v", err)
+		_ = vfs.readBw.set(vfs.Opt.PerHandleReadBwLimit, "", vfs.Opt.CaseInsensitive)
+	}
+
+	// Parse the files to write straight to the remote
+	vfs.writeThrough, err = parseWriteThrough(vfs.Opt.WriteThrough, vfs.Opt.CaseInsensitive)
+	if err != nil {
+		fs.Errorf(f, "Ignoring --vfs-write-through: %v", err)
+	}
+
+	// Metadata files need an extension to be listed
+	if vfs.Opt.ExposeMetadata && vfs.Opt.MetadataExtension == "" {
+		vfs.Opt.MetadataExtension = defaultMetadataExtension
+		fs.Infof(f, "Using --vfs-metadata-extension %s as --vfs-expose-metadata is set", defaultMetadataExtension)
+	}
+
+	// Open the remote with old versions if required
+	if vfs.Opt.ExposeVersions {
+		versions, err := openVersionsFs(context.TODO(), f)
+		if err != nil {
+			fs.Errorf(f, "Can't use --vfs-expose-versions: %v", err)
+		} else {
+			vfs.versions = versions
+			fs.Infof(f, "Showing old versions of files in %s directories", versionsDirName)
+		}
+	}
+
+	// Open the fallback remote if required
+	if vfs.Opt.FallbackRemote != "" {
+		fallback, err := cache.Get(context.TODO(), vfs.Opt.FallbackRemote)
+		if err != nil {
+			fs.Errorf(f, "Failed to open --vfs-fallback-remote %q - not using it: %v", vfs.Opt.FallbackRemote, err)
+		} else {
+			vfs.fallback = fallback
+			fs.Infof(f, "Reading from %v if reads fail", fallback)
+		}
+	}
+
+	// Read the placeholder for failed reads if required
+	vfs.readError, err = newReadError(vfs.Opt.ReadErrorFile, vfs.Opt.ReadErrorInclude, vfs.Opt.CaseInsensitive)
+	if err != nil {
+		fs.Errorf(f, "Not using --vfs-read-error-file: %v", err)
+	} else if vfs.readError != nil {
+		fs.Infof(f, "Reading %q instead of files if reading them fails", vfs.Opt.ReadErrorFile)
+	}
+
+	// Parse the error numbers to return for backend errors
+	vfs.errorMap, err = parseErrorMap(vfs.Opt.ErrorMap)
+	if err != nil {
+		fs.Errorf(f, "Ignoring invalid --vfs-error-map %q: %v", vfs.Opt.ErrorMap, err)
+	}
+
 	// Pin the Fs into the cache so that when we use cache.NewFs
 	// with the same remote string we get this one. The Pin is
 	// removed when the vfs is finalized
@@ -265,6 +506,21 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 	return vfs
 }
 
+// changeNotify is called by the backend when something changes
+func (vfs *VFS) changeNotify(relativePath string, entryType fs.EntryType) {
+	_, span := vfscommon.StartSpan(context.TODO(), &vfs.Opt, "vfs.ChangeNotify", relativePath)
+	defer span.End()
+	if span.IsRecording() {
+		span.SetAttributes(attribute.Bool("vfs.is_dir", entryType == fs.EntryDirectory))
+	}
+	vfs.lastNotify.Store(time.Now().UnixNano())
+	if vfs.debouncer != nil {
+		vfs.debouncer.add(relativePath, entryType)
+		return
+	}
+	vfs.root.changeNotify(relativePath, entryType)
+}
+
 // refresh the directory cache for all directories
 func (vfs *VFS) refresh() {
 	fs.Debugf(vfs.f, "Refreshing VFS directory cache")
@@ -278,7 +534,9 @@ func (vfs *VFS) refresh() {
 func (vfs *VFS) Stats() (out rc.Params) {
 	out = make(rc.Params)
 	out["fs"] = fs.ConfigString(vfs.f)
-	out["opt"] = vfs.Opt
+	opt := vfs.Opt
+	opt.ReadOnly = vfs.isReadOnly()
+	out["opt"] = opt
 	out["inUse"] = vfs.inUse.Load()
 
 	var (
@@ -338,6 +596,20 @@ func (vfs *VFS) SetCacheMode(cacheMode vfscommon.CacheMode) {
 	}
 }
 
+// cacheMode returns the cache mode to open files with
+//
+// This is --vfs-cache-mode unless the cache has fallen back to
+// minimal because of --vfs-cache-degrade-errors.
+func (vfs *VFS) cacheMode() vfscommon.CacheMode {
+	cacheMode := vfs.Opt.CacheMode
+	if cacheMode > vfscommon.CacheModeMinimal && vfs.cache != nil {
+		if degraded, _, _ := vfs.cache.Degraded(); degraded {
+			return vfscommon.CacheModeMinimal
+		}
+	}
+	return cacheMode
+}
+
 // shutdown the cache if it was running
 func (vfs *VFS) shutdownCache() {
 	if vfs.cancelCache != nil {
@@ -366,12 +638,26 @@ func (vfs *VFS) Shutdown() {
 	}
 	activeMu.Unlock()
 
+	// Do any deletes which are waiting
+	vfs.deletes.flush()
+
+	// Save any modification times which are waiting
+	vfs.modTimes.flush()
+
+	// Throw away the changes if they were never to be uploaded
+	if vfs.Opt.NoWriteBack && vfs.cache != nil {
+		vfs.cache.DiscardScratch()
+	}
+	vfs.cancel()
 	vfs.shutdownCache()
 
 	if vfs.pollChan != nil {
 		close(vfs.pollChan)
 		vfs.pollChan = nil
 	}
+	if vfs.debouncer != nil {
+		vfs.debouncer.stop()
+	}
 }
 
 // CleanUp deletes the contents of the on disk cache
@@ -384,6 +670,7 @@ func (vfs *VFS) CleanUp() error {
 
 // FlushDirCache empties the directory cache
 func (vfs *VFS) FlushDirCache() {
+	vfs.dirIndex.clear()
 	vfs.root.ForgetAll()
 }
 
@@ -528,6 +815,10 @@ func (vfs *VFS) OpenFile(name string, flags int, perm os.FileMode) (fd Handle, e
 	}
 
 	node, err := vfs.Stat(name)
+	if err == nil && flags&os.O_CREATE != 0 && node.IsFile() && node.Name() != path.Base(name) {
+		// Only found with a different case so let Create decide
+		err = ENOENT
+	}
 	if err != nil {
 		if err != ENOENT || flags&os.O_CREATE == 0 {
 			return nil, err
@@ -618,6 +909,13 @@ func (vfs *VFS) Statfs() (total, used, free int64) {
 	// defer log.Trace("/", "")("total=%d, used=%d, free=%d", &total, &used, &free)
 	vfs.usageMu.Lock()
 	defer vfs.usageMu.Unlock()
+	return vfs._statfs()
+}
+
+// _statfs implements Statfs
+//
+// call with usageMu held
+func (vfs *VFS) _statfs() (total, used, free int64) {
 	total, used, free = -1, -1, -1
 	doAbout := vfs.f.Features().About
 	if (doAbout != nil || vfs.Opt.UsedIsSize) && (vfs.usageTime.IsZero() || time.Since(vfs.usageTime) >= time.Duration(vfs.Opt.DirCacheTime)) {
@@ -644,6 +942,7 @@ func (vfs *VFS) Statfs() (total, used, free int64) {
 			}
 		}
 		vfs.usageTime = time.Now()
+		vfs.quotaGrown = 0
 		if err != nil {
 			fs.Errorf(vfs.f, "Statfs failed: %v", err)
 			return
@@ -664,12 +963,80 @@ func (vfs *VFS) Statfs() (total, used, free int64) {
 
 	if int64(vfs.Opt.DiskSpaceTotalSize) >= 0 {
 		total = int64(vfs.Opt.DiskSpaceTotalSize)
+		if vfs.Opt.DiskSpaceTotalSizeEnforce && used >= 0 {
+			used += vfs.quotaGrown
+			if free >= 0 {
+				free = max(total-used, 0)
+			}
+		}
 	}
 
 	total, used, free = fillInMissingSizes(total, used, free, unknownFreeBytes)
 	return
 }
 
+// updateDirSizes reads the total size of the objects under each
+// directory using the `rclone size` algorithm and stores it in the
+// directory size index.
+func (vfs *VFS) updateDirSizes(ctx context.Context) error {
+	sizes := map[string]int64{}
+	err := walk.ListR(ctx, vfs.f, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		// Not all backends check the context so stop here if cancelled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entries.ForObject(func(o fs.Object) {
+			size := o.Size()
+			if size <= 0 {
+				return
+			}
+			dir := o.Remote()
+			for dir != "" {
+				dir = path.Dir(dir)
+				if dir == "." {
+					dir = ""
+				}
+				sizes[dir] += size
+			}
+		})
+		return nil
+	})
+	vfs.dirSizesMu.Lock()
+	defer vfs.dirSizesMu.Unlock()
+	vfs.dirSizesRun = false
+	vfs.dirSizesAt = time.Now()
+	if err != nil {
+		return err
+	}
+	vfs.dirSizes = sizes
+	return nil
+}
+
+// dirSize returns the total size of the objects under the directory
+// dirPath if --vfs-dir-sizes is set, or 0 if not.
+//
+// The sizes are read from an index which is updated in the background
+// every --vfs-dir-sizes-refresh so may be out of date or 0 until the
+// first update finishes. The update is stopped when the VFS is shut
+// down.
+func (vfs *VFS) dirSize(dirPath string) int64 {
+	if !vfs.Opt.DirSizes {
+		return 0
+	}
+	vfs.dirSizesMu.Lock()
+	defer vfs.dirSizesMu.Unlock()
+	if !vfs.dirSizesRun && vfs.ctx.Err() == nil && (vfs.dirSizesAt.IsZero() || time.Since(vfs.dirSizesAt) >= time.Duration(vfs.Opt.DirSizesRefresh)) {
+		vfs.dirSizesRun = true
+		go func() {
+			err := vfs.updateDirSizes(vfs.ctx)
+			if err != nil && vfs.ctx.Err() == nil {
+				fs.Errorf(vfs.f, "Failed to read directory sizes: %v", err)
+			}
+		}()
+	}
+	return vfs.dirSizes[dirPath]
+}
+
 // Remove removes the named file or (empty) directory.
 func (vfs *VFS) Remove(name string) error {
 	node, err := vfs.Stat(name)
@@ -901,6 +1268,18 @@ func (vfs *VFS) Symlink(oldname, newname string) error {
 	return err
 }
 
+// Return true if name represents a marker for a file being
+// downloaded into the cache
+//
+// It returns the underlying path
+func (vfs *VFS) isDownloadingMarker(name string) (rawName string, found bool) {
+	suffix := vfs.Opt.CacheDownloadingSuffix
+	if suffix == "" {
+		return name, false
+	}
+	return strings.CutSuffix(name, suffix)
+}
+
 // Return true if name represents a metadata file
 //
 // It returns the underlying path
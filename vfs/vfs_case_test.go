@@ -195,3 +195,70 @@ func TestUnicodeNormalization(t *testing.T) {
 	assertFileDataVFS(t, vfs, norm.NFD.String(both), "data1")
 	assertFileAbsentVFS(t, vfs, nfd)
 }
+
+func TestCaseInsensitiveCreate(t *testing.T) {
+	for _, test := range []struct {
+		create   vfscommon.CaseCreate
+		wantErr  error
+		wantData map[string]string
+	}{
+		{create: vfscommon.CaseCreateReuse, wantData: map[string]string{"FiLeA": "new"}},
+		{create: vfscommon.CaseCreateNew, wantData: map[string]string{"FiLeA": "data1", "filea": "new"}},
+		{create: vfscommon.CaseCreateError, wantErr: EEXIST, wantData: map[string]string{"FiLeA": "data1"}},
+	} {
+		t.Run(test.create.String(), func(t *testing.T) {
+			r := fstest.NewRun(t)
+			if r.Fremote.Features().CaseInsensitive {
+				t.Skip("Can't test case sensitivity - this remote is officially not case-sensitive")
+			}
+			ctx := context.Background()
+			r.WriteObject(ctx, "FiLeA", "data1", t1)
+
+			opt := vfscommon.Opt
+			opt.CaseInsensitive = true
+			opt.CaseInsensitiveCreate = test.create
+			vfs := New(r.Fremote, &opt)
+			defer cleanupVFS(t, vfs)
+
+			// Opening without O_CREATE always finds the existing file
+			assertFileDataVFS(t, vfs, "filea", "data1")
+
+			err := vfs.WriteFile("filea", []byte("new"), 0777)
+			assert.Equal(t, test.wantErr, err)
+
+			var items []fstest.Item
+			for name, data := range test.wantData {
+				items = append(items, fstest.NewItem(name, data, t1))
+			}
+			fstest.CheckListingWithPrecision(t, r.Fremote, items, nil, fs.ModTimeNotSupported)
+		})
+	}
+}
+
+func TestCaseInsensitiveMaxScan(t *testing.T) {
+	r := fstest.NewRun(t)
+	if r.Fremote.Features().CaseInsensitive {
+		t.Skip("Can't test case sensitivity - this remote is officially not case-sensitive")
+	}
+	ctx := context.Background()
+	r.WriteObject(ctx, "FiLeA", "data1", t1)
+	r.WriteObject(ctx, "FiLeB", "data2", t1)
+
+	opt := vfscommon.Opt
+	opt.CaseInsensitive = true
+	opt.CaseInsensitiveMaxScan = 2
+	vfs := New(r.Fremote, &opt)
+	defer cleanupVFS(t, vfs)
+
+	// The directory is small enough to scan
+	assertFileDataVFS(t, vfs, "filea", "data1")
+
+	// The directory is too big to scan but exact names still work
+	r.WriteObject(ctx, "FiLeC", "data3", t1)
+	root, err := vfs.Root()
+	require.NoError(t, err)
+	root.ForgetAll()
+	assertFileDataVFS(t, vfs, "FiLeA", "data1")
+	_, err = vfs.Stat("filea")
+	assert.Equal(t, ENOENT, err)
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -312,6 +313,60 @@ func TestVFSRename(t *testing.T) {
 	assert.Equal(t, os.ErrNotExist, err)
 }
 
+func TestVFSDirSizes(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.DirSizes = true
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	file2 := r.WriteObject(ctx, "dir/sub/file2", "file2", t2)
+	file3 := r.WriteObject(ctx, "file3", "3", t3)
+	r.CheckRemoteItems(t, file1, file2, file3)
+
+	require.NoError(t, vfs.updateDirSizes(ctx))
+
+	root, err := vfs.Root()
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), root.Size())
+	node, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	assert.Equal(t, int64(19), node.Size())
+	node, err = vfs.Stat("dir/sub")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), node.Size())
+
+	// Check sizes aren't reported if not enabled
+	vfs.Opt.DirSizes = false
+	assert.Equal(t, int64(0), node.Size())
+}
+
+func TestVFSDirSizesShutdown(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.DirSizes = true
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+
+	// Cancel the VFS context as Shutdown does
+	vfs.cancel()
+
+	// An update which is running stops
+	err := vfs.updateDirSizes(vfs.ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// No new update is started
+	root, err := vfs.Root()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), root.Size())
+	vfs.dirSizesMu.Lock()
+	assert.False(t, vfs.dirSizesRun)
+	assert.Nil(t, vfs.dirSizes)
+	vfs.dirSizesMu.Unlock()
+}
+
 func TestVFSStatfs(t *testing.T) {
 	r, vfs := newTestVFS(t)
 
@@ -362,6 +417,136 @@ func TestVFSStatfs(t *testing.T) {
 	assert.Equal(t, oldTime, vfs.usageTime)
 }
 
+func TestVFSStatfsEnforce(t *testing.T) {
+	for _, cacheMode := range []vfscommon.CacheMode{vfscommon.CacheModeOff, vfscommon.CacheModeWrites} {
+		t.Run(cacheMode.String(), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.CacheMode = cacheMode
+			opt.UsedIsSize = true
+			opt.DiskSpaceTotalSize = 100
+			opt.DiskSpaceTotalSizeEnforce = true
+			opt.WriteBack = 0
+			r, vfs := newTestVFSOpt(t, &opt)
+
+			file1 := r.WriteObject(context.Background(), "file1", strings.Repeat("1", 40), t1)
+			r.CheckRemoteItems(t, file1)
+
+			total, used, free := vfs.Statfs()
+			assert.Equal(t, int64(100), total)
+			assert.Equal(t, int64(40), used)
+			assert.Equal(t, int64(60), free)
+
+			// Writes are counted in the usage before it is read again
+			require.NoError(t, vfs.WriteFile("file2", []byte(strings.Repeat("2", 50)), 0600))
+			total, used, free = vfs.Statfs()
+			assert.Equal(t, int64(100), total)
+			assert.Equal(t, int64(90), used)
+			assert.Equal(t, int64(10), free)
+
+			// Writes which don't fit fail
+			err := vfs.WriteFile("file3", []byte(strings.Repeat("3", 20)), 0600)
+			assert.Equal(t, ENOSPC, err)
+		})
+	}
+}
+
+func TestVFSBackendMinFreeSpace(t *testing.T) {
+	for _, cacheMode := range []vfscommon.CacheMode{vfscommon.CacheModeOff, vfscommon.CacheModeWrites} {
+		t.Run(cacheMode.String(), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.CacheMode = cacheMode
+			opt.BackendMinFreeSpace = 100
+			opt.DirCacheTime = fs.Duration(time.Hour)
+			opt.WriteBack = 0
+			_, vfs := newTestVFSOpt(t, &opt)
+
+			// Pretend the remote reported 200 bytes free
+			free := int64(200)
+			vfs.usageMu.Lock()
+			vfs.usage = &fs.Usage{Free: &free}
+			vfs.usageTime = time.Now()
+			vfs.usageMu.Unlock()
+
+			// Writes are counted until the usage is read again
+			require.NoError(t, vfs.WriteFile("file1", []byte(strings.Repeat("1", 60)), 0600))
+
+			// Writes which would leave less than the minimum free fail
+			err := vfs.WriteFile("file2", []byte(strings.Repeat("2", 50)), 0600)
+			assert.Equal(t, ENOSPC, err)
+			require.NoError(t, vfs.WriteFile("file3", []byte(strings.Repeat("3", 40)), 0600))
+
+			// Remotes which don't report their free space aren't limited
+			vfs.usageMu.Lock()
+			vfs.usage = &fs.Usage{}
+			vfs.usageMu.Unlock()
+			require.NoError(t, vfs.WriteFile("file4", []byte(strings.Repeat("4", 500)), 0600))
+		})
+	}
+}
+
+func TestVFSNoWriteBack(t *testing.T) {
+	r := fstest.NewRun(t)
+	ctx := context.Background()
+	file1 := r.WriteObject(ctx, "file1", "file1 contents", t1)
+	file3 := r.WriteObject(ctx, "dir/file3", "file3 contents", t1)
+	opt := vfscommon.Opt
+	opt.NoWriteBack = true
+	opt.WriteBack = fs.Duration(10 * time.Millisecond)
+	vfs := New(r.Fremote, &opt)
+	assert.Equal(t, vfscommon.CacheModeWrites, vfs.Opt.CacheMode)
+
+	// Changes are read back from the cache
+	require.NoError(t, vfs.WriteFile("file1", []byte("changed"), 0600))
+	require.NoError(t, vfs.WriteFile("file2", []byte("new file"), 0600))
+	contents, err := vfs.ReadFile("file1")
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(contents))
+	contents, err = vfs.ReadFile("file2")
+	require.NoError(t, err)
+	assert.Equal(t, "new file", string(contents))
+
+	// But never uploaded
+	start := time.Now()
+	vfs.WaitForWriters(waitForWritersDelay)
+	assert.Less(t, time.Since(start), waitForWritersDelay/2, "scratch files shouldn't be waited for")
+	time.Sleep(100 * time.Millisecond)
+	r.CheckRemoteItems(t, file1, file3)
+	assert.Equal(t, 0, vfs.cache.TotalInUse())
+
+	// Things on the remote can't be changed
+	assert.Equal(t, EROFS, vfs.Remove("dir/file3"))
+	assert.Equal(t, EROFS, vfs.Rename("dir/file3", "file3"))
+	assert.Equal(t, EROFS, vfs.Rename("dir", "dir2"))
+	node, err := vfs.Stat("dir/file3")
+	require.NoError(t, err)
+	assert.Equal(t, EROFS, node.SetModTime(t2))
+	assert.Equal(t, EROFS, vfs.Remove("dir/file3"))
+
+	// But things only in the VFS can
+	require.NoError(t, vfs.Chtimes("file1", t2, t2))
+	require.NoError(t, vfs.Mkdir("new", 0777))
+	require.NoError(t, vfs.WriteFile("new/file4", []byte("file4"), 0600))
+	require.NoError(t, vfs.Rename("new/file4", "new/file5"))
+	require.NoError(t, vfs.Rename("new", "new2"))
+	contents, err = vfs.ReadFile("new2/file5")
+	require.NoError(t, err)
+	assert.Equal(t, "file4", string(contents))
+	require.NoError(t, vfs.Remove("new2/file5"))
+	require.NoError(t, vfs.Remove("new2"))
+	node, err = vfs.Stat("file1")
+	require.NoError(t, err)
+	assert.True(t, t2.Equal(node.ModTime()))
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file3}, []string{"dir"}, r.Fremote.Precision())
+
+	// And discarded on shutdown
+	assert.True(t, vfs.cache.Exists("file2"))
+	vfs.Shutdown()
+	assert.False(t, vfs.cache.Exists("file1"))
+	assert.False(t, vfs.cache.Exists("file2"))
+	require.NoError(t, vfs.CleanUp())
+	r.CheckRemoteItems(t, file1, file3)
+}
+
 func TestVFSMkdir(t *testing.T) {
 	r, vfs := newTestVFS(t)
 
@@ -0,0 +1,182 @@
+package vfscache
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/time/rate"
+)
+
+// writeBackLimiter limits the bandwidth of uploads from the cache
+// independently of the global --bwlimit and of reads
+type writeBackLimiter struct {
+	mu        sync.Mutex
+	timetable fs.BwTimetable   // limits to apply - empty if unlimited
+	raw       string           // timetable as supplied
+	limit     fs.SizeSuffix    // limit tb was made from - <= 0 if unlimited
+	tb        *rate.Limiter    // nil if unlimited
+	now       func() time.Time // time source, replaced in tests
+}
+
+// set the limits from a timetable in the same format as --bwlimit
+//
+// For upload:download pairs only the upload limit is used.
+func (l *writeBackLimiter) set(s string) error {
+	var timetable fs.BwTimetable
+	if strings.TrimSpace(s) != "" {
+		err := timetable.Set(s)
+		if err != nil {
+			return err
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timetable = timetable
+	l.raw = s
+	l._update()
+	return nil
+}
+
+// get the timetable as supplied and the limit in use now
+//
+// A limit <= 0 means unlimited
+func (l *writeBackLimiter) get() (timetable string, limit fs.SizeSuffix) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l._update()
+	return l.raw, l.limit
+}
+
+// active returns true if there are any limits set
+func (l *writeBackLimiter) active() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.timetable) > 0
+}
+
+// _update the limiter to the limit for the current time
+//
+// call with the lock held
+func (l *writeBackLimiter) _update() {
+	now := time.Now
+	if l.now != nil {
+		now = l.now
+	}
+	limit := l.timetable.LimitAt(now()).Bandwidth.Tx
+	if limit == l.limit {
+		return
+	}
+	if l.tb != nil || limit > 0 {
+		fs.Debugf(nil, "vfs cache: writeback bandwidth limit set to %v", limit)
+	}
+	l.limit = limit
+	switch {
+	case limit <= 0:
+		l.tb = nil
+	case l.tb == nil:
+		l.tb = rate.NewLimiter(rate.Limit(limit), int(limit))
+	default:
+		l.tb.SetLimit(rate.Limit(limit))
+		l.tb.SetBurst(int(limit))
+	}
+}
+
+// wait until n bytes are allowed to have been uploaded
+//
+// The timetable is checked on each call so scheduled changes are
+// picked up by uploads in progress.
+func (l *writeBackLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	l._update()
+	tb := l.tb
+	l.mu.Unlock()
+	if tb == nil {
+		return nil
+	}
+	// WaitN can't wait for more than the burst at once
+	for burst := tb.Burst(); n > 0; n -= burst {
+		err := tb.WaitN(ctx, min(n, burst))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wrap o so that uploads read from it are limited
+func (l *writeBackLimiter) wrap(o fs.Object) fs.Object {
+	return &writeBackObject{Object: o, l: l}
+}
+
+// writeBackObject is a cache file being uploaded with its bandwidth
+// limited by a writeBackLimiter
+type writeBackObject struct {
+	fs.Object
+	l *writeBackLimiter
+}
+
+// Open the object for reading with the bandwidth limited
+func (o *writeBackObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	in, err := o.Object.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &writeBackReader{ReadCloser: in, ctx: ctx, l: o.l}, nil
+}
+
+// MimeType returns the content type of the Object if known
+func (o *writeBackObject) MimeType(ctx context.Context) string {
+	return fs.MimeType(ctx, o.Object)
+}
+
+// Metadata returns metadata for an object
+func (o *writeBackObject) Metadata(ctx context.Context) (fs.Metadata, error) {
+	return fs.GetMetadata(ctx, o.Object)
+}
+
+// UnWrap returns the wrapped Object
+func (o *writeBackObject) UnWrap() fs.Object {
+	return o.Object
+}
+
+// writeBackReader limits the rate data is read from a writeBackObject
+type writeBackReader struct {
+	io.ReadCloser
+	ctx context.Context
+	l   *writeBackLimiter
+}
+
+// Read from the object waiting for the bandwidth limit
+func (r *writeBackReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if waitErr := r.l.wait(r.ctx, n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+// WriteBackBwLimit returns the writeback bandwidth limit timetable as
+// supplied and the limit in use now, which is <= 0 if unlimited.
+func (c *Cache) WriteBackBwLimit() (timetable string, limit fs.SizeSuffix) {
+	return c.writeBackBw.get()
+}
+
+// SetWriteBackBwLimit changes the writeback bandwidth limit timetable.
+//
+// The new limits apply to uploads started afterwards and to uploads in
+// progress which were already limited from their next read.
+func (c *Cache) SetWriteBackBwLimit(timetable string) error {
+	return c.writeBackBw.set(timetable)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object          = (*writeBackObject)(nil)
+	_ fs.MimeTyper       = (*writeBackObject)(nil)
+	_ fs.Metadataer      = (*writeBackObject)(nil)
+	_ fs.ObjectUnWrapper = (*writeBackObject)(nil)
+)
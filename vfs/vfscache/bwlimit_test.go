@@ -0,0 +1,77 @@
+package vfscache
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBackLimiterTimetable(t *testing.T) {
+	var l writeBackLimiter
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local)
+	l.now = func() time.Time { return now }
+
+	timetable, limit := l.get()
+	assert.Equal(t, "", timetable)
+	assert.LessOrEqual(t, limit, fs.SizeSuffix(0))
+	assert.False(t, l.active())
+
+	require.NoError(t, l.set("08:00,512k 19:00,off"))
+	assert.True(t, l.active())
+	timetable, limit = l.get()
+	assert.Equal(t, "08:00,512k 19:00,off", timetable)
+	assert.Equal(t, 512*fs.Kibi, limit)
+	require.NotNil(t, l.tb)
+
+	// Scheduled changes are picked up
+	now = now.Add(12 * time.Hour)
+	_, limit = l.get()
+	assert.LessOrEqual(t, limit, fs.SizeSuffix(0))
+	assert.Nil(t, l.tb)
+
+	// Only the upload side of a pair is used
+	require.NoError(t, l.set("1M:10M"))
+	_, limit = l.get()
+	assert.Equal(t, fs.Mebi, limit)
+
+	// Bad timetables are rejected leaving the limit alone
+	assert.Error(t, l.set("potato"))
+	_, limit = l.get()
+	assert.Equal(t, fs.Mebi, limit)
+
+	require.NoError(t, l.set(""))
+	assert.False(t, l.active())
+}
+
+func TestWriteBackLimiterWait(t *testing.T) {
+	var l writeBackLimiter
+	ctx := context.Background()
+	contents := strings.Repeat("x", 1500)
+	o := mockobject.New("file.txt").WithContent([]byte(contents), mockobject.SeekModeNone)
+
+	// Limited - the first second's worth is free then
+	// it should take half a second to read another 500 bytes
+	require.NoError(t, l.set("1000B"))
+	wrapped := l.wrap(o)
+	assert.Equal(t, o, wrapped.(fs.ObjectUnWrapper).UnWrap())
+	in, err := wrapped.Open(ctx)
+	require.NoError(t, err)
+	start := time.Now()
+	got, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, string(got))
+	assert.InDelta(t, 500*time.Millisecond, time.Since(start), float64(250*time.Millisecond))
+
+	// A cancelled context stops the wait
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, l.wait(cancelCtx, 500))
+}
@@ -25,8 +25,10 @@ import (
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/file"
 	"github.com/rclone/rclone/lib/systemd"
+	"github.com/rclone/rclone/vfs/vfscache/downloaders"
 	"github.com/rclone/rclone/vfs/vfscache/writeback"
 	"github.com/rclone/rclone/vfs/vfscommon"
+	"golang.org/x/sync/errgroup"
 )
 
 // NB as Cache and Item are tightly linked it is necessary to have a
@@ -41,26 +43,38 @@ import (
 // Cache opened files
 type Cache struct {
 	// read only - no locking needed to read these
-	fremote    fs.Fs                // fs for the remote we are caching
-	fcache     fs.Fs                // fs for the cache directory
-	fcacheMeta fs.Fs                // fs for the cache metadata directory
-	opt        *vfscommon.Options   // vfs Options
-	root       string               // root of the cache directory
-	metaRoot   string               // root of the cache metadata directory
-	hashType   hash.Type            // hash to use locally and remotely
-	hashOption *fs.HashesOption     // corresponding OpenOption
-	writeback  *writeback.WriteBack // holds Items for writeback
-	avFn       AddVirtualFn         // if set, can be called to add dir entries
+	ctx         context.Context          // cancelled when the cache is shut down
+	fremote     fs.Fs                    // fs for the remote we are caching
+	fcache      fs.Fs                    // fs for the cache directory
+	fcacheMeta  fs.Fs                    // fs for the cache metadata directory
+	opt         *vfscommon.Options       // vfs Options
+	root        string                   // root of the cache directory
+	metaRoot    string                   // root of the cache metadata directory
+	snapRoot    string                   // root of the upload snapshot directory if in use
+	fsnap       fs.Fs                    // fs for the upload snapshot directory if in use
+	hashType    hash.Type                // hash to use locally and remotely
+	hashOption  *fs.HashesOption         // corresponding OpenOption
+	writeback   *writeback.WriteBack     // holds Items for writeback
+	avFn        AddVirtualFn             // if set, can be called to add dir entries
+	revalidate  map[string]time.Duration // extension to revalidation TTL
+	maxAgeRules []maxAgeRule             // max ages for directories from --vfs-cache-max-age-rules
+	writeBackBw writeBackLimiter         // bandwidth limit for uploads
+	degrade     degradeState             // cache write errors for --vfs-cache-degrade-errors
+	limits      cacheLimits              // limits the cleaner keeps the cache within
+	priority    *downloaders.Priority    // reads waiting for data if --vfs-read-priority
 
 	mu            sync.Mutex       // protects the following variables
 	cond          sync.Cond        // cond lock for synchronous cache cleaning
 	item          map[string]*Item // files/directories in the cache
 	errItems      map[string]error // items in error state
 	used          int64            // total size of files in the cache
+	links         map[string]int   // number of items sharing each cache file by dedup key
 	outOfSpace    bool             // out of space
 	cleanerKicked bool             // some thread kicked the cleaner upon out of space
 	kickerMu      sync.Mutex       // mutex for cleanerKicked
 	kick          chan struct{}    // channel for kicking clear to start
+	limitsChanged chan struct{}    // channel for starting the cleaner when the limits are tightened
+	largeFiles    map[string]bool  // files warned about with --vfs-cache-large-file-warn-threshold
 
 }
 
@@ -89,15 +103,14 @@ func New(ctx context.Context, fremote fs.Fs, opt *vfscommon.Options, avFn AddVir
 	parentPath := fromOSPath(parentOSPath)
 
 	// Get a relative cache path representing the remote.
-	relativeDirPath := fremote.Root() // This is a remote path in standard encoding
-	if runtime.GOOS == "windows" {
-		if strings.HasPrefix(relativeDirPath, `//?/`) {
-			relativeDirPath = relativeDirPath[2:] // Trim off the "//" for the result to be a valid when appending to another path
-		}
-	}
-	relativeDirPath = fremote.Name() + "/" + relativeDirPath
+	relativeDirPath := cacheRelativeDirPath(fremote)
 	relativeDirOSPath := toOSPath(relativeDirPath)
 
+	// Check nobody else is using the cache directory if required
+	if err = lockCacheDir(ctx, opt.CacheDirLock, parentOSPath, relativeDirOSPath); err != nil {
+		return nil, fmt.Errorf("vfs cache: %w", err)
+	}
+
 	// Create cache root dirs
 	var dataOSPath, metaOSPath string
 	if dataOSPath, metaOSPath, err = createRootDirs(parentOSPath, relativeDirOSPath); err != nil {
@@ -111,22 +124,59 @@ func New(ctx context.Context, fremote fs.Fs, opt *vfscommon.Options, avFn AddVir
 	if fdata, fmeta, err = getBackends(ctx, parentPath, relativeDirPath); err != nil {
 		return nil, err
 	}
+
+	// Create the snapshot dir if needed removing any left over
+	// from a previous run
+	var snapOSPath string
+	var fsnap fs.Fs
+	if opt.WriteBackChanged == vfscommon.WriteBackChangedSnapshot {
+		_ = os.RemoveAll(file.UNCPath(filepath.Join(parentOSPath, "vfsSnapshot", relativeDirOSPath)))
+		if snapOSPath, err = createRootDir(parentOSPath, "vfsSnapshot", relativeDirOSPath); err != nil {
+			return nil, fmt.Errorf("failed to create snapshot cache directory: %w", err)
+		}
+		if fsnap, err = getBackend(ctx, parentPath, "vfsSnapshot", relativeDirPath); err != nil {
+			return nil, fmt.Errorf("failed to get snapshot cache backend: %w", err)
+		}
+		fs.Debugf(fremote, "vfs cache: snapshot root is %q", snapOSPath)
+	}
 	hashType, hashOption := operations.CommonHash(ctx, fdata, fremote)
 
+	revalidate, err := parseRevalidate(opt.CacheRevalidate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --vfs-cache-revalidate: %w", err)
+	}
+
+	maxAgeRules, err := parseMaxAgeRules(opt.CacheMaxAgeRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --vfs-cache-max-age-rules: %w", err)
+	}
+
 	// Create the cache object
 	c := &Cache{
-		fremote:    fremote,
-		fcache:     fdata,
-		fcacheMeta: fmeta,
-		opt:        opt,
-		root:       dataOSPath,
-		metaRoot:   metaOSPath,
-		item:       make(map[string]*Item),
-		errItems:   make(map[string]error),
-		hashType:   hashType,
-		hashOption: hashOption,
-		writeback:  writeback.New(ctx, opt),
-		avFn:       avFn,
+		ctx:         ctx,
+		fremote:     fremote,
+		fcache:      fdata,
+		fcacheMeta:  fmeta,
+		opt:         opt,
+		root:        dataOSPath,
+		metaRoot:    metaOSPath,
+		snapRoot:    snapOSPath,
+		fsnap:       fsnap,
+		item:        make(map[string]*Item),
+		errItems:    make(map[string]error),
+		hashType:    hashType,
+		hashOption:  hashOption,
+		writeback:   writeback.New(ctx, opt),
+		avFn:        avFn,
+		revalidate:  revalidate,
+		maxAgeRules: maxAgeRules,
+	}
+	if opt.ReadPriority {
+		c.priority = downloaders.NewPriority()
+	}
+	err = c.writeBackBw.set(opt.WriteBackBwLimit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --vfs-write-back-bwlimit: %w", err)
 	}
 
 	// load in the cache and metadata off disk
@@ -140,6 +190,7 @@ func New(ctx context.Context, fremote fs.Fs, opt *vfscommon.Options, avFn AddVir
 
 	// Create a channel for cleaner to be kicked upon out of space con
 	c.kick = make(chan struct{}, 1)
+	c.limitsChanged = make(chan struct{}, 1)
 	c.cond = sync.Cond{L: &c.mu}
 
 	go c.cleaner(ctx)
@@ -147,6 +198,44 @@ func New(ctx context.Context, fremote fs.Fs, opt *vfscommon.Options, avFn AddVir
 	return c, nil
 }
 
+// parseRevalidate parses a comma separated list of ext=duration pairs
+// as used by --vfs-cache-revalidate
+func parseRevalidate(s string) (map[string]time.Duration, error) {
+	revalidate := make(map[string]time.Duration)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		ext, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("expecting ext=duration but got %q", part)
+		}
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext == "" {
+			return nil, fmt.Errorf("empty extension in %q", part)
+		}
+		ttl, err := fs.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("bad duration in %q: %w", part, err)
+		}
+		revalidate[ext] = ttl
+	}
+	return revalidate, nil
+}
+
+// revalidateTTL returns how old the cached data for name may be
+// before it should be checked against the remote on open.
+//
+// It returns 0 if the data never needs to be checked.
+func (c *Cache) revalidateTTL(name string) time.Duration {
+	if len(c.revalidate) == 0 {
+		return 0
+	}
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	return c.revalidate[ext]
+}
+
 // Stats returns info about the Cache
 func (c *Cache) Stats() (out rc.Params) {
 	out = make(rc.Params)
@@ -159,13 +248,30 @@ func (c *Cache) Stats() (out rc.Params) {
 	out["uploadsInProgress"] = uploadsInProgress
 	out["uploadsQueued"] = uploadsQueued
 
+	degraded, since, err := c.Degraded()
+	out["degraded"] = degraded
+	out["degradedSince"] = ""
+	out["degradedError"] = ""
+	if degraded {
+		out["degradedSince"] = since.UTC().Format(time.RFC3339Nano)
+		out["degradedError"] = err.Error()
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	dirtyFiles := 0
+	for _, item := range c.item {
+		if item.IsDirty() {
+			dirtyFiles++
+		}
+	}
 	out["files"] = len(c.item)
+	out["dirtyFiles"] = dirtyFiles
 	out["erroredFiles"] = len(c.errItems)
 	out["bytesUsed"] = c.used
 	out["outOfSpace"] = c.outOfSpace
+	out["largeFiles"] = len(c.largeFiles)
 
 	return out
 }
@@ -185,6 +291,46 @@ func (c *Cache) QueueSetExpiry(id writeback.Handle, expiry time.Time, relative t
 	return c.writeback.SetExpiry(id, expiry, relative)
 }
 
+// FlushFile uploads the dirty item name now, rather than when its
+// writeback is due, and waits for the upload to finish.
+//
+// It returns an error if the item isn't dirty or isn't queued for
+// upload yet because it is still open.
+func (c *Cache) FlushFile(ctx context.Context, name string) error {
+	item := c.DirtyItem(name)
+	if item == nil {
+		return fmt.Errorf("%q has no changes to upload", name)
+	}
+	item.mu.Lock()
+	id := item.writeBackID
+	item.mu.Unlock()
+	err := c.writeback.Flush(ctx, id)
+	if errors.Is(err, writeback.ErrorIDNotFound) {
+		return fmt.Errorf("%q isn't queued for upload - is it still open?", name)
+	}
+	return err
+}
+
+// cacheRelativeDirPath returns the path of the cache for fremote
+// relative to the cache roots as a standard path
+func cacheRelativeDirPath(fremote fs.Info) string {
+	relativeDirPath := fremote.Root() // This is a remote path in standard encoding
+	if runtime.GOOS == "windows" {
+		if strings.HasPrefix(relativeDirPath, `//?/`) {
+			relativeDirPath = relativeDirPath[2:] // Trim off the "//" for the result to be a valid when appending to another path
+		}
+	}
+	return fremote.Name() + "/" + relativeDirPath
+}
+
+// RootDir returns the OS path of the directory called name in the
+// cache directory for fremote, creating it if necessary.
+//
+// This is for keeping other state about fremote alongside the cache.
+func RootDir(fremote fs.Info, name string) (string, error) {
+	return createRootDir(config.GetCacheDir(), name, toOSPath(cacheRelativeDirPath(fremote)))
+}
+
 // createDir creates a directory path, along with any necessary parents
 func createDir(dir string) error {
 	return file.MkdirAll(dir, 0700)
@@ -277,6 +423,12 @@ func (c *Cache) toOSPathMeta(name string) string {
 	return filepath.Join(c.metaRoot, toOSPath(name))
 }
 
+// toOSPathSnapshot turns a remote relative name into an OS path in the
+// cache for an upload snapshot
+func (c *Cache) toOSPathSnapshot(name string) string {
+	return filepath.Join(c.snapRoot, toOSPath(name))
+}
+
 // _get gets name from the cache or creates a new one
 //
 // It returns the item and found as to whether this item was found in
@@ -327,6 +479,78 @@ func (c *Cache) InUse(name string) bool {
 	return item.inUse()
 }
 
+// Downloading returns true if name is being downloaded into the cache
+// and isn't fully cached yet.
+//
+// name should be a remote path not an osPath
+func (c *Cache) Downloading(name string) bool {
+	name = clean(name)
+	c.mu.Lock()
+	item := c.item[name]
+	c.mu.Unlock()
+	if item == nil {
+		return false
+	}
+	return item.downloading()
+}
+
+// DownloadProgress returns the progress of the items being
+// downloaded into the cache sorted by name
+func (c *Cache) DownloadProgress() (progress []DownloadProgress) {
+	c.mu.Lock()
+	items := make(Items, 0, len(c.item))
+	for _, item := range c.item {
+		items = append(items, item)
+	}
+	c.mu.Unlock()
+	for _, item := range items {
+		if p, ok := item.downloadProgress(); ok {
+			progress = append(progress, p)
+		}
+	}
+	sort.Slice(progress, func(i, j int) bool {
+		return progress[i].Name < progress[j].Name
+	})
+	return progress
+}
+
+// DiskSize returns the number of bytes of name stored in the cache
+//
+// name should be a remote path not an osPath
+func (c *Cache) DiskSize(name string) int64 {
+	name = clean(name)
+	c.mu.Lock()
+	item := c.item[name]
+	c.mu.Unlock()
+	if item == nil {
+		return 0
+	}
+	return item.getDiskSize()
+}
+
+// LinkCount returns the number of items sharing the cache file of
+// name with --vfs-cache-dedup, or 1 if it isn't shared.
+//
+// The counts are updated each time the cache is cleaned so they stay
+// the same between cleans.
+//
+// name should be a remote path not an osPath
+func (c *Cache) LinkCount(name string) int {
+	name = clean(name)
+	c.mu.Lock()
+	item := c.item[name]
+	links := c.links
+	c.mu.Unlock()
+	if item == nil {
+		return 1
+	}
+	key := item.linked()
+	if key == "" {
+		return 1
+	}
+	return max(links[key], 1)
+}
+
 // DirtyItem returns the Item if it exists in the cache **and** is
 // dirty otherwise it returns nil.
 //
@@ -434,6 +658,29 @@ func (c *Cache) Rename(name string, newName string, newObj fs.Object) (err error
 	return nil
 }
 
+// conflictName returns an unused name for the local changes to name
+// with --vfs-dirty-conflict rename-local
+func (c *Cache) conflictName(ctx context.Context, name string) (string, error) {
+	dir, leaf := path.Split(name)
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		newName := path.Join(dir, vfscommon.ConflictName(leaf, now, i))
+		c.mu.Lock()
+		inCache := c.item[newName] != nil
+		c.mu.Unlock()
+		if inCache {
+			continue
+		}
+		_, err := c.fremote.NewObject(ctx, newName)
+		if errors.Is(err, fs.ErrorObjectNotFound) {
+			return newName, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("no free conflict name")
+}
+
 // DirExists checks to see if the directory exists in the cache or not.
 func (c *Cache) DirExists(name string) bool {
 	path := c.toOSPath(name)
@@ -495,6 +742,46 @@ func (c *Cache) Remove(name string) (wasWriting bool) {
 	return item.remove("file deleted")
 }
 
+// DiscardChanges removes name from the cache and the writeback queue,
+// losing any changes, if it isn't open.
+//
+// This returns true if the item was removed.
+func (c *Cache) DiscardChanges(name string) (discarded bool) {
+	name = clean(name)
+	c.mu.Lock()
+	item := c.item[name]
+	if item == nil || item.isOpen() {
+		c.mu.Unlock()
+		return false
+	}
+	delete(c.item, name)
+	c.mu.Unlock()
+	item.remove("local changes discarded")
+	return true
+}
+
+// DiscardScratch removes all the files changed with --vfs-no-write-back
+// from the cache, losing the changes.
+//
+// This should be called when the VFS is shut down.
+func (c *Cache) DiscardScratch() {
+	var items []*Item
+	c.mu.Lock()
+	for name, item := range c.item {
+		if item.isScratch() {
+			items = append(items, item)
+			delete(c.item, name)
+		}
+	}
+	c.mu.Unlock()
+	for _, item := range items {
+		item.remove("discarding changes made with --vfs-no-write-back")
+	}
+	if len(items) > 0 {
+		fs.Logf(c.fremote, "vfs cache: discarded changes to %d files made with --vfs-no-write-back", len(items))
+	}
+}
+
 // SetModTime should be called to set the modification time of the cache file
 func (c *Cache) SetModTime(name string, modTime time.Time) {
 	item, _ := c.get(name)
@@ -505,6 +792,9 @@ func (c *Cache) SetModTime(name string, modTime time.Time) {
 func (c *Cache) CleanUp() error {
 	err1 := os.RemoveAll(c.root)
 	err2 := os.RemoveAll(c.metaRoot)
+	if c.snapRoot != "" {
+		_ = os.RemoveAll(c.snapRoot)
+	}
 	if err1 != nil {
 		return err1
 	}
@@ -538,6 +828,7 @@ func (c *Cache) walk(dir string, fn func(osPath string, fi os.FileInfo, name str
 // to find any new items iterating the metadata but it will clear up
 // orphan files.
 func (c *Cache) reload(ctx context.Context) error {
+	var items []*Item
 	for _, dir := range []string{c.root, c.metaRoot} {
 		err := c.walk(dir, func(osPath string, fi os.FileInfo, name string) error {
 			if fi.IsDir() {
@@ -549,6 +840,7 @@ func (c *Cache) reload(ctx context.Context) error {
 				if err != nil {
 					fs.Errorf(name, "vfs cache: failed to reload item: %v", err)
 				}
+				items = append(items, item)
 			}
 			return nil
 		})
@@ -556,9 +848,27 @@ func (c *Cache) reload(ctx context.Context) error {
 			return fmt.Errorf("failed to walk cache %q: %w", dir, err)
 		}
 	}
+	if c.opt.CacheStartup == vfscommon.CacheStartupValidate {
+		c.validate(ctx, items)
+	}
 	return nil
 }
 
+// validate checks the reloaded items against the remote for
+// --vfs-cache-startup validate-and-reuse, using --checkers items at
+// once.
+func (c *Cache) validate(ctx context.Context, items []*Item) {
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fs.GetConfig(ctx).Checkers)
+	for _, item := range items {
+		g.Go(func() error {
+			item.validate(gCtx)
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
 // KickCleaner kicks cache cleaner upon out of space situation
 func (c *Cache) KickCleaner() {
 	/* Use a separate kicker mutex for the kick to go through without waiting for the
@@ -588,7 +898,19 @@ func (c *Cache) KickCleaner() {
 
 // removeNotInUse removes items not in use with a possible maxAge cutoff
 // called with cache mutex locked and up-to-date c.used (as we update it directly here)
-func (c *Cache) removeNotInUse(item *Item, maxAge time.Duration, emptyOnly bool) {
+//
+// If p is set the item isn't removed but is recorded in p if it would
+// have been.
+func (c *Cache) removeNotInUse(item *Item, maxAge time.Duration, emptyOnly bool, p *evictionPreview) {
+	if p != nil {
+		if p.evicted[item] {
+			return
+		}
+		if removed, spaceFreed := item.removeNotInUse(maxAge, emptyOnly, true); removed {
+			p.evict(item, spaceFreed)
+		}
+		return
+	}
 	removed, spaceFreed := item.RemoveNotInUse(maxAge, emptyOnly)
 	// The item space might be freed even if we get an error after the cache file is removed
 	// The item will not be removed or reset the cache data is dirty (DataDirty)
@@ -626,6 +948,33 @@ func (c *Cache) retryFailedResets() {
 	}
 }
 
+// sortForEviction sorts items so that the ones which should be evicted
+// first according to --vfs-cache-evict-policy come first.
+func (c *Cache) sortForEviction(items Items) {
+	if c.opt.CacheEvictPolicy == vfscommon.CacheEvictLRU {
+		sort.Sort(items)
+		return
+	}
+	// Read the keys once so the items don't need locking while sorting
+	type evictionKey struct {
+		score float64
+		aTime time.Time
+	}
+	now := time.Now()
+	keys := make(map[*Item]evictionKey, len(items))
+	for _, item := range items {
+		score, aTime := item.evictionKey(c.opt.CacheEvictPolicy, now)
+		keys[item] = evictionKey{score: score, aTime: aTime}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		ki, kj := keys[items[i]], keys[items[j]]
+		if ki.score != kj.score {
+			return ki.score < kj.score
+		}
+		return ki.aTime.Before(kj.aTime)
+	})
+}
+
 // Remove cache files that are not dirty until the quota is satisfied
 func (c *Cache) purgeClean() {
 	c.mu.Lock()
@@ -644,7 +993,7 @@ func (c *Cache) purgeClean() {
 		}
 	}
 
-	sort.Sort(items)
+	c.sortForEviction(items)
 
 	// Reset items until the quota is OK
 	for _, item := range items {
@@ -677,16 +1026,23 @@ func (c *Cache) purgeClean() {
 func (c *Cache) purgeOld(maxAge time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	// cutoff := time.Now().Add(-maxAge)
-	for _, item := range c.item {
-		c.removeNotInUse(item, maxAge, false)
-	}
+	c._purgeOld(maxAge, nil)
 	if c.quotasOK() {
 		c.outOfSpace = false
 		c.cond.Broadcast()
 	}
 }
 
+// _purgeOld gets rid of any files that are over age, or records them
+// in p if set
+//
+// call with c.mu held
+func (c *Cache) _purgeOld(maxAge time.Duration, p *evictionPreview) {
+	for _, item := range c.item {
+		c.removeNotInUse(item, c.maxAgeFor(item.GetName(), maxAge), false, p)
+	}
+}
+
 // Purge any empty directories
 func (c *Cache) purgeEmptyDirs(dir string, leaveRoot bool) {
 	ctx := context.Background()
@@ -706,16 +1062,26 @@ func (c *Cache) updateUsed() (used int64) {
 	defer c.mu.Unlock()
 
 	newUsed := int64(0)
+	shared := make(map[string]int)
 	for _, item := range c.item {
+		// Count cache files shared by --vfs-cache-dedup once
+		if key := item.linked(); key != "" {
+			shared[key]++
+			if shared[key] > 1 {
+				continue
+			}
+		}
 		newUsed += item.getDiskSize()
 	}
 	c.used = newUsed
+	c.links = shared
 	return newUsed
 }
 
 // Check the available space for a disk is in limits.
 func (c *Cache) minFreeSpaceQuotaOK() bool {
-	if c.opt.CacheMinFreeSpace <= 0 {
+	minFreeSpace := c.Limits().MinFreeSpace
+	if minFreeSpace <= 0 {
 		return true
 	}
 	du, err := diskusage.New(config.GetCacheDir())
@@ -726,17 +1092,18 @@ func (c *Cache) minFreeSpaceQuotaOK() bool {
 		fs.Errorf(c.fremote, "disk usage returned error: %v", err)
 		return true
 	}
-	return du.Available >= uint64(c.opt.CacheMinFreeSpace)
+	return du.Available >= uint64(minFreeSpace)
 }
 
 // Check the available quota for a disk is in limits.
 //
 // must be called with mu held.
 func (c *Cache) maxSizeQuotaOK() bool {
-	if c.opt.CacheMaxSize <= 0 {
+	maxSize := c.Limits().MaxSize
+	if maxSize <= 0 {
 		return true
 	}
-	return c.used <= int64(c.opt.CacheMaxSize)
+	return c.used <= int64(maxSize)
 }
 
 // Check the available quotas for a disk is in limits.
@@ -748,7 +1115,8 @@ func (c *Cache) quotasOK() bool {
 
 // Return true if any quotas set
 func (c *Cache) haveQuotas() bool {
-	return c.opt.CacheMaxSize > 0 || c.opt.CacheMinFreeSpace > 0
+	limits := c.Limits()
+	return limits.MaxSize > 0 || limits.MinFreeSpace > 0
 }
 
 // Remove clean cache files that are not open until the total space
@@ -762,6 +1130,22 @@ func (c *Cache) purgeOverQuota() {
 	if c.quotasOK() {
 		return
 	}
+	c._purgeOverQuota(nil)
+	if c.quotasOK() {
+		c.outOfSpace = false
+		c.cond.Broadcast()
+	}
+}
+
+// _purgeOverQuota removes clean cache files that are not open until
+// the quotas are OK, or records them in p if set
+//
+// call with c.mu held
+func (c *Cache) _purgeOverQuota(p *evictionPreview) {
+	quotasOK := c.quotasOK
+	if p != nil {
+		quotasOK = p.quotasOK
+	}
 
 	var items Items
 
@@ -772,15 +1156,11 @@ func (c *Cache) purgeOverQuota() {
 		}
 	}
 
-	sort.Sort(items)
+	c.sortForEviction(items)
 
 	// Remove items until the quota is OK
 	for _, item := range items {
-		c.removeNotInUse(item, 0, c.quotasOK())
-	}
-	if c.quotasOK() {
-		c.outOfSpace = false
-		c.cond.Broadcast()
+		c.removeNotInUse(item, 0, quotasOK(), p)
 	}
 }
 
@@ -797,7 +1177,12 @@ func (c *Cache) clean(kicked bool) {
 	c.mu.Unlock()
 
 	// Remove any files that are over age
-	c.purgeOld(time.Duration(c.opt.CacheMaxAge))
+	c.purgeOld(c.Limits().MaxAge)
+
+	// Share the cache files of items with identical content
+	if c.opt.CacheDedup {
+		c.dedup()
+	}
 
 	// If have a maximum cache size...
 	if c.haveQuotas() {
@@ -854,6 +1239,8 @@ func (c *Cache) cleaner(ctx context.Context) {
 		select {
 		case <-c.kick: // a thread encountering ENOSPC kicked me
 			c.clean(true) // kicked is true
+		case <-c.limitsChanged: // the limits were tightened
+			c.clean(false)
 		case <-timer.C:
 			c.clean(false) // timer driven cache poll, kicked is false
 		case <-ctx.Done():
@@ -864,11 +1251,14 @@ func (c *Cache) cleaner(ctx context.Context) {
 }
 
 // TotalInUse returns the number of items in the cache which are InUse
+//
+// Items which are only kept by --vfs-no-write-back aren't counted as
+// they will never be uploaded.
 func (c *Cache) TotalInUse() (n int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	for _, item := range c.item {
-		if item.inUse() {
+		if item.busy() {
 			n++
 		}
 	}
@@ -891,6 +1281,313 @@ func (c *Cache) Dump() string {
 	return out.String()
 }
 
+// GCResult describes what was removed by GC
+type GCResult struct {
+	Orphans     int   `json:"orphans"`     // number of untracked data files removed
+	MetaOrphans int   `json:"metaOrphans"` // number of untracked metadata files removed
+	TempFiles   int   `json:"tempFiles"`   // number of leftover temporary files removed
+	Bytes       int64 `json:"bytes"`       // total bytes reclaimed
+	Errors      int   `json:"errors"`      // number of files which couldn't be removed
+}
+
+// isTempFile returns true if name looks like a temporary file left
+// over from an interrupted transfer
+func isTempFile(name string) bool {
+	suffix := fs.GetConfig(context.Background()).PartialSuffix
+	return suffix != "" && strings.HasSuffix(name, suffix)
+}
+
+// GC garbage collects the cache directories.
+//
+// It removes any data or metadata files which don't correspond to an
+// item tracked by the cache, and any temporary files older than
+// --vfs-temp-file-timeout. Tracked items are never touched so this is
+// safe to call while the cache is in use.
+func (c *Cache) GC() (res GCResult, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cutoff := time.Now().Add(-time.Duration(c.opt.TempFileTimeout))
+	for _, dir := range []string{c.root, c.metaRoot} {
+		isMeta := dir == c.metaRoot
+		err = c.walk(dir, func(osPath string, fi os.FileInfo, name string) error {
+			if fi.IsDir() {
+				return nil
+			}
+			if _, found := c.item[name]; found {
+				return nil
+			}
+			isTemp := isTempFile(name)
+			if isTemp && fi.ModTime().After(cutoff) {
+				fs.Debugf(name, "vfs cache GC: leaving temporary file as it is too new")
+				return nil
+			}
+			if err := os.Remove(osPath); err != nil {
+				fs.Errorf(name, "vfs cache GC: failed to remove: %v", err)
+				res.Errors++
+				return nil
+			}
+			fs.Infof(name, "vfs cache GC: removed untracked file %q", osPath)
+			switch {
+			case isTemp:
+				res.TempFiles++
+			case isMeta:
+				res.MetaOrphans++
+			default:
+				res.Orphans++
+			}
+			res.Bytes += fi.Size()
+			return nil
+		})
+		if err != nil {
+			return res, fmt.Errorf("failed to walk cache %q: %w", dir, err)
+		}
+	}
+
+	c.purgeEmptyDirs("", true)
+	return res, nil
+}
+
+// EvictionCandidate describes an item which would be evicted from the cache
+type EvictionCandidate struct {
+	Name   string    `json:"name"`   // name of the item
+	Size   int64     `json:"size"`   // bytes used on disk by the item
+	ATime  time.Time `json:"atime"`  // time the item was last accessed
+	Reason string    `json:"reason"` // "age" or "quota"
+}
+
+// EvictionEntry describes an item in the cache and why it would or
+// wouldn't be evicted
+type EvictionEntry struct {
+	Name    string    `json:"name"`    // name of the item
+	Size    int64     `json:"size"`    // bytes used on disk by the item
+	ATime   time.Time `json:"atime"`   // time the item was last accessed
+	Expires time.Time `json:"expires"` // time the item is older than the max age
+	Score   float64   `json:"score"`   // --vfs-cache-evict-policy score, lower is evicted first
+	Status  string    `json:"status"`  // "expired", "evictable", "open" or "dirty"
+}
+
+// EvictionOrder returns all the items in the cache in the order the
+// cache cleaner would evict them with the current limits.
+//
+// Items older than the max age come first as they are evicted at the
+// next clean, then the items which are evicted in turn if the cache is
+// over quota according to --vfs-cache-evict-policy, then the items
+// which are open or dirty which are never evicted.
+func (c *Cache) EvictionOrder() (entries []EvictionEntry) {
+	maxAge := c.Limits().MaxAge
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	items := make(Items, 0, len(c.item))
+	for _, item := range c.item {
+		items = append(items, item)
+	}
+	c.sortForEviction(items)
+
+	now := time.Now()
+	var expired, inUse []EvictionEntry
+	for _, item := range items {
+		score, aTime := item.evictionKey(c.opt.CacheEvictPolicy, now)
+		entry := EvictionEntry{
+			Name:   item.GetName(),
+			Size:   item.getDiskSize(),
+			ATime:  aTime,
+			Score:  score,
+			Status: "evictable",
+		}
+		entry.Expires = aTime.Add(c.maxAgeFor(entry.Name, maxAge))
+		item.mu.Lock()
+		opens, dirty := item.opens, item.info.Dirty
+		item.mu.Unlock()
+		switch {
+		case opens != 0:
+			entry.Status = "open"
+			inUse = append(inUse, entry)
+		case dirty:
+			entry.Status = "dirty"
+			inUse = append(inUse, entry)
+		case !entry.Expires.After(now):
+			entry.Status = "expired"
+			expired = append(expired, entry)
+		default:
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(expired, entries...)
+	return append(entries, inUse...)
+}
+
+// evictionPreview records what the cache cleaner would evict with
+// limits without evicting anything
+type evictionPreview struct {
+	limits     Limits
+	used       int64  // bytes the cache would use after the evictions
+	available  int64  // bytes available on the cache disk or -1 if unknown
+	freed      int64  // bytes freed by the evictions
+	reason     string // why items are being evicted - "age" or "quota"
+	evicted    map[*Item]bool
+	candidates []EvictionCandidate
+}
+
+// evict records that item would be evicted freeing spaceFreed bytes
+func (p *evictionPreview) evict(item *Item, spaceFreed int64) {
+	p.evicted[item] = true
+	p.candidates = append(p.candidates, EvictionCandidate{
+		Name:   item.GetName(),
+		Size:   spaceFreed,
+		ATime:  item.getATime(),
+		Reason: p.reason,
+	})
+	p.used -= spaceFreed
+	p.freed += spaceFreed
+}
+
+// quotasOK returns true if the cache would be within the limits - see
+// Cache.quotasOK
+func (p *evictionPreview) quotasOK() bool {
+	if p.limits.MaxSize > 0 && p.used > int64(p.limits.MaxSize) {
+		return false
+	}
+	if p.limits.MinFreeSpace > 0 && p.available >= 0 && p.available+p.freed < int64(p.limits.MinFreeSpace) {
+		return false
+	}
+	return true
+}
+
+// EvictionPreview returns the items which would be evicted from the
+// cache if it was cleaned with limits, in the order they would be
+// evicted. A MaxSize or MinFreeSpace of 0 means no limit.
+//
+// It runs the same purges as the cache cleaner but doesn't remove
+// anything. Items which are open or dirty are never evicted so are
+// never returned.
+func (c *Cache) EvictionPreview(limits Limits) []EvictionCandidate {
+	c.updateUsed()
+
+	p := &evictionPreview{
+		limits:    limits,
+		available: -1,
+		evicted:   map[*Item]bool{},
+	}
+	if limits.MinFreeSpace > 0 {
+		du, err := diskusage.New(config.GetCacheDir())
+		if err == nil {
+			p.available = int64(du.Available)
+		} else if err != diskusage.ErrUnsupported {
+			fs.Errorf(c.fremote, "disk usage returned error: %v", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p.used = c.used
+
+	// The same purges as clean
+	p.reason = "age"
+	c._purgeOld(limits.MaxAge, p)
+	if (limits.MaxSize > 0 || limits.MinFreeSpace > 0) && !p.quotasOK() {
+		p.reason = "quota"
+		c._purgeOverQuota(p)
+	}
+
+	// Items over age are found in no particular order so show the
+	// oldest first
+	sort.SliceStable(p.candidates, func(i, j int) bool {
+		ci, cj := p.candidates[i], p.candidates[j]
+		if ci.Reason != cj.Reason {
+			return ci.Reason == "age"
+		}
+		return ci.Reason == "age" && ci.ATime.Before(cj.ATime)
+	})
+	return p.candidates
+}
+
+// ErrNoSpace is returned by CheckSpace if there isn't room in the
+// cache for an item
+var ErrNoSpace = errors.New("not enough space in the VFS cache")
+
+// CheckSpace checks there is room to download an object of size bytes
+// into the cache as name.
+//
+// It counts the space used by name already and the space which could
+// be freed by evicting items which aren't in use, and checks the
+// result is within --vfs-cache-max-size and leaves at least
+// --vfs-cache-min-free-space free on the cache disk. If not, it
+// returns an error wrapping ErrNoSpace.
+//
+// name should be a remote path not an osPath
+func (c *Cache) CheckSpace(name string, size int64) error {
+	name = clean(name)
+	c.mu.Lock()
+	used, evictable := int64(0), int64(0)
+	for itemName, item := range c.item {
+		diskSize := item.getDiskSize()
+		used += diskSize
+		if itemName == name {
+			// space already used by this item counts towards it
+			size -= diskSize
+		} else if !item.inUse() {
+			evictable += diskSize
+		}
+	}
+	c.mu.Unlock()
+	if size <= 0 {
+		return nil
+	}
+
+	limits := c.Limits()
+	if maxSize := int64(limits.MaxSize); maxSize > 0 && used-evictable+size > maxSize {
+		return fmt.Errorf("%w: need %v but only %v can be made available within --vfs-cache-max-size %v",
+			ErrNoSpace, fs.SizeSuffix(size), fs.SizeSuffix(max(maxSize-used+evictable, 0)), limits.MaxSize)
+	}
+
+	du, err := diskusage.New(config.GetCacheDir())
+	if err == diskusage.ErrUnsupported {
+		return nil
+	} else if err != nil {
+		fs.Errorf(c.fremote, "disk usage returned error: %v", err)
+		return nil
+	}
+	minFreeSpace := int64(max(limits.MinFreeSpace, 0))
+	if available := int64(du.Available) + evictable - minFreeSpace; size > available {
+		return fmt.Errorf("%w: need %v but only %v can be made available on the cache disk with --vfs-cache-min-free-space %v",
+			ErrNoSpace, fs.SizeSuffix(size), fs.SizeSuffix(max(available, 0)), limits.MinFreeSpace)
+	}
+	return nil
+}
+
+// WarnLargeFile logs a warning if a file called name of size is
+// bigger than --vfs-cache-large-file-warn-threshold of
+// --vfs-cache-max-size as reading it will push most of the other files
+// out of the cache. It warns about each file once.
+//
+// It returns true if the file is that big.
+func (c *Cache) WarnLargeFile(name string, size int64) bool {
+	threshold := c.opt.CacheLargeFileWarnThreshold
+	maxSize := c.Limits().MaxSize
+	if threshold <= 0 || maxSize <= 0 {
+		return false
+	}
+	if float64(size) <= threshold*float64(maxSize) {
+		return false
+	}
+	name = clean(name)
+	c.mu.Lock()
+	warned := c.largeFiles[name]
+	if !warned {
+		if c.largeFiles == nil {
+			c.largeFiles = make(map[string]bool)
+		}
+		c.largeFiles[name] = true
+	}
+	c.mu.Unlock()
+	if !warned {
+		fs.Logf(name, "vfs cache: file size %v is more than %g of --vfs-cache-max-size %v so reading it will evict most of the cache", fs.SizeSuffix(size), threshold, maxSize)
+	}
+	return true
+}
+
 // AddVirtual adds a virtual directory entry by calling the addVirtual
 // callback if one has been registered.
 func (c *Cache) AddVirtual(remote string, size int64, isDir bool) error {
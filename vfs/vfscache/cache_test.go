@@ -3,6 +3,7 @@ package vfscache
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -52,7 +53,7 @@ func itemSpaceAsString(c *Cache) []string {
 
 // open an item and write to it
 func itemWrite(t *testing.T, item *Item, contents string) {
-	require.NoError(t, item.Open(nil))
+	require.NoError(t, item.Open(context.Background(), nil))
 	_, err := item.WriteAt([]byte(contents), 0)
 	require.NoError(t, err)
 }
@@ -143,7 +144,7 @@ func TestCacheNew(t *testing.T) {
 		`name="potato" opens=0 size=0`,
 	}, itemAsString(c))
 	potato := c.Item("/potato")
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	assert.Equal(t, []string{
 		`name="potato" opens=1 size=0`,
 	}, itemAsString(c))
@@ -197,11 +198,11 @@ func TestCacheOpens(t *testing.T) {
 
 	assert.Equal(t, []string(nil), itemAsString(c))
 	potato := c.Item("potato")
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	assert.Equal(t, []string{
 		`name="potato" opens=1 size=0`,
 	}, itemAsString(c))
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	assert.Equal(t, []string{
 		`name="potato" opens=2 size=0`,
 	}, itemAsString(c))
@@ -214,13 +215,13 @@ func TestCacheOpens(t *testing.T) {
 		`name="potato" opens=0 size=0`,
 	}, itemAsString(c))
 
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	a1 := c.Item("a//b/c/d/one")
 	a2 := c.Item("a/b/c/d/e/two")
 	a3 := c.Item("a/b/c/d/e/f/three")
-	require.NoError(t, a1.Open(nil))
-	require.NoError(t, a2.Open(nil))
-	require.NoError(t, a3.Open(nil))
+	require.NoError(t, a1.Open(context.Background(), nil))
+	require.NoError(t, a2.Open(context.Background(), nil))
+	require.NoError(t, a3.Open(context.Background(), nil))
 	assert.Equal(t, []string{
 		`name="a/b/c/d/e/f/three" opens=1 size=0`,
 		`name="a/b/c/d/e/two" opens=1 size=0`,
@@ -245,7 +246,7 @@ func TestCacheOpenMkdir(t *testing.T) {
 
 	// open
 	potato := c.Item("sub/potato")
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 
 	assert.Equal(t, []string{
 		`name="sub/potato" opens=1 size=0`,
@@ -294,11 +295,11 @@ func TestCachePurgeOld(t *testing.T) {
 	c.purgeOld(-10 * time.Second)
 
 	potato2 := c.Item("sub/dir2/potato2")
-	require.NoError(t, potato2.Open(nil))
+	require.NoError(t, potato2.Open(context.Background(), nil))
 	potato := c.Item("sub/dir/potato")
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	require.NoError(t, potato2.Close(nil))
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 
 	assert.Equal(t, []string{
 		`name="sub/dir/potato" opens=2 size=0`,
@@ -388,7 +389,7 @@ func TestCachePurgeOverQuota(t *testing.T) {
 
 	// Put potato back
 	potato = c.Item("sub/dir/potato")
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	require.NoError(t, potato.Truncate(5))
 	require.NoError(t, potato.Close(nil))
 
@@ -455,8 +456,8 @@ func TestCachePurgeClean(t *testing.T) {
 	_ = contents
 
 	// Open the object to create metadata for it
-	require.NoError(t, potato1.Open(obj))
-	require.NoError(t, potato1.Open(obj))
+	require.NoError(t, potato1.Open(context.Background(), obj))
+	require.NoError(t, potato1.Open(context.Background(), obj))
 
 	size, err := potato1.GetSize()
 	require.NoError(t, err)
@@ -473,11 +474,11 @@ func TestCachePurgeClean(t *testing.T) {
 
 	// Add some potatoes
 	potato2 := c.Item("sub/dir/potato2")
-	require.NoError(t, potato2.Open(nil))
+	require.NoError(t, potato2.Open(context.Background(), nil))
 	require.NoError(t, potato2.Truncate(5))
 
 	potato3 := c.Item("sub/dir/potato3")
-	require.NoError(t, potato3.Open(nil))
+	require.NoError(t, potato3.Open(context.Background(), nil))
 	require.NoError(t, potato3.Truncate(6))
 
 	c.updateUsed()
@@ -523,7 +524,7 @@ func TestCacheInUse(t *testing.T) {
 
 	assert.False(t, c.InUse("potato"))
 
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 
 	assert.True(t, c.InUse("potato"))
 
@@ -541,7 +542,7 @@ func TestCacheDirtyItem(t *testing.T) {
 
 	assert.Nil(t, c.DirtyItem("potato"))
 
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	require.NoError(t, potato.Truncate(5))
 
 	assert.Equal(t, potato, c.DirtyItem("potato"))
@@ -560,7 +561,7 @@ func TestCacheExistsAndRemove(t *testing.T) {
 
 	assert.False(t, c.Exists("potato"))
 
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 
 	assert.True(t, c.Exists("potato"))
 
@@ -581,7 +582,7 @@ func TestCacheRename(t *testing.T) {
 
 	assert.False(t, c.Exists("potato"))
 	potato := c.Item("potato")
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	require.NoError(t, potato.Close(nil))
 	assert.True(t, c.Exists("potato"))
 
@@ -658,7 +659,7 @@ func TestCacheSetModTime(t *testing.T) {
 	t1 := time.Date(2010, 1, 2, 3, 4, 5, 9, time.UTC)
 
 	potato := c.Item("potato")
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	require.NoError(t, potato.Truncate(5))
 	require.NoError(t, potato.Close(nil))
 
@@ -678,7 +679,7 @@ func TestCacheTotaInUse(t *testing.T) {
 	potato := c.Item("potato")
 	assert.Equal(t, int(0), c.TotalInUse())
 
-	require.NoError(t, potato.Open(nil))
+	require.NoError(t, potato.Open(context.Background(), nil))
 	assert.Equal(t, int(1), c.TotalInUse())
 
 	require.NoError(t, potato.Truncate(5))
@@ -687,7 +688,7 @@ func TestCacheTotaInUse(t *testing.T) {
 	potato2 := c.Item("potato2")
 	assert.Equal(t, int(1), c.TotalInUse())
 
-	require.NoError(t, potato2.Open(nil))
+	require.NoError(t, potato2.Open(context.Background(), nil))
 	assert.Equal(t, int(2), c.TotalInUse())
 
 	require.NoError(t, potato2.Close(nil))
@@ -723,6 +724,8 @@ func TestCacheStats(t *testing.T) {
 
 	out := c.Stats()
 	assert.Equal(t, int64(0), out["bytesUsed"])
+	assert.Equal(t, 0, out["dirtyFiles"])
+	assert.Equal(t, 0, out["largeFiles"])
 	assert.Equal(t, 0, out["erroredFiles"])
 	assert.Equal(t, 0, out["files"])
 	assert.Equal(t, 0, out["uploadsInProgress"])
@@ -751,3 +754,469 @@ func TestCacheQueueSetExpiry(t *testing.T) {
 	err := c.QueueSetExpiry(123123, time.Now(), 0)
 	assert.Equal(t, writeback.ErrorIDNotFound, err)
 }
+
+func TestCacheGC(t *testing.T) {
+	_, c := newTestCache(t)
+
+	// A tracked item which must be left alone
+	item, _ := c.get("tracked")
+	itemWrite(t, item, "hello")
+	require.NoError(t, item.Close(nil))
+	trackedPath := c.toOSPath("tracked")
+	assertPathExist(t, trackedPath)
+
+	// Untracked data and metadata files
+	writeFile := func(osPath string, contents string, modTime time.Time) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(osPath), 0700))
+		require.NoError(t, os.WriteFile(osPath, []byte(contents), 0600))
+		require.NoError(t, os.Chtimes(osPath, modTime, modTime))
+	}
+	now := time.Now()
+	orphan := c.toOSPath("dir/orphan")
+	writeFile(orphan, "orphan", now)
+	metaOrphan := c.toOSPathMeta("metaorphan")
+	writeFile(metaOrphan, "{}", now)
+	oldTemp := c.toOSPath("old.partial")
+	writeFile(oldTemp, "old", now.Add(-2*time.Duration(c.opt.TempFileTimeout)))
+	newTemp := c.toOSPath("new.partial")
+	writeFile(newTemp, "new", now)
+
+	res, err := c.GC()
+	require.NoError(t, err)
+	assert.Equal(t, GCResult{
+		Orphans:     1,
+		MetaOrphans: 1,
+		TempFiles:   1,
+		Bytes:       int64(len("orphan") + len("{}") + len("old")),
+	}, res)
+
+	assertPathExist(t, trackedPath)
+	assertPathExist(t, c.toOSPathMeta("tracked"))
+	assertPathNotExist(t, orphan)
+	assertPathNotExist(t, filepath.Dir(orphan))
+	assertPathNotExist(t, metaOrphan)
+	assertPathNotExist(t, oldTemp)
+	assertPathExist(t, newTemp)
+	assert.Equal(t, []string{`name="tracked" opens=0 size=5`}, itemAsString(c))
+}
+
+func TestCacheParseRevalidate(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{in: "", want: map[string]time.Duration{}},
+		{in: "log=1m", want: map[string]time.Duration{"log": time.Minute}},
+		{in: " .LOG = 1m , txt=2h", want: map[string]time.Duration{"log": time.Minute, "txt": 2 * time.Hour}},
+		{in: "log", wantErr: true},
+		{in: "=1m", wantErr: true},
+		{in: "log=potato", wantErr: true},
+	} {
+		got, err := parseRevalidate(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+			assert.Equal(t, test.want, got, test.in)
+		}
+	}
+}
+
+func TestCacheRevalidateTTL(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.CacheRevalidate = "log=1m,TXT=1h"
+	_, c := newTestCacheOpt(t, opt)
+
+	assert.Equal(t, time.Minute, c.revalidateTTL("dir/file.log"))
+	assert.Equal(t, time.Hour, c.revalidateTTL("file.txt"))
+	assert.Equal(t, time.Hour, c.revalidateTTL("FILE.TXT"))
+	assert.Equal(t, time.Duration(0), c.revalidateTTL("file.jpg"))
+	assert.Equal(t, time.Duration(0), c.revalidateTTL("log"))
+}
+
+func TestCacheSortForEviction(t *testing.T) {
+	_, c := newTestCache(t)
+
+	now := time.Now()
+	newItem := func(name string, accesses int64, age time.Duration) *Item {
+		item, _ := c.get(name)
+		item.info.Accesses = accesses
+		item.info.ATime = now.Add(-age)
+		return item
+	}
+	// recent but rarely used
+	recent := newItem("recent", 1, time.Minute)
+	// popular but not accessed for a while
+	popular := newItem("popular", 100, 2*time.Hour)
+	// old and rarely used
+	old := newItem("old", 2, 10*time.Hour)
+
+	names := func(items Items) (out []string) {
+		for _, item := range items {
+			out = append(out, item.name)
+		}
+		return out
+	}
+
+	for _, test := range []struct {
+		policy vfscommon.CacheEvictPolicy
+		want   []string
+	}{
+		{policy: vfscommon.CacheEvictLRU, want: []string{"old", "popular", "recent"}},
+		{policy: vfscommon.CacheEvictLFU, want: []string{"recent", "old", "popular"}},
+		{policy: vfscommon.CacheEvictLRFU, want: []string{"old", "recent", "popular"}},
+	} {
+		c.opt.CacheEvictPolicy = test.policy
+		items := Items{recent, popular, old}
+		c.sortForEviction(items)
+		assert.Equal(t, test.want, names(items), test.policy.String())
+	}
+}
+
+func TestCacheEvictionPreview(t *testing.T) {
+	_, c := newTestCache(t)
+
+	potato := c.Item("sub/dir/potato")
+	itemWrite(t, potato, "hello")
+	potato2 := c.Item("sub/dir2/potato2")
+	itemWrite(t, potato2, "hello2")
+	open := c.Item("open")
+	itemWrite(t, open, "open")
+	require.NoError(t, potato.Close(nil))
+	require.NoError(t, potato2.Close(nil))
+
+	now := time.Now()
+	potato.info.ATime = now.Add(-2 * time.Hour)
+	potato2.info.ATime = now.Add(-time.Minute)
+	open.info.ATime = now.Add(-3 * time.Hour)
+
+	names := func(candidates []EvictionCandidate) (out []string) {
+		for _, candidate := range candidates {
+			out = append(out, candidate.Name+":"+candidate.Reason)
+		}
+		return out
+	}
+
+	preview := func(maxSize fs.SizeSuffix, maxAge time.Duration) []string {
+		return names(c.EvictionPreview(Limits{MaxSize: maxSize, MaxAge: maxAge}))
+	}
+
+	// No limits - nothing evicted
+	assert.Equal(t, []string(nil), preview(0, 24*time.Hour))
+
+	// Only potato is over age - open file never evicted
+	assert.Equal(t, []string{"sub/dir/potato:age"}, preview(0, time.Hour))
+
+	// Oldest first until under quota
+	assert.Equal(t, []string{"sub/dir/potato:quota"}, preview(10, 24*time.Hour))
+	assert.Equal(t, []string{"sub/dir/potato:quota", "sub/dir2/potato2:quota"}, preview(1, 24*time.Hour))
+
+	// Age and quota together
+	limits := Limits{MaxSize: 5, MaxAge: time.Hour}
+	candidates := c.EvictionPreview(limits)
+	assert.Equal(t, []string{"sub/dir/potato:age", "sub/dir2/potato2:quota"}, names(candidates))
+	assert.Equal(t, int64(5), candidates[0].Size)
+	assert.Equal(t, int64(6), candidates[1].Size)
+
+	// Check nothing was actually removed
+	assert.Equal(t, []string{
+		`name="open" opens=1 size=4`,
+		`name="sub/dir/potato" opens=0 size=5`,
+		`name="sub/dir2/potato2" opens=0 size=6`,
+	}, itemAsString(c))
+	assertPathExist(t, c.toOSPath("sub/dir/potato"))
+
+	// Check the cleaner removes just what the preview said it would
+	require.NoError(t, c.SetLimits(limits))
+	c.purgeOld(limits.MaxAge)
+	c.purgeOverQuota()
+	assert.Equal(t, []string{
+		`name="open" opens=1 size=4`,
+	}, itemAsString(c))
+	assertPathNotExist(t, c.toOSPath("sub/dir/potato"))
+	assertPathNotExist(t, c.toOSPath("sub/dir2/potato2"))
+	require.NoError(t, open.Close(nil))
+}
+
+func TestCacheEvictionOrder(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.WriteBack = 0
+	opt.CacheMaxAge = fs.Duration(time.Hour)
+	_, c := newTestCacheOpt(t, opt)
+
+	assert.Empty(t, c.EvictionOrder())
+
+	potato := c.Item("sub/dir/potato")
+	itemWrite(t, potato, "hello")
+	potato2 := c.Item("sub/dir2/potato2")
+	itemWrite(t, potato2, "hello2")
+	potato3 := c.Item("potato3")
+	itemWrite(t, potato3, "hello3!")
+	open := c.Item("open")
+	itemWrite(t, open, "open")
+	require.NoError(t, potato.Close(nil))
+	require.NoError(t, potato2.Close(nil))
+	require.NoError(t, potato3.Close(nil))
+
+	now := time.Now()
+	potato.info.ATime = now.Add(-2 * time.Hour)
+	potato2.info.ATime = now.Add(-time.Minute)
+	potato3.info.ATime = now.Add(-2 * time.Minute)
+	open.info.ATime = now.Add(-3 * time.Hour)
+
+	entries := c.EvictionOrder()
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name+":"+entry.Status)
+	}
+	assert.Equal(t, []string{
+		"sub/dir/potato:expired",
+		"potato3:evictable",
+		"sub/dir2/potato2:evictable",
+		"open:open",
+	}, got)
+	assert.Equal(t, int64(5), entries[0].Size)
+	assert.True(t, potato.info.ATime.Equal(entries[0].ATime))
+	assert.True(t, potato.info.ATime.Add(time.Hour).Equal(entries[0].Expires))
+	assert.Equal(t, float64(0), entries[0].Score)
+
+	require.NoError(t, open.Close(nil))
+}
+
+func TestCacheCheckSpace(t *testing.T) {
+	_, c := newTestCache(t)
+
+	potato := c.Item("sub/dir/potato")
+	itemWrite(t, potato, "hello")
+	potato2 := c.Item("sub/dir2/potato2")
+	itemWrite(t, potato2, "hello2")
+	open := c.Item("open")
+	itemWrite(t, open, "open")
+	require.NoError(t, potato.Close(nil))
+	require.NoError(t, potato2.Close(nil))
+
+	// No quota so only the disk space is checked
+	assert.NoError(t, c.CheckSpace("new", 16))
+	err := c.CheckSpace("new", 1<<60)
+	assert.ErrorIs(t, err, ErrNoSpace)
+	assert.ErrorContains(t, err, "cache disk")
+
+	// Everything but the open item can be evicted
+	c.opt.CacheMaxSize = 20
+	assert.NoError(t, c.CheckSpace("new", 16))
+	err = c.CheckSpace("new", 17)
+	assert.ErrorIs(t, err, ErrNoSpace)
+	assert.ErrorContains(t, err, "--vfs-cache-max-size")
+
+	// Space already used by the item counts towards it
+	assert.NoError(t, c.CheckSpace("sub/dir/potato", 16))
+	assert.ErrorIs(t, c.CheckSpace("sub/dir/potato", 17), ErrNoSpace)
+
+	// Check nothing was removed
+	assert.Equal(t, []string{
+		`name="open" opens=1 size=4`,
+		`name="sub/dir/potato" opens=0 size=5`,
+		`name="sub/dir2/potato2" opens=0 size=6`,
+	}, itemAsString(c))
+	require.NoError(t, open.Close(nil))
+}
+
+func TestCacheDedup(t *testing.T) {
+	r, c := newTestCache(t)
+	ctx := context.Background()
+
+	// cache the remote file name with contents
+	cacheFile := func(name, contents string) *Item {
+		r.WriteObject(ctx, name, contents, time.Now())
+		obj, err := r.Fremote.NewObject(ctx, name)
+		require.NoError(t, err)
+		item := c.Item(name)
+		require.NoError(t, item.Open(context.Background(), obj))
+		buf := make([]byte, len(contents))
+		_, err = item.ReadAt(buf, 0)
+		require.NoError(t, err)
+		require.NoError(t, item.Close(nil))
+		return item
+	}
+	stat := func(item *Item) os.FileInfo {
+		return assertPathExist(t, c.toOSPath(item.name))
+	}
+	read := func(item *Item) string {
+		buf, err := os.ReadFile(c.toOSPath(item.name))
+		require.NoError(t, err)
+		return string(buf)
+	}
+
+	contents := "identical contents"
+	a := cacheFile("a", contents)
+	b := cacheFile("dir/b", contents)
+	d := cacheFile("d", contents)
+	other := cacheFile("other", "different contents")
+	assert.Equal(t, int64(3*len(contents)+len("different contents")), c.updateUsed())
+
+	c.dedup()
+	assert.True(t, os.SameFile(stat(a), stat(b)))
+	assert.True(t, os.SameFile(stat(a), stat(d)))
+	assert.False(t, os.SameFile(stat(a), stat(other)))
+	assert.True(t, a.info.Linked)
+	assert.False(t, other.info.Linked)
+	assert.Equal(t, int64(len(contents)+len("different contents")), c.updateUsed())
+	assert.Equal(t, 3, c.LinkCount("a"))
+	assert.Equal(t, 3, c.LinkCount("dir/b"))
+	assert.Equal(t, 1, c.LinkCount("other"))
+	assert.Equal(t, 1, c.LinkCount("not/cached"))
+
+	// Running again changes nothing
+	c.dedup()
+	assert.True(t, os.SameFile(stat(a), stat(b)))
+
+	// Writing to an item gives it its own copy
+	obj, err := r.Fremote.NewObject(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, a.Open(context.Background(), obj))
+	_, err = a.WriteAt([]byte("HELLO"), 0)
+	require.NoError(t, err)
+	require.NoError(t, a.Close(nil))
+	assert.False(t, os.SameFile(stat(a), stat(d)))
+	assert.Equal(t, "HELLO"+contents[5:], read(a))
+	assert.Equal(t, contents, read(d))
+	assert.Equal(t, 1, c.LinkCount("a"))
+	c.updateUsed()
+	assert.Equal(t, 2, c.LinkCount("d"))
+
+	// A changed remote object is downloaded afresh
+	r.WriteObject(ctx, "dir/b", "new contents", time.Now())
+	obj, err = r.Fremote.NewObject(ctx, "dir/b")
+	require.NoError(t, err)
+	require.NoError(t, b.Open(context.Background(), obj))
+	buf := make([]byte, len("new contents"))
+	_, err = b.ReadAt(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "new contents", string(buf))
+	require.NoError(t, b.Close(nil))
+	assert.False(t, os.SameFile(stat(b), stat(d)))
+	assert.Equal(t, contents, read(d))
+}
+
+func TestCacheOpenCached(t *testing.T) {
+	r, c := newTestCache(t)
+	ctx := context.Background()
+	cacheDir := config.GetCacheDir()
+	t1 := time.Date(2010, 1, 2, 3, 4, 5, 9, time.UTC)
+	t2 := t1.Add(time.Hour)
+
+	contents := "cached contents"
+	r.WriteObject(ctx, "dir/file", contents, t1)
+	obj, err := r.Fremote.NewObject(ctx, "dir/file")
+	require.NoError(t, err)
+
+	read := func(options ...fs.OpenOption) (string, bool) {
+		in, err := OpenCached(ctx, cacheDir, obj, options...)
+		require.NoError(t, err)
+		if in == nil {
+			return "", false
+		}
+		buf, err := io.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return string(buf), true
+	}
+
+	// Not in the cache
+	_, ok := read()
+	assert.False(t, ok)
+
+	// Partially downloaded
+	item := c.Item("dir/file")
+	require.NoError(t, item.Open(context.Background(), obj))
+	buf := make([]byte, 6)
+	_, err = item.ReadAt(buf, 0)
+	require.NoError(t, err)
+	_, ok = read()
+	assert.False(t, ok)
+
+	// Fully downloaded
+	buf = make([]byte, len(contents))
+	_, err = item.ReadAt(buf, 0)
+	require.NoError(t, err)
+	require.NoError(t, item.Close(nil))
+	got, ok := read()
+	assert.True(t, ok)
+	assert.Equal(t, contents, got)
+	got, ok = read(&fs.RangeOption{Start: 7, End: 9})
+	assert.True(t, ok)
+	assert.Equal(t, "con", got)
+	got, ok = read(&fs.SeekOption{Offset: 7})
+	assert.True(t, ok)
+	assert.Equal(t, "contents", got)
+
+	// Changed on the remote
+	r.WriteObject(ctx, "dir/file", "changed contents", t2)
+	obj, err = r.Fremote.NewObject(ctx, "dir/file")
+	require.NoError(t, err)
+	_, ok = read()
+	assert.False(t, ok)
+}
+
+func TestCacheStartup(t *testing.T) {
+	r, c := newTestCache(t)
+	ctx := context.Background()
+	t1 := time.Date(2010, 1, 2, 3, 4, 5, 9, time.UTC)
+	t2 := t1.Add(time.Hour)
+
+	// Fill the cache with some files
+	for _, name := range []string{"same", "changed", "deleted"} {
+		r.WriteObject(ctx, name, name+" contents", t1)
+		obj, err := r.Fremote.NewObject(ctx, name)
+		require.NoError(t, err)
+		item := c.Item(name)
+		require.NoError(t, item.Open(context.Background(), obj))
+		buf := make([]byte, obj.Size())
+		_, err = item.ReadAt(buf, 0)
+		require.NoError(t, err)
+		require.NoError(t, item.Close(nil))
+	}
+
+	// Leave a file with changes waiting to upload
+	item := c.Item("dirty")
+	require.NoError(t, item.Open(context.Background(), nil))
+	_, err := item.WriteAt([]byte("dirty contents"), 0)
+	require.NoError(t, err)
+	item.mu.Lock()
+	require.NoError(t, item._save())
+	require.NoError(t, item.fd.Close())
+	item.fd = nil
+	item.mu.Unlock()
+
+	// Change the remote underneath the cache
+	r.WriteObject(ctx, "changed", "changed contents!", t2)
+	obj, err := r.Fremote.NewObject(ctx, "deleted")
+	require.NoError(t, err)
+	require.NoError(t, obj.Remove(ctx))
+
+	// Start a new cache in the same directory and return the
+	// names of the files it has cached
+	restart := func(startup vfscommon.CacheStartup) (names []string) {
+		opt := vfscommon.Opt
+		opt.CachePollInterval = 0
+		opt.WriteBack = fs.Duration(time.Hour)
+		opt.CacheStartup = startup
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		c2, err := New(ctx, r.Fremote, &opt, addVirtual)
+		require.NoError(t, err)
+		for _, name := range []string{"same", "changed", "deleted", "dirty"} {
+			if c2.Exists(name) {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"same", "changed", "deleted", "dirty"}, restart(vfscommon.CacheStartupReuse))
+	assert.Equal(t, []string{"same", "dirty"}, restart(vfscommon.CacheStartupValidate))
+	assert.Equal(t, []string{"dirty"}, restart(vfscommon.CacheStartupClear))
+}
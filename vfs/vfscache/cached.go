@@ -0,0 +1,94 @@
+package vfscache
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/file"
+	"github.com/rclone/rclone/lib/ranges"
+	"github.com/rclone/rclone/lib/readers"
+)
+
+// OpenCached opens the copy of o in the VFS cache under cacheDir for
+// reading if there is an up to date copy of all of it there. This
+// lets other users of the remote read data a VFS has downloaded
+// already without downloading it again.
+//
+// cacheDir should be the --cache-dir of the VFS. The copy is found
+// using the name and root of the Fs o is on, so the VFS must be
+// serving the same remote as o, or a parent directory of it.
+//
+// The copy is only used if it isn't dirty and its fingerprint matches
+// o. The VFS could start changing the copy while it is being read,
+// so this is only safe to use if the file isn't being written through
+// the VFS.
+//
+// It returns a nil reader and no error if the copy can't be used.
+func OpenCached(ctx context.Context, cacheDir string, o fs.Object, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	name := path.Join(cacheRelativeDirPath(o.Fs()), o.Remote())
+	osPath := file.UNCPath(filepath.Join(cacheDir, "vfs", toOSPath(name)))
+	osPathMeta := file.UNCPath(filepath.Join(cacheDir, "vfsMeta", toOSPath(name)))
+
+	// Read the metadata for the copy
+	meta, err := os.Open(osPathMeta)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var info Info
+	err = json.NewDecoder(meta).Decode(&info)
+	_ = meta.Close()
+	if err != nil {
+		fs.Debugf(o, "vfs cache: ignoring corrupt metadata in %q: %v", osPathMeta, err)
+		return nil, nil
+	}
+
+	// Check the copy is complete and up to date
+	size := o.Size()
+	if info.Dirty || size < 0 || info.Size != size || !info.Rs.Present(ranges.Range{Pos: 0, Size: size}) {
+		return nil, nil
+	}
+	if info.Fingerprint != fs.Fingerprint(ctx, o, true) && info.Fingerprint != fs.Fingerprint(ctx, o, false) {
+		fs.Debugf(o, "vfs cache: not using stale copy (cached fingerprint %q)", info.Fingerprint)
+		return nil, nil
+	}
+
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if limit < 0 {
+		limit = size - offset
+	}
+
+	fd, err := file.Open(osPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if offset != 0 {
+		_, err = fd.Seek(offset, io.SeekStart)
+		if err != nil {
+			_ = fd.Close()
+			return nil, err
+		}
+	}
+	fs.Debugf(o, "vfs cache: reading copy from %q", osPath)
+	return readers.NewLimitedReadCloser(fd, limit), nil
+}
@@ -0,0 +1,244 @@
+package vfscache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/file"
+)
+
+// _dedupable returns true if the item's cache file can be shared with
+// other items with the same content
+//
+// The item must be complete, clean and not in use so nothing writes
+// to the cache file while it is being shared.
+//
+// call with the lock held
+func (item *Item) _dedupable() bool {
+	return item.opens == 0 && item.pendingAccesses == 0 && !item.info.Dirty &&
+		item.info.Fingerprint != "" && item.info.Size > 0 && item._present()
+}
+
+// hashFile returns the SHA-256 of the file at osPath as a hex string
+func hashFile(osPath string) (hash string, err error) {
+	in, err := file.Open(osPath)
+	if err != nil {
+		return "", err
+	}
+	defer fs.CheckClose(in, &err)
+	h := sha256.New()
+	_, err = io.Copy(h, in)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dedupKey returns the key used to find other items with the same
+// content, hashing the cache file if necessary.
+//
+// It returns "" if the item can't be shared at the moment.
+func (item *Item) dedupKey() string {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if !item._dedupable() {
+		return ""
+	}
+	if item.info.Hash == "" {
+		hash, err := hashFile(item.c.toOSPath(item.name)) // No locking in Cache
+		if err != nil {
+			fs.Errorf(item.name, "vfs cache: dedup: failed to hash cache file: %v", err)
+			return ""
+		}
+		item.info.Hash = hash
+		err = item._save()
+		if err != nil {
+			fs.Errorf(item.name, "vfs cache: dedup: failed to save item info: %v", err)
+		}
+	}
+	return item._dedupKey()
+}
+
+// _dedupKey returns the key for the content hashed already
+//
+// call with the lock held
+func (item *Item) _dedupKey() string {
+	if item.info.Hash == "" {
+		return ""
+	}
+	return fmt.Sprintf("%d-%s", item.info.Size, item.info.Hash)
+}
+
+// linked returns the dedup key if the cache file may be shared with
+// other items or "" otherwise
+func (item *Item) linked() string {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if !item.info.Linked {
+		return ""
+	}
+	return item._dedupKey()
+}
+
+// _unlink gives the item its own copy of the cache file if it may be
+// shared with other items so it can be changed without changing them.
+//
+// call with the lock held
+func (item *Item) _unlink() (err error) {
+	if !item.info.Linked {
+		return nil
+	}
+	osPath := item.c.toOSPath(item.name) // No locking in Cache
+	tmpPath := osPath + ".dedup"
+	err = copyFile(osPath, tmpPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to copy shared cache file: %w", err)
+	}
+	wasOpen := item.fd != nil
+	if wasOpen {
+		err = item.fd.Close()
+		item.fd = nil
+		if err != nil {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("failed to close shared cache file: %w", err)
+		}
+	}
+	err = os.Rename(tmpPath, osPath)
+	if err == nil {
+		item.info.Linked = false
+		err = item._save()
+	}
+	if wasOpen {
+		fd, openErr := file.OpenFile(osPath, os.O_RDWR, 0600)
+		if openErr != nil {
+			return fmt.Errorf("failed to reopen cache file: %w", openErr)
+		}
+		item.fd = fd
+	}
+	if err != nil {
+		return fmt.Errorf("failed to replace shared cache file: %w", err)
+	}
+	fs.Debugf(item.name, "vfs cache: dedup: copied cache file to stop sharing it")
+	return nil
+}
+
+// copyFile copies the file at src to a new file at dst
+func copyFile(src, dst string) (err error) {
+	in, err := file.Open(src)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	out, err := file.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(out, &err)
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// share replaces the cache file of item with a hard link to the cache
+// file of src if they both still have the content given by key
+func (c *Cache) share(src, item *Item, key string) (err error) {
+	srcPath, osPath := c.toOSPath(src.name), c.toOSPath(item.name)
+	tmpPath := osPath + ".dedup"
+
+	// Link to the source while it is locked so it can't change
+	src.mu.Lock()
+	if !src._dedupable() || src._dedupKey() != key {
+		src.mu.Unlock()
+		return nil
+	}
+	if !src.info.Linked {
+		src.info.Linked = true
+		err = src._save()
+	}
+	if err == nil {
+		_ = os.Remove(tmpPath)
+		err = os.Link(srcPath, tmpPath)
+	}
+	src.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if !item._dedupable() || item._dedupKey() != key {
+		return os.Remove(tmpPath)
+	}
+	err = os.Rename(tmpPath, osPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	item.info.Linked = true
+	fs.Infof(item.name, "vfs cache: dedup: sharing cache file with %q as the content is identical", src.name)
+	return item._save()
+}
+
+// dedup shares the cache files of items with identical content using
+// hard links so the data is only stored once
+func (c *Cache) dedup() {
+	c.mu.Lock()
+	items := make(Items, 0, len(c.item))
+	for _, item := range c.item {
+		items = append(items, item)
+	}
+	c.mu.Unlock()
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].name < items[j].name
+	})
+
+	// Group the items by content
+	groups := map[string]Items{}
+	var keys []string
+	for _, item := range items {
+		key := item.dedupKey()
+		if key == "" {
+			continue
+		}
+		if groups[key] == nil {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		// Prefer an item which is shared already as the source
+		src := group[0]
+		for _, item := range group {
+			if item.linked() != "" {
+				src = item
+				break
+			}
+		}
+		srcInfo, err := os.Stat(c.toOSPath(src.name))
+		if err != nil {
+			continue
+		}
+		for _, item := range group {
+			if item == src {
+				continue
+			}
+			if fi, err := os.Stat(c.toOSPath(item.name)); err == nil && os.SameFile(srcInfo, fi) {
+				continue
+			}
+			err = c.share(src, item, key)
+			if err != nil {
+				fs.Errorf(item.name, "vfs cache: dedup: failed to share cache file with %q: %v", src.name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package vfscache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// degradeState tracks the cache write errors so the VFS can fall back
+// to cache mode minimal if the cache disk is failing
+type degradeState struct {
+	mu       sync.Mutex
+	errors   int       // cache write errors in a row
+	degraded bool      // set if fallen back to cache mode minimal
+	since    time.Time // when degraded was set
+	err      error     // the error which set degraded
+}
+
+// writeResult records the result of writing to the cache disk
+//
+// If --vfs-cache-degrade-errors writes in a row fail then the cache is
+// marked as degraded.
+func (c *Cache) writeResult(err error) {
+	if c.opt.CacheDegradeErrors <= 0 || c.opt.CacheMode <= vfscommon.CacheModeMinimal {
+		return
+	}
+	d := &c.degrade
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err == nil {
+		d.errors = 0
+		return
+	}
+	d.errors++
+	if d.degraded || d.errors < c.opt.CacheDegradeErrors {
+		return
+	}
+	d.degraded = true
+	d.since = time.Now()
+	d.err = err
+	fs.Errorf(nil, "vfs cache: %d writes to the cache in a row failed - falling back to --vfs-cache-mode minimal until restarted: %v", d.errors, err)
+}
+
+// Degraded returns true if the cache has fallen back to cache mode
+// minimal because writes to the cache disk kept failing.
+//
+// If it has, it returns when that happened and the error which caused
+// it.
+func (c *Cache) Degraded() (degraded bool, since time.Time, err error) {
+	d := &c.degrade
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.degraded, d.since, d.err
+}
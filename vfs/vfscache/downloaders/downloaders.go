@@ -58,13 +58,14 @@ type Item interface {
 // waiting for segments to be downloaded to a file.
 type Downloaders struct {
 	// Write once - no locking required
-	ctx    context.Context
-	cancel context.CancelFunc
-	item   Item
-	opt    *vfscommon.Options
-	src    fs.Object // source object
-	remote string
-	wg     sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	item     Item
+	opt      *vfscommon.Options
+	src      fs.Object // source object
+	remote   string
+	priority *Priority // reads waiting in the cache for --vfs-read-priority
+	wg       sync.WaitGroup
 
 	// Read write
 	mu         sync.Mutex
@@ -72,13 +73,17 @@ type Downloaders struct {
 	waiters    []waiter
 	errorCount int   // number of consecutive errors
 	lastErr    error // last error received
+	readAhead  int64 // current read ahead, grown by --vfs-read-ahead-growth
+	seqPos     int64 // where the next sequential read is expected
+	seqRead    int64 // bytes read sequentially since the read ahead last grew
 }
 
 // waiter is a range we are waiting for and a channel to signal when
 // the range is found
 type waiter struct {
-	r       ranges.Range
-	errChan chan<- error
+	r        ranges.Range
+	errChan  chan<- error
+	prefetch bool // set if nobody is reading the range yet
 }
 
 // downloader represents a running download for part of a file.
@@ -102,18 +107,22 @@ type downloader struct {
 }
 
 // New makes a downloader for item
-func New(item Item, opt *vfscommon.Options, remote string, src fs.Object) (dls *Downloaders) {
+//
+// priority may be nil if read ahead and prefetch downloads shouldn't
+// pause for reads.
+func New(item Item, opt *vfscommon.Options, remote string, src fs.Object, priority *Priority) (dls *Downloaders) {
 	if src == nil {
 		panic("internal error: newDownloaders called with nil src object")
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	dls = &Downloaders{
-		ctx:    ctx,
-		cancel: cancel,
-		item:   item,
-		opt:    opt,
-		src:    src,
-		remote: remote,
+		ctx:      ctx,
+		cancel:   cancel,
+		item:     item,
+		opt:      opt,
+		src:      src,
+		remote:   remote,
+		priority: priority,
 	}
 	dls.wg.Add(1)
 	go func() {
@@ -249,14 +258,33 @@ func (dls *Downloaders) Close(inErr error) (err error) {
 // Download the range passed in returning when it has been downloaded
 // with an error from the downloading go routine.
 func (dls *Downloaders) Download(r ranges.Range) (err error) {
+	return dls.download(r, false)
+}
+
+// Prefetch the range passed in to the cache, blocking until it is
+// downloaded or there is an error.
+//
+// Unlike Download this doesn't count as a read so it can be paused
+// for reads with --vfs-read-priority.
+func (dls *Downloaders) Prefetch(r ranges.Range) (err error) {
+	return dls.download(r, true)
+}
+
+// download the range passed in, blocking until it is downloaded or
+// there is an error
+func (dls *Downloaders) download(r ranges.Range, prefetch bool) (err error) {
 	// defer log.Trace(dls.src, "r=%+v", r)("err=%v", &err)
 
 	dls.mu.Lock()
 
+	if !prefetch {
+		dls._growReadAhead(r)
+	}
 	errChan := make(chan error)
 	waiter := waiter{
-		r:       r,
-		errChan: errChan,
+		r:        r,
+		errChan:  errChan,
+		prefetch: prefetch,
 	}
 
 	err = dls._ensureDownloader(r)
@@ -267,9 +295,86 @@ func (dls *Downloaders) Download(r ranges.Range) (err error) {
 
 	dls.waiters = append(dls.waiters, waiter)
 	dls.mu.Unlock()
+	if !prefetch {
+		dls.priority.startRead()
+		defer dls.priority.endRead()
+	}
 	return <-errChan
 }
 
+// _hasReads returns true if any of the waiters are reads
+//
+// call with lock held
+func (dls *Downloaders) _hasReads() bool {
+	for _, waiter := range dls.waiters {
+		if !waiter.prefetch {
+			return true
+		}
+	}
+	return false
+}
+
+// pauseForReads pauses the download if nothing is reading from this
+// file while reads elsewhere are waiting for data with
+// --vfs-read-priority, until quit is closed.
+func (dls *Downloaders) pauseForReads(quit <-chan struct{}) {
+	if dls.priority == nil {
+		return
+	}
+	dls.mu.Lock()
+	reading := dls._hasReads()
+	dls.mu.Unlock()
+	if reading {
+		return
+	}
+	if dls.priority.wait(quit) {
+		fs.Debugf(dls.src, "vfs cache: paused download for reads with --vfs-read-priority")
+	}
+}
+
+// _readAhead returns the number of bytes to read ahead
+//
+// call with lock held
+func (dls *Downloaders) _readAhead() int64 {
+	if dls.readAhead > 0 {
+		return dls.readAhead
+	}
+	return int64(dls.opt.ReadAhead)
+}
+
+// _growReadAhead grows the read ahead by --vfs-read-ahead-growth for
+// each read ahead's worth of data read sequentially, up to
+// --vfs-read-ahead-limit, so long sequential reads fetch further
+// ahead. A read which isn't sequential resets it to --vfs-read-ahead.
+//
+// call with lock held
+func (dls *Downloaders) _growReadAhead(r ranges.Range) {
+	growth, start := dls.opt.ReadAheadGrowth, int64(dls.opt.ReadAhead)
+	if growth <= 1 || start <= 0 {
+		return
+	}
+	if dls.readAhead == 0 || r.Pos < dls.seqPos-minWindow || r.Pos > dls.seqPos+minWindow {
+		dls.readAhead = start
+		dls.seqRead = r.Size
+		dls.seqPos = r.End()
+		return
+	}
+	dls.seqPos = max(dls.seqPos, r.End())
+	dls.seqRead += r.Size
+	if dls.seqRead < dls.readAhead {
+		return
+	}
+	dls.seqRead = 0
+	readAhead := int64(float64(dls.readAhead) * growth)
+	if limit := int64(dls.opt.ReadAheadLimit); limit >= 0 && readAhead > limit {
+		readAhead = max(limit, start)
+	}
+	if readAhead != dls.readAhead {
+		fs.Debugf(dls.src, "vfs cache: growing read ahead to %v", fs.SizeSuffix(readAhead))
+		dls.readAhead = readAhead
+	}
+}
+
 // close any waiters with the error passed in
 //
 // call with lock held
@@ -291,8 +396,8 @@ func (dls *Downloaders) _ensureDownloader(r ranges.Range) (err error) {
 	window := int64(fs.GetConfig(context.TODO()).BufferSize)
 
 	// Increase the read range by the read ahead if set
-	if dls.opt.ReadAhead > 0 {
-		r.Size += int64(dls.opt.ReadAhead)
+	if readAhead := dls._readAhead(); readAhead > 0 {
+		r.Size += readAhead
 	}
 
 	// We may be reopening a downloader after a failure here or
@@ -382,6 +487,18 @@ func (dls *Downloaders) EnsureDownloader(r ranges.Range) (err error) {
 	return dls._ensureDownloader(r)
 }
 
+// Progress returns the combined speed of the running downloaders in
+// bytes per second and the number of reads waiting for data to be
+// downloaded.
+func (dls *Downloaders) Progress() (speed float64, waiters int) {
+	dls.mu.Lock()
+	defer dls.mu.Unlock()
+	for _, dl := range dls.dls {
+		speed += dl.speed()
+	}
+	return speed, len(dls.waiters)
+}
+
 // _dispatchWaiters() sends any waiters which have completed back to
 // their callers.
 //
@@ -453,6 +570,9 @@ func (dls *Downloaders) kickWaiters() (err error) {
 func (dl *downloader) Write(p []byte) (n int, err error) {
 	// defer log.Trace(dl.dls.src, "p_len=%d", len(p))("n=%d, err=%v", &n, &err)
 
+	// Let reads waiting for data go first if nobody is waiting for this
+	dl.dls.pauseForReads(dl.quit)
+
 	// Kick the waiters on exit if some characters received
 	defer func() {
 		if n <= 0 {
@@ -540,7 +660,7 @@ func (dl *downloader) open(offset int64) (err error) {
 	// }
 	// in0, err := operations.NewReOpen(dl.dls.ctx, dl.dls.src, ci.LowLevelRetries, dl.dls.item.c.hashOption, rangeOption)
 
-	in0 := chunkedreader.New(context.TODO(), dl.dls.src, int64(dl.dls.opt.ChunkSize), int64(dl.dls.opt.ChunkSizeLimit), dl.dls.opt.ChunkStreams)
+	in0 := chunkedreader.NewWithLimit(context.TODO(), dl.dls.src, int64(dl.dls.opt.ChunkSize), int64(dl.dls.opt.ChunkSizeLimit), dl.dls.opt.ChunkStreams, dl.dls.opt.ChunkLimitStrategy, time.Duration(dl.dls.opt.ChunkStallTimeout), int64(dl.dls.opt.MinChunkStreamSize))
 	_, err = in0.Seek(offset, 0)
 	if err != nil {
 		return fmt.Errorf("vfs reader: failed to open source file: %w", err)
@@ -649,6 +769,21 @@ func (dl *downloader) setRange(r ranges.Range) {
 	}
 }
 
+// speed returns the average speed of the downloader in bytes per
+// second or 0 if it isn't running
+func (dl *downloader) speed() float64 {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if dl.tr == nil || dl._closed {
+		return 0
+	}
+	snapshot := dl.tr.Snapshot()
+	if elapsed := time.Since(snapshot.StartedAt).Seconds(); elapsed > 0 {
+		return float64(snapshot.Bytes) / elapsed
+	}
+	return 0
+}
+
 // get the current range this downloader is working on
 func (dl *downloader) getRange() (start, offset int64) {
 	dl.mu.Lock()
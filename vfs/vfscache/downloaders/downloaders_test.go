@@ -8,6 +8,7 @@ import (
 	"time"
 
 	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/ranges"
@@ -94,7 +95,7 @@ func TestDownloaders(t *testing.T) {
 			size: size,
 		}
 		opt := vfscommon.Opt
-		dls := New(item, &opt, remote, src)
+		dls := New(item, &opt, remote, src, nil)
 		return item, dls
 	}
 	cancel := func(dls *Downloaders) {
@@ -128,3 +129,93 @@ func TestDownloaders(t *testing.T) {
 		assert.True(t, item.HasRange(r))
 	})
 }
+
+func TestDownloadersGrowReadAhead(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.ReadAhead = 4 * fs.Mebi
+	opt.ReadAheadGrowth = 2
+	opt.ReadAheadLimit = 16 * fs.Mebi
+	dls := &Downloaders{opt: &opt}
+
+	// read size bytes sequentially from pos in 1 MiB reads
+	const readSize = int64(fs.Mebi)
+	read := func(pos, size int64) {
+		for end := pos + size; pos < end; pos += readSize {
+			dls._growReadAhead(ranges.Range{Pos: pos, Size: readSize})
+		}
+	}
+
+	read(0, readSize)
+	assert.Equal(t, int64(4*fs.Mebi), dls._readAhead())
+
+	// Doubles after each read ahead's worth of sequential reads
+	read(readSize, 3*readSize)
+	assert.Equal(t, int64(8*fs.Mebi), dls._readAhead())
+	read(4*readSize, 8*readSize)
+	assert.Equal(t, int64(16*fs.Mebi), dls._readAhead())
+
+	// Stops at the limit
+	read(12*readSize, 64*readSize)
+	assert.Equal(t, int64(16*fs.Mebi), dls._readAhead())
+
+	// A seek resets it
+	read(1000*readSize, readSize)
+	assert.Equal(t, int64(4*fs.Mebi), dls._readAhead())
+
+	// Nothing changes without growth
+	opt.ReadAheadGrowth = 1
+	dls = &Downloaders{opt: &opt}
+	read(0, 64*readSize)
+	assert.Equal(t, int64(4*fs.Mebi), dls._readAhead())
+}
+
+func TestDownloadersPauseForReads(t *testing.T) {
+	priority := NewPriority()
+	dls := &Downloaders{priority: priority}
+	quit := make(chan struct{})
+
+	// paused returns whether pauseForReads blocks
+	paused := func() bool {
+		done := make(chan struct{})
+		go func() {
+			dls.pauseForReads(quit)
+			close(done)
+		}()
+		select {
+		case <-done:
+			return false
+		case <-time.After(50 * time.Millisecond):
+		}
+		// Finishing the reads lets it go
+		priority.endRead()
+		<-done
+		priority.startRead()
+		return true
+	}
+
+	// Nothing to wait for without reads
+	assert.False(t, paused())
+
+	// Pauses for reads elsewhere
+	priority.startRead()
+	assert.Equal(t, 1, priority.waiting())
+	assert.True(t, paused())
+
+	// But not if this file is being read
+	dls.waiters = []waiter{{prefetch: true}, {}}
+	assert.False(t, paused())
+
+	// Prefetches are paused too
+	dls.waiters = []waiter{{prefetch: true}}
+	assert.True(t, paused())
+
+	// Quitting stops the pause
+	close(quit)
+	dls.pauseForReads(quit)
+	priority.endRead()
+	assert.Equal(t, 0, priority.waiting())
+
+	// A nil Priority never pauses
+	dls.priority = nil
+	assert.False(t, paused())
+}
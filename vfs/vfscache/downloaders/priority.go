@@ -0,0 +1,88 @@
+package downloaders
+
+import (
+	"sync"
+	"time"
+)
+
+// maximum time a download nobody is waiting for is paused at once
+// for reads with --vfs-read-priority
+const maxPriorityPause = time.Second
+
+// Priority counts the reads waiting for data across all the
+// Downloaders in a cache so that downloads nobody is waiting for,
+// read ahead and prefetches, can pause while reads are waiting for
+// data when --vfs-read-priority is set.
+//
+// The methods may be called on a nil *Priority which never pauses
+// anything.
+type Priority struct {
+	mu    sync.Mutex
+	reads int           // number of reads waiting for data
+	idle  chan struct{} // closed when reads drops to 0
+}
+
+// NewPriority makes a new Priority
+func NewPriority() *Priority {
+	return &Priority{}
+}
+
+// startRead records that a read is waiting for data
+func (p *Priority) startRead() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reads == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.reads++
+}
+
+// endRead records that a read has stopped waiting for data
+func (p *Priority) endRead() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reads--
+	if p.reads == 0 {
+		close(p.idle)
+	}
+}
+
+// waiting returns the number of reads waiting for data
+func (p *Priority) waiting() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.reads
+}
+
+// wait pauses while any reads are waiting for data, for up to
+// maxPriorityPause or until quit is closed. It returns true if it
+// paused.
+func (p *Priority) wait(quit <-chan struct{}) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	if p.reads == 0 {
+		p.mu.Unlock()
+		return false
+	}
+	idle := p.idle
+	p.mu.Unlock()
+	timer := time.NewTimer(maxPriorityPause)
+	defer timer.Stop()
+	select {
+	case <-idle:
+	case <-quit:
+	case <-timer.C:
+	}
+	return true
+}
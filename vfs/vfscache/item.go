@@ -1,22 +1,28 @@
 package vfscache
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/lib/file"
 	"github.com/rclone/rclone/lib/ranges"
 	"github.com/rclone/rclone/vfs/vfscache/downloaders"
 	"github.com/rclone/rclone/vfs/vfscache/writeback"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // NB as Cache and Item are tightly linked it is necessary to have a
@@ -68,6 +74,7 @@ type Item struct {
 	pendingAccesses int                      // number of threads - cache reset not allowed if not zero
 	modified        bool                     // set if the file has been modified since the last Open
 	beingReset      bool                     // cache cleaner is resetting the cache file, access not allowed
+	writeErr        error                    // set if writing to the cache file failed with --vfs-cache-write-error fail or retry
 }
 
 // Info is persisted to backing store
@@ -78,6 +85,13 @@ type Info struct {
 	Rs          ranges.Ranges // which parts of the file are present
 	Fingerprint string        // fingerprint of remote object
 	Dirty       bool          // set if the backing file has been modified
+	DirtyTime   time.Time     // time the backing file was first modified since it was last written back
+	Validated   time.Time     // last time the fingerprint was read from the remote
+	Accesses    int64         // number of times the file has been opened
+	Hash        string        // SHA-256 of the complete cached data if --vfs-cache-dedup is set
+	Linked      bool          // set if the cache file may be shared with other items by --vfs-cache-dedup
+	Scratch     bool          // set if written with --vfs-no-write-back so must never be uploaded
+	WriteTime   time.Time     // last time the data was written, even if ModTime was set since
 }
 
 // Items are a slice of *Item ordered by ATime
@@ -117,6 +131,27 @@ func (v Items) Less(i, j int) bool {
 	return iItem.info.ATime.Before(jItem.info.ATime)
 }
 
+// lrfuHalfLife is the time it takes for the weight of an access to
+// halve when using the lrfu eviction policy
+const lrfuHalfLife = time.Hour
+
+// evictionKey returns the values used to order the item for eviction
+// with policy. Items with a lower score are evicted first, with ties
+// broken by the oldest access time.
+func (item *Item) evictionKey(policy vfscommon.CacheEvictPolicy, now time.Time) (score float64, aTime time.Time) {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	aTime = item.info.ATime
+	switch policy {
+	case vfscommon.CacheEvictLFU:
+		score = float64(item.info.Accesses)
+	case vfscommon.CacheEvictLRFU:
+		age := now.Sub(aTime)
+		score = float64(item.info.Accesses) * math.Exp2(-float64(age)/float64(lrfuHalfLife))
+	}
+	return score, aTime
+}
+
 // clean the item after its cache file has been deleted
 func (info *Info) clean() {
 	*info = Info{}
@@ -172,6 +207,13 @@ func (item *Item) inUse() bool {
 	return item.opens != 0 || item.info.Dirty
 }
 
+// isOpen returns true if the item is open
+func (item *Item) isOpen() bool {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	return item.opens != 0
+}
+
 // getDiskSize returns the size on disk (approximately) of the item
 //
 // We return the sizes of the chunks we have fetched, however there is
@@ -182,6 +224,52 @@ func (item *Item) getDiskSize() int64 {
 	return item.info.Rs.Size()
 }
 
+// downloading returns true if the item is being downloaded and isn't
+// fully cached yet
+func (item *Item) downloading() bool {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	return item.downloaders != nil && !item._present()
+}
+
+// DownloadProgress is the progress of downloading an item into the
+// cache
+type DownloadProgress struct {
+	Name    string  // name in the VFS
+	Bytes   int64   // bytes of the file in the cache
+	Size    int64   // size of the file
+	Speed   float64 // combined speed of the downloaders in bytes per second
+	Waiters int     // number of reads waiting for data
+}
+
+// downloadProgress returns the progress of downloading the item and
+// true, or false if it isn't being downloaded
+func (item *Item) downloadProgress() (p DownloadProgress, ok bool) {
+	item.mu.Lock()
+	dls := item.downloaders
+	if dls == nil || item._present() {
+		item.mu.Unlock()
+		return p, false
+	}
+	p = DownloadProgress{
+		Name:  item.name,
+		Bytes: item.info.Rs.Size(),
+		Size:  item.info.Size,
+	}
+	item.mu.Unlock()
+	// Called without the item lock as the downloaders call back
+	// into the item with their lock held
+	p.Speed, p.Waiters = dls.Progress()
+	return p, true
+}
+
+// getATime returns the time the item was last accessed
+func (item *Item) getATime() time.Time {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	return item.info.ATime
+}
+
 // load reads an item from the disk or returns nil if not found
 func (item *Item) load() (exists bool, err error) {
 	item.mu.Lock()
@@ -233,6 +321,17 @@ func (item *Item) _truncate(size int64) (err error) {
 		return nil
 	}
 
+	// Don't change the size of data shared with other items
+	if item.info.Linked {
+		osPath := item.c.toOSPath(item.name) // No locking in Cache
+		if fi, err := os.Stat(osPath); err == nil && fi.Size() != size {
+			err = item._unlink()
+			if err != nil {
+				return fmt.Errorf("vfs cache: truncate: %w", err)
+			}
+		}
+	}
+
 	// Use open handle if available
 	fd := item.fd
 	if fd == nil {
@@ -284,6 +383,7 @@ func (item *Item) _truncate(size int64) (err error) {
 		if err != nil {
 			return fmt.Errorf("vfs cache: truncate: %w", err)
 		}
+		item.info.Hash = ""
 	}
 
 	item.info.Size = size
@@ -329,6 +429,9 @@ func (item *Item) Truncate(size int64) (err error) {
 	if item.fd == nil {
 		return errors.New("vfs cache item truncate: internal error: didn't Open file")
 	}
+	if item.writeErr != nil {
+		return fmt.Errorf("vfs cache item truncate: earlier write failed: %w", item.writeErr)
+	}
 
 	// Read old size
 	oldSize, err := item._getSize()
@@ -429,14 +532,25 @@ func (item *Item) Exists() bool {
 func (item *Item) _dirty() {
 	item.info.ModTime = time.Now()
 	item.info.ATime = item.info.ModTime
+	item.info.WriteTime = item.info.ModTime
+	item.info.Hash = ""
 	if !item.modified {
 		item.modified = true
 		item.mu.Unlock()
-		item.c.writeback.Remove(item.writeBackID)
+		// Leave overdue uploads running so files which are
+		// always being modified are still written back
+		if !item.c.writeback.Overdue(item.writeBackID) {
+			item.c.writeback.Remove(item.writeBackID)
+		}
 		item.mu.Lock()
 	}
 	if !item.info.Dirty {
 		item.info.Dirty = true
+		item.info.DirtyTime = item.info.ModTime
+		// mark changes made with --vfs-no-write-back before they
+		// are saved so they are never uploaded, even by a later
+		// rclone after a crash
+		item.info.Scratch = item.c.opt.NoWriteBack
 		err := item._save()
 		if err != nil {
 			fs.Errorf(item.name, "vfs cache: failed to save item info: %v", err)
@@ -460,6 +574,57 @@ func (item *Item) IsDirty() bool {
 	return item.info.Dirty
 }
 
+// RemoteChanged returns true if the item is dirty and waiting to be
+// uploaded and the remote object o is different to the one the item
+// was last synced with.
+//
+// Items which are being uploaded are ignored as the change is likely
+// to be the upload.
+func (item *Item) RemoteChanged(o fs.Object) bool {
+	item.mu.Lock()
+	dirty, fingerprint, id := item.info.Dirty, item.info.Fingerprint, item.writeBackID
+	item.mu.Unlock()
+	if !dirty || item.c.writeback.Uploading(id) {
+		return false
+	}
+	if fingerprint == "" {
+		// The item was created locally so any remote object is a change
+		return true
+	}
+	remoteFingerprint := fs.Fingerprint(context.TODO(), o, item.c.opt.FastFingerprint)
+	return !vfscommon.FingerprintsEqual(remoteFingerprint, fingerprint, time.Duration(item.c.opt.ClockSkewTolerance))
+}
+
+// HoldUpload removes the item from the writeback queue leaving the
+// changes in the cache. The item will be queued for upload again
+// when it is next closed or the cache is reloaded.
+//
+// This returns true if the item was queued for upload.
+func (item *Item) HoldUpload() bool {
+	item.mu.Lock()
+	id := item.writeBackID
+	item.mu.Unlock()
+	return item.c.writeback.Remove(id)
+}
+
+// busy returns true if the item is open or waiting to be uploaded
+//
+// Unlike inUse this doesn't count changes made with
+// --vfs-no-write-back as they will never be uploaded.
+func (item *Item) busy() bool {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	return item.opens != 0 || (item.info.Dirty && !item.info.Scratch)
+}
+
+// isScratch returns true if the item has changes made with
+// --vfs-no-write-back which must not be uploaded
+func (item *Item) isScratch() bool {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	return item.info.Dirty && item.info.Scratch
+}
+
 // Create the cache file and store the metadata on disk
 // Called with item.mu locked
 func (item *Item) _createFile(osPath string) (err error) {
@@ -493,10 +658,10 @@ func (item *Item) _createFile(osPath string) (err error) {
 
 // Open the local file from the object passed in.  Wraps open()
 // to provide recovery from out of space error.
-func (item *Item) Open(o fs.Object) (err error) {
-	for range fs.GetConfig(context.TODO()).LowLevelRetries {
+func (item *Item) Open(ctx context.Context, o fs.Object) (err error) {
+	for range fs.GetConfig(ctx).LowLevelRetries {
 		item.preAccess()
-		err = item.open(o)
+		err = item.open(ctx, o)
 		item.postAccess()
 		if err == nil {
 			break
@@ -513,18 +678,20 @@ func (item *Item) Open(o fs.Object) (err error) {
 
 // Open the local file from the object passed in (which may be nil)
 // which implies we are about to create the file
-func (item *Item) open(o fs.Object) (err error) {
+func (item *Item) open(ctx context.Context, o fs.Object) (err error) {
 	// defer log.Trace(o, "item=%p", item)("err=%v", &err)
 	item.mu.Lock()
 	defer item.mu.Unlock()
 
 	item.info.ATime = time.Now()
+	item.info.Accesses++
 
 	osPath, err := item.c.createItemDir(item.name) // No locking in Cache
 	if err != nil {
 		return fmt.Errorf("vfs cache item: createItemDir failed: %w", err)
 	}
 
+	o = item._revalidate(ctx, o)
 	err = item._checkObject(o)
 	if err != nil {
 		return fmt.Errorf("vfs cache item: check object failed: %w", err)
@@ -567,7 +734,7 @@ func (item *Item) open(o fs.Object) (err error) {
 
 	// Create the downloaders
 	if item.o != nil {
-		item.downloaders = downloaders.New(item, item.c.opt, item.name, item.o)
+		item.downloaders = downloaders.New(item, item.c.opt, item.name, item.o, item.c.priority)
 	}
 
 	return err
@@ -582,25 +749,83 @@ func unlockMutexForCall(mu *sync.Mutex, f func()) {
 	f()
 }
 
+// writeBackContext returns ctx set up to upload files from the cache
+// with --vfs-write-back-chunk-streams parallel streams if it is set.
+//
+// The streams are only used for files over --multi-thread-cutoff on
+// remotes which support multi-thread uploads, otherwise files are
+// uploaded with a single stream.
+func writeBackContext(ctx context.Context, opt *vfscommon.Options) context.Context {
+	if opt.WriteBackChunkStreams <= 0 {
+		return ctx
+	}
+	ctx, ci := fs.AddConfig(ctx)
+	ci.MultiThreadStreams = opt.WriteBackChunkStreams
+	// Use the streams even if the remote is local like the cache
+	ci.MultiThreadSet = true
+	return ctx
+}
+
 // Store stores the local cache file to the remote object, returning
 // the new remote object. objOld is the old object if known.
 //
 // Call with lock held
 func (item *Item) _store(ctx context.Context, storeFn StoreFn) (err error) {
 	// defer log.Trace(item.name, "item=%p", item)("err=%v", &err)
+	ctx, span := vfscommon.StartSpan(ctx, item.c.opt, "vfs.cache.Upload", item.name)
+	if span.IsRecording() {
+		span.SetAttributes(attribute.Int64("vfs.size", item.info.Size))
+	}
+	defer func() { vfscommon.EndSpan(span, err) }()
 
 	// Transfer the temp file to the remote
-	cacheObj, err := item.c.fcache.NewObject(ctx, item.name)
-	if err != nil && err != fs.ErrorObjectNotFound {
-		return fmt.Errorf("vfs cache: failed to find cache file: %w", err)
+	var (
+		cacheObj  fs.Object
+		keepDirty bool // set if the file still needs writing back after the upload
+	)
+	switch item.c.opt.WriteBackChanged {
+	case vfscommon.WriteBackChangedSnapshot:
+		var removeSnapshot func()
+		cacheObj, removeSnapshot, err = item._snapshot(ctx)
+		if err != nil {
+			return fmt.Errorf("vfs cache: failed to snapshot cache file: %w", err)
+		}
+		defer removeSnapshot()
+	default:
+		cacheObj, err = item.c.fcache.NewObject(ctx, item.name)
+		if err != nil && err != fs.ErrorObjectNotFound {
+			return fmt.Errorf("vfs cache: failed to find cache file: %w", err)
+		}
 	}
 
 	// Object has disappeared if cacheObj == nil
 	if cacheObj != nil {
+		uploadObj := cacheObj
+		if item.c.writeBackBw.active() {
+			uploadObj = item.c.writeBackBw.wrap(cacheObj)
+		}
 		o, name := item.o, item.name
+		modTime, started := item.info.ModTime, time.Now()
 		unlockMutexForCall(&item.mu, func() {
-			o, err = operations.Copy(ctx, item.c.fremote, o, name, cacheObj)
+			if item.sameAsRemote(ctx, name, cacheObj, o, modTime) {
+				fs.Infof(name, "vfs cache: not uploading as the contents are the same as the remote")
+				return
+			}
+			o, err = operations.Copy(writeBackContext(ctx, item.c.opt), item.c.fremote, o, name, uploadObj)
 		})
+		if err == nil && item.c.opt.WriteBackChanged == vfscommon.WriteBackChangedRetry && !item.info.ModTime.Equal(modTime) {
+			// Note the object so the retry updates it, but
+			// leave the item dirty
+			item.o = o
+			return errors.New("vfs cache: file was modified during upload - will retry")
+		}
+		if err == nil && item.c.opt.WriteBackMaxAge > 0 && !item.info.ModTime.Equal(modTime) {
+			// The upload wasn't cancelled as it was overdue
+			// so the changes made since it started still
+			// need writing back
+			keepDirty = true
+			item.info.DirtyTime = started
+		}
 		if err != nil {
 			if errors.Is(err, fs.ErrorCantUploadEmptyFiles) {
 				fs.Errorf(name, "Writeback failed: %v", err)
@@ -625,7 +850,7 @@ func (item *Item) _store(ctx context.Context, storeFn StoreFn) (err error) {
 	}
 
 	// Show item is clean and is eligible for cache removal
-	item.info.Dirty = false
+	item.info.Dirty = keepDirty
 	err = item._save()
 	if err != nil {
 		fs.Errorf(item.name, "vfs cache: failed to write metadata file: %v", err)
@@ -634,6 +859,94 @@ func (item *Item) _store(ctx context.Context, storeFn StoreFn) (err error) {
 	return nil
 }
 
+// snapshotTries is the number of times to try to snapshot a cache
+// file which keeps changing while it is being copied
+const snapshotTries = 3
+
+// _snapshot copies the cache file into the snapshot directory so it
+// can be uploaded without being affected by writes to the cache file.
+//
+// The copy is made with the lock released so the item can be used
+// meanwhile. If the item is changed during the copy it is copied
+// again.
+//
+// It returns the snapshot as an object, or nil if the cache file has
+// disappeared, and a function to remove the snapshot.
+//
+// call with lock held
+func (item *Item) _snapshot(ctx context.Context) (snapObj fs.Object, remove func(), err error) {
+	osPath := item.c.toOSPath(item.name) // No locking in Cache
+	snapPath := item.c.toOSPathSnapshot(item.name)
+	remove = func() {
+		if err := os.Remove(snapPath); err != nil && !os.IsNotExist(err) {
+			fs.Errorf(item.name, "vfs cache: failed to remove upload snapshot: %v", err)
+		}
+	}
+	var modTime time.Time
+	for try := 1; ; try++ {
+		var (
+			size  int64
+			found bool
+		)
+		modTime, size = item.info.ModTime, item.info.Size
+		unlockMutexForCall(&item.mu, func() {
+			found, err = copySnapshot(osPath, snapPath)
+		})
+		if err != nil {
+			remove()
+			return nil, func() {}, err
+		}
+		if !found {
+			return nil, func() {}, nil
+		}
+		if item.info.ModTime.Equal(modTime) && item.info.Size == size {
+			break
+		}
+		if try >= snapshotTries {
+			remove()
+			return nil, func() {}, errors.New("cache file kept changing while being copied")
+		}
+		fs.Debugf(item.name, "vfs cache: cache file changed while making upload snapshot - retrying")
+	}
+	err = os.Chtimes(snapPath, modTime, modTime)
+	if err == nil {
+		snapObj, err = item.c.fsnap.NewObject(ctx, item.name)
+	}
+	if err != nil {
+		remove()
+		return nil, func() {}, err
+	}
+	return snapObj, remove, nil
+}
+
+// copySnapshot copies the cache file osPath to snapPath returning
+// false if osPath doesn't exist.
+//
+// It is a variable so the tests can change the file while it is
+// being copied.
+var copySnapshot = func(osPath, snapPath string) (found bool, err error) {
+	in, err := file.Open(osPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer fs.CheckClose(in, &err)
+	if err = createDir(filepath.Dir(snapPath)); err != nil {
+		return false, err
+	}
+	out, err := file.Create(snapPath)
+	if err != nil {
+		return false, err
+	}
+	_, err = io.Copy(out, in)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return true, err
+}
+
 // Store stores the local cache file to the remote object, returning
 // the new remote object. objOld is the old object if known.
 func (item *Item) store(ctx context.Context, storeFn StoreFn) (err error) {
@@ -672,7 +985,7 @@ func (item *Item) Close(storeFn StoreFn) (err error) {
 	// FIXME It would be nice to do this asynchronously however it
 	// would require keeping the downloaders alive after the item
 	// has been closed
-	if item.info.Dirty && item.o != nil {
+	if item.info.Dirty && item.o != nil && item.writeErr == nil {
 		err = item._ensure(0, item.info.Size)
 		if err != nil {
 			return fmt.Errorf("vfs cache: failed to download missing parts of cache file: %w", err)
@@ -726,8 +1039,20 @@ func (item *Item) Close(storeFn StoreFn) (err error) {
 		}
 	}
 
+	// don't upload the file if writing to it failed as it may be
+	// corrupt, but keep it dirty as it may hold changes from
+	// earlier handles which haven't been uploaded yet. It is
+	// uploaded the next time it is closed.
+	if item.writeErr != nil {
+		checkErr(fmt.Errorf("vfs cache: not uploading changes as writing to the cache file failed: %w", item.writeErr))
+		item.writeErr = nil
+		return err
+	}
+
 	// upload the file to backing store if changed
-	if item.info.Dirty {
+	if item.info.Dirty && item.info.Scratch {
+		fs.Debugf(item.name, "vfs cache: not uploading as --vfs-no-write-back is set")
+	} else if item.info.Dirty {
 		fs.Infof(item.name, "vfs cache: queuing for upload in %v", item.c.opt.WriteBack)
 		if syncWriteBack {
 			// do synchronous writeback
@@ -735,11 +1060,12 @@ func (item *Item) Close(storeFn StoreFn) (err error) {
 		} else {
 			// asynchronous writeback
 			item.c.writeback.SetID(&item.writeBackID)
-			id := item.writeBackID
+			id, dirtyTime := item.writeBackID, item.info.DirtyTime
 			item.mu.Unlock()
 			item.c.writeback.Add(id, item.name, item.info.Size, item.modified, func(ctx context.Context) error {
 				return item.store(ctx, storeFn)
 			})
+			item.c.writeback.SetDirtyTime(id, dirtyTime)
 			item.mu.Lock()
 		}
 	}
@@ -750,6 +1076,32 @@ func (item *Item) Close(storeFn StoreFn) (err error) {
 	return err
 }
 
+// sameAsRemote returns true if --vfs-write-back-skip-identical is set
+// and cacheObj, the cache file of name, has the same size and hash as
+// the remote object o so it doesn't need uploading. The modification
+// time of o is set to modTime if it differs.
+//
+// call without the lock held as it may read the whole file
+func (item *Item) sameAsRemote(ctx context.Context, name string, cacheObj, o fs.Object, modTime time.Time) bool {
+	if !item.c.opt.WriteBackSkipIdentical || o == nil || o.Size() != cacheObj.Size() {
+		return false
+	}
+	equal, ht, err := operations.CheckHashes(ctx, cacheObj, o)
+	if err != nil || ht == hash.None || !equal {
+		return false
+	}
+	if precision := item.c.fremote.Precision(); precision != fs.ModTimeNotSupported {
+		if dt := o.ModTime(ctx).Sub(modTime); dt < -precision || dt > precision {
+			err = o.SetModTime(ctx, modTime)
+			if err != nil {
+				fs.Debugf(name, "vfs cache: uploading identical file as setting the modification time failed: %v", err)
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // reload is called with valid items recovered from a cache reload.
 //
 // If they are dirty then it makes sure they get uploaded.
@@ -758,22 +1110,38 @@ func (item *Item) Close(storeFn StoreFn) (err error) {
 // metaDirty will be false.
 func (item *Item) reload(ctx context.Context) error {
 	item.mu.Lock()
-	dirty := item.info.Dirty
+	dirty, scratch := item.info.Dirty, item.info.Scratch
 	item.mu.Unlock()
+	if scratch {
+		item.remove("left over from --vfs-no-write-back")
+		return nil
+	}
 	if !dirty {
+		if item.c.opt.CacheStartup == vfscommon.CacheStartupClear {
+			item.remove("cleared on startup")
+		}
 		return nil
 	}
+	if item.c.opt.CacheStartup == vfscommon.CacheStartupClear {
+		fs.Errorf(item.name, "vfs cache: not clearing on startup as it has changes waiting to upload")
+	}
 	// see if the object still exists
 	obj, _ := item.c.fremote.NewObject(ctx, item.name)
-	// open the file with the object (or nil)
-	err := item.Open(obj)
-	if err != nil {
-		return err
-	}
-	// close the file to execute the writeback if needed
-	err = item.Close(nil)
-	if err != nil {
-		return err
+	obj, action := item.recoverPartial(ctx, obj)
+	switch action {
+	case reloadDiscard:
+		return nil
+	case reloadUpload:
+		// open the file with the object (or nil)
+		err := item.Open(ctx, obj)
+		if err != nil {
+			return err
+		}
+		// close the file to execute the writeback if needed
+		err = item.Close(nil)
+		if err != nil {
+			return err
+		}
 	}
 	// put the file into the directory listings
 	size, err := item._getSize()
@@ -787,6 +1155,174 @@ func (item *Item) reload(ctx context.Context) error {
 	return nil
 }
 
+// reloadAction is what reload does with a dirty item
+type reloadAction int
+
+// reloadAction values
+const (
+	reloadUpload  reloadAction = iota // upload the changes
+	reloadHold                        // keep the changes without uploading them
+	reloadDiscard                     // the item has been removed
+)
+
+// recoverPartial checks whether o, the remote object for a dirty item
+// being reloaded, has changed since the item was cached.
+//
+// If it was left partially uploaded when rclone stopped it is dealt
+// with as set by --vfs-writeback-recovery. It is only taken to be
+// partial if it is smaller than the cache file and is the same as the
+// start of it. Any other change must have been made by another writer
+// so it is dealt with as set by --vfs-dirty-conflict.
+//
+// It returns the object to open the item with, which is nil if the
+// object was removed, and what to do with the item.
+func (item *Item) recoverPartial(ctx context.Context, o fs.Object) (_ fs.Object, action reloadAction) {
+	if o == nil {
+		return o, reloadUpload
+	}
+	item.mu.Lock()
+	fingerprint := item.info.Fingerprint
+	size, err := item._getSize()
+	item.mu.Unlock()
+	if err != nil {
+		return o, reloadUpload
+	}
+	if fingerprint != "" && vfscommon.FingerprintsEqual(fs.Fingerprint(ctx, o, item.c.opt.FastFingerprint), fingerprint, time.Duration(item.c.opt.ClockSkewTolerance)) {
+		return o, reloadUpload // unchanged so the upload didn't get started
+	}
+	if o.Size() == size {
+		cacheObj, err := item.c.fcache.NewObject(ctx, item.name)
+		if err == nil {
+			equal, ht, err := operations.CheckHashes(ctx, cacheObj, o)
+			if err == nil && ht != hash.None && equal {
+				return o, reloadUpload // the upload completed
+			}
+		}
+	}
+	partial, err := item.isPartial(ctx, o, size)
+	if err != nil {
+		fs.Debugf(item.name, "vfs cache: couldn't check for partially uploaded object: %v", err)
+	}
+	if !partial {
+		return item.reloadConflict(ctx, o)
+	}
+	mode := item.c.opt.WriteBackRecovery
+	fs.Logf(item.name, "vfs cache: found partially uploaded object (size %d, cached size %d) left by a previous run - --vfs-writeback-recovery is %v", o.Size(), size, mode)
+	if mode == vfscommon.WriteBackRecoveryReupload {
+		return o, reloadUpload
+	}
+	err = o.Remove(ctx)
+	if err != nil {
+		fs.Errorf(item.name, "vfs cache: failed to remove partially uploaded object - uploading changes again: %v", err)
+		return o, reloadUpload
+	}
+	if mode == vfscommon.WriteBackRecoveryDiscard {
+		item.remove("partially uploaded by a previous run")
+		return nil, reloadDiscard
+	}
+	return nil, reloadUpload
+}
+
+// isPartial returns true if o is smaller than the cache file, which is
+// size long, and is the same as the start of it, so is most likely an
+// upload of the cache file which didn't finish
+func (item *Item) isPartial(ctx context.Context, o fs.Object, size int64) (bool, error) {
+	if o.Size() <= 0 || o.Size() >= size {
+		return false, nil
+	}
+	in, err := operations.Open(ctx, o)
+	if err != nil {
+		return false, err
+	}
+	defer fs.CheckClose(in, &err)
+	fd, err := os.Open(item.c.toOSPath(item.name))
+	if err != nil {
+		return false, err
+	}
+	defer fs.CheckClose(fd, &err)
+	const bufSize = 64 * 1024
+	remoteBuf, cacheBuf := make([]byte, bufSize), make([]byte, bufSize)
+	for remaining := o.Size(); remaining > 0; {
+		n := int(min(remaining, bufSize))
+		if _, err = io.ReadFull(in, remoteBuf[:n]); err != nil {
+			return false, err
+		}
+		if _, err = io.ReadFull(fd, cacheBuf[:n]); err != nil {
+			return false, err
+		}
+		if !bytes.Equal(remoteBuf[:n], cacheBuf[:n]) {
+			return false, nil
+		}
+		remaining -= int64(n)
+	}
+	return true, nil
+}
+
+// reloadConflict deals with o having been changed by another writer
+// while the item had changes waiting to be uploaded when rclone
+// stopped, as set by --vfs-dirty-conflict.
+//
+// It returns the object to open the item with and what to do with the
+// item.
+func (item *Item) reloadConflict(ctx context.Context, o fs.Object) (_ fs.Object, action reloadAction) {
+	switch item.c.opt.DirtyConflict {
+	case vfscommon.DirtyConflictRemoteWins:
+		fs.Logf(item.name, "vfs cache: remote file changed while local changes were waiting to upload - discarding local changes")
+		item.remove("remote file changed while local changes were waiting to upload")
+		return nil, reloadDiscard
+	case vfscommon.DirtyConflictRenameLocal:
+		oldName := item.name
+		newName, err := item.c.conflictName(ctx, oldName)
+		if err == nil {
+			err = item.c.Rename(oldName, newName, nil)
+		}
+		if err != nil {
+			fs.Errorf(oldName, "vfs cache: remote file changed while local changes were waiting to upload - failed to rename local changes, keeping them: %v", err)
+			return o, reloadUpload
+		}
+		fs.Logf(oldName, "vfs cache: remote file changed while local changes were waiting to upload - uploading local changes as %q", newName)
+		return nil, reloadUpload
+	case vfscommon.DirtyConflictError:
+		fs.Errorf(item.name, "vfs cache: remote file changed while local changes were waiting to upload - not uploading local changes until the file is written again")
+		return o, reloadHold
+	default:
+		fs.Logf(item.name, "vfs cache: remote file changed while local changes were waiting to upload - keeping local changes")
+		return o, reloadUpload
+	}
+}
+
+// validate checks the clean cached data against the remote object
+// for --vfs-cache-startup validate-and-reuse, removing it if the
+// remote object has gone or changed.
+//
+// Dirty items and items reload has removed are left alone. If the
+// remote can't be read then the cached data is kept.
+func (item *Item) validate(ctx context.Context) {
+	item.mu.Lock()
+	skip := item.info.Dirty || !item._exists()
+	item.mu.Unlock()
+	if skip {
+		return
+	}
+	o, err := item.c.fremote.NewObject(ctx, item.name)
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		item._remove("stale on startup (remote deleted)")
+		return
+	} else if err != nil {
+		fs.Errorf(item.name, "vfs cache: failed to validate cached data on startup - keeping it: %v", err)
+		return
+	}
+	remoteFingerprint := fs.Fingerprint(ctx, o, item.c.opt.FastFingerprint)
+	if !vfscommon.FingerprintsEqual(remoteFingerprint, item.info.Fingerprint, time.Duration(item.c.opt.ClockSkewTolerance)) {
+		fs.Debugf(item.name, "vfs cache: removing cached entry as stale on startup (remote fingerprint %q != cached fingerprint %q)", remoteFingerprint, item.info.Fingerprint)
+		item._remove("stale on startup (remote is different)")
+		return
+	}
+	item.info.Validated = time.Now()
+}
+
 // check the fingerprint of an object and update the item or delete
 // the cached file accordingly
 //
@@ -815,11 +1351,12 @@ func (item *Item) _checkObject(o fs.Object) error {
 		fs.Debugf(item.name, "vfs cache: checking remote fingerprint %q against cached fingerprint %q", remoteFingerprint, item.info.Fingerprint)
 		if item.info.Fingerprint != "" {
 			// remote object && local object
-			if remoteFingerprint != item.info.Fingerprint {
+			if !vfscommon.FingerprintsEqual(remoteFingerprint, item.info.Fingerprint, time.Duration(item.c.opt.ClockSkewTolerance)) {
 				if !item.info.Dirty {
 					fs.Debugf(item.name, "vfs cache: removing cached entry as stale (remote fingerprint %q != cached fingerprint %q)", remoteFingerprint, item.info.Fingerprint)
 					item._remove("stale (remote is different)")
 					item.info.Fingerprint = remoteFingerprint
+					item.info.Validated = time.Now()
 				} else {
 					fs.Debugf(item.name, "vfs cache: remote object has changed but local object modified - keeping it (remote fingerprint %q != cached fingerprint %q)", remoteFingerprint, item.info.Fingerprint)
 				}
@@ -828,6 +1365,7 @@ func (item *Item) _checkObject(o fs.Object) error {
 			// remote object && no local object
 			// Set fingerprint
 			item.info.Fingerprint = remoteFingerprint
+			item.info.Validated = time.Now()
 		}
 		item.info.Size = o.Size()
 	}
@@ -841,6 +1379,46 @@ func (item *Item) _checkObject(o fs.Object) error {
 	return nil
 }
 
+// _revalidate reads the object o afresh from the remote if the cached
+// data is older than the --vfs-cache-revalidate TTL for its extension.
+//
+// This is so _checkObject compares the cached fingerprint against the
+// current state of the remote rather than the possibly stale object
+// from the directory cache. If the object can't be read, or the item
+// was opened or modified while it was being read, then o is returned
+// unchanged.
+//
+// call with lock held - it is dropped while reading the object
+func (item *Item) _revalidate(ctx context.Context, o fs.Object) fs.Object {
+	needsRevalidate := func() bool {
+		return item.opens == 0 && !item.info.Dirty && item.info.Fingerprint != ""
+	}
+	if o == nil || !needsRevalidate() {
+		return o
+	}
+	ttl := item.c.revalidateTTL(item.name)
+	if ttl <= 0 || time.Since(item.info.Validated) < ttl {
+		return o
+	}
+	var (
+		newO fs.Object
+		err  error
+	)
+	unlockMutexForCall(&item.mu, func() {
+		newO, err = item.c.fremote.NewObject(ctx, o.Remote())
+	})
+	if err != nil {
+		fs.Debugf(item.name, "vfs cache: failed to revalidate cached data: %v", err)
+		return o
+	}
+	if !needsRevalidate() {
+		return o
+	}
+	fs.Debugf(item.name, "vfs cache: revalidated cached data older than %v", ttl)
+	item.info.Validated = time.Now()
+	return newO
+}
+
 // WrittenBack checks to see if the item has been written back or not
 func (item *Item) WrittenBack() bool {
 	item.mu.Lock()
@@ -908,6 +1486,12 @@ func (item *Item) remove(reason string) (wasWriting bool) {
 // RemoveNotInUse is called to remove cache file that has not been accessed recently
 // It may also be called for removing empty cache files too when the quota is already reached.
 func (item *Item) RemoveNotInUse(maxAge time.Duration, emptyOnly bool) (removed bool, spaceFreed int64) {
+	return item.removeNotInUse(maxAge, emptyOnly, false)
+}
+
+// removeNotInUse is RemoveNotInUse but if dryRun is set it only
+// returns what would be removed without removing it.
+func (item *Item) removeNotInUse(maxAge time.Duration, emptyOnly bool, dryRun bool) (removed bool, spaceFreed int64) {
 	item.mu.Lock()
 	defer item.mu.Unlock()
 
@@ -935,6 +1519,9 @@ func (item *Item) RemoveNotInUse(maxAge time.Duration, emptyOnly bool) (removed
 		if !emptyOnly || spaceUsed == 0 {
 			spaceFreed = spaceUsed
 			removed = true
+			if dryRun {
+				return
+			}
 			if item._remove("Removing old cache file not in use") {
 				fs.Errorf(item.name, "item removed when it was writing/uploaded")
 			}
@@ -1058,7 +1645,7 @@ func (item *Item) Reset() (rr ResetResult, spaceFreed int64, err error) {
 
 	// Create the downloaders
 	if item.o != nil {
-		item.downloaders = downloaders.New(item, item.c.opt, item.name, item.o)
+		item.downloaders = downloaders.New(item, item.c.opt, item.name, item.o, item.c.priority)
 	}
 
 	/* The item will stay in the beingReset state if we get an error that prevents us from
@@ -1134,6 +1721,14 @@ func (item *Item) FindMissing(r ranges.Range) (outr ranges.Range) {
 //
 // call with the item lock held
 func (item *Item) _ensure(offset, size int64) (err error) {
+	return item._ensureFor(offset, size, false)
+}
+
+// _ensureFor does _ensure for a read, or for a prefetch if prefetch
+// is set so the download can be paused for reads
+//
+// call with the lock held
+func (item *Item) _ensureFor(offset, size int64, prefetch bool) (err error) {
 	// defer log.Trace(item.name, "offset=%d, size=%d", offset, size)("err=%v", &err)
 	if offset+size > item.info.Size {
 		size = item.info.Size - offset
@@ -1145,8 +1740,17 @@ func (item *Item) _ensure(offset, size int64) (err error) {
 		return errors.New("no space left on device")
 	} */
 	fs.Debugf(nil, "vfs cache: looking for range=%+v in %+v - present %v", r, item.info.Rs, present)
+	_, span := vfscommon.StartSpan(context.TODO(), item.c.opt, "vfs.cache.Read", item.name)
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.Int64("vfs.offset", offset),
+			attribute.Int64("vfs.size", size),
+			attribute.Bool("vfs.cache.hit", present),
+		)
+	}
 	item.mu.Unlock()
 	defer item.mu.Lock()
+	defer func() { vfscommon.EndSpan(span, err) }()
 	if present {
 		// This is a file we are writing so no downloaders needed
 		if item.downloaders == nil {
@@ -1170,7 +1774,10 @@ func (item *Item) _ensure(offset, size int64) (err error) {
 			}
 			item.o = o
 		}
-		item.downloaders = downloaders.New(item, item.c.opt, item.name, item.o)
+		item.downloaders = downloaders.New(item, item.c.opt, item.name, item.o, item.c.priority)
+	}
+	if prefetch {
+		return item.downloaders.Prefetch(r)
 	}
 	return item.downloaders.Download(r)
 }
@@ -1199,6 +1806,7 @@ func (item *Item) _updateFingerprint() {
 	}
 	oldFingerprint := item.info.Fingerprint
 	item.info.Fingerprint = fs.Fingerprint(context.TODO(), item.o, item.c.opt.FastFingerprint)
+	item.info.Validated = time.Now()
 	if oldFingerprint != item.info.Fingerprint {
 		fs.Debugf(item.o, "vfs cache: fingerprint now %q", item.info.Fingerprint)
 	}
@@ -1242,6 +1850,17 @@ func (item *Item) GetModTime() (modTime time.Time, err error) {
 	return modTime, nil
 }
 
+// GetWriteTime returns the last time the data of the item was
+// written, which is zero if it hasn't been written.
+//
+// Unlike GetModTime this isn't changed by setting the modification
+// time.
+func (item *Item) GetWriteTime() time.Time {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	return item.info.WriteTime
+}
+
 // ReadAt bytes from the file at off
 func (item *Item) ReadAt(b []byte, off int64) (n int, err error) {
 	n = 0
@@ -1283,6 +1902,11 @@ func (item *Item) readAt(b []byte, off int64) (n int, err error) {
 	}
 	defer item.mu.Unlock()
 
+	// A zero length read needs no data so don't start downloading
+	if len(b) == 0 {
+		return 0, nil
+	}
+
 	err = item._ensure(off, int64(len(b)))
 	if err != nil {
 		return 0, err
@@ -1312,13 +1936,26 @@ func (item *Item) WriteAt(b []byte, off int64) (n int, err error) {
 		item.mu.Unlock()
 		return 0, errors.New("vfs cache item WriteAt: internal error: didn't Open file")
 	}
+	if item.writeErr != nil {
+		item.mu.Unlock()
+		return 0, fmt.Errorf("vfs cache item WriteAt: earlier write failed: %w", item.writeErr)
+	}
+	// Don't write to data shared with other items
+	err = item._unlink()
+	if err != nil {
+		item.mu.Unlock()
+		return 0, fmt.Errorf("vfs cache item WriteAt: %w", err)
+	}
 	item.mu.Unlock()
 	// Do the writing with Item.mu unlocked
-	n, err = item.fd.WriteAt(b, off)
-	if err == nil && n != len(b) {
-		err = fmt.Errorf("short write: tried to write %d but only %d written", len(b), n)
-	}
+	n, err = item.writeAt(b, off)
+	item.c.writeResult(err)
 	item.mu.Lock()
+	if err != nil && item.c.opt.CacheWriteError != vfscommon.CacheWriteErrorOff {
+		// Fail all further writes so the file isn't uploaded
+		fs.Errorf(item.name, "vfs cache: write failed - not uploading changes to the file until it is reopened: %v", err)
+		item.writeErr = err
+	}
 	item._written(off, int64(n))
 	if n > 0 {
 		item._dirty()
@@ -1339,6 +1976,28 @@ func (item *Item) WriteAt(b []byte, off int64) (n int, err error) {
 	return n, err
 }
 
+// writeAt writes b to the cache file at off, retrying the part not
+// written if --vfs-cache-write-error is retry
+func (item *Item) writeAt(b []byte, off int64) (n int, err error) {
+	retries := 0
+	if item.c.opt.CacheWriteError == vfscommon.CacheWriteErrorRetry {
+		retries = fs.GetConfig(context.TODO()).LowLevelRetries
+	}
+	for try := 0; ; try++ {
+		var nn int
+		nn, err = item.fd.WriteAt(b[n:], off+int64(n))
+		n += nn
+		if err == nil && n != len(b) {
+			err = fmt.Errorf("short write: tried to write %d but only %d written", len(b), n)
+		}
+		if err == nil || try >= retries {
+			return n, err
+		}
+		fs.Debugf(item.name, "vfs cache: retrying write %d/%d after error: %v", try+1, retries, err)
+		time.Sleep(time.Duration(try+1) * 10 * time.Millisecond)
+	}
+}
+
 // WriteAtNoOverwrite writes b to the file, but will not overwrite
 // already present ranges.
 //
@@ -1380,6 +2039,7 @@ func (item *Item) WriteAtNoOverwrite(b []byte, off int64) (n int, skipped int, e
 			if err == nil && nn != size {
 				err = fmt.Errorf("downloader: short write: tried to write %d but only %d written", size, nn)
 			}
+			item.c.writeResult(err)
 			item._written(off, int64(nn))
 		}
 		off += int64(nn)
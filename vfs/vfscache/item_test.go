@@ -13,8 +13,10 @@ import (
 	"time"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/lib/ranges"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
@@ -65,7 +67,7 @@ func TestItemExists(t *testing.T) {
 	item, _ := c.get("potato")
 
 	assert.False(t, item.Exists())
-	require.NoError(t, item.Open(nil))
+	require.NoError(t, item.Open(context.Background(), nil))
 	assert.True(t, item.Exists())
 	require.NoError(t, item.Close(nil))
 	assert.True(t, item.Exists())
@@ -76,7 +78,7 @@ func TestItemExists(t *testing.T) {
 func TestItemGetSize(t *testing.T) {
 	r, c := newItemTestCache(t)
 	item, _ := c.get("potato")
-	require.NoError(t, item.Open(nil))
+	require.NoError(t, item.Open(context.Background(), nil))
 
 	size, err := item.GetSize()
 	require.NoError(t, err)
@@ -97,7 +99,7 @@ func TestItemGetSize(t *testing.T) {
 func TestItemDirty(t *testing.T) {
 	r, c := newItemTestCache(t)
 	item, _ := c.get("potato")
-	require.NoError(t, item.Open(nil))
+	require.NoError(t, item.Open(context.Background(), nil))
 
 	assert.Equal(t, false, item.IsDirty())
 
@@ -124,7 +126,7 @@ func TestItemSync(t *testing.T) {
 
 	require.Error(t, item.Sync())
 
-	require.NoError(t, item.Open(nil))
+	require.NoError(t, item.Open(context.Background(), nil))
 
 	require.NoError(t, item.Sync())
 
@@ -137,7 +139,7 @@ func TestItemTruncateNew(t *testing.T) {
 
 	require.Error(t, item.Truncate(0))
 
-	require.NoError(t, item.Open(nil))
+	require.NoError(t, item.Open(context.Background(), nil))
 
 	require.NoError(t, item.Truncate(100))
 
@@ -166,7 +168,7 @@ func TestItemTruncateExisting(t *testing.T) {
 	require.Error(t, item.Truncate(40))
 	checkObject(t, r, "existing", contents)
 
-	require.NoError(t, item.Open(obj))
+	require.NoError(t, item.Open(context.Background(), obj))
 
 	require.NoError(t, item.Truncate(40))
 
@@ -186,9 +188,19 @@ func TestItemReadAt(t *testing.T) {
 	_, err := item.ReadAt(buf, 10)
 	require.Error(t, err)
 
-	require.NoError(t, item.Open(obj))
+	require.NoError(t, item.Open(context.Background(), obj))
 
-	n, err := item.ReadAt(buf, 10)
+	// A zero length read doesn't start downloading even with
+	// read ahead
+	c.opt.ReadAhead = fs.Mebi
+	n, err := item.ReadAt(buf[:0], 10)
+	assert.Equal(t, 0, n)
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, item.HasRange(ranges.Range{Pos: 10, Size: 1}))
+	c.opt.ReadAhead = 0
+
+	n, err = item.ReadAt(buf, 10)
 	assert.Equal(t, 10, n)
 	require.NoError(t, err)
 	assert.Equal(t, contents[10:20], string(buf[:n]))
@@ -219,7 +231,7 @@ func TestItemWriteAtNew(t *testing.T) {
 	_, err := item.WriteAt(buf, 10)
 	require.Error(t, err)
 
-	require.NoError(t, item.Open(nil))
+	require.NoError(t, item.Open(context.Background(), nil))
 
 	assert.Equal(t, int64(0), item.getDiskSize())
 
@@ -242,12 +254,124 @@ func TestItemWriteAtNew(t *testing.T) {
 	checkObject(t, r, "potato", zeroes[:10]+"HELLO"+zeroes[:5]+"THEND")
 }
 
+func TestItemWriteErrorDegrade(t *testing.T) {
+	_, c := newItemTestCache(t)
+	c.opt.CacheMode = vfscommon.CacheModeFull
+	c.opt.CacheDegradeErrors = 2
+	item, _ := c.get("potato")
+	require.NoError(t, item.Open(context.Background(), nil))
+
+	// Make writes to the cache file fail
+	osPath := c.toOSPath("potato")
+	good := item.fd
+	bad, err := os.Open(osPath)
+	require.NoError(t, err)
+	defer func() {
+		item.fd = good
+		require.NoError(t, bad.Close())
+		require.NoError(t, item.Close(nil))
+	}()
+	writeBad := func() {
+		item.fd = bad
+		_, err := item.WriteAt([]byte("HELLO"), 0)
+		require.Error(t, err)
+		item.fd = good
+	}
+
+	// A successful write resets the count
+	writeBad()
+	itemWrite(t, item, "HELLO")
+	writeBad()
+	degraded, _, _ := c.Degraded()
+	assert.False(t, degraded)
+	assert.Equal(t, false, c.Stats()["degraded"])
+
+	// Falls back after enough failures in a row
+	writeBad()
+	degraded, since, err := c.Degraded()
+	assert.True(t, degraded)
+	assert.WithinDuration(t, time.Now(), since, time.Minute)
+	assert.Error(t, err)
+	stats := c.Stats()
+	assert.Equal(t, true, stats["degraded"])
+	assert.Equal(t, err.Error(), stats["degradedError"])
+
+	// Stays degraded after a successful write
+	itemWrite(t, item, "HELLO")
+	degraded, _, _ = c.Degraded()
+	assert.True(t, degraded)
+}
+
+func TestItemWriteError(t *testing.T) {
+	ci := fs.GetConfig(context.Background())
+	oldLowLevelRetries := ci.LowLevelRetries
+	ci.LowLevelRetries = 2
+	defer func() {
+		ci.LowLevelRetries = oldLowLevelRetries
+	}()
+
+	for _, mode := range []vfscommon.CacheWriteError{vfscommon.CacheWriteErrorOff, vfscommon.CacheWriteErrorFail, vfscommon.CacheWriteErrorRetry} {
+		t.Run(mode.String(), func(t *testing.T) {
+			r, c := newItemTestCache(t)
+			c.opt.CacheWriteError = mode
+			item, _ := c.get("potato")
+			require.NoError(t, item.Open(context.Background(), nil))
+
+			n, err := item.WriteAt([]byte("HELLO"), 0)
+			require.NoError(t, err)
+			assert.Equal(t, 5, n)
+
+			// Make writes to the cache file fail part of the way through
+			osPath := c.toOSPath("potato")
+			good := item.fd
+			bad, err := os.Open(osPath)
+			require.NoError(t, err)
+			item.fd = bad
+			_, err = item.WriteAt([]byte("THEND"), 5)
+			require.Error(t, err)
+			item.fd = good
+			require.NoError(t, bad.Close())
+
+			n, err = item.WriteAt([]byte("THEND"), 5)
+			if mode == vfscommon.CacheWriteErrorOff {
+				// Carries on as normal and uploads the file
+				require.NoError(t, err)
+				assert.Equal(t, 5, n)
+				require.NoError(t, item.Close(nil))
+				checkObject(t, r, "potato", "HELLOTHEND")
+				return
+			}
+
+			// Further writes fail and the file isn't uploaded
+			assert.ErrorContains(t, err, "earlier write failed")
+			assert.ErrorContains(t, item.Truncate(0), "earlier write failed")
+			assert.ErrorContains(t, item.Close(nil), "not uploading changes")
+			_, err = r.Fremote.NewObject(context.Background(), "potato")
+			assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+
+			// The changes which were written are kept
+			assert.True(t, item.IsDirty())
+			contents, err := os.ReadFile(osPath)
+			require.NoError(t, err)
+			assert.Equal(t, "HELLO", string(contents))
+
+			// The item can be used again and is uploaded
+			require.NoError(t, item.Open(context.Background(), nil))
+			n, err = item.WriteAt([]byte("THEND"), 5)
+			require.NoError(t, err)
+			assert.Equal(t, 5, n)
+			require.NoError(t, item.Close(nil))
+			checkObject(t, r, "potato", "HELLOTHEND")
+		})
+	}
+}
+
 func TestItemWriteAtExisting(t *testing.T) {
 	r, c := newItemTestCache(t)
 
 	contents, obj, item := newFile(t, r, c, "existing")
 
-	require.NoError(t, item.Open(obj))
+	require.NoError(t, item.Open(context.Background(), obj))
 
 	n, err := item.WriteAt([]byte("HELLO"), 10)
 	require.NoError(t, err)
@@ -273,7 +397,7 @@ func TestItemLoadMeta(t *testing.T) {
 	_ = contents
 
 	// Open the object to create metadata for it
-	require.NoError(t, item.Open(obj))
+	require.NoError(t, item.Open(context.Background(), obj))
 	require.NoError(t, item.Close(nil))
 	info := item.info
 
@@ -284,7 +408,7 @@ func TestItemLoadMeta(t *testing.T) {
 
 	// Reload the item so we have to load the metadata
 	item2, _ := c._get("existing")
-	require.NoError(t, item2.Open(obj))
+	require.NoError(t, item2.Open(context.Background(), obj))
 	info2 := item.info
 	require.NoError(t, item2.Close(nil))
 
@@ -301,7 +425,7 @@ func TestItemReload(t *testing.T) {
 	_ = contents
 
 	// Open the object to create metadata for it
-	require.NoError(t, item.Open(obj))
+	require.NoError(t, item.Open(context.Background(), obj))
 
 	// Make it dirty
 	n, err := item.WriteAt([]byte("THEENDMYFRIEND"), 95)
@@ -338,6 +462,139 @@ func TestItemReload(t *testing.T) {
 	}, avInfos)
 }
 
+func TestItemReloadPartial(t *testing.T) {
+	for _, mode := range []vfscommon.WriteBackRecovery{
+		vfscommon.WriteBackRecoveryReupload,
+		vfscommon.WriteBackRecoveryRemove,
+		vfscommon.WriteBackRecoveryDiscard,
+	} {
+		t.Run(mode.String(), func(t *testing.T) {
+			r, c := newItemTestCache(t)
+			c.opt.WriteBackRecovery = mode
+
+			_, obj, item := newFile(t, r, c, "existing")
+
+			// Make it dirty with new contents
+			require.NoError(t, item.Open(context.Background(), obj))
+			newContents := random.String(100)
+			_, err := item.WriteAt([]byte(newContents), 0)
+			require.NoError(t, err)
+			item.mu.Lock()
+			require.NoError(t, item._save())
+			require.NoError(t, item.fd.Close())
+			item.fd = nil
+			item.mu.Unlock()
+			c.mu.Lock()
+			delete(c.item, item.name)
+			c.mu.Unlock()
+
+			// Leave part of the new contents on the remote as if
+			// the upload was interrupted
+			r.WriteObject(context.Background(), "existing", newContents[:40], time.Now())
+
+			item2, _ := c._get("existing")
+			require.NoError(t, item2.reload(context.Background()))
+			assert.False(t, item2.IsDirty())
+
+			if mode == vfscommon.WriteBackRecoveryDiscard {
+				assert.False(t, item2.Exists())
+				_, err = r.Fremote.NewObject(context.Background(), "existing")
+				assert.Equal(t, fs.ErrorObjectNotFound, err)
+			} else {
+				checkObject(t, r, "existing", newContents)
+			}
+		})
+	}
+}
+
+func TestItemReloadConflict(t *testing.T) {
+	for _, conflict := range []vfscommon.DirtyConflict{
+		vfscommon.DirtyConflictLocalWins,
+		vfscommon.DirtyConflictRemoteWins,
+		vfscommon.DirtyConflictRenameLocal,
+		vfscommon.DirtyConflictError,
+	} {
+		t.Run(conflict.String(), func(t *testing.T) {
+			ctx := context.Background()
+			r, c := newItemTestCache(t)
+			c.opt.WriteBackRecovery = vfscommon.WriteBackRecoveryDiscard
+			c.opt.DirtyConflict = conflict
+
+			_, obj, item := newFile(t, r, c, "existing")
+
+			// Make it dirty with new contents
+			require.NoError(t, item.Open(context.Background(), obj))
+			newContents := random.String(100)
+			_, err := item.WriteAt([]byte(newContents), 0)
+			require.NoError(t, err)
+			item.mu.Lock()
+			require.NoError(t, item._save())
+			require.NoError(t, item.fd.Close())
+			item.fd = nil
+			item.mu.Unlock()
+			c.mu.Lock()
+			delete(c.item, item.name)
+			c.mu.Unlock()
+
+			// Another writer changes the remote to something
+			// which isn't part of the new contents
+			otherContents := random.String(40)
+			r.WriteObject(ctx, "existing", otherContents, time.Now())
+
+			item2, _ := c._get("existing")
+			require.NoError(t, item2.reload(ctx))
+
+			// The other writer's changes are never removed
+			switch conflict {
+			case vfscommon.DirtyConflictLocalWins:
+				assert.False(t, item2.IsDirty())
+				checkObject(t, r, "existing", newContents)
+			case vfscommon.DirtyConflictRemoteWins:
+				assert.False(t, item2.Exists())
+				checkObject(t, r, "existing", otherContents)
+			case vfscommon.DirtyConflictRenameLocal:
+				assert.False(t, item2.IsDirty())
+				assert.NotEqual(t, "existing", item2.name)
+				assert.Contains(t, item2.name, "existing.conflict-")
+				checkObject(t, r, "existing", otherContents)
+				checkObject(t, r, item2.name, newContents)
+			case vfscommon.DirtyConflictError:
+				assert.True(t, item2.IsDirty())
+				assert.True(t, item2.Exists())
+				checkObject(t, r, "existing", otherContents)
+			}
+		})
+	}
+}
+
+func TestItemReloadScratch(t *testing.T) {
+	r, c := newItemTestCache(t)
+
+	contents, obj, item := newFile(t, r, c, "existing")
+
+	// Make it dirty with --vfs-no-write-back and stop without
+	// closing it as if rclone crashed
+	c.opt.NoWriteBack = true
+	require.NoError(t, item.Open(context.Background(), obj))
+	_, err := item.WriteAt([]byte("THEENDMYFRIEND"), 95)
+	require.NoError(t, err)
+	item.mu.Lock()
+	assert.True(t, item.info.Scratch)
+	require.NoError(t, item.fd.Close())
+	item.fd = nil
+	item.mu.Unlock()
+	c.mu.Lock()
+	delete(c.item, item.name)
+	c.mu.Unlock()
+
+	// Reloading discards the changes rather than uploading them
+	item2, _ := c._get("existing")
+	require.NoError(t, item2.reload(context.Background()))
+	assert.False(t, item2.IsDirty())
+	assert.False(t, item2.Exists())
+	checkObject(t, r, "existing", contents)
+}
+
 func TestItemReloadRemoteGone(t *testing.T) {
 	r, c := newItemTestCache(t)
 
@@ -345,7 +602,7 @@ func TestItemReloadRemoteGone(t *testing.T) {
 	_ = contents
 
 	// Open the object to create metadata for it
-	require.NoError(t, item.Open(obj))
+	require.NoError(t, item.Open(context.Background(), obj))
 
 	size, err := item.GetSize()
 	require.NoError(t, err)
@@ -366,7 +623,7 @@ func TestItemReloadRemoteGone(t *testing.T) {
 	require.NoError(t, obj.Remove(context.Background()))
 
 	// Re-open with no object
-	require.NoError(t, item.Open(nil))
+	require.NoError(t, item.Open(context.Background(), nil))
 
 	// Check size is now 0
 	size, err = item.GetSize()
@@ -387,7 +644,7 @@ func TestItemReloadCacheStale(t *testing.T) {
 	contents, obj, item := newFile(t, r, c, "existing")
 
 	// Open the object to create metadata for it
-	require.NoError(t, item.Open(obj))
+	require.NoError(t, item.Open(context.Background(), obj))
 
 	size, err := item.GetSize()
 	require.NoError(t, err)
@@ -411,7 +668,7 @@ func TestItemReloadCacheStale(t *testing.T) {
 	// Re-open with updated object
 	oldFingerprint := item.info.Fingerprint
 	assert.NotEqual(t, "", oldFingerprint)
-	require.NoError(t, item.Open(obj))
+	require.NoError(t, item.Open(context.Background(), obj))
 
 	// Make sure fingerprint was updated
 	assert.NotEqual(t, oldFingerprint, item.info.Fingerprint)
@@ -442,6 +699,38 @@ func TestItemReloadCacheStale(t *testing.T) {
 	checkObject(t, r, "existing", "HELLO"+contents2[5:])
 }
 
+func TestItemRevalidate(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.WriteBack = 0
+	opt.CacheRevalidate = "log=1ms"
+	r, c := newTestCacheOpt(t, opt)
+
+	_, obj, item := newFile(t, r, c, "file.log")
+	require.NoError(t, item.Open(context.Background(), obj))
+	buf := make([]byte, 10)
+	_, err := item.ReadAt(buf, 0)
+	require.NoError(t, err)
+	require.NoError(t, item.Close(nil))
+	validated := item.info.Validated
+	assert.False(t, validated.IsZero())
+
+	// Update the remote but re-open with the old object
+	time.Sleep(10 * time.Millisecond)
+	contents2, _, _ := newFileLength(t, r, c, "file.log", 110)
+	require.NoError(t, item.Open(context.Background(), obj))
+
+	// Check the new object was read from the remote
+	assert.True(t, item.info.Validated.After(validated))
+	size, err := item.GetSize()
+	require.NoError(t, err)
+	assert.Equal(t, int64(110), size)
+	n, err := item.ReadAt(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, contents2[:10], string(buf[:n]))
+	require.NoError(t, item.Close(nil))
+}
+
 func TestItemReadWrite(t *testing.T) {
 	r, c := newItemTestCache(t)
 	const (
@@ -450,7 +739,7 @@ func TestItemReadWrite(t *testing.T) {
 	)
 
 	item, _ := c.get(fileName)
-	require.NoError(t, item.Open(nil))
+	require.NoError(t, item.Open(context.Background(), nil))
 
 	// Create the test file
 	in := readers.NewPatternReader(size)
@@ -508,7 +797,7 @@ func TestItemReadWrite(t *testing.T) {
 
 	// Read it back sequentially
 	t.Run("Sequential", func(t *testing.T) {
-		require.NoError(t, item.Open(obj))
+		require.NoError(t, item.Open(context.Background(), obj))
 		assert.False(t, item.present())
 		offset := int64(0)
 		for {
@@ -525,7 +814,7 @@ func TestItemReadWrite(t *testing.T) {
 
 	// Read it back randomly
 	t.Run("Random", func(t *testing.T) {
-		require.NoError(t, item.Open(obj))
+		require.NoError(t, item.Open(context.Background(), obj))
 		assert.False(t, item.present())
 		for !item.present() {
 			blockSize := rand.Intn(len(buf))
@@ -538,7 +827,7 @@ func TestItemReadWrite(t *testing.T) {
 
 	// Read it back randomly concurrently
 	t.Run("RandomConcurrent", func(t *testing.T) {
-		require.NoError(t, item.Open(obj))
+		require.NoError(t, item.Open(context.Background(), obj))
 		assert.False(t, item.present())
 		var wg sync.WaitGroup
 		for range 8 {
@@ -563,7 +852,7 @@ func TestItemReadWrite(t *testing.T) {
 	// Read it back in reverse which creates the maximum number of
 	// downloaders
 	t.Run("Reverse", func(t *testing.T) {
-		require.NoError(t, item.Open(obj))
+		require.NoError(t, item.Open(context.Background(), obj))
 		assert.False(t, item.present())
 		offset := int64(size)
 		for {
@@ -582,3 +871,131 @@ func TestItemReadWrite(t *testing.T) {
 		assert.False(t, item.remove(fileName))
 	})
 }
+
+func TestItemStoreSnapshot(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.WriteBack = 0
+	opt.WriteBackChanged = vfscommon.WriteBackChangedSnapshot
+	r, c := newTestCacheOpt(t, opt)
+	require.NotEqual(t, "", c.snapRoot)
+
+	item, _ := c.get("dir/potato")
+	itemWrite(t, item, "hello snapshot")
+	require.NoError(t, item.Close(nil))
+
+	checkObject(t, r, "dir/potato", "hello snapshot")
+	assertPathNotExist(t, c.toOSPathSnapshot("dir/potato"))
+	assert.False(t, item.IsDirty())
+}
+
+func TestItemStoreSnapshotRetry(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.WriteBack = 0
+	opt.WriteBackChanged = vfscommon.WriteBackChangedSnapshot
+	r, c := newTestCacheOpt(t, opt)
+
+	item, _ := c.get("dir/potato")
+	itemWrite(t, item, "hello snapshot")
+
+	// Change the item during the first copy, which can only be
+	// done if the lock isn't held
+	oldCopySnapshot := copySnapshot
+	defer func() { copySnapshot = oldCopySnapshot }()
+	tries := 0
+	copySnapshot = func(osPath, snapPath string) (bool, error) {
+		tries++
+		if tries == 1 {
+			require.NoError(t, os.WriteFile(osPath, []byte("HELLO SNAPSHOT"), 0600))
+			item.mu.Lock()
+			item.info.ModTime = item.info.ModTime.Add(time.Second)
+			item.mu.Unlock()
+		}
+		return oldCopySnapshot(osPath, snapPath)
+	}
+	require.NoError(t, item.Close(nil))
+
+	assert.Equal(t, 2, tries)
+	checkObject(t, r, "dir/potato", "HELLO SNAPSHOT")
+	assertPathNotExist(t, c.toOSPathSnapshot("dir/potato"))
+}
+
+func TestItemWriteBackContext(t *testing.T) {
+	ctx := context.Background()
+	opt := vfscommon.Opt
+	assert.Equal(t, ctx, writeBackContext(ctx, &opt))
+
+	opt.WriteBackChunkStreams = 8
+	ci := fs.GetConfig(writeBackContext(ctx, &opt))
+	assert.Equal(t, 8, ci.MultiThreadStreams)
+	assert.True(t, ci.MultiThreadSet)
+	assert.NotEqual(t, 8, fs.GetConfig(ctx).MultiThreadStreams)
+}
+
+func TestItemStoreChunkStreams(t *testing.T) {
+	ci := fs.GetConfig(context.Background())
+	oldCutoff := ci.MultiThreadCutoff
+	ci.MultiThreadCutoff = 1024
+	defer func() { ci.MultiThreadCutoff = oldCutoff }()
+
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.WriteBack = 0
+	opt.WriteBackChunkStreams = 4
+	r, c := newTestCacheOpt(t, opt)
+
+	contents := random.String(100 * 1024)
+	item, _ := c.get("dir/potato")
+	itemWrite(t, item, contents)
+	require.NoError(t, item.Close(nil))
+
+	checkObject(t, r, "dir/potato", contents)
+	assert.False(t, item.IsDirty())
+}
+
+func TestItemWriteBackSkipIdentical(t *testing.T) {
+	for _, test := range []struct {
+		skipIdentical bool
+		change        bool
+		wantUpload    bool
+	}{
+		{skipIdentical: false, change: false, wantUpload: true},
+		{skipIdentical: true, change: false, wantUpload: false},
+		{skipIdentical: true, change: true, wantUpload: true},
+	} {
+		t.Run(fmt.Sprintf("%+v", test), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.CachePollInterval = 0
+			opt.WriteBack = 0
+			opt.WriteBackSkipIdentical = test.skipIdentical
+			r, c := newTestCacheOpt(t, opt)
+
+			contents, obj, item := newFile(t, r, c, "existing")
+			newContents := contents
+			if test.change {
+				newContents = "X" + contents[1:]
+			}
+			require.NoError(t, item.Open(context.Background(), obj))
+			time.Sleep(10 * time.Millisecond)
+			n, err := item.WriteAt([]byte(newContents), 0)
+			require.NoError(t, err)
+			assert.Equal(t, len(newContents), n)
+			modTime := item.info.ModTime
+
+			stats := accounting.GlobalStats()
+			transfers := stats.GetTransfers()
+			stored := false
+			require.NoError(t, item.Close(func(fs.Object) { stored = true }))
+			assert.Equal(t, test.wantUpload, stats.GetTransfers() > transfers)
+			assert.True(t, stored)
+			assert.False(t, item.IsDirty())
+			checkObject(t, r, "existing", newContents)
+
+			// The modification time is kept even if not uploaded
+			obj, err = r.Fremote.NewObject(context.Background(), "existing")
+			require.NoError(t, err)
+			fstest.AssertTimeEqualWithPrecision(t, "existing", modTime, obj.ModTime(context.Background()), r.Fremote.Precision())
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package vfscache
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// Limits are the limits the cache cleaner keeps the cache within
+//
+// These start as --vfs-cache-max-age, --vfs-cache-max-size and
+// --vfs-cache-min-free-space but can be changed while running.
+type Limits struct {
+	MaxAge       time.Duration // max time since last access of objects in the cache
+	MaxSize      fs.SizeSuffix // max total size of objects in the cache, <= 0 for no limit
+	MinFreeSpace fs.SizeSuffix // target min free space on the cache disk, <= 0 for no limit
+}
+
+// tighter returns true if l could cause more to be evicted than old
+func (l Limits) tighter(old Limits) bool {
+	shrunk := func(n, old fs.SizeSuffix) bool {
+		return n > 0 && (old <= 0 || n < old)
+	}
+	return l.MaxAge < old.MaxAge ||
+		shrunk(l.MaxSize, old.MaxSize) ||
+		(l.MinFreeSpace > 0 && l.MinFreeSpace > old.MinFreeSpace)
+}
+
+// cacheLimits holds the Limits if they have been changed while running
+type cacheLimits struct {
+	mu      sync.Mutex
+	changed bool   // set if limits is in use rather than the options
+	limits  Limits // limits set with SetLimits
+}
+
+// Limits returns the limits the cache is being kept within
+func (c *Cache) Limits() Limits {
+	c.limits.mu.Lock()
+	defer c.limits.mu.Unlock()
+	return c._limits()
+}
+
+// _limits returns the limits in use
+//
+// call with c.limits.mu held
+func (c *Cache) _limits() Limits {
+	if c.limits.changed {
+		return c.limits.limits
+	}
+	return Limits{
+		MaxAge:       time.Duration(c.opt.CacheMaxAge),
+		MaxSize:      c.opt.CacheMaxSize,
+		MinFreeSpace: c.opt.CacheMinFreeSpace,
+	}
+}
+
+// SetLimits changes the limits the cache is being kept within.
+//
+// If the new limits are tighter than the old ones the cache cleaner
+// is run straight away, otherwise they take effect from its next run.
+func (c *Cache) SetLimits(limits Limits) error {
+	if limits.MaxAge < 0 {
+		return errors.New("max age can't be negative")
+	}
+	c.limits.mu.Lock()
+	old := c._limits()
+	c.limits.changed = true
+	c.limits.limits = limits
+	c.limits.mu.Unlock()
+	fs.Infof(c.fremote, "vfs cache: limits set to max age %v, max size %v, min free space %v", limits.MaxAge, limits.MaxSize, limits.MinFreeSpace)
+	if limits.tighter(old) {
+		select {
+		case c.limitsChanged <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// maxAgeRule is a max age for the files in dir from
+// --vfs-cache-max-age-rules
+type maxAgeRule struct {
+	dir    string
+	maxAge time.Duration
+}
+
+// parseMaxAgeRules parses a comma separated list of dir=duration
+// pairs as used by --vfs-cache-max-age-rules
+//
+// The rules are returned with the deepest directories first so the
+// first rule which matches a file is the one to use.
+func parseMaxAgeRules(s string) (rules []maxAgeRule, err error) {
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		dir, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("expecting dir=duration but got %q", part)
+		}
+		dir = strings.Trim(path.Clean("/"+strings.TrimSpace(dir)), "/")
+		if dir == "" {
+			return nil, fmt.Errorf("empty directory in %q - use --vfs-cache-max-age for the root", part)
+		}
+		if seen[dir] {
+			return nil, fmt.Errorf("directory %q given more than once", dir)
+		}
+		seen[dir] = true
+		maxAge, err := fs.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("bad duration in %q: %w", part, err)
+		}
+		if maxAge < 0 {
+			return nil, fmt.Errorf("negative duration in %q", part)
+		}
+		rules = append(rules, maxAgeRule{dir: dir, maxAge: maxAge})
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].dir) > len(rules[j].dir)
+	})
+	return rules, nil
+}
+
+// maxAgeFor returns the max age for the item called name, which is
+// the one from the --vfs-cache-max-age-rules for the deepest directory
+// it is in or maxAge if there isn't one.
+func (c *Cache) maxAgeFor(name string, maxAge time.Duration) time.Duration {
+	for _, rule := range c.maxAgeRules {
+		if name == rule.dir || strings.HasPrefix(name, rule.dir+"/") {
+			return rule.maxAge
+		}
+	}
+	return maxAge
+}
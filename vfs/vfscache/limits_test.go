@@ -0,0 +1,149 @@
+package vfscache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsTighter(t *testing.T) {
+	base := Limits{MaxAge: time.Hour, MaxSize: 10 * fs.Mebi, MinFreeSpace: fs.Gibi}
+	for _, test := range []struct {
+		name string
+		in   Limits
+		want bool
+	}{
+		{"same", base, false},
+		{"shorter age", Limits{MaxAge: time.Minute, MaxSize: base.MaxSize, MinFreeSpace: base.MinFreeSpace}, true},
+		{"longer age", Limits{MaxAge: 2 * time.Hour, MaxSize: base.MaxSize, MinFreeSpace: base.MinFreeSpace}, false},
+		{"smaller size", Limits{MaxAge: base.MaxAge, MaxSize: fs.Mebi, MinFreeSpace: base.MinFreeSpace}, true},
+		{"size off", Limits{MaxAge: base.MaxAge, MaxSize: -1, MinFreeSpace: base.MinFreeSpace}, false},
+		{"more free space", Limits{MaxAge: base.MaxAge, MaxSize: base.MaxSize, MinFreeSpace: 2 * fs.Gibi}, true},
+		{"free space off", Limits{MaxAge: base.MaxAge, MaxSize: base.MaxSize, MinFreeSpace: -1}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.in.tighter(base))
+		})
+	}
+	assert.True(t, Limits{MaxSize: fs.Mebi}.tighter(Limits{MaxSize: -1}))
+}
+
+func TestCacheSetLimits(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	opt.CacheMaxAge = fs.Duration(time.Hour)
+	opt.CacheMaxSize = -1
+	opt.CacheMinFreeSpace = -1
+	opt.WriteBack = fs.Duration(10 * time.Millisecond)
+	_, c := newTestCacheOpt(t, opt)
+
+	// Starts with the options
+	assert.Equal(t, Limits{MaxAge: time.Hour, MaxSize: -1, MinFreeSpace: -1}, c.Limits())
+
+	potato := c.Item("potato")
+	require.NoError(t, potato.Open(context.Background(), nil))
+	require.NoError(t, potato.Truncate(5))
+	require.NoError(t, potato.Close(nil))
+	assert.Equal(t, []string{`name="potato" opens=0 size=5`}, itemAsString(c))
+	require.Eventually(t, func() bool {
+		return !potato.IsDirty()
+	}, 10*time.Second, 10*time.Millisecond, "upload")
+
+	// Negative ages are rejected
+	assert.Error(t, c.SetLimits(Limits{MaxAge: -time.Hour}))
+	assert.Equal(t, time.Hour, c.Limits().MaxAge)
+
+	// Tightening the limits runs the cleaner which evicts potato
+	require.NoError(t, c.SetLimits(Limits{MaxAge: time.Hour, MaxSize: 1, MinFreeSpace: -1}))
+	assert.Equal(t, Limits{MaxAge: time.Hour, MaxSize: 1, MinFreeSpace: -1}, c.Limits())
+	assert.Eventually(t, func() bool {
+		return len(itemAsString(c)) == 0
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestParseMaxAgeRules(t *testing.T) {
+	rules, err := parseMaxAgeRules("")
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	rules, err = parseMaxAgeRules(" news=10m, /archive/old/ =30d,archive=1h")
+	require.NoError(t, err)
+	assert.Equal(t, []maxAgeRule{
+		{dir: "archive/old", maxAge: 30 * 24 * time.Hour},
+		{dir: "archive", maxAge: time.Hour},
+		{dir: "news", maxAge: 10 * time.Minute},
+	}, rules)
+
+	for _, bad := range []string{"news", "=1h", "/=1h", "news=potato", "news=-1h", "news=1h,news/=2h"} {
+		_, err = parseMaxAgeRules(bad)
+		assert.Error(t, err, bad)
+	}
+
+	c := &Cache{maxAgeRules: rules}
+	for _, test := range []struct {
+		name string
+		want time.Duration
+	}{
+		{"news/today.txt", 10 * time.Minute},
+		{"newsletter.txt", time.Minute},
+		{"archive/2020.zip", time.Hour},
+		{"archive/old/2000.zip", 30 * 24 * time.Hour},
+		{"archive/older.zip", time.Hour},
+		{"file.txt", time.Minute},
+	} {
+		assert.Equal(t, test.want, c.maxAgeFor(test.name, time.Minute), test.name)
+	}
+}
+
+func TestCacheMaxAgeRules(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.WriteBack = 0
+	opt.CacheMaxAgeRules = "fast=1m"
+	_, c := newTestCacheOpt(t, opt)
+
+	fast := c.Item("fast/potato")
+	itemWrite(t, fast, "hello")
+	slow := c.Item("slow/potato")
+	itemWrite(t, slow, "hello")
+	require.NoError(t, fast.Close(nil))
+	require.NoError(t, slow.Close(nil))
+
+	now := time.Now()
+	fast.info.ATime = now.Add(-10 * time.Minute)
+	slow.info.ATime = now.Add(-10 * time.Minute)
+
+	candidates := c.EvictionPreview(Limits{MaxAge: time.Hour})
+	require.Len(t, candidates, 1)
+	assert.Equal(t, "fast/potato", candidates[0].Name)
+	assert.Equal(t, "age", candidates[0].Reason)
+
+	c.purgeOld(time.Hour)
+	assert.Equal(t, []string{`name="slow/potato" opens=0 size=5`}, itemAsString(c))
+}
+
+func TestCacheWarnLargeFile(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.CacheMaxSize = 100
+	_, c := newTestCacheOpt(t, opt)
+
+	// Off by default
+	assert.False(t, c.WarnLargeFile("potato", 1000))
+
+	c.opt.CacheLargeFileWarnThreshold = 0.5
+	assert.False(t, c.WarnLargeFile("potato", 50))
+	assert.True(t, c.WarnLargeFile("potato", 51))
+	assert.True(t, c.WarnLargeFile("potato", 51))
+	assert.True(t, c.WarnLargeFile("potato2", 100))
+	assert.Equal(t, 2, c.Stats()["largeFiles"])
+
+	// No warnings without a max size
+	require.NoError(t, c.SetLimits(Limits{MaxSize: -1}))
+	assert.False(t, c.WarnLargeFile("potato3", 1000))
+}
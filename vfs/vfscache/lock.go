@@ -0,0 +1,271 @@
+package vfscache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/file"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+var (
+	// how often the owner of a cache directory lock renews it
+	cacheLockRenew = 10 * time.Second
+	// how long after its last renewal a lock is treated as abandoned
+	cacheLockExpiry = 3 * cacheLockRenew
+	// how often to check a lock when waiting for it
+	cacheLockPoll = time.Second
+)
+
+var (
+	cacheLocksMu sync.Mutex
+	cacheLocks   = map[string]*cacheLock{} // locks held by this process by path
+)
+
+// cacheLockInfo is the contents of a cache directory lock file
+type cacheLockInfo struct {
+	ID      string    `json:"id"`      // random ID for this lock
+	PID     int       `json:"pid"`     // process ID of the owner
+	Host    string    `json:"host"`    // hostname of the owner
+	Started time.Time `json:"started"` // when the lock was taken
+	Updated time.Time `json:"updated"` // when the lock was last renewed
+}
+
+// cacheLock is a lock on a cache directory held by this process so
+// that a second VFS using the same cache directory, for example
+// another rclone mount of the same remote, can be detected with
+// --vfs-cache-dir-lock.
+//
+// The lock is a file holding a cacheLockInfo which is created with
+// O_EXCL so only one process can make it. The owner renews it every
+// cacheLockRenew and removes it when its context is cancelled. A lock
+// which hasn't been renewed for cacheLockExpiry was left by an rclone
+// which didn't shut down cleanly and is taken over.
+type cacheLock struct {
+	ctx  context.Context
+	path string
+	info cacheLockInfo
+}
+
+// cacheLockPath returns the path of the lock file for the cache of the
+// remote with relativeDirOSPath
+func cacheLockPath(parentOSPath string, relativeDirOSPath string) string {
+	return file.UNCPath(filepath.Join(parentOSPath, "vfsLock", relativeDirOSPath+".lock"))
+}
+
+// readCacheLock reads the lock file at path returning nil if there
+// isn't one in use
+func readCacheLock(path string) *cacheLockInfo {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	var info cacheLockInfo
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &info)
+	}
+	if err != nil {
+		// The owner may be writing it so it is in use unless it
+		// hasn't been written for cacheLockExpiry
+		fs.Debugf(nil, "vfs cache: unreadable lock file %q: %v", path, err)
+		info = cacheLockInfo{Started: fi.ModTime(), Updated: fi.ModTime()}
+	}
+	if time.Since(info.Updated) > cacheLockExpiry {
+		fs.Logf(nil, "vfs cache: taking over lock on the cache directory abandoned by process %d on %q", info.PID, info.Host)
+		return nil
+	}
+	return &info
+}
+
+// write the lock file
+func (l *cacheLock) write() error {
+	l.info.Updated = time.Now()
+	data, err := json.Marshal(l.info)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.path, data, 0600)
+}
+
+// create makes the lock file, failing with an error satisfying
+// os.IsExist if it is already there
+func (l *cacheLock) create() (err error) {
+	l.info.Updated = time.Now()
+	data, err := json.Marshal(l.info)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(f, &err)
+	_, err = f.Write(data)
+	return err
+}
+
+// removeAbandonedLock removes the lock file at path, which was fi when
+// it was found to be abandoned, unless it has been replaced since
+func removeAbandonedLock(path string, fi os.FileInfo) error {
+	now, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !os.SameFile(fi, now) || !fi.ModTime().Equal(now.ModTime()) {
+		return nil
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// lockOwner returns the owner of the lock at path which was taken by
+// another process while trying to take it
+func lockOwner(path string) *cacheLockInfo {
+	if owner := readCacheLock(path); owner != nil {
+		return owner
+	}
+	return &cacheLockInfo{Started: time.Now(), Updated: time.Now()}
+}
+
+// _tryLock takes the lock at path if nobody else is holding it,
+// returning the info of the other owner if they are
+//
+// call with cacheLocksMu held
+func _tryLock(ctx context.Context, path string) (l *cacheLock, owner *cacheLockInfo, err error) {
+	held := cacheLocks[path]
+	if held != nil && held.ctx.Err() == nil {
+		return nil, &held.info, nil
+	}
+	fi, statErr := os.Stat(path)
+	owner = readCacheLock(path)
+	// A lock held by a cache in this process which has been shut down
+	// can be taken straight away
+	if owner != nil && (held == nil || owner.ID != held.info.ID) {
+		return nil, owner, nil
+	}
+	host, _ := os.Hostname()
+	l = &cacheLock{
+		ctx:  ctx,
+		path: path,
+		info: cacheLockInfo{
+			ID:      random.String(16),
+			PID:     os.Getpid(),
+			Host:    host,
+			Started: time.Now(),
+		},
+	}
+	if err = file.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create cache lock directory: %w", err)
+	}
+	// The lock file is abandoned or was ours so remove it
+	if statErr == nil {
+		if err = removeAbandonedLock(path, fi); err != nil {
+			return nil, nil, fmt.Errorf("failed to remove abandoned cache lock: %w", err)
+		}
+	}
+	if err = l.create(); os.IsExist(err) {
+		return nil, lockOwner(path), nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to write cache lock: %w", err)
+	}
+	// Check another process taking over the same abandoned lock
+	// didn't replace it
+	if got := readCacheLock(path); got == nil || got.ID != l.info.ID {
+		return nil, lockOwner(path), nil
+	}
+	cacheLocks[path] = l
+	return l, nil, nil
+}
+
+// lockCacheDir locks the cache directory of the remote with
+// relativeDirOSPath according to --vfs-cache-dir-lock, releasing the
+// lock when ctx is cancelled
+func lockCacheDir(ctx context.Context, mode vfscommon.CacheDirLock, parentOSPath string, relativeDirOSPath string) error {
+	if mode == vfscommon.CacheDirLockOff {
+		return nil
+	}
+	path := cacheLockPath(parentOSPath, relativeDirOSPath)
+	logged := false
+	for {
+		cacheLocksMu.Lock()
+		l, owner, err := _tryLock(ctx, path)
+		cacheLocksMu.Unlock()
+		if err != nil {
+			return err
+		}
+		if l != nil {
+			fs.Debugf(nil, "vfs cache: locked cache directory with %q", path)
+			go l.renew()
+			return nil
+		}
+		inUse := fmt.Errorf("cache directory is in use by rclone process %d on %q since %v - use a different --cache-dir for each rclone using the same remote or stop the other one (lock file %q)",
+			owner.PID, owner.Host, owner.Started.Format(time.RFC3339), path)
+		if mode == vfscommon.CacheDirLockError {
+			return inUse
+		}
+		if !logged {
+			fs.Logf(nil, "vfs cache: waiting as --vfs-cache-dir-lock is %v: %v", mode, inUse)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return errors.New("stopped waiting for the cache directory lock")
+		case <-time.After(cacheLockPoll):
+		}
+	}
+}
+
+// renew the lock until its context is cancelled then remove it
+func (l *cacheLock) renew() {
+	ticker := time.NewTicker(cacheLockRenew)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if l.ctx.Err() != nil {
+				continue
+			}
+			if owner := readCacheLock(l.path); owner != nil && owner.ID != l.info.ID {
+				fs.Errorf(nil, "vfs cache: lock on the cache directory taken by rclone process %d on %q - the cache may be corrupted", owner.PID, owner.Host)
+				return
+			}
+			if err := l.write(); err != nil {
+				fs.Errorf(nil, "vfs cache: failed to renew cache directory lock: %v", err)
+			}
+		case <-l.ctx.Done():
+			l.release()
+			return
+		}
+	}
+}
+
+// release removes the lock if it is still ours
+func (l *cacheLock) release() {
+	cacheLocksMu.Lock()
+	defer cacheLocksMu.Unlock()
+	if cacheLocks[l.path] != l {
+		return
+	}
+	delete(cacheLocks, l.path)
+	if owner := readCacheLock(l.path); owner != nil && owner.ID != l.info.ID {
+		return
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		fs.Errorf(nil, "vfs cache: failed to remove cache directory lock: %v", err)
+	}
+	fs.Debugf(nil, "vfs cache: unlocked cache directory")
+}
@@ -0,0 +1,156 @@
+package vfscache
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// write a lock file for another rclone updated at updated
+func writeForeignLock(t *testing.T, path string, updated time.Time) {
+	data, err := json.Marshal(cacheLockInfo{
+		ID:      "other",
+		PID:     1,
+		Host:    "otherhost",
+		Started: updated,
+		Updated: updated,
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+}
+
+func TestLockCacheDir(t *testing.T) {
+	oldPoll := cacheLockPoll
+	cacheLockPoll = 10 * time.Millisecond
+	defer func() { cacheLockPoll = oldPoll }()
+
+	dir := t.TempDir()
+	path := cacheLockPath(dir, "remote")
+
+	// off doesn't lock anything
+	require.NoError(t, lockCacheDir(context.Background(), vfscommon.CacheDirLockOff, dir, "remote"))
+	assertPathNotExist(t, path)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	require.NoError(t, lockCacheDir(ctx1, vfscommon.CacheDirLockError, dir, "remote"))
+	assertPathExist(t, path)
+
+	// A second user gets an error
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	err := lockCacheDir(ctx2, vfscommon.CacheDirLockError, dir, "remote")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cache directory is in use by rclone process")
+
+	// A different remote doesn't conflict
+	require.NoError(t, lockCacheDir(ctx2, vfscommon.CacheDirLockError, dir, "other"))
+
+	// The lock can be taken as soon as the first user stops
+	cancel1()
+	require.NoError(t, lockCacheDir(ctx2, vfscommon.CacheDirLockError, dir, "remote"))
+
+	// Waiting gives up when the context is cancelled
+	ctx3, cancel3 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel3()
+	err = lockCacheDir(ctx3, vfscommon.CacheDirLockWait, dir, "remote")
+	require.Error(t, err)
+
+	// Waiting takes the lock when it is released
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel2()
+	}()
+	ctx4, cancel4 := context.WithCancel(context.Background())
+	require.NoError(t, lockCacheDir(ctx4, vfscommon.CacheDirLockWait, dir, "remote"))
+
+	// The lock file is removed when the last user stops
+	cancel4()
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestLockCacheDirForeign(t *testing.T) {
+	dir := t.TempDir()
+	path := cacheLockPath(dir, "remote")
+	require.NoError(t, os.MkdirAll(dir+"/vfsLock", 0700))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A lock kept up to date by another rclone is respected
+	writeForeignLock(t, path, time.Now())
+	err := lockCacheDir(ctx, vfscommon.CacheDirLockError, dir, "remote")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `process 1 on "otherhost"`)
+
+	// An abandoned lock is taken over
+	writeForeignLock(t, path, time.Now().Add(-2*cacheLockExpiry))
+	require.NoError(t, lockCacheDir(ctx, vfscommon.CacheDirLockError, dir, "remote"))
+	info := readCacheLock(path)
+	require.NotNil(t, info)
+	assert.Equal(t, os.Getpid(), info.PID)
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A lock being written by another rclone is respected
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	err = lockCacheDir(ctx, vfscommon.CacheDirLockError, dir, "remote")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cache directory is in use")
+
+	// Unless it hasn't been written for a long time
+	old := time.Now().Add(-2 * cacheLockExpiry)
+	require.NoError(t, os.Chtimes(path, old, old))
+	require.NoError(t, lockCacheDir(ctx, vfscommon.CacheDirLockError, dir, "remote"))
+	info = readCacheLock(path)
+	require.NotNil(t, info)
+	assert.Equal(t, os.Getpid(), info.PID)
+}
+
+func TestTryLockExclusive(t *testing.T) {
+	dir := t.TempDir()
+	path := cacheLockPath(dir, "remote")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cacheLocksMu.Lock()
+	defer cacheLocksMu.Unlock()
+	l, owner, err := _tryLock(ctx, path)
+	require.NoError(t, err)
+	require.NotNil(t, l)
+	assert.Nil(t, owner)
+	defer delete(cacheLocks, path)
+
+	// Creating the lock file again fails as it is there already
+	l2 := &cacheLock{ctx: ctx, path: path, info: cacheLockInfo{ID: "other"}}
+	err = l2.create()
+	assert.True(t, os.IsExist(err))
+	info := readCacheLock(path)
+	require.NotNil(t, info)
+	assert.Equal(t, l.info.ID, info.ID)
+}
+
+func TestCacheNewDirLock(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CachePollInterval = 0
+	opt.WriteBack = 0
+	opt.CacheDirLock = vfscommon.CacheDirLockError
+	r, _ := newTestCacheOpt(t, opt)
+
+	_, err := New(context.Background(), r.Fremote, &opt, addVirtual)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "use a different --cache-dir")
+}
@@ -0,0 +1,163 @@
+package vfscache
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/ranges"
+)
+
+// ManifestEntry describes an item in the cache so the cache can be
+// checked after it has been copied elsewhere
+type ManifestEntry struct {
+	Name        string        `json:"name"`           // name of the item
+	Size        int64         `json:"size"`           // size of the file
+	Hash        string        `json:"hash,omitempty"` // SHA-256 of the cached data if the file is completely cached
+	Ranges      ranges.Ranges `json:"ranges"`         // which parts of the file are cached
+	Dirty       bool          `json:"dirty"`          // set if the file has changes waiting to be uploaded
+	ModTime     time.Time     `json:"modTime"`        // modification time of the file
+	Fingerprint string        `json:"fingerprint"`    // fingerprint of the remote object
+}
+
+// ManifestProblem describes an item which doesn't match its
+// ManifestEntry
+type ManifestProblem struct {
+	Name    string `json:"name"`    // name of the item
+	Problem string `json:"problem"` // what is wrong with it
+}
+
+// Manifest returns a ManifestEntry for each item in the cache sorted
+// by name.
+//
+// This hashes the cache files of the completely cached items which
+// haven't been hashed already so may take some time.
+func (c *Cache) Manifest() (entries []ManifestEntry) {
+	c.mu.Lock()
+	items := make(Items, 0, len(c.item))
+	for _, item := range c.item {
+		items = append(items, item)
+	}
+	c.mu.Unlock()
+
+	entries = make([]ManifestEntry, 0, len(items))
+	for _, item := range items {
+		if entry, ok := item.manifestEntry(); ok {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// manifestEntry returns the ManifestEntry for the item, or false if
+// the item has no cache file
+func (item *Item) manifestEntry() (entry ManifestEntry, ok bool) {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if !item._exists() {
+		return entry, false
+	}
+	entry = ManifestEntry{
+		Name:        item.name,
+		Size:        item.info.Size,
+		Hash:        item.info.Hash,
+		Ranges:      append(ranges.Ranges{}, item.info.Rs...),
+		Dirty:       item.info.Dirty,
+		ModTime:     item.info.ModTime,
+		Fingerprint: item.info.Fingerprint,
+	}
+	if entry.Hash == "" && item._present() {
+		hash, err := item._hashUnlocked()
+		if err != nil {
+			fs.Errorf(item.name, "vfs cache: manifest: failed to hash cache file: %v", err)
+		} else {
+			entry.Hash = hash
+		}
+	}
+	return entry, true
+}
+
+// _hashUnlocked returns the SHA-256 of the cache file, hashing it
+// with the lock released so the item can be used meanwhile.
+//
+// It returns an error if the item was changed while it was being
+// hashed.
+//
+// call with the lock held
+func (item *Item) _hashUnlocked() (hash string, err error) {
+	osPath := item.c.toOSPath(item.name) // No locking in Cache
+	modTime, size := item.info.ModTime, item.info.Size
+	item.mu.Unlock()
+	hash, err = hashFile(osPath)
+	item.mu.Lock()
+	if err != nil {
+		return "", err
+	}
+	if !item.info.ModTime.Equal(modTime) || item.info.Size != size {
+		return "", errors.New("cache file changed while being hashed")
+	}
+	return hash, nil
+}
+
+// CheckManifest checks the items in the cache against the entries of
+// a manifest made by Manifest, for example after copying the cache
+// directory to another machine.
+//
+// It returns a ManifestProblem for each entry whose item is missing
+// or doesn't match.
+func (c *Cache) CheckManifest(entries []ManifestEntry) (problems []ManifestProblem) {
+	for _, entry := range entries {
+		name := clean(entry.Name)
+		c.mu.Lock()
+		item, found := c.item[name]
+		c.mu.Unlock()
+		problem := "missing from cache"
+		if found {
+			problem = item.checkManifestEntry(&entry)
+		}
+		if problem != "" {
+			problems = append(problems, ManifestProblem{Name: name, Problem: problem})
+		}
+	}
+	return problems
+}
+
+// checkManifestEntry checks the item against entry returning a
+// description of the problem or "" if it matches
+func (item *Item) checkManifestEntry(entry *ManifestEntry) string {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	osPath := item.c.toOSPath(item.name) // No locking in Cache
+	fi, err := os.Stat(osPath)
+	if err != nil {
+		return "cache file missing"
+	}
+	switch {
+	case item.info.Size != entry.Size || fi.Size() != entry.Size:
+		return "size differs"
+	case entry.Dirty && !item.info.Dirty:
+		return "changes waiting to be uploaded are missing"
+	case item.info.Fingerprint != entry.Fingerprint:
+		return "fingerprint differs"
+	}
+	for _, r := range entry.Ranges {
+		if !item.info.Rs.Present(r) {
+			return "cached data missing"
+		}
+	}
+	if entry.Hash != "" {
+		hash, err := item._hashUnlocked()
+		if err != nil {
+			return "failed to hash cache file: " + err.Error()
+		}
+		if hash != entry.Hash {
+			return "hash differs"
+		}
+	}
+	return ""
+}
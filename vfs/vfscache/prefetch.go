@@ -0,0 +1,54 @@
+package vfscache
+
+import (
+	"context"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/ranges"
+)
+
+// Prefetch downloads the whole of o into the cache, if it isn't
+// there already, so it can be read later without waiting for the
+// remote.
+//
+// So that prefetching doesn't evict files which are cached already it
+// does nothing if o won't fit within --vfs-cache-max-size.
+func (c *Cache) Prefetch(ctx context.Context, o fs.Object) (err error) {
+	if c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+	name := clean(o.Remote())
+	size := o.Size()
+	if size <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	item := c.item[name]
+	used := c.used
+	c.mu.Unlock()
+	if item != nil && item.HasRange(ranges.Range{Pos: 0, Size: size}) {
+		return nil
+	}
+	if maxSize := int64(c.Limits().MaxSize); maxSize > 0 && used+size > maxSize {
+		fs.Debugf(name, "vfs cache: not prefetching as it would exceed --vfs-cache-max-size")
+		return nil
+	}
+
+	fs.Debugf(name, "vfs cache: prefetching")
+	item = c.Item(name)
+	err = item.Open(ctx, o)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := item.Close(nil)
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	item.preAccess()
+	defer item.postAccess()
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	return item._ensureFor(0, item.info.Size, true)
+}
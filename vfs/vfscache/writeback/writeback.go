@@ -6,6 +6,7 @@ import (
 	"container/heap"
 	"context"
 	"errors"
+	"fmt"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -67,6 +68,10 @@ type writeBackItem struct {
 	id        Handle             // id of the item
 	index     int                // index into the priority queue for update
 	expiry    time.Time          // When this expires we will write it back
+	modified  time.Time          // When the item was last modified
+	dirty     time.Time          // When the changes waiting to be written back were first made
+	started   time.Time          // When the current upload started
+	redo      bool               // set to upload the item again once the current upload finishes
 	uploading bool               // True if item is being processed by upload() method
 	onHeap    bool               // true if this item is on the items heap
 	cancel    context.CancelFunc // To cancel the upload with
@@ -74,6 +79,7 @@ type writeBackItem struct {
 	putFn     PutFn              // To write the object data
 	tries     int                // number of times we have tried to upload
 	delay     time.Duration      // delay between upload attempts
+	err       error              // error from the last upload attempt
 }
 
 // A writeBackItems implements a priority queue by implementing
@@ -134,18 +140,52 @@ func (wb *WriteBack) _newExpiry() time.Time {
 	return expiry
 }
 
+// _itemExpiry returns the time wbItem should be written back. This is
+// the same as _newExpiry but no sooner than --vfs-write-back-min-age
+// after the item was last modified and no later than
+// --vfs-write-back-max-age after it was first changed.
+//
+// call with the lock held
+func (wb *WriteBack) _itemExpiry(wbItem *writeBackItem) time.Time {
+	expiry := wb._newExpiry()
+	if wb.opt.WriteBackMinAge > 0 {
+		minExpiry := wbItem.modified.Add(time.Duration(wb.opt.WriteBackMinAge))
+		if minExpiry.After(expiry) {
+			expiry = minExpiry
+		}
+	}
+	if wb.opt.WriteBackMaxAge > 0 {
+		maxExpiry := wbItem.dirty.Add(time.Duration(wb.opt.WriteBackMaxAge))
+		if maxExpiry.Before(expiry) {
+			expiry = maxExpiry
+		}
+	}
+	return expiry
+}
+
+// _overdue returns true if wbItem has been waiting to be written back
+// for longer than --vfs-write-back-max-age
+//
+// call with the lock held
+func (wb *WriteBack) _overdue(wbItem *writeBackItem) bool {
+	return wb.opt.WriteBackMaxAge > 0 && time.Since(wbItem.dirty) >= time.Duration(wb.opt.WriteBackMaxAge)
+}
+
 // make a new writeBackItem
 //
 // call with the lock held
 func (wb *WriteBack) _newItem(id Handle, name string, size int64) *writeBackItem {
 	wb.SetID(&id)
+	now := time.Now()
 	wbItem := &writeBackItem{
-		name:   name,
-		size:   size,
-		expiry: wb._newExpiry(),
-		delay:  time.Duration(wb.opt.WriteBack),
-		id:     id,
+		name:     name,
+		size:     size,
+		modified: now,
+		dirty:    now,
+		delay:    time.Duration(wb.opt.WriteBack),
+		id:       id,
 	}
+	wbItem.expiry = wb._itemExpiry(wbItem)
 	wb._addItem(wbItem)
 	wb._pushItem(wbItem)
 	return wbItem
@@ -265,11 +305,22 @@ func (wb *WriteBack) Add(id Handle, name string, size int64, modified bool, putF
 		wbItem = wb._newItem(id, name, size)
 	} else {
 		if wbItem.uploading && modified {
-			// We are uploading already so cancel the upload
-			wb._cancelUpload(wbItem)
+			if wb._overdue(wbItem) {
+				// Let the upload finish so a file which keeps
+				// being modified is still written back, then
+				// upload the changes made since
+				fs.Debugf(wbItem.name, "vfs cache: not cancelling upload as --vfs-write-back-max-age has passed")
+				wbItem.redo = true
+			} else {
+				// We are uploading already so cancel the upload
+				wb._cancelUpload(wbItem)
+			}
+		}
+		if modified {
+			wbItem.modified = time.Now()
 		}
 		// Kick the timer on
-		wb.items._update(wbItem, wb._newExpiry())
+		wb.items._update(wbItem, wb._itemExpiry(wbItem))
 	}
 	wbItem.putFn = putFn
 	wbItem.size = size
@@ -309,6 +360,43 @@ func (wb *WriteBack) Remove(id Handle) (found bool) {
 	return wb._remove(id)
 }
 
+// Overdue returns true if the item with id is being uploaded and has
+// been waiting to be written back for longer than
+// --vfs-write-back-max-age, in which case changes to it shouldn't
+// cancel the upload.
+func (wb *WriteBack) Overdue(id Handle) bool {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	wbItem, ok := wb.lookup[id]
+	return ok && wbItem.uploading && wb._overdue(wbItem)
+}
+
+// SetDirtyTime records that the changes to the item with id waiting to
+// be written back were first made at dirty, if that is before the time
+// it was added to the queue. This is used by --vfs-write-back-max-age.
+func (wb *WriteBack) SetDirtyTime(id Handle, dirty time.Time) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	wbItem, ok := wb.lookup[id]
+	if !ok || wbItem.uploading || dirty.IsZero() || !dirty.Before(wbItem.dirty) {
+		return
+	}
+	wbItem.dirty = dirty
+	wb.items._update(wbItem, wb._itemExpiry(wbItem))
+	wb._resetTimer()
+}
+
+// Uploading returns true if the item with id is being uploaded
+func (wb *WriteBack) Uploading(id Handle) bool {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	wbItem, ok := wb.lookup[id]
+	return ok && wbItem.uploading
+}
+
 // Rename should be called when a file might be uploading and it gains
 // a new name. This will cancel the upload and put it back in the
 // queue.
@@ -335,7 +423,7 @@ func (wb *WriteBack) Rename(id Handle, name string) {
 
 	wbItem.name = name
 	// Kick the timer on
-	wb.items._update(wbItem, wb._newExpiry())
+	wb.items._update(wbItem, wb._itemExpiry(wbItem))
 
 	wb._resetTimer()
 }
@@ -359,6 +447,9 @@ func (wb *WriteBack) upload(ctx context.Context, wbItem *writeBackItem) {
 
 	wbItem.uploading = false
 	wb.uploads--
+	wbItem.err = err
+	redo := wbItem.redo
+	wbItem.redo = false
 
 	if err != nil {
 		// FIXME should this have a max number of transfer attempts?
@@ -376,6 +467,15 @@ func (wb *WriteBack) upload(ctx context.Context, wbItem *writeBackItem) {
 		// push the item back on the queue for retry
 		wb._pushItem(wbItem)
 		wb.items._update(wbItem, time.Now().Add(wbItem.delay))
+	} else if redo {
+		fs.Infof(wbItem.name, "vfs cache: upload succeeded try #%d - uploading changes made during the upload", wbItem.tries)
+		// the changes made during the upload have been waiting
+		// since it started
+		wbItem.dirty = wbItem.started
+		wbItem.tries = 0
+		wbItem.delay = time.Duration(wb.opt.WriteBack)
+		wb._pushItem(wbItem)
+		wb.items._update(wbItem, wb._itemExpiry(wbItem))
 	} else {
 		fs.Infof(wbItem.name, "vfs cache: upload succeeded try #%d", wbItem.tries)
 		// show that we are done with the item
@@ -423,6 +523,22 @@ func (wb *WriteBack) cancelUpload(id Handle) bool {
 	return true
 }
 
+// mark the item as uploading and start the uploader
+//
+// the item should not be on the heap
+//
+// call with lock held
+func (wb *WriteBack) _startUpload(ctx context.Context, wbItem *writeBackItem) {
+	//fs.Debugf(wbItem.name, "uploading = true %p item %p", wbItem, wbItem.item)
+	wbItem.uploading = true
+	wbItem.started = time.Now()
+	wb.uploads++
+	newCtx, cancel := context.WithCancel(ctx)
+	wbItem.cancel = cancel
+	wbItem.done = make(chan struct{})
+	go wb.upload(newCtx, wbItem)
+}
+
 // this uploads as many items as possible
 func (wb *WriteBack) processItems(ctx context.Context) {
 	wb.mu.Lock()
@@ -440,15 +556,9 @@ func (wb *WriteBack) processItems(ctx context.Context) {
 			resetTimer = false
 			break
 		}
-		// Pop the item, mark as uploading and start the uploader
+		// Pop the item and start the uploader
 		wbItem = wb._popItem()
-		//fs.Debugf(wbItem.name, "uploading = true %p item %p", wbItem, wbItem.item)
-		wbItem.uploading = true
-		wb.uploads++
-		newCtx, cancel := context.WithCancel(ctx)
-		wbItem.cancel = cancel
-		wbItem.done = make(chan struct{})
-		go wb.upload(newCtx, wbItem)
+		wb._startUpload(ctx, wbItem)
 	}
 
 	if resetTimer {
@@ -540,3 +650,49 @@ func (wb *WriteBack) SetExpiry(id Handle, expiry time.Time, relative time.Durati
 	wb._resetTimer()
 	return nil
 }
+
+// Flush uploads a single item now, rather than when it expires, and
+// waits for the upload to finish. The other items in the queue are
+// left alone.
+//
+// If the item is being uploaded already it waits for that upload.
+//
+// If the item isn't found then it will return ErrorIDNotFound
+func (wb *WriteBack) Flush(ctx context.Context, id Handle) error {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	wbItem, ok := wb.lookup[id]
+	if !ok {
+		return ErrorIDNotFound
+	}
+	if !wbItem.uploading {
+		if wb.ctx.Err() != nil {
+			return wb.ctx.Err()
+		}
+		fs.Debugf(wbItem.name, "vfs cache: flushing upload")
+		wb._removeItem(wbItem)
+		wb._startUpload(wb.ctx, wbItem)
+	}
+	done := wbItem.done
+
+	// wait without the lock so the upload can finish
+	wb.mu.Unlock()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	wb.mu.Lock()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// the item is removed from the lookup when the upload succeeds
+	if wb.lookup[id] != wbItem {
+		return nil
+	}
+	if wbItem.err != nil && !errors.Is(wbItem.err, context.Canceled) {
+		return fmt.Errorf("upload failed: %w", wbItem.err)
+	}
+	return errors.New("upload was cancelled, probably because the file was changed")
+}
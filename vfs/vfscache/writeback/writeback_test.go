@@ -760,3 +760,124 @@ func TestWriteBackCancelUpload(t *testing.T) {
 	checkInLookup(t, wb, wbItem)
 	assert.True(t, pi.cancelled)
 }
+
+func TestWriteBackMinAge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := vfscommon.Opt
+	opt.WriteBack = fs.Duration(100 * time.Millisecond)
+	opt.WriteBackMinAge = fs.Duration(time.Hour)
+	wb := New(ctx, &opt)
+
+	// A new item isn't written back until it is old enough
+	pi := newPutItem(t)
+	id := wb.Add(0, "one", 10, true, pi.put)
+	wb.mu.Lock()
+	wbItem := wb.lookup[id]
+	assert.WithinDuration(t, time.Now().Add(time.Hour), wbItem.expiry, time.Minute)
+	wb.mu.Unlock()
+
+	// If it is removed before then it is never written back
+	assert.True(t, wb.Remove(id))
+	assert.False(t, pi.called)
+
+	// Closing an item without modifying it doesn't reset its age
+	pi2 := newPutItem(t)
+	id2 := wb.Add(0, "two", 10, true, pi2.put)
+	wb.mu.Lock()
+	wb.lookup[id2].modified = time.Now().Add(-2 * time.Hour)
+	wb.mu.Unlock()
+	wb.Add(id2, "two", 10, false, pi2.put)
+
+	<-pi2.started
+	pi2.finish(nil)
+	waitUntilNoTransfers(t, wb)
+	assert.True(t, pi2.called)
+}
+
+func TestWriteBackMaxAge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := vfscommon.Opt
+	opt.WriteBack = fs.Duration(time.Hour)
+	opt.WriteBackMaxAge = fs.Duration(2 * time.Hour)
+	wb := New(ctx, &opt)
+
+	// Modifying an item doesn't delay it past the max age
+	pi := newPutItem(t)
+	id := wb.Add(0, "one", 10, true, pi.put)
+	wb.mu.Lock()
+	wbItem := wb.lookup[id]
+	wbItem.dirty = time.Now().Add(-90 * time.Minute)
+	wb.mu.Unlock()
+	wb.Add(id, "one", 10, true, pi.put)
+	wb.mu.Lock()
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), wbItem.expiry, time.Minute)
+	wb.mu.Unlock()
+	assert.False(t, wb.Overdue(id))
+
+	// Setting a dirty time older than the max age writes it back now
+	wb.SetDirtyTime(id, time.Now().Add(-3*time.Hour))
+	<-pi.started
+	assert.True(t, wb.Overdue(id))
+
+	// Modifying it while overdue doesn't cancel the upload but
+	// queues it again afterwards
+	wb.Add(id, "one", 20, true, pi.put)
+	checkNotOnHeap(t, wb, wbItem)
+	pi.finish(nil)
+	waitUntilNoTransfers(t, wb)
+	assert.False(t, pi.cancelled)
+	checkOnHeap(t, wb, wbItem)
+	checkInLookup(t, wb, wbItem)
+	wb.mu.Lock()
+	assert.WithinDuration(t, time.Now().Add(time.Hour), wbItem.expiry, time.Minute)
+	wb.mu.Unlock()
+	assert.False(t, wb.Overdue(id))
+}
+
+func TestWriteBackFlush(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := vfscommon.Opt
+	opt.WriteBack = fs.Duration(time.Hour)
+	wb := New(ctx, &opt)
+
+	assert.Equal(t, ErrorIDNotFound, wb.Flush(ctx, 123123123))
+
+	pi1 := newPutItem(t)
+	pi2 := newPutItem(t)
+	id1 := wb.Add(0, "one", 10, true, pi1.put)
+	id2 := wb.Add(0, "two", 20, true, pi2.put)
+	wbItem1 := wb.lookup[id1]
+	wbItem2 := wb.lookup[id2]
+
+	flush := func(id Handle) chan error {
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- wb.Flush(ctx, id)
+		}()
+		return errChan
+	}
+
+	// Only the flushed item is uploaded
+	errChan := flush(id1)
+	<-pi1.started
+	checkNotOnHeap(t, wb, wbItem1)
+	checkOnHeap(t, wb, wbItem2)
+	pi1.finish(nil) // transfer successful
+	require.NoError(t, <-errChan)
+	checkNotInLookup(t, wb, wbItem1)
+	checkOnHeap(t, wb, wbItem2)
+	assert.Equal(t, "two", wb.string(t))
+
+	// A failed upload is returned and queued for retry
+	errChan = flush(id2)
+	<-pi2.started
+	pi2.finish(errors.New("transfer failed BOOM"))
+	err := <-errChan
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BOOM")
+	checkOnHeap(t, wb, wbItem2)
+	checkInLookup(t, wb, wbItem2)
+}
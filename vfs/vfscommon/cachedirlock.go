@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type cacheDirLockChoices struct{}
+
+func (cacheDirLockChoices) Choices() []string {
+	return []string{
+		CacheDirLockOff:   "off",
+		CacheDirLockError: "error",
+		CacheDirLockWait:  "wait",
+	}
+}
+
+// CacheDirLock controls what happens when the cache starts if another
+// VFS is using the same cache directory
+type CacheDirLock = fs.Enum[cacheDirLockChoices]
+
+// CacheDirLock options
+const (
+	CacheDirLockOff   CacheDirLock = iota // don't lock the cache directory
+	CacheDirLockError                     // fail to start the cache if it is in use
+	CacheDirLockWait                      // wait until the other VFS has stopped using it
+)
+
+// Type of the value
+func (cacheDirLockChoices) Type() string {
+	return "CacheDirLock"
+}
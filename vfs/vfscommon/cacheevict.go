@@ -0,0 +1,30 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type cacheEvictPolicyChoices struct{}
+
+func (cacheEvictPolicyChoices) Choices() []string {
+	return []string{
+		CacheEvictLRU:  "lru",
+		CacheEvictLFU:  "lfu",
+		CacheEvictLRFU: "lrfu",
+	}
+}
+
+// CacheEvictPolicy controls which files are evicted from the cache first
+type CacheEvictPolicy = fs.Enum[cacheEvictPolicyChoices]
+
+// CacheEvictPolicy options
+const (
+	CacheEvictLRU  CacheEvictPolicy = iota // evict the least recently used files first
+	CacheEvictLFU                          // evict the least frequently used files first
+	CacheEvictLRFU                         // evict files with the lowest access count decayed by age first
+)
+
+// Type of the value
+func (cacheEvictPolicyChoices) Type() string {
+	return "CacheEvictPolicy"
+}
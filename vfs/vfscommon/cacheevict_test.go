@@ -0,0 +1,38 @@
+package vfscommon
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// Check CacheEvictPolicy it satisfies the pflag interface
+var _ pflag.Value = (*CacheEvictPolicy)(nil)
+
+// Check CacheEvictPolicy it satisfies the json.Unmarshaller interface
+var _ json.Unmarshaler = (*CacheEvictPolicy)(nil)
+
+func TestCacheEvictPolicyString(t *testing.T) {
+	assert.Equal(t, "lru", CacheEvictLRU.String())
+	assert.Equal(t, "lfu", CacheEvictLFU.String())
+	assert.Equal(t, "lrfu", CacheEvictLRFU.String())
+	assert.Equal(t, "Unknown(17)", CacheEvictPolicy(17).String())
+}
+
+func TestCacheEvictPolicySet(t *testing.T) {
+	var m CacheEvictPolicy
+
+	err := m.Set("lfu")
+	assert.NoError(t, err)
+	assert.Equal(t, CacheEvictLFU, m)
+
+	err = m.Set("potato")
+	assert.Error(t, err)
+}
+
+func TestCacheEvictPolicyType(t *testing.T) {
+	var m CacheEvictPolicy
+	assert.Equal(t, "CacheEvictPolicy", m.Type())
+}
@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type cacheSpaceCheckChoices struct{}
+
+func (cacheSpaceCheckChoices) Choices() []string {
+	return []string{
+		CacheSpaceCheckOff:         "off",
+		CacheSpaceCheckError:       "error",
+		CacheSpaceCheckPassthrough: "passthrough",
+	}
+}
+
+// CacheSpaceCheck controls what happens when a file is opened for
+// reading in cache mode full and there isn't room in the cache for it
+type CacheSpaceCheck = fs.Enum[cacheSpaceCheckChoices]
+
+// CacheSpaceCheck options
+const (
+	CacheSpaceCheckOff         CacheSpaceCheck = iota // don't check the space
+	CacheSpaceCheckError                              // fail the open with an error
+	CacheSpaceCheckPassthrough                        // read the file without the cache
+)
+
+// Type of the value
+func (cacheSpaceCheckChoices) Type() string {
+	return "CacheSpaceCheck"
+}
@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type cacheStartupChoices struct{}
+
+func (cacheStartupChoices) Choices() []string {
+	return []string{
+		CacheStartupReuse:    "reuse",
+		CacheStartupValidate: "validate-and-reuse",
+		CacheStartupClear:    "clear",
+	}
+}
+
+// CacheStartup controls what happens to the files left in the cache
+// directory by a previous run when the cache starts
+type CacheStartup = fs.Enum[cacheStartupChoices]
+
+// CacheStartup options
+const (
+	CacheStartupReuse    CacheStartup = iota // use the cached files as they are
+	CacheStartupValidate                     // check the clean cached files against the remote before using them
+	CacheStartupClear                        // remove the cached files without changes waiting to upload
+)
+
+// Type of the value
+func (cacheStartupChoices) Type() string {
+	return "CacheStartup"
+}
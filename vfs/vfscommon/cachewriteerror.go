@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type cacheWriteErrorChoices struct{}
+
+func (cacheWriteErrorChoices) Choices() []string {
+	return []string{
+		CacheWriteErrorOff:   "off",
+		CacheWriteErrorFail:  "fail",
+		CacheWriteErrorRetry: "retry",
+	}
+}
+
+// CacheWriteError controls what happens when writing to a file in
+// the cache fails part of the way through
+type CacheWriteError = fs.Enum[cacheWriteErrorChoices]
+
+// CacheWriteError options
+const (
+	CacheWriteErrorOff   CacheWriteError = iota // return the error and carry on
+	CacheWriteErrorFail                         // fail all further writes and don't upload the file
+	CacheWriteErrorRetry                        // retry the write then fail as CacheWriteErrorFail
+)
+
+// Type of the value
+func (cacheWriteErrorChoices) Type() string {
+	return "CacheWriteError"
+}
@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type caseCreateChoices struct{}
+
+func (caseCreateChoices) Choices() []string {
+	return []string{
+		CaseCreateReuse: "reuse",
+		CaseCreateNew:   "new",
+		CaseCreateError: "error",
+	}
+}
+
+// CaseCreate controls what happens when a file is created with a name
+// which only matches an existing file with a different case
+type CaseCreate = fs.Enum[caseCreateChoices]
+
+// CaseCreate options
+const (
+	CaseCreateReuse CaseCreate = iota // open the existing file
+	CaseCreateNew                     // create a new file with the name given
+	CaseCreateError                   // return an error
+)
+
+// Type of the value
+func (caseCreateChoices) Type() string {
+	return "CaseCreate"
+}
@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type consistencyChoices struct{}
+
+func (consistencyChoices) Choices() []string {
+	return []string{
+		ConsistencyEventual:    "eventual",
+		ConsistencyCloseToOpen: "close-to-open",
+		ConsistencyStrong:      "strong",
+	}
+}
+
+// Consistency controls when files are checked against the remote
+// when they are opened
+type Consistency = fs.Enum[consistencyChoices]
+
+// Consistency options
+const (
+	ConsistencyEventual    Consistency = iota // rely on the directory cache and polling
+	ConsistencyCloseToOpen                    // check when opened unless open already
+	ConsistencyStrong                         // check every time opened
+)
+
+// Type of the value
+func (consistencyChoices) Type() string {
+	return "Consistency"
+}
@@ -0,0 +1,55 @@
+package vfscommon
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+type dirtyConflictChoices struct{}
+
+func (dirtyConflictChoices) Choices() []string {
+	return []string{
+		DirtyConflictLocalWins:   "local-wins",
+		DirtyConflictRemoteWins:  "remote-wins",
+		DirtyConflictRenameLocal: "rename-local",
+		DirtyConflictError:       "error",
+	}
+}
+
+// DirtyConflict controls what happens when the remote reports a
+// change to a file which has local changes waiting to be uploaded
+type DirtyConflict = fs.Enum[dirtyConflictChoices]
+
+// DirtyConflict options
+const (
+	DirtyConflictLocalWins   DirtyConflict = iota // upload the local changes over the remote changes
+	DirtyConflictRemoteWins                       // discard the local changes
+	DirtyConflictRenameLocal                      // upload the local changes under a new name
+	DirtyConflictError                            // stop the upload and leave the changes in the cache
+)
+
+// Type of the value
+func (dirtyConflictChoices) Type() string {
+	return "DirtyConflict"
+}
+
+// conflictTimeFormat is the format of the time put into the names of
+// files renamed with --vfs-dirty-conflict rename-local
+const conflictTimeFormat = "2006-01-02-150405"
+
+// ConflictName returns the name to try on attempt i for the local
+// changes to the file called leaf renamed at t with
+// --vfs-dirty-conflict rename-local
+func ConflictName(leaf string, t time.Time, i int) string {
+	ext := path.Ext(leaf)
+	base := strings.TrimSuffix(leaf, ext)
+	stamp := t.Format(conflictTimeFormat)
+	if i > 0 {
+		return fmt.Sprintf("%s.conflict-%s-%d%s", base, stamp, i, ext)
+	}
+	return fmt.Sprintf("%s.conflict-%s%s", base, stamp, ext)
+}
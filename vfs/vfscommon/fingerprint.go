@@ -0,0 +1,46 @@
+package vfscommon
+
+import (
+	"strings"
+	"time"
+)
+
+// fingerprintTimeLayout is the layout of the modtimes in a
+// fingerprint made by fs.Fingerprint
+const fingerprintTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// FingerprintsEqual returns whether the fingerprints a and b made by
+// fs.Fingerprint should be treated as the same.
+//
+// The sizes and hashes must be identical but the modtimes may differ
+// by up to tolerance to allow for clocks which are out of step. If
+// tolerance is 0 the fingerprints must be identical.
+func FingerprintsEqual(a, b string, tolerance time.Duration) bool {
+	if a == b {
+		return true
+	}
+	if tolerance <= 0 {
+		return false
+	}
+	aParts := strings.Split(a, ",")
+	bParts := strings.Split(b, ",")
+	if len(aParts) != len(bParts) {
+		return false
+	}
+	for i := range aParts {
+		if aParts[i] == bParts[i] {
+			continue
+		}
+		// Only the modtimes are allowed to differ
+		aTime, aErr := time.Parse(fingerprintTimeLayout, aParts[i])
+		bTime, bErr := time.Parse(fingerprintTimeLayout, bParts[i])
+		if aErr != nil || bErr != nil {
+			return false
+		}
+		dt := aTime.Sub(bTime)
+		if dt < -tolerance || dt > tolerance {
+			return false
+		}
+	}
+	return true
+}
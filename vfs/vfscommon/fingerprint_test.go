@@ -0,0 +1,40 @@
+package vfscommon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprintsEqual(t *testing.T) {
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fp := func(size int64, modTime time.Time, hash string) string {
+		s := fmt.Sprintf("%d,%v", size, modTime)
+		if hash != "" {
+			s += "," + hash
+		}
+		return s
+	}
+	for _, test := range []struct {
+		a, b      string
+		tolerance time.Duration
+		want      bool
+	}{
+		{fp(1, t0, ""), fp(1, t0, ""), 0, true},
+		{fp(1, t0, ""), fp(1, t0.Add(time.Second), ""), 0, false},
+		{fp(1, t0, ""), fp(1, t0.Add(time.Second), ""), 2 * time.Second, true},
+		{fp(1, t0.Add(time.Second), ""), fp(1, t0, ""), 2 * time.Second, true},
+		{fp(1, t0, ""), fp(1, t0.Add(3*time.Second), ""), 2 * time.Second, false},
+		{fp(1, t0, ""), fp(1, t0.Add(2*time.Second), ""), 2 * time.Second, true},
+		{fp(1, t0, ""), fp(2, t0.Add(time.Second), ""), 2 * time.Second, false},
+		{fp(1, t0, "abc"), fp(1, t0.Add(time.Second), "abc"), 2 * time.Second, true},
+		{fp(1, t0, "abc"), fp(1, t0.Add(time.Second), "abd"), 2 * time.Second, false},
+		{fp(1, t0, "abc"), fp(1, t0.Add(time.Second), ""), 2 * time.Second, false},
+		{"1,abc", "1,abd", time.Hour, false},
+	} {
+		got := FingerprintsEqual(test.a, test.b, test.tolerance)
+		assert.Equal(t, test.want, got, fmt.Sprintf("%q vs %q within %v", test.a, test.b, test.tolerance))
+	}
+}
@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type invalidNamesChoices struct{}
+
+func (invalidNamesChoices) Choices() []string {
+	return []string{
+		InvalidNamesAllow:  "allow",
+		InvalidNamesReject: "reject",
+		InvalidNamesEncode: "encode",
+	}
+}
+
+// InvalidNames controls what happens to names given to the VFS which
+// the backend can't store, as set by --vfs-invalid-names
+type InvalidNames = fs.Enum[invalidNamesChoices]
+
+// InvalidNames options
+const (
+	InvalidNamesAllow  InvalidNames = iota // pass the name to the backend as it is
+	InvalidNamesReject                     // refuse to create the file or directory
+	InvalidNamesEncode                     // encode the name so the backend can store it
+)
+
+// Type of the value
+func (invalidNamesChoices) Type() string {
+	return "InvalidNames"
+}
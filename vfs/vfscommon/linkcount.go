@@ -0,0 +1,28 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type linkCountChoices struct{}
+
+func (linkCountChoices) Choices() []string {
+	return []string{
+		LinkCountOne:   "one",
+		LinkCountDedup: "dedup",
+	}
+}
+
+// LinkCount controls the number of hard links reported for files
+type LinkCount = fs.Enum[linkCountChoices]
+
+// LinkCount options
+const (
+	LinkCountOne   LinkCount = iota // every file has one link
+	LinkCountDedup                  // files sharing a cache file with --vfs-cache-dedup have a link for each
+)
+
+// Type of the value
+func (linkCountChoices) Type() string {
+	return "LinkCount"
+}
@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type modTimeStoreChoices struct{}
+
+func (modTimeStoreChoices) Choices() []string {
+	return []string{
+		ModTimeStoreOff:      "off",
+		ModTimeStoreMetadata: "metadata",
+		ModTimeStoreIndex:    "index",
+	}
+}
+
+// ModTimeStore controls where the modification times set on files
+// are kept if the backend can't set them
+type ModTimeStore = fs.Enum[modTimeStoreChoices]
+
+// ModTimeStore options
+const (
+	ModTimeStoreOff      ModTimeStore = iota // don't keep modification times the backend can't set
+	ModTimeStoreMetadata                     // keep them in the metadata of the objects
+	ModTimeStoreIndex                        // keep them in an index in the cache directory
+)
+
+// Type of the value
+func (modTimeStoreChoices) Type() string {
+	return "ModTimeStore"
+}
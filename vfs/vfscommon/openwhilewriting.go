@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type openWhileWritingChoices struct{}
+
+func (openWhileWritingChoices) Choices() []string {
+	return []string{
+		OpenWhileWritingCache:  "cache",
+		OpenWhileWritingRemote: "remote",
+		OpenWhileWritingBusy:   "busy",
+	}
+}
+
+// OpenWhileWriting controls what happens when a file is opened for
+// reading while another handle is writing it
+type OpenWhileWriting = fs.Enum[openWhileWritingChoices]
+
+// OpenWhileWriting options
+const (
+	OpenWhileWritingCache  OpenWhileWriting = iota // read the data written so far as usual
+	OpenWhileWritingRemote                         // read the version last written to the remote
+	OpenWhileWritingBusy                           // fail the open with EBUSY
+)
+
+// Type of the value
+func (openWhileWritingChoices) Type() string {
+	return "OpenWhileWriting"
+}
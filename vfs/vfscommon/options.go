@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/chunkedreader"
+	"github.com/rclone/rclone/lib/encoder"
 )
 
 // OptionsInfo describes the Options in use
@@ -30,6 +32,21 @@ var OptionsInfo = fs.Options{{
 	Default: fs.Duration(5 * 60 * time.Second),
 	Help:    "Time to cache directory entries for",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_dir_cache_jitter",
+	Default: fs.Duration(0),
+	Help:    "Spread the expiry of cached directory entries over up to this much extra time",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_dir_cache_max_entries",
+	Default: 0,
+	Help:    "Max number of directory listings to cache in memory, forgetting the least recently used (0 is unlimited)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_persist_dir_cache",
+	Default: false,
+	Help:    "Keep directory listings on disk so they can be used after a restart",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_refresh",
 	Default: false,
@@ -40,6 +57,11 @@ var OptionsInfo = fs.Options{{
 	Default: fs.Duration(time.Minute),
 	Help:    "Time to wait between polling for changes, must be smaller than dir-cache-time and only on supported remotes (set 0 to disable)",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_notify_debounce",
+	Default: fs.Duration(0),
+	Help:    "Wait for change notifications for a file to stop for this long before invalidating it (0 to disable)",
+	Groups:  "VFS",
 }, {
 	Name:    "read_only",
 	Default: false,
@@ -50,11 +72,36 @@ var OptionsInfo = fs.Options{{
 	Default: false,
 	Help:    "Translate symlinks to/from regular files with a '" + fs.LinkSuffix + "' extension for the VFS",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_links_max_depth",
+	Default: 32,
+	Help:    "Max number of symlinks to follow when resolving a symlink before reporting a loop",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_delete_batch_window",
+	Default: fs.Duration(0),
+	Help:    "Time to wait so file deletes can be done together, 0 to delete straight away",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_stable_inodes",
+	Default: false,
+	Help:    "Make inode numbers from the paths of the files so they stay the same across restarts",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_reserved_names",
+	Default: ReservedNamesHide,
+	Help:    "What to do with entries from the remote named . or .. or with invalid characters",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_cache_mode",
 	Default: CacheModeOff,
 	Help:    "Cache mode off|minimal|writes|full",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_downloading_suffix",
+	Default: "",
+	Help:    "If set list an empty marker file with this suffix next to files being downloaded into the cache",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_cache_poll_interval",
 	Default: fs.Duration(60 * time.Second),
@@ -65,6 +112,11 @@ var OptionsInfo = fs.Options{{
 	Default: fs.Duration(3600 * time.Second),
 	Help:    "Max time since last access of objects in the cache",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_max_age_rules",
+	Default: "",
+	Help:    "Comma separated list of dir=duration overriding --vfs-cache-max-age for the files in dir, e.g. news=10m,archive=30d",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_cache_max_size",
 	Default: fs.SizeSuffix(-1),
@@ -75,6 +127,96 @@ var OptionsInfo = fs.Options{{
 	Default: fs.SizeSuffix(-1),
 	Help:    "Target minimum free space on the disk containing the cache",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_evict_policy",
+	Default: CacheEvictLRU,
+	Help:    "Order to evict files from the cache when over quota lru|lfu|lrfu",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_space_check",
+	Default: CacheSpaceCheckOff,
+	Help:    "Check there is room in the cache before reading a file in cache mode full off|error|passthrough",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_large_file_warn_threshold",
+	Default: 0.0,
+	Help:    "Warn when reading a file bigger than this fraction of --vfs-cache-max-size in cache mode full (0 is off)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_write_error",
+	Default: CacheWriteErrorOff,
+	Help:    "What to do if a write to a file in the cache fails off|fail|retry",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_startup",
+	Default: CacheStartupReuse,
+	Help:    "What to do with files left in the cache by a previous run reuse|validate-and-reuse|clear",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_dir_lock",
+	Default: CacheDirLockOff,
+	Help:    "What to do if another rclone is using the same cache directory off|error|wait",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_writeback_recovery",
+	Default: WriteBackRecoveryReupload,
+	Help:    "What to do on startup with objects left partially uploaded by a previous run",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_degrade_errors",
+	Default: 0,
+	Help:    "Fall back to --vfs-cache-mode minimal after this many cache write errors in a row (0 to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_status_file",
+	Default: "",
+	Help:    "Name of a read only file in the root showing the cache status as JSON (empty to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_dedup",
+	Default: false,
+	Help:    "Store cached files with identical content only once using hard links",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_link_count",
+	Default: LinkCountOne,
+	Help:    "Number of hard links to report for files one|dedup",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_skip_empty",
+	Default: false,
+	Help:    "Don't cache zero-byte files opened for read in --vfs-cache-mode full",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_prefetch_on_list",
+	Default: 0,
+	Help:    "Number of files to download into the cache when a directory is listed in --vfs-cache-mode full (0 to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_prefetch_on_list_pattern",
+	Default: "",
+	Help:    "Only prefetch files with names matching this glob, e.g. *.mkv",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_temp_file_timeout",
+	Default: fs.Duration(time.Hour),
+	Help:    "Age after which leftover temporary files in the cache are removed by vfs/cache-gc",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_consistency",
+	Default: ConsistencyEventual,
+	Help:    "When to check files against the remote as they are opened eventual|close-to-open|strong",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_open_while_writing",
+	Default: OpenWhileWritingCache,
+	Help:    "What to read when opening a file which is being written cache|remote|busy",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_revalidate",
+	Default: "",
+	Help:    "Comma separated list of ext=duration setting how old cached data can be before it is checked against the remote on open, e.g. log=1m,txt=1h",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_read_chunk_size",
 	Default: 128 * fs.Mebi,
@@ -90,6 +232,21 @@ var OptionsInfo = fs.Options{{
 	Default: 0,
 	Help:    "The number of parallel streams to read at once",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_chunk_limit_strategy",
+	Default: chunkedreader.LimitCap,
+	Help:    "What to do when the chunk size reaches --vfs-read-chunk-size-limit",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_chunk_stall_timeout",
+	Default: fs.Duration(0),
+	Help:    "Re-request the range of a parallel read stream which gets no data for this long (0 to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_chunk_min_stream_size",
+	Default: fs.SizeSuffix(0),
+	Help:    "Use fewer parallel read streams if each would request less than this (0 to disable)",
+	Groups:  "VFS",
 }, {
 	Name:    "dir_perms",
 	Default: FileMode(0777),
@@ -105,11 +262,66 @@ var OptionsInfo = fs.Options{{
 	Default: FileMode(0666),
 	Help:    "Link permissions",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_listing_stability_window",
+	Default: 0,
+	Help:    "Number of listings in a row an entry must be added or removed in before the directory cache is changed (0 to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_list_retries",
+	Default: 0,
+	Help:    "Number of times to retry a directory listing which fails with a transient error (0 to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_list_retry_delay",
+	Default: fs.Duration(time.Second),
+	Help:    "Time to wait between retries of a failed directory listing",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_max_dir_entries",
+	Default: 0,
+	Help:    "Max number of entries to show when reading a directory, the rest are hidden (0 is unlimited)",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_case_insensitive",
 	Default: runtime.GOOS == "windows" || runtime.GOOS == "darwin", // default to true on Windows and Mac, false otherwise,
 	Help:    "If a file name not found, find a case insensitive match",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_case_insensitive_create",
+	Default: CaseCreateReuse,
+	Help:    "What to do when creating a file which exists with a different case reuse|new|error",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_case_insensitive_max_scan",
+	Default: 0,
+	Help:    "Don't look for a case insensitive match in directories with more entries than this (0 for unlimited)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_encoding",
+	Default: encoder.EncodeZero,
+	Help:    "Extra encodings to apply to names on top of the backend's, e.g. RightSpace,RightPeriod for Windows clients",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_invalid_names",
+	Default: InvalidNamesAllow,
+	Help:    "What to do with new names the backend can't store allow|reject|encode",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_invalid_names_encoding",
+	Default: encoder.EncodeZero,
+	Help:    "Characters the backend can't store for --vfs-invalid-names, as a list of encodings, if not read from the backend",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_collapse_dirs",
+	Default: false,
+	Help:    "Show chains of directories with a single child directory as one directory, e.g. a/b/c as a-b-c",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_collapse_dirs_separator",
+	Default: "-",
+	Help:    "Separator to join the names of collapsed directories with",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_block_norm_dupes",
 	Default: false,
@@ -120,36 +332,211 @@ var OptionsInfo = fs.Options{{
 	Default: fs.Duration(1000 * time.Millisecond),
 	Help:    "Time to wait for in-sequence write before giving error",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_sequential_write_only",
+	Default: false,
+	Help:    "Fail writes which don't carry on from the end of the file straight away",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_read_wait",
 	Default: fs.Duration(20 * time.Millisecond),
 	Help:    "Time to wait for in-sequence read before seeking",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_timeout",
+	Default: fs.Duration(0),
+	Help:    "Max time for each read from the remote when not using the cache before giving a retryable error (0 to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_per_handle_read_bwlimit",
+	Default: fs.SizeSuffix(-1),
+	Help:    "Bandwidth limit for reading from each open file handle (off to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_per_handle_read_bwlimit_rules",
+	Default: "",
+	Help:    "Per path --vfs-per-handle-read-bwlimit in the form glob=limit,glob=limit",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_fallback_remote",
+	Default: "",
+	Help:    "Remote with the same contents to read from if reading from the remote fails when not using the cache",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_overlay_base",
+	Default: "",
+	Help:    "Read only remote to show underneath the remote, which all writes go to",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_overlay_precedence",
+	Default: OverlayPrecedenceWritable,
+	Help:    "Which remote to show files from if they are in both with --vfs-overlay-base writable|base",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_back_remote",
+	Default: "",
+	Help:    "Remote to write changes to instead of the remote, reading files not on it from the remote",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_xattrs",
+	Default: false,
+	Help:    "Show the cache status of files as user.rclone.* extended attributes on mounts",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_error_file",
+	Default: "",
+	Help:    "Local file whose contents are read instead of a file if reading it fails",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_error_include",
+	Default: "",
+	Help:    "Only use --vfs-read-error-file for files matching this glob, e.g. *.{mkv,mp4}",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_error_map",
+	Default: "",
+	Help:    "Error numbers to return for classes of backend error as a list of class=errno",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_timeout",
+	Default: fs.Duration(0),
+	Help:    "Max time for each write to the remote when not using the cache before giving a retryable error (0 to disable)",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_write_back",
 	Default: fs.Duration(5 * time.Second),
 	Help:    "Time to writeback files after last use when using cache",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_back_min_age",
+	Default: fs.Duration(0),
+	Help:    "Min time a file must be unmodified before writing it back when using cache",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_back_max_age",
+	Default: fs.Duration(0),
+	Help:    "Max time a file can wait to be written back after it was first changed, even if it is still being modified",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_back_changed",
+	Default: WriteBackChangedAllow,
+	Help:    "What to do with files changed while being uploaded from the cache allow|snapshot|retry",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_dirty_conflict",
+	Default: DirtyConflictLocalWins,
+	Help:    "What to do if the remote changes a file waiting to be uploaded local-wins|remote-wins|rename-local|error",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_no_write_back",
+	Default: false,
+	Help:    "Keep changes in the cache and never upload them - they are lost when rclone exits",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_back_bwlimit",
+	Default: "",
+	Help:    "Bandwidth limit for uploads from the cache, optionally a timetable as for --bwlimit",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_back_skip_identical",
+	Default: false,
+	Help:    "Don't upload files closed with the same contents as the remote",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_back_chunk_streams",
+	Default: 0,
+	Help:    "The number of parallel streams to upload each large file from the cache with (0 to use --multi-thread-streams)",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_read_ahead",
 	Default: 0 * fs.Mebi,
 	Help:    "Extra read ahead over --buffer-size when using cache-mode full",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_ahead_growth",
+	Default: 1.0,
+	Help:    "Multiply --vfs-read-ahead by this after each read ahead's worth of a sequential read (1 to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_ahead_limit",
+	Default: fs.SizeSuffix(-1),
+	Help:    "Maximum the read ahead can grow to with --vfs-read-ahead-growth ('off' is unlimited)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_ahead_transform",
+	Default: fs.SizeSuffix(-1),
+	Help:    "Max --vfs-read-ahead if the remote encrypts or compresses the data ('off' to not change it)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_read_priority",
+	Default: false,
+	Help:    "Pause read ahead and prefetch downloads while reads are waiting for data",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_write_through",
+	Default: "",
+	Help:    "Write files with paths matching this glob straight to the remote without using the cache",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_used_is_size",
 	Default: false,
 	Help:    "Use the `rclone size` algorithm for Used size",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_report_size",
+	Default: ReportSizeApparent,
+	Help:    "Size to report as the space files use on disk apparent|allocated",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_snapshot_time",
+	Default: fs.Time{},
+	Help:    "Show the remote read only as it was at this time if the backend supports it",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_expose_versions",
+	Default: false,
+	Help:    "Show old versions of files in a read only .versions directory if the backend supports versions",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_lazy_mkdir",
+	Default: false,
+	Help:    "Defer creating directories on the remote until a file is written in them",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_dir_sizes",
+	Default: false,
+	Help:    "Report the total size of the files in directories using the `rclone size` algorithm",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_dir_sizes_refresh",
+	Default: fs.Duration(5 * time.Minute),
+	Help:    "Time between updates of the directory sizes if --vfs-dir-sizes is set",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_fast_fingerprint",
 	Default: false,
 	Help:    "Use fast (less accurate) fingerprints for change detection",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_clock_skew_tolerance",
+	Default: fs.Duration(0),
+	Help:    "Treat modtimes within this of each other as equal when detecting changes",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_disk_space_total_size",
 	Default: fs.SizeSuffix(-1),
 	Help:    "Specify the total space of disk",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_disk_space_total_size_enforce",
+	Default: false,
+	Help:    "Fail writes which would take the used space over --vfs-disk-space-total-size",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_backend_min_free_space",
+	Default: fs.SizeSuffix(-1),
+	Help:    "Fail writes which would leave less than this free on the remote ('off' to disable)",
+	Groups:  "VFS",
 }, {
 	Name:    "umask",
 	Default: FileMode(getUmask()),
@@ -170,6 +557,41 @@ var OptionsInfo = fs.Options{{
 	Default: "",
 	Help:    "Set the extension to read metadata from.",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_expose_metadata",
+	Default: false,
+	Help:    "Show the metadata files set by --vfs-metadata-extension in directory listings",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_tracing",
+	Default: false,
+	Help:    "Emit OpenTelemetry trace spans for VFS operations",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_access_history",
+	Default: 0,
+	Help:    "Number of recent file accesses to keep for the vfs/access-history rc command (0 to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_recent_files",
+	Default: 0,
+	Help:    "Number of most recently modified files to show in a .recent directory in the root (0 to disable)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_dirty_write_time",
+	Default: false,
+	Help:    "Report the time files waiting to be uploaded were last written as their modtime",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_modtime_store",
+	Default: ModTimeStoreOff,
+	Help:    "Where to keep modification times set on files if the backend can't set them",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_birth_time",
+	Default: false,
+	Help:    "Report the creation time of files on the remote as their birth time where supported",
+	Groups:  "VFS",
 }}
 
 func init() {
@@ -178,38 +600,122 @@ func init() {
 
 // Options is options for creating the vfs
 type Options struct {
-	NoSeek             bool          `config:"no_seek"`        // don't allow seeking if set
-	NoChecksum         bool          `config:"no_checksum"`    // don't check checksums if set
-	ReadOnly           bool          `config:"read_only"`      // if set VFS is read only
-	Links              bool          `config:"vfs_links"`      // if set interpret link files
-	NoModTime          bool          `config:"no_modtime"`     // don't read mod times for files
-	DirCacheTime       fs.Duration   `config:"dir_cache_time"` // how long to consider directory listing cache valid
-	Refresh            bool          `config:"vfs_refresh"`    // refreshes the directory listing recursively on start
-	PollInterval       fs.Duration   `config:"poll_interval"`
-	Umask              FileMode      `config:"umask"`
-	UID                uint32        `config:"uid"`
-	GID                uint32        `config:"gid"`
-	DirPerms           FileMode      `config:"dir_perms"`
-	FilePerms          FileMode      `config:"file_perms"`
-	LinkPerms          FileMode      `config:"link_perms"`
-	ChunkSize          fs.SizeSuffix `config:"vfs_read_chunk_size"`       // if > 0 read files in chunks
-	ChunkSizeLimit     fs.SizeSuffix `config:"vfs_read_chunk_size_limit"` // if > ChunkSize double the chunk size after each chunk until reached
-	ChunkStreams       int           `config:"vfs_read_chunk_streams"`    // Number of download streams to use
-	CacheMode          CacheMode     `config:"vfs_cache_mode"`
-	CacheMaxAge        fs.Duration   `config:"vfs_cache_max_age"`
-	CacheMaxSize       fs.SizeSuffix `config:"vfs_cache_max_size"`
-	CacheMinFreeSpace  fs.SizeSuffix `config:"vfs_cache_min_free_space"`
-	CachePollInterval  fs.Duration   `config:"vfs_cache_poll_interval"`
-	CaseInsensitive    bool          `config:"vfs_case_insensitive"`
-	BlockNormDupes     bool          `config:"vfs_block_norm_dupes"`
-	WriteWait          fs.Duration   `config:"vfs_write_wait"`       // time to wait for in-sequence write
-	ReadWait           fs.Duration   `config:"vfs_read_wait"`        // time to wait for in-sequence read
-	WriteBack          fs.Duration   `config:"vfs_write_back"`       // time to wait before writing back dirty files
-	ReadAhead          fs.SizeSuffix `config:"vfs_read_ahead"`       // bytes to read ahead in cache mode "full"
-	UsedIsSize         bool          `config:"vfs_used_is_size"`     // if true, use the `rclone size` algorithm for Used size
-	FastFingerprint    bool          `config:"vfs_fast_fingerprint"` // if set use fast fingerprints
-	DiskSpaceTotalSize fs.SizeSuffix `config:"vfs_disk_space_total_size"`
-	MetadataExtension  string        `config:"vfs_metadata_extension"` // if set respond to files with this extension with metadata
+	NoSeek                      bool                 `config:"no_seek"`                   // don't allow seeking if set
+	NoChecksum                  bool                 `config:"no_checksum"`               // don't check checksums if set
+	ReadOnly                    bool                 `config:"read_only"`                 // if set VFS is read only
+	Links                       bool                 `config:"vfs_links"`                 // if set interpret link files
+	ReservedNames               ReservedNames        `config:"vfs_reserved_names"`        // what to do with entries from the remote named . or .. or with invalid characters
+	StableInodes                bool                 `config:"vfs_stable_inodes"`         // if set make inode numbers from the paths of the files
+	DeleteBatchWindow           fs.Duration          `config:"vfs_delete_batch_window"`   // time to wait so file deletes can be done together
+	LinksMaxDepth               int                  `config:"vfs_links_max_depth"`       // max number of symlinks to follow when resolving a symlink
+	NoModTime                   bool                 `config:"no_modtime"`                // don't read mod times for files
+	DirCacheTime                fs.Duration          `config:"dir_cache_time"`            // how long to consider directory listing cache valid
+	DirCacheJitter              fs.Duration          `config:"vfs_dir_cache_jitter"`      // max random time added to DirCacheTime for each directory
+	DirCacheMaxEntries          int                  `config:"vfs_dir_cache_max_entries"` // max number of directory listings to cache
+	PersistDirCache             bool                 `config:"vfs_persist_dir_cache"`     // keep directory listings on disk to use after a restart
+	Refresh                     bool                 `config:"vfs_refresh"`               // refreshes the directory listing recursively on start
+	PollInterval                fs.Duration          `config:"poll_interval"`
+	NotifyDebounce              fs.Duration          `config:"vfs_notify_debounce"` // quiet period to coalesce change notifications for a path over
+	Umask                       FileMode             `config:"umask"`
+	UID                         uint32               `config:"uid"`
+	GID                         uint32               `config:"gid"`
+	DirPerms                    FileMode             `config:"dir_perms"`
+	FilePerms                   FileMode             `config:"file_perms"`
+	LinkPerms                   FileMode             `config:"link_perms"`
+	ChunkSize                   fs.SizeSuffix        `config:"vfs_read_chunk_size"`            // if > 0 read files in chunks
+	ChunkSizeLimit              fs.SizeSuffix        `config:"vfs_read_chunk_size_limit"`      // if > ChunkSize double the chunk size after each chunk until reached
+	ChunkStreams                int                  `config:"vfs_read_chunk_streams"`         // Number of download streams to use
+	ChunkLimitStrategy          chunkedreader.Limit  `config:"vfs_read_chunk_limit_strategy"`  // What to do when the chunk size reaches ChunkSizeLimit
+	ChunkStallTimeout           fs.Duration          `config:"vfs_read_chunk_stall_timeout"`   // re-request the range of a stream which gets no data for this long
+	MinChunkStreamSize          fs.SizeSuffix        `config:"vfs_read_chunk_min_stream_size"` // use fewer streams if each would request less than this
+	CacheMode                   CacheMode            `config:"vfs_cache_mode"`
+	CacheMaxAge                 fs.Duration          `config:"vfs_cache_max_age"`
+	CacheMaxAgeRules            string               `config:"vfs_cache_max_age_rules"` // dir=duration list of max ages overriding CacheMaxAge
+	CacheMaxSize                fs.SizeSuffix        `config:"vfs_cache_max_size"`
+	CacheMinFreeSpace           fs.SizeSuffix        `config:"vfs_cache_min_free_space"`
+	CacheDownloadingSuffix      string               `config:"vfs_cache_downloading_suffix"` // suffix for markers of files being downloaded
+	CachePollInterval           fs.Duration          `config:"vfs_cache_poll_interval"`
+	CacheEvictPolicy            CacheEvictPolicy     `config:"vfs_cache_evict_policy"`
+	CacheSpaceCheck             CacheSpaceCheck      `config:"vfs_cache_space_check"`               // what to do if a file won't fit in the cache in cache mode "full"
+	CacheLargeFileWarnThreshold float64              `config:"vfs_cache_large_file_warn_threshold"` // warn when reading files bigger than this fraction of CacheMaxSize
+	CacheWriteError             CacheWriteError      `config:"vfs_cache_write_error"`               // what to do if writing to the cache file fails
+	CacheStartup                CacheStartup         `config:"vfs_cache_startup"`                   // what to do with files left in the cache by a previous run
+	CacheDirLock                CacheDirLock         `config:"vfs_cache_dir_lock"`                  // what to do if another VFS is using the cache directory
+	WriteBackRecovery           WriteBackRecovery    `config:"vfs_writeback_recovery"`              // what to do on startup with objects left partially uploaded by a previous run
+	CacheDegradeErrors          int                  `config:"vfs_cache_degrade_errors"`            // cache write errors in a row before falling back to cache mode minimal
+	CacheStatusFile             string               `config:"vfs_cache_status_file"`               // name of a file in the root showing the cache status
+	CacheDedup                  bool                 `config:"vfs_cache_dedup"`                     // if set share the cache files of items with identical content
+	LinkCount                   LinkCount            `config:"vfs_link_count"`                      // number of hard links to report for files
+	CacheSkipEmpty              bool                 `config:"vfs_cache_skip_empty"`                // if set read zero-byte files directly in cache mode "full"
+	PrefetchOnList              int                  `config:"vfs_prefetch_on_list"`                // number of files to download into the cache when a directory is listed
+	PrefetchOnListPattern       string               `config:"vfs_prefetch_on_list_pattern"`        // glob the names of the files to prefetch must match
+	CacheRevalidate             string               `config:"vfs_cache_revalidate"`                // ext=duration list of when to recheck cached data
+	Consistency                 Consistency          `config:"vfs_consistency"`                     // when to check files against the remote as they are opened
+	OpenWhileWriting            OpenWhileWriting     `config:"vfs_open_while_writing"`              // what to read when opening a file which is being written
+	TempFileTimeout             fs.Duration          `config:"vfs_temp_file_timeout"`               // min age of temporary files removed by cache GC
+	ListingStabilityWindow      int                  `config:"vfs_listing_stability_window"`        // listings in a row an entry must change in before it is applied
+	ListRetries                 int                  `config:"vfs_list_retries"`                    // number of times to retry a directory listing which failed with a transient error
+	ListRetryDelay              fs.Duration          `config:"vfs_list_retry_delay"`                // time to wait between directory listing retries
+	MaxDirEntries               int                  `config:"vfs_max_dir_entries"`                 // max number of entries shown when reading a directory
+	CaseInsensitive             bool                 `config:"vfs_case_insensitive"`
+	CaseInsensitiveCreate       CaseCreate           `config:"vfs_case_insensitive_create"`   // what to do when creating a file which exists with a different case
+	CaseInsensitiveMaxScan      int                  `config:"vfs_case_insensitive_max_scan"` // max entries to scan for a case insensitive match, 0 for unlimited
+	Encoding                    encoder.MultiEncoder `config:"vfs_encoding"`                  // extra encodings to apply to names
+	InvalidNames                InvalidNames         `config:"vfs_invalid_names"`             // what to do with new names the backend can't store
+	InvalidNamesEncoding        encoder.MultiEncoder `config:"vfs_invalid_names_encoding"`    // characters the backend can't store
+	CollapseDirs                bool                 `config:"vfs_collapse_dirs"`             // show single child directory chains as one directory
+	CollapseDirsSeparator       string               `config:"vfs_collapse_dirs_separator"`   // separator to join collapsed directory names with
+	BlockNormDupes              bool                 `config:"vfs_block_norm_dupes"`
+	WriteWait                   fs.Duration          `config:"vfs_write_wait"`                    // time to wait for in-sequence write
+	SequentialWriteOnly         bool                 `config:"vfs_sequential_write_only"`         // if set fail out of sequence writes without waiting
+	ReadWait                    fs.Duration          `config:"vfs_read_wait"`                     // time to wait for in-sequence read
+	ReadTimeout                 fs.Duration          `config:"vfs_read_timeout"`                  // max time for each read from the remote
+	PerHandleReadBwLimit        fs.SizeSuffix        `config:"vfs_per_handle_read_bwlimit"`       // bandwidth limit for reading from each open handle
+	PerHandleReadBwLimitRules   string               `config:"vfs_per_handle_read_bwlimit_rules"` // per path overrides of PerHandleReadBwLimit
+	FallbackRemote              string               `config:"vfs_fallback_remote"`               // remote to read from if reading from the remote fails
+	OverlayBase                 string               `config:"vfs_overlay_base"`                  // read only remote to show underneath the remote
+	OverlayPrecedence           OverlayPrecedence    `config:"vfs_overlay_precedence"`            // which remote to show files in both from
+	WriteBackRemote             string               `config:"vfs_write_back_remote"`             // remote to write to, reading files not on it from the remote
+	CacheXattrs                 bool                 `config:"vfs_cache_xattrs"`                  // show the cache status of files as extended attributes
+	ReadErrorFile               string               `config:"vfs_read_error_file"`               // file to read instead if reading fails
+	ReadErrorInclude            string               `config:"vfs_read_error_include"`            // glob for files to use ReadErrorFile for
+	ErrorMap                    string               `config:"vfs_error_map"`                     // class=errno list of error numbers to return for backend errors
+	WriteTimeout                fs.Duration          `config:"vfs_write_timeout"`                 // max time for each write to the remote
+	WriteBack                   fs.Duration          `config:"vfs_write_back"`                    // time to wait before writing back dirty files
+	WriteBackMinAge             fs.Duration          `config:"vfs_write_back_min_age"`            // min time since last modification before writing back
+	WriteBackMaxAge             fs.Duration          `config:"vfs_write_back_max_age"`            // max time since first modification before writing back
+	WriteBackChanged            WriteBackChanged     `config:"vfs_write_back_changed"`            // what to do with files changed during upload
+	DirtyConflict               DirtyConflict        `config:"vfs_dirty_conflict"`                // what to do if the remote changes a dirty file
+	WriteBackBwLimit            string               `config:"vfs_write_back_bwlimit"`            // bandwidth limit timetable for uploads from the cache
+	WriteBackSkipIdentical      bool                 `config:"vfs_write_back_skip_identical"`     // if set don't upload files which are the same as the remote
+	WriteBackChunkStreams       int                  `config:"vfs_write_back_chunk_streams"`      // number of streams to upload each large file with
+	NoWriteBack                 bool                 `config:"vfs_no_write_back"`                 // if set keep changes in the cache and never upload them
+	ReadAhead                   fs.SizeSuffix        `config:"vfs_read_ahead"`                    // bytes to read ahead in cache mode "full"
+	ReadAheadGrowth             float64              `config:"vfs_read_ahead_growth"`             // multiply ReadAhead by this as sequential reads continue
+	ReadAheadLimit              fs.SizeSuffix        `config:"vfs_read_ahead_limit"`              // max ReadAhead can grow to
+	ReadAheadTransform          fs.SizeSuffix        `config:"vfs_read_ahead_transform"`          // max ReadAhead if the remote is a crypt or compress backend, or -1 to not change it
+	ReadPriority                bool                 `config:"vfs_read_priority"`                 // if set reads waiting for data pause read ahead and prefetch downloads
+	WriteThrough                string               `config:"vfs_write_through"`                 // glob for files to write straight to the remote
+	UsedIsSize                  bool                 `config:"vfs_used_is_size"`                  // if true, use the `rclone size` algorithm for Used size
+	ReportSize                  ReportSize           `config:"vfs_report_size"`                   // which size to report as the space files use on disk
+	SnapshotTime                fs.Time              `config:"vfs_snapshot_time"`                 // if set show the remote as it was at this time
+	ExposeVersions              bool                 `config:"vfs_expose_versions"`               // if set show old versions of files in .versions directories
+	LazyMkdir                   bool                 `config:"vfs_lazy_mkdir"`                    // if set only create directories when a file is written in them
+	DirSizes                    bool                 `config:"vfs_dir_sizes"`                     // if set report directory sizes
+	DirSizesRefresh             fs.Duration          `config:"vfs_dir_sizes_refresh"`             // time between updates of the directory sizes
+	FastFingerprint             bool                 `config:"vfs_fast_fingerprint"`              // if set use fast fingerprints
+	ClockSkewTolerance          fs.Duration          `config:"vfs_clock_skew_tolerance"`          // modtimes within this of each other are treated as equal
+	DiskSpaceTotalSize          fs.SizeSuffix        `config:"vfs_disk_space_total_size"`
+	DiskSpaceTotalSizeEnforce   bool                 `config:"vfs_disk_space_total_size_enforce"` // if set fail writes which would use more than DiskSpaceTotalSize
+	BackendMinFreeSpace         fs.SizeSuffix        `config:"vfs_backend_min_free_space"`        // if >= 0 fail writes which would leave less than this free on the remote
+	MetadataExtension           string               `config:"vfs_metadata_extension"`            // if set respond to files with this extension with metadata
+	ExposeMetadata              bool                 `config:"vfs_expose_metadata"`               // if set list the metadata files in directories
+	Tracing                     bool                 `config:"vfs_tracing"`                       // if set emit trace spans for VFS operations
+	AccessHistory               int                  `config:"vfs_access_history"`                // number of recent file accesses to keep
+	RecentFiles                 int                  `config:"vfs_recent_files"`                  // number of most recently modified files to show in .recent
+	DirtyWriteTime              bool                 `config:"vfs_dirty_write_time"`              // if set report the last write time as the modtime of dirty files
+	BirthTime                   bool                 `config:"vfs_birth_time"`                    // if set report the creation time on the remote as the birth time
+	ModTimeStore                ModTimeStore         `config:"vfs_modtime_store"`                 // where to keep modification times the backend can't set
 }
 
 // Opt is the default options modified by the environment variables and command line flags
@@ -0,0 +1,29 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type overlayPrecedenceChoices struct{}
+
+func (overlayPrecedenceChoices) Choices() []string {
+	return []string{
+		OverlayPrecedenceWritable: "writable",
+		OverlayPrecedenceBase:     "base",
+	}
+}
+
+// OverlayPrecedence controls which layer of --vfs-overlay-base is shown
+// when a file exists in both
+type OverlayPrecedence = fs.Enum[overlayPrecedenceChoices]
+
+// OverlayPrecedence options
+const (
+	OverlayPrecedenceWritable OverlayPrecedence = iota // show the file from the writable remote
+	OverlayPrecedenceBase                              // show the file from the read only base remote
+)
+
+// Type of the value
+func (overlayPrecedenceChoices) Type() string {
+	return "OverlayPrecedence"
+}
@@ -0,0 +1,29 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type reportSizeChoices struct{}
+
+func (reportSizeChoices) Choices() []string {
+	return []string{
+		ReportSizeApparent:  "apparent",
+		ReportSizeAllocated: "allocated",
+	}
+}
+
+// ReportSize controls which size of a file is reported as the space
+// it uses on disk
+type ReportSize = fs.Enum[reportSizeChoices]
+
+// ReportSize options
+const (
+	ReportSizeApparent  ReportSize = iota // the size of the file
+	ReportSizeAllocated                   // the size of the data stored in the cache
+)
+
+// Type of the value
+func (reportSizeChoices) Type() string {
+	return "ReportSize"
+}
@@ -0,0 +1,30 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type reservedNamesChoices struct{}
+
+func (reservedNamesChoices) Choices() []string {
+	return []string{
+		ReservedNamesHide:   "hide",
+		ReservedNamesEncode: "encode",
+	}
+}
+
+// ReservedNames controls what happens to entries in directory
+// listings from the remote whose names can't be used in a file
+// system, such as "." and ".."
+type ReservedNames = fs.Enum[reservedNamesChoices]
+
+// ReservedNames options
+const (
+	ReservedNamesHide   ReservedNames = iota // leave the entries out of the listing
+	ReservedNamesEncode                      // show the entries with the invalid characters replaced
+)
+
+// Type of the value
+func (reservedNamesChoices) Type() string {
+	return "ReservedNames"
+}
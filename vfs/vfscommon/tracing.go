@@ -0,0 +1,40 @@
+package vfscommon
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TracerName is the name of the OpenTelemetry tracer used for the
+// spans made for --vfs-tracing
+const TracerName = "github.com/rclone/rclone/vfs"
+
+// noopSpan is returned by StartSpan when tracing is off
+var noopSpan trace.Span = noop.Span{}
+
+// StartSpan starts a span called name for a VFS operation on path if
+// --vfs-tracing is set in opt, using the global tracer provider.
+//
+// If tracing is off it returns ctx and a span which does nothing, so
+// it is cheap to call on the hot paths. Callers should check
+// span.IsRecording() before making any more attributes.
+func StartSpan(ctx context.Context, opt *Options, name, path string) (context.Context, trace.Span) {
+	if opt == nil || !opt.Tracing {
+		return ctx, noopSpan
+	}
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attribute.String("vfs.path", path)))
+}
+
+// EndSpan ends span, recording err on it if set
+func EndSpan(span trace.Span, err error) {
+	if err != nil && span.IsRecording() {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
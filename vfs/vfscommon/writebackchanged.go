@@ -0,0 +1,31 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type writeBackChangedChoices struct{}
+
+func (writeBackChangedChoices) Choices() []string {
+	return []string{
+		WriteBackChangedAllow:    "allow",
+		WriteBackChangedSnapshot: "snapshot",
+		WriteBackChangedRetry:    "retry",
+	}
+}
+
+// WriteBackChanged controls what happens to files which are changed
+// while they are being uploaded from the cache
+type WriteBackChanged = fs.Enum[writeBackChangedChoices]
+
+// WriteBackChanged options
+const (
+	WriteBackChangedAllow    WriteBackChanged = iota // upload the file as it is read
+	WriteBackChangedSnapshot                         // copy the file to a stable snapshot and upload that
+	WriteBackChangedRetry                            // upload the file again if it changed during the upload
+)
+
+// Type of the value
+func (writeBackChangedChoices) Type() string {
+	return "WriteBackChanged"
+}
@@ -0,0 +1,32 @@
+package vfscommon
+
+import (
+	"github.com/rclone/rclone/fs"
+)
+
+type writeBackRecoveryChoices struct{}
+
+func (writeBackRecoveryChoices) Choices() []string {
+	return []string{
+		WriteBackRecoveryReupload: "reupload",
+		WriteBackRecoveryRemove:   "remove-partial",
+		WriteBackRecoveryDiscard:  "discard",
+	}
+}
+
+// WriteBackRecovery controls what happens on startup to objects which
+// were left partially uploaded on the remote when rclone stopped
+// while uploading changes from the cache
+type WriteBackRecovery = fs.Enum[writeBackRecoveryChoices]
+
+// WriteBackRecovery options
+const (
+	WriteBackRecoveryReupload WriteBackRecovery = iota // upload the changes again replacing the partial object
+	WriteBackRecoveryRemove                            // remove the partial object then upload the changes again
+	WriteBackRecoveryDiscard                           // remove the partial object and throw away the changes
+)
+
+// Type of the value
+func (writeBackRecoveryChoices) Type() string {
+	return "WriteBackRecovery"
+}
@@ -2,12 +2,15 @@ package vfs
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/operations"
 )
 
@@ -20,8 +23,11 @@ type WriteFileHandle struct {
 	pipeWriter  *io.PipeWriter
 	o           fs.Object
 	result      chan error
+	cancel      context.CancelFunc // cancels the upload
 	file        *File
 	offset      int64
+	position    atomic.Int64 // copy of offset for vfs/write-state
+	seq         *seqState    // writes waiting to be in sequence for vfs/write-state
 	flags       int
 	closed      bool // set if handle has been closed
 	writeCalled bool // set the first time Write() is called
@@ -47,6 +53,7 @@ func newWriteFileHandle(d *Dir, f *File, remote string, flags int) (*WriteFileHa
 		file:   f,
 	}
 	fh.cond = sync.Cond{L: &fh.mu}
+	fh.seq = f.VFS().addSeqState("write", f, &fh.position)
 	fh.file.addWriter(fh)
 	return fh, nil
 }
@@ -67,11 +74,18 @@ func (fh *WriteFileHandle) openPending() (err error) {
 		fs.Errorf(fh.remote, "WriteFileHandle: Can't open for write without O_TRUNC on existing file without --vfs-cache-mode >= writes")
 		return EPERM
 	}
+	if err = fh.file.Dir().materialize(); err != nil {
+		fs.Errorf(fh.remote, "WriteFileHandle: %v", err)
+		return err
+	}
 	var pipeReader *io.PipeReader
 	pipeReader, fh.pipeWriter = io.Pipe()
+	var ctx context.Context
+	ctx, fh.cancel = context.WithCancel(context.Background())
 	go func() {
+		defer fh.cancel()
 		// NB Rcat deals with Stats.Transferring, etc.
-		o, err := operations.Rcat(context.TODO(), fh.file.Fs(), fh.remote, pipeReader, time.Now(), nil)
+		o, err := operations.Rcat(ctx, fh.file.Fs(), fh.remote, pipeReader, time.Now(), nil)
 		if err != nil {
 			fs.Errorf(fh.remote, "WriteFileHandle.New Rcat failed: %v", err)
 		}
@@ -128,23 +142,41 @@ func (fh *WriteFileHandle) WriteAt(p []byte, off int64) (n int, err error) {
 // Implementation of WriteAt - call with lock held
 func (fh *WriteFileHandle) writeAt(p []byte, off int64) (n int, err error) {
 	// defer log.Trace(fh.remote, "len=%d off=%d", len(p), off)("n=%d, fh.off=%d, err=%v", &n, &fh.offset, &err)
+	span := fh.file.startSpan("vfs.Write")
+	defer func() {
+		endIOSpan(span, off, len(p), n, err)
+		if n > 0 {
+			fh.file.recordAccess(accessWrite, n)
+		}
+		err = fh.file.VFS().mapError(err)
+	}()
 	if fh.closed {
 		fs.Errorf(fh.remote, "WriteFileHandle.Write: error: %v", EBADF)
 		return 0, ECLOSED
 	}
+	if fh.file.VFS().writesRejected() {
+		return 0, EROFS
+	}
+	if err = fh.file.VFS().checkSequentialWrite(fh.remote, off, fh.offset); err != nil {
+		return 0, err
+	}
 	if fh.offset != off {
-		waitSequential("write", fh.remote, &fh.cond, time.Duration(fh.file.VFS().Opt.WriteWait), &fh.offset, off)
+		waitSequential("write", fh.remote, &fh.cond, time.Duration(fh.file.VFS().Opt.WriteWait), &fh.offset, off, fh.seq, len(p))
 	}
 	if fh.offset != off {
 		fs.Errorf(fh.remote, "WriteFileHandle.Write: can't seek in file without --vfs-cache-mode >= writes")
 		return 0, ESPIPE
 	}
+	if err = fh.file.VFS().checkQuota(int64(len(p))); err != nil {
+		return 0, err
+	}
 	if err = fh.openPending(); err != nil {
 		return 0, err
 	}
 	fh.writeCalled = true
-	n, err = fh.pipeWriter.Write(p)
+	n, err = fh.pipeWrite(p)
 	fh.offset += int64(n)
+	fh.position.Store(fh.offset)
 	fh.file.setSize(fh.offset)
 	if err != nil {
 		fs.Errorf(fh.remote, "WriteFileHandle.Write error: %v", err)
@@ -155,6 +187,35 @@ func (fh *WriteFileHandle) writeAt(p []byte, off int64) (n int, err error) {
 	return n, nil
 }
 
+// pipeWrite writes p to the upload pipe.
+//
+// If --vfs-write-timeout is set and the write takes longer than that
+// then the upload is aborted and a retryable error is returned.
+//
+// Call with fh.mu held
+func (fh *WriteFileHandle) pipeWrite(p []byte) (n int, err error) {
+	timeout := time.Duration(fh.file.VFS().Opt.WriteTimeout)
+	if timeout <= 0 {
+		return fh.pipeWriter.Write(p)
+	}
+	var timedOut atomic.Bool
+	timeoutErr := fserrors.RetryError(fmt.Errorf("write timed out after %v: %w", timeout, errTimeout))
+	pipeWriter, cancel := fh.pipeWriter, fh.cancel
+	timer := time.AfterFunc(timeout, func() {
+		timedOut.Store(true)
+		// Make sure the upload fails rather than
+		// committing a truncated file
+		_ = pipeWriter.CloseWithError(timeoutErr)
+		cancel()
+	})
+	n, err = fh.pipeWriter.Write(p)
+	timer.Stop()
+	if timedOut.Load() {
+		err = timeoutErr
+	}
+	return n, err
+}
+
 // Write writes len(p) bytes from p to the underlying data stream. It returns
 // the number of bytes written from p (0 <= n <= len(p)) and any error
 // encountered that caused the write to stop early. Write must return a non-nil
@@ -190,6 +251,7 @@ func (fh *WriteFileHandle) close() (err error) {
 		return ECLOSED
 	}
 	fh.closed = true
+	fh.file.VFS().removeSeqState(fh.seq)
 	// leave writer open until file is transferred
 	defer func() {
 		fh.file.delWriter(fh)
@@ -210,7 +272,7 @@ func (fh *WriteFileHandle) close() (err error) {
 		// Remove vfs file entry when no object is present
 		_ = fh.file.Remove()
 	}
-	return err
+	return fh.file.VFS().mapError(err)
 }
 
 // Close closes the file
@@ -328,3 +390,14 @@ func (fh *WriteFileHandle) Sync() error {
 func (fh *WriteFileHandle) Name() string {
 	return fh.file.String()
 }
+
+// checkSequentialWrite returns an error if --vfs-sequential-write-only
+// is set and a write at off doesn't start at end, the end of the data
+// written so far.
+func (vfs *VFS) checkSequentialWrite(remote string, off int64, end int64) error {
+	if !vfs.Opt.SequentialWriteOnly || off == end {
+		return nil
+	}
+	fs.Errorf(remote, "vfs: write at offset %d rejected as it doesn't carry on from the end of the file at %d and --vfs-sequential-write-only is set", off, end)
+	return ESPIPE
+}
@@ -7,10 +7,12 @@ import (
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/random"
 	"github.com/stretchr/testify/assert"
@@ -383,3 +385,47 @@ func TestFileReadAtZeroLength(t *testing.T) {
 func TestFileReadAtNonZeroLength(t *testing.T) {
 	testFileReadAt(t, 100)
 }
+
+func TestWriteFileHandleWriteTimeout(t *testing.T) {
+	_, vfs, fh := writeHandleCreate(t)
+	defer func() {
+		_ = fh.Close()
+	}()
+	vfs.Opt.WriteTimeout = fs.Duration(50 * time.Millisecond)
+
+	// Replace the upload pipe with one which is never read
+	_, pipeWriter := io.Pipe()
+	var cancelled atomic.Bool
+	fh.mu.Lock()
+	fh.pipeWriter = pipeWriter
+	fh.cancel = func() { cancelled.Store(true) }
+	n, err := fh.pipeWrite([]byte("hello"))
+	fh.mu.Unlock()
+
+	assert.Equal(t, 0, n)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errTimeout))
+	assert.True(t, fserrors.IsRetryError(err))
+	assert.True(t, cancelled.Load())
+}
+
+func TestWriteFileHandleSequentialWriteOnly(t *testing.T) {
+	_, vfs, fh := writeHandleCreate(t)
+	vfs.Opt.SequentialWriteOnly = true
+	vfs.Opt.WriteWait = fs.Duration(time.Hour)
+
+	n, err := fh.WriteAt([]byte("hello"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	// An out of sequence write fails without waiting
+	start := time.Now()
+	_, err = fh.WriteAt([]byte("world"), 10)
+	assert.Equal(t, ESPIPE, err)
+	assert.Less(t, time.Since(start), time.Minute)
+
+	n, err = fh.WriteAt([]byte(" world"), 5)
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	require.NoError(t, fh.Close())
+}
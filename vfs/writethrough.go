@@ -0,0 +1,47 @@
+package vfs
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// parseWriteThrough parses the --vfs-write-through glob returning nil
+// if it isn't set
+func parseWriteThrough(glob string, ignoreCase bool) (*regexp.Regexp, error) {
+	if glob == "" {
+		return nil, nil
+	}
+	re, err := filter.GlobPathToRegexp(glob, ignoreCase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --vfs-write-through %q: %w", glob, err)
+	}
+	return re, nil
+}
+
+// writeThrough returns true if a write only open of the file with
+// flags should stream the data straight to the remote rather than
+// writing it to the cache, as set by --vfs-write-through.
+//
+// This is only possible if the whole file is being written so the
+// file must be truncated or not exist yet. Files which are in the
+// cache already carry on using it so their writes stay in order.
+//
+// Call without the mutex held
+func (f *File) writeThrough(flags int) bool {
+	vfs := f.VFS()
+	if vfs.writeThrough == nil || vfs.Opt.CacheMode < vfscommon.CacheModeWrites {
+		return false
+	}
+	if flags&os.O_TRUNC == 0 && f.exists() {
+		return false
+	}
+	cachePath := f.CachePath()
+	if vfs.cache.InUse(cachePath) || vfs.cache.Exists(cachePath) {
+		return false
+	}
+	return vfs.writeThrough.MatchString(f.Path())
+}
@@ -0,0 +1,42 @@
+package vfs
+
+import (
+	"strconv"
+)
+
+// Names of the extended attributes set by --vfs-cache-xattrs
+const (
+	XattrCacheMode   = "user.rclone.cache_mode"   // the --vfs-cache-mode in use
+	XattrCacheStatus = "user.rclone.cache_status" // uncached, partial, cached or dirty
+	XattrCacheBytes  = "user.rclone.cache_bytes"  // number of bytes of the file in the cache
+)
+
+// Xattrs returns the extended attributes showing the cache state of
+// the file, keyed by name, or nil if --vfs-cache-xattrs isn't set.
+//
+// These are made afresh from the cache on each call.
+func (f *File) Xattrs() map[string]string {
+	vfs := f.VFS()
+	if !vfs.Opt.CacheXattrs {
+		return nil
+	}
+	status, cached := "uncached", int64(0)
+	if vfs.cache != nil {
+		name := f.CachePath()
+		cached = vfs.cache.DiskSize(name)
+		size := f.Size()
+		switch {
+		case vfs.cache.DirtyItem(name) != nil:
+			status = "dirty"
+		case cached > 0 && cached >= size:
+			status = "cached"
+		case cached > 0:
+			status = "partial"
+		}
+	}
+	return map[string]string{
+		XattrCacheMode:   vfs.Opt.CacheMode.String(),
+		XattrCacheStatus: status,
+		XattrCacheBytes:  strconv.FormatInt(cached, 10),
+	}
+}